@@ -0,0 +1,210 @@
+// Package binlog supports binlog-based incremental backups: it reads the binary log position
+// a dump was taken at, plans which logs to pull next, and reads or cuts mysqlbinlog output by
+// event time for point-in-time recovery.
+package binlog
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"time"
+
+	"dback/backend/db"
+	"dback/models"
+)
+
+// ErrNoPosition means a dump does not say which binary log position it is consistent with.
+var ErrNoPosition = errors.New("dump has no binary log position")
+
+// ErrPurged means the binary log an incremental chain continues from is gone from the server.
+var ErrPurged = errors.New("binary log was purged from the server")
+
+// maxLine is how much of a line is buffered at once; longer lines are passed on in chunks.
+const maxLine = 64 << 10
+
+// positionPattern matches the commented CHANGE MASTER / CHANGE REPLICATION SOURCE statement
+// that --master-data=2 and --source-data=2 write near the top of a dump.
+var positionPattern = regexp.MustCompile(`(?:MASTER|SOURCE)_LOG_FILE='([^']+)',\s*(?:MASTER|SOURCE)_LOG_POS=(\d+)`)
+
+// dumpBodyPrefixes start the first object of a dump; the position comment comes before them.
+var dumpBodyPrefixes = [][]byte{
+	[]byte("DROP TABLE"),
+	[]byte("CREATE TABLE"),
+	[]byte("INSERT "),
+}
+
+// ParseDumpPosition reads the binary log position from the header of a plain-text dump.
+func ParseDumpPosition(r io.Reader) (models.BinlogPosition, error) {
+	br := bufio.NewReaderSize(r, maxLine)
+	atLineStart := true
+	for {
+		line, err := br.ReadSlice('\n')
+		if atLineStart && len(line) > 0 {
+			for _, prefix := range dumpBodyPrefixes {
+				if bytes.HasPrefix(line, prefix) {
+					return models.BinlogPosition{}, ErrNoPosition
+				}
+			}
+			if m := positionPattern.FindSubmatch(line); m != nil {
+				pos, perr := strconv.ParseInt(string(m[2]), 10, 64)
+				if perr != nil {
+					return models.BinlogPosition{}, fmt.Errorf("binary log position %q: %w", m[2], perr)
+				}
+				return models.BinlogPosition{File: string(m[1]), Pos: pos}, nil
+			}
+		}
+		atLineStart = !errors.Is(err, bufio.ErrBufferFull)
+		if errors.Is(err, io.EOF) {
+			return models.BinlogPosition{}, ErrNoPosition
+		}
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return models.BinlogPosition{}, err
+		}
+	}
+}
+
+// Plan picks the binary logs holding the events after from: from's file and every later
+// one. end is where the newest log ends; it equals from when nothing was written since.
+func Plan(from models.BinlogPosition, logs []db.BinaryLog) (files []string, end models.BinlogPosition, err error) {
+	start := -1
+	for i, l := range logs {
+		if l.Name == from.File {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return nil, from, fmt.Errorf("%w: %s", ErrPurged, from.File)
+	}
+	for _, l := range logs[start:] {
+		files = append(files, l.Name)
+	}
+	last := logs[len(logs)-1]
+	end = models.BinlogPosition{File: last.Name, Pos: last.Size}
+	if end.File == from.File && end.Pos < from.Pos {
+		end.Pos = from.Pos
+	}
+	return files, end, nil
+}
+
+// Summary describes the events in mysqlbinlog output. Times are UTC, as printed by
+// mysqlbinlog run with TZ=UTC.
+type Summary struct {
+	Events int
+	First  time.Time
+	Last   time.Time
+}
+
+// Scan reads mysqlbinlog output and summarizes its events.
+func Scan(r io.Reader) (Summary, error) {
+	sum, _, err := copyEvents(io.Discard, r, time.Time{})
+	return sum, err
+}
+
+// Cut copies mysqlbinlog output to w up to and including the last event at or before stop.
+// When later events are dropped, an open transaction is rolled back and the delimiter reset,
+// so the output still replays cleanly. cut reports whether anything was dropped.
+func Cut(w io.Writer, r io.Reader, stop time.Time) (sum Summary, cut bool, err error) {
+	return copyEvents(w, r, stop)
+}
+
+// cutTrailer ends output cut before its last events. mysqlbinlog statements end with the
+// "/*!*/;" delimiter.
+const cutTrailer = "ROLLBACK /* cut at point in time */ /*!*/;\nDELIMITER ;\n# End of log file\n"
+
+var (
+	prefixAt = []byte("# at ")
+	// eventHeader is the comment mysqlbinlog prints before each event:
+	// "#260114  9:05:01 server id 1  end_log_pos 1234 CRC32 0x…	Query	…".
+	eventHeader = regexp.MustCompile(`^#(\d{2})(\d{2})(\d{2})\s+(\d{1,2}):(\d{2}):(\d{2})\s+server id`)
+	// metaEvents are log bookkeeping, not changes; their times do not count as event times.
+	metaEvents = [][]byte{
+		[]byte("\tStart: "),
+		[]byte("\tFormat_desc"),
+		[]byte("\tRotate to "),
+		[]byte("\tPrevious-GTIDs"),
+		[]byte("\tGtid_list"),
+		[]byte("\tBinlog checkpoint"),
+		[]byte("\tStop"),
+	}
+)
+
+func copyEvents(w io.Writer, r io.Reader, stop time.Time) (Summary, bool, error) {
+	var sum Summary
+	br := bufio.NewReaderSize(r, maxLine)
+	var held []byte // a "# at N" line, written once its event is known to be kept
+	atLineStart := true
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			if atLineStart && bytes.HasPrefix(line, prefixAt) {
+				if held != nil {
+					if _, werr := w.Write(held); werr != nil {
+						return sum, false, werr
+					}
+				}
+				held = append(held[:0], line...)
+				line = nil
+			} else if atLineStart && held != nil {
+				if at, meta, ok := parseEventHeader(line); ok {
+					if !stop.IsZero() && at.After(stop) {
+						_, werr := io.WriteString(w, cutTrailer)
+						return sum, true, werr
+					}
+					if !meta {
+						if sum.Events == 0 {
+							sum.First = at
+						}
+						sum.Last = at
+						sum.Events++
+					}
+				}
+				if _, werr := w.Write(held); werr != nil {
+					return sum, false, werr
+				}
+				held = nil
+			}
+			if len(line) > 0 {
+				if _, werr := w.Write(line); werr != nil {
+					return sum, false, werr
+				}
+			}
+		}
+		atLineStart = !errors.Is(err, bufio.ErrBufferFull)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil && !errors.Is(err, bufio.ErrBufferFull) {
+			return sum, false, err
+		}
+	}
+	if held != nil {
+		if _, err := w.Write(held); err != nil {
+			return sum, false, err
+		}
+	}
+	return sum, false, nil
+}
+
+// parseEventHeader reads the time of an event header line and whether it is a bookkeeping event.
+func parseEventHeader(line []byte) (at time.Time, meta bool, ok bool) {
+	m := eventHeader.FindSubmatch(line)
+	if m == nil {
+		return time.Time{}, false, false
+	}
+	n := make([]int, 6)
+	for i := range n {
+		n[i], _ = strconv.Atoi(string(m[i+1]))
+	}
+	at = time.Date(2000+n[0], time.Month(n[1]), n[2], n[3], n[4], n[5], 0, time.UTC)
+	for _, marker := range metaEvents {
+		if bytes.Contains(line, marker) {
+			return at, true, true
+		}
+	}
+	return at, false, true
+}
@@ -0,0 +1,120 @@
+package binlog
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"dback/backend/db"
+	"dback/models"
+)
+
+// mysqlbinlogSample is trimmed mysqlbinlog output: the format description event, two
+// transactions a minute apart and the rotate event that closes the file.
+const mysqlbinlogSample = `/*!50530 SET @@SESSION.PSEUDO_SLAVE_MODE=1*/;
+DELIMITER /*!*/;
+# at 4
+#260110  8:00:00 server id 1  end_log_pos 256 CRC32 0x1f2e3d4c 	Start: binlog v 4, server v 10.11.6-MariaDB created 260110  8:00:00
+BINLOG '
+AAAAAA==
+'/*!*/;
+# at 1200
+#260114  9:05:01 server id 1  end_log_pos 1300 CRC32 0x0a0b0c0d 	Query	thread_id=8	exec_time=0	error_code=0
+SET TIMESTAMP=1768381501/*!*/;
+BEGIN
+/*!*/;
+# at 1300
+#260114  9:05:01 server id 1  end_log_pos 1400 CRC32 0x0a0b0c0e 	Query	thread_id=8	exec_time=0	error_code=0
+use ` + "`shop`" + `/*!*/;
+INSERT INTO orders VALUES (1)
+/*!*/;
+# at 1400
+#260114  9:05:01 server id 1  end_log_pos 1431 CRC32 0x0a0b0c0f 	Xid = 41
+COMMIT/*!*/;
+# at 1431
+#260114  9:06:30 server id 1  end_log_pos 1500 CRC32 0x0a0b0c10 	Query	thread_id=9	exec_time=0	error_code=0
+SET TIMESTAMP=1768381590/*!*/;
+BEGIN
+/*!*/;
+# at 1500
+#260114  9:06:30 server id 1  end_log_pos 1600 CRC32 0x0a0b0c11 	Query	thread_id=9	exec_time=0	error_code=0
+DELETE FROM orders
+/*!*/;
+# at 1600
+#260114  9:06:31 server id 1  end_log_pos 1631 CRC32 0x0a0b0c12 	Xid = 42
+COMMIT/*!*/;
+# at 1631
+#260114  9:10:00 server id 1  end_log_pos 1680 CRC32 0x0a0b0c13 	Rotate to mysql-bin.000008  pos: 4
+DELIMITER ;
+# End of log file
+`
+
+func TestParseDumpPosition(t *testing.T) {
+	for _, line := range []string{
+		"-- CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000007', MASTER_LOG_POS=1200;\n",
+		"-- CHANGE REPLICATION SOURCE TO SOURCE_LOG_FILE='mysql-bin.000007', SOURCE_LOG_POS=1200;\n",
+	} {
+		dump := "/*!40101 SET NAMES utf8mb4 */;\n" + line + "DROP TABLE IF EXISTS `orders`;\n"
+		pos, err := ParseDumpPosition(strings.NewReader(dump))
+		if err != nil || pos != (models.BinlogPosition{File: "mysql-bin.000007", Pos: 1200}) {
+			t.Fatalf("ParseDumpPosition(%q) = %+v, %v", line, pos, err)
+		}
+	}
+	_, err := ParseDumpPosition(strings.NewReader("SET NAMES utf8mb4;\nDROP TABLE IF EXISTS `t`;\n-- MASTER_LOG_FILE='x', MASTER_LOG_POS=1;\n"))
+	if !errors.Is(err, ErrNoPosition) {
+		t.Fatalf("a position after the first table should not count, got %v", err)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	logs := []db.BinaryLog{{Name: "mysql-bin.000006", Size: 900}, {Name: "mysql-bin.000007", Size: 1680}, {Name: "mysql-bin.000008", Size: 98}}
+	files, end, err := Plan(models.BinlogPosition{File: "mysql-bin.000007", Pos: 1200}, logs)
+	if err != nil || strings.Join(files, ",") != "mysql-bin.000007,mysql-bin.000008" || end != (models.BinlogPosition{File: "mysql-bin.000008", Pos: 98}) {
+		t.Fatalf("Plan = %v, %+v, %v", files, end, err)
+	}
+
+	from := models.BinlogPosition{File: "mysql-bin.000008", Pos: 98}
+	if _, end, _ := Plan(from, logs); end != from {
+		t.Fatalf("no new events should end at the start, got %+v", end)
+	}
+	if _, _, err := Plan(models.BinlogPosition{File: "mysql-bin.000002", Pos: 4}, logs); !errors.Is(err, ErrPurged) {
+		t.Fatalf("a purged log should fail, got %v", err)
+	}
+}
+
+func TestScanSkipsBookkeepingEvents(t *testing.T) {
+	sum, err := Scan(strings.NewReader(mysqlbinlogSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Summary{
+		Events: 6,
+		First:  time.Date(2026, 1, 14, 9, 5, 1, 0, time.UTC),
+		Last:   time.Date(2026, 1, 14, 9, 6, 31, 0, time.UTC),
+	}
+	if sum != want {
+		t.Fatalf("Scan = %+v, want %+v", sum, want)
+	}
+}
+
+func TestCutStopsBeforeLaterEvents(t *testing.T) {
+	var out bytes.Buffer
+	sum, cut, err := Cut(&out, strings.NewReader(mysqlbinlogSample), time.Date(2026, 1, 14, 9, 6, 0, 0, time.UTC))
+	if err != nil || !cut {
+		t.Fatalf("Cut = %v, %v", cut, err)
+	}
+	text := out.String()
+	if !strings.Contains(text, "INSERT INTO orders") || strings.Contains(text, "DELETE FROM orders") || strings.Contains(text, "# at 1431") {
+		t.Fatalf("cut output = %s", text)
+	}
+	if !strings.HasSuffix(text, cutTrailer) || sum.Events != 3 {
+		t.Fatalf("cut output should end with the trailer after 3 events (%d): %s", sum.Events, text)
+	}
+
+	out.Reset()
+	if _, cut, err := Cut(&out, strings.NewReader(mysqlbinlogSample), time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)); err != nil || cut || out.String() != mysqlbinlogSample {
+		t.Fatalf("a stop after every event should copy the input unchanged (cut=%v, err=%v)", cut, err)
+	}
+}
@@ -0,0 +1,86 @@
+package chunkstore
+
+import (
+	"errors"
+	"io"
+)
+
+// Chunk sizes. Cut points are chosen by content, so an insert or delete early in a dump
+// only changes the chunks around it and the rest of the dump still matches stored chunks.
+const (
+	minChunkSize = 256 << 10
+	maxChunkSize = 4 << 20
+	// avgChunkBits gives the average chunk size past the minimum: 1 MiB.
+	avgChunkBits = 20
+)
+
+// cutMask tests the high bits of the gear hash, which depend on the last 64 bytes read.
+const cutMask = uint64(1<<avgChunkBits-1) << (64 - avgChunkBits)
+
+// gear maps each byte to a random 64-bit value. It is generated from a fixed seed, so
+// every version of the app cuts the same data in the same places.
+var gear = func() [256]uint64 {
+	var t [256]uint64
+	seed := uint64(0x64626b636863756e) // "dbkchcun"
+	for i := range t {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		t[i] = z ^ (z >> 31)
+	}
+	return t
+}()
+
+// chunker splits a stream into content-defined chunks.
+type chunker struct {
+	r   io.Reader
+	buf []byte
+	n   int // bytes in buf
+	cut int // length of the chunk last returned, still at the start of buf
+	eof bool
+}
+
+func newChunker(r io.Reader) *chunker {
+	return &chunker{r: r, buf: make([]byte, maxChunkSize)}
+}
+
+// next returns the next chunk, valid until the following call, or io.EOF after the last.
+func (c *chunker) next() ([]byte, error) {
+	c.n = copy(c.buf, c.buf[c.cut:c.n])
+	c.cut = 0
+	for !c.eof && c.n < len(c.buf) {
+		m, err := c.r.Read(c.buf[c.n:])
+		c.n += m
+		if errors.Is(err, io.EOF) {
+			c.eof = true
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if c.n == 0 {
+		return nil, io.EOF
+	}
+	c.cut = cutPoint(c.buf[:c.n])
+	return c.buf[:c.cut], nil
+}
+
+// cutPoint returns the length of the chunk at the start of data.
+func cutPoint(data []byte) int {
+	if len(data) <= minChunkSize {
+		return len(data)
+	}
+	end := len(data)
+	if end > maxChunkSize {
+		end = maxChunkSize
+	}
+	var h uint64
+	for i := minChunkSize; i < end; i++ {
+		h = h<<1 + gear[data[i]]
+		if h&cutMask == 0 {
+			return i + 1
+		}
+	}
+	return end
+}
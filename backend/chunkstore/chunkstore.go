@@ -0,0 +1,342 @@
+// Package chunkstore keeps backups in a deduplicating repository inside the backup
+// destination folder. Each dump is cut into content-defined chunks, every chunk is stored
+// once under its SHA-256, and the backup itself becomes a small manifest listing its chunks.
+// Consecutive dumps of the same database share most of their chunks, so each new backup
+// only adds the parts that changed.
+package chunkstore
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dback/backend/codec"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Repository layout: {destination}/.dback-repo/chunks/{id[:2]}/{id}, each chunk the zstd
+// compression of the dump bytes whose SHA-256 is id; locks/ holds one file per backup
+// being stored. Manifests live in the host folders, where the .sql.gz would have been.
+const (
+	FormatName    = "dback-chunks"
+	FormatVersion = 1
+	// Dir is the repository folder inside a backup destination.
+	Dir = ".dback-repo"
+	// ManifestExt replaces .sql.gz on backups stored in a repository.
+	ManifestExt = ".chunks.json"
+
+	chunksDir = "chunks"
+	locksDir  = "locks"
+	// pruneLock is the lock Prune holds; stores hold locks with random names.
+	pruneLock = "prune"
+)
+
+// Manifest describes one backup stored in a repository.
+type Manifest struct {
+	Format  string `json:"format"`
+	Version int    `json:"version"`
+	// Repository is the repository folder, relative to the manifest's folder.
+	Repository string    `json:"repository"`
+	Database   string    `json:"database,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Size and SHA256 describe the whole uncompressed dump.
+	Size   int64      `json:"size"`
+	SHA256 string     `json:"sha256"`
+	Chunks []ChunkRef `json:"chunks"`
+}
+
+// ChunkRef is one chunk of a dump, in order. Size is its uncompressed length.
+type ChunkRef struct {
+	ID   string `json:"id"`
+	Size int64  `json:"size"`
+}
+
+// StoreResult says how much of a dump was new to the repository.
+type StoreResult struct {
+	Chunks    int
+	NewChunks int
+	// AddedBytes is the compressed size of the new chunks: what the backup cost on disk.
+	AddedBytes int64
+}
+
+// IsManifestPath reports whether path names a repository backup by its extension.
+func IsManifestPath(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ManifestExt)
+}
+
+// ManifestPath returns the manifest path for a compressed .sql backup path.
+func ManifestPath(path string) string {
+	return strings.TrimSuffix(codec.TrimExt(path), ".sql") + ManifestExt
+}
+
+// RepositoryDir returns the repository folder of a backup destination.
+func RepositoryDir(destination string) string {
+	return filepath.Join(destination, Dir)
+}
+
+// Exists reports whether root holds a repository.
+func Exists(root string) bool {
+	info, err := os.Stat(filepath.Join(root, chunksDir))
+	return err == nil && info.IsDir()
+}
+
+func chunkPath(root, id string) string {
+	return filepath.Join(root, chunksDir, id[:2], id)
+}
+
+// StoreFile stores the dump at srcPath, in any codec, in the repository at root and writes
+// its manifest to manifestPath. m supplies the manifest's descriptive fields. The manifest
+// is written last, so a failed store leaves only unreferenced chunks that Prune removes.
+func StoreFile(srcPath, manifestPath, root string, m Manifest) (Manifest, StoreResult, error) {
+	r, _, err := codec.Open(srcPath)
+	if err != nil {
+		return Manifest{}, StoreResult{}, err
+	}
+	defer r.Close()
+	return Store(r, manifestPath, root, m)
+}
+
+// Store cuts the uncompressed dump read from r into chunks, adds the ones the repository at
+// root does not have yet and writes the manifest to manifestPath.
+func Store(r io.Reader, manifestPath, root string, m Manifest) (Manifest, StoreResult, error) {
+	rel, err := filepath.Rel(filepath.Dir(manifestPath), root)
+	if err != nil {
+		return Manifest{}, StoreResult{}, fmt.Errorf("repository folder: %w", err)
+	}
+	unlock, err := lock(root)
+	if err != nil {
+		return Manifest{}, StoreResult{}, err
+	}
+	defer unlock()
+
+	enc, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	if err != nil {
+		return Manifest{}, StoreResult{}, err
+	}
+	defer enc.Close()
+
+	m.Format, m.Version, m.Repository = FormatName, FormatVersion, filepath.ToSlash(rel)
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	m.Size, m.Chunks = 0, nil
+	var res StoreResult
+	whole := sha256.New()
+	c := newChunker(r)
+	var packed []byte
+	for {
+		data, err := c.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, StoreResult{}, fmt.Errorf("read dump: %w", err)
+		}
+		whole.Write(data)
+		sum := sha256.Sum256(data)
+		id := hex.EncodeToString(sum[:])
+		m.Chunks = append(m.Chunks, ChunkRef{ID: id, Size: int64(len(data))})
+		m.Size += int64(len(data))
+		res.Chunks++
+		path := chunkPath(root, id)
+		if _, err := os.Stat(path); err == nil {
+			continue
+		}
+		packed = enc.EncodeAll(data, packed[:0])
+		if err := writeAtomic(path, packed); err != nil {
+			return Manifest{}, StoreResult{}, fmt.Errorf("store chunk: %w", err)
+		}
+		res.NewChunks++
+		res.AddedBytes += int64(len(packed))
+	}
+	m.SHA256 = hex.EncodeToString(whole.Sum(nil))
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, StoreResult{}, err
+	}
+	if err := writeAtomic(manifestPath, data); err != nil {
+		return Manifest{}, StoreResult{}, fmt.Errorf("write manifest: %w", err)
+	}
+	return m, res, nil
+}
+
+// writeAtomic writes data to a temporary file next to path and renames it into place.
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// ReadManifest returns the manifest at path.
+func ReadManifest(path string) (Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	if m.Format != FormatName {
+		return Manifest{}, fmt.Errorf("not a repository backup (format %q)", m.Format)
+	}
+	if m.Version > FormatVersion {
+		return Manifest{}, fmt.Errorf("repository backup version %d is newer than supported (%d)", m.Version, FormatVersion)
+	}
+	return m, nil
+}
+
+// Root returns the repository folder a manifest's chunks are in.
+func (m Manifest) Root(manifestPath string) string {
+	return filepath.Join(filepath.Dir(manifestPath), filepath.FromSlash(m.Repository))
+}
+
+// Open reassembles the dump of the manifest at path as it is read. Every chunk is checked
+// against its SHA-256, so a damaged or missing chunk is an error rather than a bad restore.
+func Open(path string) (io.ReadCloser, error) {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{root: m.Root(path), chunks: m.Chunks, dec: dec}, nil
+}
+
+type reader struct {
+	root   string
+	chunks []ChunkRef
+	dec    *zstd.Decoder
+	cur    []byte
+	buf    []byte
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.cur) == 0 {
+		if len(r.chunks) == 0 {
+			return 0, io.EOF
+		}
+		data, err := r.load(r.chunks[0])
+		if err != nil {
+			return 0, err
+		}
+		r.chunks, r.cur = r.chunks[1:], data
+	}
+	n := copy(p, r.cur)
+	r.cur = r.cur[n:]
+	return n, nil
+}
+
+func (r *reader) load(c ChunkRef) ([]byte, error) {
+	if len(c.ID) < 2 {
+		return nil, fmt.Errorf("manifest lists an invalid chunk %q", c.ID)
+	}
+	packed, err := os.ReadFile(chunkPath(r.root, c.ID))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("chunk %s is missing from the repository", shortID(c.ID))
+		}
+		return nil, err
+	}
+	data, err := r.dec.DecodeAll(packed, r.buf[:0])
+	if err != nil {
+		return nil, fmt.Errorf("chunk %s is damaged: %w", shortID(c.ID), err)
+	}
+	r.buf = data
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != c.ID || int64(len(data)) != c.Size {
+		return nil, fmt.Errorf("chunk %s is damaged: content does not match its checksum", shortID(c.ID))
+	}
+	return data, nil
+}
+
+func (r *reader) Close() error {
+	r.dec.Close()
+	return nil
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
+
+// Check reads the whole dump of the manifest at path and compares it with the manifest.
+func Check(path string) error {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return err
+	}
+	r, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, bufio.NewReaderSize(r, 1<<20))
+	if err != nil {
+		return err
+	}
+	if n != m.Size || hex.EncodeToString(h.Sum(nil)) != m.SHA256 {
+		return errors.New("reassembled dump does not match the manifest")
+	}
+	return nil
+}
+
+// lock marks the repository as in use by a store until unlock is called, so Prune does not
+// delete chunks whose manifest is not written yet. Each side writes its lock before looking
+// for the other's, so a store and a prune that start together cannot both go ahead.
+func lock(root string) (func(), error) {
+	dir := filepath.Join(root, locksDir)
+	if err := os.MkdirAll(filepath.Join(root, chunksDir), 0755); err != nil {
+		return nil, fmt.Errorf("create repository: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create repository: %w", err)
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, hex.EncodeToString(id[:]))
+	if err := os.WriteFile(path, []byte(time.Now().UTC().Format(time.RFC3339)), 0644); err != nil {
+		return nil, fmt.Errorf("lock repository: %w", err)
+	}
+	if info, err := os.Stat(filepath.Join(dir, pruneLock)); err == nil && time.Since(info.ModTime()) < staleLock {
+		_ = os.Remove(path)
+		return nil, errors.New("the repository is being pruned; try again when it has finished")
+	}
+	return func() { _ = os.Remove(path) }, nil
+}
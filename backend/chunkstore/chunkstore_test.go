@@ -0,0 +1,198 @@
+package chunkstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeDump returns a dump of about size bytes whose rows differ, so chunks do not repeat.
+func fakeDump(seed int64, size int) []byte {
+	rng := rand.New(rand.NewSource(seed))
+	var b bytes.Buffer
+	b.WriteString("CREATE TABLE `orders` (`id` int, `note` text);\n")
+	for id := 0; b.Len() < size; id++ {
+		fmt.Fprintf(&b, "INSERT INTO `orders` VALUES (%d,'%x');\n", id, rng.Int63())
+	}
+	return b.Bytes()
+}
+
+func writeGzip(t *testing.T, path string, data []byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	gw.Write(data)
+	gw.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readAll(t *testing.T, manifestPath string) []byte {
+	t.Helper()
+	r, err := Open(manifestPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestStoreDeduplicatesConsecutiveDumps(t *testing.T) {
+	dest := t.TempDir()
+	root := RepositoryDir(dest)
+	hostDir := filepath.Join(dest, "shop")
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	first := fakeDump(1, 8<<20)
+	src := filepath.Join(hostDir, "shop_1.sql.gz")
+	writeGzip(t, src, first)
+	m1, res1, err := StoreFile(src, ManifestPath(src), root, Manifest{Database: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res1.NewChunks != res1.Chunks || res1.Chunks < 4 {
+		t.Fatalf("first store = %+v", res1)
+	}
+	if m1.Repository != "../"+Dir || m1.Size != int64(len(first)) {
+		t.Fatalf("manifest = %+v", m1)
+	}
+
+	// The next night a few rows changed in the middle and some were added at the end.
+	second := append([]byte(nil), first[:3<<20]...)
+	second = append(second, "INSERT INTO `orders` VALUES (-1,'changed');\n"...)
+	second = append(second, first[3<<20:]...)
+	second = append(second, "INSERT INTO `orders` VALUES (-2,'added');\n"...)
+	src2 := filepath.Join(hostDir, "shop_2.sql.gz")
+	writeGzip(t, src2, second)
+	_, res2, err := StoreFile(src2, ManifestPath(src2), root, Manifest{Database: "shop"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res2.NewChunks > 3 || res2.NewChunks == 0 {
+		t.Fatalf("second store added %d of %d chunks, want only those around the changes", res2.NewChunks, res2.Chunks)
+	}
+
+	if got := readAll(t, ManifestPath(src)); !bytes.Equal(got, first) {
+		t.Fatal("first dump does not reassemble")
+	}
+	if got := readAll(t, ManifestPath(src2)); !bytes.Equal(got, second) {
+		t.Fatal("second dump does not reassemble")
+	}
+	if err := Check(ManifestPath(src2)); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ReadStats(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Backups != 2 || stats.LogicalBytes != int64(len(first)+len(second)) || stats.UnusedChunks != 0 || stats.MissingChunks != 0 {
+		t.Fatalf("stats = %+v", stats)
+	}
+	if r := stats.DedupRatio(); r < 1.5 {
+		t.Fatalf("dedup ratio = %.2f", r)
+	}
+
+	// Deleting the first backup leaves the chunks only it used to prune.
+	if err := os.Remove(ManifestPath(src)); err != nil {
+		t.Fatal(err)
+	}
+	stats, _ = ReadStats(root)
+	if stats.Backups != 1 || stats.UnusedChunks == 0 || stats.ReclaimableBytes == 0 {
+		t.Fatalf("stats after delete = %+v", stats)
+	}
+	pruned, err := Prune(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned.Chunks != stats.UnusedChunks || pruned.FreedBytes != stats.ReclaimableBytes {
+		t.Fatalf("pruned %+v, stats said %+v", pruned, stats)
+	}
+	if err := Check(ManifestPath(src2)); err != nil {
+		t.Fatalf("second backup damaged by prune: %v", err)
+	}
+}
+
+func TestOpenDetectsDamagedChunk(t *testing.T) {
+	dest := t.TempDir()
+	src := filepath.Join(dest, "db.sql.gz")
+	writeGzip(t, src, fakeDump(2, 1<<20))
+	m, _, err := StoreFile(src, ManifestPath(src), RepositoryDir(dest), Manifest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := chunkPath(RepositoryDir(dest), m.Chunks[0].ID)
+	if err := os.WriteFile(path, []byte("not zstd"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Check(ManifestPath(src)); err == nil || !strings.Contains(err.Error(), "damaged") {
+		t.Fatalf("Check = %v, want damaged chunk", err)
+	}
+	os.Remove(path)
+	if err := Check(ManifestPath(src)); err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Fatalf("Check = %v, want missing chunk", err)
+	}
+}
+
+func TestPruneWaitsForStores(t *testing.T) {
+	dest := t.TempDir()
+	src := filepath.Join(dest, "db.sql.gz")
+	writeGzip(t, src, fakeDump(3, 1<<10))
+	root := RepositoryDir(dest)
+	if _, _, err := StoreFile(src, ManifestPath(src), root, Manifest{}); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err := lock(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Prune(root); err != ErrInUse {
+		t.Fatalf("Prune during a store = %v", err)
+	}
+	unlock()
+	if _, err := Prune(root); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCutPointsFollowContent(t *testing.T) {
+	data := fakeDump(4, 6<<20)
+	cuts := func(data []byte) map[string]bool {
+		set := map[string]bool{}
+		c := newChunker(bytes.NewReader(data))
+		for {
+			chunk, err := c.next()
+			if err == io.EOF {
+				return set
+			}
+			if len(chunk) > maxChunkSize {
+				t.Fatalf("chunk of %d bytes", len(chunk))
+			}
+			set[string(chunk[len(chunk)-64:])] = true
+		}
+	}
+	a := cuts(data)
+	b := cuts(append([]byte("-- shifted\n"), data...))
+	shared := 0
+	for k := range a {
+		if b[k] {
+			shared++
+		}
+	}
+	if shared < len(a)-2 {
+		t.Fatalf("only %d of %d cut points survive a shift", shared, len(a))
+	}
+}
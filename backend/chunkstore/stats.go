@@ -0,0 +1,208 @@
+package chunkstore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// staleLock is how old a lock may get before Prune assumes its store crashed.
+const staleLock = 24 * time.Hour
+
+// ErrInUse is returned by Prune while a backup is being stored in the repository.
+var ErrInUse = errors.New("a backup is being stored in the repository; try again when it has finished")
+
+// Stats describes a repository and the backups stored in it.
+type Stats struct {
+	Root string
+	// Backups is the number of manifests found in the destination folder.
+	Backups int
+	// LogicalBytes is the uncompressed size of every backup, as if each were stored in full.
+	LogicalBytes int64
+	// UniqueBytes is the uncompressed size of the distinct chunks the backups use.
+	UniqueBytes int64
+	// Chunks and StoredBytes count every chunk file on disk.
+	Chunks      int
+	StoredBytes int64
+	// UnusedChunks are chunks no backup uses any more; ReclaimableBytes is their size on
+	// disk, freed by Prune.
+	UnusedChunks     int
+	ReclaimableBytes int64
+	// MissingChunks are used by a backup but not on disk: those backups cannot be restored.
+	MissingChunks int
+}
+
+// DedupRatio is how many times smaller deduplication makes the backups, before compression;
+// 0 when there is nothing stored.
+func (s Stats) DedupRatio() float64 {
+	if s.UniqueBytes == 0 {
+		return 0
+	}
+	return float64(s.LogicalBytes) / float64(s.UniqueBytes)
+}
+
+// PruneResult says what Prune deleted.
+type PruneResult struct {
+	Chunks     int
+	FreedBytes int64
+}
+
+// ReadStats reads the repository at root and the manifests that use it. Manifests are
+// looked for in the destination folder that holds the repository and its host folders.
+func ReadStats(root string) (Stats, error) {
+	stats := Stats{Root: root}
+	used, err := usedChunks(root, &stats)
+	if err != nil {
+		return stats, err
+	}
+	found := map[string]bool{}
+	err = walkChunks(root, func(id string, size int64) {
+		stats.Chunks++
+		stats.StoredBytes += size
+		if _, ok := used[id]; ok {
+			found[id] = true
+			return
+		}
+		stats.UnusedChunks++
+		stats.ReclaimableBytes += size
+	})
+	if err != nil {
+		return stats, err
+	}
+	for id, size := range used {
+		stats.UniqueBytes += size
+		if !found[id] {
+			stats.MissingChunks++
+		}
+	}
+	return stats, nil
+}
+
+// Prune deletes the chunks of the repository at root that no backup uses any more, such as
+// those of backups removed by retention. It refuses while a backup is being stored.
+func Prune(root string) (PruneResult, error) {
+	if !Exists(root) {
+		return PruneResult{}, fmt.Errorf("no repository in %s", root)
+	}
+	if err := os.MkdirAll(filepath.Join(root, locksDir), 0755); err != nil {
+		return PruneResult{}, err
+	}
+	lockPath := filepath.Join(root, locksDir, pruneLock)
+	if err := os.WriteFile(lockPath, []byte(time.Now().UTC().Format(time.RFC3339)), 0644); err != nil {
+		return PruneResult{}, fmt.Errorf("lock repository: %w", err)
+	}
+	defer os.Remove(lockPath)
+	if err := checkUnlocked(root); err != nil {
+		return PruneResult{}, err
+	}
+	used, err := usedChunks(root, &Stats{})
+	if err != nil {
+		return PruneResult{}, err
+	}
+	var res PruneResult
+	var errs []error
+	err = walkChunks(root, func(id string, size int64) {
+		if _, ok := used[id]; ok {
+			return
+		}
+		if err := os.Remove(chunkPath(root, id)); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		res.Chunks++
+		res.FreedBytes += size
+	})
+	if err != nil {
+		errs = append(errs, err)
+	}
+	return res, errors.Join(errs...)
+}
+
+// checkUnlocked returns ErrInUse when a store holds a lock that is not stale.
+func checkUnlocked(root string) error {
+	entries, err := os.ReadDir(filepath.Join(root, locksDir))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == pruneLock {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		if time.Since(info.ModTime()) < staleLock {
+			return ErrInUse
+		}
+	}
+	return nil
+}
+
+// usedChunks returns the uncompressed size of every chunk used by a manifest of the
+// repository, counting the manifests into stats.
+func usedChunks(root string, stats *Stats) (map[string]int64, error) {
+	root = filepath.Clean(root)
+	used := map[string]int64{}
+	err := filepath.WalkDir(filepath.Dir(root), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path == root {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !IsManifestPath(path) {
+			return nil
+		}
+		m, err := ReadManifest(path)
+		if err != nil || filepath.Clean(m.Root(path)) != root {
+			// Not a manifest of this repository.
+			return nil
+		}
+		stats.Backups++
+		stats.LogicalBytes += m.Size
+		for _, c := range m.Chunks {
+			used[c.ID] = c.Size
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read backups: %w", err)
+	}
+	return used, nil
+}
+
+// walkChunks calls fn with the ID and size on disk of every chunk in the repository.
+func walkChunks(root string, fn func(id string, size int64)) error {
+	err := filepath.WalkDir(filepath.Join(root, chunksDir), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() || strings.HasPrefix(d.Name(), ".tmp-") {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fn(d.Name(), info.Size())
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("read chunks: %w", err)
+	}
+	return nil
+}
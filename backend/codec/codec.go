@@ -0,0 +1,369 @@
+// Package codec knows the compression formats backups are written and read in: the shell
+// commands that compress and decompress them on a host, their file extensions, and local
+// readers that detect the format from a file's first bytes.
+package codec
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"dback/models"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// spec describes one codec.
+type spec struct {
+	ext          string
+	minLevel     int
+	maxLevel     int
+	defaultLevel int
+	tool         string // binary needed on the host
+	compress     string // shell command with one %d for the level; empty when read-only
+	decompress   string
+	test         string // shell command taking the file path as its last argument
+}
+
+var specs = map[models.Compression]spec{
+	models.CompressionGzip: {
+		ext: ".gz", minLevel: 1, maxLevel: 9, defaultLevel: 1, tool: "gzip",
+		compress:   "if command -v pigz >/dev/null 2>&1; then pigz -%[1]d; else gzip -%[1]d; fi",
+		decompress: "gzip -dc",
+		test:       "gzip -t",
+	},
+	models.CompressionZstd: {
+		ext: ".zst", minLevel: 1, maxLevel: 19, defaultLevel: 3, tool: "zstd",
+		compress:   "zstd -q -c -%d",
+		decompress: "zstd -q -dc",
+		test:       "zstd -q -t",
+	},
+	models.CompressionXz: {
+		ext: ".xz", minLevel: 1, maxLevel: 9, defaultLevel: 3, tool: "xz",
+		compress:   "xz -c -%d",
+		decompress: "xz -dc",
+		test:       "xz -t",
+	},
+	models.CompressionBzip2: {
+		ext: ".bz2", tool: "bzip2",
+		decompress: "bzip2 -dc",
+		test:       "bzip2 -t",
+	},
+	models.CompressionNone: {
+		compress:   "cat",
+		decompress: "cat",
+	},
+}
+
+// lookup returns the codec's spec; empty means gzip.
+func lookup(c models.Compression) (spec, bool) {
+	if c == "" {
+		c = models.CompressionGzip
+	}
+	s, ok := specs[c]
+	return s, ok
+}
+
+// Normalize maps the empty profile setting to gzip.
+func Normalize(c models.Compression) models.Compression {
+	if c == "" {
+		return models.CompressionGzip
+	}
+	return c
+}
+
+// Validate checks a profile's codec and level. Level 0 means the codec's default.
+func Validate(c models.Compression, level int) error {
+	s, ok := lookup(c)
+	if !ok {
+		return fmt.Errorf("unknown compression %q", c)
+	}
+	if s.compress == "" {
+		return fmt.Errorf("%s is only supported when restoring existing files", c)
+	}
+	if level == 0 || s.maxLevel == 0 {
+		return nil
+	}
+	if level < s.minLevel || level > s.maxLevel {
+		return fmt.Errorf("%s level must be between %d and %d", Normalize(c), s.minLevel, s.maxLevel)
+	}
+	return nil
+}
+
+// Levels returns the codec's level range and default; all zero when it has no levels.
+func Levels(c models.Compression) (lo, hi, def int) {
+	s, _ := lookup(c)
+	return s.minLevel, s.maxLevel, s.defaultLevel
+}
+
+// Ext is the suffix added after ".sql" for the codec, e.g. ".gz"; empty for none.
+func Ext(c models.Compression) string {
+	s, _ := lookup(c)
+	return s.ext
+}
+
+// Tool is the binary a host needs to compress or decompress the codec; empty for none.
+func Tool(c models.Compression) string {
+	s, _ := lookup(c)
+	return s.tool
+}
+
+// CompressCommand reads stdin and writes it compressed to stdout.
+func CompressCommand(c models.Compression, level int) string {
+	s, ok := lookup(c)
+	if !ok || s.compress == "" {
+		s = specs[models.CompressionGzip]
+	}
+	if !strings.Contains(s.compress, "%") {
+		return s.compress
+	}
+	if level == 0 {
+		level = s.defaultLevel
+	}
+	return fmt.Sprintf(s.compress, level)
+}
+
+// DecompressCommand reads the codec on stdin, or from a file given as its last argument,
+// and writes plain SQL to stdout. Unknown codecs pass through with cat.
+func DecompressCommand(c models.Compression) string {
+	if s, ok := specs[c]; ok {
+		return s.decompress
+	}
+	return "cat"
+}
+
+// TestCommand checks a compressed file on the host; empty when the codec cannot be tested.
+func TestCommand(c models.Compression, path string) string {
+	s, _ := lookup(c)
+	if s.test == "" {
+		return ""
+	}
+	return s.test + " " + path
+}
+
+// FromPath guesses the codec from a file name's extension.
+func FromPath(path string) models.Compression {
+	lower := strings.ToLower(path)
+	for c, s := range specs {
+		if s.ext != "" && strings.HasSuffix(lower, s.ext) {
+			return c
+		}
+	}
+	if strings.HasSuffix(lower, ".zstd") {
+		return models.CompressionZstd
+	}
+	return models.CompressionNone
+}
+
+// TrimExt removes a codec extension from path.
+func TrimExt(path string) string {
+	if s := specs[FromPath(path)]; s.ext != "" {
+		return path[:len(path)-len(s.ext)]
+	}
+	return strings.TrimSuffix(path, ".zstd")
+}
+
+// Sniff reads the codec from the first bytes of a file.
+func Sniff(head []byte) models.Compression {
+	switch {
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return models.CompressionGzip
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return models.CompressionZstd
+	case bytes.HasPrefix(head, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}):
+		return models.CompressionXz
+	case len(head) >= 4 && bytes.HasPrefix(head, []byte("BZh")) && head[3] >= '1' && head[3] <= '9':
+		return models.CompressionBzip2
+	}
+	return models.CompressionNone
+}
+
+// sniffLen is how many bytes Sniff needs.
+const sniffLen = 6
+
+// Detect reads the codec of r from its first bytes and rewinds it.
+func Detect(r io.ReadSeeker) (models.Compression, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	return Sniff(head[:n]), nil
+}
+
+// NewReader decompresses r. xz is read with the xz tool, which must be on PATH.
+func NewReader(r io.Reader, c models.Compression) (io.ReadCloser, error) {
+	switch Normalize(c) {
+	case models.CompressionGzip:
+		return gzip.NewReader(r)
+	case models.CompressionZstd:
+		d, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	case models.CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(r)), nil
+	case models.CompressionXz:
+		return newToolReader(r, "xz", "-dc")
+	case models.CompressionNone:
+		return io.NopCloser(r), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", c)
+}
+
+// Open opens a backup file and decompresses it in whatever codec it was written with.
+func Open(path string) (io.ReadCloser, models.Compression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	r, c, err := Decompress(f)
+	if err != nil {
+		f.Close()
+		return nil, c, err
+	}
+	return &fileReader{ReadCloser: r, file: f}, c, nil
+}
+
+// Decompress detects the codec of r from its first bytes and decompresses it.
+func Decompress(r io.Reader) (io.ReadCloser, models.Compression, error) {
+	br := bufio.NewReaderSize(r, 1<<20)
+	head, _ := br.Peek(sniffLen)
+	c := Sniff(head)
+	rc, err := NewReader(br, c)
+	if err != nil {
+		return nil, c, fmt.Errorf("read %s: %w", c, err)
+	}
+	return rc, c, nil
+}
+
+type fileReader struct {
+	io.ReadCloser
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	err := r.ReadCloser.Close()
+	if cerr := r.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Check reads a whole backup file to make sure it decompresses. A file named for a codec
+// must be in that codec; plain files pass. Without a local xz tool, xz files only have
+// their header and footer checked.
+func Check(path string) error {
+	r, c, err := Open(path)
+	if errors.Is(err, ErrToolMissing) && c == models.CompressionXz {
+		return checkXzFraming(path)
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if c == models.CompressionNone {
+		if want := FromPath(path); want != models.CompressionNone {
+			return fmt.Errorf("file is named %s but is not %s compressed", Ext(want), want)
+		}
+		return nil
+	}
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf("%s check failed: %w", c, err)
+	}
+	return nil
+}
+
+// checkXzFraming checks the stream footer magic that ends every complete xz file.
+func checkXzFraming(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	var tail [2]byte
+	if info.Size() < 32 {
+		return errors.New("xz check failed: file too short")
+	}
+	if _, err := f.ReadAt(tail[:], info.Size()-2); err != nil {
+		return err
+	}
+	if tail != [2]byte{'Y', 'Z'} {
+		return errors.New("xz check failed: stream footer missing, the file is incomplete")
+	}
+	return nil
+}
+
+// ErrToolMissing means a codec is read with a tool this computer does not have.
+var ErrToolMissing = errors.New("decompression tool not installed on this computer")
+
+// toolReader runs a decompression tool over r and reports its exit status at EOF.
+type toolReader struct {
+	out    io.ReadCloser
+	cmd    *exec.Cmd
+	stderr bytes.Buffer
+	done   bool
+	err    error
+}
+
+func newToolReader(r io.Reader, tool string, args ...string) (io.ReadCloser, error) {
+	path, err := exec.LookPath(tool)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrToolMissing, tool)
+	}
+	t := &toolReader{cmd: exec.Command(path, args...)}
+	t.cmd.Stdin = r
+	t.cmd.Stderr = &t.stderr
+	if t.out, err = t.cmd.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	if err := t.cmd.Start(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *toolReader) Read(p []byte) (int, error) {
+	n, err := t.out.Read(p)
+	if err == io.EOF {
+		if werr := t.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+func (t *toolReader) wait() error {
+	if !t.done {
+		t.done = true
+		if err := t.cmd.Wait(); err != nil {
+			t.err = fmt.Errorf("%s: %w: %s", t.cmd.Path, err, strings.TrimSpace(t.stderr.String()))
+		}
+	}
+	return t.err
+}
+
+func (t *toolReader) Close() error {
+	if !t.done {
+		_ = t.out.Close()
+		_ = t.cmd.Process.Kill()
+		_ = t.wait()
+		return nil
+	}
+	return t.err
+}
@@ -0,0 +1,142 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dback/models"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const sample = "CREATE TABLE t (id int);\nINSERT INTO t VALUES (1),(2);\n"
+
+func gzipBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write([]byte(sample))
+	w.Close()
+	return buf.Bytes()
+}
+
+func zstdBytes(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(sample))
+	w.Close()
+	return buf.Bytes()
+}
+
+// toolBytes compresses sample with a local tool, skipping the test when it is missing.
+func toolBytes(t *testing.T, tool string) []byte {
+	t.Helper()
+	if _, err := exec.LookPath(tool); err != nil {
+		t.Skipf("%s not installed", tool)
+	}
+	cmd := exec.Command(tool, "-c")
+	cmd.Stdin = strings.NewReader(sample)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOpenReadsEachCodec(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want models.Compression
+		data func(*testing.T) []byte
+	}{
+		{"dump.sql.gz", models.CompressionGzip, gzipBytes},
+		{"dump.sql.zst", models.CompressionZstd, zstdBytes},
+		{"dump.sql.bz2", models.CompressionBzip2, func(t *testing.T) []byte { return toolBytes(t, "bzip2") }},
+		{"dump.sql.xz", models.CompressionXz, func(t *testing.T) []byte { return toolBytes(t, "xz") }},
+		{"dump.sql", models.CompressionNone, func(*testing.T) []byte { return []byte(sample) }},
+	} {
+		t.Run(string(tc.want), func(t *testing.T) {
+			path := writeFile(t, tc.name, tc.data(t))
+			r, c, err := Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			got, err := io.ReadAll(r)
+			if err != nil || string(got) != sample {
+				t.Fatalf("read %q, %v", got, err)
+			}
+			if c != tc.want || FromPath(path) != tc.want {
+				t.Fatalf("codec = %s, from path %s, want %s", c, FromPath(path), tc.want)
+			}
+			if err := Check(path); err != nil {
+				t.Fatalf("Check: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckRejectsBrokenFiles(t *testing.T) {
+	gz := gzipBytes(t)
+	if err := Check(writeFile(t, "cut.sql.gz", gz[:len(gz)/2])); err == nil {
+		t.Fatal("a truncated gzip file should fail")
+	}
+	zs := zstdBytes(t)
+	if err := Check(writeFile(t, "cut.sql.zst", zs[:len(zs)-4])); err == nil {
+		t.Fatal("a truncated zstd file should fail")
+	}
+	if err := Check(writeFile(t, "plain.sql.gz", []byte(sample))); err == nil {
+		t.Fatal("a plain file named .gz should fail")
+	}
+}
+
+func TestValidateAndCommands(t *testing.T) {
+	if err := Validate("", 0); err != nil {
+		t.Fatalf("default codec: %v", err)
+	}
+	if err := Validate(models.CompressionZstd, 19); err != nil {
+		t.Fatalf("zstd 19: %v", err)
+	}
+	for _, bad := range []struct {
+		c     models.Compression
+		level int
+	}{{models.CompressionGzip, 10}, {models.CompressionBzip2, 0}, {"lz4", 0}} {
+		if err := Validate(bad.c, bad.level); err == nil {
+			t.Fatalf("%s level %d should fail", bad.c, bad.level)
+		}
+	}
+	if got := CompressCommand("", 0); !strings.Contains(got, "pigz -1") || !strings.Contains(got, "gzip -1") {
+		t.Fatalf("default compress command = %s", got)
+	}
+	if got := CompressCommand(models.CompressionXz, 6); got != "xz -c -6" {
+		t.Fatalf("xz compress command = %s", got)
+	}
+	if got := DecompressCommand(models.CompressionBzip2); got != "bzip2 -dc" {
+		t.Fatalf("bzip2 decompress command = %s", got)
+	}
+	if got := TestCommand(models.CompressionNone, "/tmp/x"); got != "" {
+		t.Fatalf("plain files have no test command, got %s", got)
+	}
+	if got := TrimExt("/b/shop_01.sql.zst"); got != "/b/shop_01.sql" {
+		t.Fatalf("TrimExt = %s", got)
+	}
+}
@@ -0,0 +1,151 @@
+package compat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	for in, want := range map[string]string{
+		"mysql  Ver 8.0.36-0ubuntu0.22.04.1 for Linux on x86_64 ((Ubuntu))":      "MySQL 8.0",
+		"mysqldump  Ver 8.4.0 for Linux on x86_64 (MySQL Community Server)":      "MySQL 8.4",
+		"mysql  Ver 14.14 Distrib 5.7.44, for Linux (x86_64)":                    "MySQL 5.7",
+		"mysql  Ver 15.1 Distrib 10.6.16-MariaDB, for debian-linux-gnu (x86_64)": "MariaDB 10.6",
+		"mariadb from 11.4.2-MariaDB, client 15.2 for debian-linux-gnu (x86_64)": "MariaDB 11.4",
+		"10.11.6-MariaDB-0ubuntu0.24.04.1-log":                                   "MariaDB 10.11",
+		"5.5.5-10.6.16-MariaDB-cll-lve":                                          "MariaDB 10.6",
+		"8.0.36":                                                                 "MySQL 8.0",
+		"\nMySQL dump 10.13  Distrib 8.0.36, for Linux (x86_64)":                 "MySQL 8.0",
+	} {
+		got, ok := ParseVersion(in)
+		if !ok || got.String() != want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %s", in, got, ok, want)
+		}
+	}
+	for _, in := range []string{"", "psql (PostgreSQL) 16.2", "no-dump-tool"} {
+		if _, ok := ParseVersion(in); ok {
+			t.Errorf("ParseVersion(%q) should not find a version", in)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	if c, ok := Select("mysqldump  Ver 8.0.36 for Linux", "10.11.6-MariaDB"); !ok || c.String() != "MySQL 8.0 → MariaDB 10.11" {
+		t.Fatalf("got %v, %v", c, ok)
+	}
+	if _, ok := Select("10.6.16-MariaDB", "mysql  Ver 8.0.36 for Linux"); !ok {
+		t.Fatal("MariaDB to MySQL needs a conversion")
+	}
+	for _, pair := range [][2]string{
+		{"8.0.36", "8.4.0"},
+		{"10.6.16-MariaDB", "11.4.2-MariaDB"},
+		{"5.7.44", "10.6.16-MariaDB"},
+		{"", "10.6.16-MariaDB"},
+		{"8.0.36", "psql (PostgreSQL) 16.2"},
+	} {
+		if c, ok := Select(pair[0], pair[1]); ok {
+			t.Errorf("Select(%q, %q) = %v; want no conversion", pair[0], pair[1], c)
+		}
+	}
+}
+
+func TestSniffVersion(t *testing.T) {
+	mysql := "-- MySQL dump 10.13  Distrib 8.0.36, for Linux (x86_64)\n--\n-- Host: localhost    Database: shop\n-- ------------------------------------------------------\n-- Server version\t8.0.36-0ubuntu0.22.04.1\n"
+	if got := SniffVersion(strings.NewReader(mysql)); got != "8.0.36-0ubuntu0.22.04.1" {
+		t.Fatalf("got %q", got)
+	}
+	maria := "/*M!999999\\- enable the sandbox mode */ \n-- MariaDB dump 10.19-11.4.2-MariaDB, for debian-linux-gnu (x86_64)\n"
+	if s, ok := ParseVersion(SniffVersion(strings.NewReader(maria))); !ok || s.Flavor != MariaDB {
+		t.Fatalf("got %v", s)
+	}
+	if got := SniffVersion(strings.NewReader("SET NAMES utf8mb4;\n")); got != "" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func apply(t *testing.T, dump string, c Conversion) (string, Report) {
+	t.Helper()
+	var out bytes.Buffer
+	report, err := Apply(strings.NewReader(dump), &out, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), report
+}
+
+func TestApplyMySQLToMariaDB(t *testing.T) {
+	dump := "SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ '3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5,\n" +
+		"3e11fa47-71ca-11e1-9e33-c80aa9429563:1-3';\n" +
+		"CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_0900_ai_ci */ /*!80016 DEFAULT ENCRYPTION='N' */;\n" +
+		"CREATE TABLE `places` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `code` varchar(8) CHARACTER SET utf8mb3 COLLATE utf8mb3_general_ci DEFAULT NULL,\n" +
+		"  `note` varchar(20) COLLATE utf8mb4_0900_bin DEFAULT NULL /*!80023 INVISIBLE */,\n" +
+		"  `pos` point NOT NULL /*!80003 SRID 4326 */\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_de_pb_0900_as_cs;\n" +
+		"INSERT INTO `places` VALUES (1,'a','utf8mb4_0900_ai_ci /*!80023 INVISIBLE */',0x00);\n"
+	c, _ := Select("8.0.36", "10.5.22-MariaDB")
+	out, report := apply(t, dump, c)
+	want := "CREATE DATABASE /*!32312 IF NOT EXISTS*/ `shop` /*!40100 DEFAULT CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_520_ci */;\n" +
+		"CREATE TABLE `places` (\n" +
+		"  `id` int NOT NULL,\n" +
+		"  `code` varchar(8) CHARACTER SET utf8 COLLATE utf8_general_ci DEFAULT NULL,\n" +
+		"  `note` varchar(20) COLLATE utf8mb4_bin DEFAULT NULL,\n" +
+		"  `pos` point NOT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci;\n" +
+		"INSERT INTO `places` VALUES (1,'a','utf8mb4_0900_ai_ci /*!80023 INVISIBLE */',0x00);\n"
+	if out != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+	wantSummary := "Compatibility MySQL 8.0 → MariaDB 10.5: GTID_PURGED statements removed (1), utf8mb4_0900 collations (3), invisible columns made visible (1), utf8mb3 charsets (2), MySQL 8.0 clauses removed (2)"
+	if report.Summary() != wantSummary {
+		t.Fatalf("summary = %s", report.Summary())
+	}
+
+	// MariaDB 10.6 reads utf8mb3 itself.
+	c, _ = Select("8.0.36", "10.6.16-MariaDB")
+	if out, _ := apply(t, dump, c); !strings.Contains(out, "utf8mb3_general_ci") {
+		t.Fatalf("utf8mb3 should be kept for MariaDB 10.6:\n%s", out)
+	}
+}
+
+func TestApplyMariaDBToMySQL(t *testing.T) {
+	dump := "/*M!999999\\- enable the sandbox mode */ \n" +
+		"-- MariaDB dump 10.19-11.4.2-MariaDB, for debian-linux-gnu (x86_64)\n" +
+		"CREATE TABLE `hosts` (\n" +
+		"  `id` uuid NOT NULL DEFAULT uuid(),\n" +
+		"  `uuid` varchar(10) DEFAULT NULL,\n" +
+		"  `addr` inet6 DEFAULT NULL,\n" +
+		"  `addr4` inet4 DEFAULT NULL\n" +
+		") ENGINE=Aria DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_uca1400_ai_ci PAGE_CHECKSUM=1 TRANSACTIONAL=1;\n" +
+		"INSERT INTO `hosts` VALUES ('a','ENGINE=Aria','::1','127.0.0.1');\n"
+	c, ok := Select("11.4.2-MariaDB", "8.0.36")
+	if !ok {
+		t.Fatal("expected a conversion")
+	}
+	out, report := apply(t, dump, c)
+	want := "-- MariaDB dump 10.19-11.4.2-MariaDB, for debian-linux-gnu (x86_64)\n" +
+		"CREATE TABLE `hosts` (\n" +
+		"  `id` char(36) NOT NULL DEFAULT uuid(),\n" +
+		"  `uuid` varchar(10) DEFAULT NULL,\n" +
+		"  `addr` varchar(39) DEFAULT NULL,\n" +
+		"  `addr4` varchar(15) DEFAULT NULL\n" +
+		") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_520_ci;\n" +
+		"INSERT INTO `hosts` VALUES ('a','ENGINE=Aria','::1','127.0.0.1');\n"
+	if out != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+	if len(report.Rewrites) != 7 {
+		t.Fatalf("rewrites = %+v", report.Rewrites)
+	}
+}
+
+func TestApplyNothingToRewrite(t *testing.T) {
+	dump := "CREATE TABLE `t` (`id` int) ENGINE=InnoDB;\n"
+	c, _ := Select("8.0.36", "10.11.6-MariaDB")
+	out, report := apply(t, dump, c)
+	if out != dump || report.Summary() != "Compatibility MySQL 8.0 → MariaDB 10.11: nothing to rewrite" {
+		t.Fatalf("out = %q, summary = %s", out, report.Summary())
+	}
+}
@@ -0,0 +1,242 @@
+package compat
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Conversion is a restore of a dump taken on From into a server running To.
+type Conversion struct {
+	From Server
+	To   Server
+}
+
+func (c Conversion) String() string {
+	return c.From.String() + " → " + c.To.String()
+}
+
+// Select returns the conversion a dump from the source server needs before it restores on
+// the destination, both given as text ParseVersion reads. ok is false when nothing needs
+// rewriting: same flavor on both sides, a MySQL source older than 8.0, or either version
+// unknown.
+func Select(source, dest string) (Conversion, bool) {
+	from, ok := ParseVersion(source)
+	if !ok {
+		return Conversion{}, false
+	}
+	to, ok := ParseVersion(dest)
+	if !ok || from.Flavor == to.Flavor {
+		return Conversion{}, false
+	}
+	if from.Flavor == MySQL && !from.AtLeast(8, 0) {
+		return Conversion{}, false
+	}
+	return Conversion{From: from, To: to}, true
+}
+
+// rule rewrites one kind of incompatible construct. A rule with drop removes the matching
+// line; with statement as well, the rest of the statement it starts goes with it.
+type rule struct {
+	kind      string
+	pattern   *regexp.Regexp
+	replace   func(match string) string
+	drop      bool
+	statement bool
+}
+
+func literal(s string) func(string) string {
+	return func(string) string { return s }
+}
+
+var (
+	mysqlInvisible  = regexp.MustCompile(`\s*/\*!80023 INVISIBLE \*/`)
+	mysql0900       = regexp.MustCompile(`\butf8mb4_(?:[a-z]+_)*0900_(?:bin|a[is]_c[is])\b`)
+	mysqlUTF8MB3    = regexp.MustCompile(`\butf8mb3`)
+	mysqlGTIDPurged = regexp.MustCompile(`^SET @@GLOBAL\.GTID_PURGED\s*=`)
+	// mysql80Comment is a versioned comment only MySQL 8.0 and newer run. MariaDB runs it
+	// too, as its version numbers are higher, and fails on what it holds (DEFAULT
+	// ENCRYPTION, SRID, ...).
+	mysql80Comment = regexp.MustCompile(` ?/\*!8\d{4}\b.*?\*/`)
+
+	mariaSandbox   = regexp.MustCompile(`^/\*M?!999999\\- enable the sandbox mode \*/`)
+	mariaUCA1400   = regexp.MustCompile(`\b(?:utf8mb4|utf8mb3|utf8)_(?:[a-z]+_)*uca1400(?:_[a-z]+)*\b`)
+	mariaAria      = regexp.MustCompile(`\bENGINE=Aria\b`)
+	mariaAriaOpts  = regexp.MustCompile(` (?:PAGE_CHECKSUM|TRANSACTIONAL)=\d`)
+	mariaTypeUUID  = regexp.MustCompile("(`[^`]*` )uuid\\b")
+	mariaTypeInet6 = regexp.MustCompile("(`[^`]*` )inet6\\b")
+	mariaTypeInet4 = regexp.MustCompile("(`[^`]*` )inet4\\b")
+)
+
+// rules returns the rewrites of c, in the order they are tried on each line.
+func (c Conversion) rules() []rule {
+	if c.From.Flavor == MariaDB {
+		return []rule{
+			{kind: "sandbox mode lines removed", pattern: mariaSandbox, drop: true},
+			{kind: "uca1400 collations", pattern: mariaUCA1400, replace: func(m string) string {
+				if strings.HasPrefix(m, "utf8mb4_") {
+					return "utf8mb4_unicode_520_ci"
+				}
+				return "utf8_unicode_520_ci"
+			}},
+			{kind: "Aria tables moved to InnoDB", pattern: mariaAria, replace: literal("ENGINE=InnoDB")},
+			{kind: "Aria table options removed", pattern: mariaAriaOpts, replace: literal("")},
+			{kind: "uuid columns stored as char(36)", pattern: mariaTypeUUID, replace: columnType(mariaTypeUUID, "char(36)")},
+			{kind: "inet6 columns stored as varchar(39)", pattern: mariaTypeInet6, replace: columnType(mariaTypeInet6, "varchar(39)")},
+			{kind: "inet4 columns stored as varchar(15)", pattern: mariaTypeInet4, replace: columnType(mariaTypeInet4, "varchar(15)")},
+		}
+	}
+	rules := []rule{
+		{kind: "GTID_PURGED statements removed", pattern: mysqlGTIDPurged, drop: true, statement: true},
+		{kind: "utf8mb4_0900 collations", pattern: mysql0900, replace: func(m string) string {
+			if strings.HasSuffix(m, "_bin") {
+				return "utf8mb4_bin"
+			}
+			return "utf8mb4_unicode_520_ci"
+		}},
+		{kind: "invisible columns made visible", pattern: mysqlInvisible, replace: literal("")},
+	}
+	// MariaDB reads utf8mb3 from 10.6 on; older servers only know it as utf8.
+	if !c.To.AtLeast(10, 6) {
+		rules = append(rules, rule{kind: "utf8mb3 charsets", pattern: mysqlUTF8MB3, replace: literal("utf8")})
+	}
+	return append(rules, rule{kind: "MySQL 8.0 clauses removed", pattern: mysql80Comment, replace: literal("")})
+}
+
+// columnType replaces the type of a column definition matched by re, keeping its name.
+func columnType(re *regexp.Regexp, typ string) func(string) string {
+	return func(m string) string {
+		return re.ReplaceAllString(m, "${1}"+typ)
+	}
+}
+
+// Count is how many times one kind of rewrite was made.
+type Count struct {
+	Kind  string
+	Count int64
+}
+
+// Report describes what a conversion rewrote.
+type Report struct {
+	Conversion Conversion
+	// Rewrites lists the kinds of rewrite made at least once, in rule order.
+	Rewrites []Count
+}
+
+// Summary is a one-line description of the report for the activity log.
+func (r Report) Summary() string {
+	if len(r.Rewrites) == 0 {
+		return "Compatibility " + r.Conversion.String() + ": nothing to rewrite"
+	}
+	parts := make([]string, 0, len(r.Rewrites))
+	for _, c := range r.Rewrites {
+		parts = append(parts, fmt.Sprintf("%s (%d)", c.Kind, c.Count))
+	}
+	return "Compatibility " + r.Conversion.String() + ": " + strings.Join(parts, ", ")
+}
+
+// maxLine is how much of a line is matched at once; longer lines are row inserts, which
+// are copied in chunks.
+const maxLine = 256 << 10
+
+var (
+	prefixInsert  = []byte("INSERT ")
+	prefixReplace = []byte("REPLACE ")
+)
+
+// Apply copies a plain-text dump from r to w with the rewrites of c. INSERT and REPLACE
+// statements are copied untouched, so row data that happens to mention a collation or
+// an engine is never changed.
+func Apply(r io.Reader, w io.Writer, c Conversion) (Report, error) {
+	rules := c.rules()
+	counts := make([]int64, len(rules))
+	out := bufio.NewWriterSize(w, 64<<10)
+	br := bufio.NewReaderSize(r, maxLine)
+	atLineStart, copying, dropping := true, false, false
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			startsLine := atLineStart
+			atLineStart = line[len(line)-1] == '\n'
+			switch {
+			case dropping:
+				// The rest of a dropped statement, up to its closing semicolon.
+				dropping = !endsStatement(line)
+			case !startsLine && copying:
+				if _, werr := out.Write(line); werr != nil {
+					return Report{}, werr
+				}
+			default:
+				t := bytes.TrimLeft(line, " \t")
+				copying = !startsLine || bytes.HasPrefix(t, prefixInsert) || bytes.HasPrefix(t, prefixReplace)
+				if copying {
+					if _, werr := out.Write(line); werr != nil {
+						return Report{}, werr
+					}
+					break
+				}
+				text, drop, statement := rewriteLine(string(line), rules, counts)
+				if drop {
+					dropping = statement && !endsStatement(line)
+					break
+				}
+				if _, werr := out.WriteString(text); werr != nil {
+					return Report{}, werr
+				}
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Report{}, err
+		}
+	}
+	if err := out.Flush(); err != nil {
+		return Report{}, err
+	}
+	report := Report{Conversion: c}
+	for i, n := range counts {
+		if n > 0 {
+			report.Rewrites = append(report.Rewrites, Count{Kind: rules[i].kind, Count: n})
+		}
+	}
+	return report, nil
+}
+
+// rewriteLine applies rules to one line, counting each match. drop is set when a rule
+// removes the line, or when the rewrites left nothing but a semicolon, which the server
+// would reject as an empty query; statement is set when the rest of the statement goes too.
+func rewriteLine(line string, rules []rule, counts []int64) (text string, drop, statement bool) {
+	changed := false
+	for i, r := range rules {
+		if r.drop {
+			if r.pattern.MatchString(line) {
+				counts[i]++
+				return "", true, r.statement
+			}
+			continue
+		}
+		n := 0
+		line = r.pattern.ReplaceAllStringFunc(line, func(m string) string {
+			n++
+			return r.replace(m)
+		})
+		if n > 0 {
+			counts[i] += int64(n)
+			changed = true
+		}
+	}
+	return line, changed && strings.TrimSpace(line) == ";", false
+}
+
+func endsStatement(line []byte) bool {
+	return bytes.HasSuffix(bytes.TrimRight(line, " \t\r\n"), []byte(";"))
+}
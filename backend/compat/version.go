@@ -0,0 +1,101 @@
+// Package compat rewrites a plain-text dump taken on one MySQL-family server so it restores
+// on another: MySQL 8.0 dumps into MariaDB, and MariaDB dumps into MySQL. Only the schema
+// and session statements are rewritten; row data is copied as it is.
+package compat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Flavor is the family of a MySQL-compatible server.
+type Flavor string
+
+const (
+	MySQL   Flavor = "MySQL"
+	MariaDB Flavor = "MariaDB"
+)
+
+// Server is a server flavor and its major and minor version.
+type Server struct {
+	Flavor Flavor
+	Major  int
+	Minor  int
+}
+
+func (s Server) String() string {
+	return fmt.Sprintf("%s %d.%d", s.Flavor, s.Major, s.Minor)
+}
+
+// AtLeast reports whether s is version major.minor or newer.
+func (s Server) AtLeast(major, minor int) bool {
+	return s.Major > major || s.Major == major && s.Minor >= minor
+}
+
+var (
+	mariaVersion   = regexp.MustCompile(`(\d+)\.(\d+)\.\d+-MariaDB`)
+	distribVersion = regexp.MustCompile(`Distrib (\d+)\.(\d+)`)
+	verVersion     = regexp.MustCompile(`Ver (\d+)\.(\d+)`)
+	bareVersion    = regexp.MustCompile(`^(\d+)\.(\d+)\.\d+`)
+)
+
+// ParseVersion reads a server version from the output of mysql --version, mysqldump
+// --version, SELECT VERSION() or a dump header, one or more lines of it. ok is false when
+// no line names a MySQL or MariaDB version (PostgreSQL tools, an empty string).
+func ParseVersion(text string) (Server, bool) {
+	for _, line := range strings.Split(text, "\n") {
+		if s, ok := parseVersionLine(strings.TrimSpace(line)); ok {
+			return s, true
+		}
+	}
+	return Server{}, false
+}
+
+func parseVersionLine(line string) (Server, bool) {
+	if line == "" || strings.Contains(line, "PostgreSQL") {
+		return Server{}, false
+	}
+	if m := mariaVersion.FindStringSubmatch(line); m != nil {
+		return server(MariaDB, m[1], m[2]), true
+	}
+	// MySQL 5.7 clients print "Ver 14.14 Distrib 5.7.44"; MySQL 8 ones print "Ver 8.0.36".
+	for _, re := range []*regexp.Regexp{distribVersion, verVersion, bareVersion} {
+		if m := re.FindStringSubmatch(line); m != nil {
+			return server(MySQL, m[1], m[2]), true
+		}
+	}
+	return Server{}, false
+}
+
+func server(flavor Flavor, major, minor string) Server {
+	s := Server{Flavor: flavor}
+	s.Major, _ = strconv.Atoi(major)
+	s.Minor, _ = strconv.Atoi(minor)
+	return s
+}
+
+// headerLines is how far into a dump SniffVersion looks for the header comments.
+const headerLines = 20
+
+// SniffVersion returns the server version a dump's header names: mysqldump and
+// mariadb-dump write "-- Server version\t8.0.36" below their own "-- MySQL dump ...
+// Distrib" line. It returns "" when the header has neither, as in plugin dumps.
+func SniffVersion(r io.Reader) string {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 4096), 64<<10)
+	var distrib string
+	for n := 0; n < headerLines && sc.Scan(); n++ {
+		line := sc.Text()
+		if v, ok := strings.CutPrefix(line, "-- Server version"); ok {
+			return strings.TrimSpace(v)
+		}
+		if distrib == "" && (strings.HasPrefix(line, "-- MySQL dump") || strings.HasPrefix(line, "-- MariaDB dump")) {
+			distrib = strings.TrimPrefix(line, "-- ")
+		}
+	}
+	return distrib
+}
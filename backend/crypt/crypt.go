@@ -0,0 +1,570 @@
+// Package crypt encrypts backup files at rest in a streaming, authenticated format.
+//
+// A file starts with a header that wraps a random file key once for each key it was
+// written for: the vault's backup key and, optionally, a recovery passphrase that can
+// decrypt the file without the vault. The payload follows in 64 KiB chunks sealed with
+// AES-256-GCM under a key derived from the file key. Each chunk's nonce carries its index
+// and a flag marking the last chunk, so reordered, dropped or truncated chunks fail to
+// open.
+//
+//	magic "DBACKENC" | version | stanza count | stanzas | header MAC | chunks...
+//	stanza: kind | salt (16) | wrapped file key (48)
+package crypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"dback/backend/codec"
+	"dback/models"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Ext is added after the codec extension of an encrypted backup, e.g. ".sql.gz.enc".
+const Ext = ".enc"
+
+// ChunkSize is the plaintext size of every chunk but the last.
+const ChunkSize = 64 * 1024
+
+const (
+	magic       = "DBACKENC"
+	version     = 1
+	keySize     = 32
+	saltSize    = 16
+	tagSize     = 16
+	macSize     = sha256.Size
+	wrappedSize = keySize + tagSize
+	stanzaSize  = 1 + saltSize + wrappedSize
+	sealedChunk = ChunkSize + tagSize
+
+	kindVault      byte = 'v'
+	kindPassphrase byte = 'p'
+)
+
+var (
+	// ErrNoKey means the file was encrypted for keys that were not supplied.
+	ErrNoKey = errors.New("no key for this encrypted backup")
+	// ErrWrongKey means a supplied key does not open the file.
+	ErrWrongKey = errors.New("wrong key for this encrypted backup")
+	// ErrCorrupt means the file was changed or cut short after it was written.
+	ErrCorrupt = errors.New("encrypted backup is corrupted or incomplete")
+)
+
+// Keys are the secrets a backup is encrypted for. Writing wraps the file key for each
+// one that is set; reading needs any one of them.
+type Keys struct {
+	// Vault is the vault's 32-byte backup key.
+	Vault []byte
+	// Passphrase is a host's recovery passphrase.
+	Passphrase string
+}
+
+// Empty reports whether no key is set.
+func (k Keys) Empty() bool {
+	return len(k.Vault) == 0 && k.Passphrase == ""
+}
+
+// NewKey returns a random 32-byte key for the vault.
+func NewKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether a file starts with the encrypted backup header.
+func IsEncrypted(head []byte) bool {
+	return bytes.HasPrefix(head, []byte(magic))
+}
+
+// IsEncryptedFile reads the first bytes of path to tell whether it is encrypted.
+func IsEncryptedFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	head := make([]byte, len(magic))
+	n, err := io.ReadFull(f, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false, err
+	}
+	return IsEncrypted(head[:n]), nil
+}
+
+// TrimExt removes the encryption extension from path.
+func TrimExt(path string) string {
+	return strings.TrimSuffix(path, Ext)
+}
+
+// Writer encrypts everything written to it. Close seals the last chunk; it does not close
+// the underlying writer.
+type Writer struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	buf     []byte
+	counter uint64
+	closed  bool
+}
+
+// NewWriter writes a header for keys to w and returns a Writer for the payload.
+func NewWriter(w io.Writer, keys Keys) (*Writer, error) {
+	if keys.Empty() {
+		return nil, ErrNoKey
+	}
+	fileKey, err := NewKey()
+	if err != nil {
+		return nil, err
+	}
+	header, err := buildHeader(fileKey, keys)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return newWriter(w, fileKey, 0)
+}
+
+func newWriter(w io.Writer, fileKey []byte, counter uint64) (*Writer, error) {
+	aead, err := payloadAEAD(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, aead: aead, buf: make([]byte, 0, ChunkSize), counter: counter}, nil
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed encrypted backup")
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data arrives, so Close always has a
+		// last chunk to mark.
+		if len(w.buf) == ChunkSize {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):ChunkSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals the buffered data as the last chunk.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.flush(true)
+}
+
+func (w *Writer) flush(last bool) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.counter, last), w.buf, nil)
+	if _, err := w.w.Write(sealed); err != nil {
+		return err
+	}
+	w.counter++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// Resume reopens a file an interrupted Writer left behind so writing can continue. The
+// partial chunk at the end is cut off; offset is how much plaintext the kept chunks hold,
+// which is where the caller resumes its source. f must be open for reading and writing.
+func Resume(f *os.File, keys Keys) (w *Writer, offset int64, err error) {
+	br := bufio.NewReader(f)
+	fileKey, headerLen, err := readHeader(br, keys)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	chunks := (info.Size() - int64(headerLen)) / sealedChunk
+	aead, err := payloadAEAD(fileKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	// The last whole chunk may be the file's final chunk when the download finished
+	// exactly on a chunk boundary; drop it so it is written again as a middle chunk.
+	if chunks > 0 {
+		sealed := make([]byte, sealedChunk)
+		if _, err := f.ReadAt(sealed, int64(headerLen)+(chunks-1)*sealedChunk); err != nil {
+			return nil, 0, err
+		}
+		if _, err := aead.Open(nil, chunkNonce(uint64(chunks-1), false), sealed, nil); err != nil {
+			chunks--
+		}
+	}
+	end := int64(headerLen) + chunks*sealedChunk
+	if err := f.Truncate(end); err != nil {
+		return nil, 0, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return nil, 0, err
+	}
+	w, err = newWriter(f, fileKey, uint64(chunks))
+	if err != nil {
+		return nil, 0, err
+	}
+	return w, chunks * ChunkSize, nil
+}
+
+// reader decrypts a payload chunk by chunk.
+type reader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	sealed  []byte
+	plain   []byte
+	counter uint64
+	done    bool
+	err     error
+}
+
+// NewReader reads the header from r, opens the file key with keys and returns a Reader
+// for the plaintext. Reads fail with ErrCorrupt when a chunk does not authenticate or the
+// file ends before its last chunk.
+func NewReader(r io.Reader, keys Keys) (io.Reader, error) {
+	br := bufio.NewReaderSize(r, sealedChunk+1)
+	fileKey, _, err := readHeader(br, keys)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := payloadAEAD(fileKey)
+	if err != nil {
+		return nil, err
+	}
+	return &reader{r: br, aead: aead, sealed: make([]byte, sealedChunk)}, nil
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *reader) next() error {
+	n, err := io.ReadFull(r.r, r.sealed)
+	switch {
+	case errors.Is(err, io.EOF):
+		return fmt.Errorf("%w: file ends before its last chunk", ErrCorrupt)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		r.done = true
+	case err != nil:
+		return err
+	default:
+		if _, peekErr := r.r.Peek(1); errors.Is(peekErr, io.EOF) {
+			r.done = true
+		}
+	}
+	plain, err := r.aead.Open(r.sealed[:0], chunkNonce(r.counter, r.done), r.sealed[:n], nil)
+	if err != nil {
+		if r.done {
+			return fmt.Errorf("%w: chunk %d does not authenticate or the file was cut short", ErrCorrupt, r.counter)
+		}
+		return fmt.Errorf("%w: chunk %d does not authenticate", ErrCorrupt, r.counter)
+	}
+	r.plain = plain
+	r.counter++
+	return nil
+}
+
+// Open opens an encrypted backup file for reading its plaintext.
+func Open(path string, keys Keys) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f, keys)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileReader{Reader: r, file: f}, nil
+}
+
+type fileReader struct {
+	io.Reader
+	file *os.File
+}
+
+func (r *fileReader) Close() error {
+	return r.file.Close()
+}
+
+// OpenDump opens an encrypted backup and decompresses the dump inside it.
+func OpenDump(path string, keys Keys) (io.ReadCloser, models.Compression, error) {
+	in, err := Open(path, keys)
+	if err != nil {
+		return nil, "", err
+	}
+	r, c, err := codec.Decompress(in)
+	if err != nil {
+		in.Close()
+		return nil, c, err
+	}
+	return &dumpReader{ReadCloser: r, in: in}, c, nil
+}
+
+type dumpReader struct {
+	io.ReadCloser
+	in io.Closer
+}
+
+func (r *dumpReader) Close() error {
+	err := r.ReadCloser.Close()
+	if cerr := r.in.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Check reads a whole encrypted backup to make sure every chunk authenticates and the dump
+// inside decompresses, and returns the dump's codec. Without a local xz tool an xz dump is
+// only decrypted.
+func Check(path string, keys Keys) (models.Compression, error) {
+	r, c, err := OpenDump(path, keys)
+	if errors.Is(err, codec.ErrToolMissing) {
+		in, openErr := Open(path, keys)
+		if openErr != nil {
+			return c, openErr
+		}
+		defer in.Close()
+		_, err = io.Copy(io.Discard, in)
+		return c, err
+	}
+	if err != nil {
+		return c, err
+	}
+	defer r.Close()
+	_, err = io.Copy(io.Discard, r)
+	return c, err
+}
+
+// CheckKey reports whether keys open path's header, without reading the payload.
+func CheckKey(path string, keys Keys) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, _, err = readHeader(bufio.NewReader(f), keys)
+	return err
+}
+
+// DecryptFile writes the plaintext of src to dst, removing dst when decryption fails.
+func DecryptFile(src, dst string, keys Keys) error {
+	in, err := Open(src, keys)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(dst)
+	}
+	return err
+}
+
+func buildHeader(fileKey []byte, keys Keys) ([]byte, error) {
+	var stanzas [][]byte
+	if len(keys.Vault) > 0 {
+		s, err := wrapStanza(kindVault, fileKey, keys)
+		if err != nil {
+			return nil, err
+		}
+		stanzas = append(stanzas, s)
+	}
+	if keys.Passphrase != "" {
+		s, err := wrapStanza(kindPassphrase, fileKey, keys)
+		if err != nil {
+			return nil, err
+		}
+		stanzas = append(stanzas, s)
+	}
+	header := append([]byte(magic), version, byte(len(stanzas)))
+	for _, s := range stanzas {
+		header = append(header, s...)
+	}
+	return append(header, headerMAC(fileKey, header)...), nil
+}
+
+func wrapStanza(kind byte, fileKey []byte, keys Keys) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := wrapAEAD(kind, salt, keys)
+	if err != nil {
+		return nil, err
+	}
+	stanza := append([]byte{kind}, salt...)
+	// Every wrapping key comes from a fresh salt, so the zero nonce is never reused.
+	return aead.Seal(stanza, make([]byte, aead.NonceSize()), fileKey, nil), nil
+}
+
+// readHeader parses the header, unwraps the file key with whichever key fits and checks
+// the header MAC. It returns the file key and the header's length.
+func readHeader(r *bufio.Reader, keys Keys) ([]byte, int, error) {
+	fixed := make([]byte, len(magic)+2)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, 0, fmt.Errorf("%w: header is incomplete", ErrCorrupt)
+	}
+	if !IsEncrypted(fixed) {
+		return nil, 0, errors.New("not an encrypted backup")
+	}
+	if fixed[len(magic)] != version {
+		return nil, 0, fmt.Errorf("unsupported encrypted backup version %d", fixed[len(magic)])
+	}
+	count := int(fixed[len(magic)+1])
+	body := make([]byte, count*stanzaSize+macSize)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, 0, fmt.Errorf("%w: header is incomplete", ErrCorrupt)
+	}
+	header := append(fixed, body[:count*stanzaSize]...)
+	mac := body[count*stanzaSize:]
+
+	var kinds []byte
+	tried := false
+	for i := 0; i < count; i++ {
+		s := header[len(fixed)+i*stanzaSize:][:stanzaSize]
+		kind, salt, wrapped := s[0], s[1:1+saltSize], s[1+saltSize:]
+		kinds = append(kinds, kind)
+		if !hasKey(kind, keys) {
+			continue
+		}
+		tried = true
+		aead, err := wrapAEAD(kind, salt, keys)
+		if err != nil {
+			return nil, 0, err
+		}
+		fileKey, err := aead.Open(nil, make([]byte, aead.NonceSize()), wrapped, nil)
+		if err != nil {
+			continue
+		}
+		if !hmac.Equal(mac, headerMAC(fileKey, header)) {
+			return nil, 0, fmt.Errorf("%w: header was modified", ErrCorrupt)
+		}
+		return fileKey, len(header) + macSize, nil
+	}
+	if tried {
+		return nil, 0, ErrWrongKey
+	}
+	return nil, 0, fmt.Errorf("%w: it needs %s", ErrNoKey, describeKinds(kinds))
+}
+
+func hasKey(kind byte, keys Keys) bool {
+	switch kind {
+	case kindVault:
+		return len(keys.Vault) > 0
+	case kindPassphrase:
+		return keys.Passphrase != ""
+	}
+	return false
+}
+
+func describeKinds(kinds []byte) string {
+	var names []string
+	for _, k := range kinds {
+		switch k {
+		case kindVault:
+			names = append(names, "the vault backup key")
+		case kindPassphrase:
+			names = append(names, "the recovery passphrase")
+		}
+	}
+	if len(names) == 0 {
+		return "a key this version does not know"
+	}
+	return strings.Join(names, " or ")
+}
+
+func wrapAEAD(kind byte, salt []byte, keys Keys) (cipher.AEAD, error) {
+	var key []byte
+	switch kind {
+	case kindVault:
+		if len(keys.Vault) != keySize {
+			return nil, fmt.Errorf("vault backup key must be %d bytes", keySize)
+		}
+		key = derive(keys.Vault, salt, "dback backup vault key")
+	case kindPassphrase:
+		// Same Argon2id parameters as the vault's master key.
+		key = argon2.IDKey([]byte(keys.Passphrase), salt, 3, 64*1024, 4, keySize)
+	default:
+		return nil, fmt.Errorf("unknown key kind %q", kind)
+	}
+	return newGCM(key)
+}
+
+func payloadAEAD(fileKey []byte) (cipher.AEAD, error) {
+	return newGCM(derive(fileKey, nil, "dback backup payload"))
+}
+
+func headerMAC(fileKey, header []byte) []byte {
+	h := hmac.New(sha256.New, derive(fileKey, nil, "dback backup header"))
+	h.Write(header)
+	return h.Sum(nil)
+}
+
+func derive(secret, salt []byte, info string) []byte {
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, []byte(info)), key); err != nil {
+		panic(err) // hkdf only fails past 255 blocks of output
+	}
+	return key
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// chunkNonce is the chunk index as 11 big-endian bytes followed by the last-chunk flag.
+func chunkNonce(counter uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce[3:11], counter)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,224 @@
+package crypt
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dback/models"
+)
+
+func testKeys(t *testing.T) Keys {
+	t.Helper()
+	key, err := NewKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Keys{Vault: key}
+}
+
+func encrypt(t *testing.T, plain []byte, keys Keys) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func decrypt(data []byte, keys Keys) ([]byte, error) {
+	r, err := NewReader(bytes.NewReader(data), keys)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTripSizes(t *testing.T) {
+	keys := testKeys(t)
+	for _, size := range []int{0, 1, ChunkSize - 1, ChunkSize, ChunkSize + 1, 3 * ChunkSize} {
+		plain := bytes.Repeat([]byte("x"), size)
+		sealed := encrypt(t, plain, keys)
+		if !IsEncrypted(sealed) {
+			t.Fatalf("size %d: header magic missing", size)
+		}
+		got, err := decrypt(sealed, keys)
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, plain) {
+			t.Fatalf("size %d: plaintext differs", size)
+		}
+	}
+}
+
+func TestEitherKeyOpensFile(t *testing.T) {
+	keys := testKeys(t)
+	keys.Passphrase = "correct horse battery staple"
+	sealed := encrypt(t, []byte("INSERT INTO t VALUES (1);"), keys)
+
+	for name, k := range map[string]Keys{
+		"vault":      {Vault: keys.Vault},
+		"passphrase": {Passphrase: keys.Passphrase},
+	} {
+		if _, err := decrypt(sealed, k); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestWrongOrMissingKey(t *testing.T) {
+	keys := testKeys(t)
+	sealed := encrypt(t, []byte("data"), keys)
+
+	if _, err := decrypt(sealed, testKeys(t)); !errors.Is(err, ErrWrongKey) {
+		t.Errorf("other vault key: got %v, want ErrWrongKey", err)
+	}
+	if _, err := decrypt(sealed, Keys{Passphrase: "guess"}); !errors.Is(err, ErrNoKey) {
+		t.Errorf("passphrase for vault-only file: got %v, want ErrNoKey", err)
+	}
+}
+
+func TestTamperingIsDetected(t *testing.T) {
+	keys := testKeys(t)
+	sealed := encrypt(t, bytes.Repeat([]byte("row;"), ChunkSize), keys)
+	headerLen := len(magic) + 2 + stanzaSize + macSize
+
+	cases := map[string][]byte{
+		"flipped payload byte":    flip(sealed, headerLen+10),
+		"flipped header count":    flip(sealed, len(magic)+1),
+		"cut at chunk boundary":   sealed[:headerLen+3*sealedChunk],
+		"cut inside a chunk":      sealed[:len(sealed)-5],
+		"chunks swapped":          swap(sealed, headerLen),
+		"header only, no payload": sealed[:headerLen],
+	}
+	for name, data := range cases {
+		if _, err := decrypt(data, keys); err == nil {
+			t.Errorf("%s: decrypted without error", name)
+		}
+	}
+}
+
+func flip(data []byte, i int) []byte {
+	out := append([]byte(nil), data...)
+	out[i] ^= 0x01
+	return out
+}
+
+func swap(data []byte, headerLen int) []byte {
+	out := append([]byte(nil), data...)
+	first := out[headerLen : headerLen+sealedChunk]
+	second := out[headerLen+sealedChunk : headerLen+2*sealedChunk]
+	tmp := append([]byte(nil), first...)
+	copy(first, second)
+	copy(second, tmp)
+	return out
+}
+
+func TestResumeContinuesFile(t *testing.T) {
+	keys := testKeys(t)
+	plain := bytes.Repeat([]byte("0123456789"), ChunkSize/2) // 5 chunks
+	path := filepath.Join(t.TempDir(), "dump.sql.gz.enc")
+
+	// Write two and a half chunks, then stop without Close as an interrupted download would.
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWriter(f, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plain[:ChunkSize*5/2]); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	f, err = os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, offset, err := Resume(f, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 2*ChunkSize {
+		t.Fatalf("offset = %d, want %d", offset, 2*ChunkSize)
+	}
+	if _, err := w.Write(plain[offset:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decrypt(data, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatal("resumed file does not decrypt to the original plaintext")
+	}
+}
+
+func TestResumeDropsFinishedLastChunk(t *testing.T) {
+	keys := testKeys(t)
+	plain := bytes.Repeat([]byte("a"), 2*ChunkSize)
+	path := filepath.Join(t.TempDir(), "dump.sql.gz.enc")
+	if err := os.WriteFile(path, encrypt(t, plain, keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, offset, err := Resume(f, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != ChunkSize {
+		t.Fatalf("offset = %d, want %d: the sealed last chunk must be written again", offset, ChunkSize)
+	}
+}
+
+func TestCheckDecompressesDump(t *testing.T) {
+	keys := testKeys(t)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write([]byte("CREATE TABLE t (id int);\n"))
+	zw.Close()
+
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.sql.gz.enc")
+	if err := os.WriteFile(good, encrypt(t, gz.Bytes(), keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if c, err := Check(good, keys); err != nil || c != models.CompressionGzip {
+		t.Fatalf("Check(good) = %q, %v", c, err)
+	}
+
+	bad := filepath.Join(dir, "bad.sql.gz.enc")
+	if err := os.WriteFile(bad, encrypt(t, gz.Bytes()[:gz.Len()-4], keys), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Check(bad, keys); err == nil {
+		t.Fatal("Check accepted a truncated gzip inside a valid encryption")
+	}
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestParseBinaryLogs(t *testing.T) {
+	out := "mysql: [Warning] Using a password on the command line interface can be insecure.\n" +
+		"Log_name\tFile_size\tEncrypted\n" +
+		"binlog.000001\t180\tNo\n" +
+		"binlog.000002\t4521\tNo\n"
+	want := []BinaryLog{{Name: "binlog.000001", Size: 180}, {Name: "binlog.000002", Size: 4521}}
+	if got := ParseBinaryLogs(out); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseBinaryLogs = %+v, want %+v", got, want)
+	}
+}
+
+func TestBuildBinlogExportCommand(t *testing.T) {
+	p := models.Profile{DBType: models.DBTypeMariaDB, DBUser: "root", DBPassword: "pw", DBHost: "db", DBPort: "3306", TargetDBName: "shop"}
+	cmd := BuildBinlogExportCommand(p, []string{"mysql-bin.000007", "mysql-bin.000008"}, 1200, 98)
+	for _, want := range []string{
+		"TZ=UTC",
+		"--read-from-remote-server",
+		"--database=",
+		"--start-position=1200",
+		"--stop-position=98",
+		"mysql-bin.000007",
+		"mysql-bin.000008",
+		"mariadb-binlog",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Fatalf("binlog command missing %q: %s", want, cmd)
+		}
+	}
+}
+
+func TestDumpRecordsBinlogPositionWhenIncrementalsEnabled(t *testing.T) {
+	p := models.Profile{DBType: models.DBTypeMySQL, TargetDBName: "shop"}
+	if strings.Contains(BuildExportCommand(p), "master-data") {
+		t.Fatal("plain dumps should not record a binlog position")
+	}
+	p.Binlog = &models.BinlogSettings{Enabled: true}
+	cmd := mysqlDumpPlanExec(p, TablePlan{SchemaOnly: []string{"log"}})
+	if !strings.HasPrefix(cmd, binlogDumpFlagSetup()) {
+		t.Fatalf("dump should pick the position flag first: %s", cmd)
+	}
+	if strings.Count(cmd, "$_bf") != 2 { // data pass, once per dump tool branch
+		t.Fatalf("only the data pass should record the position: %s", cmd)
+	}
+}
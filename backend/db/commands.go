@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 
+	"dback/backend/codec"
 	"dback/models"
 )
 
@@ -26,14 +27,22 @@ func mysqlOrMariaDB(p models.Profile) bool {
 	return p.DBType == models.DBTypeMySQL || p.DBType == models.DBTypeMariaDB
 }
 
+// supportedDB reports whether DBack can dump and restore the profile's server.
+func supportedDB(p models.Profile) bool {
+	return mysqlOrMariaDB(p) || postgres(p)
+}
+
 func ImportUsesStreaming(p models.Profile) bool {
-	return mysqlOrMariaDB(p)
+	return supportedDB(p)
 }
 
+// MaskCommand hides the database password in a command before it is logged. PostgreSQL
+// commands carry it in PGPASSWORD; their long options would trip the mysql -p masking.
 func MaskCommand(cmd string) string {
-	out := maskMySQLPasswordArgs(cmd)
-	out = strings.ReplaceAll(out, "PGPASSWORD=", "PGPASSWORD=***")
-	return out
+	if strings.Contains(cmd, "PGPASSWORD=") {
+		return maskPgPassword(cmd)
+	}
+	return maskMySQLPasswordArgs(cmd)
 }
 
 func maskMySQLPasswordArgs(cmd string) string {
@@ -78,17 +87,37 @@ func mysqlClientExec(p models.Profile, database, extraArgs string) string {
 }
 
 func mysqlDumpArgs(p models.Profile) string {
+	if p.Binlog.Active() {
+		return mysqlDumpFlags(p, "--routines", "--events", "$_bf")
+	}
+	return mysqlDumpFlags(p, "--routines", "--events")
+}
+
+// mysqlSchemaDumpArgs dumps table structure (and triggers) only; routines and events are
+// already in the data pass.
+func mysqlSchemaDumpArgs(p models.Profile) string {
+	return mysqlDumpFlags(p, "--no-data", "--skip-routines")
+}
+
+// mysqlRowsDumpArgs dumps a table's structure, triggers and the rows matching where;
+// routines and events are already in the data pass.
+func mysqlRowsDumpArgs(p models.Profile, where string) string {
+	return mysqlDumpFlags(p, "--skip-routines", "--where="+shellEscape(where))
+}
+
+func mysqlDumpFlags(p models.Profile, extra ...string) string {
 	flags := []string{
 		"--single-transaction",
 		"--quick",
 		"--lock-tables=false",
-		"--routines",
+	}
+	flags = append(flags, extra...)
+	flags = append(flags,
 		"--triggers",
-		"--events",
 		"--hex-blob",
 		"--default-character-set=utf8mb4",
 		"--skip-comments",
-	}
+	)
 	if p.DBType == models.DBTypeMySQL {
 		flags = append(flags, "--set-gtid-purged=OFF")
 	}
@@ -100,14 +129,69 @@ func mysqlDumpMySQL8FlagSetup() string {
 	return `_mf=""; _mx=$(mysqldump --version 2>&1); case "$_mx" in *"Distrib 8."*|*"Distrib 9."*|*"Ver 8."*|*"Ver 9."*) _mf="--column-statistics=0 --no-tablespaces";; esac;`
 }
 
+// binlogDumpFlagSetup sets $_bf to the option that writes the binary log position into the
+// dump as a comment: --source-data on MySQL 8.0.26+, --master-data elsewhere.
+func binlogDumpFlagSetup() string {
+	return `_bf="--master-data=2"; if mysqldump --help 2>/dev/null | grep -q -- '--source-data'; then _bf="--source-data=2"; fi;`
+}
+
+// mysqlDumpPlanExec dumps TargetDBName honouring a table plan: excluded, schema-only and
+// filtered tables are skipped with --ignore-table, then a --no-data pass adds the
+// schema-only tables' structure and one --where pass per filtered table adds its rows.
+// With binlog incrementals on, the data pass records its binlog position.
+func mysqlDumpPlanExec(p models.Profile, plan TablePlan) string {
+	if p.Binlog.Active() {
+		return binlogDumpFlagSetup() + " " + mysqlDumpPlanCommand(p, plan)
+	}
+	return mysqlDumpPlanCommand(p, plan)
+}
+
+func mysqlDumpPlanCommand(p models.Profile, plan TablePlan) string {
+	if plan.Empty() {
+		return mysqlDumpExec(p)
+	}
+	args := []string{mysqlDumpArgs(p)}
+	ignored := append(append([]string(nil), plan.Excluded...), plan.SchemaOnly...)
+	for _, table := range append(ignored, plan.FilteredNames()...) {
+		args = append(args, "--ignore-table="+shellEscape(p.TargetDBName+"."+table))
+	}
+	passes := []string{mysqlDumpTablesExec(p, strings.Join(args, " "), nil)}
+	if len(plan.SchemaOnly) > 0 {
+		passes = append(passes, mysqlDumpTablesExec(p, mysqlSchemaDumpArgs(p), plan.SchemaOnly))
+	}
+	for _, f := range plan.Filtered {
+		passes = append(passes, mysqlDumpTablesExec(p, mysqlRowsDumpArgs(p, f.Where), []string{f.Table}))
+	}
+	if len(passes) == 1 {
+		return passes[0]
+	}
+	return fmt.Sprintf("{ %s; }", strings.Join(passes, " && "))
+}
+
+// dumpPlanExec dumps TargetDBName with the dump tool of the profile's server.
+func dumpPlanExec(p models.Profile, plan TablePlan) string {
+	if postgres(p) {
+		return pgDumpExec(p, plan)
+	}
+	return mysqlDumpPlanExec(p, plan)
+}
+
 func mysqlDumpExec(p models.Profile) string {
-	dumpArgs := mysqlDumpArgs(p)
+	return mysqlDumpTablesExec(p, mysqlDumpArgs(p), nil)
+}
+
+// mysqlDumpTablesExec runs the dump tool with dumpArgs on TargetDBName, limited to tables
+// when given.
+func mysqlDumpTablesExec(p models.Profile, dumpArgs string, tables []string) string {
 	authArgs := fmt.Sprintf("-u %s -p%s", shellEscape(p.DBUser), shellEscape(p.DBPassword))
 	hostArgs := ""
 	if p.DBHost != "" {
 		hostArgs = fmt.Sprintf("-h %s -P %s", shellEscape(p.DBHost), shellEscape(p.DBPort))
 	}
 	dbArg := shellEscape(p.TargetDBName)
+	for _, table := range tables {
+		dbArg += " " + shellEscape(table)
+	}
 	mysqlDump := fmt.Sprintf("mysqldump %s %s %s %s", hostArgs, authArgs, dumpArgs, dbArg)
 	if p.DBType == models.DBTypeMySQL {
 		mysqlDump = fmt.Sprintf("%s mysqldump %s %s %s $_mf %s", mysqlDumpMySQL8FlagSetup(), hostArgs, authArgs, dumpArgs, dbArg)
@@ -120,14 +204,7 @@ func mysqlDumpExec(p models.Profile) string {
 }
 
 func importDecompressStream(compression string) string {
-	switch compression {
-	case "gzip":
-		return "gzip -dc"
-	case "zstd":
-		return "zstd -d -c"
-	default:
-		return "cat"
-	}
+	return codec.DecompressCommand(models.Compression(compression))
 }
 
 func shellWithPipefail(script string) string {
@@ -138,9 +215,10 @@ func shellWithPipefail(script string) string {
 	)
 }
 
-func compressCmd() string {
-	// pigz/gzip -1: fast compression (phpMyAdmin-like). zstd -1 as fallback.
-	return "if command -v pigz >/dev/null 2>&1; then pigz -1; elif command -v gzip >/dev/null 2>&1; then gzip -1; elif command -v zstd >/dev/null 2>&1; then zstd -1; else gzip -1; fi"
+// compressCmd compresses a dump with the profile's codec. A missing tool fails the
+// command; preflight checks for it first.
+func compressCmd(p models.Profile) string {
+	return codec.CompressCommand(p.Compression, p.CompressionLevel)
 }
 
 // BuildNativeExportCommand streams dump from native host tools.
@@ -170,8 +248,13 @@ func cloneDockerMode(p models.Profile, docker bool) models.Profile {
 
 // BuildExportCommand constructs the shell command to dump the database (streaming).
 func BuildExportCommand(p models.Profile) string {
-	dump := mysqlDumpExec(p)
-	inner := fmt.Sprintf("%s | { %s; }", dump, compressCmd())
+	return BuildFilteredExportCommand(p, TablePlan{})
+}
+
+// BuildFilteredExportCommand is BuildExportCommand limited by a resolved table plan.
+func BuildFilteredExportCommand(p models.Profile, plan TablePlan) string {
+	dump := dumpPlanExec(p, plan)
+	inner := fmt.Sprintf("%s | { %s; }", dump, compressCmd(p))
 	if p.IsDocker {
 		cmd, err := dockerExecCommand(p.ContainerID, inner)
 		if err != nil {
@@ -184,10 +267,15 @@ func BuildExportCommand(p models.Profile) string {
 
 // BuildExportToFileCommand writes compressed dump to remotePath on host.
 func BuildExportToFileCommand(p models.Profile, remotePath string) string {
-	dump := mysqlDumpExec(p)
-	inner := fmt.Sprintf("%s | { %s; } > %s", dump, compressCmd(), shellEscape(remotePath))
+	return BuildFilteredExportToFileCommand(p, remotePath, TablePlan{})
+}
+
+// BuildFilteredExportToFileCommand is BuildExportToFileCommand limited by a resolved table plan.
+func BuildFilteredExportToFileCommand(p models.Profile, remotePath string, plan TablePlan) string {
+	dump := dumpPlanExec(p, plan)
+	inner := fmt.Sprintf("%s | { %s; } > %s", dump, compressCmd(p), shellEscape(remotePath))
 	if p.IsDocker {
-		containerDump, err := dockerExecCommand(p.ContainerID, fmt.Sprintf("%s | { %s; }", dump, compressCmd()))
+		containerDump, err := dockerExecCommand(p.ContainerID, fmt.Sprintf("%s | { %s; }", dump, compressCmd(p)))
 		if err != nil {
 			return ""
 		}
@@ -198,7 +286,7 @@ func BuildExportToFileCommand(p models.Profile, remotePath string) string {
 
 // BuildImportCommand constructs restore command (streaming default).
 func BuildImportCommand(p models.Profile) string {
-	if mysqlOrMariaDB(p) {
+	if supportedDB(p) {
 		return BuildImportStreamCommand(p, "")
 	}
 	return ""
@@ -212,15 +300,25 @@ func BuildDropDatabaseCommand(databaseName string) string {
 
 // BuildImportPrepareTempCommand runs DROP/CREATE for a temporary verify database.
 func BuildImportPrepareTempCommand(p models.Profile, tempDBName string) string {
-	if !mysqlOrMariaDB(p) {
+	return buildRecreateDatabaseCommand(p, tempDBName)
+}
+
+// BuildImportPrepareCommand runs DROP/CREATE DATABASE before streaming import.
+func BuildImportPrepareCommand(p models.Profile) string {
+	return buildRecreateDatabaseCommand(p, p.TargetDBName)
+}
+
+func buildRecreateDatabaseCommand(p models.Profile, databaseName string) string {
+	if !supportedDB(p) {
 		return ""
 	}
-	db := sqlIdent(tempDBName)
-	sql := fmt.Sprintf(
-		"DROP DATABASE IF EXISTS %s; CREATE DATABASE %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;",
-		db, db,
-	)
-	inner := fmt.Sprintf("set -e; %s", mysqlClientExec(p, "", "-e "+shellEscape(sql)))
+	var inner string
+	if postgres(p) {
+		inner = fmt.Sprintf("set -e; %s", pgRecreateDatabaseExec(p, databaseName))
+	} else {
+		sql := DropDatabaseSQL(p, databaseName) + " " + CreateDatabaseSQL(p, databaseName)
+		inner = fmt.Sprintf("set -e; %s", mysqlClientExec(p, "", "-e "+shellEscape(sql)))
+	}
 	if p.IsDocker {
 		cmd, err := dockerExecCommand(p.ContainerID, inner)
 		if err != nil {
@@ -231,15 +329,16 @@ func BuildImportPrepareTempCommand(p models.Profile, tempDBName string) string {
 	return fmt.Sprintf("sh -c %s", shellEscape(inner))
 }
 
-// BuildImportPrepareCommand runs DROP/CREATE DATABASE before streaming import.
-func BuildImportPrepareCommand(p models.Profile) string {
+// BuildImportEnsureDatabaseCommand creates TargetDBName if it is missing, leaving an existing
+// database and its other tables untouched. Selective table restores use it instead of
+// BuildImportPrepareCommand; PostgreSQL hosts have no table restores.
+func BuildImportEnsureDatabaseCommand(p models.Profile) string {
 	if !mysqlOrMariaDB(p) {
 		return ""
 	}
-	db := sqlIdent(p.TargetDBName)
 	sql := fmt.Sprintf(
-		"DROP DATABASE IF EXISTS %s; CREATE DATABASE %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;",
-		db, db,
+		"CREATE DATABASE IF NOT EXISTS %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;",
+		sqlIdent(p.TargetDBName),
 	)
 	inner := fmt.Sprintf("set -e; %s", mysqlClientExec(p, "", "-e "+shellEscape(sql)))
 	if p.IsDocker {
@@ -263,7 +362,19 @@ func BuildImportStreamCommandForVerify(p models.Profile, compression, tempDBName
 	return buildImportStreamCommand(p, compression, tempDBName)
 }
 
-func importVerifySanitizeFilter(tempDBName string) string {
+// importClientExec reads a dump on stdin into TargetDBName; sessionSetup is printed ahead
+// of the dump.
+func importClientExec(p models.Profile) (client, sessionSetup string) {
+	if postgres(p) {
+		return psqlExec(p, p.TargetDBName, ""), pgImportSessionSetup
+	}
+	return mysqlClientExec(p, p.TargetDBName, ""), `printf "SET SESSION sql_mode=''; SET FOREIGN_KEY_CHECKS=0;\n"`
+}
+
+func importVerifySanitizeFilter(p models.Profile, tempDBName string) string {
+	if postgres(p) {
+		return pgVerifySanitizeFilter()
+	}
 	ident := sqlIdent(tempDBName)
 	return fmt.Sprintf(
 		`sed -e '/^CREATE DATABASE/IId' -e '/^DROP DATABASE/IId' -e 's/^USE `+"`"+`[^`+"`"+`]*`+"`"+`/USE %s/I'`,
@@ -272,14 +383,13 @@ func importVerifySanitizeFilter(tempDBName string) string {
 }
 
 func buildImportStreamCommand(p models.Profile, compression, verifyTempDB string) string {
-	if !mysqlOrMariaDB(p) {
+	if !supportedDB(p) {
 		return ""
 	}
-	client := mysqlClientExec(p, p.TargetDBName, "")
-	sessionSetup := `printf "SET SESSION sql_mode=''; SET FOREIGN_KEY_CHECKS=0;\n"`
+	client, sessionSetup := importClientExec(p)
 	stream := importDecompressStream(compression)
 	if verifyTempDB != "" {
-		stream = fmt.Sprintf("%s | %s", stream, importVerifySanitizeFilter(verifyTempDB))
+		stream = fmt.Sprintf("%s | %s", stream, importVerifySanitizeFilter(p, verifyTempDB))
 	}
 	pipe := fmt.Sprintf("{ %s; %s; } | %s", sessionSetup, stream, client)
 	cmd := shellWithPipefail(pipe)
@@ -304,27 +414,26 @@ func BuildImportFromFileCommandForVerify(p models.Profile, remotePath, compressi
 }
 
 func buildImportFromFileCommand(p models.Profile, remotePath, compression, verifyTempDB string) string {
-	if !mysqlOrMariaDB(p) {
+	if !supportedDB(p) {
 		return ""
 	}
-	client := mysqlClientExec(p, p.TargetDBName, "")
-	sessionSetup := `printf "SET SESSION sql_mode=''; SET FOREIGN_KEY_CHECKS=0;\n"`
+	client, sessionSetup := importClientExec(p)
 	stream := fmt.Sprintf("%s %s", importDecompressStream(compression), shellEscape(remotePath))
 	if verifyTempDB != "" {
-		stream = fmt.Sprintf("%s | %s", stream, importVerifySanitizeFilter(verifyTempDB))
+		stream = fmt.Sprintf("%s | %s", stream, importVerifySanitizeFilter(p, verifyTempDB))
 	}
 	pipe := fmt.Sprintf(
 		"{ %s; %s; } | %s",
 		sessionSetup, stream, client,
 	)
 	if p.IsDocker {
-		containerClient, err := dockerExecCommand(p.ContainerID, shellWithPipefail(fmt.Sprintf("{ %s; cat; } | %s", sessionSetup, mysqlClientExec(p, p.TargetDBName, ""))))
+		containerClient, err := dockerExecCommand(p.ContainerID, shellWithPipefail(fmt.Sprintf("{ %s; cat; } | %s", sessionSetup, client)))
 		if err != nil {
 			return ""
 		}
 		hostStream := importDecompressStream(compression) + " " + shellEscape(remotePath)
 		if verifyTempDB != "" {
-			hostStream = fmt.Sprintf("%s | %s", hostStream, importVerifySanitizeFilter(verifyTempDB))
+			hostStream = fmt.Sprintf("%s | %s", hostStream, importVerifySanitizeFilter(p, verifyTempDB))
 		}
 		hostPipe := fmt.Sprintf("%s | %s", hostStream, containerClient)
 		return shellWithPipefail(hostPipe)
@@ -332,14 +441,14 @@ func buildImportFromFileCommand(p models.Profile, remotePath, compression, verif
 	return shellWithPipefail(pipe)
 }
 
-// BuildQueryCommand runs SQL via mysql/mariadb CLI.
+// BuildQueryCommand runs SQL via the mysql/mariadb or psql CLI.
 func BuildQueryCommand(p models.Profile, query string, connectDB bool) (string, error) {
 	query = strings.TrimSpace(query)
 	if query == "" {
 		return "", errors.New("query is empty")
 	}
-	if !mysqlOrMariaDB(p) {
-		return "", errors.New("query only supported for MySQL/MariaDB")
+	if !supportedDB(p) {
+		return "", errors.New("query only supported for MySQL/MariaDB/PostgreSQL")
 	}
 	if err := ValidateProfileForRemoteOps(p); err != nil {
 		return "", err
@@ -347,6 +456,13 @@ func BuildQueryCommand(p models.Profile, query string, connectDB bool) (string,
 
 	b64 := base64.StdEncoding.EncodeToString([]byte(query))
 	b64Esc := shellEscape(b64)
+	if postgres(p) {
+		database := ""
+		if connectDB {
+			database = p.TargetDBName
+		}
+		return wrapQueryPipe(p, fmt.Sprintf("echo %s | base64 -d | %s", b64Esc, psqlExec(p, database, pgQueryArgs)))
+	}
 
 	authArgs := fmt.Sprintf("-u %s -p%s", shellEscape(p.DBUser), shellEscape(p.DBPassword))
 	hostArgs := ""
@@ -368,7 +484,11 @@ func BuildQueryCommand(p models.Profile, query string, connectDB bool) (string,
 		)
 	}
 	pipe := fmt.Sprintf("echo %s | base64 -d | %s", b64Esc, clientInner)
+	return wrapQueryPipe(p, pipe)
+}
 
+// wrapQueryPipe runs a query pipeline in the profile's container or a host shell.
+func wrapQueryPipe(p models.Profile, pipe string) (string, error) {
 	if p.IsDocker {
 		cmd, err := dockerExecCommand(p.ContainerID, pipe)
 		if err != nil {
@@ -408,16 +528,26 @@ func BuildPreflightScript(p models.Profile, requiredBytes int64, candidatePaths
 	dockerBlock := ""
 	checksBlock := ""
 	preflightChecks := `if ! uname -s 2>/dev/null | grep -qi linux; then fail=1; msg="$msg not-linux;"; fi
-command -v sh >/dev/null 2>&1 || { fail=1; msg="$msg missing:sh;"; }
-command -v gzip >/dev/null 2>&1 || command -v zstd >/dev/null 2>&1 || { fail=1; msg="$msg missing:compression;"; }`
+command -v sh >/dev/null 2>&1 || { fail=1; msg="$msg missing:sh;"; }`
+	if tool := codec.Tool(p.Compression); tool != "" {
+		preflightChecks += fmt.Sprintf(`
+command -v %[1]s >/dev/null 2>&1 || { fail=1; msg="$msg missing:%[1]s;"; }`, tool)
+	}
+	dumpProbe, clientProbe := containerDumpProbe(), containerClientProbe()
+	dumpPath := "command -v mysqldump 2>/dev/null || command -v mariadb-dump 2>/dev/null"
+	clientPath := "command -v mysql 2>/dev/null || command -v mariadb 2>/dev/null"
+	clientMissing := "mysql-client"
+	if postgres(p) {
+		dumpProbe, clientProbe = containerPgDumpProbe(), containerPsqlProbe()
+		dumpPath, clientPath = "command -v pg_dump 2>/dev/null", "command -v psql 2>/dev/null"
+		clientMissing = "psql-client"
+	}
 	if p.IsDocker {
 		cid := shellEscape(p.ContainerID)
-		dumpProbe := shellEscape(containerDumpProbe())
-		clientProbe := shellEscape(containerClientProbe())
-		dumpExec := fmt.Sprintf("docker exec %s sh -c %s", cid, dumpProbe)
-		clientExec := fmt.Sprintf("docker exec %s sh -c %s", cid, clientProbe)
-		dumpPathExec := fmt.Sprintf("docker exec %s sh -c %s", cid, shellEscape("command -v mysqldump 2>/dev/null || command -v mariadb-dump 2>/dev/null"))
-		clientPathExec := fmt.Sprintf("docker exec %s sh -c %s", cid, shellEscape("command -v mysql 2>/dev/null || command -v mariadb 2>/dev/null"))
+		dumpExec := fmt.Sprintf("docker exec %s sh -c %s", cid, shellEscape(dumpProbe))
+		clientExec := fmt.Sprintf("docker exec %s sh -c %s", cid, shellEscape(clientProbe))
+		dumpPathExec := fmt.Sprintf("docker exec %s sh -c %s", cid, shellEscape(dumpPath))
+		clientPathExec := fmt.Sprintf("docker exec %s sh -c %s", cid, shellEscape(clientPath))
 		dockerBlock = fmt.Sprintf(`
 echo "===DOCKER==="
 command -v docker >/dev/null 2>&1 && docker --version 2>/dev/null || echo "docker missing"
@@ -436,26 +566,37 @@ command -v docker >/dev/null 2>&1 || { fail=1; msg="$msg missing:docker;"; }
 docker inspect %s >/dev/null 2>&1 || { fail=1; msg="$msg container-not-found;"; }
 [ "$(docker inspect -f '{{.State.Status}}' %s 2>/dev/null)" = "running" ] || { fail=1; msg="$msg container-not-running;"; }
 %s >/dev/null 2>&1 || { fail=1; msg="$msg missing:container-dump-tool;"; }
-%s >/dev/null 2>&1 || { fail=1; msg="$msg missing:container-mysql-client;"; }`,
+%s >/dev/null 2>&1 || { fail=1; msg="$msg missing:container-%s;"; }`,
 			cid,
 			cid,
 			dumpPathExec,
 			clientPathExec,
+			clientMissing,
 		)
 	} else {
 		checksBlock = strings.Join([]string{
-			recordPreflightCheck("dump_version", "(command -v mysqldump >/dev/null 2>&1 && mysqldump --version) || (command -v mariadb-dump >/dev/null 2>&1 && mariadb-dump --version) || echo no-dump-tool"),
-			recordPreflightCheck("client_version", "(command -v mysql >/dev/null 2>&1 && mysql --version) || (command -v mariadb >/dev/null 2>&1 && mariadb --version) || echo no-mysql-client"),
-			recordPreflightCheck("dump_path", "command -v mysqldump 2>/dev/null || command -v mariadb-dump 2>/dev/null"),
-			recordPreflightCheck("client_path", "command -v mysql 2>/dev/null || command -v mariadb 2>/dev/null"),
+			recordPreflightCheck("dump_version", dumpProbe),
+			recordPreflightCheck("client_version", clientProbe),
+			recordPreflightCheck("dump_path", dumpPath),
+			recordPreflightCheck("client_path", clientPath),
 		}, "")
-		preflightChecks += `
-command -v mysqldump >/dev/null 2>&1 || command -v mariadb-dump >/dev/null 2>&1 || { fail=1; msg="$msg missing:dump-tool;"; }
-command -v mysql >/dev/null 2>&1 || command -v mariadb >/dev/null 2>&1 || { fail=1; msg="$msg missing:mysql-client;"; }`
+		preflightChecks += fmt.Sprintf(`
+{ %s; } >/dev/null 2>&1 || { fail=1; msg="$msg missing:dump-tool;"; }
+{ %s; } >/dev/null 2>&1 || { fail=1; msg="$msg missing:%s;"; }`, dumpPath, clientPath, clientMissing)
 	}
 	dbCheck := "command -v mysql >/dev/null && mysql --version 2>/dev/null; command -v mariadb >/dev/null && mariadb --version 2>/dev/null; command -v mysqldump >/dev/null && mysqldump --version 2>/dev/null; command -v mariadb-dump >/dev/null && mariadb-dump --version 2>/dev/null"
+	if postgres(p) {
+		dbCheck = "command -v psql >/dev/null && psql --version 2>/dev/null; command -v pg_dump >/dev/null && pg_dump --version 2>/dev/null"
+	}
 	if p.IsDocker {
 		dbCheck = fmt.Sprintf("docker exec %s sh -c 'command -v mysqldump >/dev/null && mysqldump --version; command -v mariadb-dump >/dev/null && mariadb-dump --version' 2>/dev/null || true", shellEscape(p.ContainerID))
+		if postgres(p) {
+			dbCheck = fmt.Sprintf("docker exec %s sh -c 'command -v pg_dump >/dev/null && pg_dump --version' 2>/dev/null || true", shellEscape(p.ContainerID))
+		}
+	}
+	physicalBlock := ""
+	if p.PhysicalBackup {
+		physicalBlock = fmt.Sprintf("echo \"===PHYSICAL===\"\n%s 2>/dev/null || echo no-physical-tool", BuildPhysicalProbeCommand(p))
 	}
 	return fmt.Sprintf(`set +e
 fail=0
@@ -467,13 +608,16 @@ uname -a 2>/dev/null
 echo "===DB==="
 %s
 echo "===TOOLS==="
-command -v zstd >/dev/null && zstd --version 2>/dev/null | head -1
+command -v zstd >/dev/null && echo "zstd $(zstd --version 2>/dev/null | head -1)"
 command -v gzip >/dev/null && gzip --version 2>/dev/null | head -1
+command -v xz >/dev/null && xz --version 2>/dev/null | head -1
+command -v bzip2 >/dev/null && echo "bzip2 ok"
 command -v sha256sum >/dev/null && echo sha256sum ok
 command -v dd >/dev/null && echo dd ok
 %s
 echo "===CHECKS==="
 %s
+%s
 echo "===DISK==="
 for p in %s; do
   eval target="$p"
@@ -489,7 +633,7 @@ echo %d
 echo "===RESULT==="
 echo "fail=$fail"
 echo "msg=$msg"
-`, preflightChecks, dbCheck, dockerBlock, checksBlock, paths, paths, requiredKB)
+`, preflightChecks, dbCheck, dockerBlock, checksBlock, physicalBlock, paths, paths, requiredKB)
 }
 
 // BuildRemoteTmpDir returns operation-specific tmp dir on remote host.
@@ -531,8 +675,8 @@ func BuildDownloadChunkCommand(path string, offset int64) string {
 
 // BuildDatabaseApproxRowCountCommand returns approximate total row count from information_schema.
 func BuildDatabaseApproxRowCountCommand(p models.Profile) (string, error) {
-	if !mysqlOrMariaDB(p) {
-		return "", errors.New("row count only supported for MySQL/MariaDB")
+	if !supportedDB(p) {
+		return "", errors.New("row count only supported for MySQL/MariaDB/PostgreSQL")
 	}
 	if err := ValidateProfileForRemoteOps(p); err != nil {
 		return "", err
@@ -541,6 +685,10 @@ func BuildDatabaseApproxRowCountCommand(p models.Profile) (string, error) {
 	if dbName == "" {
 		return "", errors.New("target database name is required")
 	}
+	if postgres(p) {
+		// pg_stat_user_tables only covers the database psql is connected to.
+		return BuildQueryCommand(p, "SELECT COALESCE(SUM(n_live_tup), 0) FROM pg_stat_user_tables", true)
+	}
 	query := fmt.Sprintf(
 		"SELECT COALESCE(SUM(TABLE_ROWS), 0) FROM information_schema.tables WHERE table_schema = '%s'",
 		dbName,
@@ -550,8 +698,8 @@ func BuildDatabaseApproxRowCountCommand(p models.Profile) (string, error) {
 
 // BuildDatabaseSizeCommand returns a remote command that estimates uncompressed DB size in bytes.
 func BuildDatabaseSizeCommand(p models.Profile) (string, error) {
-	if !mysqlOrMariaDB(p) {
-		return "", errors.New("database size estimate only supported for MySQL/MariaDB")
+	if !supportedDB(p) {
+		return "", errors.New("database size estimate only supported for MySQL/MariaDB/PostgreSQL")
 	}
 	if err := ValidateProfileForRemoteOps(p); err != nil {
 		return "", err
@@ -564,6 +712,9 @@ func BuildDatabaseSizeCommand(p models.Profile) (string, error) {
 		"SELECT COALESCE(SUM(data_length + index_length), 0) FROM information_schema.tables WHERE table_schema = '%s'",
 		dbName,
 	)
+	if postgres(p) {
+		query = fmt.Sprintf("SELECT pg_database_size('%s')", dbName)
+	}
 	return BuildQueryCommand(p, query, false)
 }
 
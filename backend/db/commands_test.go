@@ -140,6 +140,10 @@ func TestBuildImportStreamCommand(t *testing.T) {
 	if strings.Contains(prep, "'3306'-u") {
 		t.Fatalf("port and -u must be separate shell words: %s", prep)
 	}
+	ensure := BuildImportEnsureDatabaseCommand(p)
+	if !strings.Contains(ensure, "CREATE DATABASE IF NOT EXISTS") || strings.Contains(ensure, "DROP") {
+		t.Fatalf("ensure command must create without dropping: %s", ensure)
+	}
 }
 
 func TestBuildTmpFileCommands(t *testing.T) {
@@ -317,3 +321,21 @@ func TestBuildImportPrepareTempCommand(t *testing.T) {
 		t.Fatalf("expected temp db in command: %s", cmd)
 	}
 }
+
+func TestBuildExportCommandUsesProfileCodec(t *testing.T) {
+	p := models.Profile{DBType: models.DBTypeMariaDB, DBUser: "u", DBPassword: "p", TargetDBName: "shop",
+		Compression: models.CompressionZstd, CompressionLevel: 7}
+	cmd := BuildExportCommand(p)
+	if !strings.Contains(cmd, "zstd -q -c -7") || strings.Contains(cmd, "pigz") {
+		t.Fatalf("expected zstd -7 only: %s", cmd)
+	}
+	if cmd := BuildExportToFileCommand(models.Profile{DBType: models.DBTypeMariaDB, TargetDBName: "shop", Compression: models.CompressionNone}, "/tmp/dump.sql"); !strings.Contains(cmd, "| { cat; }") {
+		t.Fatalf("expected uncompressed dump: %s", cmd)
+	}
+	if got := importDecompressStream("bzip2"); got != "bzip2 -dc" {
+		t.Fatalf("bzip2 import stream = %s", got)
+	}
+	if got := importDecompressStream(""); got != "cat" {
+		t.Fatalf("plain import stream = %s", got)
+	}
+}
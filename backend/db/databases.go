@@ -0,0 +1,136 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"dback/models"
+)
+
+// systemDatabases are never included by DatabasesAll or DatabasesPattern.
+var systemDatabases = map[string]bool{
+	"information_schema": true,
+	"performance_schema": true,
+	"mysql":              true,
+	"sys":                true,
+}
+
+// IsSystemDatabase reports whether name is a MySQL/MariaDB system schema.
+func IsSystemDatabase(name string) bool {
+	return systemDatabases[strings.ToLower(strings.TrimSpace(name))]
+}
+
+// BuildListDatabasesCommand lists the databases visible to the profile's DB user.
+func BuildListDatabasesCommand(p models.Profile) (string, error) {
+	return BuildQueryCommand(p, ListDatabasesQuery(p), false)
+}
+
+// ListDatabasesQuery is the query BuildListDatabasesCommand runs, for callers that go
+// through a query runner instead.
+func ListDatabasesQuery(p models.Profile) string {
+	if postgres(p) {
+		return pgListDatabasesQuery
+	}
+	return "SHOW DATABASES"
+}
+
+// ParseDatabaseList reads SHOW DATABASES batch output, skipping the header and client warnings.
+func ParseDatabaseList(out string) []string {
+	var names []string
+	for i, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, "[Warning]") || strings.HasPrefix(line, "Warning:") {
+			continue
+		}
+		if line == "Database" && len(names) == 0 && i < 3 {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names
+}
+
+// DatabasePatterns splits a comma- or whitespace-separated glob list.
+func DatabasePatterns(pattern string) []string {
+	return strings.FieldsFunc(pattern, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n'
+	})
+}
+
+// ValidateDatabaseSelection checks the mode and that it selects something.
+func ValidateDatabaseSelection(sel *models.DatabaseSelection) error {
+	if !sel.Active() {
+		return nil
+	}
+	switch sel.Mode {
+	case models.DatabasesList:
+		if len(sel.Names) == 0 {
+			return errors.New("list at least one database to back up")
+		}
+	case models.DatabasesPattern:
+		patterns := DatabasePatterns(sel.Pattern)
+		if len(patterns) == 0 {
+			return errors.New("database pattern is required")
+		}
+		for _, p := range patterns {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid database pattern %q", p)
+			}
+		}
+	case models.DatabasesAll:
+	default:
+		return fmt.Errorf("unknown database selection %q", sel.Mode)
+	}
+	return nil
+}
+
+// SelectDatabases resolves a selection against the databases on the server, in name order.
+// List mode keeps the configured order and does not need available.
+func SelectDatabases(sel models.DatabaseSelection, available []string) ([]string, error) {
+	if err := ValidateDatabaseSelection(&sel); err != nil {
+		return nil, err
+	}
+	var out []string
+	seen := map[string]bool{}
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	switch sel.Mode {
+	case models.DatabasesList:
+		for _, name := range sel.Names {
+			add(name)
+		}
+		return out, nil
+	case models.DatabasesPattern:
+		patterns := DatabasePatterns(sel.Pattern)
+		for _, name := range available {
+			if IsSystemDatabase(name) {
+				continue
+			}
+			for _, p := range patterns {
+				if ok, _ := path.Match(p, name); ok {
+					add(name)
+					break
+				}
+			}
+		}
+	default:
+		for _, name := range available {
+			if !IsSystemDatabase(name) {
+				add(name)
+			}
+		}
+	}
+	sort.Strings(out)
+	if len(out) == 0 {
+		return nil, errors.New("no databases match the selection")
+	}
+	return out, nil
+}
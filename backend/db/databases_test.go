@@ -0,0 +1,69 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+
+	"dback/models"
+)
+
+func TestParseDatabaseList(t *testing.T) {
+	out := "mysql: [Warning] Using a password on the command line interface can be insecure.\nDatabase\ninformation_schema\nshop\ncrm\n"
+	got := ParseDatabaseList(out)
+	want := []string{"information_schema", "shop", "crm"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSelectDatabases(t *testing.T) {
+	available := []string{"information_schema", "mysql", "performance_schema", "sys", "shop_eu", "shop_us", "crm", "blog"}
+	tests := []struct {
+		name string
+		sel  models.DatabaseSelection
+		want []string
+	}{
+		{"all skips system schemas", models.DatabaseSelection{Mode: models.DatabasesAll}, []string{"blog", "crm", "shop_eu", "shop_us"}},
+		{"pattern", models.DatabaseSelection{Mode: models.DatabasesPattern, Pattern: "shop_*, crm"}, []string{"crm", "shop_eu", "shop_us"}},
+		{"pattern never matches system schemas", models.DatabaseSelection{Mode: models.DatabasesPattern, Pattern: "*s*"}, []string{"shop_eu", "shop_us"}},
+		{"list keeps order and drops duplicates", models.DatabaseSelection{Mode: models.DatabasesList, Names: []string{"crm", " blog ", "crm"}}, []string{"crm", "blog"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelectDatabases(tt.sel, available)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectDatabasesErrors(t *testing.T) {
+	for _, sel := range []models.DatabaseSelection{
+		{Mode: models.DatabasesList},
+		{Mode: models.DatabasesPattern, Pattern: " , "},
+		{Mode: models.DatabasesPattern, Pattern: "shop_["},
+		{Mode: models.DatabasesPattern, Pattern: "nomatch*"},
+		{Mode: "bogus"},
+	} {
+		if _, err := SelectDatabases(sel, []string{"shop"}); err == nil {
+			t.Fatalf("expected error for %+v", sel)
+		}
+	}
+}
+
+func TestValidateDatabaseName(t *testing.T) {
+	for _, name := range []string{"shop_copy", "Shop-2024", " crm "} {
+		if err := ValidateDatabaseName(name); err != nil {
+			t.Fatalf("%q: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "shop copy", "shop`; DROP", "shop/x", "mysql", "Information_Schema", "a234567890123456789012345678901234567890123456789012345678901234x"} {
+		if err := ValidateDatabaseName(name); err == nil {
+			t.Fatalf("expected error for %q", name)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+
+	"dback/models"
+)
+
+// PhysicalTool is a physical backup tool found on a host and the xbstream extractor that
+// unpacks its stream.
+type PhysicalTool struct {
+	Backup string // mariadb-backup, mariabackup or xtrabackup
+	Stream string // mbstream or xbstream
+}
+
+// Found reports whether a backup tool was found.
+func (t PhysicalTool) Found() bool {
+	return t.Backup != ""
+}
+
+// physicalTools lists backup tools in order of preference with their stream extractors.
+var physicalTools = []PhysicalTool{
+	{Backup: "mariadb-backup", Stream: "mbstream"},
+	{Backup: "mariabackup", Stream: "mbstream"},
+	{Backup: "xtrabackup", Stream: "xbstream"},
+}
+
+// PhysicalToolProbe prints the first physical backup tool on PATH and its stream extractor
+// ("mariadb-backup mbstream"), or "no-physical-tool".
+func PhysicalToolProbe() string {
+	var b strings.Builder
+	b.WriteString("_pt=no-physical-tool; ")
+	for i, t := range physicalTools {
+		if i == 0 {
+			b.WriteString("if ")
+		} else {
+			b.WriteString("elif ")
+		}
+		fmt.Fprintf(&b, "command -v %s >/dev/null 2>&1; then _pt=%s; ", t.Backup, shellEscape(t.Backup+" "+t.Stream))
+	}
+	b.WriteString(`fi; echo "$_pt"`)
+	return b.String()
+}
+
+// ParsePhysicalTool reads PhysicalToolProbe output.
+func ParsePhysicalTool(out string) PhysicalTool {
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		for _, t := range physicalTools {
+			if fields[0] == t.Backup && fields[1] == t.Stream {
+				return t
+			}
+		}
+	}
+	return PhysicalTool{}
+}
+
+// BuildPhysicalProbeCommand runs PhysicalToolProbe on the host or inside the container.
+func BuildPhysicalProbeCommand(p models.Profile) string {
+	if p.IsDocker {
+		return fmt.Sprintf("docker exec %s sh -c %s", shellEscape(p.ContainerID), shellEscape(PhysicalToolProbe()))
+	}
+	return PhysicalToolProbe()
+}
+
+// physicalAuthArgs are the connection flags shared by mariadb-backup and xtrabackup.
+func physicalAuthArgs(p models.Profile) string {
+	args := fmt.Sprintf("--user=%s --password=%s", shellEscape(p.DBUser), shellEscape(p.DBPassword))
+	if p.DBHost != "" {
+		args += fmt.Sprintf(" --host=%s --port=%s", shellEscape(p.DBHost), shellEscape(p.DBPort))
+	}
+	return args
+}
+
+// BuildPhysicalBackupCommand streams a compressed xbstream of the server's data files.
+// workDir holds the tool's own temporary files and is removed afterwards.
+func BuildPhysicalBackupCommand(p models.Profile, tool PhysicalTool, workDir string) string {
+	dir := shellEscape(workDir)
+	inner := fmt.Sprintf("mkdir -p %s && %s --backup --stream=xbstream --target-dir=%s %s | { %s; }; _rc=$?; rm -rf %s; exit $_rc",
+		dir, tool.Backup, dir, physicalAuthArgs(p), compressCmd(p), dir)
+	if p.IsDocker {
+		cmd, err := dockerExecCommand(p.ContainerID, inner)
+		if err != nil {
+			return ""
+		}
+		return cmd
+	}
+	return shellWithPipefail(inner)
+}
+
+// BuildPhysicalExtractCommand unpacks an uploaded compressed xbstream into dir.
+func BuildPhysicalExtractCommand(tool PhysicalTool, dir, compression string) string {
+	d := shellEscape(dir)
+	return shellWithPipefail(fmt.Sprintf("rm -rf %s && mkdir -p %s && %s | %s -x -C %s",
+		d, d, importDecompressStream(compression), tool.Stream, d))
+}
+
+// BuildPhysicalPrepareCommand applies the redo log of an extracted backup so its data files
+// are consistent.
+func BuildPhysicalPrepareCommand(tool PhysicalTool, dir string) string {
+	return fmt.Sprintf("%s --prepare --target-dir=%s 2>&1", tool.Backup, shellEscape(dir))
+}
+
+// BuildPhysicalCheckCommand reports what a physical restore needs on the host: "tool=",
+// "stream=" (the extractor if present) and "root=yes" when the user is root or has
+// passwordless sudo.
+func BuildPhysicalCheckCommand(tool PhysicalTool) string {
+	return fmt.Sprintf(`command -v %s >/dev/null 2>&1 && echo tool=%s; command -v %s >/dev/null 2>&1 && echo stream=%s; if [ "$(id -u)" = 0 ] || sudo -n true >/dev/null 2>&1; then echo root=yes; fi`,
+		tool.Backup, tool.Backup, tool.Stream, tool.Stream)
+}
+
+// BuildPhysicalCopyBackCommand replaces the server's data directory with a prepared backup:
+// it reads the data directory from the running server, stops the service, moves the old
+// directory aside with suffix, copies the backup back, restores ownership and starts the
+// service again. A failed copy-back puts the old directory back. It prints "previous=<dir>".
+func BuildPhysicalCopyBackCommand(p models.Profile, tool PhysicalTool, dir, suffix string) string {
+	d := shellEscape(dir)
+	script := fmt.Sprintf(`set -e
+S=""; [ "$(id -u)" = 0 ] || S="sudo -n"
+datadir=$(%s)
+datadir=${datadir%%/}
+if [ -z "$datadir" ] || [ "$datadir" = "/" ]; then echo "could not read the server's data directory" >&2; exit 1; fi
+svc=""
+for s in mariadb mysql mysqld; do if $S systemctl cat "$s.service" >/dev/null 2>&1; then svc=$s; break; fi; done
+if [ -z "$svc" ]; then echo "no mariadb, mysql or mysqld systemd service found to stop" >&2; exit 1; fi
+old="$datadir.%s"
+owner=$($S stat -c %%U:%%G "$datadir")
+$S systemctl stop "$svc"
+$S mv "$datadir" "$old"
+$S mkdir -p "$datadir"
+if ! $S %s --copy-back --target-dir=%s --datadir="$datadir" 2>&1; then
+  $S rm -rf "$datadir"; $S mv "$old" "$datadir"; $S systemctl start "$svc" || true
+  echo "copy-back failed; the previous data directory was put back" >&2; exit 1
+fi
+$S chown -R "$owner" "$datadir"
+$S systemctl start "$svc"
+echo "previous=$old"`,
+		mysqlClientExec(p, "", "-N -B -e 'SELECT @@datadir'"), suffix, tool.Backup, d)
+	return shellWithPipefail(script)
+}
@@ -0,0 +1,63 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestParsePhysicalTool(t *testing.T) {
+	if got := ParsePhysicalTool("noise\nxtrabackup xbstream\n"); got.Backup != "xtrabackup" || got.Stream != "xbstream" {
+		t.Fatalf("ParsePhysicalTool = %+v", got)
+	}
+	if got := ParsePhysicalTool("no-physical-tool\n"); got.Found() {
+		t.Fatalf("expected no tool, got %+v", got)
+	}
+	if got := ParsePhysicalTool("xtrabackup mbstream\n"); got.Found() {
+		t.Fatalf("a mismatched extractor is not a tool, got %+v", got)
+	}
+}
+
+func TestBuildPhysicalBackupCommand(t *testing.T) {
+	tool := PhysicalTool{Backup: "mariadb-backup", Stream: "mbstream"}
+	p := models.Profile{DBType: models.DBTypeMariaDB, DBUser: "root", DBPassword: "pw"}
+	cmd := BuildPhysicalBackupCommand(p, tool, "/tmp/dback/op1/physical")
+	for _, want := range []string{
+		"bash -o pipefail",
+		"mariadb-backup --backup --stream=xbstream",
+		"--target-dir=",
+		"--user=",
+		"rm -rf ",
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Fatalf("backup command missing %q: %s", want, cmd)
+		}
+	}
+	if strings.Contains(cmd, "--host=") {
+		t.Fatalf("a local server needs no --host: %s", cmd)
+	}
+
+	p.IsDocker, p.ContainerID = true, "db1"
+	if cmd := BuildPhysicalBackupCommand(p, tool, "/tmp/dback-op1"); !strings.HasPrefix(cmd, "docker exec") {
+		t.Fatalf("docker backup should run inside the container: %s", cmd)
+	}
+}
+
+func TestBuildPhysicalCopyBackCommandKeepsPreviousDatadir(t *testing.T) {
+	tool := PhysicalTool{Backup: "xtrabackup", Stream: "xbstream"}
+	p := models.Profile{DBType: models.DBTypeMySQL, DBUser: "root", DBPassword: "pw"}
+	cmd := BuildPhysicalCopyBackCommand(p, tool, "/tmp/dback/op1/physical", "dback-op1")
+	for _, want := range []string{
+		"SELECT @@datadir",
+		`systemctl stop "$svc"`,
+		`old="$datadir.dback-op1"`,
+		"xtrabackup --copy-back --target-dir=",
+		`mv "$old" "$datadir"`,
+		`echo "previous=$old"`,
+	} {
+		if !strings.Contains(cmd, want) {
+			t.Fatalf("copy-back command missing %q: %s", want, cmd)
+		}
+	}
+}
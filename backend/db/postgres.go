@@ -0,0 +1,177 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"dback/models"
+)
+
+// pgMaintenanceDB is the database psql connects to when no database is named: CREATE and
+// DROP DATABASE cannot run while connected to the database they change.
+const pgMaintenanceDB = "postgres"
+
+// pgPublicSchema holds the tables that are listed without a schema prefix.
+const pgPublicSchema = "public"
+
+func postgres(p models.Profile) bool {
+	return p.DBType == models.DBTypePostgreSQL
+}
+
+// PgIdent quotes a PostgreSQL identifier.
+func PgIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// QuoteTable quotes a table name for the profile's server. PostgreSQL tables outside the
+// public schema are listed as schema.table and quoted part by part.
+func QuoteTable(p models.Profile, name string) string {
+	if !postgres(p) {
+		return SQLIdent(name)
+	}
+	if schema, table, ok := strings.Cut(name, "."); ok {
+		return PgIdent(schema) + "." + PgIdent(table)
+	}
+	return PgIdent(name)
+}
+
+// DropDatabaseSQL returns SQL that drops a database by name on the profile's server.
+func DropDatabaseSQL(p models.Profile, databaseName string) string {
+	if postgres(p) {
+		return fmt.Sprintf("DROP DATABASE IF EXISTS %s;", PgIdent(databaseName))
+	}
+	return BuildDropDatabaseCommand(databaseName)
+}
+
+// CreateDatabaseSQL returns SQL that creates an empty database on the profile's server.
+// PostgreSQL databases are copied from template0 so nothing added to template1 clashes
+// with the restored dump.
+func CreateDatabaseSQL(p models.Profile, databaseName string) string {
+	if postgres(p) {
+		return fmt.Sprintf("CREATE DATABASE %s TEMPLATE template0;", PgIdent(databaseName))
+	}
+	return fmt.Sprintf("CREATE DATABASE %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;", sqlIdent(databaseName))
+}
+
+// pgTablePattern is a pg_dump pattern matching exactly one listed table: quoted parts make
+// case and the pattern characters * ? . literal.
+func pgTablePattern(name string) string {
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok {
+		schema, table = pgPublicSchema, name
+	}
+	return PgIdent(schema) + "." + PgIdent(table)
+}
+
+func pgEnv(p models.Profile) string {
+	return "PGPASSWORD=" + shellEscape(p.DBPassword)
+}
+
+func pgConnArgs(p models.Profile) string {
+	args := "--username=" + shellEscape(p.DBUser)
+	if p.DBHost != "" {
+		args = fmt.Sprintf("--host=%s --port=%s %s", shellEscape(p.DBHost), shellEscape(p.DBPort), args)
+	}
+	return args
+}
+
+// psqlExec runs psql against database (the maintenance database when empty). Scripts stop
+// at the first error, like the mysql client does.
+func psqlExec(p models.Profile, database, extraArgs string) string {
+	if database == "" {
+		database = pgMaintenanceDB
+	}
+	if extraArgs != "" {
+		extraArgs = " " + extraArgs
+	}
+	return fmt.Sprintf("%s psql --no-psqlrc --quiet --set=ON_ERROR_STOP=1 %s --dbname=%s%s",
+		pgEnv(p), pgConnArgs(p), shellEscape(database), extraArgs)
+}
+
+// pgDumpExec dumps TargetDBName as plain SQL honouring a table plan: excluded tables are
+// left out and schema-only tables keep their definition without rows. Ownership and grants
+// are dropped so the dump restores on a server without the same roles. PostgreSQL hosts
+// have no row filters.
+func pgDumpExec(p models.Profile, plan TablePlan) string {
+	args := []string{"--no-owner", "--no-acl", "--encoding=UTF8"}
+	for _, table := range plan.Excluded {
+		args = append(args, "--exclude-table="+shellEscape(pgTablePattern(table)))
+	}
+	for _, table := range plan.SchemaOnly {
+		args = append(args, "--exclude-table-data="+shellEscape(pgTablePattern(table)))
+	}
+	return fmt.Sprintf("%s pg_dump %s %s --dbname=%s",
+		pgEnv(p), pgConnArgs(p), strings.Join(args, " "), shellEscape(p.TargetDBName))
+}
+
+// pgRecreateDatabaseExec drops and recreates databaseName. Each -c runs on its own because
+// DROP DATABASE cannot run inside a transaction.
+func pgRecreateDatabaseExec(p models.Profile, databaseName string) string {
+	return psqlExec(p, "", fmt.Sprintf("-c %s -c %s",
+		shellEscape(DropDatabaseSQL(p, databaseName)),
+		shellEscape(CreateDatabaseSQL(p, databaseName)),
+	))
+}
+
+// pgImportSessionSetup keeps NOTICE lines from every CREATE out of the import output.
+const pgImportSessionSetup = `printf "SET client_min_messages = warning;\n"`
+
+// pgVerifySanitizeFilter drops the statements that would leave the temp verify database.
+func pgVerifySanitizeFilter() string {
+	return `sed -e '/^\\connect/d' -e '/^CREATE DATABASE/Id' -e '/^DROP DATABASE/Id'`
+}
+
+// pgQueryArgs print results the way mysql --batch does: a header line, then one line per
+// row with tab-separated columns and NULL spelled out.
+const pgQueryArgs = `--no-align --field-separator="$(printf '\t')" --pset=footer=off --pset=null=NULL`
+
+// pgListDatabasesQuery lists the databases a dump can connect to, under the same header
+// SHOW DATABASES prints.
+const pgListDatabasesQuery = `SELECT datname AS "Database" FROM pg_database WHERE NOT datistemplate AND datallowconn ORDER BY datname`
+
+// pgListTablesQuery lists the tables and views of the connected database, schema-qualified
+// outside public, under a Tables_in_ header like SHOW TABLES.
+const pgListTablesQuery = `SELECT CASE WHEN table_schema = 'public' THEN table_name ELSE table_schema || '.' || table_name END AS "Tables_in_database" FROM information_schema.tables WHERE table_schema NOT IN ('pg_catalog', 'information_schema') ORDER BY 1`
+
+// PgTableRowsQuery lists the estimated live row count of every table in the connected
+// database from pg_stat_user_tables, named like the tables BuildListTablesCommand lists.
+const PgTableRowsQuery = `SELECT CASE WHEN schemaname = 'public' THEN relname ELSE schemaname || '.' || relname END AS table_name, n_live_tup AS table_rows FROM pg_stat_user_tables ORDER BY 1;`
+
+func containerPgDumpProbe() string {
+	return "(command -v pg_dump >/dev/null 2>&1 && pg_dump --version) || echo no-dump-tool"
+}
+
+func containerPsqlProbe() string {
+	return "(command -v psql >/dev/null 2>&1 && psql --version) || echo no-psql-client"
+}
+
+// pgPasswordPattern matches PGPASSWORD and its whole value as pgEnv quotes it, blanks and
+// escaped quotes included, also when an sh -c wrapper has quoted the command again (each
+// quote is escaped again per level).
+var pgPasswordPattern = regexp.MustCompile(pgPasswordValue(2) + "|" + pgPasswordValue(1) + "|" + pgPasswordValue(0))
+
+// pgPasswordValue returns the pattern of PGPASSWORD with its value shell-quoted depth more
+// times than pgEnv does.
+func pgPasswordValue(depth int) string {
+	quote, escaped := "'", `'\''`
+	for i := 0; i < depth; i++ {
+		quote = strings.ReplaceAll(quote, "'", `'\''`)
+		escaped = strings.ReplaceAll(escaped, "'", `'\''`)
+	}
+	q := regexp.QuoteMeta(quote)
+	// The closing quote is the first one followed by a blank or the end; the first quote of
+	// an escaped one is followed by a backslash.
+	return "PGPASSWORD=" + q + "(?:[^']|" + regexp.QuoteMeta(escaped) + ")*?" + q + `(?:\s|$)`
+}
+
+func maskPgPassword(cmd string) string {
+	return pgPasswordPattern.ReplaceAllStringFunc(cmd, func(m string) string {
+		// Keep the blank that ended the value.
+		end := m[len(m)-1:]
+		if strings.TrimSpace(end) != "" {
+			end = ""
+		}
+		return "PGPASSWORD=***" + end
+	})
+}
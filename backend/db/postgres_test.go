@@ -0,0 +1,174 @@
+package db
+
+import (
+	"encoding/base64"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func testPgProfile() models.Profile {
+	return models.Profile{
+		DBType:       models.DBTypePostgreSQL,
+		DBUser:       "app",
+		DBPassword:   "pa'ss",
+		DBHost:       "127.0.0.1",
+		DBPort:       "5432",
+		TargetDBName: "shop",
+	}
+}
+
+func checkBashSyntax(t *testing.T, name, cmd string) {
+	t.Helper()
+	if out, err := exec.Command("bash", "-n", "-c", cmd).CombinedOutput(); err != nil {
+		t.Fatalf("%s: syntax error: %v\n%s\n%s", name, err, out, cmd)
+	}
+}
+
+func TestPostgresExportCommand(t *testing.T) {
+	p := testPgProfile()
+	plan := TablePlan{Excluded: []string{"log_2024"}, SchemaOnly: []string{"audit.Sessions"}}
+	cmd := BuildFilteredExportCommand(p, plan)
+	checkBashSyntax(t, "export", cmd)
+	for _, part := range []string{"pg_dump", "--no-owner", "--no-acl", "PGPASSWORD=", `"public"."log_2024"`, `--exclude-table-data=`, `"audit"."Sessions"`} {
+		if !strings.Contains(cmd, part) {
+			t.Fatalf("export command missing %q: %s", part, cmd)
+		}
+	}
+	if strings.Contains(cmd, "mysqldump") {
+		t.Fatalf("PostgreSQL export should not use mysqldump: %s", cmd)
+	}
+
+	p.IsDocker, p.ContainerID = true, "pg16"
+	checkBashSyntax(t, "docker export", BuildFilteredExportToFileCommand(p, "/tmp/dback/x/shop.sql.gz", plan))
+}
+
+func TestPostgresImportCommands(t *testing.T) {
+	p := testPgProfile()
+	prep := BuildImportPrepareCommand(p)
+	checkBashSyntax(t, "prepare", prep)
+	for _, part := range []string{`DROP DATABASE IF EXISTS "shop";`, `CREATE DATABASE "shop" TEMPLATE template0;`, "--dbname='\\''postgres'\\''"} {
+		if !strings.Contains(prep, part) {
+			t.Fatalf("prepare command missing %q: %s", part, prep)
+		}
+	}
+	if strings.Count(prep, `-c '\''`) != 2 {
+		t.Fatalf("DROP and CREATE DATABASE must run as separate statements: %s", prep)
+	}
+	if BuildImportEnsureDatabaseCommand(p) != "" {
+		t.Fatal("PostgreSQL hosts have no table restores")
+	}
+
+	stream := BuildImportStreamCommand(p, "gzip")
+	checkBashSyntax(t, "stream", stream)
+	if !strings.Contains(stream, "psql") || !strings.Contains(stream, "ON_ERROR_STOP=1") || strings.Contains(stream, "sql_mode") {
+		t.Fatalf("unexpected stream import: %s", stream)
+	}
+	verify := BuildImportFromFileCommandForVerify(p, "/tmp/dback/x/shop.sql.gz", "gzip", "dback_verify_1")
+	checkBashSyntax(t, "verify", verify)
+	if !strings.Contains(verify, "connect/d") {
+		t.Fatalf("verify import should drop \\connect lines: %s", verify)
+	}
+
+	p.IsDocker, p.ContainerID = true, "pg16"
+	checkBashSyntax(t, "docker file import", BuildImportFromFileCommand(p, "/tmp/dback/x/shop.sql.gz", "gzip"))
+}
+
+func TestPostgresQueryCommand(t *testing.T) {
+	p := testPgProfile()
+	cmd, err := BuildQueryCommand(p, "SELECT 1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkBashSyntax(t, "query", cmd)
+	if !strings.Contains(cmd, "psql") || !strings.Contains(cmd, "--no-align") || !strings.Contains(cmd, "shop") {
+		t.Fatalf("unexpected query command: %s", cmd)
+	}
+	if !strings.Contains(cmd, base64.StdEncoding.EncodeToString([]byte("SELECT 1"))) {
+		t.Fatalf("query should be passed base64-encoded: %s", cmd)
+	}
+	server, _ := BuildQueryCommand(p, "SELECT 1", false)
+	if strings.Contains(server, "shop") || !strings.Contains(server, "postgres") {
+		t.Fatalf("server queries should use the maintenance database: %s", server)
+	}
+
+	size, _ := BuildDatabaseSizeCommand(p)
+	rows, _ := BuildDatabaseApproxRowCountCommand(p)
+	if !strings.Contains(size, base64.StdEncoding.EncodeToString([]byte("SELECT pg_database_size('shop')"))) {
+		t.Fatalf("unexpected size command: %s", size)
+	}
+	if !strings.Contains(rows, base64.StdEncoding.EncodeToString([]byte("SELECT COALESCE(SUM(n_live_tup), 0) FROM pg_stat_user_tables"))) {
+		t.Fatalf("unexpected row count command: %s", rows)
+	}
+}
+
+func TestMaskCommandHidesPgPassword(t *testing.T) {
+	p := testPgProfile()
+	p.DBPassword = "s3cret"
+	masked := MaskCommand(BuildExportCommand(p))
+	if strings.Contains(masked, "s3cret") || !strings.Contains(masked, "PGPASSWORD=***") {
+		t.Fatalf("password not masked: %s", masked)
+	}
+	if !strings.Contains(masked, "--port=") {
+		t.Fatalf("PostgreSQL options should survive masking: %s", masked)
+	}
+}
+
+func TestMaskCommandHidesQuotedPgPasswords(t *testing.T) {
+	for _, pw := range []string{"zq top secret's zq pw", "'zqlead", "zqtrail'", `zq a\b`, "zq  two''quotes"} {
+		p := testPgProfile()
+		p.DBPassword = pw
+		query, err := BuildQueryCommand(p, "SELECT 1", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, cmd := range []string{
+			pgDumpExec(p, TablePlan{}),
+			BuildExportCommand(p),
+			BuildImportStreamCommand(p, "gzip"),
+			BuildImportFromFileCommand(p, "/tmp/x.sql.gz", "gzip"),
+			BuildImportPrepareCommand(p),
+			query,
+		} {
+			masked := MaskCommand(cmd)
+			if strings.Contains(masked, "zq") || !strings.Contains(masked, "PGPASSWORD=***") {
+				t.Fatalf("password %q not masked: %s", pw, masked)
+			}
+			if !strings.Contains(masked, "--username=") {
+				t.Fatalf("PostgreSQL options should survive masking: %s", masked)
+			}
+		}
+	}
+}
+
+func TestParsePsqlOutput(t *testing.T) {
+	out := "NOTICE:  database \"x\" does not exist, skipping\ntable_name\ttable_rows\norders\t12\nsales.refunds\t3"
+	result := ParseQueryOutput(models.Profile{DBType: models.DBTypePostgreSQL}, out)
+	if len(result.Columns) != 2 || len(result.Rows) != 2 || result.Rows[1][0] != "sales.refunds" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if ParseDatabaseSizeBytes("pg_database_size\n7340032") != 7340032 {
+		t.Fatal("size output not parsed")
+	}
+	if names := ParseDatabaseList("Database\nshop\npostgres"); len(names) != 2 || names[0] != "shop" {
+		t.Fatalf("databases = %v", names)
+	}
+	if names := ParseTableList("Tables_in_database\norders\nsales.refunds"); len(names) != 2 {
+		t.Fatalf("tables = %v", names)
+	}
+}
+
+func TestQuoteTable(t *testing.T) {
+	pg := models.Profile{DBType: models.DBTypePostgreSQL}
+	if got := QuoteTable(pg, `sales.Re"funds`); got != `"sales"."Re""funds"` {
+		t.Fatalf("got %s", got)
+	}
+	if got := QuoteTable(models.Profile{DBType: models.DBTypeMySQL}, "orders"); got != "`orders`" {
+		t.Fatalf("got %s", got)
+	}
+	if got := DropDatabaseSQL(pg, "dback_verify_1"); got != `DROP DATABASE IF EXISTS "dback_verify_1";` {
+		t.Fatalf("got %s", got)
+	}
+}
@@ -2,6 +2,8 @@ package db
 
 import (
 	"strings"
+
+	"dback/models"
 )
 
 type QueryResult struct {
@@ -10,6 +12,36 @@ type QueryResult struct {
 	Message string
 }
 
+// ParseQueryOutput parses the output of BuildQueryCommand for the profile's server.
+func ParseQueryOutput(p models.Profile, out string) QueryResult {
+	if postgres(p) {
+		return ParsePsqlOutput(out)
+	}
+	return ParseMySQLBatchOutput(out)
+}
+
+// psqlMessagePrefixes start the server messages psql prints among the results.
+var psqlMessagePrefixes = []string{"NOTICE:", "WARNING:", "INFO:", "DETAIL:", "HINT:"}
+
+// ParsePsqlOutput parses psql output in the pgQueryArgs format, which matches mysql --batch
+// once server messages are dropped.
+func ParsePsqlOutput(out string) QueryResult {
+	var kept []string
+	for _, line := range strings.Split(out, "\n") {
+		message := false
+		for _, prefix := range psqlMessagePrefixes {
+			if strings.HasPrefix(strings.TrimSpace(line), prefix) {
+				message = true
+				break
+			}
+		}
+		if !message {
+			kept = append(kept, line)
+		}
+	}
+	return ParseMySQLBatchOutput(strings.Join(kept, "\n"))
+}
+
 func ParseMySQLBatchOutput(out string) QueryResult {
 	out = strings.TrimSpace(out)
 	if out == "" {
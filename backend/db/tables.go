@@ -0,0 +1,197 @@
+package db
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dback/models"
+)
+
+// TablePlan is a TableFilter resolved against the tables of one database.
+type TablePlan struct {
+	Excluded   []string        // left out of the dump
+	SchemaOnly []string        // dumped with --no-data
+	Filtered   []FilteredTable // dumped with --where
+}
+
+// FilteredTable is a table dumped with only the rows matching Where.
+type FilteredTable struct {
+	Table string
+	Where string
+}
+
+// Empty reports whether the plan dumps every table with its rows.
+func (t TablePlan) Empty() bool {
+	return len(t.Excluded) == 0 && len(t.SchemaOnly) == 0 && len(t.Filtered) == 0
+}
+
+// FilteredNames lists the tables dumped with a row filter.
+func (t TablePlan) FilteredNames() []string {
+	if len(t.Filtered) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(t.Filtered))
+	for _, f := range t.Filtered {
+		names = append(names, f.Table)
+	}
+	return names
+}
+
+// BuildListTablesCommand lists the tables and views of the profile's TargetDBName.
+func BuildListTablesCommand(p models.Profile) (string, error) {
+	if postgres(p) {
+		return BuildQueryCommand(p, pgListTablesQuery, true)
+	}
+	return BuildQueryCommand(p, "SHOW TABLES", true)
+}
+
+// ParseTableList reads SHOW TABLES batch output, skipping the Tables_in_<db> header and
+// client warnings.
+func ParseTableList(out string) []string {
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.Contains(line, "[Warning]") || strings.HasPrefix(line, "Warning:") {
+			continue
+		}
+		if len(names) == 0 && strings.HasPrefix(line, "Tables_in_") {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names
+}
+
+// ValidateTableFilter checks that every pattern is a valid glob.
+func ValidateTableFilter(f *models.TableFilter) error {
+	if f == nil {
+		return nil
+	}
+	for _, list := range [][]string{f.Include, f.Exclude, f.SchemaOnly} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid table pattern %q", pattern)
+			}
+		}
+	}
+	for _, r := range f.Rows {
+		if err := validateRowFilter(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRowFilter(r models.RowFilter) error {
+	pattern := strings.TrimSpace(r.Table)
+	if pattern == "" {
+		return fmt.Errorf("row filter needs a table")
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid table pattern %q in row filter", r.Table)
+	}
+	where := strings.TrimSpace(r.Where)
+	switch {
+	case where != "" && (r.Column != "" || r.Days != 0):
+		return fmt.Errorf("row filter for %s: give either a WHERE condition or a column and a number of days", pattern)
+	case where != "":
+		if strings.Contains(where, ";") {
+			return fmt.Errorf("row filter for %s: the WHERE condition must be a single expression", pattern)
+		}
+	case strings.TrimSpace(r.Column) == "" || r.Days <= 0:
+		return fmt.Errorf("row filter for %s needs a WHERE condition, or a column and a positive number of days", pattern)
+	}
+	return nil
+}
+
+// lastDaysPattern matches the "last N days by column" form of a row filter line.
+var lastDaysPattern = regexp.MustCompile(`(?i)^last\s+(\d+)\s+days?\s+by\s+(\S+)$`)
+
+// ParseRowFilters reads row filters written one per line as "table: condition", where the
+// condition is an SQL WHERE expression ("wp_comments: comment_approved = '1'") or "last N
+// days by column" ("orders: last 90 days by created_at"). Blank lines and lines starting
+// with # are skipped.
+func ParseRowFilters(text string) ([]models.RowFilter, error) {
+	var filters []models.RowFilter
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		table, cond, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(table) == "" || strings.TrimSpace(cond) == "" {
+			return nil, fmt.Errorf("row filter on line %d: write it as table: condition", n+1)
+		}
+		f := models.RowFilter{Table: strings.TrimSpace(table)}
+		cond = strings.TrimSpace(cond)
+		if m := lastDaysPattern.FindStringSubmatch(cond); m != nil {
+			f.Days, _ = strconv.Atoi(m[1])
+			f.Column = m[2]
+		} else {
+			f.Where = cond
+		}
+		if err := validateRowFilter(f); err != nil {
+			return nil, fmt.Errorf("row filter on line %d: %w", n+1, err)
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// FormatRowFilters writes filters in the form ParseRowFilters reads.
+func FormatRowFilters(filters []models.RowFilter) string {
+	lines := make([]string, 0, len(filters))
+	for _, f := range filters {
+		cond := f.Where
+		if cond == "" {
+			cond = fmt.Sprintf("last %d days by %s", f.Days, f.Column)
+		}
+		lines = append(lines, f.Table+": "+cond)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// RowCondition is the SQL condition a row filter dumps rows with.
+func RowCondition(r models.RowFilter) string {
+	if where := strings.TrimSpace(r.Where); where != "" {
+		return where
+	}
+	return fmt.Sprintf("%s >= NOW() - INTERVAL %d DAY", SQLIdent(strings.TrimSpace(r.Column)), r.Days)
+}
+
+// PlanTables resolves a filter against the database's tables. Lists are sorted.
+func PlanTables(f models.TableFilter, tables []string) TablePlan {
+	var plan TablePlan
+	for _, table := range tables {
+		switch {
+		case len(f.Include) > 0 && !matchesAnyTable(f.Include, table), matchesAnyTable(f.Exclude, table):
+			plan.Excluded = append(plan.Excluded, table)
+		case matchesAnyTable(f.SchemaOnly, table):
+			plan.SchemaOnly = append(plan.SchemaOnly, table)
+		default:
+			for _, r := range f.Rows {
+				if ok, _ := path.Match(strings.TrimSpace(r.Table), table); ok {
+					plan.Filtered = append(plan.Filtered, FilteredTable{Table: table, Where: RowCondition(r)})
+					break
+				}
+			}
+		}
+	}
+	sort.Strings(plan.Excluded)
+	sort.Strings(plan.SchemaOnly)
+	sort.Slice(plan.Filtered, func(i, j int) bool { return plan.Filtered[i].Table < plan.Filtered[j].Table })
+	return plan
+}
+
+func matchesAnyTable(patterns []string, table string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.TrimSpace(pattern), table); ok {
+			return true
+		}
+	}
+	return false
+}
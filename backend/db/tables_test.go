@@ -0,0 +1,128 @@
+package db
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestParseTableList(t *testing.T) {
+	got := ParseTableList("Tables_in_shop\norders\nsessions\n")
+	if want := []string{"orders", "sessions"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestPlanTables(t *testing.T) {
+	tables := []string{"orders", "users", "log_2024", "log_2025", "sessions", "cache_pages"}
+	plan := PlanTables(models.TableFilter{
+		Exclude:    []string{"log_*", "cache_*"},
+		SchemaOnly: []string{"sessions"},
+	}, tables)
+	if want := []string{"cache_pages", "log_2024", "log_2025"}; !reflect.DeepEqual(plan.Excluded, want) {
+		t.Fatalf("excluded = %v, want %v", plan.Excluded, want)
+	}
+	if want := []string{"sessions"}; !reflect.DeepEqual(plan.SchemaOnly, want) {
+		t.Fatalf("schema only = %v, want %v", plan.SchemaOnly, want)
+	}
+
+	include := PlanTables(models.TableFilter{Include: []string{"orders", "users"}, SchemaOnly: []string{"log_*"}}, tables)
+	if want := []string{"cache_pages", "log_2024", "log_2025", "sessions"}; !reflect.DeepEqual(include.Excluded, want) {
+		t.Fatalf("tables outside include should be excluded, got %v", include.Excluded)
+	}
+	if len(include.SchemaOnly) != 0 {
+		t.Fatalf("excluded tables must not also be schema-only: %v", include.SchemaOnly)
+	}
+}
+
+func TestValidateTableFilter(t *testing.T) {
+	if err := ValidateTableFilter(&models.TableFilter{Exclude: []string{"log_["}}); err == nil {
+		t.Fatal("expected invalid pattern error")
+	}
+	if err := ValidateTableFilter(&models.TableFilter{Exclude: []string{"log_*"}}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBuildFilteredExportCommand(t *testing.T) {
+	p := models.Profile{DBType: models.DBTypeMariaDB, DBUser: "root", DBPassword: "x", TargetDBName: "shop"}
+	cmd := BuildFilteredExportCommand(p, TablePlan{Excluded: []string{"log_2024"}, SchemaOnly: []string{"sessions"}})
+	for _, want := range []string{"--ignore-table=", "shop.log_2024", "shop.sessions", "--no-data"} {
+		if !strings.Contains(cmd, want) {
+			t.Fatalf("export command missing %q: %s", want, cmd)
+		}
+	}
+	if plain := BuildExportCommand(p); strings.Contains(plain, "--ignore-table") || strings.Contains(plain, "--no-data") {
+		t.Fatalf("unfiltered export must dump every table: %s", plain)
+	}
+}
+
+func TestPlanTablesRowFilters(t *testing.T) {
+	tables := []string{"orders", "order_items", "sessions", "users"}
+	plan := PlanTables(models.TableFilter{
+		SchemaOnly: []string{"sessions"},
+		Rows: []models.RowFilter{
+			{Table: "order*", Column: "created_at", Days: 30},
+			{Table: "orders", Where: "status = 'paid'"},
+			{Table: "sess*", Where: "1=0"},
+		},
+	}, tables)
+	want := []FilteredTable{
+		{Table: "order_items", Where: "`created_at` >= NOW() - INTERVAL 30 DAY"},
+		{Table: "orders", Where: "`created_at` >= NOW() - INTERVAL 30 DAY"},
+	}
+	if !reflect.DeepEqual(plan.Filtered, want) {
+		t.Fatalf("filtered = %+v, want %+v", plan.Filtered, want)
+	}
+
+	cmd := BuildFilteredExportCommand(models.Profile{DBType: models.DBTypeMariaDB, DBUser: "root", TargetDBName: "shop"}, plan)
+	for _, want := range []string{"shop.orders", "--where=", "INTERVAL 30 DAY", "--skip-routines"} {
+		if !strings.Contains(cmd, want) {
+			t.Fatalf("export command missing %q: %s", want, cmd)
+		}
+	}
+}
+
+func TestValidateRowFilters(t *testing.T) {
+	for _, bad := range []models.RowFilter{
+		{Table: "orders"},
+		{Where: "id > 10"},
+		{Table: "orders", Where: "id > 10", Days: 7},
+		{Table: "orders", Column: "created_at"},
+		{Table: "orders", Where: "1; DROP TABLE users"},
+	} {
+		if err := ValidateTableFilter(&models.TableFilter{Rows: []models.RowFilter{bad}}); err == nil {
+			t.Errorf("row filter %+v accepted", bad)
+		}
+	}
+	ok := []models.RowFilter{{Table: "orders", Where: "id > 10"}, {Table: "log_*", Column: "logged_at", Days: 7}}
+	if err := ValidateTableFilter(&models.TableFilter{Rows: ok}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseRowFilters(t *testing.T) {
+	text := "# subset for developers\norders: last 90 days by created_at\nwp_comments: comment_approved = '1' AND comment_type <> 'spam'\n"
+	filters, err := ParseRowFilters(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.RowFilter{
+		{Table: "orders", Column: "created_at", Days: 90},
+		{Table: "wp_comments", Where: "comment_approved = '1' AND comment_type <> 'spam'"},
+	}
+	if !reflect.DeepEqual(filters, want) {
+		t.Fatalf("filters = %+v", filters)
+	}
+	again, err := ParseRowFilters(FormatRowFilters(filters))
+	if err != nil || !reflect.DeepEqual(again, want) {
+		t.Fatalf("round trip = %+v, %v", again, err)
+	}
+	for _, bad := range []string{"orders", "orders:", ": id > 1", "orders: last 0 days by created_at"} {
+		if _, err := ParseRowFilters(bad); err == nil {
+			t.Errorf("ParseRowFilters(%q) accepted", bad)
+		}
+	}
+}
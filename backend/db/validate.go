@@ -15,6 +15,7 @@ var (
 	dbUserPattern      = regexp.MustCompile(`^[a-zA-Z0-9_@.-]{1,64}$`)
 	dbHostPattern      = regexp.MustCompile(`^[a-zA-Z0-9_.:-]{1,253}$`)
 	dbPortPattern      = regexp.MustCompile(`^[0-9]{1,5}$`)
+	dbNamePattern      = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
 )
 
 func ValidateContainerID(id string) error {
@@ -65,6 +66,23 @@ func ValidateDBPort(port string) error {
 	return nil
 }
 
+// ValidateDatabaseName checks a database name typed at restore time. Only letters, digits,
+// underscores and hyphens are accepted, so the name needs no escaping in the import's
+// USE rewrite; system schemas are refused.
+func ValidateDatabaseName(name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("database name is required")
+	}
+	if !dbNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid database name %q: use letters, digits, _ and - (up to 64)", name)
+	}
+	if IsSystemDatabase(name) {
+		return fmt.Errorf("%q is a system database", name)
+	}
+	return nil
+}
+
 func ValidateProfileForRemoteOps(p models.Profile) error {
 	if err := ValidateDBUser(p.DBUser); err != nil {
 		return fmt.Errorf("db user: %w", err)
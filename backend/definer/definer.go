@@ -0,0 +1,217 @@
+// Package definer rewrites the DEFINER clauses of views, triggers, routines and events in a
+// plain-text MySQL dump as it is restored. mysqldump always writes them, naming the user
+// that created each object, and a destination without that user refuses to create the
+// object or leaves it unusable.
+package definer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"dback/models"
+)
+
+// ParseMode reads a mode as the CLI and settings write it: keep (or empty), strip, rewrite
+// or invoker.
+func ParseMode(s string) (models.DefinerMode, error) {
+	switch mode := models.DefinerMode(strings.ToLower(strings.TrimSpace(s))); mode {
+	case models.DefinerKeep, "keep":
+		return models.DefinerKeep, nil
+	case models.DefinerStrip, models.DefinerRewrite, models.DefinerInvoker:
+		return mode, nil
+	}
+	return models.DefinerKeep, fmt.Errorf("unknown DEFINER mode %q: use keep, strip, rewrite or invoker", s)
+}
+
+// Object is a view, trigger, routine or event whose DEFINER was rewritten.
+type Object struct {
+	Kind string // view, trigger, procedure, function or event
+	Name string
+}
+
+func (o Object) String() string {
+	return o.Kind + " " + o.Name
+}
+
+// Report describes what a restore did to DEFINER clauses.
+type Report struct {
+	Mode models.DefinerMode
+	// Objects lists the rewritten objects in dump order.
+	Objects []Object
+	// Definers lists the distinct users the dump named, as user@host.
+	Definers []string
+}
+
+// Summary is a one-line description of the report for the activity log.
+func (r Report) Summary() string {
+	if len(r.Objects) == 0 {
+		return "No DEFINER clauses in this backup"
+	}
+	names := make([]string, 0, len(r.Objects))
+	for _, o := range r.Objects {
+		names = append(names, o.String())
+	}
+	was := strings.Join(r.Definers, ", ")
+	var action string
+	switch r.Mode {
+	case models.DefinerRewrite:
+		action = "Set DEFINER to the restoring user (was " + was + ") on"
+	case models.DefinerInvoker:
+		action = "Removed DEFINER (" + was + ") and set SQL SECURITY INVOKER on"
+	default:
+		action = "Removed DEFINER (" + was + ") from"
+	}
+	return fmt.Sprintf("%s %d object(s): %s", action, len(r.Objects), strings.Join(names, ", "))
+}
+
+const (
+	identPart = "(?:`(?:[^`]|``)*`|'(?:[^'\\\\]|\\\\.|'')*'|\"[^\"]*\"|[A-Za-z0-9_.$%-]+)"
+	// objectLookahead is how many lines after a DEFINER the object name may follow: mysqldump
+	// writes a view's DEFINER on the line before its VIEW `name`.
+	objectLookahead = 3
+	// maxLine is how much of a line is matched at once; longer lines are row inserts.
+	maxLine = 256 << 10
+)
+
+var (
+	definerClause = regexp.MustCompile(`(\s*)DEFINER\s*=\s*(CURRENT_USER(?:\s*\(\s*\))?|` + identPart + `@` + identPart + `)`)
+	// emptyVersioned is what stripping leaves of a trigger's /*!50017 DEFINER=...*/.
+	emptyVersioned  = regexp.MustCompile(`\s*/\*!\d{5}\s*\*/`)
+	securityDefiner = regexp.MustCompile(`\bSQL SECURITY DEFINER\b`)
+	objectName      = regexp.MustCompile("\\b(VIEW|TRIGGER|PROCEDURE|FUNCTION|EVENT)\\s+((?:`(?:[^`]|``)+`\\.)?`(?:[^`]|``)+`|[A-Za-z0-9_$.]+)")
+
+	prefixInsert  = []byte("INSERT ")
+	prefixReplace = []byte("REPLACE ")
+)
+
+// filter rewrites one dump.
+type filter struct {
+	mode     models.DefinerMode
+	report   Report
+	definers map[string]bool
+	// pending counts down the lines left to find the name of an object whose DEFINER was
+	// rewritten; zero when none is awaited.
+	pending int
+}
+
+// Apply copies a plain-text dump from r to w with DEFINER clauses handled as mode says.
+// INSERT and REPLACE statements are copied untouched. DefinerKeep copies the dump as it is.
+func Apply(r io.Reader, w io.Writer, mode models.DefinerMode) (Report, error) {
+	f := &filter{mode: mode, report: Report{Mode: mode}, definers: map[string]bool{}}
+	out := bufio.NewWriterSize(w, 64<<10)
+	br := bufio.NewReaderSize(r, maxLine)
+	atLineStart, copying := true, false
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			startsLine := atLineStart
+			atLineStart = line[len(line)-1] == '\n'
+			if startsLine {
+				t := bytes.TrimLeft(line, " \t")
+				copying = bytes.HasPrefix(t, prefixInsert) || bytes.HasPrefix(t, prefixReplace)
+			}
+			var werr error
+			if copying || !startsLine || mode == models.DefinerKeep {
+				_, werr = out.Write(line)
+			} else {
+				_, werr = out.WriteString(f.rewrite(string(line)))
+			}
+			if werr != nil {
+				return Report{}, werr
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Report{}, err
+		}
+	}
+	if f.pending > 0 {
+		f.addObject("object", "(unnamed)")
+	}
+	if err := out.Flush(); err != nil {
+		return Report{}, err
+	}
+	return f.report, nil
+}
+
+// rewrite handles the DEFINER clauses of one line and notes the objects they belong to.
+func (f *filter) rewrite(line string) string {
+	loc := definerClause.FindStringSubmatchIndex(line)
+	if loc == nil {
+		f.findPendingObject(line)
+		if f.mode == models.DefinerInvoker {
+			line = securityDefiner.ReplaceAllString(line, "SQL SECURITY INVOKER")
+		}
+		return line
+	}
+	if f.pending > 0 {
+		f.addObject("object", "(unnamed)")
+	}
+	f.noteDefiner(line[loc[4]:loc[5]])
+	f.pending = objectLookahead + 1
+	f.findPendingObject(line[loc[1]:])
+	switch f.mode {
+	case models.DefinerRewrite:
+		line = definerClause.ReplaceAllString(line, "${1}DEFINER=CURRENT_USER")
+	default:
+		line = definerClause.ReplaceAllString(line, "")
+		line = emptyVersioned.ReplaceAllString(line, "")
+		if f.mode == models.DefinerInvoker {
+			line = securityDefiner.ReplaceAllString(line, "SQL SECURITY INVOKER")
+		}
+	}
+	return line
+}
+
+// findPendingObject looks for the name of the object awaiting one in text.
+func (f *filter) findPendingObject(text string) {
+	if f.pending == 0 {
+		return
+	}
+	if m := objectName.FindStringSubmatch(text); m != nil {
+		f.pending = 0
+		f.addObject(strings.ToLower(m[1]), unquote(m[2]))
+		return
+	}
+	f.pending--
+	if f.pending == 0 {
+		f.addObject("object", "(unnamed)")
+	}
+}
+
+func (f *filter) addObject(kind, name string) {
+	f.pending = 0
+	f.report.Objects = append(f.report.Objects, Object{Kind: kind, Name: name})
+}
+
+func (f *filter) noteDefiner(definer string) {
+	if strings.HasPrefix(strings.ToUpper(definer), "CURRENT_USER") {
+		definer = "CURRENT_USER"
+	} else if user, host, ok := strings.Cut(definer, "@"); ok {
+		definer = unquote(user) + "@" + unquote(host)
+	}
+	if !f.definers[definer] {
+		f.definers[definer] = true
+		f.report.Definers = append(f.report.Definers, definer)
+	}
+}
+
+// unquote removes the quotes of an identifier or a schema.name pair.
+func unquote(name string) string {
+	if len(name) >= 2 && (name[0] == '\'' || name[0] == '"') && name[len(name)-1] == name[0] {
+		return name[1 : len(name)-1]
+	}
+	name = strings.ReplaceAll(name, "`.`", ".")
+	name = strings.TrimSuffix(strings.TrimPrefix(name, "`"), "`")
+	return strings.ReplaceAll(name, "``", "`")
+}
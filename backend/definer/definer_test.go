@@ -0,0 +1,94 @@
+package definer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+const testDump = "/*!50001 CREATE ALGORITHM=UNDEFINED */\n" +
+	"/*!50013 DEFINER=`root`@`localhost` SQL SECURITY DEFINER */\n" +
+	"/*!50001 VIEW `order_totals` AS select 1 AS `n` */;\n" +
+	"/*!50003 CREATE*/ /*!50017 DEFINER=`root`@`localhost`*/ /*!50003 TRIGGER `orders_bi` BEFORE INSERT ON `orders` FOR EACH ROW SET NEW.total = 0 */;;\n" +
+	"CREATE DEFINER=`app`@`%` PROCEDURE `refresh`()\n" +
+	"    SQL SECURITY DEFINER\n" +
+	"BEGIN SELECT 1; END ;;\n" +
+	"/*!50106 CREATE*/ /*!50117 DEFINER=`root`@`localhost`*/ /*!50106 EVENT `nightly` ON SCHEDULE EVERY 1 DAY DO DELETE FROM t */ ;;\n" +
+	"INSERT INTO `notes` VALUES (1,'CREATE DEFINER=`root`@`localhost` VIEW x');\n"
+
+func apply(t *testing.T, mode models.DefinerMode) (string, Report) {
+	t.Helper()
+	var out bytes.Buffer
+	report, err := Apply(strings.NewReader(testDump), &out, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), report
+}
+
+func TestApplyStrip(t *testing.T) {
+	out, report := apply(t, models.DefinerStrip)
+	want := "/*!50001 CREATE ALGORITHM=UNDEFINED */\n" +
+		"/*!50013 SQL SECURITY DEFINER */\n" +
+		"/*!50001 VIEW `order_totals` AS select 1 AS `n` */;\n" +
+		"/*!50003 CREATE*/ /*!50003 TRIGGER `orders_bi` BEFORE INSERT ON `orders` FOR EACH ROW SET NEW.total = 0 */;;\n" +
+		"CREATE PROCEDURE `refresh`()\n" +
+		"    SQL SECURITY DEFINER\n" +
+		"BEGIN SELECT 1; END ;;\n" +
+		"/*!50106 CREATE*/ /*!50106 EVENT `nightly` ON SCHEDULE EVERY 1 DAY DO DELETE FROM t */ ;;\n" +
+		"INSERT INTO `notes` VALUES (1,'CREATE DEFINER=`root`@`localhost` VIEW x');\n"
+	if out != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", out, want)
+	}
+	wantSummary := "Removed DEFINER (root@localhost, app@%) from 4 object(s): view order_totals, trigger orders_bi, procedure refresh, event nightly"
+	if report.Summary() != wantSummary {
+		t.Fatalf("summary = %s", report.Summary())
+	}
+}
+
+func TestApplyRewrite(t *testing.T) {
+	out, report := apply(t, models.DefinerRewrite)
+	for _, part := range []string{
+		"/*!50013 DEFINER=CURRENT_USER SQL SECURITY DEFINER */",
+		"/*!50017 DEFINER=CURRENT_USER*/",
+		"CREATE DEFINER=CURRENT_USER PROCEDURE `refresh`()",
+		"VALUES (1,'CREATE DEFINER=`root`@`localhost` VIEW x')",
+	} {
+		if !strings.Contains(out, part) {
+			t.Fatalf("missing %q in:\n%s", part, out)
+		}
+	}
+	if len(report.Objects) != 4 || !strings.HasPrefix(report.Summary(), "Set DEFINER to the restoring user") {
+		t.Fatalf("summary = %s", report.Summary())
+	}
+}
+
+func TestApplyInvoker(t *testing.T) {
+	out, _ := apply(t, models.DefinerInvoker)
+	if strings.Contains(out, "SQL SECURITY DEFINER") || strings.Count(out, "SQL SECURITY INVOKER") != 2 {
+		t.Fatalf("got:\n%s", out)
+	}
+	if strings.Count(out, "DEFINER=") != 1 {
+		t.Fatalf("only the row data should keep a DEFINER:\n%s", out)
+	}
+}
+
+func TestApplyKeep(t *testing.T) {
+	out, report := apply(t, models.DefinerKeep)
+	if out != testDump || len(report.Objects) != 0 {
+		t.Fatalf("keep should copy the dump as it is:\n%s", out)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	for in, want := range map[string]models.DefinerMode{"": models.DefinerKeep, "keep": models.DefinerKeep, " Strip ": models.DefinerStrip, "invoker": models.DefinerInvoker} {
+		if got, err := ParseMode(in); err != nil || got != want {
+			t.Fatalf("ParseMode(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseMode("drop"); err == nil {
+		t.Fatal("expected an error")
+	}
+}
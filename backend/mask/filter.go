@@ -0,0 +1,508 @@
+package mask
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"dback/models"
+)
+
+// Column is one column of one table that masking rewrites. Values counts the non-NULL
+// values that were (or, in a preview, would be) replaced.
+type Column struct {
+	Table    string
+	Column   string
+	Strategy models.MaskStrategy
+	Values   int64
+}
+
+// Report describes what masking a dump does.
+type Report struct {
+	Columns []Column
+	// Unused lists the rules whose table pattern matched no table in the dump.
+	Unused []models.MaskRule
+	// Problems are rules that matched a table but could not be applied to it. Apply fails
+	// on the first one; Preview lists them all.
+	Problems []string
+}
+
+// Summary is a one-line description of the report for the activity log.
+func (r Report) Summary() string {
+	if len(r.Columns) == 0 {
+		return "No column in this backup matches the masking rules"
+	}
+	parts := make([]string, 0, len(r.Columns))
+	for _, c := range r.Columns {
+		parts = append(parts, fmt.Sprintf("%s.%s %s (%d)", c.Table, c.Column, c.Strategy, c.Values))
+	}
+	return fmt.Sprintf("Masked %d column(s): %s", len(r.Columns), strings.Join(parts, ", "))
+}
+
+// Apply copies a plain-text dump from r to w, replacing the values of every column the
+// rules match in INSERT and REPLACE statements. Column positions come from each table's
+// CREATE TABLE or the statement's column list; a matching table whose columns cannot be
+// placed, or that lacks a rule's column, fails the copy rather than passing rows through
+// unmasked.
+func Apply(r io.Reader, w io.Writer, rules []models.MaskRule) (Report, error) {
+	return run(r, w, rules, false)
+}
+
+// Preview reads a dump and reports which columns Apply would rewrite, collecting the
+// problems Apply would fail on.
+func Preview(r io.Reader, rules []models.MaskRule) (Report, error) {
+	return run(r, io.Discard, rules, true)
+}
+
+// maxLine is how much of a line is buffered at once; longer lines (extended INSERTs) are
+// handled in chunks.
+const maxLine = 256 << 10
+
+var (
+	prefixCreateTable   = []byte("CREATE TABLE ")
+	prefixCreateTableIf = []byte("CREATE TABLE IF NOT EXISTS ")
+	prefixInsert        = []byte("INSERT ")
+	prefixReplace       = []byte("REPLACE ")
+	tokenInto           = []byte("INTO ")
+	tokenValues         = []byte("VALUES")
+)
+
+func run(r io.Reader, w io.Writer, rules []models.MaskRule, preview bool) (Report, error) {
+	if err := Validate(rules); err != nil {
+		return Report{}, err
+	}
+	f := &filter{
+		out:     bufio.NewWriterSize(w, 64<<10),
+		rules:   rules,
+		used:    make([]bool, len(rules)),
+		preview: preview,
+		tables:  map[string]*table{},
+	}
+	br := bufio.NewReaderSize(r, maxLine)
+	atLineStart := true
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			if ferr := f.feed(line, atLineStart); ferr != nil {
+				return Report{}, ferr
+			}
+			atLineStart = line[len(line)-1] == '\n'
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Report{}, err
+		}
+	}
+	if err := f.out.Flush(); err != nil {
+		return Report{}, err
+	}
+	return f.report(), nil
+}
+
+type filter struct {
+	out     *bufio.Writer
+	rules   []models.MaskRule
+	used    []bool
+	preview bool
+	// tables holds the columns of every table created so far and the masks that apply.
+	tables   map[string]*table
+	order    []*Column
+	problems []string
+	// create is the table whose CREATE TABLE column lines are being read.
+	create *table
+	// insert is the INSERT statement being rewritten, which may span chunks and lines.
+	insert *insertState
+}
+
+type table struct {
+	name    string
+	columns []column
+	masks   []*columnMask          // by column position; nil when the table needs no masking
+	byName  map[string]*columnMask // by lower-case column name
+	matched bool                   // some rule's pattern matches the table
+}
+
+type column struct {
+	name   string
+	maxLen int
+}
+
+type columnMask struct {
+	rule   models.MaskRule
+	maxLen int
+	report *Column
+}
+
+func (f *filter) feed(line []byte, atLineStart bool) error {
+	if f.insert != nil {
+		return f.feedInsert(line)
+	}
+	if !atLineStart {
+		_, err := f.out.Write(line)
+		return err
+	}
+	t := bytes.TrimLeft(line, " \t")
+	if f.create != nil && startsStatement(t) {
+		// A CREATE TABLE without the usual closing line ends at the next statement.
+		if err := f.endCreate(); err != nil {
+			return err
+		}
+	}
+	switch {
+	case f.create != nil:
+		if err := f.createLine(t); err != nil {
+			return err
+		}
+	case bytes.HasPrefix(t, prefixCreateTableIf), bytes.HasPrefix(t, prefixCreateTable):
+		prefix := prefixCreateTable
+		if bytes.HasPrefix(t, prefixCreateTableIf) {
+			prefix = prefixCreateTableIf
+		}
+		name, _ := readIdent(t[len(prefix):])
+		f.create = &table{name: name}
+	case bytes.HasPrefix(t, prefixInsert), bytes.HasPrefix(t, prefixReplace):
+		handled, err := f.startInsert(line)
+		if handled || err != nil {
+			return err
+		}
+	}
+	_, err := f.out.Write(line)
+	return err
+}
+
+func startsStatement(t []byte) bool {
+	return bytes.HasPrefix(t, prefixInsert) || bytes.HasPrefix(t, prefixReplace) || bytes.HasPrefix(t, prefixCreateTable)
+}
+
+// createLine reads one line of a CREATE TABLE body: a column definition, an index, or the
+// closing line that ends the table.
+func (f *filter) createLine(t []byte) error {
+	if len(t) > 0 && t[0] == '`' {
+		name, n := readIdent(t)
+		f.create.columns = append(f.create.columns, column{name: name, maxLen: charLength(t[n:])})
+		return nil
+	}
+	if len(t) > 0 && t[0] == ')' {
+		return f.endCreate()
+	}
+	return nil
+}
+
+func (f *filter) endCreate() error {
+	tbl := f.create
+	f.create = nil
+	f.tables[tbl.name] = tbl
+	return f.planTable(tbl)
+}
+
+// planTable works out which of a table's columns the rules mask. A table whose CREATE
+// TABLE was not seen has no column positions; its masks can only be placed by an INSERT's
+// own column list.
+func (f *filter) planTable(tbl *table) error {
+	for i, r := range f.rules {
+		if ok, _ := path.Match(r.Table, tbl.name); !ok {
+			continue
+		}
+		f.used[i] = true
+		tbl.matched = true
+		if tbl.byName == nil {
+			tbl.byName = map[string]*columnMask{}
+		}
+		key := strings.ToLower(r.Column)
+		if tbl.byName[key] != nil {
+			// The first rule for a column wins.
+			continue
+		}
+		pos := columnIndex(tbl.columns, r.Column)
+		if pos < 0 && tbl.columns != nil {
+			if strings.ContainsAny(r.Table, "*?[") {
+				// A pattern covers tables without the column too.
+				continue
+			}
+			if err := f.problem(fmt.Sprintf("masking rule %s: table %s has no column %s", ruleName(r), tbl.name, r.Column)); err != nil {
+				return err
+			}
+			continue
+		}
+		c := &Column{Table: tbl.name, Column: r.Column, Strategy: r.Strategy}
+		m := &columnMask{rule: r, report: c}
+		if pos >= 0 {
+			c.Column, m.maxLen = tbl.columns[pos].name, tbl.columns[pos].maxLen
+			if tbl.masks == nil {
+				tbl.masks = make([]*columnMask, len(tbl.columns))
+			}
+			tbl.masks[pos] = m
+		}
+		tbl.byName[key] = m
+		f.order = append(f.order, c)
+	}
+	return nil
+}
+
+// problem records why a rule cannot be applied. Apply stops on it; Preview carries on.
+func (f *filter) problem(msg string) error {
+	if !f.preview {
+		return errors.New(msg)
+	}
+	f.problems = append(f.problems, msg)
+	return nil
+}
+
+// startInsert begins rewriting an INSERT into a masked table. It reports false when the
+// statement is passed through as it is.
+func (f *filter) startInsert(line []byte) (bool, error) {
+	into := bytes.Index(line, tokenInto)
+	if into < 0 {
+		return false, nil
+	}
+	rest := line[into+len(tokenInto):]
+	name, n := readIdent(rest)
+	tbl := f.tables[name]
+	if tbl == nil {
+		tbl = &table{name: name}
+		f.tables[name] = tbl
+		if err := f.planTable(tbl); err != nil {
+			return false, err
+		}
+	}
+	if !tbl.matched {
+		return false, nil
+	}
+	values := bytes.Index(rest, tokenValues)
+	if values < n {
+		return false, f.problem(fmt.Sprintf("masking: cannot read the INSERT statement of table %s", name))
+	}
+	masks := tbl.masks
+	if list, ok := columnList(rest[n:values]); ok {
+		masks = listMasks(tbl, list)
+	} else if tbl.columns == nil {
+		msg := fmt.Sprintf("masking: the backup has no CREATE TABLE for %s, so its columns cannot be placed", name)
+		if err := f.problem(msg); err != nil {
+			return false, err
+		}
+		// Report it once; the preview passes the table's rows through.
+		tbl.matched = false
+		return false, nil
+	}
+	if masks == nil {
+		return false, nil
+	}
+	head := into + len(tokenInto) + values + len(tokenValues)
+	if _, err := f.out.Write(line[:head]); err != nil {
+		return true, err
+	}
+	f.insert = &insertState{masks: masks}
+	return true, f.feedInsert(line[head:])
+}
+
+func (f *filter) feedInsert(p []byte) error {
+	n, done := f.insert.feed(f.out, p)
+	if !done {
+		return nil
+	}
+	f.insert = nil
+	_, err := f.out.Write(p[n:])
+	return err
+}
+
+func (f *filter) report() Report {
+	var rep Report
+	for _, c := range f.order {
+		rep.Columns = append(rep.Columns, *c)
+	}
+	for i, r := range f.rules {
+		if !f.used[i] {
+			rep.Unused = append(rep.Unused, r)
+		}
+	}
+	rep.Problems = f.problems
+	return rep
+}
+
+// listMasks maps the masks of tbl onto an INSERT's own column list.
+func listMasks(tbl *table, list []string) []*columnMask {
+	var masks []*columnMask
+	for i, name := range list {
+		m := tbl.byName[strings.ToLower(name)]
+		if m == nil {
+			continue
+		}
+		if masks == nil {
+			masks = make([]*columnMask, len(list))
+		}
+		masks[i] = m
+	}
+	return masks
+}
+
+// insertState rewrites the row tuples of one INSERT ... VALUES (...),(...); statement. Values
+// of masked columns are buffered whole; everything else is copied as it arrives.
+type insertState struct {
+	masks  []*columnMask
+	inRow  bool
+	nested int // parentheses inside a value
+	field  int
+	quote  byte
+	escape bool
+	cur    *columnMask
+	val    []byte
+}
+
+// feed processes p and reports how much of it belonged to the statement and whether the
+// statement ended.
+func (s *insertState) feed(w *bufio.Writer, p []byte) (int, bool) {
+	for i, b := range p {
+		if !s.inRow {
+			_ = w.WriteByte(b)
+			switch b {
+			case '(':
+				s.inRow, s.field, s.nested = true, 0, 0
+				s.cur = s.mask(0)
+			case ';':
+				return i + 1, true
+			}
+			continue
+		}
+		if s.quote != 0 {
+			switch {
+			case s.escape:
+				s.escape = false
+			case b == '\\':
+				s.escape = true
+			case b == s.quote:
+				s.quote = 0
+			}
+			s.value(w, b)
+			continue
+		}
+		switch b {
+		case '\'', '"':
+			s.quote = b
+		case '(':
+			s.nested++
+		case ')':
+			if s.nested == 0 {
+				s.endField(w)
+				s.inRow = false
+				_ = w.WriteByte(b)
+				continue
+			}
+			s.nested--
+		case ',':
+			if s.nested == 0 {
+				s.endField(w)
+				_ = w.WriteByte(b)
+				s.field++
+				s.cur = s.mask(s.field)
+				continue
+			}
+		}
+		s.value(w, b)
+	}
+	return len(p), false
+}
+
+func (s *insertState) mask(field int) *columnMask {
+	if field < len(s.masks) {
+		return s.masks[field]
+	}
+	return nil
+}
+
+func (s *insertState) value(w *bufio.Writer, b byte) {
+	if s.cur == nil {
+		_ = w.WriteByte(b)
+		return
+	}
+	s.val = append(s.val, b)
+}
+
+func (s *insertState) endField(w *bufio.Writer) {
+	if s.cur == nil {
+		return
+	}
+	out, changed := maskValue(s.cur.rule, s.val, s.cur.maxLen)
+	_, _ = w.Write(out)
+	if changed {
+		s.cur.report.Values++
+	}
+	s.val = s.val[:0]
+}
+
+func columnIndex(columns []column, name string) int {
+	for i, c := range columns {
+		if strings.EqualFold(c.name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// columnList reads the "(`a`,`b`)" column list between an INSERT's table name and VALUES.
+func columnList(t []byte) ([]string, bool) {
+	t = bytes.TrimSpace(t)
+	if len(t) < 2 || t[0] != '(' || t[len(t)-1] != ')' {
+		return nil, false
+	}
+	var names []string
+	for _, part := range bytes.Split(t[1:len(t)-1], []byte(",")) {
+		name, _ := readIdent(bytes.TrimSpace(part))
+		names = append(names, name)
+	}
+	return names, true
+}
+
+// charLength reads N from the type of a "varchar(N)" or "char(N)" column definition, or 0.
+func charLength(def []byte) int {
+	rest := strings.ToLower(strings.TrimSpace(string(def)))
+	for _, typ := range []string{"varchar(", "char("} {
+		if strings.HasPrefix(rest, typ) {
+			end := strings.IndexByte(rest, ')')
+			if end < 0 {
+				return 0
+			}
+			n, _ := strconv.Atoi(rest[len(typ):end])
+			return n
+		}
+	}
+	return 0
+}
+
+// readIdent reads the identifier t starts with, backquoted (doubled backquotes escape) or
+// bare, and returns it with the number of bytes it took.
+func readIdent(t []byte) (string, int) {
+	if len(t) == 0 {
+		return "", 0
+	}
+	if t[0] != '`' {
+		end := bytes.IndexAny(t, " ;*\r\n(,")
+		if end < 0 {
+			end = len(t)
+		}
+		return string(t[:end]), end
+	}
+	var name []byte
+	for i := 1; i < len(t); i++ {
+		if t[i] != '`' {
+			name = append(name, t[i])
+			continue
+		}
+		if i+1 < len(t) && t[i+1] == '`' {
+			name = append(name, '`')
+			i++
+			continue
+		}
+		return string(name), i + 1
+	}
+	return string(name), len(t)
+}
@@ -0,0 +1,159 @@
+package mask
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+const dumpSample = "/*!40101 SET NAMES utf8mb4 */;\n" +
+	"DROP TABLE IF EXISTS `wp_users`;\n" +
+	"CREATE TABLE `wp_users` (\n" +
+	"  `ID` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `user_login` varchar(60) NOT NULL DEFAULT '',\n" +
+	"  `user_email` varchar(100) NOT NULL DEFAULT '',\n" +
+	"  `display_name` varchar(8) NOT NULL DEFAULT '',\n" +
+	"  PRIMARY KEY (`ID`)\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n" +
+	"INSERT INTO `wp_users` VALUES (1,'admin','ann@shop.example','Ann O\\'Neil'),(2,'bob','bob@mail.example','Bob (Sales), Inc'),(3,'eve',NULL,'Eve');\n" +
+	"CREATE TABLE `wp_posts` (\n" +
+	"  `ID` bigint(20) unsigned NOT NULL,\n" +
+	"  `post_title` text NOT NULL\n" +
+	");\n" +
+	"INSERT INTO `wp_posts` VALUES (1,'ann@shop.example');\n"
+
+func rulesFor(t *testing.T, text string) []models.MaskRule {
+	t.Helper()
+	rules, err := ParseRules(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+func TestApplyMasksColumns(t *testing.T) {
+	rules := rulesFor(t, "wp_users.user_email keep_domain\nwp_users.display_name fixed Masked User")
+	var out bytes.Buffer
+	rep, err := Apply(strings.NewReader(dumpSample), &out, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, leaked := range []string{"'ann@shop.example','Ann", "bob@mail.example", "Neil", "Bob (Sales)"} {
+		if strings.Contains(got, leaked) {
+			t.Errorf("output still contains %q", leaked)
+		}
+	}
+	annLocal := fakeLocalPart("ann@shop.example")
+	want := "INSERT INTO `wp_users` VALUES (1,'admin','" + annLocal + "@shop.example','Masked U'),(2,'bob','" +
+		fakeLocalPart("bob@mail.example") + "@mail.example','Masked U'),(3,'eve',NULL,'Masked U');\n"
+	if !strings.Contains(got, want) {
+		t.Fatalf("masked INSERT not found; output:\n%s", got)
+	}
+	// Tables without rules pass through untouched, even when they hold the same text.
+	if !strings.Contains(got, "INSERT INTO `wp_posts` VALUES (1,'ann@shop.example');\n") {
+		t.Error("unmasked table was changed")
+	}
+	if len(rep.Columns) != 2 || rep.Columns[0].Values != 2 || rep.Columns[1].Values != 3 {
+		t.Fatalf("report = %+v", rep.Columns)
+	}
+}
+
+func TestApplyStatementSpanningChunks(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE `t` (\n  `id` int,\n  `email` varchar(255)\n);\nINSERT INTO `t` VALUES ")
+	rows := maxLine/20 + 100
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("(1,'someone@example.org')")
+	}
+	b.WriteString(";\nSELECT 1;\n")
+	var out bytes.Buffer
+	rep, err := Apply(strings.NewReader(b.String()), &out, rulesFor(t, "t.email null"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "someone@") {
+		t.Fatal("a row in a later chunk was not masked")
+	}
+	if rep.Columns[0].Values != int64(rows) || !strings.HasSuffix(out.String(), ";\nSELECT 1;\n") {
+		t.Fatalf("values = %d, want %d", rep.Columns[0].Values, rows)
+	}
+}
+
+func TestApplyUsesInsertColumnList(t *testing.T) {
+	dump := "INSERT INTO `t` (`email`, `id`) VALUES ('a@b.example',1);\n"
+	var out bytes.Buffer
+	if _, err := Apply(strings.NewReader(dump), &out, rulesFor(t, "t.EMAIL hash")); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "a@b.example") || !strings.Contains(out.String(), "',1);") {
+		t.Fatalf("output = %q", out.String())
+	}
+}
+
+func TestApplyRefusesUnplaceableColumns(t *testing.T) {
+	cases := map[string]string{
+		"missing column":  "wp_users.phone null",
+		"no CREATE TABLE": "wp_comments.comment_author_email null",
+	}
+	dump := dumpSample + "INSERT INTO `wp_comments` VALUES (1,'x@y.example');\n"
+	for name, rule := range cases {
+		if _, err := Apply(strings.NewReader(dump), &bytes.Buffer{}, rulesFor(t, rule)); err == nil {
+			t.Errorf("%s: Apply passed rows through unmasked", name)
+		}
+	}
+}
+
+func TestPreviewReportsColumns(t *testing.T) {
+	rules := rulesFor(t, "wp_*.user_email fake_email\nwp_users.phone null\nwp_orders.email hash")
+	rep, err := Preview(strings.NewReader(dumpSample), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Columns) != 1 || rep.Columns[0].Table != "wp_users" || rep.Columns[0].Values != 2 {
+		t.Fatalf("columns = %+v", rep.Columns)
+	}
+	if len(rep.Problems) != 1 || !strings.Contains(rep.Problems[0], "no column phone") {
+		t.Fatalf("problems = %v", rep.Problems)
+	}
+	if len(rep.Unused) != 1 || rep.Unused[0].Table != "wp_orders" {
+		t.Fatalf("unused = %v", rep.Unused)
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	text := "# staging scrub\nwp_users.user_email keep_domain\n\ncustomers.name fixed Jane Doe\n"
+	rules := rulesFor(t, text)
+	if len(rules) != 2 || rules[1].Value != "Jane Doe" || rules[1].Strategy != models.MaskFixed {
+		t.Fatalf("rules = %+v", rules)
+	}
+	if again := rulesFor(t, FormatRules(rules)); len(again) != 2 || again[1] != rules[1] {
+		t.Fatalf("FormatRules does not round-trip: %+v", again)
+	}
+	for _, bad := range []string{"users email null", "users.email scramble", "users.email null x", "[.email null"} {
+		if _, err := ParseRules(bad); err == nil {
+			t.Errorf("ParseRules(%q) accepted a bad rule", bad)
+		}
+	}
+}
+
+func TestMaskValueEscapes(t *testing.T) {
+	rule := models.MaskRule{Strategy: models.MaskFixed, Value: "it's\n\\"}
+	out, changed := maskValue(rule, []byte("'x'"), 0)
+	if !changed || string(out) != `'it\'s\n\\'` {
+		t.Fatalf("maskValue = %s", out)
+	}
+	if got := sqldump.Unquote([]byte(`_utf8mb4'a\'b''c'`)); got != "a'b'c" {
+		t.Fatalf("unquote = %q", got)
+	}
+	// The escaped output is read back as the same text.
+	if got := sqldump.Unquote(out); got != rule.Value {
+		t.Fatalf("round trip = %q", got)
+	}
+}
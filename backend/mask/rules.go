@@ -0,0 +1,152 @@
+// Package mask anonymizes the rows of a plain-text MySQL dump as it is restored: it rewrites
+// the values of chosen columns in INSERT statements, streaming, so the data that reaches
+// the destination server is already masked.
+package mask
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+// Strategies lists the masking strategies in the order the UI offers them.
+var Strategies = []models.MaskStrategy{
+	models.MaskFakeEmail, models.MaskKeepDomain, models.MaskHash, models.MaskNull, models.MaskFixed,
+}
+
+func knownStrategy(s models.MaskStrategy) bool {
+	for _, known := range Strategies {
+		if s == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that every rule names a valid table pattern, a column and a known strategy.
+func Validate(rules []models.MaskRule) error {
+	for _, r := range rules {
+		if strings.TrimSpace(r.Table) == "" || strings.TrimSpace(r.Column) == "" {
+			return fmt.Errorf("masking rule %s needs a table and a column", ruleName(r))
+		}
+		if _, err := path.Match(r.Table, ""); err != nil {
+			return fmt.Errorf("invalid table pattern %q in masking rule", r.Table)
+		}
+		if !knownStrategy(r.Strategy) {
+			return fmt.Errorf("masking rule %s: unknown strategy %q", ruleName(r), r.Strategy)
+		}
+	}
+	return nil
+}
+
+// ParseRules reads masking rules written one per line as "table.column strategy [value]",
+// for example "wp_users.user_email keep_domain" or "customers.name fixed Jane Doe". Blank
+// lines and lines starting with # are skipped.
+func ParseRules(text string) ([]models.MaskRule, error) {
+	var rules []models.MaskRule
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, " ", 3)
+		target := fields[0]
+		dot := strings.LastIndexByte(target, '.')
+		if dot <= 0 || dot == len(target)-1 || len(fields) < 2 {
+			return nil, fmt.Errorf("masking rule on line %d: write it as table.column strategy [value]", n)
+		}
+		r := models.MaskRule{
+			Table:    target[:dot],
+			Column:   target[dot+1:],
+			Strategy: models.MaskStrategy(strings.ToLower(strings.TrimSpace(fields[1]))),
+		}
+		if len(fields) == 3 {
+			r.Value = strings.TrimSpace(fields[2])
+		}
+		if r.Value != "" && r.Strategy != models.MaskFixed {
+			return nil, fmt.Errorf("masking rule on line %d: only the fixed strategy takes a value", n)
+		}
+		rules = append(rules, r)
+	}
+	if err := Validate(rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// FormatRules writes rules in the form ParseRules reads.
+func FormatRules(rules []models.MaskRule) string {
+	lines := make([]string, 0, len(rules))
+	for _, r := range rules {
+		line := ruleName(r) + " " + string(r.Strategy)
+		if r.Value != "" {
+			line += " " + r.Value
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func ruleName(r models.MaskRule) string {
+	return r.Table + "." + r.Column
+}
+
+// maskValue returns the replacement for one SQL value token (a quoted string, number, NULL
+// or hex literal) and whether it changed. maxLen, when known, is the column's character
+// length; generated text is cut to it so strict SQL modes accept the row.
+func maskValue(r models.MaskRule, raw []byte, maxLen int) ([]byte, bool) {
+	if isNull(raw) {
+		return raw, false
+	}
+	if r.Strategy == models.MaskNull {
+		return []byte("NULL"), true
+	}
+	text := sqldump.Unquote(raw)
+	var out string
+	switch r.Strategy {
+	case models.MaskFixed:
+		out = r.Value
+	case models.MaskHash:
+		sum := sha256.Sum256([]byte(text))
+		out = hex.EncodeToString(sum[:])
+	case models.MaskKeepDomain:
+		out = fakeEmail(text)
+		if at := strings.LastIndexByte(text, '@'); at > 0 {
+			out = fakeLocalPart(text) + text[at:]
+		}
+	default: // MaskFakeEmail
+		out = fakeEmail(text)
+	}
+	return sqldump.Quote(truncate(out, maxLen)), true
+}
+
+// fakeLocalPart derives the mailbox name from the original value, so the same address is
+// masked the same way in every table and joins on it still match.
+func fakeLocalPart(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return "user-" + hex.EncodeToString(sum[:4])
+}
+
+func fakeEmail(text string) string {
+	return fakeLocalPart(text) + "@example.com"
+}
+
+func truncate(s string, maxLen int) string {
+	if maxLen <= 0 || utf8.RuneCountInString(s) <= maxLen {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:maxLen])
+}
+
+func isNull(raw []byte) bool {
+	return strings.EqualFold(strings.TrimSpace(string(raw)), "NULL")
+}
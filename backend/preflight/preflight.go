@@ -24,6 +24,7 @@ type Result struct {
 	DBVersion      string
 	DumpTool       string
 	DockerStatus   string
+	PhysicalTool   db.PhysicalTool  // set when the profile asks for physical backups
 	DiskPaths      map[string]int64 // path -> free KB
 	RequiredKB     int64
 	SelectedTmpDir string
@@ -93,6 +94,8 @@ func parsePreflightOutput(out string, r *Result) {
 			section = "disk"
 		case "===REQUIRED_KB===":
 			section = "required"
+		case "===PHYSICAL===":
+			section = "physical"
 		default:
 			if line == "" {
 				continue
@@ -140,6 +143,10 @@ func parsePreflightOutput(out string, r *Result) {
 				if kb, err := strconv.ParseInt(line, 10, 64); err == nil {
 					r.RequiredKB = kb
 				}
+			case "physical":
+				if t := db.ParsePhysicalTool(line); t.Found() {
+					r.PhysicalTool = t
+				}
 			}
 		}
 	}
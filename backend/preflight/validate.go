@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"strings"
 
+	"dback/backend/codec"
 	"dback/models"
 )
 
@@ -44,7 +45,7 @@ func lineHasDumpTool(line string) bool {
 	if low == "no-dump-tool" || low == "no dump tool" {
 		return false
 	}
-	return strings.Contains(low, "mysqldump") || strings.Contains(low, "mariadb-dump")
+	return strings.Contains(low, "mysqldump") || strings.Contains(low, "mariadb-dump") || strings.Contains(low, "pg_dump")
 }
 
 func lineHasClient(line string) bool {
@@ -52,10 +53,30 @@ func lineHasClient(line string) bool {
 		return false
 	}
 	low := strings.ToLower(strings.TrimSpace(line))
-	if low == "no-mysql-client" || low == "no mysql client" {
+	if low == "no-mysql-client" || low == "no mysql client" || low == "no-psql-client" {
 		return false
 	}
-	return strings.Contains(low, "mysql") || strings.Contains(low, "mariadb")
+	return strings.Contains(low, "mysql") || strings.Contains(low, "mariadb") || strings.Contains(low, "psql")
+}
+
+// toolNames names the dump tool and client preflight looks for on the profile's host.
+func toolNames(p models.Profile) (dump, client string) {
+	if p.UsesPostgreSQL() {
+		return "pg_dump", "psql client"
+	}
+	return "mysqldump or mariadb-dump", "mysql or mariadb client"
+}
+
+// containerToolError describes a tool missing inside the profile's container.
+func containerToolError(status DockerStatus, p models.Profile) string {
+	if !p.UsesPostgreSQL() {
+		return status.Error()
+	}
+	dump, client := toolNames(p)
+	if status == ContainerDumpToolMissing {
+		return dump + " missing inside container"
+	}
+	return client + " missing inside container"
 }
 
 func validateParsedOutput(out string, p models.Profile, requiredKB int64) error {
@@ -88,6 +109,8 @@ func validateParsedOutput(out string, p models.Profile, requiredKB int64) error
 			section = "required"
 		case "===RESULT===":
 			section = "result"
+		case "===PHYSICAL===":
+			section = "physical"
 		default:
 			if line == "" {
 				continue
@@ -105,8 +128,7 @@ func validateParsedOutput(out string, p models.Profile, requiredKB int64) error
 					hasClient = true
 				}
 			case "tools":
-				low := strings.ToLower(line)
-				if strings.Contains(low, "gzip") || strings.Contains(low, "zstd") {
+				if tool := codec.Tool(p.Compression); tool == "" || strings.Contains(strings.ToLower(line), tool) {
 					hasCompress = true
 				}
 			case "docker":
@@ -129,7 +151,7 @@ func validateParsedOutput(out string, p models.Profile, requiredKB int64) error
 				if line == "running" {
 					dockerStatus = "running"
 				}
-				if (line == "no-mysql-client" || line == "no mysql client") && !hasClient {
+				if (line == "no-mysql-client" || line == "no mysql client" || line == "no-psql-client") && !hasClient {
 					dockerStatus = "clientmissing"
 				}
 				if (line == "no-dump-tool" || line == "no dump tool") && !hasDumpTool {
@@ -165,22 +187,23 @@ func validateParsedOutput(out string, p models.Profile, requiredKB int64) error
 	}
 	clientReported := dockerStatus == "clientmissing"
 	dumpReported := dockerStatus == "dumpmissing"
+	dumpTool, clientTool := toolNames(p)
 	if !hasDumpTool && !dumpReported {
 		if p.IsDocker {
-			fails = append(fails, ContainerDumpToolMissing.Error())
+			fails = append(fails, containerToolError(ContainerDumpToolMissing, p))
 		} else {
-			fails = append(fails, "mysqldump or mariadb-dump not found")
+			fails = append(fails, dumpTool+" not found")
 		}
 	}
 	if !hasClient && !clientReported {
 		if p.IsDocker {
-			fails = append(fails, ContainerClientMissing.Error())
+			fails = append(fails, containerToolError(ContainerClientMissing, p))
 		} else {
-			fails = append(fails, "mysql or mariadb client not found")
+			fails = append(fails, clientTool+" not found")
 		}
 	}
-	if !hasCompress {
-		fails = append(fails, "gzip or zstd not found")
+	if !hasCompress && codec.Tool(p.Compression) != "" {
+		fails = append(fails, fmt.Sprintf("%s not found (needed for %s compression)", codec.Tool(p.Compression), codec.Normalize(p.Compression)))
 	}
 
 	if p.IsDocker {
@@ -193,11 +216,11 @@ func validateParsedOutput(out string, p models.Profile, requiredKB int64) error
 			fails = append(fails, ContainerNotFound.Error())
 		case "clientmissing":
 			if !hasClient {
-				fails = append(fails, ContainerClientMissing.Error())
+				fails = append(fails, containerToolError(ContainerClientMissing, p))
 			}
 		case "dumpmissing":
 			if !hasDumpTool {
-				fails = append(fails, ContainerDumpToolMissing.Error())
+				fails = append(fails, containerToolError(ContainerDumpToolMissing, p))
 			}
 		case "running":
 			// ok
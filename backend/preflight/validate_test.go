@@ -258,3 +258,95 @@ msg=`
 		t.Fatalf("expected probe exit/out in details, got %q", details)
 	}
 }
+
+func TestParsePreflightOutputReadsPhysicalTool(t *testing.T) {
+	out := `===OS===
+Linux host
+===DB===
+mariadb Ver 15.1
+mariadb-dump Ver 10.11
+===TOOLS===
+gzip 1.12
+===PHYSICAL===
+mariadb-backup mbstream
+===DISK===
+/dev/sda1|1048576|/tmp
+===WRITE===
+ok|/tmp
+===REQUIRED_KB===
+524288
+===RESULT===
+fail=0
+msg=`
+	result := Result{DiskPaths: make(map[string]int64)}
+	parsePreflightOutput(out, &result)
+	if result.PhysicalTool.Backup != "mariadb-backup" || result.PhysicalTool.Stream != "mbstream" {
+		t.Fatalf("PhysicalTool = %+v", result.PhysicalTool)
+	}
+	if strings.Contains(result.DumpTool, "backup") {
+		t.Fatalf("physical tool leaked into DumpTool: %q", result.DumpTool)
+	}
+	p := models.Profile{DBType: models.DBTypeMariaDB, PhysicalBackup: true}
+	if err := validateParsedOutput(out, p, 524288); err != nil {
+		t.Fatalf("expected pass, got %v", err)
+	}
+
+	result = Result{DiskPaths: make(map[string]int64)}
+	parsePreflightOutput(strings.Replace(out, "mariadb-backup mbstream", "no-physical-tool", 1), &result)
+	if result.PhysicalTool.Found() {
+		t.Fatalf("no tool expected, got %+v", result.PhysicalTool)
+	}
+}
+
+func TestValidateParsedOutputRequiresProfileCodecTool(t *testing.T) {
+	out := `===OS===
+Linux host
+===DB===
+mysql Ver 8.0
+mysqldump Ver 8.0
+===TOOLS===
+gzip 1.12
+===DISK===
+/dev/sda1|1048576|/tmp
+===WRITE===
+ok|/tmp
+===RESULT===
+fail=0
+msg=`
+	p := models.Profile{DBType: models.DBTypeMySQL, Compression: models.CompressionXz}
+	err := validateParsedOutput(out, p, 524288)
+	if err == nil || !strings.Contains(err.Error(), "xz not found") {
+		t.Fatalf("expected missing xz, got %v", err)
+	}
+	p.Compression = models.CompressionNone
+	if err := validateParsedOutput(out, p, 524288); err != nil {
+		t.Fatalf("plain dumps need no codec tool, got %v", err)
+	}
+}
+
+func TestValidateParsedOutputPostgreSQL(t *testing.T) {
+	out := `===OS===
+Linux db 6.8.0 x86_64 GNU/Linux
+===DB===
+psql (PostgreSQL) 16.2
+%s
+===TOOLS===
+gzip 1.10
+===DISK===
+/dev/sda1|1048576|/tmp
+===WRITE===
+ok|/tmp
+===REQUIRED_KB===
+524288
+===RESULT===
+fail=0
+msg=`
+	p := models.Profile{DBType: models.DBTypePostgreSQL}
+	if err := validateParsedOutput(strings.Replace(out, "%s", "pg_dump (PostgreSQL) 16.2", 1), p, 524288); err != nil {
+		t.Fatalf("expected pass, got %v", err)
+	}
+	err := validateParsedOutput(strings.Replace(out, "%s", "", 1), p, 524288)
+	if err == nil || !strings.Contains(err.Error(), "pg_dump not found") {
+		t.Fatalf("expected missing pg_dump, got %v", err)
+	}
+}
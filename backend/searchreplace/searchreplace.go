@@ -0,0 +1,393 @@
+// Package searchreplace rewrites text in the rows of a plain-text MySQL dump as it is
+// restored, the way a WordPress migration moves a site to a new URL: every string value of
+// every INSERT is searched, and PHP-serialized values are rewritten with their string
+// lengths corrected so WordPress can still unserialize them.
+package searchreplace
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+// Validate checks that every pair searches for something and changes it.
+func Validate(pairs []models.ReplacePair) error {
+	for _, p := range pairs {
+		if p.From == "" {
+			return fmt.Errorf("search/replace %q: the text to search for is empty", p.From+" => "+p.To)
+		}
+		if p.From == p.To {
+			return fmt.Errorf("search/replace %q replaces the text with itself", p.From)
+		}
+	}
+	return nil
+}
+
+// pairSeparator separates the search and replacement texts of a line.
+const pairSeparator = "=>"
+
+// ParsePairs reads search/replace pairs written one per line as "from => to", for example
+// "https://example.com => https://staging.example.com". Blank lines and lines starting
+// with # are skipped.
+func ParsePairs(text string) ([]models.ReplacePair, error) {
+	var pairs []models.ReplacePair
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		from, to, ok := strings.Cut(line, pairSeparator)
+		if !ok {
+			return nil, fmt.Errorf("search/replace on line %d: write it as from => to", n)
+		}
+		pairs = append(pairs, models.ReplacePair{From: strings.TrimSpace(from), To: strings.TrimSpace(to)})
+	}
+	if err := Validate(pairs); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// FormatPairs writes pairs in the form ParsePairs reads.
+func FormatPairs(pairs []models.ReplacePair) string {
+	lines := make([]string, 0, len(pairs))
+	for _, p := range pairs {
+		lines = append(lines, p.From+" "+pairSeparator+" "+p.To)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// SiteURLPairs returns the pairs that move a WordPress site from sourceURL to destURL: the
+// URLs themselves and their JSON-escaped forms (https:\/\/...), which block editor content
+// and plugin settings store. It returns nil when either URL is unknown or they are the same.
+func SiteURLPairs(sourceURL, destURL string) []models.ReplacePair {
+	from := strings.TrimRight(strings.TrimSpace(sourceURL), "/")
+	to := strings.TrimRight(strings.TrimSpace(destURL), "/")
+	if from == "" || to == "" || from == to {
+		return nil
+	}
+	pairs := []models.ReplacePair{{From: from, To: to}}
+	escFrom, escTo := strings.ReplaceAll(from, "/", `\/`), strings.ReplaceAll(to, "/", `\/`)
+	if escFrom != from {
+		pairs = append(pairs, models.ReplacePair{From: escFrom, To: escTo})
+	}
+	return pairs
+}
+
+// TableCount is how many replacements were made in one table's rows.
+type TableCount struct {
+	Table        string
+	Replacements int64
+}
+
+// Report describes what a search/replace changed.
+type Report struct {
+	// Tables lists the tables with at least one replacement, by name.
+	Tables []TableCount
+}
+
+// Total is the number of replacements in all tables.
+func (r Report) Total() int64 {
+	var total int64
+	for _, t := range r.Tables {
+		total += t.Replacements
+	}
+	return total
+}
+
+// Summary is a one-line description of the report for the activity log.
+func (r Report) Summary() string {
+	if len(r.Tables) == 0 {
+		return "Search/replace found nothing to replace"
+	}
+	parts := make([]string, 0, len(r.Tables))
+	for _, t := range r.Tables {
+		parts = append(parts, fmt.Sprintf("%s (%d)", t.Table, t.Replacements))
+	}
+	return fmt.Sprintf("Made %d replacement(s) in %d table(s): %s", r.Total(), len(r.Tables), strings.Join(parts, ", "))
+}
+
+// Apply copies a plain-text dump from r to w, making the replacements in every quoted value
+// of its INSERT and REPLACE statements. Statements other than row inserts (CREATE TABLE,
+// views, routines) are copied as they are.
+func Apply(r io.Reader, w io.Writer, pairs []models.ReplacePair) (Report, error) {
+	if err := Validate(pairs); err != nil {
+		return Report{}, err
+	}
+	f := &filter{
+		out:    bufio.NewWriterSize(w, 64<<10),
+		r:      &replacer{pairs: pairs},
+		counts: map[string]int64{},
+	}
+	if err := sqldump.Walk(r, f); err != nil {
+		return Report{}, err
+	}
+	if f.quote != 0 {
+		// The dump ended inside a value; write what there is.
+		f.endLiteral()
+	}
+	if err := f.writeHead(); err != nil {
+		return Report{}, err
+	}
+	if err := f.out.Flush(); err != nil {
+		return Report{}, err
+	}
+	return f.report(), nil
+}
+
+// maxHead is how much of an INSERT is read looking for its VALUES keyword; a longer head
+// is not a row insert this package understands, and the statement is copied as it is.
+const maxHead = 256 << 10
+
+var (
+	prefixInsert  = []byte("INSERT ")
+	prefixReplace = []byte("REPLACE ")
+	tokenValues   = []byte("VALUES")
+)
+
+// state is where the filter is in the statement it is reading.
+type state int
+
+const (
+	// atStatement collects the start of a statement until it shows whether it inserts rows.
+	atStatement state = iota
+	// inHead collects an INSERT or REPLACE up to its VALUES keyword.
+	inHead
+	// inRows rewrites the literals of the row tuples up to the closing semicolon.
+	inRows
+	// inOther copies any other statement to the end of the line.
+	inOther
+)
+
+// filter is the sqldump.Visitor that rewrites the string literals of row inserts. It reads
+// the dump statement by statement, whatever chunks Walk hands it: the start of each
+// statement and the head of each insert are buffered whole, literals are buffered whole,
+// and everything else is copied as it arrives.
+type filter struct {
+	out    *bufio.Writer
+	r      *replacer
+	counts map[string]int64
+	state  state
+	// head is the start of the statement while atStatement or inHead, and headQuote the
+	// quote or backtick the head is inside.
+	head      []byte
+	headQuote byte
+	headEsc   bool
+	// table is the table of the INSERT statement being rewritten, which may span chunks.
+	table  string
+	quote  byte
+	escape bool
+	// closing is set after a quote that may end the literal or, doubled, escape itself.
+	closing bool
+	lit     []byte
+}
+
+func (f *filter) Begin(*sqldump.Section) error { return nil }
+func (f *filter) End(*sqldump.Section) error   { return nil }
+
+func (f *filter) Write(sec *sqldump.Section, p []byte) error {
+	for len(p) > 0 {
+		switch f.state {
+		case inOther:
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				_, err := f.out.Write(p)
+				return err
+			}
+			if _, err := f.out.Write(p[:i+1]); err != nil {
+				return err
+			}
+			p = p[i+1:]
+			f.state = atStatement
+		case atStatement:
+			f.head = append(f.head, p[0])
+			p = p[1:]
+			if err := f.startStatement(sec); err != nil {
+				return err
+			}
+		case inHead:
+			f.head = append(f.head, p[0])
+			p = p[1:]
+			if err := f.scanHead(); err != nil {
+				return err
+			}
+		case inRows:
+			p = p[f.feed(p):]
+		}
+	}
+	return nil
+}
+
+// startStatement decides what the statement collected in head is once it can tell.
+func (f *filter) startStatement(sec *sqldump.Section) error {
+	t := bytes.TrimLeft(f.head, " \t\r\n")
+	switch {
+	case len(t) == 0:
+		return nil
+	case bytes.HasPrefix(t, prefixInsert) || bytes.HasPrefix(t, prefixReplace):
+		f.state, f.table = inHead, sec.Name
+		f.headQuote, f.headEsc = 0, false
+		return nil
+	case bytes.HasPrefix(prefixInsert, t) || bytes.HasPrefix(prefixReplace, t):
+		return nil
+	}
+	return f.endHead(inOther)
+}
+
+// scanHead follows the byte just added to an insert's head, skipping quoted names and
+// strings, until the VALUES keyword ends it. A head that ends in a semicolon first (INSERT
+// ... SELECT, INSERT ... SET) has no tuples and is copied as it is.
+func (f *filter) scanHead() error {
+	b := f.head[len(f.head)-1]
+	if f.headQuote != 0 {
+		switch {
+		case f.headEsc:
+			f.headEsc = false
+		case b == '\\' && f.headQuote != '`':
+			f.headEsc = true
+		case b == f.headQuote:
+			f.headQuote = 0
+		}
+		return nil
+	}
+	switch {
+	case b == '\'' || b == '"' || b == '`':
+		f.headQuote = b
+	case b == ';':
+		return f.endHead(atStatement)
+	case !isIdent(b) && endsWithValues(f.head[:len(f.head)-1]):
+		f.quote, f.escape, f.closing = 0, false, false
+		return f.endHead(inRows)
+	case len(f.head) > maxHead:
+		return f.endHead(inOther)
+	}
+	return nil
+}
+
+// endHead writes the collected head and moves on to next; a head that ended its line
+// leaves the filter at the start of the next statement instead of copying that line.
+func (f *filter) endHead(next state) error {
+	if next == inOther && f.head[len(f.head)-1] == '\n' {
+		next = atStatement
+	}
+	f.state = next
+	return f.writeHead()
+}
+
+func (f *filter) writeHead() error {
+	_, err := f.out.Write(f.head)
+	f.head = f.head[:0]
+	return err
+}
+
+// endsWithValues reports whether head ends with the VALUES keyword as a word of its own.
+func endsWithValues(head []byte) bool {
+	n := len(head) - len(tokenValues)
+	if n < 1 || !bytes.EqualFold(head[n:], tokenValues) {
+		return false
+	}
+	return !isIdent(head[n-1])
+}
+
+func isIdent(b byte) bool {
+	return b == '_' || b == '$' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// feed copies the row tuples of an INSERT statement, collecting each quoted literal and
+// writing it back replaced, and returns how much of p it read: all of it, or up to the
+// semicolon that ends the statement. Write errors surface when the buffer is flushed.
+func (f *filter) feed(p []byte) int {
+	for i := 0; i < len(p); i++ {
+		b := p[i]
+		if f.quote != 0 {
+			switch {
+			case f.closing && b == f.quote:
+				f.closing = false
+			case f.closing:
+				f.endLiteral()
+				i-- // b follows the literal
+				continue
+			case f.escape:
+				f.escape = false
+			case b == '\\':
+				f.escape = true
+			case b == f.quote:
+				f.closing = true
+			}
+			f.lit = append(f.lit, b)
+			continue
+		}
+		switch b {
+		case '\'', '"':
+			f.quote, f.lit = b, append(f.lit[:0], b)
+			continue
+		case ';':
+			f.state = atStatement
+			_ = f.out.WriteByte(b)
+			return i + 1
+		}
+		_ = f.out.WriteByte(b)
+	}
+	return len(p)
+}
+
+// endLiteral writes the buffered literal, replaced when it contains a search text.
+func (f *filter) endLiteral() {
+	f.quote, f.escape, f.closing = 0, false, false
+	text := sqldump.Unquote(f.lit)
+	replaced, n := f.r.value(text)
+	if n == 0 {
+		_, _ = f.out.Write(f.lit)
+		return
+	}
+	f.counts[f.table] += int64(n)
+	_, _ = f.out.Write(sqldump.Quote(replaced))
+}
+
+func (f *filter) report() Report {
+	var rep Report
+	for name, n := range f.counts {
+		rep.Tables = append(rep.Tables, TableCount{Table: name, Replacements: n})
+	}
+	sort.Slice(rep.Tables, func(i, j int) bool { return rep.Tables[i].Table < rep.Tables[j].Table })
+	return rep
+}
+
+type replacer struct {
+	pairs []models.ReplacePair
+}
+
+// value returns s with the replacements made and how many were made. A PHP-serialized
+// value is rewritten string by string; anything else is replaced as plain text.
+func (r *replacer) value(s string) (string, int) {
+	if !r.matches(s) {
+		return s, 0
+	}
+	if out, n, ok := r.rewriteSerialized(s); ok {
+		return out, n
+	}
+	n := 0
+	for _, p := range r.pairs {
+		if c := strings.Count(s, p.From); c > 0 {
+			s = strings.ReplaceAll(s, p.From, p.To)
+			n += c
+		}
+	}
+	return s, n
+}
+
+func (r *replacer) matches(s string) bool {
+	for _, p := range r.pairs {
+		if strings.Contains(s, p.From) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package searchreplace
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+var siteMove = []models.ReplacePair{{From: "http://old.example", To: "https://new-site.example"}}
+
+const dumpSample = "/*!40101 SET NAMES utf8mb4 */;\n" +
+	"DROP TABLE IF EXISTS `wp_options`;\n" +
+	"CREATE TABLE `wp_options` (\n" +
+	"  `option_name` varchar(191) NOT NULL DEFAULT 'http://old.example',\n" +
+	"  `option_value` longtext NOT NULL\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n" +
+	"INSERT INTO `wp_options` VALUES ('siteurl','http://old.example'),('widget','a:2:{s:3:\\\"url\\\";s:22:\\\"http://old.example/img\\\";s:4:\\\"size\\\";i:3;}'),('it''s','http://old.example;x');\n" +
+	"DROP TABLE IF EXISTS `wp_posts`;\n" +
+	"CREATE TABLE `wp_posts` (\n" +
+	"  `post_content` longtext NOT NULL\n" +
+	");\n" +
+	"INSERT INTO `wp_posts` VALUES ('<a href=\\\"http://old.example/a\\\">http://old.example/b</a>'),('nothing here');\n"
+
+func TestApplyRewritesRows(t *testing.T) {
+	var out bytes.Buffer
+	rep, err := Apply(strings.NewReader(dumpSample), &out, siteMove)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{
+		// The schema is left alone.
+		"DEFAULT 'http://old.example',\n",
+		"('siteurl','https://new-site.example')",
+		// The serialized string grows from 22 to 28 bytes.
+		"s:28:\\\"https://new-site.example/img\\\";s:4:\\\"size\\\";i:3;}'",
+		// A doubled quote does not end the value, and a semicolon inside it does not end the statement.
+		"('it''s','https://new-site.example;x');\n",
+		"('<a href=\\\"https://new-site.example/a\\\">https://new-site.example/b</a>'),('nothing here');\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output lacks %q:\n%s", want, got)
+		}
+	}
+	want := []TableCount{{Table: "wp_options", Replacements: 3}, {Table: "wp_posts", Replacements: 2}}
+	if len(rep.Tables) != 2 || rep.Tables[0] != want[0] || rep.Tables[1] != want[1] {
+		t.Fatalf("report = %+v, want %+v", rep.Tables, want)
+	}
+	if rep.Total() != 5 {
+		t.Fatalf("total = %d", rep.Total())
+	}
+}
+
+func TestApplyReadsWholeStatements(t *testing.T) {
+	dump := "DROP TABLE IF EXISTS `wp_options`;\n" +
+		"CREATE TABLE `wp_options` (`values` text);\n" +
+		// The head spans lines and names a column `values`.
+		"INSERT INTO `wp_options` (`values`)\nVALUES\n('http://old.example/a'),\n('http://old.example/b');" +
+		// A second statement on the same line.
+		"REPLACE INTO `wp_options` values ('http://old.example/c');\n" +
+		// Copied rows have no tuples to rewrite.
+		"INSERT INTO `wp_options` SELECT 'http://old.example/d';\n" +
+		"/*!40000 ALTER TABLE `wp_options` ENABLE KEYS */;\n"
+	var whole bytes.Buffer
+	rep, err := Apply(strings.NewReader(dump), &whole, siteMove)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := strings.NewReplacer("http://old.example/a", "https://new-site.example/a",
+		"http://old.example/b", "https://new-site.example/b",
+		"http://old.example/c", "https://new-site.example/c").Replace(dump)
+	if whole.String() != want {
+		t.Fatalf("output:\n%s\nwant:\n%s", whole.String(), want)
+	}
+	if rep.Total() != 3 {
+		t.Fatalf("report = %+v", rep.Tables)
+	}
+
+	// Chunks may split a statement anywhere, its head included.
+	var chunked bytes.Buffer
+	f := &filter{out: bufio.NewWriter(&chunked), r: &replacer{pairs: siteMove}, counts: map[string]int64{}}
+	sec := &sqldump.Section{Kind: sqldump.KindTable, Name: "wp_options"}
+	for i := 0; i < len(dump); i++ {
+		if err := f.Write(sec, []byte{dump[i]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.writeHead(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.out.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if chunked.String() != want {
+		t.Fatalf("byte by byte:\n%s", chunked.String())
+	}
+}
+
+func TestApplyWithoutMatchesCopiesDump(t *testing.T) {
+	var out bytes.Buffer
+	rep, err := Apply(strings.NewReader(dumpSample), &out, []models.ReplacePair{{From: "absent.example", To: "x"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != dumpSample {
+		t.Fatalf("dump changed:\n%s", out.String())
+	}
+	if rep.Total() != 0 || rep.Summary() != "Search/replace found nothing to replace" {
+		t.Fatalf("report = %+v", rep)
+	}
+}
+
+func TestRewriteSerialized(t *testing.T) {
+	r := &replacer{pairs: siteMove}
+	cases := []struct{ in, want string }{
+		{`s:18:"http://old.example";`, `s:24:"https://new-site.example";`},
+		{`a:1:{i:0;a:2:{s:1:"u";s:22:"http://old.example/x/y";s:1:"n";N;}}`, `a:1:{i:0;a:2:{s:1:"u";s:28:"https://new-site.example/x/y";s:1:"n";N;}}`},
+		{`O:8:"stdClass":2:{s:4:"home";s:18:"http://old.example";s:1:"b";b:1;}`, `O:8:"stdClass":2:{s:4:"home";s:24:"https://new-site.example";s:1:"b";b:1;}`},
+		// Serialized data stored as a string inside serialized data.
+		{`a:1:{s:1:"v";s:26:"s:18:"http://old.example";";}`, `a:1:{s:1:"v";s:32:"s:24:"https://new-site.example";";}`},
+		// Multi-byte text counts bytes, as PHP does.
+		{`s:21:"é http://old.example";`, `s:27:"é https://new-site.example";`},
+	}
+	for _, c := range cases {
+		got, n := r.value(c.in)
+		if got != c.want || n != 1 {
+			t.Errorf("value(%s) = %s, %d; want %s, 1", c.in, got, n, c.want)
+		}
+	}
+	// A value that only looks serialized is replaced as text.
+	broken := `s:99:"http://old.example";`
+	if got, _ := r.value(broken); got != `s:99:"https://new-site.example";` {
+		t.Errorf("broken serialized value = %s", got)
+	}
+}
+
+func TestParsePairs(t *testing.T) {
+	pairs, err := ParsePairs("# move the site\nhttps://a.example => https://b.example\n\nwp-content/old=>wp-content/new\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []models.ReplacePair{{From: "https://a.example", To: "https://b.example"}, {From: "wp-content/old", To: "wp-content/new"}}
+	if len(pairs) != 2 || pairs[0] != want[0] || pairs[1] != want[1] {
+		t.Fatalf("pairs = %+v", pairs)
+	}
+	again, err := ParsePairs(FormatPairs(pairs))
+	if err != nil || len(again) != 2 || again[1] != want[1] {
+		t.Fatalf("round trip = %+v, %v", again, err)
+	}
+	for _, bad := range []string{"no separator", " => empty", "same => same"} {
+		if _, err := ParsePairs(bad); err == nil {
+			t.Errorf("ParsePairs(%q) accepted", bad)
+		}
+	}
+}
+
+func TestSiteURLPairs(t *testing.T) {
+	pairs := SiteURLPairs("https://shop.example/", "https://staging.shop.example")
+	want := []models.ReplacePair{
+		{From: "https://shop.example", To: "https://staging.shop.example"},
+		{From: `https:\/\/shop.example`, To: `https:\/\/staging.shop.example`},
+	}
+	if len(pairs) != 2 || pairs[0] != want[0] || pairs[1] != want[1] {
+		t.Fatalf("pairs = %+v", pairs)
+	}
+	if SiteURLPairs("https://shop.example", "https://shop.example/") != nil || SiteURLPairs("", "https://b.example") != nil {
+		t.Fatal("expected no pairs for the same or an unknown URL")
+	}
+}
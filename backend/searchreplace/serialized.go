@@ -0,0 +1,186 @@
+package searchreplace
+
+import (
+	"strconv"
+	"strings"
+)
+
+// phpRewriter rewrites PHP-serialized data (serialize() output as WordPress stores it in
+// options and meta), replacing inside its strings and writing each string's new byte length.
+type phpRewriter struct {
+	r   *replacer
+	s   string
+	pos int
+	out strings.Builder
+	n   int
+}
+
+// rewriteSerialized returns s with the replacements made inside its serialized strings and
+// the number made. ok is false when s is not one complete serialized value.
+func (r *replacer) rewriteSerialized(s string) (string, int, bool) {
+	if len(s) < 2 || !strings.ContainsRune("abdiNOsCE", rune(s[0])) || (s[1] != ':' && s[1] != ';') {
+		return "", 0, false
+	}
+	p := &phpRewriter{r: r, s: s}
+	if !p.value() || p.pos != len(s) {
+		return "", 0, false
+	}
+	return p.out.String(), p.n, true
+}
+
+func (p *phpRewriter) value() bool {
+	if p.pos >= len(p.s) {
+		return false
+	}
+	switch p.s[p.pos] {
+	case 'N':
+		return p.copyLiteral("N;")
+	case 'b', 'i', 'd', 'r', 'R':
+		return p.copyScalar()
+	case 's':
+		return p.str()
+	case 'E':
+		// An enum case: copied as it is, its name is not data.
+		start := p.pos
+		if !p.skipPrefix("E:") {
+			return false
+		}
+		if _, ok := p.quoted(); !ok || !p.skipPrefix(";") {
+			return false
+		}
+		p.out.WriteString(p.s[start:p.pos])
+		return true
+	case 'a':
+		start := p.pos
+		if !p.skipPrefix("a:") {
+			return false
+		}
+		count, ok := p.number(':')
+		if !ok || !p.skipPrefix("{") {
+			return false
+		}
+		p.out.WriteString(p.s[start:p.pos])
+		return p.members(count)
+	case 'O':
+		start := p.pos
+		if !p.skipPrefix("O:") {
+			return false
+		}
+		if _, ok := p.quoted(); !ok || !p.skipPrefix(":") {
+			return false
+		}
+		count, ok := p.number(':')
+		if !ok || !p.skipPrefix("{") {
+			return false
+		}
+		p.out.WriteString(p.s[start:p.pos])
+		return p.members(count)
+	case 'C':
+		// A class with its own serialize(): the payload's format is the class's, so it is
+		// copied as it is.
+		start := p.pos
+		if !p.skipPrefix("C:") {
+			return false
+		}
+		if _, ok := p.quoted(); !ok || !p.skipPrefix(":") {
+			return false
+		}
+		size, ok := p.number(':')
+		if !ok || !p.skipPrefix("{") || p.pos+size >= len(p.s) || p.s[p.pos+size] != '}' {
+			return false
+		}
+		p.pos += size + 1
+		p.out.WriteString(p.s[start:p.pos])
+		return true
+	}
+	return false
+}
+
+// members reads count key/value pairs and the closing brace of an array or object.
+func (p *phpRewriter) members(count int) bool {
+	for i := 0; i < 2*count; i++ {
+		if !p.value() {
+			return false
+		}
+	}
+	if !p.skipPrefix("}") {
+		return false
+	}
+	p.out.WriteByte('}')
+	return true
+}
+
+// str rewrites s:N:"...";, running the replacements (and, for serialized data stored inside
+// a string, this rewriter again) over the content.
+func (p *phpRewriter) str() bool {
+	if !p.skipPrefix("s:") {
+		return false
+	}
+	content, ok := p.quoted()
+	if !ok || !p.skipPrefix(";") {
+		return false
+	}
+	replaced, n := p.r.value(content)
+	p.n += n
+	p.out.WriteString("s:")
+	p.out.WriteString(strconv.Itoa(len(replaced)))
+	p.out.WriteString(":\"")
+	p.out.WriteString(replaced)
+	p.out.WriteString("\";")
+	return true
+}
+
+// quoted reads N:"<N bytes>" and returns the bytes.
+func (p *phpRewriter) quoted() (string, bool) {
+	size, ok := p.number(':')
+	if !ok || !p.skipPrefix(`"`) || p.pos+size+1 > len(p.s) || p.s[p.pos+size] != '"' {
+		return "", false
+	}
+	content := p.s[p.pos : p.pos+size]
+	p.pos += size + 1
+	return content, true
+}
+
+// number reads a non-negative decimal terminated by end.
+func (p *phpRewriter) number(end byte) (int, bool) {
+	i := strings.IndexByte(p.s[p.pos:], end)
+	if i <= 0 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(p.s[p.pos : p.pos+i])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	p.pos += i + 1
+	return n, true
+}
+
+// copyScalar copies b:, i:, d:, r: and R: values up to their semicolon.
+func (p *phpRewriter) copyScalar() bool {
+	if p.pos+1 >= len(p.s) || p.s[p.pos+1] != ':' {
+		return false
+	}
+	i := strings.IndexByte(p.s[p.pos:], ';')
+	if i < 0 {
+		return false
+	}
+	p.out.WriteString(p.s[p.pos : p.pos+i+1])
+	p.pos += i + 1
+	return true
+}
+
+func (p *phpRewriter) copyLiteral(lit string) bool {
+	if !p.skipPrefix(lit) {
+		return false
+	}
+	p.out.WriteString(lit)
+	return true
+}
+
+func (p *phpRewriter) skipPrefix(prefix string) bool {
+	if !strings.HasPrefix(p.s[p.pos:], prefix) {
+		return false
+	}
+	p.pos += len(prefix)
+	return true
+}
@@ -0,0 +1,347 @@
+package sqldump
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dback/backend/codec"
+)
+
+// Split archive layout: a tar with one gzip-compressed SQL file per section, in dump order,
+// followed by manifest.json. Concatenating the entries (Join) reproduces the original dump.
+const (
+	FormatName    = "dback-split"
+	FormatVersion = 1
+	ManifestName  = "manifest.json"
+	// SplitExt replaces .sql.gz on split backups.
+	SplitExt = ".split.tar"
+)
+
+// Manifest describes a split archive.
+type Manifest struct {
+	Format           string    `json:"format"`
+	Version          int       `json:"version"`
+	Database         string    `json:"database,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+	ExcludedTables   []string  `json:"excluded_tables,omitempty"`
+	SchemaOnlyTables []string  `json:"schema_only_tables,omitempty"`
+	FilteredTables   []string  `json:"filtered_tables,omitempty"`
+	Entries          []Entry   `json:"entries"`
+}
+
+// Entry is one section file in a split archive. Size is the uncompressed SQL size;
+// SHA256 is over the compressed file as stored in the archive.
+type Entry struct {
+	File           string `json:"file"`
+	Kind           Kind   `json:"kind"`
+	Name           string `json:"name,omitempty"`
+	Rows           int64  `json:"rows"`
+	Size           int64  `json:"size"`
+	CompressedSize int64  `json:"compressed_size"`
+	SHA256         string `json:"sha256"`
+}
+
+// TableRows returns the row count of every base table in the archive.
+func (m Manifest) TableRows() map[string]int64 {
+	rows := make(map[string]int64)
+	for _, e := range m.Entries {
+		if e.Kind == KindTable {
+			rows[e.Name] += e.Rows
+		}
+	}
+	return rows
+}
+
+// IsSplitPath reports whether path names a split archive by its extension.
+func IsSplitPath(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), SplitExt)
+}
+
+// SplitPath returns the split archive path for a compressed .sql backup path.
+func SplitPath(path string) string {
+	return strings.TrimSuffix(codec.TrimExt(path), ".sql") + SplitExt
+}
+
+// SplitFile converts the dump at srcPath, in any codec, into a split archive at dstPath. The archive
+// is written next to dstPath and renamed into place once complete. m supplies the manifest's
+// descriptive fields; its entries are filled in from the dump.
+func SplitFile(srcPath, dstPath string, m Manifest) (Manifest, error) {
+	gz, _, err := codec.Open(srcPath)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("read dump: %w", err)
+	}
+	defer gz.Close()
+
+	part := dstPath + ".part"
+	out, err := os.Create(part)
+	if err != nil {
+		return Manifest{}, err
+	}
+	m, err = Split(gz, out, filepath.Dir(dstPath), m)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(part, dstPath)
+	}
+	if err != nil {
+		os.Remove(part)
+		return Manifest{}, err
+	}
+	return m, nil
+}
+
+// Split reads a plain-text dump from r and writes a split archive to w, staging each section
+// in tmpDir while it is compressed.
+func Split(r io.Reader, w io.Writer, tmpDir string, m Manifest) (Manifest, error) {
+	tw := tar.NewWriter(w)
+	s := &splitter{tw: tw, tmpDir: tmpDir}
+	err := Walk(r, s)
+	if s.tmp != nil {
+		s.tmp.Close()
+		os.Remove(s.tmp.Name())
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+	m.Format = FormatName
+	m.Version = FormatVersion
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now().UTC()
+	}
+	m.Entries = s.entries
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := writeTarEntry(tw, ManifestName, int64(len(data)), strings.NewReader(string(data))); err != nil {
+		return Manifest{}, err
+	}
+	if err := tw.Close(); err != nil {
+		return Manifest{}, err
+	}
+	return m, nil
+}
+
+type splitter struct {
+	tw      *tar.Writer
+	tmpDir  string
+	tmp     *os.File
+	gz      *gzip.Writer
+	hash    hashCounter
+	entries []Entry
+}
+
+func (s *splitter) Begin(sec *Section) error {
+	f, err := os.CreateTemp(s.tmpDir, ".dback-split-*")
+	if err != nil {
+		return err
+	}
+	s.tmp = f
+	s.hash = hashCounter{w: f, h: sha256.New()}
+	if s.gz == nil {
+		s.gz = gzip.NewWriter(&s.hash)
+	} else {
+		s.gz.Reset(&s.hash)
+	}
+	return nil
+}
+
+func (s *splitter) Write(_ *Section, p []byte) error {
+	_, err := s.gz.Write(p)
+	return err
+}
+
+func (s *splitter) End(sec *Section) error {
+	if err := s.gz.Close(); err != nil {
+		return err
+	}
+	f := s.tmp
+	defer func() {
+		f.Close()
+		os.Remove(f.Name())
+		s.tmp = nil
+	}()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	e := Entry{
+		File:           entryFileName(len(s.entries)+1, sec),
+		Kind:           sec.Kind,
+		Name:           sec.Name,
+		Rows:           sec.Rows,
+		Size:           sec.Bytes,
+		CompressedSize: s.hash.n,
+		SHA256:         hex.EncodeToString(s.hash.h.Sum(nil)),
+	}
+	if err := writeTarEntry(s.tw, e.File, e.CompressedSize, f); err != nil {
+		return err
+	}
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+type hashCounter struct {
+	w io.Writer
+	h interface {
+		io.Writer
+		Sum([]byte) []byte
+	}
+	n int64
+}
+
+func (c *hashCounter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.h.Write(p[:n])
+	c.n += int64(n)
+	return n, err
+}
+
+func entryFileName(seq int, sec *Section) string {
+	name := string(sec.Kind)
+	if sec.Name != "" {
+		name += "-" + safeFileName(sec.Name)
+	}
+	return fmt.Sprintf("%04d-%s.sql.gz", seq, name)
+}
+
+func safeFileName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:    name,
+		Mode:    0o644,
+		Size:    size,
+		ModTime: time.Now(),
+		Format:  tar.FormatPAX,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw, r)
+	return err
+}
+
+// ReadManifest returns the manifest of the split archive at path.
+func ReadManifest(path string) (Manifest, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Manifest{}, err
+	}
+	defer f.Close()
+	tr := tar.NewReader(bufio.NewReader(f))
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return Manifest{}, errors.New("split archive has no manifest")
+		}
+		if err != nil {
+			return Manifest{}, fmt.Errorf("read split archive: %w", err)
+		}
+		if hdr.Name != ManifestName {
+			continue
+		}
+		var m Manifest
+		if err := json.NewDecoder(tr).Decode(&m); err != nil {
+			return Manifest{}, fmt.Errorf("read manifest: %w", err)
+		}
+		if m.Format != FormatName {
+			return Manifest{}, fmt.Errorf("not a split archive (format %q)", m.Format)
+		}
+		if m.Version > FormatVersion {
+			return Manifest{}, fmt.Errorf("split archive version %d is newer than supported (%d)", m.Version, FormatVersion)
+		}
+		return m, nil
+	}
+}
+
+// IsSplitArchive reports whether path is a split archive, by extension and manifest.
+func IsSplitArchive(path string) bool {
+	if !IsSplitPath(path) {
+		return false
+	}
+	_, err := ReadManifest(path)
+	return err == nil
+}
+
+// Join writes the entries of the split archive at path to w as one gzip stream (gzip
+// members concatenated), verifying each entry against the manifest. include selects
+// entries; nil includes all of them.
+func Join(path string, w io.Writer, include func(Entry) bool) error {
+	m, err := ReadManifest(path)
+	if err != nil {
+		return err
+	}
+	byFile := make(map[string]Entry, len(m.Entries))
+	for _, e := range m.Entries {
+		byFile[e.File] = e
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	tr := tar.NewReader(bufio.NewReader(f))
+	seen := 0
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("read split archive: %w", err)
+		}
+		e, ok := byFile[hdr.Name]
+		if !ok {
+			continue
+		}
+		seen++
+		dst := io.Discard
+		if include == nil || include(e) {
+			dst = w
+		}
+		h := sha256.New()
+		n, err := io.Copy(io.MultiWriter(dst, h), tr)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.File, err)
+		}
+		if n != e.CompressedSize || hex.EncodeToString(h.Sum(nil)) != e.SHA256 {
+			return fmt.Errorf("%s: checksum does not match the manifest", e.File)
+		}
+	}
+	if seen != len(m.Entries) {
+		return fmt.Errorf("split archive is missing %d of %d entries", len(m.Entries)-seen, len(m.Entries))
+	}
+	return nil
+}
+
+// VerifyArchive checks every entry of the split archive at path against its manifest
+// checksum and returns the manifest.
+func VerifyArchive(path string) (Manifest, error) {
+	if err := Join(path, io.Discard, nil); err != nil {
+		return Manifest{}, err
+	}
+	return ReadManifest(path)
+}
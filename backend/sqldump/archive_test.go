@@ -0,0 +1,114 @@
+package sqldump
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGzip(t *testing.T, path, text string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(text))
+	gz.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func gunzip(t *testing.T, data []byte) string {
+	t.Helper()
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestSplitFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01_01_2026_00_00_00.sql.gz")
+	writeGzip(t, src, mysqldumpSample)
+	dst := SplitPath(src)
+	if !strings.HasSuffix(dst, "shop_01_01_2026_00_00_00.split.tar") {
+		t.Fatalf("split path = %s", dst)
+	}
+
+	m, err := SplitFile(src, dst, Manifest{Database: "shop", ExcludedTables: []string{"log"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Entries) != 8 || m.Entries[1].File != "0002-table-orders.sql.gz" {
+		t.Fatalf("entries = %+v", m.Entries)
+	}
+	if _, err := os.Stat(dst + ".part"); !os.IsNotExist(err) {
+		t.Fatal("partial archive should be renamed into place")
+	}
+	if !IsSplitArchive(dst) || IsSplitArchive(src) {
+		t.Fatal("IsSplitArchive should recognise only the split archive")
+	}
+
+	read, err := ReadManifest(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.Database != "shop" || len(read.ExcludedTables) != 1 {
+		t.Fatalf("manifest = %+v", read)
+	}
+	if rows := read.TableRows(); rows["orders"] != 4 || rows["users"] != 0 || len(rows) != 2 {
+		t.Fatalf("table rows = %v", rows)
+	}
+
+	var joined bytes.Buffer
+	if err := Join(dst, &joined, nil); err != nil {
+		t.Fatal(err)
+	}
+	if gunzip(t, joined.Bytes()) != mysqldumpSample {
+		t.Fatal("joined archive should reproduce the dump")
+	}
+
+	joined.Reset()
+	if err := Join(dst, &joined, func(e Entry) bool { return e.Kind != KindTable || e.Name == "users" }); err != nil {
+		t.Fatal(err)
+	}
+	if text := gunzip(t, joined.Bytes()); strings.Contains(text, "CREATE TABLE `orders`") || !strings.Contains(text, "CREATE TABLE `users`") {
+		t.Fatalf("filtered join = %q", text)
+	}
+}
+
+func TestJoinDetectsCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "db.sql.gz")
+	writeGzip(t, src, mysqldumpSample)
+	dst := SplitPath(src)
+	m, err := SplitFile(src, dst, Manifest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Flip a byte inside the first entry's gzip payload.
+	i := bytes.Index(data, []byte{0x1f, 0x8b})
+	if i < 0 {
+		t.Fatal("no gzip member found")
+	}
+	data[i+int(m.Entries[0].CompressedSize)/2] ^= 0xff
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifyArchive(dst); err == nil || !strings.Contains(err.Error(), m.Entries[0].File) {
+		t.Fatalf("VerifyArchive err = %v, want checksum error for %s", err, m.Entries[0].File)
+	}
+}
@@ -0,0 +1,151 @@
+package sqldump
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// Selection picks the tables Extract keeps. The dump's session header and footer are always
+// kept; views, routines and other tables are dropped.
+type Selection struct {
+	Tables   []string
+	Triggers bool
+}
+
+func (s Selection) set() map[string]bool {
+	m := make(map[string]bool, len(s.Tables))
+	for _, name := range s.Tables {
+		m[name] = true
+	}
+	return m
+}
+
+// Keep reports whether an archive entry or dump section belongs in the extract.
+func (s Selection) Keep(kind Kind, name string) bool {
+	switch kind {
+	case KindHeader, KindFooter:
+		return true
+	case KindTable:
+		return s.set()[name]
+	case KindTrigger:
+		return s.Triggers && s.set()[name]
+	}
+	return false
+}
+
+// Extract copies the selected tables' DROP/CREATE/INSERT statements (and, with
+// sel.Triggers, their triggers) from a plain-text dump to w, between the dump's session
+// header and footer. It returns the selected tables that were found, sorted.
+func Extract(r io.Reader, w io.Writer, sel Selection) ([]string, error) {
+	x := &extractor{w: w, tables: sel.set(), triggers: sel.Triggers, found: map[string]bool{}}
+	if err := Walk(r, x); err != nil {
+		return nil, err
+	}
+	found := make([]string, 0, len(x.found))
+	for name := range x.found {
+		found = append(found, name)
+	}
+	sort.Strings(found)
+	return found, nil
+}
+
+type extractor struct {
+	w        io.Writer
+	tables   map[string]bool
+	triggers bool
+	found    map[string]bool
+	// Sections whose table is not named yet (ifsnop writes SET @saved_cs_client before
+	// CREATE TABLE, and names a trigger's table only in CREATE TRIGGER) are buffered until
+	// the name is known.
+	keep    bool
+	pending bool
+	buf     bytes.Buffer
+}
+
+func (x *extractor) decide(sec *Section) bool {
+	switch sec.Kind {
+	case KindHeader, KindFooter:
+		return true
+	case KindTable:
+		return x.tables[sec.Name]
+	case KindTrigger:
+		return x.triggers && x.tables[sec.Name]
+	}
+	return false
+}
+
+func (x *extractor) Begin(sec *Section) error {
+	x.buf.Reset()
+	x.pending = sec.Name == "" && (sec.Kind == KindTable || (sec.Kind == KindTrigger && x.triggers))
+	x.keep = !x.pending && x.decide(sec)
+	return nil
+}
+
+func (x *extractor) Write(sec *Section, p []byte) error {
+	if x.pending {
+		if sec.Name == "" {
+			x.buf.Write(p)
+			return nil
+		}
+		x.pending = false
+		x.keep = x.decide(sec)
+		if x.keep {
+			if _, err := x.w.Write(x.buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		x.buf.Reset()
+	}
+	if !x.keep || sec.Kind == KindView {
+		return nil
+	}
+	_, err := x.w.Write(p)
+	return err
+}
+
+func (x *extractor) End(sec *Section) error {
+	if x.pending {
+		x.pending = false
+		x.keep = x.decide(sec)
+		if x.keep {
+			if _, err := x.w.Write(x.buf.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	if sec.Kind == KindTable && x.keep {
+		x.found[sec.Name] = true
+	}
+	return nil
+}
+
+// ScanTables lists the base tables in a plain-text dump, in dump order.
+func ScanTables(r io.Reader) ([]string, error) {
+	var s tableScanner
+	if err := Walk(r, &s); err != nil {
+		return nil, err
+	}
+	return s.tables, nil
+}
+
+type tableScanner struct {
+	tables []string
+	seen   map[string]bool
+}
+
+func (s *tableScanner) Begin(*Section) error         { return nil }
+func (s *tableScanner) Write(*Section, []byte) error { return nil }
+func (s *tableScanner) End(sec *Section) error {
+	if sec.Kind != KindTable || sec.Name == "" {
+		return nil
+	}
+	if s.seen == nil {
+		s.seen = map[string]bool{}
+	}
+	if !s.seen[sec.Name] {
+		s.seen[sec.Name] = true
+		s.tables = append(s.tables, sec.Name)
+	}
+	return nil
+}
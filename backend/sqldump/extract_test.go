@@ -0,0 +1,85 @@
+package sqldump
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// ifsnopSample is trimmed output of the plugin's ifsnop exporter: tables open with
+// SET @saved_cs_client and triggers follow all tables.
+const ifsnopSample = "/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n" +
+	"/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n" +
+	"/*!40101 SET @saved_cs_client     = @@character_set_client */;\n" +
+	"/*!40101 SET character_set_client = utf8 */;\n" +
+	"CREATE TABLE `wp_posts` (\n  `ID` bigint NOT NULL\n);\n" +
+	"/*!40101 SET character_set_client = @saved_cs_client */;\n" +
+	"INSERT INTO `wp_posts` VALUES (1),(2);\n" +
+	"/*!40101 SET @saved_cs_client     = @@character_set_client */;\n" +
+	"/*!40101 SET character_set_client = utf8 */;\n" +
+	"CREATE TABLE `wp_options` (\n  `option_id` bigint NOT NULL\n);\n" +
+	"/*!40101 SET character_set_client = @saved_cs_client */;\n" +
+	"INSERT INTO `wp_options` VALUES (1);\n" +
+	"DROP TRIGGER IF EXISTS `stamp`;\n" +
+	"DELIMITER ;;\n" +
+	"/*!50003 CREATE*/ /*!50017 DEFINER=`wp`@`%`*/ /*!50003 TRIGGER `stamp` BEFORE INSERT ON `wp_posts` FOR EACH ROW SET NEW.ID = NEW.ID */;;\n" +
+	"DELIMITER ;\n" +
+	"/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n"
+
+func TestExtractSelectedTables(t *testing.T) {
+	var out bytes.Buffer
+	found, err := Extract(strings.NewReader(mysqldumpSample), &out, Selection{Tables: []string{"orders", "missing"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(found, []string{"orders"}) {
+		t.Fatalf("found = %v", found)
+	}
+	text := out.String()
+	for _, want := range []string{"/*!40103 SET TIME_ZONE='+00:00' */;", "CREATE TABLE `orders`", "(4,NULL)", "SET SQL_MODE=@OLD_SQL_MODE"} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("extract is missing %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"`users`", "TRIGGER", "PROCEDURE", "VIEW"} {
+		if strings.Contains(text, unwanted) {
+			t.Fatalf("extract should not contain %q:\n%s", unwanted, text)
+		}
+	}
+
+	out.Reset()
+	if _, err := Extract(strings.NewReader(mysqldumpSample), &out, Selection{Tables: []string{"orders"}, Triggers: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "TRIGGER trg") {
+		t.Fatal("triggers should be extracted when asked for")
+	}
+}
+
+func TestExtractIfsnopKeepsCharsetAndTriggers(t *testing.T) {
+	var out bytes.Buffer
+	if _, err := Extract(strings.NewReader(ifsnopSample), &out, Selection{Tables: []string{"wp_posts"}, Triggers: true}); err != nil {
+		t.Fatal(err)
+	}
+	text := out.String()
+	if !strings.Contains(text, "SET @saved_cs_client     = @@character_set_client */;\n/*!40101 SET character_set_client = utf8 */;\nCREATE TABLE `wp_posts`") {
+		t.Fatalf("table must keep its character set preamble:\n%s", text)
+	}
+	if !strings.Contains(text, "DROP TRIGGER IF EXISTS `stamp`;\nDELIMITER ;;") {
+		t.Fatalf("trigger on wp_posts should be extracted whole:\n%s", text)
+	}
+	if strings.Contains(text, "wp_options") {
+		t.Fatalf("unselected table leaked into extract:\n%s", text)
+	}
+}
+
+func TestScanTables(t *testing.T) {
+	tables, err := ScanTables(strings.NewReader(mysqldumpSample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tables, []string{"orders", "users"}) {
+		t.Fatalf("tables = %v", tables)
+	}
+}
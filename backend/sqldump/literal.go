@@ -0,0 +1,72 @@
+package sqldump
+
+import "strings"
+
+// Unquote returns the text of a SQL string literal, including charset-introduced ones
+// (_utf8mb4'...'), with MySQL escapes resolved. Other tokens are returned as written.
+func Unquote(raw []byte) string {
+	s := strings.TrimSpace(string(raw))
+	start := strings.IndexAny(s, `'"`)
+	if start < 0 {
+		return s
+	}
+	q := s[start]
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			b.WriteByte(unescape(s[i]))
+		case c == q && i+1 < len(s) && s[i+1] == q:
+			i++
+			b.WriteByte(q)
+		case c == q:
+			return b.String()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case '0':
+		return 0
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'Z':
+		return 0x1a
+	}
+	return c
+}
+
+// Quote writes s as a single-quoted literal escaped the way mysqldump escapes strings.
+func Quote(s string) []byte {
+	out := make([]byte, 0, len(s)+2)
+	out = append(out, '\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			out = append(out, '\\', '0')
+		case '\n':
+			out = append(out, '\\', 'n')
+		case '\r':
+			out = append(out, '\\', 'r')
+		case 0x1a:
+			out = append(out, '\\', 'Z')
+		case '\\', '\'', '"':
+			out = append(out, '\\', c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return append(out, '\'')
+}
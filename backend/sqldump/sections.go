@@ -0,0 +1,339 @@
+// Package sqldump splits plain-text MySQL dumps (mysqldump, mariadb-dump and the WordPress
+// plugin exporters) into per-table sections without parsing full SQL.
+package sqldump
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Kind classifies a section of a dump.
+type Kind string
+
+const (
+	KindHeader   Kind = "header"   // session settings before the first object
+	KindTable    Kind = "table"    // DROP/CREATE TABLE, data and (mysqldump) the table's triggers
+	KindView     Kind = "view"     // view placeholder or definition
+	KindTrigger  Kind = "trigger"  // triggers dumped apart from their table
+	KindRoutines Kind = "routines" // events, procedures and functions
+	KindFooter   Kind = "footer"   // session settings restored at the end
+)
+
+// Section is one contiguous part of a dump. Name is the table or view; for mysqldump
+// triggers it is the table they belong to. Rows counts the rows in INSERT statements.
+type Section struct {
+	Kind  Kind
+	Name  string
+	Rows  int64
+	Bytes int64
+}
+
+// Visitor receives a dump section by section. The p passed to Write is only valid for the
+// duration of the call. A section's Kind can change from table to view while it is read;
+// it is final when End is called.
+type Visitor interface {
+	Begin(sec *Section) error
+	Write(sec *Section, p []byte) error
+	End(sec *Section) error
+}
+
+// maxLine is how much of a line is buffered at once; longer lines (extended INSERTs) are
+// passed on in chunks and classified by their first chunk.
+const maxLine = 256 << 10
+
+var (
+	footerMarkers = [][]byte{
+		[]byte("/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE"),
+		[]byte("/*!40101 SET SQL_MODE=@OLD_SQL_MODE"),
+		[]byte("SET FOREIGN_KEY_CHECKS=1;"),
+	}
+	routineMarkers = [][]byte{
+		[]byte("/*!50003 DROP PROCEDURE IF EXISTS "),
+		[]byte("/*!50003 DROP FUNCTION IF EXISTS "),
+		[]byte("/*!50106 SET @save_time_zone"),
+		[]byte("/*!50106 DROP EVENT IF EXISTS "),
+		[]byte("DROP PROCEDURE IF EXISTS "),
+		[]byte("DROP FUNCTION IF EXISTS "),
+		[]byte("DROP EVENT IF EXISTS "),
+	}
+	prefixDropTable     = []byte("DROP TABLE IF EXISTS ")
+	prefixCreateTableIf = []byte("CREATE TABLE IF NOT EXISTS ")
+	prefixCreateTable   = []byte("CREATE TABLE ")
+	prefixDropViewCond  = []byte("/*!50001 DROP VIEW IF EXISTS ")
+	prefixDropView      = []byte("DROP VIEW IF EXISTS ")
+	prefixDropTrigger   = []byte("DROP TRIGGER IF EXISTS ")
+	prefixTriggerBlock  = []byte("/*!50003 SET @saved_cs_client")
+	prefixSavedCSClient = []byte("/*!40101 SET @saved_cs_client")
+	prefixInsert        = []byte("INSERT ")
+	prefixReplace       = []byte("REPLACE ")
+	tokenValues         = []byte("VALUES")
+)
+
+type walker struct {
+	v   Visitor
+	cur *Section
+	// lastDropTable is the table named by the previous line when it was DROP TABLE; mysqldump
+	// follows it with DROP VIEW when the "table" is a view placeholder.
+	lastDropTable string
+	// created is set once the current section has its CREATE statement.
+	created bool
+	rows    rowCounter
+}
+
+// Walk reads a plain-text dump and hands it to v section by section.
+func Walk(r io.Reader, v Visitor) error {
+	w := &walker{v: v}
+	br := bufio.NewReaderSize(r, maxLine)
+	atLineStart := true
+	for {
+		line, err := br.ReadSlice('\n')
+		if len(line) > 0 {
+			if atLineStart {
+				if cerr := w.startLine(line); cerr != nil {
+					return cerr
+				}
+			} else {
+				w.rows.feed(line)
+			}
+			w.cur.Bytes += int64(len(line))
+			if werr := v.Write(w.cur, line); werr != nil {
+				return werr
+			}
+			atLineStart = line[len(line)-1] == '\n'
+			if atLineStart {
+				w.cur.Rows += w.rows.take()
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if w.cur == nil {
+		return nil
+	}
+	w.cur.Rows += w.rows.take()
+	return v.End(w.cur)
+}
+
+// startLine classifies a line (or the first chunk of a long line), switching sections when
+// it starts a new object.
+func (w *walker) startLine(line []byte) error {
+	t := bytes.TrimLeft(line, " \t")
+	next, name := w.classify(t)
+	w.lastDropTable = ""
+	if bytes.HasPrefix(t, prefixDropTable) {
+		w.lastDropTable = name
+	}
+	if w.cur == nil {
+		if next == "" {
+			next = KindHeader
+		}
+		if err := w.begin(next, name); err != nil {
+			return err
+		}
+	} else if next != "" {
+		if err := w.v.End(w.cur); err != nil {
+			return err
+		}
+		if err := w.begin(next, name); err != nil {
+			return err
+		}
+	}
+	if bytes.HasPrefix(t, prefixInsert) || bytes.HasPrefix(t, prefixReplace) {
+		w.rows.startInsert(t)
+	}
+	return nil
+}
+
+func (w *walker) begin(kind Kind, name string) error {
+	w.cur = &Section{Kind: kind, Name: name}
+	w.created = false
+	return w.v.Begin(w.cur)
+}
+
+// classify returns the kind of section a line starts, or "" when it continues the current one.
+func (w *walker) classify(t []byte) (Kind, string) {
+	cur := w.cur
+	for _, m := range footerMarkers {
+		if bytes.HasPrefix(t, m) {
+			if cur != nil && cur.Kind == KindFooter {
+				return "", ""
+			}
+			return KindFooter, ""
+		}
+	}
+	if bytes.HasPrefix(t, prefixDropViewCond) {
+		name := identAfter(t, prefixDropViewCond)
+		if cur != nil && cur.Name == name && w.lastDropTable == name {
+			cur.Kind = KindView
+			return "", ""
+		}
+		return KindView, name
+	}
+	if cur != nil && cur.Kind == KindRoutines {
+		return "", ""
+	}
+	for _, m := range routineMarkers {
+		if bytes.HasPrefix(t, m) {
+			return KindRoutines, ""
+		}
+	}
+	switch {
+	case bytes.HasPrefix(t, prefixDropTable):
+		return KindTable, identAfter(t, prefixDropTable)
+	case bytes.HasPrefix(t, prefixSavedCSClient):
+		// mysqldump writes this between DROP TABLE and CREATE TABLE; dumps without DROP
+		// TABLE (the plugin's ifsnop exporter) open the table with it. CREATE names it.
+		if cur != nil && (cur.Kind == KindTable || cur.Kind == KindView) && !w.created {
+			return "", ""
+		}
+		return KindTable, ""
+	case bytes.HasPrefix(t, prefixDropView):
+		return KindView, identAfter(t, prefixDropView)
+	case bytes.HasPrefix(t, prefixCreateTableIf), bytes.HasPrefix(t, prefixCreateTable):
+		prefix := prefixCreateTable
+		if bytes.HasPrefix(t, prefixCreateTableIf) {
+			prefix = prefixCreateTableIf
+		}
+		name := identAfter(t, prefix)
+		if cur != nil && cur.Kind == KindTable && cur.Name == "" && !w.created {
+			cur.Name = name
+		}
+		if cur != nil && (cur.Kind == KindTable || cur.Kind == KindView) && cur.Name == name {
+			w.created = true
+			return "", ""
+		}
+		w.created = true
+		return KindTable, name
+	case bytes.HasPrefix(t, prefixTriggerBlock):
+		if cur != nil && cur.Kind == KindTable {
+			return KindTrigger, cur.Name
+		}
+	case bytes.HasPrefix(t, prefixDropTrigger):
+		return KindTrigger, ""
+	}
+	if cur != nil && cur.Kind == KindTrigger && cur.Name == "" {
+		cur.Name = triggerTable(t)
+	}
+	return "", ""
+}
+
+// triggerTable returns the table a CREATE TRIGGER statement is defined on, or "".
+func triggerTable(t []byte) string {
+	if !bytes.Contains(t, []byte("TRIGGER")) {
+		return ""
+	}
+	i := bytes.Index(t, []byte(" ON "))
+	if i < 0 {
+		return ""
+	}
+	rest := bytes.TrimLeft(t[i+len(" ON "):], " ")
+	end := bytes.Index(rest, []byte(" FOR EACH ROW"))
+	if end < 0 {
+		return ""
+	}
+	ref := bytes.TrimSpace(rest[:end])
+	// Schema-qualified references (`db`.`t`) name the table last.
+	if dot := bytes.LastIndex(ref, []byte("`.`")); dot >= 0 {
+		ref = ref[dot+2:]
+	} else if dot := bytes.LastIndexByte(ref, '.'); dot >= 0 && ref[0] != '`' {
+		ref = ref[dot+1:]
+	}
+	return identAfter(ref, nil)
+}
+
+// identAfter reads the identifier following prefix, backquoted (doubled backquotes escape) or bare.
+func identAfter(t, prefix []byte) string {
+	rest := t[len(prefix):]
+	if len(rest) == 0 {
+		return ""
+	}
+	if rest[0] != '`' {
+		end := bytes.IndexAny(rest, " ;*\r\n(")
+		if end < 0 {
+			end = len(rest)
+		}
+		return string(rest[:end])
+	}
+	var name []byte
+	for i := 1; i < len(rest); i++ {
+		if rest[i] != '`' {
+			name = append(name, rest[i])
+			continue
+		}
+		if i+1 < len(rest) && rest[i+1] == '`' {
+			name = append(name, '`')
+			i++
+			continue
+		}
+		break
+	}
+	return string(name)
+}
+
+// rowCounter counts the row tuples of INSERT ... VALUES (...),(...) statements, which
+// dumps write on a single (possibly very long) line.
+type rowCounter struct {
+	active bool
+	depth  int
+	quote  byte
+	escape bool
+	rows   int64
+}
+
+func (c *rowCounter) startInsert(line []byte) {
+	i := bytes.Index(line, tokenValues)
+	if i < 0 {
+		return
+	}
+	*c = rowCounter{active: true, rows: c.rows}
+	c.feed(line[i+len(tokenValues):])
+}
+
+func (c *rowCounter) feed(p []byte) {
+	if !c.active {
+		return
+	}
+	for _, b := range p {
+		switch {
+		case c.escape:
+			c.escape = false
+		case c.quote != 0:
+			if b == '\\' {
+				c.escape = true
+			} else if b == c.quote {
+				c.quote = 0
+			}
+		case b == '\'' || b == '"':
+			c.quote = b
+		case b == '(':
+			if c.depth == 0 {
+				c.rows++
+			}
+			c.depth++
+		case b == ')':
+			c.depth--
+		case b == ';' && c.depth == 0:
+			c.active = false
+			return
+		}
+	}
+}
+
+// take returns the rows counted so far and resets the counter.
+func (c *rowCounter) take() int64 {
+	n := c.rows
+	c.rows = 0
+	if c.quote == 0 && c.depth == 0 {
+		c.active = false
+	}
+	return n
+}
@@ -0,0 +1,152 @@
+package sqldump
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// mysqldumpSample is trimmed mysqldump output: a table with a trigger, a view placeholder,
+// routines and the closing session settings.
+const mysqldumpSample = `-- MySQL dump 10.13
+/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;
+/*!40103 SET TIME_ZONE='+00:00' */;
+
+--
+-- Table structure for table ` + "`orders`" + `
+--
+
+DROP TABLE IF EXISTS ` + "`orders`" + `;
+CREATE TABLE ` + "`orders`" + ` (
+  ` + "`id`" + ` int NOT NULL
+) ENGINE=InnoDB;
+INSERT INTO ` + "`orders`" + ` VALUES (1,'a (b)'),(2,'it\'s'),(3,');(');
+INSERT INTO ` + "`orders`" + ` VALUES (4,NULL);
+/*!50003 SET @saved_cs_client      = @@character_set_client */ ;
+/*!50003 CREATE*/ /*!50017 DEFINER=` + "`root`@`%`" + `*/ /*!50003 TRIGGER trg BEFORE INSERT ON orders FOR EACH ROW SET NEW.id = NEW.id */;;
+DROP TABLE IF EXISTS ` + "`active_orders`" + `;
+/*!50001 DROP VIEW IF EXISTS ` + "`active_orders`" + `*/;
+/*!50001 CREATE VIEW ` + "`active_orders`" + ` AS SELECT 1 AS ` + "`id`" + ` */;
+DROP TABLE IF EXISTS ` + "`users`" + `;
+CREATE TABLE ` + "`users`" + ` (
+  ` + "`id`" + ` int NOT NULL
+);
+/*!50003 DROP PROCEDURE IF EXISTS ` + "`p`" + ` */;
+CREATE PROCEDURE p() BEGIN SELECT 1; END ;;
+/*!50001 DROP VIEW IF EXISTS ` + "`active_orders`" + `*/;
+/*!50001 VIEW ` + "`active_orders`" + ` AS select 1 */;
+/*!40103 SET TIME_ZONE=@OLD_TIME_ZONE */;
+/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;
+-- Dump completed
+`
+
+type recorder struct {
+	sections []Section
+	text     strings.Builder
+}
+
+func (r *recorder) Begin(*Section) error { return nil }
+func (r *recorder) Write(_ *Section, p []byte) error {
+	r.text.Write(p)
+	return nil
+}
+func (r *recorder) End(sec *Section) error {
+	r.sections = append(r.sections, *sec)
+	return nil
+}
+
+func TestWalkMysqldump(t *testing.T) {
+	var rec recorder
+	if err := Walk(strings.NewReader(mysqldumpSample), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.text.String() != mysqldumpSample {
+		t.Fatal("sections should reproduce the dump byte for byte")
+	}
+	type kn struct {
+		Kind Kind
+		Name string
+		Rows int64
+	}
+	var got []kn
+	for _, s := range rec.sections {
+		got = append(got, kn{s.Kind, s.Name, s.Rows})
+	}
+	want := []kn{
+		{KindHeader, "", 0},
+		{KindTable, "orders", 4},
+		{KindTrigger, "orders", 0},
+		{KindView, "active_orders", 0},
+		{KindTable, "users", 0},
+		{KindRoutines, "", 0},
+		{KindView, "active_orders", 0},
+		{KindFooter, "", 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sections = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestWalkPluginDump(t *testing.T) {
+	dump := "SET NAMES utf8mb4;\nSET FOREIGN_KEY_CHECKS=0;\n" +
+		"DROP TABLE IF EXISTS `wp_posts`;\nCREATE TABLE `wp_posts` (id int);\n" +
+		"INSERT INTO `wp_posts` VALUES (1),(2);\n" +
+		"DROP TRIGGER IF EXISTS `t1`;\nCREATE TRIGGER t1 BEFORE INSERT ON wp_posts FOR EACH ROW SET @x=1;\n" +
+		"DROP VIEW IF EXISTS `v`;\nCREATE VIEW `v` AS SELECT 1;\n" +
+		"SET FOREIGN_KEY_CHECKS=1;\n"
+	var rec recorder
+	if err := Walk(strings.NewReader(dump), &rec); err != nil {
+		t.Fatal(err)
+	}
+	var kinds []Kind
+	for _, s := range rec.sections {
+		kinds = append(kinds, s.Kind)
+	}
+	want := []Kind{KindHeader, KindTable, KindTrigger, KindView, KindFooter}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("kinds = %v, want %v", kinds, want)
+	}
+	if rec.sections[1].Rows != 2 {
+		t.Fatalf("wp_posts rows = %d, want 2", rec.sections[1].Rows)
+	}
+}
+
+func TestWalkCountsRowsAcrossLongLines(t *testing.T) {
+	long := strings.Repeat("x", maxLine*2)
+	dump := "DROP TABLE IF EXISTS `t`;\nINSERT INTO `t` VALUES (1,'" + long + "'),(2,'(');\n"
+	var rec recorder
+	if err := Walk(strings.NewReader(dump), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.sections) != 1 || rec.sections[0].Rows != 2 {
+		t.Fatalf("sections = %+v, want one table with 2 rows", rec.sections)
+	}
+	if rec.sections[0].Bytes != int64(len(dump)) {
+		t.Fatalf("bytes = %d, want %d", rec.sections[0].Bytes, len(dump))
+	}
+}
+
+func TestWalkNamesIfsnopTablesAndTriggers(t *testing.T) {
+	var rec recorder
+	if err := Walk(strings.NewReader(ifsnopSample), &rec); err != nil {
+		t.Fatal(err)
+	}
+	type kn struct {
+		Kind Kind
+		Name string
+	}
+	var got []kn
+	for _, s := range rec.sections {
+		got = append(got, kn{s.Kind, s.Name})
+	}
+	want := []kn{
+		{KindHeader, ""},
+		{KindTable, "wp_posts"},
+		{KindTable, "wp_options"},
+		{KindTrigger, "wp_posts"},
+		{KindFooter, ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sections = %+v\nwant %+v", got, want)
+	}
+}
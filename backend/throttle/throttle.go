@@ -0,0 +1,299 @@
+// Package throttle caps transfer speed with token buckets. A bucket may be shared by
+// several streams, so one cap covers every transfer that passes through it.
+package throttle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// MB is the unit caps are configured in.
+const MB = 1 << 20
+
+// chunkSize bounds how much one capped Read or Write moves before it waits for tokens, so
+// a large copy buffer cannot burst far past the cap. Uncapped streams keep full buffers.
+const chunkSize = 32 << 10
+
+// rateRecheck is how long a bucket keeps the cap its Rate returned; schedules change the
+// cap at most this late.
+const rateRecheck = time.Second
+
+// Rate returns the cap in bytes per second at a time; zero or less means unlimited.
+type Rate func(time.Time) float64
+
+// Fixed returns a Rate that is always bytesPerSec.
+func Fixed(bytesPerSec float64) Rate {
+	return func(time.Time) float64 { return bytesPerSec }
+}
+
+// Bucket is a token bucket filled at its Rate and holding a quarter second of tokens.
+// Callers that take more than the bucket holds go into debt and wait it off.
+type Bucket struct {
+	rate Rate
+
+	mu      sync.Mutex
+	tokens  float64
+	last    time.Time
+	limit   float64
+	checked time.Time
+}
+
+// NewBucket returns a bucket capped by rate; a nil rate never limits.
+func NewBucket(rate Rate) *Bucket {
+	return &Bucket{rate: rate}
+}
+
+// Limit returns the cap in bytes per second in force now, or 0 when unlimited.
+func (b *Bucket) Limit() float64 {
+	if b == nil {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.limitLocked(time.Now())
+}
+
+func (b *Bucket) limitLocked(now time.Time) float64 {
+	if b.rate == nil {
+		return 0
+	}
+	if b.checked.IsZero() || now.Sub(b.checked) >= rateRecheck || now.Before(b.checked) {
+		b.limit = math.Max(b.rate(now), 0)
+		b.checked = now
+	}
+	return b.limit
+}
+
+// reserve takes n tokens at now and returns how long the caller must wait for them.
+func (b *Bucket) reserve(now time.Time, n int) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	limit := b.limitLocked(now)
+	if limit <= 0 {
+		b.tokens, b.last = 0, time.Time{}
+		return 0
+	}
+	capacity := math.Max(limit/4, chunkSize)
+	if b.last.IsZero() {
+		b.tokens = capacity
+	} else if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = math.Min(capacity, b.tokens+elapsed*limit)
+	}
+	b.last = now
+	b.tokens -= float64(n)
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / limit * float64(time.Second))
+}
+
+// Wait blocks until n bytes may pass, or ctx is done.
+func (b *Bucket) Wait(ctx context.Context, n int) error {
+	if b == nil || n <= 0 {
+		return nil
+	}
+	d := b.reserve(time.Now(), n)
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Direction is which way bytes move, seen from this machine.
+type Direction int
+
+const (
+	// Upload is data sent to a host: restores and deep verify.
+	Upload Direction = iota
+	// Download is data received from a host: backups.
+	Download
+)
+
+// Limits holds the buckets one transfer passes through in each direction, usually a
+// bucket shared by every transfer plus one of the host's own, and measures the rate the
+// transfer achieves. A nil *Limits leaves a transfer unlimited.
+type Limits struct {
+	Upload   []*Bucket
+	Download []*Bucket
+
+	meters [2]meter
+}
+
+func (l *Limits) buckets(dir Direction) []*Bucket {
+	if dir == Upload {
+		return l.Upload
+	}
+	return l.Download
+}
+
+// Limit returns the lowest cap in force now for dir, or 0 when unlimited.
+func (l *Limits) Limit(dir Direction) float64 {
+	if l == nil {
+		return 0
+	}
+	lowest := 0.0
+	for _, b := range l.buckets(dir) {
+		if limit := b.Limit(); limit > 0 && (lowest == 0 || limit < lowest) {
+			lowest = limit
+		}
+	}
+	return lowest
+}
+
+// wait passes n bytes through every bucket of dir and the meter.
+func (l *Limits) wait(ctx context.Context, dir Direction, n int) error {
+	for _, b := range l.buckets(dir) {
+		if err := b.Wait(ctx, n); err != nil {
+			return err
+		}
+	}
+	l.meters[dir].add(time.Now(), n)
+	return nil
+}
+
+// Reader returns r with dir's caps applied to what is read from it.
+func (l *Limits) Reader(ctx context.Context, dir Direction, r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &reader{ctx: ctx, limits: l, dir: dir, r: r}
+}
+
+// Writer returns w with dir's caps applied to what is written to it.
+func (l *Limits) Writer(ctx context.Context, dir Direction, w io.Writer) io.Writer {
+	if l == nil {
+		return w
+	}
+	return &writer{ctx: ctx, limits: l, dir: dir, w: w}
+}
+
+// Progress wraps a progress callback so each message ends with the rate the transfer
+// runs at and, while one applies, its cap.
+func (l *Limits) Progress(fn func(message string, current, total int64)) func(message string, current, total int64) {
+	if l == nil || fn == nil {
+		return fn
+	}
+	return func(message string, current, total int64) {
+		fn(message+l.describe(time.Now()), current, total)
+	}
+}
+
+// describe is the rate suffix for progress messages; empty until bytes have moved.
+func (l *Limits) describe(now time.Time) string {
+	dir := Download
+	if l.meters[Upload].rate(now) > l.meters[Download].rate(now) {
+		dir = Upload
+	}
+	rate := l.meters[dir].rate(now)
+	if rate <= 0 {
+		return ""
+	}
+	if limit := l.Limit(dir); limit > 0 {
+		return fmt.Sprintf(" · %s (limit %s)", FormatRate(rate), FormatRate(limit))
+	}
+	return " · " + FormatRate(rate)
+}
+
+// FormatRate renders bytes per second as MB/s.
+func FormatRate(bytesPerSec float64) string {
+	return fmt.Sprintf("%.1f MB/s", bytesPerSec/MB)
+}
+
+type reader struct {
+	ctx    context.Context
+	limits *Limits
+	dir    Direction
+	r      io.Reader
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) > chunkSize && r.limits.Limit(r.dir) > 0 {
+		p = p[:chunkSize]
+	}
+	n, err := r.r.Read(p)
+	if n > 0 {
+		if werr := r.limits.wait(r.ctx, r.dir, n); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+type writer struct {
+	ctx    context.Context
+	limits *Limits
+	dir    Direction
+	w      io.Writer
+}
+
+func (w *writer) Write(p []byte) (int, error) {
+	written := 0
+	limited := w.limits.Limit(w.dir) > 0
+	for len(p) > 0 {
+		chunk := p
+		if limited && len(chunk) > chunkSize {
+			chunk = chunk[:chunkSize]
+		}
+		if err := w.limits.wait(w.ctx, w.dir, len(chunk)); err != nil {
+			return written, err
+		}
+		n, err := w.w.Write(chunk)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// meterWindow is how often the measured rate is refreshed.
+const meterWindow = 500 * time.Millisecond
+
+// meter measures throughput as a moving average refreshed every meterWindow.
+type meter struct {
+	mu      sync.Mutex
+	total   int64
+	sampled int64
+	at      time.Time
+	avg     float64
+}
+
+func (m *meter) add(now time.Time, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.at.IsZero() {
+		m.at = now
+	}
+	m.total += int64(n)
+	if elapsed := now.Sub(m.at); elapsed >= meterWindow {
+		current := float64(m.total-m.sampled) / elapsed.Seconds()
+		if m.avg == 0 {
+			m.avg = current
+		} else {
+			m.avg = 0.7*m.avg + 0.3*current
+		}
+		m.sampled, m.at = m.total, now
+	}
+}
+
+// rate returns the measured bytes per second; a transfer idle for a few windows reads 0.
+func (m *meter) rate(now time.Time) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.at.IsZero() || now.Sub(m.at) > 4*meterWindow {
+		return 0
+	}
+	return m.avg
+}
@@ -0,0 +1,105 @@
+package throttle
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucketReserve(t *testing.T) {
+	b := NewBucket(Fixed(MB))
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// A fresh bucket holds a quarter second of tokens.
+	if d := b.reserve(start, MB/4); d != 0 {
+		t.Fatalf("first quarter second should pass at once, waited %s", d)
+	}
+	if d := b.reserve(start, MB/2); d != 500*time.Millisecond {
+		t.Fatalf("debt of half a second: waited %s", d)
+	}
+	// A second later the debt is paid and the bucket refilled, up to its capacity only.
+	if d := b.reserve(start.Add(2*time.Second), MB/4); d != 0 {
+		t.Fatalf("refilled bucket: waited %s", d)
+	}
+	if d := b.reserve(start.Add(2*time.Second), MB/4); d != 250*time.Millisecond {
+		t.Fatalf("capacity should stay a quarter second: waited %s", d)
+	}
+}
+
+func TestBucketFollowsRate(t *testing.T) {
+	limit := float64(MB)
+	b := NewBucket(func(time.Time) float64 { return limit })
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	b.reserve(start, MB)
+
+	// The cap is read again after rateRecheck; lifting it clears the debt.
+	limit = 0
+	if d := b.reserve(start.Add(rateRecheck/2), MB); d == 0 {
+		t.Fatal("the old cap should hold until it is checked again")
+	}
+	if d := b.reserve(start.Add(rateRecheck), 10*MB); d != 0 {
+		t.Fatalf("unlimited bucket waited %s", d)
+	}
+}
+
+func TestLimitsReaderWriter(t *testing.T) {
+	var nilLimits *Limits
+	src := strings.NewReader("abc")
+	if nilLimits.Reader(context.Background(), Download, src) != io.Reader(src) {
+		t.Fatal("nil limits should return the reader as it is")
+	}
+
+	l := &Limits{Upload: []*Bucket{NewBucket(nil), NewBucket(Fixed(2 * MB))}}
+	data := bytes.Repeat([]byte("x"), 3*chunkSize+5)
+	var out bytes.Buffer
+	n, err := l.Writer(context.Background(), Upload, &out).Write(data)
+	if err != nil || n != len(data) || !bytes.Equal(out.Bytes(), data) {
+		t.Fatalf("write = %d, %v", n, err)
+	}
+	got, err := io.ReadAll(l.Reader(context.Background(), Download, bytes.NewReader(data)))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("read = %d bytes, %v", len(got), err)
+	}
+	if got := l.Limit(Upload); got != 2*MB {
+		t.Fatalf("upload limit = %v", got)
+	}
+	if got := l.Limit(Download); got != 0 {
+		t.Fatalf("download limit = %v", got)
+	}
+}
+
+func TestLimitsCanceledWait(t *testing.T) {
+	l := &Limits{Download: []*Bucket{NewBucket(Fixed(1))}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := io.ReadAll(l.Reader(ctx, Download, bytes.NewReader(make([]byte, 3*chunkSize))))
+	if err != context.Canceled {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestLimitsDescribe(t *testing.T) {
+	l := &Limits{Download: []*Bucket{NewBucket(Fixed(5 * MB))}}
+	start := time.Now()
+	if got := l.describe(start); got != "" {
+		t.Fatalf("nothing moved yet: %q", got)
+	}
+	l.meters[Download].add(start, 0)
+	l.meters[Download].add(start.Add(time.Second), 4*MB)
+	if got := l.describe(start.Add(time.Second)); got != " · 4.0 MB/s (limit 5.0 MB/s)" {
+		t.Fatalf("describe = %q", got)
+	}
+	if got := l.describe(start.Add(10 * time.Second)); got != "" {
+		t.Fatalf("idle transfer: %q", got)
+	}
+
+	var messages []string
+	progress := l.Progress(func(message string, current, total int64) { messages = append(messages, message) })
+	progress("Streaming backup 1.00 MB", 1, 0)
+	if len(messages) != 1 || !strings.HasPrefix(messages[0], "Streaming backup 1.00 MB") {
+		t.Fatalf("messages = %v", messages)
+	}
+}
@@ -0,0 +1,55 @@
+package transfer
+
+import (
+	"context"
+	"io"
+
+	"dback/backend/ssh"
+	"dback/backend/throttle"
+)
+
+// throttledExecutor applies a transfer's bandwidth caps to an executor's streams: command
+// output is a download, piped input an upload. Short commands run through RunCommand
+// unthrottled.
+type throttledExecutor struct {
+	ssh.Executor
+	ctx    context.Context
+	limits *throttle.Limits
+}
+
+// throttleExecutor wraps client with limits; nil limits return client as it is.
+func throttleExecutor(ctx context.Context, client ssh.Executor, limits *throttle.Limits) ssh.Executor {
+	if limits == nil {
+		return client
+	}
+	return &throttledExecutor{Executor: client, ctx: ctx, limits: limits}
+}
+
+func (e *throttledExecutor) RunCommandStream(cmd string) (io.Reader, io.Reader, ssh.Session, error) {
+	stdout, stderr, session, err := e.Executor.RunCommandStream(cmd)
+	if err != nil {
+		return stdout, stderr, session, err
+	}
+	return e.limits.Reader(e.ctx, throttle.Download, stdout), stderr, session, nil
+}
+
+func (e *throttledExecutor) RunCommandPipeInput(cmd string) (io.WriteCloser, io.Reader, ssh.Session, error) {
+	stdin, stderr, session, err := e.Executor.RunCommandPipeInput(cmd)
+	if err != nil {
+		return stdin, stderr, session, err
+	}
+	return throttledWriteCloser{Writer: e.limits.Writer(e.ctx, throttle.Upload, stdin), Closer: stdin}, stderr, session, nil
+}
+
+type throttledWriteCloser struct {
+	io.Writer
+	io.Closer
+}
+
+// withRate adds the measured rate to a transfer's progress messages.
+func withRate(progress ProgressFunc, limits *throttle.Limits) ProgressFunc {
+	if progress == nil || limits == nil {
+		return progress
+	}
+	return limits.Progress(progress)
+}
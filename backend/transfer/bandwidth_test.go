@@ -0,0 +1,43 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"context"
+	"strings"
+	"testing"
+
+	"dback/backend/throttle"
+)
+
+func TestThrottleExecutor(t *testing.T) {
+	exec := &pipeExecutor{}
+	if throttleExecutor(context.Background(), exec, nil) != exec {
+		t.Fatal("nil limits should leave the executor as it is")
+	}
+
+	limits := &throttle.Limits{Upload: []*throttle.Bucket{throttle.NewBucket(throttle.Fixed(64 * throttle.MB))}}
+	client := throttleExecutor(context.Background(), exec, limits)
+	stdin, _, session, err := client.RunCommandPipeInput("import")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(stdin)
+	if _, err := gz.Write([]byte(strings.Repeat("INSERT INTO `t` VALUES (1);\n", 4096))); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stdin.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := session.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || strings.Count(exec.sessions[0], "\n") != 4096 {
+		t.Fatalf("sessions = %d", len(exec.sessions))
+	}
+	if got := limits.Limit(throttle.Upload); got != 64*throttle.MB {
+		t.Fatalf("upload limit = %v", got)
+	}
+}
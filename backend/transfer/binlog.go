@@ -0,0 +1,225 @@
+package transfer
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dback/backend/binlog"
+	"dback/backend/codec"
+	"dback/backend/db"
+	"dback/backend/ssh"
+	"dback/models"
+)
+
+// ErrNoBinlogEvents means the server wrote no binary log since the position asked for.
+var ErrNoBinlogEvents = errors.New("no new binary log events")
+
+// BinlogExt marks incremental backups: gzip-compressed mysqlbinlog output.
+const BinlogExt = ".binlog.sql.gz"
+
+// BinlogRequest pulls the binary log events written after From.
+type BinlogRequest struct {
+	BackupRequest
+	From models.BinlogPosition
+}
+
+// dumpBinlogRange reads the binary log position a full dump recorded when the profile takes
+// incremental backups. A dump without one is kept, with a warning; no chain can start from it.
+func dumpBinlogRange(req BackupRequest, path string) *models.BinlogRange {
+	if !req.Profile.Binlog.Active() {
+		return nil
+	}
+	pos, err := readDumpPosition(path)
+	if err != nil {
+		logReq(req, "binlog", "", 0, "Incremental backups cannot continue from this dump", "Warning", err.Error())
+		return nil
+	}
+	logReq(req, "binlog", "", 0, "Dump is consistent with binary log position "+pos.String(), "Succeeded", "")
+	return &models.BinlogRange{Start: pos, End: pos}
+}
+
+func readDumpPosition(path string) (models.BinlogPosition, error) {
+	r, _, err := codec.Open(path)
+	if err != nil {
+		return models.BinlogPosition{}, err
+	}
+	defer r.Close()
+	return binlog.ParseDumpPosition(r)
+}
+
+// BackupBinlog streams the events of the profile's database written since req.From into
+// the host's backup folder as an incremental backup.
+func BackupBinlog(ctx context.Context, req BinlogRequest) (BackupResult, error) {
+	p := req.Profile
+	if err := ctx.Err(); err != nil {
+		return BackupResult{}, err
+	}
+	if err := db.ValidateProfileForRemoteOps(p); err != nil {
+		return BackupResult{}, err
+	}
+	client, err := ssh.NewExecutor(p)
+	if err != nil {
+		return BackupResult{}, err
+	}
+	defer client.Close()
+	client = throttleExecutor(ctx, client, req.Bandwidth)
+	req.Progress = withRate(req.Progress, req.Bandwidth)
+
+	if req.Progress != nil {
+		req.Progress("Listing binary logs...", 0, 0)
+	}
+	listCmd, err := db.BuildListBinaryLogsCommand(p)
+	if err != nil {
+		return BackupResult{}, err
+	}
+	out, err := client.RunCommand(listCmd)
+	if err != nil {
+		err = fmt.Errorf("list binary logs: %w: %s", err, strings.TrimSpace(out))
+		logReq(req.BackupRequest, "binlog", "", 0, "Could not list binary logs", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	files, end, err := binlog.Plan(req.From, db.ParseBinaryLogs(out))
+	if err != nil {
+		logReq(req.BackupRequest, "binlog", "", 0, "Take a full backup to start a new chain", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	if end == req.From {
+		logReq(req.BackupRequest, "binlog", "", 0, "No new events since "+req.From.String(), "Skipped", "")
+		return BackupResult{}, ErrNoBinlogEvents
+	}
+	logReq(req.BackupRequest, "binlog", string(StrategyStreaming), 1,
+		fmt.Sprintf("Reading %s to %s (%d file(s))", req.From, end, len(files)), "Started", "")
+
+	hostDir := filepath.Join(req.Destination, safeName(p.Name))
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		return BackupResult{}, fmt.Errorf("create host backup folder: %w", err)
+	}
+	fullPath := filepath.Join(hostDir, fmt.Sprintf("%s_%s%s", safeName(p.TargetDBName), time.Now().Format("02_01_2006_15_04_05"), BinlogExt))
+	size, err := streamBinlog(ctx, client, db.BuildBinlogExportCommand(p, files, req.From.Pos, end.Pos), fullPath, req.Progress)
+	if err != nil {
+		_ = os.Remove(fullPath)
+		logReq(req.BackupRequest, "binlog", string(StrategyStreaming), 1, err.Error(), "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	sum, err := scanBinlogFile(fullPath)
+	if err != nil {
+		_ = os.Remove(fullPath)
+		logReq(req.BackupRequest, "binlog", string(StrategyStreaming), 1, "Could not read mysqlbinlog output", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	if sum, sumErr := checksumFile(fullPath); sumErr == nil {
+		logReq(req.BackupRequest, "checksum", string(StrategyStreaming), 1, "sha256="+sum, "Succeeded", "")
+	}
+	details := fmt.Sprintf("Pulled %d event(s)", sum.Events)
+	if sum.Events > 0 {
+		details += fmt.Sprintf(" from %s to %s UTC", sum.First.Format(time.DateTime), sum.Last.Format(time.DateTime))
+	}
+	logReq(req.BackupRequest, "binlog", string(StrategyStreaming), 1, details, "Succeeded", "")
+
+	file := BackupFile{
+		Database: p.TargetDBName,
+		Path:     fullPath,
+		Size:     size,
+		Binlog: &models.BinlogRange{
+			Start:      req.From,
+			End:        end,
+			FirstEvent: sum.First,
+			LastEvent:  sum.Last,
+			Events:     sum.Events,
+		},
+		Compression: models.CompressionGzip,
+	}
+	return BackupResult{Path: file.Path, Size: file.Size, Files: []BackupFile{file}}, nil
+}
+
+func streamBinlog(ctx context.Context, client ssh.Executor, cmd, fullPath string, progress ProgressFunc) (int64, error) {
+	stdout, stderr, session, err := client.RunCommandStream(cmd)
+	if err != nil {
+		return 0, err
+	}
+	defer session.Close()
+	go cancelOnContext(ctx, session, client)
+
+	var stderrBuf strings.Builder
+	stderrDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&stderrBuf, stderr)
+		close(stderrDone)
+	}()
+
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	written, err := fastCopy(out, &ssh.ProgressReader{
+		Reader: stdout,
+		Callback: func(current int64, total int64) {
+			if progress != nil {
+				progress(fmt.Sprintf("Pulling binary logs %.2f MB", float64(current)/1024/1024), current, 0)
+			}
+		},
+	})
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := session.Wait(); err != nil {
+		<-stderrDone
+		return 0, fmt.Errorf("mysqlbinlog: %w: %s", err, strings.TrimSpace(stderrBuf.String()))
+	}
+	<-stderrDone
+	if err := validateBackupIntegrity(fullPath); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func scanBinlogFile(path string) (binlog.Summary, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return binlog.Summary{}, err
+	}
+	defer in.Close()
+	gr, err := gzip.NewReader(bufio.NewReaderSize(in, 1<<20))
+	if err != nil {
+		return binlog.Summary{}, err
+	}
+	defer gr.Close()
+	return binlog.Scan(gr)
+}
+
+// pitrRestorePath is where an incremental cut at the point-in-time target is written.
+func pitrRestorePath(localPath string) string {
+	return strings.TrimSuffix(localPath, BinlogExt) + ".pitr.sql.gz"
+}
+
+// cutBinlog writes the events of a gzip incremental up to stop to w as gzip.
+func cutBinlog(localPath string, w io.Writer, stop time.Time) (binlog.Summary, error) {
+	in, err := os.Open(localPath)
+	if err != nil {
+		return binlog.Summary{}, err
+	}
+	defer in.Close()
+	gr, err := gzip.NewReader(bufio.NewReaderSize(in, 1<<20))
+	if err != nil {
+		return binlog.Summary{}, err
+	}
+	defer gr.Close()
+	gw := gzip.NewWriter(w)
+	sum, _, err := binlog.Cut(gw, gr, stop)
+	if closeErr := gw.Close(); err == nil {
+		err = closeErr
+	}
+	return sum, err
+}
@@ -0,0 +1,85 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func writeGzip(t *testing.T, path, text string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(text))
+	gz.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDumpBinlogRange(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shop.sql.gz")
+	writeGzip(t, path, "-- CHANGE MASTER TO MASTER_LOG_FILE='mysql-bin.000007', MASTER_LOG_POS=1200;\n"+splitTestDump)
+	logger := &recordingLogger{}
+	req := BackupRequest{Profile: models.Profile{Binlog: &models.BinlogSettings{Enabled: true}}, Logger: logger}
+
+	got := dumpBinlogRange(req, path)
+	want := models.BinlogPosition{File: "mysql-bin.000007", Pos: 1200}
+	if got == nil || got.Start != want || got.End != want {
+		t.Fatalf("range = %+v", got)
+	}
+	if dumpBinlogRange(BackupRequest{Logger: logger}, path) != nil {
+		t.Fatal("profiles without incrementals should not record a position")
+	}
+
+	writeGzip(t, path, splitTestDump)
+	if dumpBinlogRange(req, path) != nil || !logger.has("binlog||Warning") {
+		t.Fatalf("a dump without a position should only warn: %v", logger.entries)
+	}
+}
+
+func TestPrepareRestoreFileCutsIncremental(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_14_01_2026_09_10_00"+BinlogExt)
+	writeGzip(t, src, "DELIMITER /*!*/;\n"+
+		"# at 1200\n#260114  9:05:01 server id 1  end_log_pos 1300 CRC32 0x0 \tQuery\tthread_id=8\nINSERT INTO orders VALUES (1)\n/*!*/;\n"+
+		"# at 1300\n#260114  9:06:30 server id 1  end_log_pos 1400 CRC32 0x0 \tQuery\tthread_id=9\nDELETE FROM orders\n/*!*/;\n"+
+		"DELIMITER ;\n")
+	logger := &recordingLogger{}
+	req, cleanup, err := prepareRestoreFile(RestoreRequest{
+		LocalPath:   src,
+		Logger:      logger,
+		Incremental: true,
+		StopAt:      time.Date(2026, 1, 14, 9, 6, 0, 0, time.UTC),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.LocalPath != filepath.Join(dir, "shop_14_01_2026_09_10_00.pitr.sql.gz") || !logger.has("pitr||Succeeded") {
+		t.Fatalf("path = %s, log = %v", req.LocalPath, logger.entries)
+	}
+	f, err := os.Open(req.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := io.ReadAll(gz)
+	f.Close()
+	if !strings.Contains(string(text), "INSERT INTO orders") || strings.Contains(string(text), "DELETE FROM orders") {
+		t.Fatalf("cut = %q", text)
+	}
+	cleanup()
+	if _, err := os.Stat(req.LocalPath); !os.IsNotExist(err) {
+		t.Fatal("cleanup should remove the cut file")
+	}
+}
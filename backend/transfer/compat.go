@@ -0,0 +1,50 @@
+package transfer
+
+import (
+	"io"
+	"strings"
+
+	"dback/backend/compat"
+	"dback/backend/crypt"
+)
+
+// compatFilter returns the filter rewriting the dump for the destination server when it
+// runs the other MySQL flavor than the one the backup was taken on. destVersion is the
+// destination's preflight DBVersion; the source is req.SourceVersion, or the dump's header
+// for records made before it was recorded. ok is false when nothing needs rewriting.
+func compatFilter(req RestoreRequest, destVersion string) (f restoreFilter, ok bool) {
+	if req.Incremental || req.Physical {
+		return restoreFilter{}, false
+	}
+	source := req.SourceVersion
+	if strings.TrimSpace(source) == "" {
+		source = sniffDumpVersion(req.LocalPath, req.Keys)
+	}
+	conv, ok := compat.Select(source, destVersion)
+	if !ok {
+		return restoreFilter{}, false
+	}
+	return restoreFilter{
+		phase:   "compat",
+		failure: "Could not rewrite backup file for " + conv.String(),
+		action:  "compatibility rewrite",
+		apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+			report, err := compat.Apply(r, w, conv)
+			if err != nil {
+				return filterReport{}, err
+			}
+			return filterReport{summary: report.Summary()}, nil
+		},
+	}, true
+}
+
+// sniffDumpVersion reads the server version from the header of the dump at path, in any
+// codec and decrypted with keys, or returns "" when it has none.
+func sniffDumpVersion(path string, keys crypt.Keys) string {
+	r, err := openDump(path, keys)
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	return compat.SniffVersion(r)
+}
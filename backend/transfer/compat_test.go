@@ -0,0 +1,67 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+const compatTestDump = "-- MySQL dump 10.13  Distrib 8.0.36, for Linux (x86_64)\n" +
+	"-- Server version\t8.0.36\n" +
+	"CREATE TABLE `t` (`id` int) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci;\n" +
+	"INSERT INTO `t` VALUES (1);\n"
+
+func writeCompatTestDump(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "shop_01.sql.gz")
+	writeGzip(t, path, compatTestDump)
+	return path
+}
+
+func TestCompatRestoreStreamsRewrittenDump(t *testing.T) {
+	src := writeCompatTestDump(t)
+	logger := &recordingLogger{}
+	req := RestoreRequest{LocalPath: src, Logger: logger}
+	// The record has no server version; the dump header names it.
+	filter, ok := compatFilter(req, "mysql  Ver 15.1 Distrib 10.6.16-MariaDB, for debian-linux-gnu")
+	if !ok {
+		t.Fatal("a MySQL 8 dump going to MariaDB should be rewritten")
+	}
+	input, err := newRestoreInput(req, []restoreFilter{filter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := &pipeExecutor{}
+	if err := restoreStream(context.Background(), exec, models.Profile{TargetDBName: "shop"}, input, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || !logger.has("compat||Succeeded") {
+		t.Fatalf("sessions = %d, log = %v", len(exec.sessions), logger.entries)
+	}
+	if data := exec.sessions[0]; strings.Contains(data, "0900") || !strings.Contains(data, "COLLATE=utf8mb4_unicode_520_ci;") {
+		t.Fatalf("uploaded dump = %s", data)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(src)); len(entries) != 1 {
+		t.Fatalf("the rewrite wrote %d files next to the backup", len(entries))
+	}
+}
+
+func TestCompatFilterUnneeded(t *testing.T) {
+	src := writeCompatTestDump(t)
+	for name, tc := range map[string]struct {
+		req  RestoreRequest
+		dest string
+	}{
+		"same flavor": {RestoreRequest{LocalPath: src, SourceVersion: "mysqldump  Ver 8.0.36 for Linux"}, "mysql  Ver 8.4.0 for Linux"},
+		"incremental": {RestoreRequest{LocalPath: src, Incremental: true}, "10.6.16-MariaDB"},
+		"unknown":     {RestoreRequest{LocalPath: src}, ""},
+	} {
+		if _, ok := compatFilter(tc.req, tc.dest); ok {
+			t.Fatalf("%s: should not be rewritten", name)
+		}
+	}
+}
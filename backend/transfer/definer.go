@@ -0,0 +1,33 @@
+package transfer
+
+import (
+	"errors"
+	"io"
+
+	"dback/backend/definer"
+	"dback/models"
+)
+
+// definerFilter returns the filter handling the dump's DEFINER clauses as req.Definer says
+// and logging every object it changed. ok is false for requests that keep the clauses.
+func definerFilter(req RestoreRequest) (f restoreFilter, ok bool, err error) {
+	mode := req.Definer
+	if mode == models.DefinerKeep {
+		return restoreFilter{}, false, nil
+	}
+	if req.Incremental {
+		return restoreFilter{}, false, errors.New("DEFINER clauses cannot be rewritten in binary log events")
+	}
+	return restoreFilter{
+		phase:   "definer",
+		failure: "Could not rewrite DEFINER clauses",
+		action:  "rewrite DEFINER clauses",
+		apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+			report, err := definer.Apply(r, w, mode)
+			if err != nil {
+				return filterReport{}, err
+			}
+			return filterReport{summary: report.Summary()}, nil
+		},
+	}, true, nil
+}
@@ -0,0 +1,41 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestDefinerRestoreStreamsRewrittenDump(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz")
+	writeGzip(t, src, "CREATE DEFINER=`root`@`localhost` PROCEDURE `refresh`()\nBEGIN SELECT 1; END ;;\n")
+
+	if unchanged := mustRestoreInput(t, RestoreRequest{LocalPath: src}); unchanged.rewrite {
+		t.Fatal("keep: the upload should be the file")
+	}
+
+	logger := &recordingLogger{}
+	input := mustRestoreInput(t, RestoreRequest{LocalPath: src, Logger: logger, Definer: models.DefinerStrip})
+	exec := &pipeExecutor{}
+	if err := restoreStream(context.Background(), exec, models.Profile{TargetDBName: "shop"}, input, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || !logger.has("definer||Succeeded") {
+		t.Fatalf("sessions = %d, log = %v", len(exec.sessions), logger.entries)
+	}
+	if data := exec.sessions[0]; !strings.Contains(data, "CREATE PROCEDURE `refresh`()") || strings.Contains(data, "DEFINER=") {
+		t.Fatalf("uploaded dump = %s", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("the rewrite wrote %d files next to the backup", len(entries))
+	}
+
+	if _, err := restoreFilters(RestoreRequest{LocalPath: src, Definer: models.DefinerStrip, Incremental: true}); err == nil {
+		t.Fatal("binary log replays should refuse DEFINER rewriting")
+	}
+}
@@ -0,0 +1,71 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"dback/backend/codec"
+	"dback/backend/crypt"
+)
+
+// createRestoreFile creates a working file of a restore that only its owner can read: the
+// copies a restore works on hold the backup's rows.
+func createRestoreFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return nil, err
+	}
+	// A file left by an earlier restore keeps its mode through O_TRUNC.
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// checkRestoreKey makes sure req.Keys open an encrypted backup before the restore touches
+// the server. The restore decrypts the file as it reads it (see restoreInput), so its
+// plaintext is never written to disk. Plain files pass unchecked.
+func checkRestoreKey(req RestoreRequest) error {
+	encrypted, err := crypt.IsEncryptedFile(req.LocalPath)
+	if err != nil || !encrypted {
+		return err
+	}
+	if err := crypt.CheckKey(req.LocalPath, req.Keys); err != nil {
+		logRestore(req, "decrypt", "", 0, "Could not decrypt backup file", "Failed", err.Error())
+		return fmt.Errorf("decrypt backup: %w", err)
+	}
+	logRestore(req, "decrypt", "", 0, "Decrypting backup as it is uploaded", "Succeeded", "")
+	return nil
+}
+
+// encryptedCompression returns the codec of the dump inside an encrypted backup.
+func encryptedCompression(path string, keys crypt.Keys) (string, error) {
+	in, err := crypt.Open(path, keys)
+	if err != nil {
+		return "", fmt.Errorf("decrypt backup: %w", err)
+	}
+	defer in.Close()
+	head := make([]byte, 6)
+	n, err := io.ReadFull(in, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("decrypt backup: %w", err)
+	}
+	return string(codec.Sniff(head[:n])), nil
+}
+
+// decryptReader names the errors of a crypt reader, which otherwise surface as those of
+// whatever stage reads it.
+type decryptReader struct {
+	r io.Reader
+}
+
+func (d decryptReader) Read(p []byte) (int, error) {
+	n, err := d.r.Read(p)
+	if err != nil && err != io.EOF {
+		err = fmt.Errorf("decrypt backup: %w", err)
+	}
+	return n, err
+}
@@ -0,0 +1,154 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"dback/backend/crypt"
+	"dback/models"
+)
+
+func writeEncryptedDump(t *testing.T, path string, keys crypt.Keys) {
+	t.Helper()
+	plain := filepath.Join(t.TempDir(), "plain.sql.gz")
+	writeTestDump(t, plain)
+	data, err := os.ReadFile(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sealed bytes.Buffer
+	w, err := crypt.NewWriter(&sealed, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(data)
+	w.Close()
+	if err := os.WriteFile(path, sealed.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCheckRestoreKey(t *testing.T) {
+	key, _ := crypt.NewKey()
+	keys := crypt.Keys{Vault: key}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz.enc")
+	writeEncryptedDump(t, src, keys)
+
+	if err := checkRestoreKey(RestoreRequest{LocalPath: src, Logger: &recordingLogger{}}); !errors.Is(err, crypt.ErrNoKey) {
+		t.Fatalf("without keys: got %v, want ErrNoKey", err)
+	}
+	logger := &recordingLogger{}
+	if err := checkRestoreKey(RestoreRequest{LocalPath: src, Keys: keys, Logger: logger}); err != nil || !logger.has("decrypt||Succeeded") {
+		t.Fatalf("err = %v, log = %v", err, logger.entries)
+	}
+	plain := filepath.Join(dir, "shop_02.sql.gz")
+	writeTestDump(t, plain)
+	if err := checkRestoreKey(RestoreRequest{LocalPath: plain}); err != nil {
+		t.Fatalf("plain dump: %v", err)
+	}
+}
+
+func TestRestoreStreamsEncryptedBackup(t *testing.T) {
+	key, _ := crypt.NewKey()
+	keys := crypt.Keys{Vault: key}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz.enc")
+	writeEncryptedDump(t, src, keys)
+	ctx := context.Background()
+
+	input := mustRestoreInput(t, RestoreRequest{LocalPath: src, Keys: keys, Logger: &recordingLogger{}})
+	if !input.rewrite || input.uploadCompression() != "gzip" {
+		t.Fatalf("rewrite = %v, upload compression = %s", input.rewrite, input.uploadCompression())
+	}
+	exec := &pipeExecutor{}
+	if err := restoreStream(ctx, exec, models.Profile{TargetDBName: "shop"}, input, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || exec.sessions[0] != splitTestDump {
+		t.Fatalf("uploaded %q", exec.sessions)
+	}
+	if _, err := input.open(10, nil); err == nil {
+		t.Fatal("a decrypted upload should not resume")
+	}
+	if _, _, err := planParallelRestore(input, src); err == nil {
+		t.Fatal("a parallel restore would write the plaintext to disk")
+	}
+
+	logger := &recordingLogger{}
+	req, cleanup, err := prepareRestoreFile(RestoreRequest{
+		LocalPath: src,
+		Keys:      keys,
+		Logger:    logger,
+		Tables:    &models.TableSelection{Tables: []string{"users"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if req.LocalPath != src {
+		t.Fatalf("tables were extracted into %s", req.LocalPath)
+	}
+	exec = &pipeExecutor{}
+	if err := restoreStream(ctx, exec, models.Profile{TargetDBName: "shop"}, mustRestoreInput(t, req), nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || !strings.Contains(exec.sessions[0], "CREATE TABLE `users`") || strings.Contains(exec.sessions[0], "`orders`") {
+		t.Fatalf("uploaded %q", exec.sessions)
+	}
+	if !logger.has("tables||Succeeded") {
+		t.Fatalf("log = %v", logger.entries)
+	}
+
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("restores left files behind: %v", entries)
+	}
+
+	tables, err := ListBackupTables(src, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"orders", "users"}; !reflect.DeepEqual(tables, want) {
+		t.Fatalf("tables = %v, want %v", tables, want)
+	}
+}
+
+func TestRestoreInputRecompressesEncryptedDumpAsGzip(t *testing.T) {
+	key, _ := crypt.NewKey()
+	keys := crypt.Keys{Vault: key}
+	src := filepath.Join(t.TempDir(), "shop_01.sql.enc")
+	var sealed bytes.Buffer
+	w, err := crypt.NewWriter(&sealed, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte(splitTestDump))
+	w.Close()
+	if err := os.WriteFile(src, sealed.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	input := mustRestoreInput(t, RestoreRequest{LocalPath: src, Keys: keys})
+	if input.uploadCompression() != "none" || !input.gzipOnly() || input.uploadCompression() != "gzip" {
+		t.Fatalf("compression = %s, upload = %s", input.compression, input.uploadCompression())
+	}
+	body, err := input.open(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := gunzipString(t, data); got != splitTestDump {
+		t.Fatalf("uploaded %q", got)
+	}
+}
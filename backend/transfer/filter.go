@@ -0,0 +1,342 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"dback/backend/codec"
+	"dback/backend/crypt"
+	"dback/models"
+)
+
+// restoreFilter is one rewrite of a dump's SQL text on its way to the server.
+type restoreFilter struct {
+	// phase names the rewrite in the activity log, failure is logged when it fails and
+	// action prefixes its errors.
+	phase   string
+	failure string
+	action  string
+	// apply copies the SQL text of r to w rewritten and reports what it changed.
+	apply func(r io.Reader, w io.Writer) (filterReport, error)
+}
+
+// filterReport is what one pass of a filter over a whole dump changed.
+type filterReport struct {
+	summary  string
+	warnings []string
+}
+
+// errFilterStopped ends the filters of an upload that stopped reading them.
+var errFilterStopped = errors.New("restore upload stopped")
+
+// restoreFilters returns the rewrites req asks for, in the order they run.
+func restoreFilters(req RestoreRequest) ([]restoreFilter, error) {
+	var filters []restoreFilter
+	for _, build := range []func(RestoreRequest) (restoreFilter, bool, error){tablesFilter, replaceFilter, definerFilter, maskFilter} {
+		f, ok, err := build(req)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			filters = append(filters, f)
+		}
+	}
+	return filters, nil
+}
+
+// restoreInput is what a restore uploads: the file req points at as it is or, with
+// filters, the SQL text of that file rewritten by them as it is read and compressed as
+// gzip again. An encrypted file is decrypted as it is read. Nothing decrypted or rewritten
+// is written to disk, so such an upload has no size ahead of time and always starts from
+// the beginning.
+type restoreInput struct {
+	req  RestoreRequest
+	path string
+	// size is that of the file, which progress counts, and compression the codec of the
+	// dump in it.
+	size        int64
+	compression string
+	filters     []restoreFilter
+	// encrypted is set when the file is decrypted with keys as it is read.
+	encrypted bool
+	keys      crypt.Keys
+	// rewrite is set when the upload is produced as it is read: decrypted, rewritten by
+	// filters or recompressed. recompress is set when it is the SQL text compressed as gzip
+	// again.
+	rewrite    bool
+	recompress bool
+
+	mu     sync.Mutex
+	logged bool
+}
+
+func newRestoreInput(req RestoreRequest, filters []restoreFilter) (*restoreInput, error) {
+	f, err := os.Open(req.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	encrypted, err := crypt.IsEncryptedFile(req.LocalPath)
+	if err != nil {
+		return nil, err
+	}
+	var compression string
+	if encrypted {
+		compression, err = encryptedCompression(req.LocalPath, req.Keys)
+	} else {
+		compression, err = detectCompression(f)
+	}
+	if err != nil {
+		return nil, err
+	}
+	size := req.FileSize
+	if info, err := f.Stat(); err == nil && size <= 0 {
+		size = info.Size()
+	}
+	return &restoreInput{
+		req:         req,
+		path:        req.LocalPath,
+		size:        size,
+		compression: compression,
+		filters:     filters,
+		encrypted:   encrypted,
+		keys:        req.Keys,
+		rewrite:     encrypted || len(filters) > 0,
+		recompress:  len(filters) > 0,
+	}, nil
+}
+
+// addFilter appends a rewrite decided after the input was made, such as the compatibility
+// rewrite, which needs the destination's version.
+func (in *restoreInput) addFilter(f restoreFilter) {
+	in.filters = append(in.filters, f)
+	in.rewrite, in.recompress = true, true
+}
+
+// gzipOnly makes the upload gzip whatever the codec of the file, recompressing it as it is
+// read. It reports whether that changed anything.
+func (in *restoreInput) gzipOnly() bool {
+	if in.recompress || in.compression == string(models.CompressionGzip) {
+		return false
+	}
+	in.rewrite, in.recompress = true, true
+	return true
+}
+
+// uploadCompression is the codec of what open returns.
+func (in *restoreInput) uploadCompression() string {
+	if in.recompress {
+		return string(models.CompressionGzip)
+	}
+	return in.compression
+}
+
+// open returns the upload from offset on. progress, when set, is told how far into the
+// file reading has got, out of in.size. A decrypted or rewritten upload always starts at 0.
+func (in *restoreInput) open(offset int64, progress func(current int64)) (io.ReadCloser, error) {
+	if in.rewrite && offset > 0 {
+		return nil, errors.New("a decrypted or rewritten restore cannot resume")
+	}
+	if in.recompress {
+		return in.openStream(progress, true)
+	}
+	r, f, err := in.openFile(offset, progress)
+	if err != nil {
+		return nil, err
+	}
+	return readCloser{Reader: r, Closer: f}, nil
+}
+
+// openFile opens the file from offset on and returns a reader of the dump in its codec,
+// decrypted when the file is encrypted, and the file to close.
+func (in *restoreInput) openFile(offset int64, progress func(current int64)) (io.Reader, *os.File, error) {
+	f, err := os.Open(in.path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if offset > 0 {
+		if _, err := f.Seek(offset, io.SeekStart); err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+	}
+	var r io.Reader = f
+	if progress != nil {
+		r = &progressReader{reader: f, total: offset, callback: progress}
+	}
+	if in.encrypted {
+		dr, err := crypt.NewReader(r, in.keys)
+		if err != nil {
+			f.Close()
+			return nil, nil, fmt.Errorf("decrypt backup: %w", err)
+		}
+		r = decryptReader{r: dr}
+	}
+	return r, f, nil
+}
+
+// openSQL returns the SQL text of the file, decrypted and rewritten by the filters.
+func (in *restoreInput) openSQL(progress func(current int64)) (io.ReadCloser, error) {
+	return in.openStream(progress, false)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// openStream starts the filters over the file, each in its own goroutine joined to the
+// next by a pipe, followed by gzip compression when compress is set.
+func (in *restoreInput) openStream(progress func(current int64), compress bool) (io.ReadCloser, error) {
+	raw, f, err := in.openFile(0, progress)
+	if err != nil {
+		return nil, err
+	}
+	src, err := codec.NewReader(raw, models.Compression(in.compression))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s := &filterStream{input: in, file: f, src: src, failedStage: -1}
+	stages := make([]func(io.Reader, io.Writer) error, 0, len(in.filters)+1)
+	s.reports = make([]filterReport, len(in.filters))
+	for i, filter := range in.filters {
+		i, filter := i, filter
+		s.names = append(s.names, filter.action)
+		stages = append(stages, func(r io.Reader, w io.Writer) error {
+			report, err := filter.apply(r, w)
+			s.reports[i] = report
+			return err
+		})
+	}
+	if compress {
+		s.names = append(s.names, "compress")
+		stages = append(stages, gzipStage)
+	}
+	s.start(stages)
+	return s, nil
+}
+
+// gzipStage compresses r into w.
+func gzipStage(r io.Reader, w io.Writer) error {
+	gw := gzip.NewWriter(w)
+	_, err := io.Copy(gw, r)
+	if closeErr := gw.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// filterStream reads the output of a restoreInput's filters. A read error is the error of
+// the stage that failed first, named for it; closing the stream stops every stage and logs
+// the filters' reports once a pass has read the whole dump.
+type filterStream struct {
+	input *restoreInput
+	file  *os.File
+	src   io.ReadCloser
+	out   *io.PipeReader
+	wg    sync.WaitGroup
+
+	names   []string
+	reports []filterReport
+	done    bool
+
+	mu          sync.Mutex
+	stopped     bool
+	failed      error
+	failedStage int
+}
+
+func (s *filterStream) start(stages []func(io.Reader, io.Writer) error) {
+	var r io.Reader = s.src
+	for i, stage := range stages {
+		pr, pw := io.Pipe()
+		s.wg.Add(1)
+		go func(i int, stage func(io.Reader, io.Writer) error, r io.Reader) {
+			defer s.wg.Done()
+			err := stage(r, pw)
+			if err != nil {
+				s.fail(i, err)
+			}
+			if up, ok := r.(*io.PipeReader); ok {
+				// Stop the stage before this one if it is still writing.
+				up.CloseWithError(errFilterStopped)
+			}
+			pw.CloseWithError(err)
+		}(i, stage, r)
+		r = pr
+	}
+	s.out = r.(*io.PipeReader)
+}
+
+// fail records the first stage error. Errors passed along from a stage that already
+// failed come later, and those of stages the stream stopped are not failures.
+func (s *filterStream) fail(stage int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped || s.failed != nil || errors.Is(err, errFilterStopped) {
+		return
+	}
+	s.failed = fmt.Errorf("%s: %w", s.names[stage], err)
+	s.failedStage = stage
+}
+
+func (s *filterStream) Read(p []byte) (int, error) {
+	n, err := s.out.Read(p)
+	switch {
+	case err == io.EOF:
+		s.done = true
+	case err != nil:
+		s.mu.Lock()
+		if s.failed != nil {
+			err = s.failed
+		}
+		s.mu.Unlock()
+	}
+	return n, err
+}
+
+func (s *filterStream) Close() error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.out.CloseWithError(errFilterStopped)
+	// Closing the file ends a first stage still reading it.
+	_ = s.file.Close()
+	s.wg.Wait()
+	_ = s.src.Close()
+	s.input.logFilters(s)
+	return nil
+}
+
+// logFilters logs what each filter did after the first pass that read the whole dump, or
+// the filter that failed.
+func (in *restoreInput) logFilters(s *filterStream) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.logged {
+		return
+	}
+	if s.failed != nil {
+		if s.failedStage < len(in.filters) {
+			in.logged = true
+			filter := in.filters[s.failedStage]
+			logRestore(in.req, filter.phase, "", 0, filter.failure, "Failed", s.failed.Error())
+		}
+		return
+	}
+	if !s.done {
+		return
+	}
+	in.logged = true
+	for i, filter := range in.filters {
+		logRestore(in.req, filter.phase, "", 0, s.reports[i].summary, "Succeeded", "")
+		for _, warning := range s.reports[i].warnings {
+			logRestore(in.req, filter.phase, "", 0, warning, "Warning", "")
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package transfer
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// mustRestoreInput builds the upload of req with the filters it asks for.
+func mustRestoreInput(t *testing.T, req RestoreRequest) *restoreInput {
+	t.Helper()
+	filters, err := restoreFilters(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input, err := newRestoreInput(req, filters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input
+}
+
+func upperFilter(phase string) restoreFilter {
+	return restoreFilter{phase: phase, failure: "Could not " + phase, action: phase, apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return filterReport{}, err
+		}
+		_, err = w.Write([]byte(strings.ToUpper(string(data))))
+		return filterReport{summary: phase + " done"}, err
+	}}
+}
+
+func TestRestoreInputRunsFiltersInOrder(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	writeGzip(t, src, "insert into t values ('a');\n")
+	logger := &recordingLogger{}
+	prefix := restoreFilter{phase: "prefix", action: "prefix", apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+		if _, err := io.WriteString(w, "-- rewritten\n"); err != nil {
+			return filterReport{}, err
+		}
+		_, err := io.Copy(w, r)
+		return filterReport{summary: "prefixed", warnings: []string{"check me"}}, err
+	}}
+	input, err := newRestoreInput(RestoreRequest{LocalPath: src, Logger: logger}, []restoreFilter{upperFilter("upper"), prefix})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input.uploadCompression() != "gzip" {
+		t.Fatalf("upload compression = %s", input.uploadCompression())
+	}
+	for pass := 0; pass < 2; pass++ {
+		var read int64
+		body, err := input.open(0, func(current int64) { read = current })
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body.Close()
+		if got := gunzipString(t, data); got != "-- rewritten\nINSERT INTO T VALUES ('A');\n" {
+			t.Fatalf("pass %d uploaded %q", pass, got)
+		}
+		if read != input.size {
+			t.Fatalf("progress = %d of %d", read, input.size)
+		}
+	}
+	if got := strings.Join(logger.entries, ","); got != "upper||Succeeded,prefix||Succeeded,prefix||Warning" {
+		t.Fatalf("log = %s", got)
+	}
+	if _, err := input.open(10, nil); err == nil {
+		t.Fatal("a rewritten upload should not resume")
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("filters wrote %d files", len(entries))
+	}
+}
+
+func TestRestoreInputNamesFailingFilter(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "shop.sql.gz")
+	writeGzip(t, src, "SELECT 1;\n")
+	logger := &recordingLogger{}
+	broken := restoreFilter{phase: "broken", failure: "Could not break", action: "break dump", apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+		return filterReport{}, errors.New("unbalanced quote")
+	}}
+	input, err := newRestoreInput(RestoreRequest{LocalPath: src, Logger: logger}, []restoreFilter{upperFilter("upper"), broken, upperFilter("after")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := input.open(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = io.ReadAll(body)
+	body.Close()
+	if err == nil || err.Error() != "break dump: unbalanced quote" {
+		t.Fatalf("err = %v", err)
+	}
+	if !logger.has("broken||Failed") || logger.has("upper||Succeeded") {
+		t.Fatalf("log = %v", logger.entries)
+	}
+}
+
+func TestRestoreInputStopsWhenClosedEarly(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "shop.sql.gz")
+	writeGzip(t, src, strings.Repeat("INSERT INTO `t` VALUES (1,'some text');\n", 200000))
+	logger := &recordingLogger{}
+	input, err := newRestoreInput(RestoreRequest{LocalPath: src, Logger: logger}, []restoreFilter{upperFilter("upper")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := input.open(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadFull(body, make([]byte, 64)); err != nil {
+		t.Fatal(err)
+	}
+	body.Close()
+	if len(logger.entries) != 0 {
+		t.Fatalf("an unfinished pass logged %v", logger.entries)
+	}
+}
+
+func TestRestoreInputWithoutFiltersReadsFile(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "shop.sql.gz")
+	writeGzip(t, src, "SELECT 1;\n")
+	input := mustRestoreInput(t, RestoreRequest{LocalPath: src})
+	if input.rewrite || input.uploadCompression() != "gzip" {
+		t.Fatalf("input = %+v", input)
+	}
+	raw, _ := os.ReadFile(src)
+	body, err := input.open(4, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, _ := io.ReadAll(body)
+	if string(data) != string(raw[4:]) {
+		t.Fatal("open should resume from the offset")
+	}
+}
@@ -0,0 +1,163 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"dback/backend/db"
+	"dback/backend/ssh"
+	"dback/backend/wordpress"
+	"dback/models"
+)
+
+// Hook stages, used as the log phase.
+const (
+	hookPreExport  = "pre-export hook"
+	hookPostExport = "post-export hook"
+)
+
+// postHookTimeout bounds post-export hooks, which still run after the backup was canceled.
+const postHookTimeout = 5 * time.Minute
+
+// maxHookOutput caps hook output copied into the activity log.
+const maxHookOutput = 500
+
+// exportHooks runs hook SQL and shell commands on a host. shell is nil when the host has no
+// remote shell (WordPress).
+type exportHooks struct {
+	sql   func(ctx context.Context, query string) (string, error)
+	shell func(ctx context.Context, command string) (string, error)
+}
+
+func sshExportHooks(client ssh.Executor, p models.Profile) exportHooks {
+	return exportHooks{
+		sql: func(ctx context.Context, query string) (string, error) {
+			cmd, err := db.BuildQueryCommand(p, query, true)
+			if err != nil {
+				return "", err
+			}
+			return runHookCommand(ctx, client, cmd)
+		},
+		shell: func(ctx context.Context, command string) (string, error) {
+			return runHookCommand(ctx, client, command)
+		},
+	}
+}
+
+func wordpressExportHooks(client *wordpress.Client, p models.Profile) exportHooks {
+	return exportHooks{
+		sql: func(ctx context.Context, query string) (string, error) {
+			result, err := client.Query(ctx, query, db.WordPressImportDatabase(p))
+			if err != nil {
+				return "", err
+			}
+			if result.Message != "" {
+				return result.Message, nil
+			}
+			return fmt.Sprintf("%d row(s) returned", len(result.Rows)), nil
+		},
+	}
+}
+
+func runHookCommand(ctx context.Context, client ssh.Executor, cmd string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	out, err := client.RunCommand(cmd)
+	out = strings.TrimSpace(out)
+	if err != nil && out != "" {
+		return out, fmt.Errorf("%w: %s", err, truncateHookOutput(out))
+	}
+	return out, err
+}
+
+// runExportHooks runs the profile's hooks for one stage. Pre-export runs the shell command
+// before the SQL, post-export the SQL before the shell command, so they nest around the dump.
+// A failure is returned only when the profile aborts on hook failures.
+func runExportHooks(ctx context.Context, req BackupRequest, stage string, hooks exportHooks) error {
+	p := req.Profile
+	query, command := p.PreExportQuery, p.PreExportCommand
+	if stage == hookPostExport {
+		query, command = p.PostExportQuery, p.PostExportCommand
+	}
+	query = strings.TrimSpace(models.SubstituteQuery(query, p.QueryVars()))
+	command = strings.TrimSpace(command)
+
+	runSQL := func() error {
+		if query == "" {
+			return nil
+		}
+		out, err := hooks.sql(ctx, query)
+		return logHookResult(req, stage, "sql", out, err)
+	}
+	runShell := func() error {
+		if command == "" {
+			return nil
+		}
+		if hooks.shell == nil {
+			logReq(req, stage, "shell", 0, "Remote shell hooks are not supported for this host", "Skipped", "")
+			return nil
+		}
+		out, err := hooks.shell(ctx, command)
+		return logHookResult(req, stage, "shell", out, err)
+	}
+
+	var errs []error
+	if stage == hookPreExport {
+		errs = append(errs, runShell(), runSQL())
+	} else {
+		errs = append(errs, runSQL(), runShell())
+	}
+	err := errors.Join(errs...)
+	if err == nil {
+		return nil
+	}
+	if !p.AbortOnExportHookFailure {
+		logReq(req, stage, "", 0, "Hook failed; continuing because this host does not abort on hook failures", "Warning", err.Error())
+		return nil
+	}
+	return fmt.Errorf("%s failed: %w", stage, err)
+}
+
+func logHookResult(req BackupRequest, stage, kind, out string, err error) error {
+	if err != nil {
+		logReq(req, stage, kind, 0, "Hook failed", "Failed", err.Error())
+		return err
+	}
+	details := "Hook completed"
+	if out != "" {
+		details += ": " + truncateHookOutput(out)
+	}
+	logReq(req, stage, kind, 0, details, "Succeeded", "")
+	return nil
+}
+
+func truncateHookOutput(out string) string {
+	if len(out) <= maxHookOutput {
+		return out
+	}
+	return out[:maxHookOutput] + "..."
+}
+
+// withExportHooks runs pre-export hooks, the dump, then post-export hooks. Post hooks run
+// even when the pre hooks or the dump fail, so e.g. maintenance mode is always switched off.
+// With AbortOnExportHookFailure a failing post hook fails the backup and removes its files.
+func withExportHooks(ctx context.Context, req BackupRequest, hooks exportHooks, dump func() (BackupResult, error)) (BackupResult, error) {
+	result, err := BackupResult{}, runExportHooks(ctx, req, hookPreExport, hooks)
+	if err == nil {
+		result, err = dump()
+	}
+	postCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), postHookTimeout)
+	defer cancel()
+	if hookErr := runExportHooks(postCtx, req, hookPostExport, hooks); hookErr != nil && err == nil {
+		for _, f := range result.Files {
+			_ = os.Remove(f.Path)
+		}
+		return BackupResult{}, hookErr
+	}
+	return result, err
+}
@@ -0,0 +1,138 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+type recordingLogger struct {
+	entries []string
+}
+
+func (l *recordingLogger) Phase(action, phase, strategy string, attempt int, details, status, errStr string) {
+	l.entries = append(l.entries, phase+"|"+strategy+"|"+status)
+}
+
+func (l *recordingLogger) has(entry string) bool {
+	for _, e := range l.entries {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
+
+func fakeHooks(calls *[]string, failing string) exportHooks {
+	run := func(kind string) func(context.Context, string) (string, error) {
+		return func(_ context.Context, s string) (string, error) {
+			*calls = append(*calls, kind+":"+s)
+			if s == failing {
+				return "", errors.New("hook broke")
+			}
+			return "ok", nil
+		}
+	}
+	return exportHooks{sql: run("sql"), shell: run("shell")}
+}
+
+func hookProfile(abort bool) models.Profile {
+	return models.Profile{
+		Name:                     "Prod",
+		TargetDBName:             "shop",
+		PreExportCommand:         "maintenance on",
+		PreExportQuery:           "FLUSH LOGS; -- {databasename}",
+		PostExportQuery:          "SELECT 1",
+		PostExportCommand:        "maintenance off",
+		AbortOnExportHookFailure: abort,
+	}
+}
+
+func TestExportHooksRunAroundDump(t *testing.T) {
+	var calls []string
+	logger := &recordingLogger{}
+	req := BackupRequest{Profile: hookProfile(false), Logger: logger}
+	_, err := withExportHooks(context.Background(), req, fakeHooks(&calls, ""), func() (BackupResult, error) {
+		calls = append(calls, "dump")
+		return BackupResult{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "shell:maintenance on,sql:FLUSH LOGS; -- shop,dump,sql:SELECT 1,shell:maintenance off"
+	if got := strings.Join(calls, ","); got != want {
+		t.Fatalf("unexpected order:\n got %s\nwant %s", got, want)
+	}
+	if !logger.has(hookPreExport+"|shell|Succeeded") || !logger.has(hookPostExport+"|sql|Succeeded") {
+		t.Fatalf("hooks not logged: %v", logger.entries)
+	}
+}
+
+func TestExportHookFailureWithoutAbortContinues(t *testing.T) {
+	var calls []string
+	logger := &recordingLogger{}
+	req := BackupRequest{Profile: hookProfile(false), Logger: logger}
+	dumped := false
+	_, err := withExportHooks(context.Background(), req, fakeHooks(&calls, "maintenance on"), func() (BackupResult, error) {
+		dumped = true
+		return BackupResult{}, nil
+	})
+	if err != nil || !dumped {
+		t.Fatalf("expected backup to continue, err=%v dumped=%v", err, dumped)
+	}
+	if !logger.has(hookPreExport + "|shell|Failed") {
+		t.Fatalf("expected failed hook to be logged: %v", logger.entries)
+	}
+}
+
+func TestExportPreHookFailureAborts(t *testing.T) {
+	var calls []string
+	req := BackupRequest{Profile: hookProfile(true), Logger: &recordingLogger{}}
+	_, err := withExportHooks(context.Background(), req, fakeHooks(&calls, "maintenance on"), func() (BackupResult, error) {
+		t.Fatal("dump must not run after an aborting pre hook")
+		return BackupResult{}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), hookPreExport) {
+		t.Fatalf("expected pre hook error, got %v", err)
+	}
+	if calls[len(calls)-1] != "shell:maintenance off" {
+		t.Fatalf("post hooks must still run: %v", calls)
+	}
+}
+
+func TestExportPostHookFailureAbortsAndRemovesFile(t *testing.T) {
+	var calls []string
+	path := filepath.Join(t.TempDir(), "prod.sql.gz")
+	if err := os.WriteFile(path, []byte("dump"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	req := BackupRequest{Profile: hookProfile(true), Logger: &recordingLogger{}}
+	_, err := withExportHooks(context.Background(), req, fakeHooks(&calls, "SELECT 1"), func() (BackupResult, error) {
+		return BackupResult{Path: path, Size: 4, Files: []BackupFile{{Database: "prod", Path: path, Size: 4}}}, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), hookPostExport) {
+		t.Fatalf("expected post hook error, got %v", err)
+	}
+	if _, statErr := os.Stat(path); !os.IsNotExist(statErr) {
+		t.Fatalf("expected backup file to be removed, stat err=%v", statErr)
+	}
+}
+
+func TestExportShellHooksSkippedWithoutShell(t *testing.T) {
+	var calls []string
+	logger := &recordingLogger{}
+	hooks := fakeHooks(&calls, "")
+	hooks.shell = nil
+	req := BackupRequest{Profile: hookProfile(true), Logger: logger}
+	if err := runExportHooks(context.Background(), req, hookPreExport, hooks); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 || !logger.has(hookPreExport+"|shell|Skipped") {
+		t.Fatalf("expected only SQL to run and shell to be skipped: calls=%v log=%v", calls, logger.entries)
+	}
+}
@@ -0,0 +1,93 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"dback/backend/chunkstore"
+	"dback/backend/codec"
+	"dback/backend/crypt"
+	"dback/backend/mask"
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+// maskFilter returns the filter applying the destination's masking rules to the dump on
+// its way to the server, so unmasked rows never leave this machine. ok is false for
+// destinations without rules.
+func maskFilter(req RestoreRequest) (f restoreFilter, ok bool, err error) {
+	rules := req.Profile.Masking
+	if len(rules) == 0 {
+		return restoreFilter{}, false, nil
+	}
+	if req.Incremental {
+		return restoreFilter{}, false, errors.New("binary log events cannot be masked")
+	}
+	if req.Profile.UsesPostgreSQL() {
+		return restoreFilter{}, false, errors.New("pg_dump writes rows as COPY data, which cannot be masked")
+	}
+	return restoreFilter{
+		phase:   "mask",
+		failure: "Could not mask backup file",
+		action:  "mask data",
+		apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+			report, err := mask.Apply(r, w, rules)
+			if err != nil {
+				return filterReport{}, err
+			}
+			rep := filterReport{summary: report.Summary()}
+			if len(report.Unused) > 0 {
+				rep.warnings = append(rep.warnings, "Rules matching no table in this backup: "+mask.FormatRules(report.Unused))
+			}
+			return rep, nil
+		},
+	}, true, nil
+}
+
+// PreviewMasking reports which columns of a backup file a restore with rules would mask,
+// without writing anything. It reads split archives and, with keys, encrypted dumps.
+func PreviewMasking(localPath string, keys crypt.Keys, rules []models.MaskRule) (mask.Report, error) {
+	r, err := openDump(localPath, keys)
+	if err != nil {
+		return mask.Report{}, fmt.Errorf("read backup: %w", err)
+	}
+	defer r.Close()
+	return mask.Preview(r, rules)
+}
+
+// openDump opens the SQL text of a backup file: a dump in any codec, an encrypted dump, or
+// a split archive or repository backup joined on the fly.
+func openDump(localPath string, keys crypt.Keys) (io.ReadCloser, error) {
+	if chunkstore.IsManifestPath(localPath) {
+		return chunkstore.Open(localPath)
+	}
+	if sqldump.IsSplitPath(localPath) {
+		pr, pw := io.Pipe()
+		go func() { pw.CloseWithError(sqldump.Join(localPath, pw, nil)) }()
+		gr, err := gzip.NewReader(pr)
+		if err != nil {
+			_ = pr.Close()
+			return nil, err
+		}
+		return joinedDump{Reader: gr, pipe: pr}, nil
+	}
+	if encrypted, err := crypt.IsEncryptedFile(localPath); err == nil && encrypted {
+		r, _, err := crypt.OpenDump(localPath, keys)
+		return r, err
+	}
+	r, _, err := codec.Open(localPath)
+	return r, err
+}
+
+// joinedDump reads a split archive as it is joined; closing it stops the join.
+type joinedDump struct {
+	*gzip.Reader
+	pipe *io.PipeReader
+}
+
+func (d joinedDump) Close() error {
+	_ = d.Reader.Close()
+	return d.pipe.Close()
+}
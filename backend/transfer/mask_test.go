@@ -0,0 +1,89 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dback/backend/crypt"
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+const maskTestDump = "SET NAMES utf8mb4;\n" +
+	"DROP TABLE IF EXISTS `customers`;\nCREATE TABLE `customers` (\n  `id` int NOT NULL,\n  `email` varchar(120) DEFAULT NULL\n);\n" +
+	"INSERT INTO `customers` VALUES (1,'ann@shop.example'),(2,NULL);\n" +
+	"/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n"
+
+var maskTestRules = []models.MaskRule{{Table: "customers", Column: "email", Strategy: models.MaskKeepDomain}}
+
+func writeMaskTestDump(t *testing.T, path string) {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(maskTestDump))
+	gz.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMaskedRestoreStreamsMaskedRows(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz")
+	writeMaskTestDump(t, src)
+	logger := &recordingLogger{}
+	input := mustRestoreInput(t, RestoreRequest{
+		Profile:   models.Profile{Masking: maskTestRules},
+		LocalPath: src,
+		Logger:    logger,
+	})
+	exec := &pipeExecutor{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := restoreStream(ctx, exec, models.Profile{TargetDBName: "shop"}, input, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || !logger.has("mask||Succeeded") {
+		t.Fatalf("sessions = %d, log = %v", len(exec.sessions), logger.entries)
+	}
+	if data := exec.sessions[0]; strings.Contains(data, "ann@") || !strings.Contains(data, "@shop.example'),(2,NULL);") {
+		t.Fatalf("uploaded dump = %s", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("masking wrote %d files next to the backup", len(entries))
+	}
+
+	if _, err := restoreFilters(RestoreRequest{Profile: models.Profile{Masking: maskTestRules}, Incremental: true}); err == nil {
+		t.Fatal("binary log events should not be masked")
+	}
+	if _, err := restoreFilters(RestoreRequest{Profile: models.Profile{DBType: models.DBTypePostgreSQL, Masking: maskTestRules}}); err == nil {
+		t.Fatal("pg_dump rows should not pass through masking unmasked")
+	}
+	// Without rules the file is restored as it is.
+	if plain := mustRestoreInput(t, RestoreRequest{LocalPath: src}); plain.rewrite {
+		t.Fatal("no rules: the upload should be the file")
+	}
+}
+
+func TestPreviewMaskingReadsSplitArchive(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz")
+	writeMaskTestDump(t, src)
+	dst := sqldump.SplitPath(src)
+	if _, err := sqldump.SplitFile(src, dst, sqldump.Manifest{Database: "shop"}); err != nil {
+		t.Fatal(err)
+	}
+	rules := append(maskTestRules, models.MaskRule{Table: "orders", Column: "note", Strategy: models.MaskNull})
+	rep, err := PreviewMasking(dst, crypt.Keys{}, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rep.Columns) != 1 || rep.Columns[0].Values != 1 || len(rep.Unused) != 1 {
+		t.Fatalf("report = %+v", rep)
+	}
+}
@@ -8,6 +8,12 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"dback/backend/chunkstore"
+	"dback/backend/crypt"
+	"dback/backend/sqldump"
+	"dback/models"
 )
 
 const metaSuffix = ".dback-meta.json"
@@ -50,6 +56,39 @@ func removeMeta(localPath string) {
 	_ = os.Remove(metaPathFor(localPath))
 }
 
+// FindResumableBackup returns the partial backup file an interrupted tmp-file download of
+// operationID left under destination, if its resume metadata is still there.
+func FindResumableBackup(destination string, p models.Profile, operationID string) (string, bool) {
+	if operationID == "" {
+		return "", false
+	}
+	matches, err := filepath.Glob(filepath.Join(destination, safeName(p.Name), "*"+metaSuffix))
+	if err != nil {
+		return "", false
+	}
+	for _, metaPath := range matches {
+		localPath := strings.TrimSuffix(metaPath, metaSuffix)
+		if meta, ok := loadMeta(localPath); ok && meta.OperationID == operationID && meta.LocalPath == localPath {
+			return localPath, true
+		}
+	}
+	return "", false
+}
+
+// HasResumableRestore reports whether an interrupted tmp-file upload of operationID can continue.
+func HasResumableRestore(localPath, operationID string) bool {
+	if sqldump.IsSplitPath(localPath) {
+		localPath = joinedRestorePath(localPath)
+	} else if chunkstore.IsManifestPath(localPath) {
+		localPath = reassembledRestorePath(localPath)
+	} else if strings.HasSuffix(localPath, crypt.Ext) {
+		// Each restore decrypts into a temporary file of its own; it starts over.
+		return false
+	}
+	meta, ok := loadMeta(localPath)
+	return ok && operationID != "" && meta.OperationID == operationID && meta.Offset > 0
+}
+
 func checksumFile(path string) (string, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -111,6 +150,45 @@ func openLocalAppend(path string, offset int64) (*os.File, error) {
 	return f, nil
 }
 
+// openEncryptedAppend opens an encrypted download for writing. When resuming, the file's
+// whole chunks are kept and offset is the plaintext they hold; otherwise, or when the file
+// cannot be resumed, it starts over.
+func openEncryptedAppend(path string, keys crypt.Keys, resume bool) (*os.File, *crypt.Writer, int64, error) {
+	if resume {
+		if f, err := os.OpenFile(path, os.O_RDWR, 0600); err == nil {
+			if w, offset, err := crypt.Resume(f, keys); err == nil {
+				return f, w, offset, nil
+			}
+			_ = f.Close()
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	w, err := crypt.NewWriter(f, keys)
+	if err != nil {
+		_ = f.Close()
+		return nil, nil, 0, fmt.Errorf("encrypt backup: %w", err)
+	}
+	return f, w, 0, nil
+}
+
+// checksumEncrypted returns the SHA256 of an encrypted file's plaintext, to compare with the
+// checksum of the dump on the host.
+func checksumEncrypted(path string, keys crypt.Keys) (string, error) {
+	r, err := crypt.Open(path, keys)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 func remoteMetaPath(tmpDir string) string {
 	return filepath.Join(tmpDir, "meta.json")
 }
@@ -2,7 +2,10 @@ package transfer
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+
+	"dback/models"
 )
 
 func TestValidateLocalFileSize(t *testing.T) {
@@ -36,3 +39,44 @@ func TestSafeName(t *testing.T) {
 		t.Fatalf("unexpected safe name: %q", got)
 	}
 }
+
+func TestFindResumableBackup(t *testing.T) {
+	dest := t.TempDir()
+	p := models.Profile{Name: "Prod DB"}
+	hostDir := filepath.Join(dest, safeName(p.Name))
+	if err := os.MkdirAll(hostDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	localPath := filepath.Join(hostDir, "prod_20260101.sql.gz")
+	if err := saveMeta(FileMeta{OperationID: "op1", LocalPath: localPath, Offset: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if got, ok := FindResumableBackup(dest, p, "op1"); !ok || got != localPath {
+		t.Fatalf("expected %q, got %q (%v)", localPath, got, ok)
+	}
+	if _, ok := FindResumableBackup(dest, p, "op2"); ok {
+		t.Fatal("metadata of another operation must not match")
+	}
+	if _, ok := FindResumableBackup(dest, p, ""); ok {
+		t.Fatal("empty operation ID must not match")
+	}
+}
+
+func TestHasResumableRestore(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "dump.sql.gz")
+	if HasResumableRestore(localPath, "op1") {
+		t.Fatal("no metadata yet")
+	}
+	if err := saveMeta(FileMeta{OperationID: "op1", LocalPath: localPath}); err != nil {
+		t.Fatal(err)
+	}
+	if HasResumableRestore(localPath, "op1") {
+		t.Fatal("nothing uploaded yet, nothing to resume")
+	}
+	if err := saveMeta(FileMeta{OperationID: "op1", LocalPath: localPath, Offset: 4096}); err != nil {
+		t.Fatal(err)
+	}
+	if !HasResumableRestore(localPath, "op1") || HasResumableRestore(localPath, "op2") {
+		t.Fatal("expected only op1 to be resumable")
+	}
+}
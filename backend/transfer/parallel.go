@@ -0,0 +1,477 @@
+package transfer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"dback/backend/codec"
+	"dback/backend/db"
+	"dback/backend/sqldump"
+	"dback/backend/ssh"
+)
+
+// StrategyParallel imports the tables of a dump over several sessions at once.
+const StrategyParallel Strategy = "parallel"
+
+// MaxParallelRestore is the most import sessions a parallel restore opens.
+const MaxParallelRestore = 16
+
+// parallelSessionSetup opens every session of a parallel restore. Tables load in any order,
+// so foreign key and unique checks are off, as mysqldump's own header sets them; dumps
+// without that header (the WordPress plugin's) need it too.
+const parallelSessionSetup = "SET FOREIGN_KEY_CHECKS=0;\nSET UNIQUE_CHECKS=0;\n"
+
+// maxParallelHeader caps the header read into memory to set up the table sessions.
+const maxParallelHeader = 1 << 20
+
+// restorePart is one section of a dump in a parallel restore: a byte range of the plan's
+// file holding one gzip member.
+type restorePart struct {
+	kind   sqldump.Kind
+	name   string
+	offset int64
+	size   int64
+}
+
+// parallelPlan is a dump cut at table boundaries. Its tables import concurrently; the rest
+// (views, triggers, routines and the footer) follows in dump order in one session once
+// every table is loaded, so views and triggers find their tables.
+type parallelPlan struct {
+	path   string
+	header restorePart
+	tables []restorePart
+	rest   []restorePart
+	// tableSetup (gzip) opens each table session: parallelSessionSetup and the dump's header
+	// without its GTID_PURGED statement, which only the last session runs. restSetup is
+	// parallelSessionSetup alone; the last session replays the header itself.
+	tableSetup []byte
+	restSetup  []byte
+	// fromArchive is set when the parts are the entries of a split archive.
+	fromArchive bool
+}
+
+func (pl parallelPlan) size() int64 {
+	n := pl.header.size
+	for _, part := range pl.tables {
+		n += part.size
+	}
+	for _, part := range pl.rest {
+		n += part.size
+	}
+	return n
+}
+
+// parallelRestorePattern is the os.CreateTemp pattern of the file a dump is rewritten
+// into, one gzip member per section, for a parallel restore.
+func parallelRestorePattern(localPath string) string {
+	base := strings.TrimSuffix(codec.TrimExt(strings.TrimSuffix(filepath.Base(localPath), sqldump.SplitExt)), ".sql")
+	return base + ".*.parallel.sql.gz"
+}
+
+// planParallelRestore cuts the dump input uploads into sections. When it is a split
+// archive reassembled unchanged (source is the archive), the archive's entries are the
+// sections already; otherwise the dump, rewritten by input's filters, is written to a
+// temporary file as one gzip member per section. cleanup removes that file. An encrypted
+// dump is refused, as that file would hold its plaintext.
+func planParallelRestore(input *restoreInput, source string) (parallelPlan, func(), error) {
+	req := input.req
+	if input.encrypted {
+		return parallelPlan{}, nil, errors.New("an encrypted backup is restored in one session, so its plaintext is never written to disk")
+	}
+	if !input.rewrite && sqldump.IsSplitPath(source) && input.path == joinedRestorePath(source) {
+		if plan, ok := planFromArchive(source, input.path); ok {
+			return plan, func() {}, nil
+		}
+	}
+	if req.Progress != nil {
+		req.Progress("Splitting dump at table boundaries...", 0, 0)
+	}
+	var dump io.ReadCloser
+	var err error
+	if input.rewrite {
+		dump, err = input.openSQL(nil)
+	} else {
+		dump, _, err = codec.Open(input.path)
+	}
+	if err != nil {
+		return parallelPlan{}, nil, err
+	}
+	defer dump.Close()
+	out, err := os.CreateTemp(filepath.Dir(input.path), parallelRestorePattern(input.path))
+	if err != nil {
+		return parallelPlan{}, nil, err
+	}
+	target := out.Name()
+	parts, err := splitSections(dump, out)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		var plan parallelPlan
+		if plan, err = newParallelPlan(target, parts); err == nil {
+			return plan, func() { _ = os.Remove(target) }, nil
+		}
+	}
+	_ = os.Remove(target)
+	return parallelPlan{}, nil, err
+}
+
+// planFromArchive lays the manifest entries of the split archive at source over joined,
+// which Join wrote from it entry by entry.
+func planFromArchive(source, joined string) (parallelPlan, bool) {
+	m, err := sqldump.ReadManifest(source)
+	if err != nil {
+		return parallelPlan{}, false
+	}
+	info, err := os.Stat(joined)
+	if err != nil {
+		return parallelPlan{}, false
+	}
+	parts := make([]restorePart, 0, len(m.Entries))
+	offset := int64(0)
+	for _, e := range m.Entries {
+		parts = append(parts, restorePart{kind: e.Kind, name: e.Name, offset: offset, size: e.CompressedSize})
+		offset += e.CompressedSize
+	}
+	if offset != info.Size() {
+		return parallelPlan{}, false
+	}
+	plan, err := newParallelPlan(joined, parts)
+	if err != nil {
+		return parallelPlan{}, false
+	}
+	plan.fromArchive = true
+	return plan, true
+}
+
+// splitSections writes the SQL text of r to w with each section in its own gzip member,
+// and returns where each one is.
+func splitSections(r io.Reader, w io.Writer) ([]restorePart, error) {
+	bw := bufio.NewWriterSize(w, 64<<10)
+	s := &sectionSplitter{w: &countingWriter{w: bw}}
+	if err := sqldump.Walk(r, s); err != nil {
+		return nil, err
+	}
+	if err := bw.Flush(); err != nil {
+		return nil, err
+	}
+	return s.parts, nil
+}
+
+// sectionSplitter is a sqldump.Visitor writing one gzip member per section.
+type sectionSplitter struct {
+	w     *countingWriter
+	gz    *gzip.Writer
+	start int64
+	parts []restorePart
+}
+
+func (s *sectionSplitter) Begin(*sqldump.Section) error {
+	s.start = s.w.n
+	if s.gz == nil {
+		s.gz = gzip.NewWriter(s.w)
+	} else {
+		s.gz.Reset(s.w)
+	}
+	return nil
+}
+
+func (s *sectionSplitter) Write(_ *sqldump.Section, p []byte) error {
+	_, err := s.gz.Write(p)
+	return err
+}
+
+func (s *sectionSplitter) End(sec *sqldump.Section) error {
+	if err := s.gz.Close(); err != nil {
+		return err
+	}
+	s.parts = append(s.parts, restorePart{kind: sec.Kind, name: sec.Name, offset: s.start, size: s.w.n - s.start})
+	return nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// newParallelPlan sorts the sections of the dump at path into a plan.
+func newParallelPlan(path string, parts []restorePart) (parallelPlan, error) {
+	plan := parallelPlan{path: path}
+	for i, part := range parts {
+		switch {
+		case i == 0 && part.kind == sqldump.KindHeader:
+			plan.header = part
+		case part.kind == sqldump.KindTable:
+			plan.tables = append(plan.tables, part)
+		default:
+			plan.rest = append(plan.rest, part)
+		}
+	}
+	header, err := readPart(path, plan.header)
+	if err != nil {
+		return parallelPlan{}, err
+	}
+	if plan.tableSetup, err = gzipBytes(parallelSessionSetup + dropGTIDPurged(header)); err != nil {
+		return parallelPlan{}, err
+	}
+	if plan.restSetup, err = gzipBytes(parallelSessionSetup); err != nil {
+		return parallelPlan{}, err
+	}
+	return plan, nil
+}
+
+// readPart returns the uncompressed text of a small part, such as the header.
+func readPart(path string, part restorePart) (string, error) {
+	if part.size == 0 {
+		return "", nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(io.NewSectionReader(f, part.offset, part.size))
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+	data, err := io.ReadAll(io.LimitReader(gz, maxParallelHeader+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxParallelHeader {
+		return "", errors.New("the dump header is too large for a parallel restore")
+	}
+	return string(data), nil
+}
+
+// dropGTIDPurged removes the SET @@GLOBAL.GTID_PURGED statement from a dump header: it can
+// run only once on a server, so the table sessions leave it to the last session.
+func dropGTIDPurged(header string) string {
+	var b strings.Builder
+	dropping := false
+	for _, line := range strings.SplitAfter(header, "\n") {
+		if !dropping && strings.HasPrefix(strings.TrimSpace(line), "SET @@GLOBAL.GTID_PURGED") {
+			dropping = true
+		}
+		if dropping {
+			dropping = !strings.HasSuffix(strings.TrimSpace(line), ";")
+			continue
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func gzipBytes(s string) ([]byte, error) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write([]byte(s)); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parallelSummary describes a plan for the activity log.
+func parallelSummary(plan parallelPlan, workers int) string {
+	if workers > len(plan.tables) {
+		workers = len(plan.tables)
+	}
+	from := "split at table boundaries"
+	if plan.fromArchive {
+		from = "from the split archive's entries"
+	}
+	return fmt.Sprintf("%d table(s) %s, imported over %d session(s); %d view, trigger, routine and footer section(s) follow in one session",
+		len(plan.tables), from, workers, len(plan.rest))
+}
+
+// restoreParallel imports the plan's tables over up to workers sessions of client, largest
+// first, then the rest of the dump in one more session. importCmd reads a gzip stream. The
+// first failure stops the other sessions.
+func restoreParallel(ctx context.Context, client ssh.Executor, importCmd string, plan parallelPlan, workers int, progress ProgressFunc) error {
+	if workers > len(plan.tables) {
+		workers = len(plan.tables)
+	}
+	tracker := &parallelProgress{progress: progress, total: plan.size(), tables: len(plan.tables)}
+	tables := append([]restorePart(nil), plan.tables...)
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].size > tables[j].size })
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		once     sync.Once
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	jobs := make(chan restorePart)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for part := range jobs {
+				err := importParts(ctx, client, importCmd, plan.path, plan.tableSetup, []restorePart{part}, func(n int64) {
+					tracker.advance(part.name, n)
+				})
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("table %s: %w", part.name, err)
+						cancel()
+					})
+					return
+				}
+				tracker.finish(part.name)
+			}
+		}()
+	}
+feed:
+	for _, part := range tables {
+		select {
+		case jobs <- part:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	rest := append([]restorePart{plan.header}, plan.rest...)
+	err := importParts(ctx, client, importCmd, plan.path, plan.restSetup, rest, func(n int64) {
+		tracker.advance("", n)
+	})
+	if err != nil {
+		return fmt.Errorf("views, triggers and routines: %w", err)
+	}
+	return nil
+}
+
+// importParts streams setup and then parts of the file at path into one import session.
+// advance is told how many bytes of the parts have been sent.
+func importParts(ctx context.Context, client ssh.Executor, importCmd, path string, setup []byte, parts []restorePart, advance func(int64)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	readers := make([]io.Reader, 0, len(parts))
+	for _, part := range parts {
+		if part.size > 0 {
+			readers = append(readers, io.NewSectionReader(f, part.offset, part.size))
+		}
+	}
+	var sent int64
+	body := &progressReader{reader: io.MultiReader(readers...), callback: func(current int64) {
+		advance(current - sent)
+		sent = current
+	}}
+
+	stdin, stderr, session, err := client.RunCommandPipeInput(importCmd)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = session.Close()
+		case <-done:
+		}
+	}()
+
+	var stderrBuf strings.Builder
+	go func() { _, _ = io.Copy(&stderrBuf, stderr) }()
+
+	_, err = fastCopy(stdin, io.MultiReader(bytes.NewReader(setup), body))
+	if ctx.Err() != nil {
+		_ = stdin.Close()
+		return ctx.Err()
+	}
+	if closeErr := stdin.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(stderrBuf.String()))
+	}
+	return nil
+}
+
+// parallelProgress reports a parallel restore through one ProgressFunc, naming the table
+// that last sent data. Sessions report from their own goroutines.
+type parallelProgress struct {
+	mu       sync.Mutex
+	progress ProgressFunc
+	total    int64
+	sent     int64
+	tables   int
+	finished int
+	last     time.Time
+}
+
+// advance adds n bytes sent for table; "" is the session after the tables.
+func (t *parallelProgress) advance(table string, n int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent += n
+	if t.progress == nil || time.Since(t.last) < 100*time.Millisecond {
+		return
+	}
+	t.last = time.Now()
+	t.report(table)
+}
+
+func (t *parallelProgress) finish(table string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finished++
+	if t.progress != nil {
+		t.report(table)
+	}
+}
+
+func (t *parallelProgress) report(table string) {
+	if table == "" {
+		t.progress(fmt.Sprintf("Restoring views, triggers and routines %.1f%%", percent(t.sent, t.total)), t.sent, t.total)
+		return
+	}
+	t.progress(fmt.Sprintf("Restoring %s · %d/%d tables done %.1f%%", table, t.finished, t.tables, percent(t.sent, t.total)), t.sent, t.total)
+}
+
+// parallelImportCommand builds the import command of a parallel restore session, which
+// reads the plan's gzip members.
+func parallelImportCommand(req RestoreRequest) string {
+	p := restoreProfile(req)
+	if override := strings.TrimSpace(req.TargetDBOverride); override != "" {
+		return db.BuildImportStreamCommandForVerify(p, "gzip", override)
+	}
+	return db.BuildImportStreamCommand(p, "gzip")
+}
@@ -0,0 +1,210 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"dback/backend/sqldump"
+	"dback/backend/ssh"
+)
+
+const parallelTestDump = "/*!40101 SET NAMES utf8mb4 */;\n" +
+	"/*!40014 SET @OLD_FOREIGN_KEY_CHECKS=@@FOREIGN_KEY_CHECKS, FOREIGN_KEY_CHECKS=0 */;\n" +
+	"SET @@GLOBAL.GTID_PURGED=/*!80000 '+'*/ '3E11FA47-71CA-11E1-9E33-C80AA9429562:1-5,\n" +
+	"4E11FA47-71CA-11E1-9E33-C80AA9429562:1-9';\n" +
+	"DROP TABLE IF EXISTS `orders`;\nCREATE TABLE `orders` (id int, user_id int, FOREIGN KEY (user_id) REFERENCES users (id));\nINSERT INTO `orders` VALUES (1,1),(2,1);\n" +
+	"DROP TABLE IF EXISTS `users`;\nCREATE TABLE `users` (id int);\nINSERT INTO `users` VALUES (1);\n" +
+	"DROP TRIGGER IF EXISTS `orders_ai`;\nCREATE TRIGGER `orders_ai` AFTER INSERT ON `orders` FOR EACH ROW SET @n = 1;\n" +
+	"DROP VIEW IF EXISTS `recent`;\nCREATE VIEW `recent` AS SELECT * FROM `orders`;\n" +
+	"/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n"
+
+// pipeExecutor records the SQL each import session receives.
+type pipeExecutor struct {
+	mu       sync.Mutex
+	sessions []string
+	failOn   string
+}
+
+func (e *pipeExecutor) RunCommandPipeInput(string) (io.WriteCloser, io.Reader, ssh.Session, error) {
+	pr, pw := io.Pipe()
+	s := &pipeSession{done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		gz, err := gzip.NewReader(pr)
+		if err != nil {
+			s.err = err
+			return
+		}
+		data, err := io.ReadAll(gz)
+		if err != nil {
+			s.err = err
+			return
+		}
+		if e.failOn != "" && strings.Contains(string(data), e.failOn) {
+			s.err = errors.New("ERROR 1064 at line 3")
+		}
+		e.mu.Lock()
+		e.sessions = append(e.sessions, string(data))
+		e.mu.Unlock()
+	}()
+	return pw, strings.NewReader(""), s, nil
+}
+
+func (e *pipeExecutor) RunCommandStream(string) (io.Reader, io.Reader, ssh.Session, error) {
+	return nil, nil, nil, errors.New("not supported")
+}
+
+func (e *pipeExecutor) RunCommand(string) (string, error) { return "", nil }
+
+func (e *pipeExecutor) Close() error { return nil }
+
+type pipeSession struct {
+	done chan struct{}
+	err  error
+}
+
+func (s *pipeSession) Close() error { return nil }
+
+func (s *pipeSession) Wait() error {
+	<-s.done
+	return s.err
+}
+
+func TestPlanParallelRestoreSplitsAtTables(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	writeGzip(t, src, parallelTestDump)
+
+	plan, cleanup, err := planParallelRestore(mustRestoreInput(t, RestoreRequest{LocalPath: src}), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Dir(plan.path) != dir || !strings.HasSuffix(plan.path, ".parallel.sql.gz") || plan.fromArchive {
+		t.Fatalf("plan path = %s, from archive = %v", plan.path, plan.fromArchive)
+	}
+	var tables, rest []string
+	for _, part := range plan.tables {
+		tables = append(tables, part.name)
+	}
+	for _, part := range plan.rest {
+		rest = append(rest, string(part.kind))
+	}
+	if strings.Join(tables, ",") != "orders,users" || strings.Join(rest, ",") != "trigger,view,footer" {
+		t.Fatalf("tables = %v, rest = %v", tables, rest)
+	}
+	setup := gunzipString(t, plan.tableSetup)
+	if !strings.HasPrefix(setup, parallelSessionSetup) || !strings.Contains(setup, "SET NAMES utf8mb4") || strings.Contains(setup, "GTID") {
+		t.Fatalf("table setup = %q", setup)
+	}
+	cleanup()
+	if _, err := os.Stat(plan.path); !os.IsNotExist(err) {
+		t.Fatal("cleanup should remove the split dump")
+	}
+}
+
+func TestPlanParallelRestoreUsesSplitArchive(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	writeGzip(t, src, parallelTestDump)
+	archive := sqldump.SplitPath(src)
+	if _, err := sqldump.SplitFile(src, archive, sqldump.Manifest{}); err != nil {
+		t.Fatal(err)
+	}
+	req, cleanup, err := prepareRestoreFile(RestoreRequest{LocalPath: archive, Logger: &recordingLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	plan, planned, err := planParallelRestore(mustRestoreInput(t, req), archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer planned()
+	if !plan.fromArchive || plan.path != req.LocalPath || len(plan.tables) != 2 || len(plan.rest) != 3 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if got := gunzipString(t, readRange(t, plan.path, plan.tables[1])); !strings.HasPrefix(got, "DROP TABLE IF EXISTS `users`") {
+		t.Fatalf("users part = %q", got)
+	}
+}
+
+func TestRestoreParallel(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	writeGzip(t, src, parallelTestDump)
+	plan, cleanup, err := planParallelRestore(mustRestoreInput(t, RestoreRequest{LocalPath: src}), src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	exec := &pipeExecutor{}
+	var mu sync.Mutex
+	var messages []string
+	progress := func(message string, current, total int64) {
+		mu.Lock()
+		messages = append(messages, message)
+		mu.Unlock()
+	}
+	if err := restoreParallel(context.Background(), exec, "import", plan, 4, progress); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 3 {
+		t.Fatalf("sessions = %d", len(exec.sessions))
+	}
+	for _, sql := range exec.sessions[:2] {
+		if !strings.HasPrefix(sql, parallelSessionSetup) || strings.Contains(sql, "GTID_PURGED") || strings.Contains(sql, "TRIGGER") {
+			t.Fatalf("table session = %q", sql)
+		}
+	}
+	last := exec.sessions[2]
+	if !strings.Contains(last, "GTID_PURGED") || !strings.Contains(last, "CREATE TRIGGER") || !strings.Contains(last, "CREATE VIEW") || strings.Contains(last, "INSERT INTO") {
+		t.Fatalf("last session = %q", last)
+	}
+	if !strings.Contains(strings.Join(messages, "\n"), "tables done") {
+		t.Fatalf("progress = %v", messages)
+	}
+
+	exec = &pipeExecutor{failOn: "`users`"}
+	err = restoreParallel(context.Background(), exec, "import", plan, 2, nil)
+	if err == nil || !strings.Contains(err.Error(), "table users") || !strings.Contains(err.Error(), "ERROR 1064") {
+		t.Fatalf("failing table: %v", err)
+	}
+}
+
+func TestDropGTIDPurged(t *testing.T) {
+	header := "SET NAMES utf8mb4;\nSET @@GLOBAL.GTID_PURGED='a:1-2,\nb:1-3';\nSET TIME_ZONE='+00:00';\n"
+	if got := dropGTIDPurged(header); got != "SET NAMES utf8mb4;\nSET TIME_ZONE='+00:00';\n" {
+		t.Fatalf("got %q", got)
+	}
+}
+
+func gunzipString(t *testing.T, data []byte) string {
+	t.Helper()
+	gz, err := gzip.NewReader(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func readRange(t *testing.T, path string, part restorePart) []byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data[part.offset : part.offset+part.size]
+}
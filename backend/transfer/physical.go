@@ -0,0 +1,275 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"dback/backend/codec"
+	"dback/backend/db"
+	"dback/backend/preflight"
+	"dback/backend/ssh"
+	"dback/models"
+)
+
+// StrategyPhysical copies the server's data files with mariadb-backup or xtrabackup.
+const StrategyPhysical Strategy = "physical"
+
+// PhysicalExt marks physical backups: an xbstream of the data directory, followed by the
+// codec's extension.
+const PhysicalExt = ".xbstream"
+
+// ErrPhysicalUnsupported means a host cannot take or restore a physical backup.
+var ErrPhysicalUnsupported = errors.New("physical backup not possible on this host")
+
+// physicalErrTail is how much of the tool's log is kept in an error message.
+const physicalErrTail = 1500
+
+// backupSSHPhysical streams a physical backup of the whole server into the host's backup
+// folder. It is only called when preflight found a backup tool.
+func backupSSHPhysical(ctx context.Context, client ssh.Executor, req BackupRequest, pf preflight.Result) (BackupResult, error) {
+	p := req.Profile
+	tool := pf.PhysicalTool
+	hostDir := filepath.Join(req.Destination, safeName(p.Name))
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		return BackupResult{}, fmt.Errorf("create host backup folder: %w", err)
+	}
+	fullPath := filepath.Join(hostDir, fmt.Sprintf("%s_%s%s%s", safeName(p.TargetDBName), time.Now().Format("02_01_2006_15_04_05"), PhysicalExt, codec.Ext(p.Compression)))
+
+	workDir := pf.SelectedTmpDir + "/physical"
+	if p.IsDocker {
+		workDir = "/tmp/dback-" + req.OperationID
+	}
+	cmd := db.BuildPhysicalBackupCommand(p, tool, workDir)
+	logReq(req, "command", string(StrategyPhysical), 0, db.MaskCommand(cmd), "Built", "")
+	logReq(req, "backup", string(StrategyPhysical), 1, "Copying data files with "+tool.Backup, "Started", "")
+
+	size, err := streamPhysicalBackup(ctx, client, cmd, fullPath, tool, req.Progress)
+	if err == nil {
+		err = validateBackupIntegrity(fullPath)
+	}
+	if err != nil {
+		_ = os.Remove(fullPath)
+		logReq(req, "backup", string(StrategyPhysical), 1, err.Error(), "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	if sum, sumErr := checksumFile(fullPath); sumErr == nil {
+		logReq(req, "checksum", string(StrategyPhysical), 1, "sha256="+sum, "Succeeded", "")
+	}
+	logReq(req, "backup", string(StrategyPhysical), 1, "Backup completed", "Succeeded", "")
+	file := BackupFile{Database: p.TargetDBName, Path: fullPath, Size: size, Type: models.BackupTypePhysical, Compression: codec.Normalize(p.Compression)}
+	return BackupResult{Path: file.Path, Size: file.Size, Files: []BackupFile{file}}, nil
+}
+
+func streamPhysicalBackup(ctx context.Context, client ssh.Executor, cmd, fullPath string, tool db.PhysicalTool, progress ProgressFunc) (int64, error) {
+	stdout, stderr, session, err := client.RunCommandStream(cmd)
+	if err != nil {
+		return 0, err
+	}
+	defer session.Close()
+	go cancelOnContext(ctx, session, client)
+
+	var stderrBuf strings.Builder
+	stderrDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&stderrBuf, stderr)
+		close(stderrDone)
+	}()
+
+	out, err := os.Create(fullPath)
+	if err != nil {
+		return 0, err
+	}
+	defer out.Close()
+	written, err := fastCopy(out, &ssh.ProgressReader{
+		Reader: stdout,
+		Callback: func(current int64, total int64) {
+			if progress != nil {
+				progress(fmt.Sprintf("Copying data files %.2f MB", float64(current)/1024/1024), current, 0)
+			}
+		},
+	})
+	if ctx.Err() != nil {
+		return 0, ctx.Err()
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := session.Wait(); err != nil {
+		<-stderrDone
+		return 0, physicalToolError(tool.Backup, err, stderrBuf.String())
+	}
+	<-stderrDone
+	return written, nil
+}
+
+// physicalToolError explains the usual causes of a failed mariadb-backup/xtrabackup run.
+func physicalToolError(tool string, err error, log string) error {
+	log = strings.TrimSpace(log)
+	if len(log) > physicalErrTail {
+		log = "..." + log[len(log)-physicalErrTail:]
+	}
+	low := strings.ToLower(log)
+	switch {
+	case strings.Contains(low, "access denied") || strings.Contains(low, "privilege"):
+		return fmt.Errorf("%s lacks database privileges (it needs RELOAD, PROCESS, LOCK TABLES and REPLICATION CLIENT, or BACKUP_ADMIN on MySQL 8): %w: %s", tool, err, log)
+	case strings.Contains(low, "permission denied"):
+		return fmt.Errorf("%s cannot read the data directory; run it as the mysql user or root: %w: %s", tool, err, log)
+	}
+	return fmt.Errorf("%s: %w: %s", tool, err, log)
+}
+
+// restorePhysical uploads a physical backup, prepares it and copies it back over the
+// destination server's data directory. The previous data directory is kept beside it.
+func restorePhysical(ctx context.Context, req RestoreRequest) error {
+	p := req.Profile
+	if p.IsDocker {
+		return fmt.Errorf("%w: a server inside a Docker container cannot be stopped to replace its data directory; restore on the Docker host itself", ErrPhysicalUnsupported)
+	}
+	in, err := os.Open(req.LocalPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if req.FileSize <= 0 {
+		if info, statErr := os.Stat(req.LocalPath); statErr == nil {
+			req.FileSize = info.Size()
+		}
+	}
+	compression, err := detectCompression(in)
+	if err != nil {
+		return err
+	}
+
+	client, err := ssh.NewExecutor(p)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	client = throttleExecutor(ctx, client, req.Bandwidth)
+	req.Progress = withRate(req.Progress, req.Bandwidth)
+
+	// Extracted data files take several times the compressed size.
+	pf, pfErr := preflight.Run(client, p, req.FileSize*4, req.OperationID)
+	if pfErr != nil {
+		logRestore(req, "preflight", "", 0, preflight.FailureDetails(pf, pfErr), "Failed", pfErr.Error())
+		return pfErr
+	}
+	logRestore(req, "preflight", "", 0, preflight.Summary(pf), "Succeeded", "")
+
+	tool, err := checkPhysicalRestore(client, p)
+	if err != nil {
+		logRestore(req, "physical", string(StrategyPhysical), 0, "Physical restore not possible", "Failed", err.Error())
+		return err
+	}
+	dir := pf.SelectedTmpDir + "/physical"
+	defer func() { _, _ = client.RunCommand(shellCleanup(pf.SelectedTmpDir)) }()
+
+	if req.Progress != nil {
+		req.Progress("Uploading data files...", 0, req.FileSize)
+	}
+	logRestore(req, "restore", string(StrategyPhysical), 1, "Extracting backup with "+tool.Stream+" into "+dir, "Started", "")
+	if err := uploadPhysical(ctx, client, db.BuildPhysicalExtractCommand(tool, dir, compression), in, req.FileSize, req.Progress); err != nil {
+		logRestore(req, "restore", string(StrategyPhysical), 1, err.Error(), "Failed", err.Error())
+		return err
+	}
+
+	if req.Progress != nil {
+		req.Progress("Preparing data files...", req.FileSize, req.FileSize)
+	}
+	if out, err := client.RunCommand(db.BuildPhysicalPrepareCommand(tool, dir)); err != nil {
+		err = physicalToolError(tool.Backup+" --prepare", err, out)
+		logRestore(req, "prepare", string(StrategyPhysical), 1, err.Error(), "Failed", err.Error())
+		return err
+	}
+	logRestore(req, "prepare", string(StrategyPhysical), 1, "Backup prepared", "Succeeded", "")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if req.Progress != nil {
+		req.Progress("Stopping server and copying data files back...", req.FileSize, req.FileSize)
+	}
+	suffix := "dback-" + req.OperationID
+	out, err := client.RunCommand(db.BuildPhysicalCopyBackCommand(p, tool, dir, suffix))
+	if err != nil {
+		err = physicalToolError(tool.Backup+" --copy-back", err, out)
+		logRestore(req, "copy-back", string(StrategyPhysical), 1, err.Error(), "Failed", err.Error())
+		return err
+	}
+	details := "Data directory replaced and server started"
+	if previous := parsePrevious(out); previous != "" {
+		details += "; previous data directory kept at " + previous
+	}
+	logRestore(req, "copy-back", string(StrategyPhysical), 1, details, "Succeeded", "")
+	logRestore(req, "restore", string(StrategyPhysical), 1, "Restore completed", "Succeeded", "")
+	return nil
+}
+
+// checkPhysicalRestore finds the backup tool and extractor on the destination and makes sure
+// the SSH user may stop the server.
+func checkPhysicalRestore(client ssh.Executor, p models.Profile) (db.PhysicalTool, error) {
+	out, _ := client.RunCommand(db.BuildPhysicalProbeCommand(p))
+	tool := db.ParsePhysicalTool(out)
+	if !tool.Found() {
+		return tool, fmt.Errorf("%w: neither mariadb-backup nor xtrabackup is installed on %s; install the tool matching the server version", ErrPhysicalUnsupported, p.Name)
+	}
+	out, _ = client.RunCommand(db.BuildPhysicalCheckCommand(tool))
+	if !strings.Contains(out, "stream="+tool.Stream) {
+		return tool, fmt.Errorf("%w: %s is not installed on %s; it comes with %s", ErrPhysicalUnsupported, tool.Stream, p.Name, tool.Backup)
+	}
+	if !strings.Contains(out, "root=yes") {
+		return tool, fmt.Errorf("%w: restoring data files needs root or passwordless sudo on %s to stop the server and replace its data directory", ErrPhysicalUnsupported, p.Name)
+	}
+	return tool, nil
+}
+
+func uploadPhysical(ctx context.Context, client ssh.Executor, cmd string, in *os.File, total int64, progress ProgressFunc) error {
+	stdin, stderr, session, err := client.RunCommandPipeInput(cmd)
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+	go cancelOnContext(ctx, session, client)
+
+	var stderrBuf strings.Builder
+	go func() { _, _ = io.Copy(&stderrBuf, stderr) }()
+
+	_, err = fastCopy(stdin, &ssh.ProgressReader{
+		Reader: in,
+		Total:  total,
+		Callback: func(current int64, total int64) {
+			if progress != nil {
+				progress(fmt.Sprintf("Uploading data files %.1f%%", percent(current, total)), current, total)
+			}
+		},
+	})
+	if ctx.Err() != nil {
+		_ = stdin.Close()
+		return ctx.Err()
+	}
+	if closeErr := stdin.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("upload: %w", err)
+	}
+	if err := session.Wait(); err != nil {
+		return fmt.Errorf("extract: %w: %s", err, strings.TrimSpace(stderrBuf.String()))
+	}
+	return nil
+}
+
+func parsePrevious(out string) string {
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "previous="); ok {
+			return v
+		}
+	}
+	return ""
+}
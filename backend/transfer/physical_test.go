@@ -0,0 +1,33 @@
+package transfer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPhysicalToolErrorExplainsPrivileges(t *testing.T) {
+	exit := errors.New("exit status 1")
+	err := physicalToolError("mariadb-backup", exit, "[ERROR] Access denied; you need (at least one of) the RELOAD privilege(s)")
+	if !errors.Is(err, exit) || !strings.Contains(err.Error(), "lacks database privileges") {
+		t.Fatalf("privilege error = %v", err)
+	}
+	err = physicalToolError("xtrabackup", exit, "Can't open ./ibdata1: Permission denied")
+	if !strings.Contains(err.Error(), "cannot read the data directory") {
+		t.Fatalf("permission error = %v", err)
+	}
+	err = physicalToolError("xtrabackup", exit, strings.Repeat("x", physicalErrTail+100))
+	if len(err.Error()) > physicalErrTail+100 {
+		t.Fatalf("long tool output should be trimmed, got %d bytes", len(err.Error()))
+	}
+}
+
+func TestParsePrevious(t *testing.T) {
+	out := "[00] Copying ibdata1\n[00] completed OK!\nprevious=/var/lib/mysql.dback-op1\n"
+	if got := parsePrevious(out); got != "/var/lib/mysql.dback-op1" {
+		t.Fatalf("parsePrevious = %q", got)
+	}
+	if got := parsePrevious("completed OK!"); got != "" {
+		t.Fatalf("parsePrevious without marker = %q", got)
+	}
+}
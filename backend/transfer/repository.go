@@ -0,0 +1,85 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"dback/backend/chunkstore"
+	"dback/models"
+)
+
+// repositoryBackup moves a downloaded dump into the destination folder's chunk repository
+// when the profile asks for it, leaving a manifest in its place. If storing fails the dump
+// is kept as a single file. Size stays the dump's size; AddedBytes is what it cost on disk.
+func repositoryBackup(req BackupRequest, file BackupFile) BackupFile {
+	if req.Profile.DumpFormat != models.DumpFormatRepository {
+		return file
+	}
+	if req.Progress != nil {
+		req.Progress("Storing in deduplicated repository...", file.Size, file.Size)
+	}
+	dst := chunkstore.ManifestPath(file.Path)
+	_, res, err := chunkstore.StoreFile(file.Path, dst, chunkstore.RepositoryDir(req.Destination), chunkstore.Manifest{Database: file.Database})
+	if err != nil {
+		logReq(req, "repository", "", 0, "Keeping single .sql.gz file", "Warning", err.Error())
+		return file
+	}
+	_ = os.Remove(file.Path)
+	logReq(req, "repository", "", 0, fmt.Sprintf("Stored %d chunk(s), %d new, %.2f MB added to the repository", res.Chunks, res.NewChunks, float64(res.AddedBytes)/1024/1024), "Succeeded", "")
+	// Chunks are stored with zstd, whatever the dump was.
+	file.Path, file.Compression, file.AddedBytes = dst, models.CompressionZstd, res.AddedBytes
+	return file
+}
+
+// reassembledRestorePath is where a repository backup is reassembled for restore. It is
+// stable so an interrupted tmp-file upload can resume from the same file.
+func reassembledRestorePath(localPath string) string {
+	return strings.TrimSuffix(localPath, chunkstore.ManifestExt) + ".restore.sql.gz"
+}
+
+// reassembleRestoreFile points req at a .sql.gz rebuilt from the chunks of a repository
+// backup, written next to its manifest, so the rest of the restore reads the dump as usual.
+// Other files are returned unchanged. cleanup removes the rebuilt file.
+func reassembleRestoreFile(req RestoreRequest) (RestoreRequest, func(), error) {
+	if !chunkstore.IsManifestPath(req.LocalPath) {
+		return req, func() {}, nil
+	}
+	if req.Progress != nil {
+		req.Progress("Reassembling backup from repository...", 0, 0)
+	}
+	target := reassembledRestorePath(req.LocalPath)
+	if err := reassemble(req.LocalPath, target); err != nil {
+		_ = os.Remove(target)
+		logRestore(req, "repository", "", 0, "Could not reassemble backup", "Failed", err.Error())
+		return req, nil, fmt.Errorf("reassemble backup: %w", err)
+	}
+	logRestore(req, "repository", "", 0, "Reassembled backup into "+target, "Succeeded", "")
+	req.LocalPath = target
+	req.FileSize = 0
+	return req, func() { _ = os.Remove(target) }, nil
+}
+
+func reassemble(manifestPath, target string) error {
+	r, err := chunkstore.Open(manifestPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	out, err := createRestoreFile(target)
+	if err != nil {
+		return err
+	}
+	// Fast compression: the file only lives for the restore.
+	gw, _ := gzip.NewWriterLevel(out, gzip.BestSpeed)
+	_, err = io.Copy(gw, r)
+	if closeErr := gw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,90 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dback/backend/chunkstore"
+	"dback/backend/crypt"
+	"dback/models"
+)
+
+func TestRepositoryBackupRoundTrip(t *testing.T) {
+	dest := t.TempDir()
+	hostDir := filepath.Join(dest, "shop")
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	src := filepath.Join(hostDir, "shop_01_01_2026_00_00_00.sql.gz")
+	size := writeTestDump(t, src)
+	logger := &recordingLogger{}
+	req := BackupRequest{Profile: models.Profile{DumpFormat: models.DumpFormatRepository}, Destination: dest, Logger: logger}
+
+	file := repositoryBackup(req, BackupFile{Database: "shop", Path: src, Size: size, Compression: models.CompressionGzip})
+	if file.Path != filepath.Join(hostDir, "shop_01_01_2026_00_00_00.chunks.json") || !logger.has("repository||Succeeded") {
+		t.Fatalf("file = %+v, log = %v", file, logger.entries)
+	}
+	if file.Size != size || file.AddedBytes == 0 || file.Compression != models.CompressionZstd {
+		t.Fatalf("file = %+v", file)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatal("the .sql.gz should be replaced by the manifest")
+	}
+	if !chunkstore.Exists(filepath.Join(dest, chunkstore.Dir)) {
+		t.Fatal("repository not created in the destination folder")
+	}
+
+	// The same dump again adds nothing.
+	writeTestDump(t, src)
+	again := repositoryBackup(req, BackupFile{Database: "shop", Path: src, Size: size})
+	if again.AddedBytes != 0 {
+		t.Fatalf("identical dump added %d bytes", again.AddedBytes)
+	}
+
+	restore, cleanup, err := reassembleRestoreFile(RestoreRequest{LocalPath: file.Path, Logger: &recordingLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restore.LocalPath != reassembledRestorePath(file.Path) {
+		t.Fatalf("restore path = %s", restore.LocalPath)
+	}
+	f, err := os.Open(restore.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := io.ReadAll(gz)
+	f.Close()
+	if string(text) != splitTestDump {
+		t.Fatalf("reassembled dump = %q", text)
+	}
+	cleanup()
+	if _, err := os.Stat(restore.LocalPath); !os.IsNotExist(err) {
+		t.Fatal("cleanup should remove the reassembled file")
+	}
+
+	tables, err := ListBackupTables(file.Path, crypt.Keys{})
+	if err != nil || len(tables) != 2 || tables[0] != "orders" {
+		t.Fatalf("tables = %v, %v", tables, err)
+	}
+}
+
+func TestRepositoryBackupSkippedForSingleFormat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	size := writeTestDump(t, src)
+	file := repositoryBackup(BackupRequest{Destination: dir, Logger: &recordingLogger{}}, BackupFile{Path: src, Size: size})
+	if file.Path != src {
+		t.Fatalf("single format should keep %s, got %s", src, file.Path)
+	}
+	req, _, err := reassembleRestoreFile(RestoreRequest{LocalPath: src})
+	if err != nil || req.LocalPath != src {
+		t.Fatalf("plain dumps should restore as-is: %v %s", err, req.LocalPath)
+	}
+}
@@ -0,0 +1,33 @@
+package transfer
+
+import (
+	"errors"
+	"io"
+
+	"dback/backend/searchreplace"
+)
+
+// replaceFilter returns the filter applying req's search/replace pairs to the rows of the
+// dump and logging how many replacements each table got. ok is false for requests
+// without pairs.
+func replaceFilter(req RestoreRequest) (f restoreFilter, ok bool, err error) {
+	pairs := req.SearchReplace
+	if len(pairs) == 0 {
+		return restoreFilter{}, false, nil
+	}
+	if req.Incremental {
+		return restoreFilter{}, false, errors.New("search/replace cannot rewrite binary log events")
+	}
+	return restoreFilter{
+		phase:   "replace",
+		failure: "Could not search/replace backup file",
+		action:  "search/replace",
+		apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+			report, err := searchreplace.Apply(r, w, pairs)
+			if err != nil {
+				return filterReport{}, err
+			}
+			return filterReport{summary: report.Summary()}, nil
+		},
+	}, true, nil
+}
@@ -0,0 +1,43 @@
+package transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestReplacedRestoreStreamsRewrittenRows(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz")
+	writeMaskTestDump(t, src)
+	logger := &recordingLogger{}
+	input := mustRestoreInput(t, RestoreRequest{
+		SearchReplace: []models.ReplacePair{{From: "shop.example", To: "staging.example"}},
+		LocalPath:     src,
+		Logger:        logger,
+	})
+	exec := &pipeExecutor{}
+	if err := restoreStream(context.Background(), exec, models.Profile{TargetDBName: "shop"}, input, nil, ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(exec.sessions) != 1 || !logger.has("replace||Succeeded") {
+		t.Fatalf("sessions = %d, log = %v", len(exec.sessions), logger.entries)
+	}
+	if data := exec.sessions[0]; !strings.Contains(data, "(1,'ann@staging.example'),(2,NULL);") {
+		t.Fatalf("uploaded dump = %s", data)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Fatalf("search/replace wrote %d files next to the backup", len(entries))
+	}
+
+	if plain := mustRestoreInput(t, RestoreRequest{LocalPath: src}); plain.rewrite {
+		t.Fatal("no pairs: the upload should be the file")
+	}
+	if _, err := restoreFilters(RestoreRequest{SearchReplace: []models.ReplacePair{{From: "a", To: "b"}}, Incremental: true}); err == nil {
+		t.Fatal("binary log events should not be rewritten")
+	}
+}
@@ -0,0 +1,249 @@
+package transfer
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"dback/backend/binlog"
+	"dback/backend/codec"
+	"dback/backend/crypt"
+	"dback/backend/db"
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+// splitBackup converts a downloaded .sql.gz into a split archive when the profile asks for
+// one. The .sql.gz is kept, with a warning, if the conversion fails.
+func splitBackup(req BackupRequest, file BackupFile, tables db.TablePlan) BackupFile {
+	if req.Profile.DumpFormat != models.DumpFormatSplit {
+		return file
+	}
+	if req.Progress != nil {
+		req.Progress("Splitting dump per table...", file.Size, file.Size)
+	}
+	dst := sqldump.SplitPath(file.Path)
+	m, err := sqldump.SplitFile(file.Path, dst, sqldump.Manifest{
+		Database:         file.Database,
+		ExcludedTables:   tables.Excluded,
+		SchemaOnlyTables: tables.SchemaOnly,
+		FilteredTables:   tables.FilteredNames(),
+	})
+	if err != nil {
+		logReq(req, "split", "", 0, "Keeping single .sql.gz file", "Warning", err.Error())
+		return file
+	}
+	info, err := os.Stat(dst)
+	if err != nil {
+		logReq(req, "split", "", 0, "Keeping single .sql.gz file", "Warning", err.Error())
+		_ = os.Remove(dst)
+		return file
+	}
+	_ = os.Remove(file.Path)
+	logReq(req, "split", "", 0, fmt.Sprintf("Split into %d file(s), %d table(s)", len(m.Entries), len(m.TableRows())), "Succeeded", "")
+	if sum, sumErr := checksumFile(dst); sumErr == nil {
+		logReq(req, "checksum", "", 0, "sha256="+sum, "Succeeded", "")
+	}
+	// Each table's section in the archive is gzip, whatever the dump was.
+	file.Path, file.Size, file.Compression = dst, info.Size(), models.CompressionGzip
+	return file
+}
+
+// joinedRestorePath is where a split archive is reassembled for restore. It is stable so an
+// interrupted tmp-file upload can resume from the same file.
+func joinedRestorePath(localPath string) string {
+	return strings.TrimSuffix(localPath, sqldump.SplitExt) + ".restore.sql.gz"
+}
+
+// tablesRestorePath is where the tables picked for a selective restore are extracted.
+func tablesRestorePath(localPath string) string {
+	base := strings.TrimSuffix(codec.TrimExt(strings.TrimSuffix(localPath, sqldump.SplitExt)), ".sql")
+	return base + ".tables.sql.gz"
+}
+
+// prepareRestoreFile points req at a plain .sql.gz to upload: a split archive is
+// reassembled next to it, a selective restore extracts just the chosen tables, and an
+// incremental with StopAt is cut at that time. Plain full restores are returned unchanged.
+// cleanup removes the temporary file.
+func prepareRestoreFile(req RestoreRequest) (RestoreRequest, func(), error) {
+	selective := req.Tables.Active()
+	cut := !req.StopAt.IsZero()
+	if !selective && !cut && !sqldump.IsSplitPath(req.LocalPath) {
+		return req, func() {}, nil
+	}
+	if selective && !cut && !sqldump.IsSplitPath(req.LocalPath) {
+		// The tables of an encrypted dump are picked by tablesFilter as it is decrypted,
+		// never written out in the clear.
+		if encrypted, err := crypt.IsEncryptedFile(req.LocalPath); err != nil || encrypted {
+			return req, func() {}, err
+		}
+	}
+	phase, target, message := "split", joinedRestorePath(req.LocalPath), "Checking split archive..."
+	switch {
+	case cut:
+		phase, target, message = "pitr", pitrRestorePath(req.LocalPath), "Cutting binary log at the target time..."
+	case selective:
+		phase, target, message = "tables", tablesRestorePath(req.LocalPath), "Extracting selected tables..."
+	}
+	if req.Progress != nil {
+		req.Progress(message, 0, 0)
+	}
+	out, err := createRestoreFile(target)
+	if err != nil {
+		return req, nil, err
+	}
+	var found []string
+	var events binlog.Summary
+	var writeErr error
+	switch {
+	case cut:
+		events, writeErr = cutBinlog(req.LocalPath, out, req.StopAt)
+	case selective:
+		found, writeErr = extractTables(req.LocalPath, out, sqldump.Selection{Tables: req.Tables.Tables, Triggers: req.Tables.Triggers})
+	default:
+		writeErr = sqldump.Join(req.LocalPath, out, nil)
+	}
+	closeErr := out.Close()
+	if writeErr == nil {
+		writeErr = closeErr
+	}
+	if writeErr == nil && selective && len(found) == 0 {
+		writeErr = errors.New("none of the selected tables are in this backup")
+	}
+	if writeErr != nil {
+		_ = os.Remove(target)
+		logRestore(req, phase, "", 0, "Could not prepare backup file", "Failed", writeErr.Error())
+		return req, nil, writeErr
+	}
+	switch {
+	case cut:
+		logRestore(req, "pitr", "", 0, fmt.Sprintf("Replaying %d event(s) up to %s UTC", events.Events, req.StopAt.UTC().Format(time.DateTime)), "Succeeded", "")
+	case selective:
+		logRestore(req, "tables", "", 0, tablesSummary(req.Tables.Tables, found), "Succeeded", "")
+	default:
+		logRestore(req, "split", "", 0, "Reassembled split archive into "+target, "Succeeded", "")
+	}
+	req.LocalPath = target
+	req.FileSize = 0
+	return req, func() {
+		_ = os.Remove(target)
+		if selective || cut {
+			// Selective and point-in-time restores are retried from the start, never resumed.
+			removeMeta(target)
+		}
+	}, nil
+}
+
+// extractTables writes the selected tables of a dump in any codec, or of a split archive,
+// to w as gzip.
+func extractTables(localPath string, w io.Writer, sel sqldump.Selection) ([]string, error) {
+	if sqldump.IsSplitPath(localPath) {
+		m, err := sqldump.ReadManifest(localPath)
+		if err != nil {
+			return nil, err
+		}
+		var found []string
+		for _, e := range m.Entries {
+			if e.Kind == sqldump.KindTable && sel.Keep(e.Kind, e.Name) {
+				found = append(found, e.Name)
+			}
+		}
+		sort.Strings(found)
+		return found, sqldump.Join(localPath, w, func(e sqldump.Entry) bool { return sel.Keep(e.Kind, e.Name) })
+	}
+	gr, _, err := codec.Open(localPath)
+	if err != nil {
+		return nil, err
+	}
+	defer gr.Close()
+	gw := gzip.NewWriter(w)
+	found, err := sqldump.Extract(gr, gw, sel)
+	if closeErr := gw.Close(); err == nil {
+		err = closeErr
+	}
+	return found, err
+}
+
+// tablesFilter picks the selected tables out of an encrypted dump as it is decrypted, the
+// way prepareRestoreFile extracts them from a plain one.
+func tablesFilter(req RestoreRequest) (restoreFilter, bool, error) {
+	if !req.Tables.Active() || !req.StopAt.IsZero() || sqldump.IsSplitPath(req.LocalPath) {
+		return restoreFilter{}, false, nil
+	}
+	encrypted, err := crypt.IsEncryptedFile(req.LocalPath)
+	if err != nil || !encrypted {
+		return restoreFilter{}, false, err
+	}
+	sel := sqldump.Selection{Tables: req.Tables.Tables, Triggers: req.Tables.Triggers}
+	return restoreFilter{
+		phase:   "tables",
+		failure: "Could not extract selected tables",
+		action:  "extract tables",
+		apply: func(r io.Reader, w io.Writer) (filterReport, error) {
+			found, err := sqldump.Extract(r, w, sel)
+			if err != nil {
+				return filterReport{}, err
+			}
+			if len(found) == 0 {
+				return filterReport{}, errors.New("none of the selected tables are in this backup")
+			}
+			return filterReport{summary: tablesSummary(sel.Tables, found)}, nil
+		},
+	}, true, nil
+}
+
+// tablesSummary describes a selective restore for the activity log.
+func tablesSummary(selected, found []string) string {
+	details := fmt.Sprintf("Restoring %d table(s): %s", len(found), strings.Join(found, ", "))
+	if missing := missingTables(selected, found); len(missing) > 0 {
+		details += "; not in backup: " + strings.Join(missing, ", ")
+	}
+	return details
+}
+
+func missingTables(selected, found []string) []string {
+	have := make(map[string]bool, len(found))
+	for _, name := range found {
+		have[name] = true
+	}
+	var missing []string
+	for _, name := range selected {
+		if !have[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// ListBackupTables lists the tables in a backup file, from the manifest of a split archive
+// or by scanning a dump in any codec. keys decrypt an encrypted dump.
+func ListBackupTables(localPath string, keys crypt.Keys) ([]string, error) {
+	if sqldump.IsSplitPath(localPath) {
+		m, err := sqldump.ReadManifest(localPath)
+		if err != nil {
+			return nil, err
+		}
+		tables := make([]string, 0, len(m.Entries))
+		for name := range m.TableRows() {
+			tables = append(tables, name)
+		}
+		sort.Strings(tables)
+		return tables, nil
+	}
+	gr, err := openDump(localPath, keys)
+	if err != nil {
+		return nil, fmt.Errorf("read backup: %w", err)
+	}
+	defer gr.Close()
+	tables, err := sqldump.ScanTables(gr)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
@@ -0,0 +1,132 @@
+package transfer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dback/backend/db"
+	"dback/backend/sqldump"
+	"dback/models"
+)
+
+const splitTestDump = "SET NAMES utf8mb4;\n" +
+	"DROP TABLE IF EXISTS `orders`;\nCREATE TABLE `orders` (id int);\nINSERT INTO `orders` VALUES (1),(2);\n" +
+	"DROP TABLE IF EXISTS `users`;\nCREATE TABLE `users` (id int);\n" +
+	"/*!40101 SET SQL_MODE=@OLD_SQL_MODE */;\n"
+
+func writeTestDump(t *testing.T, path string) int64 {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(splitTestDump))
+	gz.Close()
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return int64(buf.Len())
+}
+
+func TestSplitBackupRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01_01_2026_00_00_00.sql.gz")
+	size := writeTestDump(t, src)
+	logger := &recordingLogger{}
+	req := BackupRequest{Profile: models.Profile{DumpFormat: models.DumpFormatSplit}, Logger: logger}
+
+	file := splitBackup(req, BackupFile{Database: "shop", Path: src, Size: size}, db.TablePlan{Excluded: []string{"log"}})
+	if file.Path != filepath.Join(dir, "shop_01_01_2026_00_00_00.split.tar") || !logger.has("split||Succeeded") {
+		t.Fatalf("file = %+v, log = %v", file, logger.entries)
+	}
+	if _, err := os.Stat(src); !os.IsNotExist(err) {
+		t.Fatal("the .sql.gz should be replaced by the split archive")
+	}
+	m, err := sqldump.ReadManifest(file.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Database != "shop" || m.TableRows()["orders"] != 2 || len(m.ExcludedTables) != 1 {
+		t.Fatalf("manifest = %+v", m)
+	}
+
+	restore, cleanup, err := prepareRestoreFile(RestoreRequest{LocalPath: file.Path, Logger: &recordingLogger{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restore.LocalPath != joinedRestorePath(file.Path) {
+		t.Fatalf("restore path = %s", restore.LocalPath)
+	}
+	if err := validateBackupIntegrity(restore.LocalPath); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(restore.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := io.ReadAll(gz)
+	f.Close()
+	if string(text) != splitTestDump {
+		t.Fatalf("reassembled dump = %q", text)
+	}
+	cleanup()
+	if _, err := os.Stat(restore.LocalPath); !os.IsNotExist(err) {
+		t.Fatal("cleanup should remove the reassembled file")
+	}
+}
+
+func TestSplitBackupSkippedForSingleFormat(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	size := writeTestDump(t, src)
+	file := splitBackup(BackupRequest{Logger: &recordingLogger{}}, BackupFile{Path: src, Size: size}, db.TablePlan{})
+	if file.Path != src {
+		t.Fatalf("single format should keep %s, got %s", src, file.Path)
+	}
+	req, _, err := prepareRestoreFile(RestoreRequest{LocalPath: src})
+	if err != nil || req.LocalPath != src {
+		t.Fatalf("plain dumps should restore as-is: %v %s", err, req.LocalPath)
+	}
+}
+
+func TestPrepareRestoreFileExtractsTables(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop.sql.gz")
+	writeTestDump(t, src)
+	logger := &recordingLogger{}
+	req, cleanup, err := prepareRestoreFile(RestoreRequest{
+		LocalPath: src,
+		Logger:    logger,
+		Tables:    &models.TableSelection{Tables: []string{"users"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	if req.LocalPath != filepath.Join(dir, "shop.tables.sql.gz") || !logger.has("tables||Succeeded") {
+		t.Fatalf("path = %s, log = %v", req.LocalPath, logger.entries)
+	}
+	data, err := os.ReadFile(req.LocalPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, _ := io.ReadAll(gz)
+	if !bytes.Contains(text, []byte("CREATE TABLE `users`")) || bytes.Contains(text, []byte("`orders`")) {
+		t.Fatalf("extract = %q", text)
+	}
+
+	_, _, err = prepareRestoreFile(RestoreRequest{LocalPath: src, Logger: logger, Tables: &models.TableSelection{Tables: []string{"nope"}}})
+	if err == nil {
+		t.Fatal("a selection with no tables in the backup should fail")
+	}
+}
@@ -10,9 +10,12 @@ import (
 	"strings"
 	"time"
 
+	"dback/backend/codec"
+	"dback/backend/crypt"
 	"dback/backend/db"
 	"dback/backend/preflight"
 	"dback/backend/ssh"
+	"dback/backend/throttle"
 	"dback/models"
 )
 
@@ -35,11 +38,43 @@ type BackupRequest struct {
 	Destination string
 	Logger      Logger
 	Progress    ProgressFunc
+	// ResumePath continues an interrupted tmp-file download of the same OperationID
+	// (see FindResumableBackup) instead of starting a new file.
+	ResumePath string
+	// Keys encrypts the dump as it is written to disk; nil writes it as the host sent it.
+	Keys *crypt.Keys
+	// Bandwidth caps the dump download and adds the rate to progress messages; nil leaves
+	// it unlimited.
+	Bandwidth *throttle.Limits
 }
 
+// BackupResult describes the downloaded dump. Path and Size are the first file; Files lists
+// every file when the profile backs up several databases.
 type BackupResult struct {
-	Path string
-	Size int64
+	Path  string
+	Size  int64
+	Files []BackupFile
+}
+
+// BackupFile is one downloaded dump of one database.
+type BackupFile struct {
+	Database string
+	Path     string
+	Size     int64
+	// Binlog is the binary log position of a full dump, or the range an incremental covers.
+	Binlog *models.BinlogRange
+	// Type is empty for a logical dump.
+	Type models.BackupType
+	// Compression is the codec the file was written with.
+	Compression models.Compression
+	// Encrypted marks a file written with BackupRequest.Keys.
+	Encrypted bool
+	// Partial marks a dump that row filters left some rows out of.
+	Partial bool
+	// AddedBytes is what storing the dump added to the chunk repository.
+	AddedBytes int64
+	// ServerVersion is the database version preflight found on the source host.
+	ServerVersion string
 }
 
 // BackupSSH performs backup with streaming first, tmp-file fallback on retryable errors.
@@ -56,6 +91,8 @@ func BackupSSH(ctx context.Context, req BackupRequest) (BackupResult, error) {
 		return BackupResult{}, err
 	}
 	defer client.Close()
+	client = throttleExecutor(ctx, client, req.Bandwidth)
+	req.Progress = withRate(req.Progress, req.Bandwidth)
 
 	pf, pfErr := preflight.Run(client, p, 0, req.OperationID)
 	if pfErr != nil {
@@ -64,19 +101,113 @@ func BackupSSH(ctx context.Context, req BackupRequest) (BackupResult, error) {
 	}
 	logReq(req, "preflight", "", 0, preflight.Summary(pf), "Succeeded", "")
 
+	return withExportHooks(ctx, req, sshExportHooks(client, p), func() (BackupResult, error) {
+		if p.PhysicalBackup {
+			if pf.PhysicalTool.Found() {
+				return backupSSHPhysical(ctx, client, req, pf)
+			}
+			logReq(req, "physical", "", 0, "Neither mariadb-backup nor xtrabackup found on the host; taking a logical dump", "Warning", "")
+		}
+		if p.Databases.Active() {
+			return backupSSHDatabases(ctx, client, req, pf)
+		}
+		return backupSSHDump(ctx, client, req, pf)
+	})
+}
+
+// backupSSHDatabases dumps each selected database to its own file. A failing database does
+// not stop the others; the files that succeeded are returned with the joined errors.
+func backupSSHDatabases(ctx context.Context, client ssh.Executor, req BackupRequest, pf preflight.Result) (BackupResult, error) {
+	p := req.Profile
+	names, err := resolveDatabases(client, p)
+	if err != nil {
+		logReq(req, "databases", "", 0, "Could not resolve databases to back up", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	logReq(req, "databases", "", 0, fmt.Sprintf("Backing up %d database(s): %s", len(names), strings.Join(names, ", ")), "Succeeded", "")
+
+	var result BackupResult
+	var errs []error
+	for i, name := range names {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		one := req
+		one.Profile.TargetDBName = name
+		one.Profile.Databases = nil
+		one.ResumePath = ""
+		if req.Progress != nil {
+			prefix := fmt.Sprintf("[%d/%d %s] ", i+1, len(names), name)
+			one.Progress = func(msg string, current, total int64) {
+				req.Progress(prefix+msg, current, total)
+			}
+		}
+		res, err := backupSSHDump(ctx, client, one, pf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return result, ctx.Err()
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		result.Files = append(result.Files, res.Files...)
+	}
+	if len(result.Files) > 0 {
+		result.Path, result.Size = result.Files[0].Path, result.Files[0].Size
+	}
+	return result, errors.Join(errs...)
+}
+
+// resolveDatabases lists the server's databases when the selection needs them.
+func resolveDatabases(client ssh.Executor, p models.Profile) ([]string, error) {
+	var available []string
+	if p.Databases.Mode != models.DatabasesList {
+		cmd, err := db.BuildListDatabasesCommand(p)
+		if err != nil {
+			return nil, err
+		}
+		out, err := client.RunCommand(cmd)
+		if err != nil {
+			return nil, fmt.Errorf("list databases: %w: %s", err, strings.TrimSpace(out))
+		}
+		available = db.ParseDatabaseList(out)
+	}
+	return db.SelectDatabases(*p.Databases, available)
+}
+
+// backupSSHDump downloads the dump, trying each strategy in turn.
+func backupSSHDump(ctx context.Context, client ssh.Executor, req BackupRequest, pf preflight.Result) (BackupResult, error) {
+	p := req.Profile
 	estimatedTotal := estimateBackupTotal(client, p, req.Progress)
 
 	hostDir := filepath.Join(req.Destination, safeName(p.Name))
 	if err := os.MkdirAll(hostDir, 0755); err != nil {
 		return BackupResult{}, fmt.Errorf("create host backup folder: %w", err)
 	}
-	fileName := fmt.Sprintf("%s_%s.sql.gz", safeName(p.TargetDBName), time.Now().Format("02_01_2006_15_04_05"))
+	fileName := fmt.Sprintf("%s_%s.sql%s", safeName(p.TargetDBName), time.Now().Format("02_01_2006_15_04_05"), codec.Ext(p.Compression))
+	if req.Keys != nil {
+		fileName += crypt.Ext
+	}
 	fullPath := filepath.Join(hostDir, fileName)
 
-	exportCmd := db.BuildExportCommand(p)
+	tables, err := resolveTablePlan(client, p)
+	if err != nil {
+		logReq(req, "tables", "", 0, "Could not resolve table filter", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	if p.Tables.Active() {
+		logReq(req, "tables", "", 0, describeTablePlan(tables), "Succeeded", "")
+	}
+	exportCmd := db.BuildFilteredExportCommand(p, tables)
 	logReq(req, "command", string(StrategyStreaming), 0, db.MaskCommand(exportCmd), "Built", "")
 
 	strategies := backupStrategies(p)
+	if req.ResumePath != "" {
+		// Only the tmp-file strategy keeps resumable state; stream again if that fails.
+		fullPath = req.ResumePath
+		strategies = []Strategy{StrategyTmpFile, StrategyStreaming}
+		logReq(req, "resume", string(StrategyTmpFile), 0, "Resuming interrupted download of "+fullPath, "Started", "")
+	}
 	var lastErr error
 	for attempt, strategy := range strategies {
 		if err := ctx.Err(); err != nil {
@@ -87,12 +218,12 @@ func BackupSSH(ctx context.Context, req BackupRequest) (BackupResult, error) {
 		var err error
 		switch strategy {
 		case StrategyStreaming:
-			size, err = backupStream(ctx, client, p, fullPath, estimatedTotal, req.Progress)
+			size, err = backupStream(ctx, client, p, tables, fullPath, req.Keys, estimatedTotal, req.Progress)
 		case StrategyTmpFile:
-			size, err = backupTmpFile(ctx, client, p, pf.SelectedTmpDir, fullPath, req.OperationID, estimatedTotal, req.Progress)
+			size, err = backupTmpFile(ctx, client, p, tables, pf.SelectedTmpDir, fullPath, req.OperationID, req.Keys, estimatedTotal, req.Progress)
 		}
 		if err == nil {
-			if validateErr := validateBackupIntegrity(fullPath); validateErr != nil {
+			if validateErr := validateDumpIntegrity(fullPath, req.Keys); validateErr != nil {
 				err = validateErr
 			} else if validateErr := validateLocalFile(fullPath, size, ""); validateErr != nil {
 				err = validateErr
@@ -103,7 +234,10 @@ func BackupSSH(ctx context.Context, req BackupRequest) (BackupResult, error) {
 		if err == nil {
 			removeMeta(fullPath)
 			logReq(req, "backup", string(strategy), attempt+1, "Backup completed", "Succeeded", "")
-			return BackupResult{Path: fullPath, Size: size}, nil
+			file := BackupFile{Database: p.TargetDBName, Path: fullPath, Size: size, Binlog: dumpBinlogRange(req, fullPath), Compression: codec.Normalize(p.Compression), Encrypted: req.Keys != nil, Partial: len(tables.Filtered) > 0, ServerVersion: firstLine(pf.DBVersion)}
+			file = splitBackup(req, file, tables)
+			file = repositoryBackup(req, file)
+			return BackupResult{Path: file.Path, Size: file.Size, Files: []BackupFile{file}}, nil
 		}
 		lastErr = err
 		logReq(req, "backup", string(strategy), attempt+1, err.Error(), "Failed", err.Error())
@@ -120,6 +254,44 @@ func BackupSSH(ctx context.Context, req BackupRequest) (BackupResult, error) {
 	return BackupResult{}, lastErr
 }
 
+// resolveTablePlan lists TargetDBName's tables and applies the profile's table filter.
+func resolveTablePlan(client ssh.Executor, p models.Profile) (db.TablePlan, error) {
+	if !p.Tables.Active() {
+		return db.TablePlan{}, nil
+	}
+	cmd, err := db.BuildListTablesCommand(p)
+	if err != nil {
+		return db.TablePlan{}, err
+	}
+	out, err := client.RunCommand(cmd)
+	if err != nil {
+		return db.TablePlan{}, fmt.Errorf("list tables: %w: %s", err, strings.TrimSpace(out))
+	}
+	return db.PlanTables(*p.Tables, db.ParseTableList(out)), nil
+}
+
+// describeTablePlan summarizes a table plan for the activity log.
+func describeTablePlan(plan db.TablePlan) string {
+	if plan.Empty() {
+		return "Table filter matches no tables; dumping all tables"
+	}
+	parts := make([]string, 0, 3)
+	if len(plan.Excluded) > 0 {
+		parts = append(parts, fmt.Sprintf("excluding %d table(s): %s", len(plan.Excluded), strings.Join(plan.Excluded, ", ")))
+	}
+	if len(plan.SchemaOnly) > 0 {
+		parts = append(parts, fmt.Sprintf("structure only for %d table(s): %s", len(plan.SchemaOnly), strings.Join(plan.SchemaOnly, ", ")))
+	}
+	if len(plan.Filtered) > 0 {
+		filtered := make([]string, 0, len(plan.Filtered))
+		for _, f := range plan.Filtered {
+			filtered = append(filtered, fmt.Sprintf("%s (%s)", f.Table, f.Where))
+		}
+		parts = append(parts, fmt.Sprintf("rows filtered for %d table(s): %s", len(plan.Filtered), strings.Join(filtered, ", ")))
+	}
+	return "Table filter: " + strings.Join(parts, "; ")
+}
+
 func backupStrategies(p models.Profile) []Strategy {
 	// Jump host: dump to remote file first, then download (phpMyAdmin-style).
 	// Streaming through a double SSH tunnel is slow and can truncate large dumps.
@@ -129,8 +301,8 @@ func backupStrategies(p models.Profile) []Strategy {
 	return []Strategy{StrategyStreaming, StrategyTmpFile}
 }
 
-func backupStream(ctx context.Context, client ssh.Executor, p models.Profile, fullPath string, estimatedTotal int64, progress ProgressFunc) (int64, error) {
-	cmd := db.BuildExportCommand(p)
+func backupStream(ctx context.Context, client ssh.Executor, p models.Profile, tables db.TablePlan, fullPath string, keys *crypt.Keys, estimatedTotal int64, progress ProgressFunc) (int64, error) {
+	cmd := db.BuildFilteredExportCommand(p, tables)
 	stdout, stderr, session, err := client.RunCommandStream(cmd)
 	if err != nil {
 		return 0, err
@@ -153,8 +325,17 @@ func backupStream(ctx context.Context, client ssh.Executor, p models.Profile, fu
 		return 0, err
 	}
 	defer out.Close()
+	var dst io.Writer = out
+	var enc *crypt.Writer
+	if keys != nil {
+		if enc, err = crypt.NewWriter(out, *keys); err != nil {
+			_ = os.Remove(fullPath)
+			return 0, fmt.Errorf("encrypt backup: %w", err)
+		}
+		dst = enc
+	}
 
-	written, err := fastCopy(out, &ssh.ProgressReader{
+	written, err := fastCopy(dst, &ssh.ProgressReader{
 		Reader: stdout,
 		Total:  estimatedTotal,
 		Callback: func(current int64, total int64) {
@@ -172,6 +353,9 @@ func backupStream(ctx context.Context, client ssh.Executor, p models.Profile, fu
 		_ = os.Remove(fullPath)
 		return 0, ctx.Err()
 	}
+	if err == nil && enc != nil {
+		err = enc.Close()
+	}
 	if err != nil {
 		_ = os.Remove(fullPath)
 		return 0, err
@@ -183,9 +367,9 @@ func backupStream(ctx context.Context, client ssh.Executor, p models.Profile, fu
 	<-stderrDone
 	if msg := strings.TrimSpace(stderrBuf.String()); msg != "" && dumpStderrIsFatal(msg) {
 		_ = os.Remove(fullPath)
-		return 0, fmt.Errorf("mysqldump error: %s", msg)
+		return 0, fmt.Errorf("dump error: %s", msg)
 	}
-	if err := validateBackupIntegrity(fullPath); err != nil {
+	if err := validateDumpIntegrity(fullPath, keys); err != nil {
 		_ = os.Remove(fullPath)
 		return 0, err
 	}
@@ -193,17 +377,27 @@ func backupStream(ctx context.Context, client ssh.Executor, p models.Profile, fu
 		_ = os.Remove(fullPath)
 		return 0, fmt.Errorf("backup file too small (%d bytes)", written)
 	}
+	if enc != nil {
+		// Report the size on disk, which adds the header and a tag per chunk to the dump.
+		info, err := out.Stat()
+		if err != nil {
+			return 0, err
+		}
+		written = info.Size()
+	}
 	return written, nil
 }
 
-func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, tmpDir, localPath, operationID string, estimatedTotal int64, progress ProgressFunc) (int64, error) {
-	remotePath := tmpDir + "/dump.sql.gz"
+func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, tables db.TablePlan, tmpDir, localPath, operationID string, keys *crypt.Keys, estimatedTotal int64, progress ProgressFunc) (int64, error) {
+	remotePath := tmpDir + "/dump.sql" + codec.Ext(p.Compression)
 	mkdir := shellMkdir(tmpDir)
 	if _, err := client.RunCommand(mkdir); err != nil {
 		return 0, fmt.Errorf("create tmp dir: %w", err)
 	}
 
+	resuming := false
 	if meta, ok := loadMeta(localPath); ok && meta.RemotePath == remotePath {
+		resuming = true
 		if progress != nil {
 			total := meta.Size
 			if total <= 0 {
@@ -215,7 +409,7 @@ func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, t
 		if progress != nil {
 			progress("Creating remote dump on server...", 0, estimatedTotal)
 		}
-		exportCmd := db.BuildExportToFileCommand(p, remotePath)
+		exportCmd := db.BuildFilteredExportToFileCommand(p, remotePath, tables)
 		if out, err := client.RunCommand(exportCmd); err != nil {
 			return 0, fmt.Errorf("remote dump: %w: %s", err, strings.TrimSpace(out))
 		}
@@ -231,7 +425,7 @@ func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, t
 		return 0, fmt.Errorf("remote dump too small (%d bytes)", remoteSize)
 	}
 
-	if err := validateRemoteBackupIntegrity(client, remotePath); err != nil {
+	if err := validateRemoteBackupIntegrity(client, remotePath, p.Compression); err != nil {
 		return 0, err
 	}
 
@@ -245,13 +439,22 @@ func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, t
 		Size:        remoteSize,
 		Checksum:    remoteChecksum,
 	}
-	offset := localResumeOffset(localPath, meta, remoteSize)
-	meta.Offset = offset
-	_ = saveMeta(meta)
-
 	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
 		return 0, err
 	}
+	offset := localResumeOffset(localPath, meta, remoteSize)
+	var enc *crypt.Writer
+	if keys != nil {
+		// An encrypted file resumes after its last whole chunk.
+		var encFile *os.File
+		encFile, enc, offset, err = openEncryptedAppend(localPath, *keys, resuming)
+		if err != nil {
+			return 0, err
+		}
+		defer encFile.Close()
+	}
+	meta.Offset = offset
+	_ = saveMeta(meta)
 
 	if progress != nil {
 		progress(fmt.Sprintf("Downloading backup (%.1f MB)...", float64(remoteSize)/1024/1024), offset, remoteSize)
@@ -268,11 +471,15 @@ func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, t
 	var stderrBuf strings.Builder
 	go func() { _, _ = io.Copy(&stderrBuf, stderr) }()
 
-	out, err := openLocalAppend(localPath, offset)
-	if err != nil {
-		return 0, err
+	var out io.Writer = enc
+	if enc == nil {
+		f, err := openLocalAppend(localPath, offset)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		out = f
 	}
-	defer out.Close()
 
 	written, err := fastCopy(out, &ssh.ProgressReader{
 		Reader: stdout,
@@ -299,7 +506,21 @@ func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, t
 	if remoteSize > 0 && finalSize != remoteSize {
 		return finalSize, fmt.Errorf("incomplete download: got %d want %d", finalSize, remoteSize)
 	}
-	if remoteChecksum != "" {
+	if enc != nil {
+		if err := enc.Close(); err != nil {
+			return finalSize, err
+		}
+		if remoteChecksum != "" {
+			if sum, err := checksumEncrypted(localPath, *keys); err != nil || sum != remoteChecksum {
+				return finalSize, fmt.Errorf("checksum mismatch")
+			}
+		}
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return finalSize, err
+		}
+		finalSize = info.Size()
+	} else if remoteChecksum != "" {
 		if err := validateLocalFile(localPath, remoteSize, remoteChecksum); err != nil {
 			return finalSize, err
 		}
@@ -310,13 +531,44 @@ func backupTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, t
 }
 
 type RestoreRequest struct {
-	Profile          models.Profile
-	OperationID      string
-	LocalPath        string
-	FileSize         int64
-	Logger           Logger
-	Progress         ProgressFunc
-	TargetDBOverride string // optional temp database for deep verify
+	Profile     models.Profile
+	OperationID string
+	LocalPath   string
+	FileSize    int64
+	Logger      Logger
+	Progress    ProgressFunc
+	// TargetDBOverride restores into this database instead of the profile's, created
+	// empty first: deep verify's temp database, or a restore under a new name.
+	TargetDBOverride string
+	// Resume continues an interrupted tmp-file upload of the same OperationID (see HasResumableRestore).
+	Resume bool
+	// Tables restores only these tables from the backup and leaves the database's other
+	// tables in place: the target database is not dropped first.
+	Tables *models.TableSelection
+	// Incremental replays a binlog incremental on top of an earlier restore: the target
+	// database is neither dropped nor created. StopAt, when set, drops the events after it.
+	Incremental bool
+	StopAt      time.Time
+	// Physical restores a physical backup with prepare and copy-back, replacing the
+	// destination server's whole data directory.
+	Physical bool
+	// Keys decrypt an encrypted backup; plain files ignore them.
+	Keys crypt.Keys
+	// SearchReplace rewrites text in the restored rows (see searchreplace.Apply).
+	SearchReplace []models.ReplacePair
+	// Definer is what happens to the DEFINER clauses of views, triggers, routines and
+	// events (see definer.Apply).
+	Definer models.DefinerMode
+	// SourceVersion is the server version the backup was taken on; with the destination's
+	// preflight version it decides the compatibility rewrite (see compat.Select).
+	SourceVersion string
+	// Parallel imports the dump's tables over this many sessions at once (up to
+	// MaxParallelRestore); 0 or 1 restores in one session. PostgreSQL, incremental and
+	// resumed restores always use one.
+	Parallel int
+	// Bandwidth caps the upload to the host and adds the rate to progress messages; nil
+	// leaves it unlimited.
+	Bandwidth *throttle.Limits
 }
 
 func restoreProfile(req RestoreRequest) models.Profile {
@@ -333,16 +585,34 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 	if err := db.ValidateProfileForRemoteOps(p); err != nil {
 		return err
 	}
-	in, err := os.Open(req.LocalPath)
+	if req.Physical {
+		return restorePhysical(ctx, req)
+	}
+	source := req.LocalPath
+	req, reassembled, err := reassembleRestoreFile(req)
+	if err != nil {
+		return err
+	}
+	defer reassembled()
+	if err := checkRestoreKey(req); err != nil {
+		return err
+	}
+	req, cleanup, err := prepareRestoreFile(req)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	filters, err := restoreFilters(req)
+	if err != nil {
+		return err
+	}
+	input, err := newRestoreInput(req, filters)
 	if err != nil {
 		return err
 	}
-	defer in.Close()
 
 	if req.FileSize <= 0 {
-		if info, statErr := os.Stat(req.LocalPath); statErr == nil {
-			req.FileSize = info.Size()
-		}
+		req.FileSize = input.size
 	}
 	if sum, sumErr := checksumFile(req.LocalPath); sumErr == nil {
 		logRestore(req, "checksum", "", 0, "local sha256="+sum, "Info", "")
@@ -353,21 +623,27 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 		return err
 	}
 	defer client.Close()
-
-	pf, pfErr := preflight.Run(client, p, req.FileSize, req.OperationID)
+	client = throttleExecutor(ctx, client, req.Bandwidth)
+	req.Progress = withRate(req.Progress, req.Bandwidth)
+
+	// Preflight checks for the tool that decompresses this dump, whatever the host's own
+	// backup codec is.
+	pfProfile := p
+	pfProfile.Compression = models.Compression(input.compression)
+	pf, pfErr := preflight.Run(client, pfProfile, req.FileSize, req.OperationID)
 	if pfErr != nil {
 		logRestore(req, "preflight", "", 0, preflight.FailureDetails(pf, pfErr), "Failed", pfErr.Error())
 		return pfErr
 	}
 	logRestore(req, "preflight", "", 0, preflight.Summary(pf), "Succeeded", "")
 
-	if req.Progress != nil {
-		req.Progress("Preparing restore...", 0, req.FileSize)
+	if f, ok := compatFilter(req, pf.DBVersion); ok {
+		input.addFilter(f)
 	}
+	compression := input.uploadCompression()
 
-	compression, err := detectCompression(in)
-	if err != nil {
-		return err
+	if req.Progress != nil {
+		req.Progress("Preparing restore...", 0, req.FileSize)
 	}
 
 	importCmd := db.BuildImportStreamCommand(p, compression)
@@ -377,6 +653,22 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 	logRestore(req, "command", string(StrategyStreaming), 0, db.MaskCommand(importCmd), "Built", "")
 
 	strategies := []Strategy{StrategyStreaming, StrategyTmpFile}
+	var plan parallelPlan
+	if req.Parallel > 1 && !req.Resume && !req.Incremental && !p.UsesPostgreSQL() {
+		var planned func()
+		plan, planned, err = planParallelRestore(input, source)
+		if err != nil {
+			logRestore(req, "parallel", "", 0, "Could not split the dump at table boundaries; restoring in one session", "Warning", err.Error())
+		} else {
+			defer planned()
+			strategies = []Strategy{StrategyParallel, StrategyTmpFile}
+			logRestore(req, "parallel", string(StrategyParallel), 0, parallelSummary(plan, req.Parallel), "Succeeded", "")
+		}
+	}
+	if req.Resume {
+		strategies = []Strategy{StrategyTmpFile, StrategyStreaming}
+		logRestore(req, "resume", string(StrategyTmpFile), 0, "Resuming interrupted upload of "+req.LocalPath, "Started", "")
+	}
 	var lastErr error
 	for attempt, strategy := range strategies {
 		if err := ctx.Err(); err != nil {
@@ -387,13 +679,17 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 		var prep string
 		if override := strings.TrimSpace(req.TargetDBOverride); override != "" {
 			prep = db.BuildImportPrepareTempCommand(p, override)
-		} else {
+		} else if req.Tables.Active() {
+			prep = db.BuildImportEnsureDatabaseCommand(p)
+		} else if !req.Incremental {
 			prep = db.BuildImportPrepareCommand(p)
 		}
 		if prep != "" {
 			if req.Progress != nil {
-				if req.TargetDBOverride != "" {
-					req.Progress("Preparing temporary verify database...", 0, req.FileSize)
+				if override := strings.TrimSpace(req.TargetDBOverride); override != "" {
+					req.Progress(fmt.Sprintf("Preparing database %s...", override), 0, req.FileSize)
+				} else if req.Tables.Active() {
+					req.Progress("Checking target database...", 0, req.FileSize)
 				} else {
 					req.Progress("Recreating target database...", 0, req.FileSize)
 				}
@@ -406,7 +702,11 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 				}
 				continue
 			}
-			logRestore(req, "prepare", string(strategy), attempt+1, "DROP/CREATE completed", "Succeeded", "")
+			details := "DROP/CREATE completed"
+			if req.Tables.Active() {
+				details = "CREATE DATABASE IF NOT EXISTS completed; other tables left in place"
+			}
+			logRestore(req, "prepare", string(strategy), attempt+1, details, "Succeeded", "")
 		}
 
 		if req.Progress != nil {
@@ -415,16 +715,12 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 
 		var restoreErr error
 		switch strategy {
+		case StrategyParallel:
+			restoreErr = restoreParallel(ctx, client, parallelImportCommand(req), plan, req.Parallel, req.Progress)
 		case StrategyStreaming:
-			if _, seekErr := in.Seek(0, io.SeekStart); seekErr != nil {
-				return seekErr
-			}
-			restoreErr = restoreStream(ctx, client, p, in, req.FileSize, compression, req.Progress, req.TargetDBOverride)
+			restoreErr = restoreStream(ctx, client, p, input, req.Progress, req.TargetDBOverride)
 		case StrategyTmpFile:
-			if _, seekErr := in.Seek(0, io.SeekStart); seekErr != nil {
-				return seekErr
-			}
-			restoreErr = restoreTmpFile(ctx, client, p, pf.SelectedTmpDir, req.LocalPath, in, req.FileSize, compression, req.OperationID, req.Progress, req.TargetDBOverride)
+			restoreErr = restoreTmpFile(ctx, client, p, pf.SelectedTmpDir, input, req.OperationID, req.Progress, req.TargetDBOverride)
 		}
 		if restoreErr == nil {
 			logRestore(req, "restore", string(strategy), attempt+1, "Restore completed", "Succeeded", "")
@@ -443,11 +739,21 @@ func RestoreSSH(ctx context.Context, req RestoreRequest) error {
 	return lastErr
 }
 
-func restoreStream(ctx context.Context, client ssh.Executor, p models.Profile, in *os.File, total int64, compression string, progress ProgressFunc, targetDBOverride string) error {
+func restoreStream(ctx context.Context, client ssh.Executor, p models.Profile, input *restoreInput, progress ProgressFunc, targetDBOverride string) error {
+	compression := input.uploadCompression()
 	importCmd := db.BuildImportStreamCommand(p, compression)
 	if override := strings.TrimSpace(targetDBOverride); override != "" {
 		importCmd = db.BuildImportStreamCommandForVerify(p, compression, override)
 	}
+	body, err := input.open(0, func(current int64) {
+		if progress != nil {
+			progress(fmt.Sprintf("Streaming restore %.1f%%", percent(current, input.size)), current, input.size)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
 	stdin, stderr, session, err := client.RunCommandPipeInput(importCmd)
 	if err != nil {
 		return err
@@ -458,15 +764,7 @@ func restoreStream(ctx context.Context, client ssh.Executor, p models.Profile, i
 	var stderrBuf strings.Builder
 	go func() { _, _ = io.Copy(&stderrBuf, stderr) }()
 
-	_, err = fastCopy(stdin, &ssh.ProgressReader{
-		Reader: in,
-		Total:  total,
-		Callback: func(current int64, total int64) {
-			if progress != nil {
-				progress(fmt.Sprintf("Streaming restore %.1f%%", percent(current, total)), current, total)
-			}
-		},
-	})
+	_, err = fastCopy(stdin, body)
 	if ctx.Err() != nil {
 		_ = stdin.Close()
 		return ctx.Err()
@@ -483,53 +781,56 @@ func restoreStream(ctx context.Context, client ssh.Executor, p models.Profile, i
 	return nil
 }
 
-func restoreTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, tmpDir, localPath string, in *os.File, total int64, compression, operationID string, progress ProgressFunc, targetDBOverride string) error {
-	remotePath := tmpDir + "/import.sql.gz"
+// restoreTmpFile uploads input to a file in tmpDir on the host and imports it from there.
+// An upload of the file as it is continues from where an interrupted one stopped; a
+// rewritten one starts over.
+func restoreTmpFile(ctx context.Context, client ssh.Executor, p models.Profile, tmpDir string, input *restoreInput, operationID string, progress ProgressFunc, targetDBOverride string) error {
+	compression := input.uploadCompression()
+	remotePath := tmpDir + "/import.sql" + codec.Ext(models.Compression(compression))
 	mkdir := shellMkdir(tmpDir)
 	if _, err := client.RunCommand(mkdir); err != nil {
 		return err
 	}
 
-	meta, hasMeta := loadMeta(localPath)
+	localPath, total := input.path, input.size
 	offset := int64(0)
-	if hasMeta && meta.RemotePath == remotePath {
-		offset = meta.Offset
-	} else {
-		sizeOut, _ := client.RunCommand(db.BuildFileSizeCommand(remotePath))
-		fmt.Sscanf(strings.TrimSpace(sizeOut), "%d", &offset)
-	}
-
-	uploadCmd := db.BuildUploadCommand(remotePath, offset > 0)
-	stdin, stderr, session, err := client.RunCommandPipeInput(uploadCmd)
-	if err != nil {
-		return err
-	}
-
-	if offset > 0 {
-		if _, err := in.Seek(offset, io.SeekStart); err != nil {
-			_ = session.Close()
-			return err
+	if !input.rewrite {
+		meta, hasMeta := loadMeta(localPath)
+		if hasMeta && meta.RemotePath == remotePath {
+			offset = meta.Offset
+		} else {
+			sizeOut, _ := client.RunCommand(db.BuildFileSizeCommand(remotePath))
+			fmt.Sscanf(strings.TrimSpace(sizeOut), "%d", &offset)
 		}
 	}
 
-	_, copyErr := fastCopy(stdin, &ssh.ProgressReader{
-		Reader: in,
-		Total:  total - offset,
-		Callback: func(current int64, total int64) {
-			meta = FileMeta{
+	body, err := input.open(offset, func(current int64) {
+		if !input.rewrite {
+			_ = saveMeta(FileMeta{
 				OperationID: operationID,
 				RemotePath:  remotePath,
 				LocalPath:   localPath,
 				Size:        total,
-				Offset:      offset + current,
+				Offset:      current,
 				Compression: compression,
-			}
-			_ = saveMeta(meta)
-			if progress != nil {
-				progress(fmt.Sprintf("Uploading tmp file %.1f%%", percent(offset+current, total)), offset+current, total)
-			}
-		},
+			})
+		}
+		if progress != nil {
+			progress(fmt.Sprintf("Uploading tmp file %.1f%%", percent(current, total)), current, total)
+		}
 	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	uploadCmd := db.BuildUploadCommand(remotePath, offset > 0)
+	stdin, stderr, session, err := client.RunCommandPipeInput(uploadCmd)
+	if err != nil {
+		return err
+	}
+
+	sent, copyErr := fastCopy(stdin, body)
 	_ = stdin.Close()
 	if copyErr != nil {
 		_ = session.Close()
@@ -544,8 +845,12 @@ func restoreTmpFile(ctx context.Context, client ssh.Executor, p models.Profile,
 	remoteSizeOut, _ := client.RunCommand(db.BuildFileSizeCommand(remotePath))
 	var remoteSize int64
 	fmt.Sscanf(strings.TrimSpace(remoteSizeOut), "%d", &remoteSize)
-	if total > 0 && remoteSize != total {
-		return fmt.Errorf("upload size mismatch: remote %d local %d", remoteSize, total)
+	want := offset + sent
+	if !input.rewrite && total > 0 {
+		want = total
+	}
+	if remoteSize != want {
+		return fmt.Errorf("upload size mismatch: remote %d local %d", remoteSize, want)
 	}
 
 	importCmd := db.BuildImportFromFileCommand(p, remotePath, compression)
@@ -582,22 +887,10 @@ func isRetryable(err error) bool {
 	return false
 }
 
+// detectCompression reads the codec of a backup file from its first bytes.
 func detectCompression(file *os.File) (string, error) {
-	var magic [4]byte
-	n, err := io.ReadFull(file, magic[:])
-	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
-		return "", err
-	}
-	if _, seekErr := file.Seek(0, io.SeekStart); seekErr != nil {
-		return "", seekErr
-	}
-	if n >= 2 && magic[0] == 0x1f && magic[1] == 0x8b {
-		return "gzip", nil
-	}
-	if n >= 4 && magic == [4]byte{0x28, 0xb5, 0x2f, 0xfd} {
-		return "zstd", nil
-	}
-	return "", nil
+	c, err := codec.Detect(file)
+	return string(c), err
 }
 
 func cancelOnContext(ctx context.Context, session ssh.Session, client ssh.Executor) {
@@ -662,6 +955,16 @@ func logReq(req BackupRequest, phase, strategy string, attempt int, details, sta
 	}
 }
 
+// firstLine returns the first non-blank line of s, trimmed.
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
 func logRestore(req RestoreRequest, phase, strategy string, attempt int, details, status, errStr string) {
 	if req.Logger != nil {
 		req.Logger.Phase("Import", phase, strategy, attempt, details, status, errStr)
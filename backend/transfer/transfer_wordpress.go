@@ -9,7 +9,9 @@ import (
 	"time"
 
 	"dback/backend/db"
+	"dback/backend/throttle"
 	"dback/backend/wordpress"
+	"dback/models"
 )
 
 // BackupWordPress downloads a gzip SQL dump via the WordPress REST plugin.
@@ -37,7 +39,25 @@ func BackupWordPress(ctx context.Context, req BackupRequest) (BackupResult, erro
 		return BackupResult{}, err
 	}
 	logReq(req, "preflight", "", 0, pf.Summary, "Succeeded", "")
+	if p.Tables != nil && len(p.Tables.Rows) > 0 && !pf.PluginAtLeast(rowFilterPluginVersion) {
+		err := fmt.Errorf("row filters need DBack DB Tools %s or newer on the site (it runs %q); install the current plugin first", rowFilterPluginVersion, pf.PluginVersion)
+		logReq(req, "tables", "", 0, "Plugin too old for row filters", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+
+	return withExportHooks(ctx, req, wordpressExportHooks(client, p), func() (BackupResult, error) {
+		return backupWordPressDump(ctx, client, req, pf.DBVersion)
+	})
+}
+
+// rowFilterPluginVersion is the first plugin version that applies table_where conditions;
+// older plugins would silently dump every row.
+const rowFilterPluginVersion = "1.3.0"
 
+// backupWordPressDump streams the plugin export into a local .sql.gz file. serverVersion is
+// the site's database version from preflight.
+func backupWordPressDump(ctx context.Context, client *wordpress.Client, req BackupRequest, serverVersion string) (BackupResult, error) {
+	p := req.Profile
 	hostDir := filepath.Join(req.Destination, safeName(p.Name))
 	if err := os.MkdirAll(hostDir, 0755); err != nil {
 		return BackupResult{}, fmt.Errorf("create host backup folder: %w", err)
@@ -49,15 +69,25 @@ func BackupWordPress(ctx context.Context, req BackupRequest) (BackupResult, erro
 	fileName := fmt.Sprintf("%s_%s.sql.gz", dbLabel, time.Now().Format("02_01_2006_15_04_05"))
 	fullPath := filepath.Join(hostDir, fileName)
 
+	tables, err := resolveWordPressTablePlan(ctx, client, p)
+	if err != nil {
+		logReq(req, "tables", "", 0, "Could not resolve table filter", "Failed", err.Error())
+		return BackupResult{}, err
+	}
+	if p.Tables.Active() {
+		logReq(req, "tables", "", 0, describeTablePlan(tables), "Succeeded", "")
+	}
+
 	if req.Progress != nil {
 		req.Progress("Starting WordPress export...", 0, 0)
 	}
 
-	body, err := client.Export(ctx)
+	body, err := client.ExportTables(ctx, tables)
 	if err != nil {
 		return BackupResult{}, err
 	}
 	defer body.Close()
+	progress := withRate(req.Progress, req.Bandwidth)
 
 	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
 		return BackupResult{}, err
@@ -69,10 +99,10 @@ func BackupWordPress(ctx context.Context, req BackupRequest) (BackupResult, erro
 	defer out.Close()
 
 	written, err := fastCopy(out, &progressReader{
-		reader: body,
+		reader: req.Bandwidth.Reader(ctx, throttle.Download, body),
 		callback: func(current int64) {
-			if req.Progress != nil {
-				req.Progress(fmt.Sprintf("Streaming backup %.2f MB", float64(current)/1024/1024), current, 0)
+			if progress != nil {
+				progress(fmt.Sprintf("Streaming backup %.2f MB", float64(current)/1024/1024), current, 0)
 			}
 		},
 	})
@@ -96,7 +126,28 @@ func BackupWordPress(ctx context.Context, req BackupRequest) (BackupResult, erro
 		logReq(req, "checksum", string(StrategyStreaming), 0, "sha256="+sum, "Succeeded", "")
 	}
 	logReq(req, "backup", string(StrategyStreaming), 1, "Backup completed", "Succeeded", "")
-	return BackupResult{Path: fullPath, Size: written}, nil
+	file := splitBackup(req, BackupFile{Database: p.TargetDBName, Path: fullPath, Size: written, Compression: models.CompressionGzip, Partial: len(tables.Filtered) > 0, ServerVersion: firstLine(serverVersion)}, tables)
+	file = repositoryBackup(req, file)
+	return BackupResult{Path: file.Path, Size: file.Size, Files: []BackupFile{file}}, nil
+}
+
+// resolveWordPressTablePlan lists the WordPress database's tables and applies the profile's
+// table filter.
+func resolveWordPressTablePlan(ctx context.Context, client *wordpress.Client, p models.Profile) (db.TablePlan, error) {
+	if !p.Tables.Active() {
+		return db.TablePlan{}, nil
+	}
+	result, err := client.Query(ctx, "SHOW TABLES", "")
+	if err != nil {
+		return db.TablePlan{}, fmt.Errorf("list tables: %w", err)
+	}
+	tables := make([]string, 0, len(result.Rows))
+	for _, row := range result.Rows {
+		if len(row) > 0 && row[0] != "" {
+			tables = append(tables, row[0])
+		}
+	}
+	return db.PlanTables(*p.Tables, tables), nil
 }
 
 // RestoreWordPress uploads a gzip SQL dump to the WordPress REST plugin.
@@ -105,19 +156,26 @@ func RestoreWordPress(ctx context.Context, req RestoreRequest) error {
 	if err := db.ValidateProfileForWordPress(p); err != nil {
 		return err
 	}
-	in, err := os.Open(req.LocalPath)
+	req, reassembled, err := reassembleRestoreFile(req)
 	if err != nil {
 		return err
 	}
-	defer in.Close()
-
-	if req.FileSize <= 0 {
-		if info, statErr := os.Stat(req.LocalPath); statErr == nil {
-			req.FileSize = info.Size()
-		}
+	defer reassembled()
+	if err := checkRestoreKey(req); err != nil {
+		return err
 	}
-	if sum, sumErr := checksumFile(req.LocalPath); sumErr == nil {
-		logRestore(req, "checksum", "", 0, "local sha256="+sum, "Info", "")
+	req, cleanup, err := prepareRestoreFile(req)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	filters, err := restoreFilters(req)
+	if err != nil {
+		return err
+	}
+	input, err := newRestoreInput(req, filters)
+	if err != nil {
+		return err
 	}
 
 	client, err := wordpress.NewClient(p)
@@ -136,29 +194,45 @@ func RestoreWordPress(ctx context.Context, req RestoreRequest) error {
 	}
 	logRestore(req, "preflight", "", 0, pf.Summary, "Succeeded", "")
 
+	if f, ok := compatFilter(req, pf.DBVersion); ok {
+		input.addFilter(f)
+	}
+	// The plugin imports gzip only. Filtered uploads are compressed as gzip on the way
+	// already; a backup in another codec is recompressed as it is read.
+	if from := input.compression; input.gzipOnly() {
+		logRestore(req, "recompress", "", 0, fmt.Sprintf("Recompressing %s backup as gzip for the plugin", from), "Info", "")
+	}
+
+	if sum, sumErr := checksumFile(req.LocalPath); sumErr == nil {
+		logRestore(req, "checksum", "", 0, "local sha256="+sum, "Info", "")
+	}
+
 	if req.Progress != nil {
-		req.Progress("Uploading backup to WordPress...", 0, req.FileSize)
-	}
-
-	uploadReader := io.Reader(in)
-	if req.FileSize > 0 {
-		uploadReader = &progressReader{
-			reader: in,
-			callback: func(current int64) {
-				if req.Progress != nil {
-					req.Progress(fmt.Sprintf("Uploading restore %.1f%%", percent(current, req.FileSize)), current, req.FileSize)
-				}
-			},
+		req.Progress("Uploading backup to WordPress...", 0, input.size)
+	}
+
+	progress := withRate(req.Progress, req.Bandwidth)
+	body, err := input.open(0, func(current int64) {
+		if progress != nil && input.size > 0 {
+			progress(fmt.Sprintf("Uploading restore %.1f%%", percent(current, input.size)), current, input.size)
 		}
+	})
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	size := input.size
+	if input.rewrite {
+		size = 0
 	}
 
-	if err := client.Import(ctx, uploadReader, req.FileSize, db.WordPressImportDatabase(p)); err != nil {
+	if err := client.Import(ctx, req.Bandwidth.Reader(ctx, throttle.Upload, body), size, db.WordPressImportDatabase(p)); err != nil {
 		logRestore(req, "restore", string(StrategyStreaming), 1, err.Error(), "Failed", err.Error())
 		return err
 	}
 	logRestore(req, "restore", string(StrategyStreaming), 1, "Restore completed", "Succeeded", "")
 	if req.Progress != nil {
-		req.Progress("Restore completed", req.FileSize, req.FileSize)
+		req.Progress("Restore completed", input.size, input.size)
 	}
 	return nil
 }
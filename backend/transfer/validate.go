@@ -1,38 +1,45 @@
 package transfer
 
 import (
-	"compress/gzip"
 	"fmt"
-	"io"
-	"os"
 	"strings"
+
+	"dback/backend/codec"
+	"dback/backend/crypt"
+	"dback/models"
 )
 
-// validateBackupIntegrity ensures the backup file is a complete, valid gzip archive.
-// Uses pure Go to avoid requiring an external gzip binary on any platform.
+// validateBackupIntegrity ensures the backup file decompresses completely in the codec it
+// was written with. gzip, zstd and bzip2 are checked in pure Go so no external binary is
+// needed; xz uses the local xz tool when there is one.
 func validateBackupIntegrity(path string) error {
-	f, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("backup file is corrupt or incomplete (gzip check failed): %s", err)
+	if err := codec.Check(path); err != nil {
+		return fmt.Errorf("backup file is corrupt or incomplete: %s", err)
 	}
-	defer f.Close()
-	r, err := gzip.NewReader(f)
-	if err != nil {
-		return fmt.Errorf("backup file is corrupt or incomplete (gzip check failed): %s", err)
+	return nil
+}
+
+// validateDumpIntegrity checks a downloaded dump; an encrypted one must also decrypt with
+// keys, chunk by chunk.
+func validateDumpIntegrity(path string, keys *crypt.Keys) error {
+	if keys == nil {
+		return validateBackupIntegrity(path)
 	}
-	defer r.Close()
-	if _, err := io.Copy(io.Discard, r); err != nil {
-		return fmt.Errorf("backup file is corrupt or incomplete (gzip check failed): %s", err)
+	if _, err := crypt.Check(path, *keys); err != nil {
+		return fmt.Errorf("backup file is corrupt or incomplete: %s", err)
 	}
 	return nil
 }
 
-// validateRemoteBackupIntegrity runs gzip -t on the remote host before download.
+// validateRemoteBackupIntegrity tests the compressed dump on the remote host before download.
 func validateRemoteBackupIntegrity(client interface {
 	RunCommand(string) (string, error)
-}, remotePath string) error {
-	cmd := fmt.Sprintf("gzip -t %s 2>&1", shellQuote(remotePath))
-	out, err := client.RunCommand(cmd)
+}, remotePath string, compression models.Compression) error {
+	cmd := codec.TestCommand(compression, shellQuote(remotePath))
+	if cmd == "" {
+		return nil
+	}
+	out, err := client.RunCommand(cmd + " 2>&1")
 	if err != nil {
 		msg := strings.TrimSpace(out)
 		if msg == "" {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"dback/backend/db"
+	"dback/backend/sqldump"
 	"dback/models"
 )
 
@@ -73,9 +74,16 @@ func CaptureFingerprint(ctx context.Context, runner QueryRunner, profile models.
 
 	useDatabaseFunc := profile.UsesWordPress() && strings.TrimSpace(databaseName) == ""
 	connectDB := profile.UsesWordPress()
+	query := BuildFastTableRowsQuery(databaseName, useDatabaseFunc)
+	if profile.UsesPostgreSQL() {
+		// pg_stat_user_tables only covers the database psql is connected to.
+		if strings.TrimSpace(databaseName) != "" {
+			profile.TargetDBName = databaseName
+		}
+		connectDB, query = true, db.PgTableRowsQuery
+	}
 
 	if mode == ModeFast {
-		query := BuildFastTableRowsQuery(databaseName, useDatabaseFunc)
 		result, err := runner.RunQuery(ctx, profile, query, connectDB)
 		if err != nil {
 			return models.BackupFingerprint{}, err
@@ -84,10 +92,18 @@ func CaptureFingerprint(ctx context.Context, runner QueryRunner, profile models.
 		if err != nil {
 			return models.BackupFingerprint{}, err
 		}
-		return buildFingerprint(mode, counts), nil
+		plan := tablePlan(profile, counts)
+		// Estimates say nothing about a row filter; filtered tables are always counted.
+		for _, f := range plan.Filtered {
+			n, err := countRows(ctx, runner, profile, f.Table, f.Where, connectDB)
+			if err != nil {
+				return models.BackupFingerprint{}, err
+			}
+			counts[f.Table] = n
+		}
+		return buildFingerprint(mode, counts, plan), nil
 	}
 
-	query := BuildFastTableRowsQuery(databaseName, useDatabaseFunc)
 	result, err := runner.RunQuery(ctx, profile, query, connectDB)
 	if err != nil {
 		return models.BackupFingerprint{}, err
@@ -100,23 +116,77 @@ func CaptureFingerprint(ctx context.Context, runner QueryRunner, profile models.
 		return models.BackupFingerprint{}, fmt.Errorf("no tables found for fingerprint")
 	}
 
+	plan := tablePlan(profile, tables)
+	skip := make(map[string]bool, len(plan.Excluded)+len(plan.SchemaOnly))
+	for _, table := range append(append([]string(nil), plan.Excluded...), plan.SchemaOnly...) {
+		skip[table] = true
+	}
+	where := make(map[string]string, len(plan.Filtered))
+	for _, f := range plan.Filtered {
+		where[f.Table] = f.Where
+	}
 	counts := make(map[string]int64, len(tables))
 	for table := range tables {
 		if err := ctx.Err(); err != nil {
 			return models.BackupFingerprint{}, err
 		}
-		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s;", db.SQLIdent(table))
-		countResult, err := runner.RunQuery(ctx, profile, countQuery, connectDB)
-		if err != nil {
-			return models.BackupFingerprint{}, fmt.Errorf("count %s: %w", table, err)
+		if skip[table] {
+			counts[table] = 0
+			continue
 		}
-		n, err := parseSingleCount(countResult)
+		n, err := countRows(ctx, runner, profile, table, where[table], connectDB)
 		if err != nil {
-			return models.BackupFingerprint{}, fmt.Errorf("count %s: %w", table, err)
+			return models.BackupFingerprint{}, err
 		}
 		counts[table] = n
 	}
-	return buildFingerprint(mode, counts), nil
+	return buildFingerprint(mode, counts, plan), nil
+}
+
+// countRows counts the rows of table, only those matching where when it is set.
+func countRows(ctx context.Context, runner QueryRunner, profile models.Profile, table, where string, connectDB bool) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) FROM %s;", db.QuoteTable(profile, table))
+	if where != "" {
+		query = fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s;", db.QuoteTable(profile, table), where)
+	}
+	result, err := runner.RunQuery(ctx, profile, query, connectDB)
+	if err != nil {
+		return 0, fmt.Errorf("count %s: %w", table, err)
+	}
+	n, err := parseSingleCount(result)
+	if err != nil {
+		return 0, fmt.Errorf("count %s: %w", table, err)
+	}
+	return n, nil
+}
+
+// FingerprintFromManifest builds an exact fingerprint from a split archive's manifest, which
+// already holds the row count of every dumped table, without querying the source.
+func FingerprintFromManifest(m sqldump.Manifest) models.BackupFingerprint {
+	plan := db.TablePlan{
+		Excluded:   m.ExcludedTables,
+		SchemaOnly: m.SchemaOnlyTables,
+	}
+	for _, name := range m.FilteredTables {
+		plan.Filtered = append(plan.Filtered, db.FilteredTable{Table: name})
+	}
+	fp := buildFingerprint(ModeExact, m.TableRows(), plan)
+	if !m.CreatedAt.IsZero() {
+		fp.CapturedAt = m.CreatedAt
+	}
+	return fp
+}
+
+// tablePlan applies the profile's table filter to the fingerprinted tables.
+func tablePlan(profile models.Profile, counts map[string]int64) db.TablePlan {
+	if !profile.Tables.Active() {
+		return db.TablePlan{}
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	return db.PlanTables(*profile.Tables, names)
 }
 
 // CountTablesExact returns exact row counts for the given tables in databaseName.
@@ -133,7 +203,7 @@ func CountTablesExact(ctx context.Context, runner QueryRunner, profile models.Pr
 		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
-		query := fmt.Sprintf("SELECT COUNT(*) FROM %s;", db.SQLIdent(table))
+		query := fmt.Sprintf("SELECT COUNT(*) FROM %s;", db.QuoteTable(p, table))
 		result, err := runner.RunQuery(ctx, p, query, true)
 		if err != nil {
 			return nil, fmt.Errorf("count %s: %w", table, err)
@@ -147,18 +217,37 @@ func CountTablesExact(ctx context.Context, runner QueryRunner, profile models.Pr
 	return counts, nil
 }
 
-func buildFingerprint(mode string, counts map[string]int64) models.BackupFingerprint {
+// buildFingerprint records row counts; excluded tables are left out and schema-only tables
+// are expected to restore empty.
+func buildFingerprint(mode string, counts map[string]int64, plan db.TablePlan) models.BackupFingerprint {
+	excluded := make(map[string]bool, len(plan.Excluded))
+	for _, name := range plan.Excluded {
+		excluded[name] = true
+	}
+	schemaOnly := make(map[string]bool, len(plan.SchemaOnly))
+	for _, name := range plan.SchemaOnly {
+		schemaOnly[name] = true
+	}
 	tables := make(map[string]models.FingerprintTable, len(counts))
 	var total int64
 	for name, rows := range counts {
+		if excluded[name] {
+			continue
+		}
+		if schemaOnly[name] {
+			rows = 0
+		}
 		tables[name] = models.FingerprintTable{Rows: rows}
 		total += rows
 	}
 	return models.BackupFingerprint{
-		CapturedAt: time.Now().UTC(),
-		Mode:       mode,
-		Tables:     tables,
-		TotalRows:  total,
+		CapturedAt:       time.Now().UTC(),
+		Mode:             mode,
+		Tables:           tables,
+		TotalRows:        total,
+		ExcludedTables:   plan.Excluded,
+		SchemaOnlyTables: plan.SchemaOnly,
+		FilteredTables:   plan.FilteredNames(),
 	}
 }
 
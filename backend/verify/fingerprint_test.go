@@ -1,10 +1,13 @@
 package verify
 
 import (
+	"context"
 	"strings"
 	"testing"
 
 	"dback/backend/db"
+	"dback/backend/sqldump"
+	"dback/models"
 )
 
 func TestParseTableRowsResult(t *testing.T) {
@@ -47,3 +50,107 @@ func TestBuildFastTableRowsQuery(t *testing.T) {
 		t.Fatalf("unexpected wordpress query: %q", wp)
 	}
 }
+
+func TestBuildFingerprintAppliesTablePlan(t *testing.T) {
+	counts := map[string]int64{"orders": 10, "log_2024": 5000, "sessions": 70}
+	fp := buildFingerprint(ModeFast, counts, db.TablePlan{Excluded: []string{"log_2024"}, SchemaOnly: []string{"sessions"}})
+	if _, ok := fp.Tables["log_2024"]; ok {
+		t.Fatal("excluded table must not be fingerprinted")
+	}
+	if fp.Tables["sessions"].Rows != 0 || fp.Tables["orders"].Rows != 10 || fp.TotalRows != 10 {
+		t.Fatalf("unexpected fingerprint: %+v", fp)
+	}
+	if len(fp.ExcludedTables) != 1 || len(fp.SchemaOnlyTables) != 1 {
+		t.Fatalf("fingerprint must record the table plan: %+v", fp)
+	}
+}
+
+func TestFingerprintFromManifest(t *testing.T) {
+	m := sqldump.Manifest{
+		SchemaOnlyTables: []string{"sessions"},
+		ExcludedTables:   []string{"log_2024"},
+		Entries: []sqldump.Entry{
+			{Kind: sqldump.KindHeader},
+			{Kind: sqldump.KindTable, Name: "orders", Rows: 10},
+			{Kind: sqldump.KindTrigger, Name: "orders"},
+			{Kind: sqldump.KindTable, Name: "sessions"},
+			{Kind: sqldump.KindView, Name: "active_orders"},
+		},
+	}
+	fp := FingerprintFromManifest(m)
+	if fp.Mode != ModeExact || len(fp.Tables) != 2 || fp.Tables["orders"].Rows != 10 || fp.TotalRows != 10 {
+		t.Fatalf("unexpected fingerprint: %+v", fp)
+	}
+	if len(fp.ExcludedTables) != 1 || len(fp.SchemaOnlyTables) != 1 {
+		t.Fatalf("fingerprint must record the manifest's table plan: %+v", fp)
+	}
+}
+
+// queryLog answers the fingerprint queries: table estimates, and 7 for any COUNT(*).
+type queryLog struct{ queries []string }
+
+func (q *queryLog) RunQuery(_ context.Context, _ models.Profile, query string, _ bool) (db.QueryResult, error) {
+	q.queries = append(q.queries, query)
+	if strings.Contains(query, "information_schema") {
+		return db.QueryResult{Columns: []string{"table_name", "table_rows"}, Rows: [][]string{{"orders", "9000"}, {"users", "40"}}}, nil
+	}
+	return db.QueryResult{Columns: []string{"COUNT(*)"}, Rows: [][]string{{"7"}}}, nil
+}
+
+func TestCaptureFingerprintCountsFilteredRows(t *testing.T) {
+	runner := &queryLog{}
+	profile := models.Profile{Tables: &models.TableFilter{Rows: []models.RowFilter{{Table: "orders", Column: "created_at", Days: 30}}}}
+	fp, err := CaptureFingerprint(context.Background(), runner, profile, "shop", ModeFast)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp.Tables["orders"].Rows != 7 || fp.Tables["users"].Rows != 40 {
+		t.Fatalf("tables = %+v", fp.Tables)
+	}
+	if len(fp.FilteredTables) != 1 || fp.FilteredTables[0] != "orders" {
+		t.Fatalf("filtered = %v", fp.FilteredTables)
+	}
+	if last := runner.queries[len(runner.queries)-1]; !strings.Contains(last, "WHERE `created_at` >= NOW() - INTERVAL 30 DAY") {
+		t.Fatalf("count query = %s", last)
+	}
+}
+
+// pgQueryLog answers like psql connected to the fingerprinted database.
+type pgQueryLog struct {
+	queries   []string
+	databases []string
+}
+
+func (q *pgQueryLog) RunQuery(_ context.Context, p models.Profile, query string, connectDB bool) (db.QueryResult, error) {
+	if connectDB {
+		q.databases = append(q.databases, p.TargetDBName)
+	}
+	q.queries = append(q.queries, query)
+	if strings.Contains(query, "pg_stat_user_tables") {
+		return db.QueryResult{Columns: []string{"table_name", "table_rows"}, Rows: [][]string{{"orders", "9000"}, {"sales.refunds", "12"}}}, nil
+	}
+	return db.QueryResult{Columns: []string{"count"}, Rows: [][]string{{"11"}}}, nil
+}
+
+func TestCaptureFingerprintPostgreSQL(t *testing.T) {
+	runner := &pgQueryLog{}
+	profile := models.Profile{DBType: models.DBTypePostgreSQL, TargetDBName: "postgres"}
+	fp, err := CaptureFingerprint(context.Background(), runner, profile, "shop", ModeExact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fp.Tables) != 2 || fp.Tables["sales.refunds"].Rows != 11 {
+		t.Fatalf("tables = %+v", fp.Tables)
+	}
+	for _, name := range runner.databases {
+		if name != "shop" {
+			t.Fatalf("queries must connect to the fingerprinted database, got %v", runner.databases)
+		}
+	}
+	if len(runner.databases) != len(runner.queries) {
+		t.Fatalf("every query must connect to the database: %v", runner.databases)
+	}
+	if !strings.Contains(strings.Join(runner.queries, "\n"), `SELECT COUNT(*) FROM "sales"."refunds";`) {
+		t.Fatalf("queries = %v", runner.queries)
+	}
+}
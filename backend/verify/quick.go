@@ -3,6 +3,11 @@ package verify
 import (
 	"fmt"
 	"os"
+
+	"dback/backend/chunkstore"
+	"dback/backend/codec"
+	"dback/backend/crypt"
+	"dback/models"
 )
 
 // QuickCheckResult holds the outcome of a SHA256 integrity check.
@@ -10,9 +15,14 @@ type QuickCheckResult struct {
 	Passed   bool
 	Actual   string
 	Expected string
+	// Compression and Problem are set by CodecCheck: the file's codec and why it did not
+	// decrypt or decompress. QuickCheck sets Problem when a repository chunk is damaged.
+	Compression models.Compression
+	Problem     string
 }
 
-// QuickCheck recalculates SHA256 of filePath and compares it to expectedSHA.
+// QuickCheck recalculates SHA256 of filePath and compares it to expectedSHA. For a repository
+// backup filePath is its manifest, and every chunk it lists is read and checked as well.
 func QuickCheck(filePath, expectedSHA string) (QuickCheckResult, error) {
 	if expectedSHA == "" {
 		return QuickCheckResult{}, fmt.Errorf("no stored checksum for this backup; create a new backup to enable verify")
@@ -27,9 +37,70 @@ func QuickCheck(filePath, expectedSHA string) (QuickCheckResult, error) {
 	if err != nil {
 		return QuickCheckResult{}, err
 	}
-	return QuickCheckResult{
+	result := QuickCheckResult{
 		Passed:   actual == expectedSHA,
 		Actual:   actual,
 		Expected: expectedSHA,
-	}, nil
+	}
+	if result.Passed && chunkstore.IsManifestPath(filePath) {
+		if err := chunkstore.Check(filePath); err != nil {
+			result.Passed, result.Problem = false, err.Error()
+		}
+	}
+	return result, nil
+}
+
+// CodecCheck decompresses filePath completely in whatever codec it has. It checks backups
+// that have no stored checksum, such as files copied in from elsewhere; Actual is the
+// file's SHA256 so a passing file can be checked by checksum from then on. An encrypted
+// file is decrypted with keys first.
+func CodecCheck(filePath string, keys crypt.Keys) (QuickCheckResult, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return QuickCheckResult{}, fmt.Errorf("backup file not found locally; download it from S3 sync first")
+		}
+		return QuickCheckResult{}, err
+	}
+	c, err := codec.Detect(f)
+	f.Close()
+	if err != nil {
+		return QuickCheckResult{}, err
+	}
+	if chunkstore.IsManifestPath(filePath) {
+		actual, err := ChecksumFile(filePath)
+		if err != nil {
+			return QuickCheckResult{}, err
+		}
+		result := QuickCheckResult{Passed: true, Actual: actual, Compression: models.CompressionZstd}
+		if err := chunkstore.Check(filePath); err != nil {
+			result.Passed, result.Problem = false, err.Error()
+		}
+		return result, nil
+	}
+	encrypted, err := crypt.IsEncryptedFile(filePath)
+	if err != nil {
+		return QuickCheckResult{}, err
+	}
+	actual, err := ChecksumFile(filePath)
+	if err != nil {
+		return QuickCheckResult{}, err
+	}
+	result := QuickCheckResult{Passed: true, Actual: actual, Compression: c}
+	if encrypted {
+		// A missing or wrong key says nothing about the file, so it is an error, not a failure.
+		if err := crypt.CheckKey(filePath, keys); err != nil {
+			return QuickCheckResult{}, err
+		}
+		inner, err := crypt.Check(filePath, keys)
+		result.Compression = inner
+		if err != nil {
+			result.Passed, result.Problem = false, err.Error()
+		}
+		return result, nil
+	}
+	if err := codec.Check(filePath); err != nil {
+		result.Passed, result.Problem = false, err.Error()
+	}
+	return result, nil
 }
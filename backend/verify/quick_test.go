@@ -3,7 +3,11 @@ package verify
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"dback/backend/chunkstore"
+	"dback/backend/crypt"
 )
 
 func TestQuickCheck_Match(t *testing.T) {
@@ -51,3 +55,50 @@ func TestQuickCheck_MissingChecksum(t *testing.T) {
 		t.Fatal("expected error for missing checksum")
 	}
 }
+
+func TestCodecCheck(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "imported.sql.bz2")
+	// "BZh9" then an empty stream trailer: a valid bzip2 file with no data.
+	empty := []byte{0x42, 0x5a, 0x68, 0x39, 0x17, 0x72, 0x45, 0x38, 0x50, 0x90, 0x00, 0x00, 0x00, 0x00}
+	if err := os.WriteFile(path, empty, 0600); err != nil {
+		t.Fatal(err)
+	}
+	result, err := CodecCheck(path, crypt.Keys{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Passed || result.Compression != "bzip2" || result.Actual == "" {
+		t.Fatalf("expected a passing bzip2 check, got %#v", result)
+	}
+	if err := os.WriteFile(path, empty[:8], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := CodecCheck(path, crypt.Keys{}); err != nil || result.Passed || result.Problem == "" {
+		t.Fatalf("expected a truncated bzip2 file to fail, got %#v, %v", result, err)
+	}
+}
+
+func TestQuickCheck_RepositoryChunks(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "host", "backup.chunks.json")
+	m, _, err := chunkstore.Store(strings.NewReader("CREATE TABLE t (id int);\n"), manifest, chunkstore.RepositoryDir(dir), chunkstore.Manifest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := ChecksumFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := QuickCheck(manifest, sum); err != nil || !result.Passed {
+		t.Fatalf("expected pass, got %#v, %v", result, err)
+	}
+	id := m.Chunks[0].ID
+	if err := os.Remove(filepath.Join(chunkstore.RepositoryDir(dir), "chunks", id[:2], id)); err != nil {
+		t.Fatal(err)
+	}
+	result, err := QuickCheck(manifest, sum)
+	if err != nil || result.Passed || !strings.Contains(result.Problem, "missing") {
+		t.Fatalf("expected a missing chunk to fail, got %#v, %v", result, err)
+	}
+}
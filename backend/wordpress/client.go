@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strings"
 
 	"dback/backend/db"
@@ -96,10 +97,30 @@ func (c *Client) Preflight(ctx context.Context) (PreflightResult, error) {
 }
 
 func (c *Client) Export(ctx context.Context) (io.ReadCloser, error) {
+	return c.ExportTables(ctx, db.TablePlan{})
+}
+
+// ExportTables streams a dump that leaves out plan.Excluded, dumps plan.SchemaOnly
+// without rows and plan.Filtered with only the rows matching their conditions. Plugins
+// older than 1.2.0 ignore the plan and dump everything; row conditions need 1.3.0.
+func (c *Client) ExportTables(ctx context.Context, plan db.TablePlan) (io.ReadCloser, error) {
 	req, err := c.newRequest(ctx, http.MethodGet, "/export", nil)
 	if err != nil {
 		return nil, err
 	}
+	if !plan.Empty() {
+		q := url.Values{}
+		for _, table := range plan.Excluded {
+			q.Add("exclude_tables[]", table)
+		}
+		for _, table := range plan.SchemaOnly {
+			q.Add("no_data_tables[]", table)
+		}
+		for _, f := range plan.Filtered {
+			q.Set("table_where["+f.Table+"]", f.Where)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
 	req.Header.Set("Accept", "application/gzip")
 	resp, err := c.http.Do(req)
 	if err != nil {
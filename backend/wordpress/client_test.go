@@ -8,9 +8,11 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
+	"dback/backend/db"
 	"dback/models"
 )
 
@@ -115,6 +117,67 @@ func TestClientExport(t *testing.T) {
 	}
 }
 
+func TestClientExportTablesSendsPlan(t *testing.T) {
+	t.Parallel()
+
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Header().Set("Content-Type", "application/gzip")
+		_, _ = w.Write([]byte{0x1f, 0x8b})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(models.Profile{
+		ConnectionType: models.ConnectionTypeWordPress,
+		WPUrl:          server.URL,
+		WPKey:          "secret-key",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	body, err := client.ExportTables(context.Background(), db.TablePlan{
+		Excluded:   []string{"wp_logs", "wp_cache"},
+		SchemaOnly: []string{"wp_sessions"},
+		Filtered:   []db.FilteredTable{{Table: "wp_posts", Where: "post_date >= '2024-01-01'"}},
+	})
+	if err != nil {
+		t.Fatalf("ExportTables: %v", err)
+	}
+	body.Close()
+	if got := query["exclude_tables[]"]; len(got) != 2 || got[0] != "wp_logs" || got[1] != "wp_cache" {
+		t.Fatalf("unexpected exclude_tables: %v", got)
+	}
+	if got := query["no_data_tables[]"]; len(got) != 1 || got[0] != "wp_sessions" {
+		t.Fatalf("unexpected no_data_tables: %v", got)
+	}
+	if got := query.Get("table_where[wp_posts]"); got != "post_date >= '2024-01-01'" {
+		t.Fatalf("unexpected table_where: %q", got)
+	}
+}
+
+func TestPreflightPluginAtLeast(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		version string
+		want    bool
+	}{
+		{"1.3.0", true},
+		{"1.10.0", true},
+		{"2.0", true},
+		{"1.2.9", false},
+		{"1.3", true},
+		{"", false},
+	}
+	for _, c := range cases {
+		r := parsePreflightResult(map[string]interface{}{"plugin_version": c.version})
+		if got := r.PluginAtLeast("1.3.0"); got != c.want {
+			t.Errorf("PluginAtLeast(1.3.0) with %q = %v, want %v", c.version, got, c.want)
+		}
+	}
+}
+
 func TestClientQueryWithDatabaseHeader(t *testing.T) {
 	t.Parallel()
 
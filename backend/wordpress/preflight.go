@@ -2,6 +2,7 @@ package wordpress
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -12,6 +13,8 @@ type PreflightResult struct {
 	Raw       map[string]interface{}
 	DBVersion string
 	Driver    string
+	// PluginVersion is the version of the DBack DB Tools plugin answering the request.
+	PluginVersion string
 }
 
 type PreflightCheck struct {
@@ -31,6 +34,9 @@ func parsePreflightResult(data map[string]interface{}) PreflightResult {
 	if v, ok := data["driver"].(string); ok {
 		result.Driver = v
 	}
+	if v, ok := data["plugin_version"].(string); ok {
+		result.PluginVersion = v
+	}
 	if checks, ok := data["checks"].([]interface{}); ok {
 		for _, item := range checks {
 			m, ok := item.(map[string]interface{})
@@ -73,6 +79,27 @@ func preflightSummary(r PreflightResult) string {
 	return b.String()
 }
 
+// PluginAtLeast reports whether the plugin is version min or newer. An unknown version
+// counts as older.
+func (r PreflightResult) PluginAtLeast(min string) bool {
+	have := strings.Split(strings.TrimSpace(r.PluginVersion), ".")
+	want := strings.Split(min, ".")
+	for i, w := range want {
+		wn, _ := strconv.Atoi(w)
+		if i >= len(have) {
+			return wn == 0
+		}
+		hn, err := strconv.Atoi(have[i])
+		if err != nil {
+			return false
+		}
+		if hn != wn {
+			return hn > wn
+		}
+	}
+	return true
+}
+
 func (r PreflightResult) FailureError() error {
 	if r.Success {
 		return nil
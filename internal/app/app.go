@@ -7,15 +7,23 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"dback/backend/codec"
 	"dback/backend/db"
+	"dback/backend/definer"
+	"dback/backend/mask"
+	"dback/backend/searchreplace"
 	"dback/backend/ssh"
+	"dback/backend/throttle"
 	"dback/backend/transfer"
+	"dback/backend/verify"
 	"dback/backend/wordpress"
 	"dback/internal/debug"
+	"dback/internal/notify"
 	"dback/internal/paths"
 	"dback/internal/store"
 	"dback/models"
@@ -31,13 +39,26 @@ type App struct {
 	templates []models.SQLTemplate
 	history   []models.ExportRecord
 	logs      []models.LogEntry
+	jobs      []models.JobRecord
+	// owner tells this App's jobs from those of other processes sharing the vault.
+	owner string
+
+	// opTags holds trigger/parent tags of in-flight operations, copied onto their log entries.
+	opTags        map[string]operationTags
+	stopScheduler context.CancelFunc
+	notifyWG      sync.WaitGroup // in-flight notification deliveries
+
+	// Token buckets shared by every transfer, capped by the app-wide bandwidth limit.
+	bandwidthOnce  sync.Once
+	uploadBucket   *throttle.Bucket
+	downloadBucket *throttle.Bucket
 }
 
 func New(baseDir string) (*App, error) {
 	knownHostsPath := filepath.Join(baseDir, "ssh_known_hosts")
 	log.Printf("app.New: baseDir=%q knownHostsFile=%q", baseDir, knownHostsPath)
 	ssh.SetKnownHostsFile(knownHostsPath)
-	return &App{store: store.New(baseDir)}, nil
+	return &App{store: store.New(baseDir), owner: newID()}, nil
 }
 
 func (a *App) HasVault() bool {
@@ -63,7 +84,10 @@ func (a *App) CreateVault(passphrase string) error {
 		return err
 	}
 	log.Printf("app.CreateVault: vault created, reloading")
-	return a.Reload()
+	if err := a.Reload(); err != nil {
+		return err
+	}
+	return a.markInterruptedJobs()
 }
 
 func (a *App) Unlock(passphrase string) error {
@@ -73,15 +97,21 @@ func (a *App) Unlock(passphrase string) error {
 		return err
 	}
 	log.Printf("app.Unlock: vault unlocked, reloading")
-	return a.Reload()
+	if err := a.Reload(); err != nil {
+		return err
+	}
+	return a.markInterruptedJobs()
 }
 
 func (a *App) Lock() {
+	a.StopScheduler()
+	a.WaitNotifications()
 	a.mu.Lock()
 	a.profiles = nil
 	a.templates = nil
 	a.history = nil
 	a.logs = nil
+	a.jobs = nil
 	a.mu.Unlock()
 	a.store.Lock()
 }
@@ -119,11 +149,17 @@ func (a *App) Reload() error {
 		log.Printf("app.Reload: LoadLogs failed: %v", err)
 		return err
 	}
+	jobs, err := a.store.LoadJobs()
+	if err != nil {
+		log.Printf("app.Reload: LoadJobs failed: %v", err)
+		return err
+	}
 	a.mu.Lock()
 	a.profiles = profiles
 	a.templates = templates
 	a.history = history
 	a.logs = logs
+	a.jobs = jobs
 	a.mu.Unlock()
 
 	if migratedProfiles {
@@ -171,6 +207,86 @@ func (a *App) SaveProfile(profile models.Profile) error {
 			return err
 		}
 	}
+	if profile.Schedule != nil {
+		if err := ValidateSchedule(*profile.Schedule); err != nil {
+			return err
+		}
+	}
+	if profile.Bandwidth != nil {
+		if err := ValidateBandwidth(*profile.Bandwidth); err != nil {
+			return err
+		}
+		if !profile.Bandwidth.Active() {
+			profile.Bandwidth = nil
+		}
+	}
+	if profile.Retention != nil {
+		if err := ValidateRetention(*profile.Retention); err != nil {
+			return err
+		}
+		if !profile.Retention.Active() {
+			profile.Retention = nil
+		}
+	}
+	if profile.Databases.Active() {
+		if profile.UsesWordPress() {
+			return fmt.Errorf("WordPress hosts back up their own database only")
+		}
+		if err := db.ValidateDatabaseSelection(profile.Databases); err != nil {
+			return err
+		}
+	} else {
+		profile.Databases = nil
+	}
+	if profile.Tables.Active() {
+		if err := db.ValidateTableFilter(profile.Tables); err != nil {
+			return err
+		}
+	} else {
+		profile.Tables = nil
+	}
+	if profile.Binlog.Active() {
+		if err := ValidateBinlogSettings(profile); err != nil {
+			return err
+		}
+	} else {
+		profile.Binlog = nil
+	}
+	if err := ValidatePhysicalBackup(profile); err != nil {
+		return err
+	}
+	if err := ValidatePostgreSQL(profile); err != nil {
+		return err
+	}
+	if profile.Encryption.Active() {
+		if err := ValidateEncryption(profile); err != nil {
+			return err
+		}
+	} else {
+		profile.Encryption = nil
+	}
+	if err := mask.Validate(profile.Masking); err != nil {
+		return err
+	}
+	if len(profile.Masking) == 0 {
+		profile.Masking = nil
+	}
+	if err := codec.Validate(profile.Compression, profile.CompressionLevel); err != nil {
+		return err
+	}
+	if profile.UsesWordPress() && codec.Normalize(profile.Compression) != models.CompressionGzip {
+		return fmt.Errorf("WordPress hosts export gzip dumps only")
+	}
+	if codec.Normalize(profile.Compression) == models.CompressionGzip {
+		profile.Compression = ""
+	}
+	switch profile.DumpFormat {
+	case "", models.DumpFormatSingle:
+		profile.DumpFormat = ""
+	case models.DumpFormatSplit, models.DumpFormatRepository:
+	default:
+		return fmt.Errorf("unknown dump format %q", profile.DumpFormat)
+	}
 	profile.ExportSettings = nil
 	profile.ImportSettings = nil
 
@@ -310,8 +426,53 @@ func (a *App) ImportAppData(path string, includeSecrets bool, passphrase string)
 }
 
 func (a *App) Backup(ctx context.Context, profile models.Profile, progress ProgressFunc) (models.ExportRecord, error) {
-	operationID := newID()
+	return a.BackupWithTrigger(ctx, profile, models.TriggerManual, progress)
+}
+
+// BackupWithTrigger runs a backup and tags its history record and log entries with trigger.
+func (a *App) BackupWithTrigger(ctx context.Context, profile models.Profile, trigger string, progress ProgressFunc) (models.ExportRecord, error) {
+	return a.backup(ctx, profile, runOptions{tags: operationTags{trigger: trigger}}, progress)
+}
+
+// runOptions describe how a backup or restore was started.
+type runOptions struct {
+	tags        operationTags
+	jobID       string                 // persisted job to reuse on resume/retry
+	operationID string                 // original operation ID when resuming
+	resumePath  string                 // partial backup file left by an interrupted download
+	resume      bool                   // continue an interrupted restore upload
+	recordID    string                 // restored backup record
+	tables      *models.TableSelection // selective table restore
+	pointInTime *time.Time             // replay binlog incrementals up to this time
+	replace     []models.ReplacePair   // search/replace the restored rows
+	definer     models.DefinerMode     // what happens to DEFINER clauses
+	targetDB    string                 // restore into this new database
+	parallel    int                    // import tables over this many sessions
+}
+
+// backup runs one backup as a persisted job.
+func (a *App) backup(ctx context.Context, profile models.Profile, opts runOptions, progress ProgressFunc) (models.ExportRecord, error) {
+	operationID := opts.operationID
+	if operationID == "" {
+		operationID = newID()
+	}
 	started := time.Now()
+	jobID := a.startJob(opts, operationID, models.JobKindBackup, profile)
+	record, err := a.runBackup(ctx, profile, operationID, opts, progress)
+	a.finishJob(jobID, jobStatusFor(err), err)
+	var notified *models.ExportRecord
+	if err == nil {
+		notified = &record
+	}
+	a.notify(notify.EventBackup, operationID, profile, opts.tags.trigger, started, notified, err)
+	return record, err
+}
+
+func (a *App) runBackup(ctx context.Context, profile models.Profile, operationID string, opts runOptions, progress ProgressFunc) (models.ExportRecord, error) {
+	tags := opts.tags
+	started := time.Now()
+	a.setOperationTags(operationID, tags)
+	defer a.setOperationTags(operationID, operationTags{})
 	dest := paths.EffectiveBackupDestination(profile.Destination)
 	if err := os.MkdirAll(dest, 0755); err != nil {
 		return models.ExportRecord{}, err
@@ -319,33 +480,41 @@ func (a *App) Backup(ctx context.Context, profile models.Profile, progress Progr
 
 	logger := a.newOpLogger(operationID, &profile)
 	a.logPhase(operationID, &profile, "Export", "start", "", 0, "Starting backup", "Info", "Started", "")
+	a.logBandwidth(operationID, profile, "Export", throttle.Download)
 
-	var fullPath string
-	var size int64
-	var err error
-
+	keys, err := a.encryptionKeys(profile)
+	if err != nil {
+		a.logPhase(operationID, &profile, "Export", "failure", "", 0, "Backup failed", "Error", "Failed", err.Error())
+		return models.ExportRecord{}, err
+	}
 	var result transfer.BackupResult
-	var backupErr error
 	if profile.UsesWordPress() {
-		result, backupErr = transfer.BackupWordPress(ctx, transfer.BackupRequest{
+		result, err = transfer.BackupWordPress(ctx, transfer.BackupRequest{
 			Profile:     profile,
 			OperationID: operationID,
 			Destination: dest,
 			Logger:      logger,
 			Progress:    progress,
+			Bandwidth:   a.transferLimits(profile),
 		})
 	} else {
-		result, backupErr = transfer.BackupSSH(ctx, transfer.BackupRequest{
+		result, err = transfer.BackupSSH(ctx, transfer.BackupRequest{
 			Profile:     profile,
 			OperationID: operationID,
 			Destination: dest,
 			Logger:      logger,
 			Progress:    progress,
+			ResumePath:  opts.resumePath,
+			Keys:        keys,
+			Bandwidth:   a.transferLimits(profile),
 		})
 	}
-	fullPath, size, err = result.Path, result.Size, backupErr
+	files := result.Files
+	if len(files) == 0 && result.Path != "" {
+		files = []transfer.BackupFile{{Database: profile.TargetDBName, Path: result.Path, Size: result.Size}}
+	}
 
-	if err != nil {
+	if err != nil && len(files) == 0 {
 		if errors.Is(err, context.Canceled) {
 			a.logPhase(operationID, &profile, "Export", "cancel", "", 0, "Backup canceled", "Info", "Canceled", "")
 			return models.ExportRecord{}, err
@@ -354,24 +523,80 @@ func (a *App) Backup(ctx context.Context, profile models.Profile, progress Progr
 		return models.ExportRecord{}, err
 	}
 
+	// A multi-database backup that failed part-way still records the databases it got.
+	dumpErr := err
+	var records []models.ExportRecord
+	var errs []error
+	for _, file := range files {
+		record, err := a.recordBackup(ctx, profile, operationID, tags, file, progress)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		records = append(records, record)
+	}
+	failed := len(errs)
+	if dumpErr != nil {
+		errs = append(errs, dumpErr)
+		if joined, ok := dumpErr.(interface{ Unwrap() []error }); ok {
+			failed += len(joined.Unwrap())
+		} else {
+			failed++
+		}
+	}
+	if len(records) == 0 {
+		return models.ExportRecord{}, errors.Join(errs...)
+	}
+
+	last := records[len(records)-1]
+	if profile.Databases.Active() {
+		a.logPhase(operationID, &profile, "Export", "databases", "", 0, fmt.Sprintf("Backed up %d of %d database(s)", len(records), len(records)+failed), "Info", "Succeeded", "")
+	}
+	if len(errs) > 0 {
+		err := errors.Join(errs...)
+		a.logPhase(operationID, &profile, "Export", "failure", "", 0, "Backup failed for some databases", "Error", "Failed", err.Error())
+		a.enforceRetention(ctx, operationID, profile)
+		return records[0], err
+	}
+	a.logPhaseWithFile(operationID, profile, "Export", "complete", "", 0, fmt.Sprintf("Backup completed in %s", time.Since(started).Round(time.Millisecond)), "Info", "Succeeded", "", last.FilePath, last.FileSizeBytes)
+	a.enforceRetention(ctx, operationID, profile)
+	if progress != nil {
+		progress("Backup completed", last.FileSizeBytes, last.FileSizeBytes)
+	}
+	return records[0], nil
+}
+
+// recordBackup validates one downloaded dump and adds it to the history with its fingerprint.
+func (a *App) recordBackup(ctx context.Context, profile models.Profile, operationID string, tags operationTags, file transfer.BackupFile, progress ProgressFunc) (models.ExportRecord, error) {
+	size := file.Size
 	if size < 128 {
-		_ = os.Remove(fullPath)
-		err = fmt.Errorf("backup file too small (%d bytes)", size)
-		a.logPhase(operationID, &profile, "Export", "validation", "", 0, err.Error(), "Error", "Failed", err.Error())
+		_ = os.Remove(file.Path)
+		err := fmt.Errorf("backup file too small (%d bytes)", size)
+		a.logPhaseWithFile(operationID, profile, "Export", "validation", "", 0, err.Error(), "Error", "Failed", err.Error(), file.Path, size)
 		return models.ExportRecord{}, err
 	}
 
 	record := models.ExportRecord{
-		ID:             newID(),
-		OperationID:    operationID,
-		ProfileID:      profile.ID,
-		ProfileName:    profile.Name,
-		DatabaseName:   profile.TargetDBName,
-		ExportDate:     time.Now(),
-		FilePath:       fullPath,
-		FileSize:       formatSize(size),
-		FileSizeBytes:  size,
-		ConnectionType: profile.ConnectionType,
+		ID:                newID(),
+		OperationID:       operationID,
+		ProfileID:         profile.ID,
+		ProfileName:       profile.Name,
+		DatabaseName:      file.Database,
+		ExportDate:        time.Now(),
+		FilePath:          file.Path,
+		FileSize:          formatSize(size),
+		FileSizeBytes:     size,
+		ConnectionType:    profile.ConnectionType,
+		Trigger:           tags.trigger,
+		ParentOperationID: tags.parentID,
+		Binlog:            file.Binlog,
+		Type:              file.Type,
+		Compression:       file.Compression,
+		Encrypted:         file.Encrypted,
+		Partial:           file.Partial,
+		AddedBytes:        file.AddedBytes,
+		DBType:            profile.DBType,
+		ServerVersion:     file.ServerVersion,
 	}
 	if profile.UsesWordPress() && strings.TrimSpace(record.DatabaseName) == "" {
 		record.DatabaseName = "wordpress"
@@ -387,9 +612,18 @@ func (a *App) Backup(ctx context.Context, profile models.Profile, progress Progr
 	if progress != nil {
 		progress("Capturing fingerprint...", size, size)
 	}
-	sha256, fingerprint := a.captureBackupMetadata(ctx, profile, fullPath, profile.TargetDBName)
-	record.Sha256 = sha256
-	record.Fingerprint = fingerprint
+	if record.Physical() {
+		// Data files carry no table checksums to compare against; keep the checksum only.
+		if sum, err := verify.ChecksumFile(file.Path); err == nil {
+			record.Sha256 = sum
+		}
+	} else {
+		dbProfile := profile
+		dbProfile.TargetDBName = file.Database
+		sha256, fingerprint := a.captureBackupMetadata(ctx, dbProfile, file.Path, file.Database)
+		record.Sha256 = sha256
+		record.Fingerprint = fingerprint
+	}
 
 	if progress != nil {
 		progress("Verifying backup integrity...", size, size)
@@ -398,11 +632,6 @@ func (a *App) Backup(ctx context.Context, profile models.Profile, progress Progr
 	if err := a.UpdateHistoryRecord(record); err != nil {
 		return record, err
 	}
-
-	a.logPhaseWithFile(operationID, profile, "Export", "complete", "", 0, fmt.Sprintf("Backup completed in %s", time.Since(started).Round(time.Millisecond)), "Info", "Succeeded", "", fullPath, size)
-	if progress != nil {
-		progress("Backup completed", size, size)
-	}
 	return record, nil
 }
 
@@ -468,50 +697,207 @@ func (a *App) RunImportQuery(ctx context.Context, profile models.Profile, query
 			Message: err.Error(),
 		}, fmt.Errorf("%w: %s", err, output)
 	}
-	result := db.ParseMySQLBatchOutput(output)
+	result := db.ParseQueryOutput(profile, output)
 	result.Message = output
 	return result, nil
 }
 
 func (a *App) Restore(ctx context.Context, record models.ExportRecord, destination models.Profile, progress ProgressFunc) error {
+	return a.restore(ctx, record, destination, runOptions{}, progress)
+}
+
+// RestoreTables restores only the selected tables of a backup. The destination database is
+// created if missing but never dropped, so its other tables stay as they are. The
+// destination's pre-import query is skipped because it usually recreates the database.
+func (a *App) RestoreTables(ctx context.Context, record models.ExportRecord, destination models.Profile, sel models.TableSelection, progress ProgressFunc) error {
+	if !sel.Active() {
+		return fmt.Errorf("select at least one table to restore")
+	}
+	return a.RestoreWithOptions(ctx, record, destination, RestoreOptions{Tables: &sel}, progress)
+}
+
+// RestoreOptions change what a logical restore writes to the destination.
+type RestoreOptions struct {
+	// Tables restores only these tables (see RestoreTables).
+	Tables *models.TableSelection
+	// SearchReplace rewrites text in the restored rows, PHP-serialized values included;
+	// DefaultSearchReplace suggests the pairs for a WordPress site moving to a new URL.
+	SearchReplace []models.ReplacePair
+	// Definer strips or rewrites the DEFINER clauses of views, triggers, routines and
+	// events, for destinations without the user that created them.
+	Definer models.DefinerMode
+	// TargetDB restores into this new database on the destination, created empty first;
+	// the host's configured database is left untouched.
+	TargetDB string
+	// Parallel imports the backup's tables over this many sessions at once, up to
+	// transfer.MaxParallelRestore; 0 or 1 restores in one session.
+	Parallel int
+}
+
+// RestoreWithOptions restores a backup like Restore, with opts applied.
+func (a *App) RestoreWithOptions(ctx context.Context, record models.ExportRecord, destination models.Profile, opts RestoreOptions, progress ProgressFunc) error {
+	if err := searchreplace.Validate(opts.SearchReplace); err != nil {
+		return err
+	}
+	if _, err := definer.ParseMode(string(opts.Definer)); err != nil {
+		return err
+	}
+	if opts.Parallel < 0 || opts.Parallel > transfer.MaxParallelRestore {
+		return fmt.Errorf("parallel restore takes 0 to %d sessions (0 or 1 restores in one session)", transfer.MaxParallelRestore)
+	}
+	run := runOptions{replace: opts.SearchReplace, definer: opts.Definer, parallel: opts.Parallel}
+	if name := strings.TrimSpace(opts.TargetDB); name != "" {
+		if err := db.ValidateDatabaseName(name); err != nil {
+			return err
+		}
+		run.targetDB = name
+	}
+	if opts.Tables.Active() {
+		run.tables = opts.Tables
+	}
+	return a.restore(ctx, record, destination, run, progress)
+}
+
+// BackupTables lists the tables of a backup for the selective restore picker: from the
+// backup's fingerprint when it has one, otherwise by scanning the file.
+func (a *App) BackupTables(record models.ExportRecord) ([]string, error) {
+	if record.Physical() {
+		return nil, fmt.Errorf("a physical backup restores the whole server; tables cannot be picked")
+	}
+	if fp := record.Fingerprint; fp != nil && len(fp.Tables) > 0 {
+		tables := make([]string, 0, len(fp.Tables))
+		for name := range fp.Tables {
+			tables = append(tables, name)
+		}
+		sort.Strings(tables)
+		return tables, nil
+	}
+	return transfer.ListBackupTables(record.FilePath, a.decryptionKeys(record))
+}
+
+// restore runs one restore as a persisted job.
+func (a *App) restore(ctx context.Context, record models.ExportRecord, destination models.Profile, opts runOptions, progress ProgressFunc) error {
 	if !destination.AllowsImport() {
 		return fmt.Errorf("host %q is protected from import", destination.Name)
 	}
-	operationID := newID()
+	if record.Incremental() {
+		return fmt.Errorf("an incremental backup is restored through point-in-time restore of its full backup")
+	}
+	operationID := opts.operationID
+	if operationID == "" {
+		operationID = newID()
+	}
+	opts.recordID = record.ID
+	started := time.Now()
+	jobID := a.startJob(opts, operationID, models.JobKindRestore, destination)
+	err := a.runRestore(ctx, record, destination, operationID, opts, progress)
+	a.finishJob(jobID, jobStatusFor(err), err)
+	a.notify(notify.EventRestore, operationID, destination, "", started, &record, err)
+	return err
+}
+
+func (a *App) runRestore(ctx context.Context, record models.ExportRecord, destination models.Profile, operationID string, opts runOptions, progress ProgressFunc) error {
 	started := time.Now()
 	if info, err := os.Stat(record.FilePath); err != nil {
 		return err
 	} else if info.Size() < 128 {
 		return fmt.Errorf("backup file too small (%d bytes)", info.Size())
 	}
+	var binlogSteps []models.ExportRecord
+	if opts.pointInTime != nil {
+		if opts.tables.Active() {
+			return fmt.Errorf("point-in-time restore always restores the whole database")
+		}
+		if len(opts.replace) > 0 {
+			return fmt.Errorf("binary log events cannot be search/replaced; restore without a point in time")
+		}
+		if opts.definer != models.DefinerKeep {
+			return fmt.Errorf("DEFINER clauses cannot be rewritten in binary log events; restore without a point in time")
+		}
+		if opts.targetDB != "" {
+			return fmt.Errorf("binary log events replay into the original database; restore without a new name")
+		}
+		steps, err := a.planPointInTime(record, destination, *opts.pointInTime)
+		if err != nil {
+			return err
+		}
+		binlogSteps = steps
+	}
+	if record.Physical() {
+		if err := checkPhysicalRestore(destination, opts); err != nil {
+			return err
+		}
+	}
+	if err := checkPostgresRestore(record, destination, opts); err != nil {
+		return err
+	}
+	if err := checkMaskedRestore(record, destination, opts); err != nil {
+		return err
+	}
+	if opts.parallel > 1 && destination.UsesWordPress() {
+		return fmt.Errorf("parallel restore needs an SSH or Localhost host; the WordPress plugin imports one file")
+	}
 	logger := a.newOpLogger(operationID, &destination)
 	a.logPhaseWithFile(operationID, destination, "Import", "start", "", 0, "Starting restore", "Info", "Started", "", record.FilePath, record.FileSizeBytes)
+	a.logBandwidth(operationID, destination, "Import", throttle.Upload)
 
-	if err := a.runPreImportQueryPhase(ctx, operationID, destination, record.FileSizeBytes, progress); err != nil {
+	if opts.targetDB != "" {
+		if err := a.prepareRestoreAs(ctx, operationID, destination, opts); err != nil {
+			return err
+		}
+	} else if opts.tables.Active() {
+		a.logPhase(operationID, &destination, "Import", "tables", "", 0,
+			fmt.Sprintf("Restoring %d selected table(s); pre-import query skipped", len(opts.tables.Tables)), "Info", "Started", "")
+	} else if record.Physical() {
+		a.logPhase(operationID, &destination, "Import", "physical", "", 0,
+			"Replacing the server's data directory; pre-import query skipped", "Info", "Started", "")
+	} else if err := a.runPreImportQueryPhase(ctx, operationID, destination, record.FileSizeBytes, progress); err != nil {
 		return err
 	}
 
+	keys := a.decryptionKeys(record)
 	var err error
 	if destination.UsesWordPress() {
 		err = transfer.RestoreWordPress(ctx, transfer.RestoreRequest{
-			Profile:     destination,
-			OperationID: operationID,
-			LocalPath:   record.FilePath,
-			FileSize:    record.FileSizeBytes,
-			Logger:      logger,
-			Progress:    progress,
+			Profile:          destination,
+			OperationID:      operationID,
+			LocalPath:        record.FilePath,
+			FileSize:         record.FileSizeBytes,
+			Logger:           logger,
+			Progress:         progress,
+			Tables:           opts.tables,
+			Keys:             keys,
+			SearchReplace:    opts.replace,
+			Definer:          opts.definer,
+			SourceVersion:    record.ServerVersion,
+			TargetDBOverride: opts.targetDB,
+			Bandwidth:        a.transferLimits(destination),
 		})
 	} else {
 		err = transfer.RestoreSSH(ctx, transfer.RestoreRequest{
-			Profile:     destination,
-			OperationID: operationID,
-			LocalPath:   record.FilePath,
-			FileSize:    record.FileSizeBytes,
-			Logger:      logger,
-			Progress:    progress,
+			Profile:          destination,
+			OperationID:      operationID,
+			LocalPath:        record.FilePath,
+			FileSize:         record.FileSizeBytes,
+			Logger:           logger,
+			Progress:         progress,
+			Resume:           opts.resume,
+			Parallel:         opts.parallel,
+			Tables:           opts.tables,
+			Physical:         record.Physical(),
+			Keys:             keys,
+			SearchReplace:    opts.replace,
+			Definer:          opts.definer,
+			SourceVersion:    record.ServerVersion,
+			TargetDBOverride: opts.targetDB,
+			Bandwidth:        a.transferLimits(destination),
 		})
 	}
 
+	if err == nil && opts.pointInTime != nil {
+		err = a.replayBinlogs(ctx, operationID, destination, binlogSteps, *opts.pointInTime, progress)
+	}
+
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
 			a.logPhase(operationID, &destination, "Import", "cancel", "", 0, "Restore canceled", "Info", "Canceled", "")
@@ -526,6 +912,10 @@ func (a *App) Restore(ctx context.Context, record models.ExportRecord, destinati
 		progress("Restore completed", record.FileSizeBytes, record.FileSizeBytes)
 	}
 
+	a.recordRestore(record, destination, operationID, opts)
+	if opts.targetDB != "" {
+		return nil
+	}
 	if err := a.runPostImportQueryPhase(ctx, operationID, destination); err != nil {
 		return err
 	}
@@ -582,6 +972,9 @@ func (a *App) logPhaseWithFile(operationID string, profile models.Profile, actio
 		entry.ProfileName = profile.Name
 	}
 	a.mu.Lock()
+	tags := a.opTags[operationID]
+	entry.Trigger = tags.trigger
+	entry.ParentOperationID = tags.parentID
 	a.logs = append(a.logs, entry)
 	logs := append([]models.LogEntry(nil), a.logs...)
 	a.mu.Unlock()
@@ -591,6 +984,29 @@ func (a *App) logPhaseWithFile(operationID string, profile models.Profile, actio
 	debug.Log(level, action+"."+phase, status, details, profileName, operationID, errStr)
 }
 
+// operationTags describe how an operation was started; the zero value means a manual, standalone run.
+type operationTags struct {
+	trigger  string
+	parentID string
+}
+
+// setOperationTags tags log entries of an operation; zero tags clear it.
+func (a *App) setOperationTags(operationID string, tags operationTags) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if tags.trigger == models.TriggerManual {
+		tags.trigger = ""
+	}
+	if tags == (operationTags{}) {
+		delete(a.opTags, operationID)
+		return
+	}
+	if a.opTags == nil {
+		a.opTags = map[string]operationTags{}
+	}
+	a.opTags[operationID] = tags
+}
+
 func profileValue(p *models.Profile) models.Profile {
 	if p == nil {
 		return models.Profile{}
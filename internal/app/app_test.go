@@ -1,10 +1,13 @@
 package app
 
 import (
+	"context"
 	"errors"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"dback/backend/transfer"
 	"dback/internal/store"
 	"dback/models"
 )
@@ -173,3 +176,31 @@ func TestPreviewImportProfilesDetectsConflicts(t *testing.T) {
 		t.Fatalf("expected id conflict, got %#v", conflicts)
 	}
 }
+
+func TestSaveProfileValidatesDatabaseSelection(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	if err := a.SaveProfile(models.Profile{ID: "p1", Name: "Prod", Databases: &models.DatabaseSelection{Mode: models.DatabasesList}}); err == nil {
+		t.Fatal("expected an empty database list to be rejected")
+	}
+	wp := models.Profile{ID: "p2", Name: "Blog", ConnectionType: models.ConnectionTypeWordPress, Databases: &models.DatabaseSelection{Mode: models.DatabasesAll}}
+	if err := a.SaveProfile(wp); err == nil {
+		t.Fatal("expected a database selection on a WordPress host to be rejected")
+	}
+	if err := a.SaveProfile(models.Profile{ID: "p3", Name: "Shop", Databases: &models.DatabaseSelection{}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range a.Profiles() {
+		if p.ID == "p3" && p.Databases != nil {
+			t.Fatalf("an inactive selection should not be stored: %+v", p.Databases)
+		}
+	}
+}
+
+func TestRestoreWithOptionsRejectsParallel(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	dest := models.Profile{ID: "staging", Name: "Staging", ConnectionType: models.ConnectionTypeSSH}
+	err := a.RestoreWithOptions(context.Background(), models.ExportRecord{}, dest, RestoreOptions{Parallel: transfer.MaxParallelRestore + 1}, nil)
+	if err == nil || !strings.Contains(err.Error(), "sessions") {
+		t.Fatalf("too many sessions: %v", err)
+	}
+}
@@ -0,0 +1,138 @@
+package app
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"dback/backend/throttle"
+	"dback/models"
+)
+
+// Bandwidth returns the app-wide transfer cap, or nil.
+func (a *App) Bandwidth() *models.BandwidthLimit {
+	return a.store.Bandwidth()
+}
+
+// SetBandwidth stores the app-wide transfer cap; nil removes it. Running transfers pick up
+// the change within a second.
+func (a *App) SetBandwidth(limit *models.BandwidthLimit) error {
+	if limit != nil {
+		if err := ValidateBandwidth(*limit); err != nil {
+			return err
+		}
+	}
+	return a.store.SetBandwidth(limit)
+}
+
+// ValidateBandwidth rejects negative caps and malformed windows.
+func ValidateBandwidth(b models.BandwidthLimit) error {
+	for _, v := range []float64{b.UploadMBps, b.DownloadMBps} {
+		if v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("bandwidth caps must be zero (unlimited) or a positive MB/s")
+		}
+	}
+	_, err := parseClockWindow(b.WindowStart, b.WindowEnd)
+	return err
+}
+
+// bandwidthCap returns limit's cap for dir at now in bytes per second, or 0 when it does
+// not apply. A window that does not parse is ignored, so the caps always apply.
+func bandwidthCap(limit *models.BandwidthLimit, dir throttle.Direction, now time.Time) float64 {
+	if !limit.Active() {
+		return 0
+	}
+	if window, err := parseClockWindow(limit.WindowStart, limit.WindowEnd); err == nil && !window.contains(now) {
+		return 0
+	}
+	if dir == throttle.Upload {
+		return limit.UploadMBps * throttle.MB
+	}
+	return limit.DownloadMBps * throttle.MB
+}
+
+// globalBuckets returns the buckets every transfer shares, created on first use. They read
+// the app-wide cap from the vault, so a change applies to transfers already running.
+func (a *App) globalBuckets() (upload, download *throttle.Bucket) {
+	a.bandwidthOnce.Do(func() {
+		rate := func(dir throttle.Direction) throttle.Rate {
+			return func(now time.Time) float64 {
+				return bandwidthCap(a.store.Bandwidth(), dir, now)
+			}
+		}
+		a.uploadBucket = throttle.NewBucket(rate(throttle.Upload))
+		a.downloadBucket = throttle.NewBucket(rate(throttle.Download))
+	})
+	return a.uploadBucket, a.downloadBucket
+}
+
+// transferLimits returns the buckets a transfer with profile passes through: the shared
+// app-wide ones and, when the host has caps, its own. Localhost transfers never cross the
+// network and are not limited.
+func (a *App) transferLimits(profile models.Profile) *throttle.Limits {
+	if profile.ConnectionType == models.ConnectionTypeLocalhost {
+		return nil
+	}
+	upload, download := a.globalBuckets()
+	limits := &throttle.Limits{Upload: []*throttle.Bucket{upload}, Download: []*throttle.Bucket{download}}
+	if host := profile.Bandwidth; host.Active() {
+		limits.Upload = append(limits.Upload, throttle.NewBucket(func(now time.Time) float64 {
+			return bandwidthCap(host, throttle.Upload, now)
+		}))
+		limits.Download = append(limits.Download, throttle.NewBucket(func(now time.Time) float64 {
+			return bandwidthCap(host, throttle.Download, now)
+		}))
+	}
+	return limits
+}
+
+// bandwidthSummary describes the caps a transfer in dir starts with, for the activity log;
+// empty when none is configured.
+func (a *App) bandwidthSummary(profile models.Profile, dir throttle.Direction) string {
+	if profile.ConnectionType == models.ConnectionTypeLocalhost {
+		return ""
+	}
+	var parts []string
+	for _, c := range []struct {
+		label string
+		limit *models.BandwidthLimit
+	}{
+		{"app-wide", a.Bandwidth()},
+		{"host", profile.Bandwidth},
+	} {
+		if part := describeBandwidth(c.limit, dir); part != "" {
+			parts = append(parts, c.label+" "+part)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "Bandwidth cap: " + strings.Join(parts, "; ")
+}
+
+// describeBandwidth renders one cap for dir, with its window; empty when dir is unlimited.
+func describeBandwidth(limit *models.BandwidthLimit, dir throttle.Direction) string {
+	if !limit.Active() {
+		return ""
+	}
+	mbps := limit.DownloadMBps
+	if dir == throttle.Upload {
+		mbps = limit.UploadMBps
+	}
+	if mbps <= 0 {
+		return ""
+	}
+	s := throttle.FormatRate(mbps * throttle.MB)
+	if window, err := parseClockWindow(limit.WindowStart, limit.WindowEnd); err == nil && window.set {
+		s += fmt.Sprintf(" from %s to %s", strings.TrimSpace(limit.WindowStart), strings.TrimSpace(limit.WindowEnd))
+	}
+	return s
+}
+
+// logBandwidth notes the caps a transfer starts with in the activity log.
+func (a *App) logBandwidth(operationID string, profile models.Profile, action string, dir throttle.Direction) {
+	if summary := a.bandwidthSummary(profile, dir); summary != "" {
+		a.logPhase(operationID, &profile, action, "bandwidth", "", 0, summary, "Info", "Started", "")
+	}
+}
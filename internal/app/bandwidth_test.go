@@ -0,0 +1,83 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"dback/backend/throttle"
+	"dback/models"
+)
+
+func TestValidateBandwidth(t *testing.T) {
+	for _, b := range []models.BandwidthLimit{
+		{UploadMBps: -1},
+		{DownloadMBps: 5, WindowStart: "9am", WindowEnd: "17:00"},
+		{DownloadMBps: 5, WindowStart: "09:00"},
+	} {
+		if err := ValidateBandwidth(b); err == nil {
+			t.Fatalf("%+v should be rejected", b)
+		}
+	}
+	if err := ValidateBandwidth(models.BandwidthLimit{DownloadMBps: 2.5, WindowStart: "22:00", WindowEnd: "06:00"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBandwidthCapFollowsWindow(t *testing.T) {
+	limit := &models.BandwidthLimit{UploadMBps: 1, DownloadMBps: 4, WindowStart: "08:00", WindowEnd: "18:00"}
+	day := time.Date(2024, 3, 4, 10, 0, 0, 0, time.Local)
+	if got := bandwidthCap(limit, throttle.Download, day); got != 4*throttle.MB {
+		t.Fatalf("daytime download cap = %v", got)
+	}
+	if got := bandwidthCap(limit, throttle.Upload, day); got != throttle.MB {
+		t.Fatalf("daytime upload cap = %v", got)
+	}
+	if got := bandwidthCap(limit, throttle.Download, day.Add(10*time.Hour)); got != 0 {
+		t.Fatalf("night cap = %v", got)
+	}
+	if got := bandwidthCap(nil, throttle.Download, day); got != 0 {
+		t.Fatalf("nil cap = %v", got)
+	}
+}
+
+func TestSetBandwidthAndTransferLimits(t *testing.T) {
+	dir := t.TempDir()
+	a := openApp(t, dir)
+	if err := a.SetBandwidth(&models.BandwidthLimit{DownloadMBps: -2}); err == nil {
+		t.Fatal("negative cap should be rejected")
+	}
+	if err := a.SetBandwidth(&models.BandwidthLimit{DownloadMBps: 8}); err != nil {
+		t.Fatal(err)
+	}
+	if got := openApp(t, dir).Bandwidth(); got == nil || got.DownloadMBps != 8 {
+		t.Fatalf("saved cap = %+v", got)
+	}
+
+	if a.transferLimits(models.Profile{ConnectionType: models.ConnectionTypeLocalhost}) != nil {
+		t.Fatal("localhost transfers should not be limited")
+	}
+	host := models.Profile{ConnectionType: models.ConnectionTypeSSH, Bandwidth: &models.BandwidthLimit{DownloadMBps: 2}}
+	limits := a.transferLimits(host)
+	if len(limits.Download) != 2 || limits.Limit(throttle.Download) != 2*throttle.MB {
+		t.Fatalf("download buckets = %d, limit = %v", len(limits.Download), limits.Limit(throttle.Download))
+	}
+	if limits.Limit(throttle.Upload) != 0 {
+		t.Fatalf("upload limit = %v", limits.Limit(throttle.Upload))
+	}
+	if other := a.transferLimits(models.Profile{ConnectionType: models.ConnectionTypeSSH}); other.Download[0] != limits.Download[0] {
+		t.Fatal("transfers should share the app-wide bucket")
+	}
+	if got := a.bandwidthSummary(host, throttle.Download); got != "Bandwidth cap: app-wide 8.0 MB/s; host 2.0 MB/s" {
+		t.Fatalf("summary = %q", got)
+	}
+	if got := a.bandwidthSummary(host, throttle.Upload); got != "" {
+		t.Fatalf("upload summary = %q", got)
+	}
+
+	if err := a.SetBandwidth(nil); err != nil {
+		t.Fatal(err)
+	}
+	if a.Bandwidth() != nil {
+		t.Fatal("cap should be removed")
+	}
+}
@@ -0,0 +1,314 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"dback/backend/transfer"
+	"dback/backend/verify"
+	"dback/internal/notify"
+	"dback/internal/paths"
+	"dback/models"
+)
+
+// ErrNoBinlogEvents is returned by BackupBinlog when the server wrote nothing since the
+// newest backup.
+var ErrNoBinlogEvents = transfer.ErrNoBinlogEvents
+
+// ValidateBinlogSettings checks that a host can take binlog incrementals.
+func ValidateBinlogSettings(p models.Profile) error {
+	if !p.Binlog.Active() {
+		return nil
+	}
+	if p.UsesWordPress() {
+		return fmt.Errorf("incremental binlog backups need an SSH or Localhost host")
+	}
+	if p.Databases.Active() {
+		return fmt.Errorf("incremental binlog backups follow a single database; turn off multi-database backups")
+	}
+	if p.Binlog.IntervalMinutes < 0 {
+		return fmt.Errorf("binlog pull interval must not be negative")
+	}
+	return nil
+}
+
+// BackupBinlog pulls the binary log events written since the host's newest backup and
+// records them as the next incremental of that backup's chain.
+func (a *App) BackupBinlog(ctx context.Context, profile models.Profile, trigger string, progress ProgressFunc) (models.ExportRecord, error) {
+	return a.backupBinlog(ctx, profile, runOptions{tags: operationTags{trigger: trigger}}, progress)
+}
+
+// backupBinlog runs one incremental backup as a persisted job. Only failures are notified;
+// incrementals run too often for success notices.
+func (a *App) backupBinlog(ctx context.Context, profile models.Profile, opts runOptions, progress ProgressFunc) (models.ExportRecord, error) {
+	if !profile.Binlog.Active() {
+		return models.ExportRecord{}, fmt.Errorf("incremental backups are not enabled for %q", profile.Name)
+	}
+	tip, ok := a.binlogTip(profile)
+	if !ok {
+		return models.ExportRecord{}, fmt.Errorf("no backup of %q has a binary log position yet; run a full backup first", profile.Name)
+	}
+	operationID := opts.operationID
+	if operationID == "" {
+		operationID = newID()
+	}
+	started := time.Now()
+	jobID := a.startJob(opts, operationID, models.JobKindBinlog, profile)
+	record, err := a.runBinlogBackup(ctx, profile, operationID, opts.tags, tip, progress)
+	if errors.Is(err, ErrNoBinlogEvents) {
+		a.finishJob(jobID, models.JobSucceeded, nil)
+		return record, err
+	}
+	a.finishJob(jobID, jobStatusFor(err), err)
+	if err != nil {
+		a.notify(notify.EventBackup, operationID, profile, opts.tags.trigger, started, nil, err)
+	}
+	return record, err
+}
+
+func (a *App) runBinlogBackup(ctx context.Context, profile models.Profile, operationID string, tags operationTags, tip models.ExportRecord, progress ProgressFunc) (models.ExportRecord, error) {
+	a.setOperationTags(operationID, tags)
+	defer a.setOperationTags(operationID, operationTags{})
+	dest := paths.EffectiveBackupDestination(profile.Destination)
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return models.ExportRecord{}, err
+	}
+	a.logPhase(operationID, &profile, "Export", "start", "", 0, "Starting incremental binlog backup from "+tip.Binlog.End.String(), "Info", "Started", "")
+
+	result, err := transfer.BackupBinlog(ctx, transfer.BinlogRequest{
+		BackupRequest: transfer.BackupRequest{
+			Profile:     profile,
+			OperationID: operationID,
+			Destination: dest,
+			Logger:      a.newOpLogger(operationID, &profile),
+			Progress:    progress,
+			Bandwidth:   a.transferLimits(profile),
+		},
+		From: tip.Binlog.End,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrNoBinlogEvents):
+		case errors.Is(err, context.Canceled):
+			a.logPhase(operationID, &profile, "Export", "cancel", "", 0, "Backup canceled", "Info", "Canceled", "")
+		default:
+			a.logPhase(operationID, &profile, "Export", "failure", "", 0, "Incremental backup failed", "Error", "Failed", err.Error())
+		}
+		return models.ExportRecord{}, err
+	}
+	file := result.Files[0]
+	record := models.ExportRecord{
+		ID:                newID(),
+		OperationID:       operationID,
+		ProfileID:         profile.ID,
+		ProfileName:       profile.Name,
+		DatabaseName:      file.Database,
+		ExportDate:        time.Now(),
+		FilePath:          file.Path,
+		FileSize:          formatSize(file.Size),
+		FileSizeBytes:     file.Size,
+		ConnectionType:    profile.ConnectionType,
+		Trigger:           tags.trigger,
+		ParentOperationID: tags.parentID,
+		Type:              models.BackupTypeBinlog,
+		Binlog:            file.Binlog,
+		ParentRecordID:    tip.ID,
+	}
+	record.Sha256, _ = verify.ChecksumFile(file.Path)
+	applyAutoQuickVerify(&record)
+	a.mu.Lock()
+	a.history = append(a.history, record)
+	history := append([]models.ExportRecord(nil), a.history...)
+	a.mu.Unlock()
+	if err := a.store.SaveHistory(history); err != nil {
+		return record, err
+	}
+	a.logPhaseWithFile(operationID, profile, "Export", "complete", "", 0, fmt.Sprintf("Incremental backup of %d event(s) completed", file.Binlog.Events), "Info", "Succeeded", "", record.FilePath, record.FileSizeBytes)
+	if progress != nil {
+		progress("Incremental backup completed", record.FileSizeBytes, record.FileSizeBytes)
+	}
+	return record, nil
+}
+
+// binlogTip returns the newest backup of the host's database with a binary log position:
+// the next incremental continues from it.
+func (a *App) binlogTip(profile models.Profile) (models.ExportRecord, bool) {
+	var tip models.ExportRecord
+	found := false
+	for _, rec := range a.History() {
+		if rec.ProfileID != profile.ID || rec.DatabaseName != profile.TargetDBName || rec.Binlog == nil || rec.Binlog.End.IsZero() {
+			continue
+		}
+		if !found || rec.ExportDate.After(tip.ExportDate) {
+			tip, found = rec, true
+		}
+	}
+	return tip, found
+}
+
+// BinlogChain returns the incrementals that continue a full backup, oldest first.
+func (a *App) BinlogChain(base models.ExportRecord) []models.ExportRecord {
+	return binlogChain(a.History(), base.ID)
+}
+
+func binlogChain(history []models.ExportRecord, baseID string) []models.ExportRecord {
+	children := map[string][]models.ExportRecord{}
+	for _, rec := range history {
+		if rec.Incremental() && rec.ParentRecordID != "" {
+			children[rec.ParentRecordID] = append(children[rec.ParentRecordID], rec)
+		}
+	}
+	var chain []models.ExportRecord
+	for id := baseID; len(children[id]) > 0; {
+		next := children[id]
+		sort.SliceStable(next, func(i, j int) bool { return next[i].ExportDate.Before(next[j].ExportDate) })
+		chain = append(chain, next[0])
+		id = next[0].ID
+	}
+	return chain
+}
+
+// PointInTimeRange returns the times a full backup can be rolled forward to: from when the
+// dump finished to when its newest incremental was pulled.
+func (a *App) PointInTimeRange(base models.ExportRecord) (from, to time.Time, ok bool) {
+	if base.Incremental() || base.Binlog == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	chain := a.BinlogChain(base)
+	if len(chain) == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+	return base.ExportDate, chain[len(chain)-1].ExportDate, true
+}
+
+// RestorePointInTime restores a full backup and replays its binlog chain up to target.
+// Binary logs name the source database, so the destination must use the same database name.
+func (a *App) RestorePointInTime(ctx context.Context, base models.ExportRecord, target time.Time, destination models.Profile, progress ProgressFunc) error {
+	if _, err := a.planPointInTime(base, destination, target); err != nil {
+		return err
+	}
+	return a.restore(ctx, base, destination, runOptions{pointInTime: &target}, progress)
+}
+
+// planPointInTime checks a point-in-time restore and returns the incrementals to replay.
+func (a *App) planPointInTime(base models.ExportRecord, destination models.Profile, target time.Time) ([]models.ExportRecord, error) {
+	if base.Incremental() || base.Binlog == nil {
+		return nil, fmt.Errorf("this backup has no binary log position; enable incremental backups on the host and take a new full backup")
+	}
+	if destination.UsesWordPress() {
+		return nil, fmt.Errorf("point-in-time restore needs an SSH or Localhost destination")
+	}
+	if destination.TargetDBName != base.DatabaseName {
+		return nil, fmt.Errorf("binary logs replay into database %q; set the destination's database to %q", base.DatabaseName, base.DatabaseName)
+	}
+	return pointInTimeSteps(base, a.BinlogChain(base), target)
+}
+
+// pointInTimeSteps picks the incrementals with events up to target. The last one may hold
+// later events too; it is cut at target when replayed.
+func pointInTimeSteps(base models.ExportRecord, chain []models.ExportRecord, target time.Time) ([]models.ExportRecord, error) {
+	if target.Before(base.ExportDate) {
+		return nil, fmt.Errorf("pick a time after the full backup finished (%s)", base.ExportDate.Local().Format("2006-01-02 15:04:05"))
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("this backup has no incremental backups to replay")
+	}
+	if last := chain[len(chain)-1]; target.After(last.ExportDate) {
+		return nil, fmt.Errorf("incremental backups only reach %s; pick an earlier time", last.ExportDate.Local().Format("2006-01-02 15:04:05"))
+	}
+	var steps []models.ExportRecord
+	for _, inc := range chain {
+		if inc.Binlog == nil || inc.Binlog.Events == 0 {
+			continue
+		}
+		if inc.Binlog.FirstEvent.After(target) {
+			break
+		}
+		steps = append(steps, inc)
+	}
+	return steps, nil
+}
+
+// replayBinlogs applies incrementals on top of a restored full backup, cutting the last at
+// target.
+func (a *App) replayBinlogs(ctx context.Context, operationID string, destination models.Profile, steps []models.ExportRecord, target time.Time, progress ProgressFunc) error {
+	logger := a.newOpLogger(operationID, &destination)
+	for i, inc := range steps {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var stopAt time.Time
+		if inc.Binlog.LastEvent.After(target) {
+			stopAt = target
+		}
+		stepProgress := progress
+		if progress != nil {
+			prefix := fmt.Sprintf("[binlog %d/%d] ", i+1, len(steps))
+			stepProgress = func(msg string, current, total int64) {
+				progress(prefix+msg, current, total)
+			}
+		}
+		err := transfer.RestoreSSH(ctx, transfer.RestoreRequest{
+			Profile:     destination,
+			OperationID: operationID,
+			LocalPath:   inc.FilePath,
+			FileSize:    inc.FileSizeBytes,
+			Logger:      logger,
+			Progress:    stepProgress,
+			Incremental: true,
+			StopAt:      stopAt,
+			Bandwidth:   a.transferLimits(destination),
+		})
+		if err != nil {
+			return fmt.Errorf("replay %s: %w", filepath.Base(inc.FilePath), err)
+		}
+	}
+	a.logPhase(operationID, &destination, "Import", "pitr", "", 0,
+		fmt.Sprintf("Replayed %d incremental backup(s) up to %s", len(steps), target.Local().Format("2006-01-02 15:04:05")), "Info", "Succeeded", "")
+	return nil
+}
+
+// splitIncrementals separates full backups, which retention rules count, from incrementals.
+func splitIncrementals(records []models.ExportRecord) (full, incremental []models.ExportRecord) {
+	for _, rec := range records {
+		if rec.Incremental() {
+			incremental = append(incremental, rec)
+		} else {
+			full = append(full, rec)
+		}
+	}
+	return full, incremental
+}
+
+// orphanedIncrementals returns the incrementals whose chain no longer reaches a kept full
+// backup once removals are applied.
+func orphanedIncrementals(all, incremental []models.ExportRecord, removals []RetentionRemoval) []RetentionRemoval {
+	removed := map[string]bool{}
+	for _, r := range removals {
+		removed[r.Record.ID] = true
+	}
+	byID := map[string]models.ExportRecord{}
+	for _, rec := range all {
+		byID[rec.ID] = rec
+	}
+	var out []RetentionRemoval
+	for _, inc := range incremental {
+		root := inc
+		for root.Incremental() {
+			parent, ok := byID[root.ParentRecordID]
+			if !ok {
+				break
+			}
+			root = parent
+		}
+		if root.Incremental() || removed[root.ID] {
+			out = append(out, RetentionRemoval{Record: inc, Reason: "full backup of its chain removed"})
+		}
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+// binlogHistory is a full backup at 08:00 followed by three hourly incrementals; the second
+// pulled no events.
+func binlogHistory(day time.Time) []models.ExportRecord {
+	at := func(h, m int) time.Time { return day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute) }
+	pos := models.BinlogPosition{File: "mysql-bin.000007", Pos: 1200}
+	incremental := func(id, parent string, pulled time.Time, events int, first, last time.Time) models.ExportRecord {
+		return models.ExportRecord{
+			ID: id, ProfileID: "p1", DatabaseName: "shop", ExportDate: pulled,
+			Type: models.BackupTypeBinlog, ParentRecordID: parent,
+			Binlog: &models.BinlogRange{Start: pos, End: pos, Events: events, FirstEvent: first, LastEvent: last},
+		}
+	}
+	return []models.ExportRecord{
+		{ID: "full", ProfileID: "p1", DatabaseName: "shop", ExportDate: at(8, 0), Binlog: &models.BinlogRange{Start: pos, End: pos}},
+		incremental("inc3", "inc2", at(11, 0), 4, at(10, 5), at(10, 50)),
+		incremental("inc1", "full", at(9, 0), 10, at(8, 10), at(8, 55)),
+		incremental("inc2", "inc1", at(10, 0), 0, time.Time{}, time.Time{}),
+	}
+}
+
+func recordIDs(records []models.ExportRecord) string {
+	ids := make([]string, 0, len(records))
+	for _, rec := range records {
+		ids = append(ids, rec.ID)
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestBinlogChainFollowsParents(t *testing.T) {
+	history := binlogHistory(time.Date(2026, 1, 14, 0, 0, 0, 0, time.Local))
+	if got := recordIDs(binlogChain(history, "full")); got != "inc1,inc2,inc3" {
+		t.Fatalf("chain = %s", got)
+	}
+	if got := binlogChain(history, "inc3"); len(got) != 0 {
+		t.Fatalf("the last incremental has no successors, got %s", recordIDs(got))
+	}
+}
+
+func TestPointInTimeSteps(t *testing.T) {
+	day := time.Date(2026, 1, 14, 0, 0, 0, 0, time.Local)
+	history := binlogHistory(day)
+	base, chain := history[0], binlogChain(history, "full")
+
+	steps, err := pointInTimeSteps(base, chain, day.Add(8*time.Hour+30*time.Minute))
+	if err != nil || recordIDs(steps) != "inc1" {
+		t.Fatalf("steps at 08:30 = %s, %v", recordIDs(steps), err)
+	}
+	steps, err = pointInTimeSteps(base, chain, day.Add(10*time.Hour+30*time.Minute))
+	if err != nil || recordIDs(steps) != "inc1,inc3" {
+		t.Fatalf("steps at 10:30 should skip the empty incremental, got %s, %v", recordIDs(steps), err)
+	}
+	if _, err := pointInTimeSteps(base, chain, day.Add(7*time.Hour)); err == nil {
+		t.Fatal("a time before the full backup should fail")
+	}
+	if _, err := pointInTimeSteps(base, chain, day.Add(12*time.Hour)); err == nil {
+		t.Fatal("a time after the newest incremental should fail")
+	}
+	if _, err := pointInTimeSteps(base, nil, day.Add(9*time.Hour)); err == nil {
+		t.Fatal("a backup without incrementals should fail")
+	}
+}
+
+func TestRetentionRemovesIncrementalsWithTheirFullBackup(t *testing.T) {
+	day := time.Date(2026, 1, 14, 0, 0, 0, 0, time.Local)
+	history := binlogHistory(day)
+	newer := models.ExportRecord{ID: "full2", ProfileID: "p1", DatabaseName: "shop", ExportDate: day.Add(12 * time.Hour)}
+	history = append(history, newer)
+
+	full, incremental := splitIncrementals(history)
+	if recordIDs(full) != "full,full2" || len(incremental) != 3 {
+		t.Fatalf("split = %s / %s", recordIDs(full), recordIDs(incremental))
+	}
+	removals := planRetention(models.RetentionPolicy{KeepLast: 1}, full, day.Add(13*time.Hour))
+	removals = append(removals, orphanedIncrementals(history, incremental, removals)...)
+	ids := removedIDs(removals)
+	for _, id := range []string{"full", "inc1", "inc2", "inc3"} {
+		if !ids[id] {
+			t.Fatalf("%s should be removed, got %v", id, ids)
+		}
+	}
+	if ids["full2"] {
+		t.Fatal("the newest full backup should be kept")
+	}
+	if got := orphanedIncrementals(history, incremental, nil); len(got) != 0 {
+		t.Fatalf("incrementals of a kept full backup should stay, got %d removal(s)", len(got))
+	}
+}
@@ -74,13 +74,15 @@ func (a *App) TestDatabaseConnection(ctx context.Context, profile models.Profile
 	if !profile.SupportsSQLQuery() {
 		return fmt.Errorf("database test requires MySQL or MariaDB")
 	}
-	if strings.TrimSpace(profile.TargetDBName) == "" {
+	// A host that backs up several databases may leave the target database empty.
+	connectDB := !profile.Databases.Active()
+	if connectDB && strings.TrimSpace(profile.TargetDBName) == "" {
 		return fmt.Errorf("target database name is required")
 	}
 	if err := db.ValidateProfileForRemoteOps(profile); err != nil {
 		return err
 	}
-	cmd, err := db.BuildQueryCommand(profile, "SELECT 1", true)
+	cmd, err := db.BuildQueryCommand(profile, "SELECT 1", connectDB)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+
+	"dback/backend/crypt"
+	"dback/models"
+)
+
+// minRecoveryPassphraseLen keeps recovery passphrases out of quick brute-force range; they
+// protect files that may sit in off-site storage for years.
+const minRecoveryPassphraseLen = 8
+
+// ValidateEncryption checks that a host's backups can be encrypted as they are written.
+func ValidateEncryption(p models.Profile) error {
+	if !p.Encryption.Active() {
+		return nil
+	}
+	switch {
+	case p.UsesWordPress():
+		return fmt.Errorf("backup encryption needs an SSH or Localhost host")
+	case p.PhysicalBackup:
+		return fmt.Errorf("physical backups cannot be encrypted; turn off one of them")
+	case p.DumpFormat == models.DumpFormatSplit, p.DumpFormat == models.DumpFormatRepository:
+		return fmt.Errorf("encrypted backups use the single-file dump format")
+	case p.Binlog.Active():
+		return fmt.Errorf("incremental binlog backups are not encrypted; turn them off to encrypt backups")
+	}
+	if pass := p.Encryption.RecoveryPassphrase; pass != "" && len(pass) < minRecoveryPassphraseLen {
+		return fmt.Errorf("recovery passphrase must be at least %d characters", minRecoveryPassphraseLen)
+	}
+	return nil
+}
+
+// NoRecoveryPassphraseWarning is shown for a host that encrypts its backups without a
+// recovery passphrase.
+const NoRecoveryPassphraseWarning = "No recovery passphrase is set: these backups open only with this vault's backup key. Set one, or keep an export of the app data with secrets."
+
+// RecoveryWarning returns NoRecoveryPassphraseWarning when p encrypts its backups without a
+// recovery passphrase, and "" otherwise.
+func RecoveryWarning(p models.Profile) string {
+	if p.Encryption.Active() && p.Encryption.RecoveryPassphrase == "" {
+		return NoRecoveryPassphraseWarning
+	}
+	return ""
+}
+
+// encryptionKeys returns the keys a new backup of p is encrypted for, or nil when the host
+// writes plain files.
+func (a *App) encryptionKeys(p models.Profile) (*crypt.Keys, error) {
+	if !p.Encryption.Active() {
+		return nil, nil
+	}
+	key, err := a.store.BackupKey()
+	if err != nil {
+		return nil, fmt.Errorf("backup encryption key: %w", err)
+	}
+	return &crypt.Keys{Vault: key, Passphrase: p.Encryption.RecoveryPassphrase}, nil
+}
+
+// decryptionKeys returns the keys that may open a record's file: the vault backup key that
+// opens it and the recovery passphrase of the host that made it. Plain files get no keys.
+func (a *App) decryptionKeys(record models.ExportRecord) crypt.Keys {
+	if !record.Encrypted {
+		if encrypted, err := crypt.IsEncryptedFile(record.FilePath); err != nil || !encrypted {
+			return crypt.Keys{}
+		}
+	}
+	var keys crypt.Keys
+	if key, err := a.vaultKeyFor(record.FilePath); err == nil {
+		keys.Vault = key
+	}
+	a.mu.Lock()
+	for _, p := range a.profiles {
+		if p.ID == record.ProfileID && p.Encryption != nil {
+			keys.Passphrase = p.Encryption.RecoveryPassphrase
+		}
+	}
+	a.mu.Unlock()
+	return keys
+}
+
+// vaultKeyFor returns the vault backup key that opens the encrypted file at path: the
+// vault's own or one imported with app data from another vault. It is the vault's own key
+// when none does, so errors name the usual key.
+func (a *App) vaultKeyFor(path string) ([]byte, error) {
+	keys, err := a.store.BackupKeys()
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("vault has no backup key")
+	}
+	for _, key := range keys {
+		if crypt.CheckKey(path, crypt.Keys{Vault: key}) == nil {
+			return key, nil
+		}
+	}
+	return keys[0], nil
+}
+
+// DecryptBackupFile writes the plaintext of an encrypted backup to dst, opening it with a
+// vault backup key or, when set, a recovery passphrase.
+func (a *App) DecryptBackupFile(src, dst, passphrase string) error {
+	key, err := a.vaultKeyFor(src)
+	if err != nil {
+		return fmt.Errorf("backup encryption key: %w", err)
+	}
+	return crypt.DecryptFile(src, dst, crypt.Keys{Vault: key, Passphrase: passphrase})
+}
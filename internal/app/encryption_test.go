@@ -0,0 +1,87 @@
+package app
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"dback/backend/crypt"
+	"dback/models"
+)
+
+func TestValidateEncryption(t *testing.T) {
+	p := models.Profile{ConnectionType: models.ConnectionTypeSSH, Encryption: &models.BackupEncryption{Enabled: true}}
+	if err := ValidateEncryption(p); err != nil {
+		t.Fatalf("plain SSH host: %v", err)
+	}
+	for name, mod := range map[string]func(*models.Profile){
+		"wordpress":  func(p *models.Profile) { p.ConnectionType = models.ConnectionTypeWordPress },
+		"physical":   func(p *models.Profile) { p.PhysicalBackup = true },
+		"split":      func(p *models.Profile) { p.DumpFormat = models.DumpFormatSplit },
+		"repository": func(p *models.Profile) { p.DumpFormat = models.DumpFormatRepository },
+		"binlog":     func(p *models.Profile) { p.Binlog = &models.BinlogSettings{Enabled: true} },
+		"short pass": func(p *models.Profile) {
+			p.Encryption = &models.BackupEncryption{Enabled: true, RecoveryPassphrase: "short"}
+		},
+	} {
+		q := p
+		mod(&q)
+		if err := ValidateEncryption(q); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestImportedBackupKeysOpenBackups(t *testing.T) {
+	src := openApp(t, t.TempDir())
+	key, err := src.store.BackupKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	backup := filepath.Join(dir, "shop.sql.enc")
+	var sealed bytes.Buffer
+	w, err := crypt.NewWriter(&sealed, crypt.Keys{Vault: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("SELECT 1;\n"))
+	w.Close()
+	if err := os.WriteFile(backup, sealed.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	bundle := filepath.Join(dir, "app.json")
+	if err := src.ExportAppData(bundle, true, "bundle-passphrase"); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := openApp(t, t.TempDir())
+	own, err := dst.store.BackupKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.DecryptBackupFile(backup, filepath.Join(dir, "before.sql"), ""); err == nil {
+		t.Fatal("another vault's backup opened without its key")
+	}
+	if err := dst.ImportAppData(bundle, true, "bundle-passphrase"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.DecryptBackupFile(backup, filepath.Join(dir, "after.sql"), ""); err != nil {
+		t.Fatalf("imported key: %v", err)
+	}
+	if again, _ := dst.store.BackupKey(); !bytes.Equal(again, own) {
+		t.Fatal("importing app data replaced the key new backups are written with")
+	}
+}
+
+func TestRecoveryWarning(t *testing.T) {
+	if RecoveryWarning(models.Profile{Encryption: &models.BackupEncryption{Enabled: true}}) == "" {
+		t.Fatal("no warning for an encrypted host without a recovery passphrase")
+	}
+	for _, e := range []*models.BackupEncryption{nil, {Enabled: false}, {Enabled: true, RecoveryPassphrase: "long enough"}} {
+		if w := RecoveryWarning(models.Profile{Encryption: e}); w != "" {
+			t.Fatalf("%+v: %s", e, w)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"dback/models"
+)
+
+// DefaultGroupBackupConcurrency is used when BackupGroup gets a non-positive limit.
+const DefaultGroupBackupConcurrency = 2
+
+// Per-host outcomes of a group backup.
+const (
+	GroupBackupSucceeded = "succeeded"
+	GroupBackupFailed    = "failed"
+	GroupBackupSkipped   = "skipped"
+)
+
+// GroupBackupResult is the outcome of one host in a group backup.
+type GroupBackupResult struct {
+	Profile models.Profile
+	Status  string
+	Record  models.ExportRecord
+	Err     error
+}
+
+// GroupBackupSummary collects per-host results under one aggregate operation ID.
+type GroupBackupSummary struct {
+	OperationID string
+	Group       string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Results     []GroupBackupResult
+}
+
+// Counts returns how many hosts succeeded, failed and were skipped.
+func (s GroupBackupSummary) Counts() (succeeded, failed, skipped int) {
+	for _, r := range s.Results {
+		switch r.Status {
+		case GroupBackupSucceeded:
+			succeeded++
+		case GroupBackupFailed:
+			failed++
+		default:
+			skipped++
+		}
+	}
+	return succeeded, failed, skipped
+}
+
+// String is a one-line summary for job tables and logs.
+func (s GroupBackupSummary) String() string {
+	succeeded, failed, skipped := s.Counts()
+	return fmt.Sprintf("%d succeeded, %d failed, %d skipped", succeeded, failed, skipped)
+}
+
+// GroupBackupProgressFunc reports progress of a single host inside a group backup.
+type GroupBackupProgressFunc func(profile models.Profile, message string, current, total int64)
+
+// GroupProfiles returns the hosts in a group, in profile order.
+func (a *App) GroupProfiles(group string) []models.Profile {
+	group = normalizeGroup(group)
+	var out []models.Profile
+	for _, p := range a.Profiles() {
+		if strings.EqualFold(normalizeGroup(p.Group), group) {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// BackupGroup backs up every host in a group with at most concurrency backups at once.
+// A failing host does not stop the others; hosts not started before ctx is canceled are skipped.
+func (a *App) BackupGroup(ctx context.Context, group string, concurrency int, progress GroupBackupProgressFunc) (GroupBackupSummary, error) {
+	profiles := a.GroupProfiles(group)
+	summary := GroupBackupSummary{
+		OperationID: newID(),
+		Group:       normalizeGroup(group),
+		StartedAt:   time.Now(),
+		Results:     make([]GroupBackupResult, len(profiles)),
+	}
+	if len(profiles) == 0 {
+		return summary, fmt.Errorf("group %q has no hosts", summary.Group)
+	}
+	if concurrency <= 0 {
+		concurrency = DefaultGroupBackupConcurrency
+	}
+	a.logPhase(summary.OperationID, nil, "GroupBackup", "start", "", 0, fmt.Sprintf("Backing up %d host(s) in group %q, %d at a time", len(profiles), summary.Group, concurrency), "Info", "Started", "")
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, p := range profiles {
+		summary.Results[i] = GroupBackupResult{Profile: p, Status: GroupBackupSkipped}
+		select {
+		case <-ctx.Done():
+			continue
+		case sem <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			<-sem
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p models.Profile) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			var hostProgress ProgressFunc
+			if progress != nil {
+				hostProgress = func(message string, current, total int64) {
+					progress(p, message, current, total)
+				}
+			}
+			record, err := a.backup(ctx, p, runOptions{tags: operationTags{parentID: summary.OperationID}}, hostProgress)
+			result := GroupBackupResult{Profile: p, Status: GroupBackupSucceeded, Record: record}
+			if err != nil {
+				result.Status = GroupBackupFailed
+				result.Err = err
+			}
+			summary.Results[i] = result
+		}(i, p)
+	}
+	wg.Wait()
+	summary.FinishedAt = time.Now()
+
+	for _, r := range summary.Results {
+		p := r.Profile
+		switch r.Status {
+		case GroupBackupSucceeded:
+			a.logPhaseWithFile(summary.OperationID, p, "GroupBackup", "host", "", 0, "Backup succeeded", "Info", "Succeeded", "", r.Record.FilePath, r.Record.FileSizeBytes)
+		case GroupBackupFailed:
+			a.logPhase(summary.OperationID, &p, "GroupBackup", "host", "", 0, "Backup failed", "Error", "Failed", r.Err.Error())
+		default:
+			a.logPhase(summary.OperationID, &p, "GroupBackup", "host", "", 0, "Backup skipped (canceled)", "Warning", "Skipped", "")
+		}
+	}
+	_, failed, _ := summary.Counts()
+	status, level := "Succeeded", "Info"
+	if failed > 0 {
+		status, level = "Failed", "Error"
+	}
+	a.logPhase(summary.OperationID, nil, "GroupBackup", "complete", "", 0, fmt.Sprintf("Group %q: %s in %s", summary.Group, summary, summary.FinishedAt.Sub(summary.StartedAt).Round(time.Millisecond)), level, status, "")
+	return summary, ctx.Err()
+}
+
+// normalizeGroup matches how SaveProfile defaults an empty group.
+func normalizeGroup(group string) string {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return "Default"
+	}
+	return group
+}
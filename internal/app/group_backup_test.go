@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"dback/models"
+)
+
+func saveGroupProfiles(t *testing.T, a *App, dest string) {
+	t.Helper()
+	for _, p := range []models.Profile{
+		// Empty DB users fail validation before any connection is attempted.
+		{ID: "g1", Name: "Gold One", Group: "Gold", ConnectionType: models.ConnectionTypeSSH, Destination: dest},
+		{ID: "g2", Name: "Gold Two", Group: "gold", ConnectionType: models.ConnectionTypeSSH, Destination: dest},
+		{ID: "s1", Name: "Silver", Group: "Silver", ConnectionType: models.ConnectionTypeSSH, Destination: dest},
+	} {
+		if err := a.SaveProfile(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestBackupGroupContinuesAfterFailures(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	saveGroupProfiles(t, a, t.TempDir())
+
+	summary, err := a.BackupGroup(context.Background(), "Gold", 1, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Results) != 2 {
+		t.Fatalf("expected 2 hosts in group, got %d", len(summary.Results))
+	}
+	if succeeded, failed, skipped := summary.Counts(); succeeded != 0 || failed != 2 || skipped != 0 {
+		t.Fatalf("unexpected counts: %s", summary)
+	}
+
+	children := 0
+	for _, entry := range a.Logs() {
+		if entry.ParentOperationID == summary.OperationID && entry.Action == "Export" {
+			children++
+		}
+	}
+	if children == 0 {
+		t.Fatal("expected host log entries to reference the group operation")
+	}
+}
+
+func TestBackupGroupSkipsHostsWhenCanceled(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	saveGroupProfiles(t, a, t.TempDir())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	summary, err := a.BackupGroup(ctx, "Gold", 2, nil)
+	if err == nil {
+		t.Fatal("expected cancellation error")
+	}
+	if _, _, skipped := summary.Counts(); skipped != 2 {
+		t.Fatalf("expected both hosts skipped: %s", summary)
+	}
+}
+
+func TestBackupGroupUnknownGroup(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	if _, err := a.BackupGroup(context.Background(), "Nope", 0, nil); err == nil {
+		t.Fatal("expected error for empty group")
+	}
+}
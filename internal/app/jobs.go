@@ -0,0 +1,308 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"dback/backend/transfer"
+	"dback/internal/paths"
+	"dback/models"
+)
+
+// maxFinishedJobs bounds how many completed jobs stay in the vault.
+const maxFinishedJobs = 200
+
+// Jobs returns persisted backup and restore jobs, newest first.
+func (a *App) Jobs() []models.JobRecord {
+	a.mu.Lock()
+	jobs := append([]models.JobRecord(nil), a.jobs...)
+	a.mu.Unlock()
+	sort.SliceStable(jobs, func(i, j int) bool { return jobs[i].CreatedAt.After(jobs[j].CreatedAt) })
+	return jobs
+}
+
+// InterruptedJobs returns jobs that were cut off by an app exit and not yet resumed or dismissed.
+func (a *App) InterruptedJobs() []models.JobRecord {
+	var out []models.JobRecord
+	for _, job := range a.Jobs() {
+		if job.Status == models.JobInterrupted {
+			out = append(out, job)
+		}
+	}
+	return out
+}
+
+// CanResumeJob reports whether an interrupted job left resumable transfer state on disk.
+// Other interrupted jobs can only be retried from the start.
+func (a *App) CanResumeJob(job models.JobRecord) bool {
+	if job.Status != models.JobInterrupted {
+		return false
+	}
+	switch job.Kind {
+	case models.JobKindBackup:
+		profile, ok := a.profileByID(job.ProfileID)
+		if !ok || profile.UsesWordPress() {
+			return false
+		}
+		_, ok = transfer.FindResumableBackup(paths.EffectiveBackupDestination(profile.Destination), profile, job.OperationID)
+		return ok
+	case models.JobKindRestore:
+		dest, ok := a.profileByID(job.DestProfileID)
+		if !ok || dest.UsesWordPress() {
+			return false
+		}
+		record, _, err := a.findHistoryRecord(job.RecordID)
+		return err == nil && transfer.HasResumableRestore(record.FilePath, job.OperationID)
+	}
+	return false
+}
+
+// ResumeJob continues an interrupted job under its original operation ID so the transfer
+// picks up from its saved offset. Jobs without resumable state start over.
+func (a *App) ResumeJob(ctx context.Context, jobID string, progress ProgressFunc) (models.ExportRecord, error) {
+	return a.rerunJob(ctx, jobID, a.CanResumeJob, progress)
+}
+
+// RetryJob runs an interrupted or failed job again from the start.
+func (a *App) RetryJob(ctx context.Context, jobID string, progress ProgressFunc) (models.ExportRecord, error) {
+	return a.rerunJob(ctx, jobID, func(models.JobRecord) bool { return false }, progress)
+}
+
+// DismissJob hides an interrupted job from the resume prompt.
+func (a *App) DismissJob(jobID string) error {
+	return a.updateJob(jobID, func(job *models.JobRecord) {
+		job.Status = models.JobDismissed
+	})
+}
+
+func (a *App) rerunJob(ctx context.Context, jobID string, resumable func(models.JobRecord) bool, progress ProgressFunc) (models.ExportRecord, error) {
+	job, ok := a.jobByID(jobID)
+	if !ok {
+		return models.ExportRecord{}, fmt.Errorf("job not found")
+	}
+	if job.Status == models.JobRunning || job.Status == models.JobQueued {
+		return models.ExportRecord{}, fmt.Errorf("job is still running")
+	}
+	resume := resumable(job)
+	opts := runOptions{jobID: job.ID}
+	if resume {
+		opts.operationID = job.OperationID
+	}
+
+	switch job.Kind {
+	case models.JobKindBackup:
+		profile, ok := a.profileByID(job.ProfileID)
+		if !ok {
+			return models.ExportRecord{}, fmt.Errorf("host for this job no longer exists")
+		}
+		if resume {
+			opts.resumePath, _ = transfer.FindResumableBackup(paths.EffectiveBackupDestination(profile.Destination), profile, job.OperationID)
+		}
+		return a.backup(ctx, profile, opts, progress)
+	case models.JobKindRestore:
+		record, _, err := a.findHistoryRecord(job.RecordID)
+		if err != nil {
+			return models.ExportRecord{}, err
+		}
+		dest, ok := a.profileByID(job.DestProfileID)
+		if !ok {
+			return models.ExportRecord{}, fmt.Errorf("destination host for this job no longer exists")
+		}
+		opts.resume = resume
+		opts.tables = job.Tables
+		opts.pointInTime = job.PointInTime
+		opts.replace = job.SearchReplace
+		opts.definer = job.Definer
+		opts.targetDB = job.TargetDB
+		opts.parallel = job.Parallel
+		return record, a.restore(ctx, record, dest, opts, progress)
+	case models.JobKindBinlog:
+		profile, ok := a.profileByID(job.ProfileID)
+		if !ok {
+			return models.ExportRecord{}, fmt.Errorf("host for this job no longer exists")
+		}
+		return a.backupBinlog(ctx, profile, opts, progress)
+	}
+	return models.ExportRecord{}, fmt.Errorf("unknown job kind %q", job.Kind)
+}
+
+// startJob persists a running job for an operation. An existing job ID (resume/retry) is reused.
+func (a *App) startJob(opts runOptions, operationID, kind string, profile models.Profile) string {
+	now := time.Now()
+	a.mu.Lock()
+	jobID := opts.jobID
+	found := false
+	for i := range a.jobs {
+		if a.jobs[i].ID == jobID && jobID != "" {
+			a.jobs[i].OperationID = operationID
+			a.jobs[i].Status = models.JobRunning
+			a.jobs[i].Error = ""
+			a.jobs[i].Attempts++
+			a.jobs[i].StartedAt = now
+			a.jobs[i].FinishedAt = time.Time{}
+			a.jobs[i].OwnerPID = os.Getpid()
+			a.jobs[i].Owner = a.owner
+			found = true
+			break
+		}
+	}
+	if !found {
+		jobID = newID()
+		job := models.JobRecord{
+			ID:          jobID,
+			OperationID: operationID,
+			Kind:        kind,
+			ProfileID:   profile.ID,
+			ProfileName: profile.Name,
+			Status:      models.JobRunning,
+			Attempts:    1,
+			CreatedAt:   now,
+			StartedAt:   now,
+			OwnerPID:    os.Getpid(),
+			Owner:       a.owner,
+		}
+		if kind == models.JobKindRestore {
+			job.DestProfileID = profile.ID
+			job.RecordID = opts.recordID
+			job.Tables = opts.tables
+			job.PointInTime = opts.pointInTime
+			job.SearchReplace = opts.replace
+			job.Definer = opts.definer
+			job.TargetDB = opts.targetDB
+			job.Parallel = opts.parallel
+		}
+		a.jobs = append(a.jobs, job)
+	}
+	jobs := append([]models.JobRecord(nil), a.jobs...)
+	a.mu.Unlock()
+	if err := a.store.SaveJobs(jobs); err != nil {
+		log.Printf("app.startJob: SaveJobs failed: %v", err)
+	}
+	return jobID
+}
+
+// finishJob records the outcome of a job started with startJob.
+func (a *App) finishJob(jobID string, status string, err error) {
+	if uerr := a.updateJob(jobID, func(job *models.JobRecord) {
+		job.Status = status
+		job.FinishedAt = time.Now()
+		if err != nil {
+			job.Error = err.Error()
+		}
+	}); uerr != nil {
+		log.Printf("app.finishJob: %v", uerr)
+	}
+}
+
+func (a *App) updateJob(jobID string, update func(*models.JobRecord)) error {
+	a.mu.Lock()
+	found := false
+	for i := range a.jobs {
+		if a.jobs[i].ID == jobID {
+			update(&a.jobs[i])
+			found = true
+			break
+		}
+	}
+	if !found {
+		a.mu.Unlock()
+		return fmt.Errorf("job not found")
+	}
+	a.jobs = trimFinishedJobs(a.jobs)
+	jobs := append([]models.JobRecord(nil), a.jobs...)
+	a.mu.Unlock()
+	return a.store.SaveJobs(jobs)
+}
+
+// markInterruptedJobs flags jobs that were still running when the process running them
+// exited. Jobs another live process (the desktop app, a CLI run) is running are left alone.
+func (a *App) markInterruptedJobs() error {
+	a.mu.Lock()
+	changed := false
+	for i := range a.jobs {
+		if a.jobs[i].Unfinished() && a.jobOwnerGone(a.jobs[i]) {
+			a.jobs[i].Status = models.JobInterrupted
+			changed = true
+		}
+	}
+	jobs := append([]models.JobRecord(nil), a.jobs...)
+	a.mu.Unlock()
+	if !changed {
+		return nil
+	}
+	return a.store.SaveJobs(jobs)
+}
+
+// jobOwnerGone reports whether the process that ran job has exited. A job recorded under
+// this process's ID by another App belongs to an earlier process whose ID was reused; jobs
+// saved before owners were recorded have none.
+func (a *App) jobOwnerGone(job models.JobRecord) bool {
+	switch {
+	case job.OwnerPID <= 0:
+		return true
+	case job.OwnerPID == os.Getpid():
+		return job.Owner != a.owner
+	default:
+		return !processAlive(job.OwnerPID)
+	}
+}
+
+func (a *App) jobByID(id string) (models.JobRecord, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, job := range a.jobs {
+		if job.ID == id {
+			return job, true
+		}
+	}
+	return models.JobRecord{}, false
+}
+
+func (a *App) profileByID(id string) (models.Profile, bool) {
+	for _, p := range a.Profiles() {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return models.Profile{}, false
+}
+
+// jobStatusFor maps an operation error to the stored job status.
+func jobStatusFor(err error) string {
+	switch {
+	case err == nil:
+		return models.JobSucceeded
+	case errors.Is(err, context.Canceled):
+		return models.JobCanceled
+	default:
+		return models.JobFailed
+	}
+}
+
+// trimFinishedJobs drops the oldest finished jobs beyond maxFinishedJobs.
+func trimFinishedJobs(jobs []models.JobRecord) []models.JobRecord {
+	finished := 0
+	for _, job := range jobs {
+		if !job.Unfinished() && job.Status != models.JobInterrupted {
+			finished++
+		}
+	}
+	if finished <= maxFinishedJobs {
+		return jobs
+	}
+	drop := finished - maxFinishedJobs
+	kept := jobs[:0]
+	for _, job := range jobs {
+		if drop > 0 && !job.Unfinished() && job.Status != models.JobInterrupted {
+			drop--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	return kept
+}
@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"testing"
+
+	"dback/models"
+)
+
+func TestFailedBackupRecordsJob(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	p := models.Profile{ID: "p1", Name: "Prod", ConnectionType: models.ConnectionTypeSSH, Destination: t.TempDir()}
+	if err := a.SaveProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Backup(context.Background(), p, nil); err == nil {
+		t.Fatal("expected backup without a DB user to fail")
+	}
+	jobs := a.Jobs()
+	if len(jobs) != 1 {
+		t.Fatalf("expected 1 job, got %d", len(jobs))
+	}
+	job := jobs[0]
+	if job.Kind != models.JobKindBackup || job.Status != models.JobFailed || job.Error == "" || job.Attempts != 1 {
+		t.Fatalf("unexpected job: %+v", job)
+	}
+
+	if _, err := a.RetryJob(context.Background(), job.ID, nil); err == nil {
+		t.Fatal("expected retry to fail again")
+	}
+	if jobs := a.Jobs(); len(jobs) != 1 || jobs[0].Attempts != 2 {
+		t.Fatalf("retry must reuse the job: %+v", jobs)
+	}
+}
+
+func TestUnlockMarksRunningJobsInterrupted(t *testing.T) {
+	dir := t.TempDir()
+	a := openApp(t, dir)
+	p := models.Profile{ID: "p1", Name: "Prod"}
+	if err := a.SaveProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	jobID := a.startJob(runOptions{}, "op1", models.JobKindBackup, p)
+
+	reopened := openApp(t, dir)
+	interrupted := reopened.InterruptedJobs()
+	if len(interrupted) != 1 || interrupted[0].ID != jobID || interrupted[0].OperationID != "op1" {
+		t.Fatalf("expected the running job to be interrupted, got %+v", interrupted)
+	}
+	if reopened.CanResumeJob(interrupted[0]) {
+		t.Fatal("no partial download exists, job must not be resumable")
+	}
+
+	if err := reopened.DismissJob(jobID); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(openApp(t, dir).InterruptedJobs()); got != 0 {
+		t.Fatalf("dismissed job still interrupted: %d", got)
+	}
+}
+
+func TestUnlockLeavesJobsOfLiveProcesses(t *testing.T) {
+	exited := exec.Command(os.Args[0], "-test.run=^$")
+	if err := exited.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	a := openApp(t, dir)
+	if err := a.store.SaveJobs([]models.JobRecord{
+		{ID: "other", Status: models.JobRunning, OwnerPID: os.Getppid(), Owner: "desktop"},
+		{ID: "exited", Status: models.JobRunning, OwnerPID: exited.Process.Pid, Owner: "cli"},
+		{ID: "legacy", Status: models.JobRunning},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := map[string]string{}
+	for _, job := range openApp(t, dir).Jobs() {
+		got[job.ID] = job.Status
+	}
+	if got["other"] != models.JobRunning {
+		t.Fatalf("a job of a running process was marked %q", got["other"])
+	}
+	if got["exited"] != models.JobInterrupted || got["legacy"] != models.JobInterrupted {
+		t.Fatalf("jobs of exited processes: %v", got)
+	}
+}
+
+func TestTrimFinishedJobsKeepsUnfinished(t *testing.T) {
+	var jobs []models.JobRecord
+	jobs = append(jobs, models.JobRecord{ID: "interrupted", Status: models.JobInterrupted})
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		jobs = append(jobs, models.JobRecord{Status: models.JobSucceeded})
+	}
+	jobs = append(jobs, models.JobRecord{ID: "running", Status: models.JobRunning})
+
+	trimmed := trimFinishedJobs(jobs)
+	if len(trimmed) != maxFinishedJobs+2 {
+		t.Fatalf("expected %d jobs, got %d", maxFinishedJobs+2, len(trimmed))
+	}
+	if trimmed[0].ID != "interrupted" || trimmed[len(trimmed)-1].ID != "running" {
+		t.Fatal("unfinished and interrupted jobs must be kept")
+	}
+}
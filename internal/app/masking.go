@@ -0,0 +1,39 @@
+package app
+
+import (
+	"fmt"
+
+	"dback/backend/mask"
+	"dback/backend/transfer"
+	"dback/models"
+)
+
+// checkMaskedRestore rejects restores to a host with masking rules that would put rows on it
+// without passing them through the masking filter.
+func checkMaskedRestore(record models.ExportRecord, destination models.Profile, opts runOptions) error {
+	if len(destination.Masking) == 0 {
+		return nil
+	}
+	switch {
+	case record.Physical():
+		return fmt.Errorf("host %q masks restored data; a physical backup copies data files and cannot be masked", destination.Name)
+	case opts.pointInTime != nil:
+		return fmt.Errorf("host %q masks restored data; binary log events cannot be masked, so restore without a point in time", destination.Name)
+	}
+	return nil
+}
+
+// PreviewMasking reports which columns of a backup a restore to destination would mask,
+// and any rule that could not be applied, without restoring anything.
+func (a *App) PreviewMasking(record models.ExportRecord, destination models.Profile) (mask.Report, error) {
+	if len(destination.Masking) == 0 {
+		return mask.Report{}, fmt.Errorf("host %q has no masking rules", destination.Name)
+	}
+	if record.Physical() {
+		return mask.Report{}, fmt.Errorf("a physical backup copies data files and cannot be masked")
+	}
+	if record.PostgreSQL() {
+		return mask.Report{}, fmt.Errorf("a PostgreSQL backup holds rows as COPY data and cannot be masked")
+	}
+	return transfer.PreviewMasking(record.FilePath, a.decryptionKeys(record), destination.Masking)
+}
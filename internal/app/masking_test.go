@@ -0,0 +1,45 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func TestCheckMaskedRestore(t *testing.T) {
+	dest := models.Profile{Name: "Staging", Masking: []models.MaskRule{{Table: "users", Column: "email", Strategy: models.MaskFakeEmail}}}
+	logical := models.ExportRecord{}
+	if err := checkMaskedRestore(logical, dest, runOptions{}); err != nil {
+		t.Fatalf("logical restore: %v", err)
+	}
+	if err := checkMaskedRestore(logical, dest, runOptions{tables: &models.TableSelection{Tables: []string{"users"}}}); err != nil {
+		t.Fatalf("table restore: %v", err)
+	}
+	at := time.Now()
+	if err := checkMaskedRestore(logical, dest, runOptions{pointInTime: &at}); err == nil {
+		t.Fatal("point-in-time restore would replay unmasked binlog events")
+	}
+	if err := checkMaskedRestore(models.ExportRecord{Type: models.BackupTypePhysical}, dest, runOptions{}); err == nil {
+		t.Fatal("physical restore would copy unmasked data files")
+	}
+	if err := checkMaskedRestore(models.ExportRecord{Type: models.BackupTypePhysical}, models.Profile{}, runOptions{}); err != nil {
+		t.Fatalf("host without rules: %v", err)
+	}
+}
+
+func TestSaveProfileValidatesMaskingRules(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	p := models.Profile{ID: "p1", Name: "Staging", Masking: []models.MaskRule{{Table: "users", Column: "email", Strategy: "scramble"}}}
+	if err := a.SaveProfile(p); err == nil || !strings.Contains(err.Error(), "unknown strategy") {
+		t.Fatalf("SaveProfile = %v, want unknown strategy", err)
+	}
+	p.Masking[0].Strategy = models.MaskKeepDomain
+	if err := a.SaveProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	if got := a.Profiles()[0].Masking; len(got) != 1 || got[0].Strategy != models.MaskKeepDomain {
+		t.Fatalf("masking = %+v", got)
+	}
+}
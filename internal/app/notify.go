@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"dback/internal/notify"
+	"dback/models"
+)
+
+// notifyTimeout bounds one delivery so an unreachable webhook or mail server cannot hang around.
+const notifyTimeout = 30 * time.Second
+
+// NotificationSinks returns the configured notification sinks.
+func (a *App) NotificationSinks() ([]models.NotificationSink, error) {
+	return a.store.LoadNotificationSinks()
+}
+
+// SaveNotificationSink validates and adds or replaces a sink; a new sink gets an ID.
+func (a *App) SaveNotificationSink(sink models.NotificationSink) (models.NotificationSink, error) {
+	sink.Name = strings.TrimSpace(sink.Name)
+	sink.Group = strings.TrimSpace(sink.Group)
+	if err := notify.Validate(sink); err != nil {
+		return sink, err
+	}
+	sinks, err := a.store.LoadNotificationSinks()
+	if err != nil {
+		return sink, err
+	}
+	if sink.ID == "" {
+		sink.ID = newID()
+	}
+	replaced := false
+	for i := range sinks {
+		if sinks[i].ID == sink.ID {
+			sinks[i] = sink
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		sinks = append(sinks, sink)
+	}
+	return sink, a.store.SaveNotificationSinks(sinks)
+}
+
+// DeleteNotificationSink removes a sink by ID.
+func (a *App) DeleteNotificationSink(id string) error {
+	sinks, err := a.store.LoadNotificationSinks()
+	if err != nil {
+		return err
+	}
+	kept := sinks[:0]
+	for _, sink := range sinks {
+		if sink.ID != id {
+			kept = append(kept, sink)
+		}
+	}
+	return a.store.SaveNotificationSinks(kept)
+}
+
+// TestNotificationSink sends a sample event to a sink, whatever its rule says.
+func (a *App) TestNotificationSink(ctx context.Context, sink models.NotificationSink) error {
+	ctx, cancel := context.WithTimeout(ctx, notifyTimeout)
+	defer cancel()
+	now := time.Now()
+	return notify.Send(ctx, sink, notify.Event{
+		Type:        notify.EventBackup,
+		Status:      notify.StatusSuccess,
+		OperationID: "test",
+		ProfileName: "DBack test notification",
+		StartedAt:   now,
+		FinishedAt:  now,
+	})
+}
+
+// notify sends a finished operation to every matching sink in the background. Canceled
+// operations are not reported. Delivery results are written to the activity log.
+func (a *App) notify(eventType, operationID string, profile models.Profile, trigger string, started time.Time, record *models.ExportRecord, opErr error) {
+	if errors.Is(opErr, context.Canceled) {
+		return
+	}
+	sinks, err := a.store.LoadNotificationSinks()
+	if err != nil || len(sinks) == 0 {
+		return
+	}
+	event := notify.Event{
+		Type:        eventType,
+		Status:      notify.StatusSuccess,
+		OperationID: operationID,
+		ProfileID:   profile.ID,
+		ProfileName: profile.Name,
+		Group:       normalizeGroup(profile.Group),
+		Trigger:     trigger,
+		StartedAt:   started,
+		FinishedAt:  time.Now(),
+		Record:      record,
+	}
+	if opErr != nil {
+		event.Status = notify.StatusFailure
+		event.Error = opErr.Error()
+	}
+	var matched []models.NotificationSink
+	for _, sink := range sinks {
+		if notify.Matches(sink, event) {
+			matched = append(matched, sink)
+		}
+	}
+	if len(matched) == 0 {
+		return
+	}
+	for _, entry := range a.Logs() {
+		if entry.OperationID == operationID {
+			event.Logs = append(event.Logs, entry)
+		}
+	}
+
+	for _, sink := range matched {
+		a.notifyWG.Add(1)
+		go func(sink models.NotificationSink) {
+			defer a.notifyWG.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+			defer cancel()
+			details := fmt.Sprintf("%s notification %q", sink.Kind, sink.Name)
+			if err := notify.Send(ctx, sink, event); err != nil {
+				a.logPhase(operationID, &profile, "Notify", "send", sink.Kind, 0, "Could not send "+details, "Warning", "Failed", err.Error())
+				return
+			}
+			a.logPhase(operationID, &profile, "Notify", "send", sink.Kind, 0, "Sent "+details, "Info", "Succeeded", "")
+		}(sink)
+	}
+}
+
+// WaitNotifications waits for the notifications still being sent, at most notifyTimeout,
+// which bounds each delivery too. It reports whether all of them finished; a process that
+// exits after an operation calls it first so failure alerts are not lost.
+func (a *App) WaitNotifications() bool {
+	done := make(chan struct{})
+	go func() {
+		a.notifyWG.Wait()
+		close(done)
+	}()
+	timer := time.NewTimer(notifyTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
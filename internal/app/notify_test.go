@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"dback/internal/notify"
+	"dback/models"
+)
+
+func TestFailedBackupSendsNotification(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	var mu sync.Mutex
+	var events []notify.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		_ = json.NewDecoder(r.Body).Decode(&e)
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	p := models.Profile{ID: "p1", Name: "Prod", Group: "Gold", ConnectionType: models.ConnectionTypeSSH, Destination: t.TempDir()}
+	if err := a.SaveProfile(p); err != nil {
+		t.Fatal(err)
+	}
+	for _, sink := range []models.NotificationSink{
+		{Name: "on-call", Kind: models.NotifyWebhook, Enabled: true, On: models.NotifyOnFailure, Group: "Gold", WebhookURL: srv.URL},
+		{Name: "success only", Kind: models.NotifyWebhook, Enabled: true, On: models.NotifyOnSuccess, WebhookURL: srv.URL},
+	} {
+		if _, err := a.SaveNotificationSink(sink); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := a.Backup(context.Background(), p, nil); err == nil {
+		t.Fatal("expected backup without a DB user to fail")
+	}
+	if !a.WaitNotifications() {
+		t.Fatal("notifications still in flight")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(events))
+	}
+	e := events[0]
+	if e.Type != notify.EventBackup || e.Status != notify.StatusFailure || e.ProfileName != "Prod" || e.Error == "" || len(e.Logs) == 0 {
+		t.Fatalf("unexpected event: %+v", e)
+	}
+	sent := false
+	for _, entry := range a.Logs() {
+		if entry.Action == "Notify" && entry.Status == "Succeeded" {
+			sent = true
+		}
+	}
+	if !sent {
+		t.Fatal("expected a Notify log entry")
+	}
+}
+
+func TestNotificationSinkCRUD(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	if _, err := a.SaveNotificationSink(models.NotificationSink{Name: "bad", Kind: models.NotifyWebhook, On: models.NotifyOnBoth}); err == nil {
+		t.Fatal("expected validation error")
+	}
+	sink, err := a.SaveNotificationSink(models.NotificationSink{Name: " cmd ", Kind: models.NotifyCommand, On: models.NotifyOnBoth, Command: "true"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sink.ID == "" || sink.Name != "cmd" {
+		t.Fatalf("unexpected saved sink: %+v", sink)
+	}
+	sink.Command = "false"
+	if _, err := a.SaveNotificationSink(sink); err != nil {
+		t.Fatal(err)
+	}
+	sinks, _ := a.NotificationSinks()
+	if len(sinks) != 1 || sinks[0].Command != "false" {
+		t.Fatalf("expected the sink to be replaced: %+v", sinks)
+	}
+	if err := a.DeleteNotificationSink(sink.ID); err != nil {
+		t.Fatal(err)
+	}
+	if sinks, _ := a.NotificationSinks(); len(sinks) != 0 {
+		t.Fatalf("expected no sinks, got %d", len(sinks))
+	}
+}
@@ -0,0 +1,49 @@
+package app
+
+import (
+	"fmt"
+
+	"dback/models"
+)
+
+// ValidatePhysicalBackup checks that a host's other backup settings fit a physical backup,
+// which always copies the whole server.
+func ValidatePhysicalBackup(p models.Profile) error {
+	if !p.PhysicalBackup {
+		return nil
+	}
+	switch {
+	case p.UsesWordPress():
+		return fmt.Errorf("physical backups need an SSH or Localhost host")
+	case p.Databases.Active():
+		return fmt.Errorf("a physical backup copies every database; turn off multi-database backups")
+	case p.Tables.Active():
+		return fmt.Errorf("a physical backup copies every table; clear the table filters")
+	case p.DumpFormat == models.DumpFormatSplit, p.DumpFormat == models.DumpFormatRepository:
+		return fmt.Errorf("physical backups are not SQL dumps; use the single-file dump format")
+	case p.Binlog.Active():
+		return fmt.Errorf("incremental binlog backups build on logical dumps; turn them off for physical backups")
+	}
+	return nil
+}
+
+// checkPhysicalRestore rejects restore options a physical backup cannot honour.
+func checkPhysicalRestore(destination models.Profile, opts runOptions) error {
+	switch {
+	case destination.UsesWordPress():
+		return fmt.Errorf("a physical backup can only be restored to an SSH or Localhost host")
+	case opts.tables.Active():
+		return fmt.Errorf("a physical backup restores the whole server; tables cannot be picked")
+	case opts.pointInTime != nil:
+		return fmt.Errorf("point-in-time restore needs a logical backup")
+	case len(opts.replace) > 0:
+		return fmt.Errorf("search/replace needs a logical backup")
+	case opts.definer != models.DefinerKeep:
+		return fmt.Errorf("DEFINER rewriting needs a logical backup")
+	case opts.parallel > 1:
+		return fmt.Errorf("a physical backup is copied back whole; it has no tables to import in parallel")
+	case opts.targetDB != "":
+		return fmt.Errorf("a physical backup restores the whole server; it cannot be restored under a new database name")
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func TestValidatePhysicalBackup(t *testing.T) {
+	p := models.Profile{ConnectionType: models.ConnectionTypeSSH, PhysicalBackup: true}
+	if err := ValidatePhysicalBackup(p); err != nil {
+		t.Fatalf("plain SSH host: %v", err)
+	}
+	for name, mod := range map[string]func(*models.Profile){
+		"wordpress":  func(p *models.Profile) { p.ConnectionType = models.ConnectionTypeWordPress },
+		"split":      func(p *models.Profile) { p.DumpFormat = models.DumpFormatSplit },
+		"repository": func(p *models.Profile) { p.DumpFormat = models.DumpFormatRepository },
+		"tables":     func(p *models.Profile) { p.Tables = &models.TableFilter{Exclude: []string{"log_*"}} },
+		"binlog":     func(p *models.Profile) { p.Binlog = &models.BinlogSettings{Enabled: true} },
+	} {
+		q := p
+		mod(&q)
+		if err := ValidatePhysicalBackup(q); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckPhysicalRestoreRejectsPartialRestores(t *testing.T) {
+	dest := models.Profile{ConnectionType: models.ConnectionTypeSSH}
+	if err := checkPhysicalRestore(dest, runOptions{}); err != nil {
+		t.Fatalf("whole restore: %v", err)
+	}
+	at := time.Now()
+	if err := checkPhysicalRestore(dest, runOptions{pointInTime: &at}); err == nil {
+		t.Fatal("point-in-time restore of a physical backup should fail")
+	}
+	sel := models.TableSelection{Tables: []string{"orders"}}
+	if err := checkPhysicalRestore(dest, runOptions{tables: &sel}); err == nil {
+		t.Fatal("table restore of a physical backup should fail")
+	}
+	if err := checkPhysicalRestore(dest, runOptions{parallel: 4}); err == nil {
+		t.Fatal("parallel restore of a physical backup should fail")
+	}
+}
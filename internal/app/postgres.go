@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+
+	"dback/models"
+)
+
+// ValidatePostgreSQL checks that a PostgreSQL host only uses features pg_dump and psql can
+// serve; the rest read or write MySQL dumps and servers.
+func ValidatePostgreSQL(p models.Profile) error {
+	if !p.UsesPostgreSQL() {
+		return nil
+	}
+	switch {
+	case p.UsesWordPress():
+		return fmt.Errorf("WordPress hosts run on MySQL or MariaDB")
+	case p.PhysicalBackup:
+		return fmt.Errorf("physical backups use mariadb-backup or xtrabackup; PostgreSQL hosts take pg_dump backups")
+	case p.Binlog.Active():
+		return fmt.Errorf("incremental binlog backups need MySQL or MariaDB")
+	case p.DumpFormat == models.DumpFormatSplit:
+		return fmt.Errorf("the split dump format reads mysqldump output; use the single-file or repository format")
+	case p.Tables != nil && len(p.Tables.Rows) > 0:
+		return fmt.Errorf("pg_dump cannot filter rows; PostgreSQL hosts support include, exclude and schema-only table filters")
+	case len(p.Masking) > 0:
+		return fmt.Errorf("masking rewrites mysqldump INSERT statements; PostgreSQL hosts cannot mask restored data")
+	}
+	return nil
+}
+
+// checkPostgresRestore rejects restores across server types and the restore options and
+// host masking rules that rewrite mysqldump output.
+func checkPostgresRestore(record models.ExportRecord, destination models.Profile, opts runOptions) error {
+	switch {
+	case record.PostgreSQL() && !destination.UsesPostgreSQL():
+		return fmt.Errorf("a PostgreSQL backup can only be restored to a PostgreSQL host")
+	case !record.PostgreSQL() && destination.UsesPostgreSQL():
+		return fmt.Errorf("a MySQL backup can only be restored to a MySQL or MariaDB host")
+	case !record.PostgreSQL():
+		return nil
+	case opts.tables.Active():
+		return fmt.Errorf("PostgreSQL backups restore whole; tables cannot be picked")
+	case len(opts.replace) > 0:
+		return fmt.Errorf("search/replace rewrites mysqldump output and cannot be used on PostgreSQL backups")
+	case opts.definer != models.DefinerKeep:
+		return fmt.Errorf("PostgreSQL backups are dumped without owners; there are no DEFINER clauses to rewrite")
+	case opts.pointInTime != nil:
+		return fmt.Errorf("point-in-time restore needs MySQL binary logs")
+	case opts.parallel > 1:
+		return fmt.Errorf("parallel restore splits mysqldump output and cannot be used on PostgreSQL backups")
+	case len(destination.Masking) > 0:
+		return fmt.Errorf("host %q masks restored data; pg_dump writes rows as COPY data, which masking cannot rewrite, so remove its masking rules to restore", destination.Name)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func TestValidatePostgreSQL(t *testing.T) {
+	p := models.Profile{
+		ConnectionType: models.ConnectionTypeSSH,
+		DBType:         models.DBTypePostgreSQL,
+		DumpFormat:     models.DumpFormatRepository,
+		Tables:         &models.TableFilter{Exclude: []string{"log_*"}, SchemaOnly: []string{"sessions"}},
+	}
+	if err := ValidatePostgreSQL(p); err != nil {
+		t.Fatalf("pg_dump host with table filters: %v", err)
+	}
+	for name, mod := range map[string]func(*models.Profile){
+		"wordpress": func(p *models.Profile) { p.ConnectionType = models.ConnectionTypeWordPress },
+		"physical":  func(p *models.Profile) { p.PhysicalBackup = true },
+		"binlog":    func(p *models.Profile) { p.Binlog = &models.BinlogSettings{Enabled: true} },
+		"split":     func(p *models.Profile) { p.DumpFormat = models.DumpFormatSplit },
+		"rows": func(p *models.Profile) {
+			p.Tables = &models.TableFilter{Rows: []models.RowFilter{{Table: "orders", Where: "id > 1"}}}
+		},
+		"masking": func(p *models.Profile) { p.Masking = []models.MaskRule{{Table: "users", Column: "email"}} },
+	} {
+		q := p
+		mod(&q)
+		if err := ValidatePostgreSQL(q); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCheckPostgresRestore(t *testing.T) {
+	pgRecord := models.ExportRecord{DBType: models.DBTypePostgreSQL}
+	pgHost := models.Profile{ConnectionType: models.ConnectionTypeSSH, DBType: models.DBTypePostgreSQL}
+	mysqlHost := models.Profile{ConnectionType: models.ConnectionTypeSSH, DBType: models.DBTypeMySQL}
+
+	if err := checkPostgresRestore(pgRecord, pgHost, runOptions{}); err != nil {
+		t.Fatalf("whole restore: %v", err)
+	}
+	if err := checkPostgresRestore(models.ExportRecord{}, mysqlHost, runOptions{}); err != nil {
+		t.Fatalf("MySQL restore: %v", err)
+	}
+	if err := checkPostgresRestore(pgRecord, mysqlHost, runOptions{}); err == nil {
+		t.Fatal("a PostgreSQL backup must not restore into MySQL")
+	}
+	if err := checkPostgresRestore(models.ExportRecord{}, pgHost, runOptions{}); err == nil {
+		t.Fatal("a MySQL backup must not restore into PostgreSQL")
+	}
+	sel := models.TableSelection{Tables: []string{"orders"}}
+	at := time.Now()
+	for name, opts := range map[string]runOptions{
+		"tables":   {tables: &sel},
+		"replace":  {replace: []models.ReplacePair{{From: "a", To: "b"}}},
+		"pitr":     {pointInTime: &at},
+		"definer":  {definer: models.DefinerStrip},
+		"parallel": {parallel: 4},
+	} {
+		if err := checkPostgresRestore(pgRecord, pgHost, opts); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+
+	// pg_dump rows would reach the host unmasked.
+	masked := pgHost
+	masked.Masking = []models.MaskRule{{Table: "users", Column: "email", Strategy: models.MaskFakeEmail}}
+	if err := checkPostgresRestore(pgRecord, masked, runOptions{}); err == nil {
+		t.Fatal("a PostgreSQL restore to a masking host must fail")
+	}
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"dback/backend/chunkstore"
+	"dback/internal/paths"
+	"dback/models"
+)
+
+// repositoryDirs returns the chunk repositories of the default backup destination and of
+// every host's own destination, where one exists.
+func (a *App) repositoryDirs() []string {
+	seen := map[string]bool{}
+	var dirs []string
+	add := func(destination string) {
+		root := chunkstore.RepositoryDir(paths.EffectiveBackupDestination(destination))
+		if seen[root] || !chunkstore.Exists(root) {
+			return
+		}
+		seen[root] = true
+		dirs = append(dirs, root)
+	}
+	add("")
+	for _, p := range a.Profiles() {
+		add(p.Destination)
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// RepositoryStats reads every chunk repository: how much the backups in it would take
+// stored in full, how much deduplication saves and how much space pruning would reclaim.
+func (a *App) RepositoryStats(ctx context.Context) ([]chunkstore.Stats, error) {
+	var stats []chunkstore.Stats
+	var errs []error
+	for _, root := range a.repositoryDirs() {
+		if err := ctx.Err(); err != nil {
+			return stats, err
+		}
+		s, err := chunkstore.ReadStats(root)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", root, err))
+			continue
+		}
+		stats = append(stats, s)
+	}
+	return stats, errors.Join(errs...)
+}
+
+// PruneRepository deletes the chunks of the repository at root that no backup uses any more.
+func (a *App) PruneRepository(ctx context.Context, root string) (chunkstore.PruneResult, error) {
+	if err := ctx.Err(); err != nil {
+		return chunkstore.PruneResult{}, err
+	}
+	known := false
+	for _, dir := range a.repositoryDirs() {
+		known = known || dir == root
+	}
+	if !known {
+		return chunkstore.PruneResult{}, fmt.Errorf("no backup repository at %s", root)
+	}
+	return a.pruneRepository(newID(), models.Profile{}, root)
+}
+
+// pruneRepository prunes root and logs the outcome under operationID.
+func (a *App) pruneRepository(operationID string, profile models.Profile, root string) (chunkstore.PruneResult, error) {
+	res, err := chunkstore.Prune(root)
+	if errors.Is(err, chunkstore.ErrInUse) {
+		a.logPhaseWithFile(operationID, profile, "Repository", "prune", "", 0, "Repository in use; unused chunks are kept until the next prune", "Info", "Skipped", err.Error(), root, 0)
+		return res, err
+	}
+	if err != nil {
+		a.logPhaseWithFile(operationID, profile, "Repository", "prune", "", 0, "Could not prune repository", "Error", "Failed", err.Error(), root, 0)
+		return res, err
+	}
+	a.logPhaseWithFile(operationID, profile, "Repository", "prune", "", 0, fmt.Sprintf("Deleted %d unused chunk(s), freed %s", res.Chunks, formatSize(res.FreedBytes)), "Info", "Succeeded", "", root, 0)
+	return res, nil
+}
+
+// diskBytes is what deleting a backup frees: the file, or for a repository backup the
+// chunks it added, which later backups may still share.
+func diskBytes(rec models.ExportRecord) int64 {
+	if chunkstore.IsManifestPath(rec.FilePath) {
+		return rec.AddedBytes
+	}
+	return rec.FileSizeBytes
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"dback/backend/chunkstore"
+	"dback/models"
+)
+
+func TestRetentionPrunesRepository(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	dest := t.TempDir()
+	if err := a.SaveProfile(models.Profile{ID: "p1", Name: "Prod", Destination: dest, DumpFormat: models.DumpFormatRepository, Retention: &models.RetentionPolicy{KeepLast: 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	var history []models.ExportRecord
+	for i := 0; i < 2; i++ {
+		manifest := filepath.Join(dest, "Prod", fmt.Sprintf("shop_%d%s", i, chunkstore.ManifestExt))
+		dump := fmt.Sprintf("CREATE TABLE t (id int);\nINSERT INTO t VALUES (%d);\n", i)
+		_, res, err := chunkstore.Store(strings.NewReader(dump), manifest, chunkstore.RepositoryDir(dest), chunkstore.Manifest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		history = append(history, models.ExportRecord{
+			ID:            fmt.Sprintf("r%d", i),
+			ProfileID:     "p1",
+			ProfileName:   "Prod",
+			ExportDate:    now.Add(-time.Duration(i) * time.Hour),
+			FilePath:      manifest,
+			FileSizeBytes: int64(len(dump)),
+			AddedBytes:    res.AddedBytes,
+		})
+	}
+	a.history = history
+	if err := a.store.SaveHistory(history); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := a.RepositoryStats(context.Background())
+	if err != nil || len(stats) != 1 || stats[0].Backups != 2 || stats[0].Chunks != 2 {
+		t.Fatalf("stats = %+v, %v", stats, err)
+	}
+
+	report, err := a.ApplyRetention(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 1 || report.FreedBytes != history[1].AddedBytes {
+		t.Fatalf("report = %+v, want the older backup's chunk freed", report)
+	}
+	stats, _ = a.RepositoryStats(context.Background())
+	if stats[0].Backups != 1 || stats[0].Chunks != 1 || stats[0].UnusedChunks != 0 {
+		t.Fatalf("stats after retention = %+v", stats)
+	}
+
+	if _, err := a.PruneRepository(context.Background(), filepath.Join(dest, "elsewhere")); err == nil {
+		t.Fatal("expected an unknown repository to be refused")
+	}
+}
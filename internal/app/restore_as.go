@@ -0,0 +1,118 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"dback/backend/db"
+	"dback/models"
+)
+
+// prepareRestoreAs checks a restore under a new database name before anything runs on the
+// destination. The name must differ from the host's configured database and, on a first
+// attempt, must not exist yet: the import drops and recreates it. A retried job owns the
+// database its earlier attempt created, so the check is skipped then. WordPress hosts import
+// through the plugin, which does not create databases, so the database is created here.
+func (a *App) prepareRestoreAs(ctx context.Context, operationID string, destination models.Profile, opts runOptions) error {
+	name := opts.targetDB
+	if strings.EqualFold(name, strings.TrimSpace(destination.TargetDBName)) {
+		return fmt.Errorf("%q is the host's configured database; restore without a new name to replace it", name)
+	}
+	if opts.jobID == "" {
+		exists, err := a.databaseExists(ctx, destination, name)
+		if err != nil {
+			return fmt.Errorf("check database %q: %w", name, err)
+		}
+		if exists {
+			return fmt.Errorf("database %q already exists on %s; pick a new name", name, destination.Name)
+		}
+	}
+	if destination.UsesWordPress() {
+		sql := db.CreateDatabaseSQL(destination, name)
+		if opts.jobID != "" {
+			sql = db.DropDatabaseSQL(destination, name) + " " + sql
+		}
+		if _, err := a.RunImportQuery(ctx, destination, sql, false); err != nil {
+			return fmt.Errorf("create database %q: %w", name, err)
+		}
+	}
+	a.logPhase(operationID, &destination, "Import", "restore-as", "", 0,
+		fmt.Sprintf("Restoring into new database %s; %s is left untouched and its pre- and post-import queries are skipped", name, destination.TargetDBName), "Info", "Started", "")
+	return nil
+}
+
+// databaseExists reports whether the destination server has a database called name.
+func (a *App) databaseExists(ctx context.Context, profile models.Profile, name string) (bool, error) {
+	result, err := a.RunImportQuery(ctx, profile, db.ListDatabasesQuery(profile), false)
+	if err != nil {
+		return false, err
+	}
+	for _, row := range result.Rows {
+		if len(row) > 0 && strings.EqualFold(strings.TrimSpace(row[0]), name) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// recordRestore appends a completed restore to the backup's restore history and logs
+// where it went.
+func (a *App) recordRestore(record models.ExportRecord, destination models.Profile, operationID string, opts runOptions) {
+	entry := models.RestoreEntry{
+		RestoredAt:      time.Now().UTC(),
+		OperationID:     operationID,
+		DestProfileID:   destination.ID,
+		DestProfileName: destination.Name,
+		Database:        destination.TargetDBName,
+		NewDatabase:     opts.targetDB != "",
+	}
+	if entry.NewDatabase {
+		entry.Database = opts.targetDB
+	} else if record.Physical() {
+		entry.Database = ""
+	}
+	a.logPhase(operationID, &destination, "Import", "restored-to", "", 0, restoreEntrySummary(entry), "Info", "Succeeded", "")
+
+	a.mu.Lock()
+	found := false
+	for i := range a.history {
+		if a.history[i].ID == record.ID {
+			a.history[i].Restores = appendRestoreEntry(a.history[i].Restores, entry)
+			found = true
+			break
+		}
+	}
+	if !found {
+		a.mu.Unlock()
+		return
+	}
+	history := append([]models.ExportRecord(nil), a.history...)
+	a.mu.Unlock()
+	if err := a.store.SaveHistory(history); err != nil {
+		log.Printf("app.recordRestore: SaveHistory failed: %v", err)
+	}
+}
+
+// appendRestoreEntry adds entry to a restore history, dropping the oldest entries past
+// models.RestoreHistoryLimit.
+func appendRestoreEntry(restores []models.RestoreEntry, entry models.RestoreEntry) []models.RestoreEntry {
+	restores = append(restores, entry)
+	if n := len(restores) - models.RestoreHistoryLimit; n > 0 {
+		restores = append([]models.RestoreEntry(nil), restores[n:]...)
+	}
+	return restores
+}
+
+// restoreEntrySummary describes where a restore went, for the activity log.
+func restoreEntrySummary(e models.RestoreEntry) string {
+	switch {
+	case e.Database == "":
+		return "Restored the whole server on " + e.DestProfileName
+	case e.NewDatabase:
+		return fmt.Sprintf("Restored into new database %s on %s", e.Database, e.DestProfileName)
+	}
+	return fmt.Sprintf("Restored into database %s on %s", e.Database, e.DestProfileName)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func TestRestoreWithOptionsRejectsTargetDB(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	dest := models.Profile{ID: "staging", Name: "Staging", ConnectionType: models.ConnectionTypeSSH, TargetDBName: "shop"}
+	err := a.RestoreWithOptions(context.Background(), models.ExportRecord{}, dest, RestoreOptions{TargetDB: "shop copy"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid database name") {
+		t.Fatalf("bad name: %v", err)
+	}
+	err = a.prepareRestoreAs(context.Background(), "op", dest, runOptions{targetDB: "SHOP"})
+	if err == nil || !strings.Contains(err.Error(), "configured database") {
+		t.Fatalf("configured database: %v", err)
+	}
+	if err := checkPhysicalRestore(dest, runOptions{targetDB: "shop_copy"}); err == nil {
+		t.Fatal("a physical backup cannot be restored under a new name")
+	}
+}
+
+func TestRecordRestoreAppendsHistory(t *testing.T) {
+	dir := t.TempDir()
+	a := openApp(t, dir)
+	record := models.ExportRecord{ID: "r1", ProfileID: "p1", DatabaseName: "shop"}
+	a.history = []models.ExportRecord{record}
+	dest := models.Profile{ID: "staging", Name: "Staging", TargetDBName: "shop"}
+
+	a.recordRestore(record, dest, "op1", runOptions{})
+	a.recordRestore(record, dest, "op2", runOptions{targetDB: "shop_copy"})
+
+	restores := openApp(t, dir).History()[0].Restores
+	if len(restores) != 2 {
+		t.Fatalf("restores = %+v", restores)
+	}
+	if got := restores[0]; got.Database != "shop" || got.NewDatabase || got.DestProfileName != "Staging" || got.OperationID != "op1" {
+		t.Fatalf("first = %+v", got)
+	}
+	if got := restores[1]; got.Database != "shop_copy" || !got.NewDatabase {
+		t.Fatalf("second = %+v", got)
+	}
+	if got := restoreEntrySummary(restores[1]); got != "Restored into new database shop_copy on Staging" {
+		t.Fatalf("summary = %q", got)
+	}
+}
+
+func TestAppendRestoreEntryKeepsNewest(t *testing.T) {
+	var restores []models.RestoreEntry
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < models.RestoreHistoryLimit+5; i++ {
+		restores = appendRestoreEntry(restores, models.RestoreEntry{RestoredAt: start.Add(time.Duration(i) * time.Hour)})
+	}
+	if len(restores) != models.RestoreHistoryLimit {
+		t.Fatalf("len = %d", len(restores))
+	}
+	if !restores[0].RestoredAt.Equal(start.Add(5 * time.Hour)) {
+		t.Fatalf("oldest kept = %s", restores[0].RestoredAt)
+	}
+}
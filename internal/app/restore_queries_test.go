@@ -4,6 +4,8 @@ import (
 	"compress/gzip"
 	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
@@ -228,3 +230,83 @@ func fileSize(t *testing.T, path string) int64 {
 	}
 	return info.Size()
 }
+
+func TestRestoreTablesSkipsPreImportQuery(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+	var order []string
+	var imported string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/wp-json/dback/v1/preflight/":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+		case "/wp-json/dback/v1/query/":
+			order = append(order, "query")
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "type": "command"})
+		case "/wp-json/dback/v1/import/":
+			order = append(order, "import")
+			if gr, err := gzip.NewReader(r.Body); err == nil {
+				data, _ := io.ReadAll(gr)
+				imported = string(data)
+			}
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "statements_executed": float64(1)})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	var dump strings.Builder
+	dump.WriteString("SET NAMES utf8mb4;\n")
+	for _, table := range []string{"wp_options", "wp_posts"} {
+		fmt.Fprintf(&dump, "DROP TABLE IF EXISTS `%s`;\nCREATE TABLE `%s` (id int);\n", table, table)
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(&dump, "INSERT INTO `%s` VALUES (%d);\n", table, i*7919%1000)
+		}
+	}
+	backupPath := writeTestGzipBackup(t, dump.String())
+
+	a := openApp(t, t.TempDir())
+	if err := a.SaveProfile(models.Profile{
+		ID:             "wp1",
+		Name:           "WP Site",
+		ConnectionType: models.ConnectionTypeWordPress,
+		WPUrl:          server.URL,
+		WPKey:          "secret-key",
+		PreImportQuery: "DROP DATABASE IF EXISTS test; CREATE DATABASE test;",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	record := models.ExportRecord{ID: "r1", FilePath: backupPath, FileSizeBytes: fileSize(t, backupPath)}
+
+	tables, err := a.BackupTables(record)
+	if err != nil || strings.Join(tables, ",") != "wp_options,wp_posts" {
+		t.Fatalf("BackupTables = %v, %v", tables, err)
+	}
+
+	sel := models.TableSelection{Tables: []string{"wp_options"}}
+	if err := a.RestoreTables(context.Background(), record, a.Profiles()[0], sel, nil); err != nil {
+		t.Fatalf("RestoreTables: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(order) != 1 || order[0] != "import" {
+		t.Fatalf("expected only the import call, got %#v", order)
+	}
+	if !strings.Contains(imported, "CREATE TABLE `wp_options`") || strings.Contains(imported, "wp_posts") {
+		t.Fatalf("imported dump = %q", imported)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(backupPath), "backup.tables.sql.gz")); !os.IsNotExist(err) {
+		t.Fatal("the extracted tables file should be removed after restore")
+	}
+	jobs := a.Jobs()
+	if len(jobs) != 1 || jobs[0].Tables == nil || jobs[0].Tables.Tables[0] != "wp_options" {
+		t.Fatalf("job should record the table selection: %+v", jobs)
+	}
+}
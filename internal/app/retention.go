@@ -0,0 +1,270 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"dback/backend/chunkstore"
+	"dback/models"
+)
+
+// RetentionRemoval is a backup pruned by retention (or, on a dry run, one that would be).
+type RetentionRemoval struct {
+	Record models.ExportRecord
+	Reason string
+}
+
+// RetentionReport summarizes one retention pass.
+type RetentionReport struct {
+	DryRun     bool
+	Removed    []RetentionRemoval
+	FreedBytes int64
+}
+
+// GroupRetention returns the retention policy shared by hosts in a group, or nil.
+func (a *App) GroupRetention(group string) *models.RetentionPolicy {
+	return a.store.GroupRetention(normalizeGroup(group))
+}
+
+// SetGroupRetention stores the retention policy for a group; nil removes it.
+func (a *App) SetGroupRetention(group string, policy *models.RetentionPolicy) error {
+	if policy != nil {
+		if err := ValidateRetention(*policy); err != nil {
+			return err
+		}
+	}
+	return a.store.SetGroupRetention(normalizeGroup(group), policy)
+}
+
+// EffectiveRetention returns the policy that applies to a host: its own, else its group's.
+func (a *App) EffectiveRetention(profile models.Profile) (policy *models.RetentionPolicy, fromGroup bool) {
+	if profile.Retention.Active() {
+		return profile.Retention, false
+	}
+	if group := a.GroupRetention(profile.Group); group.Active() {
+		return group, true
+	}
+	return nil, false
+}
+
+// ValidateRetention rejects negative counts.
+func ValidateRetention(p models.RetentionPolicy) error {
+	for _, v := range []int{p.KeepLast, p.KeepDaily, p.KeepWeekly, p.KeepMonthly, p.MaxAgeDays} {
+		if v < 0 {
+			return fmt.Errorf("retention values must not be negative")
+		}
+	}
+	return nil
+}
+
+// ApplyRetention prunes backups of every host with a retention policy. With dryRun it only
+// reports what would be removed.
+func (a *App) ApplyRetention(ctx context.Context, dryRun bool) (RetentionReport, error) {
+	return a.applyRetention(ctx, newID(), dryRun, func(models.Profile) bool { return true })
+}
+
+// ApplyProfileRetention prunes backups of a single host.
+func (a *App) ApplyProfileRetention(ctx context.Context, profileID string, dryRun bool) (RetentionReport, error) {
+	return a.applyRetention(ctx, newID(), dryRun, func(p models.Profile) bool { return p.ID == profileID })
+}
+
+func (a *App) applyRetention(ctx context.Context, operationID string, dryRun bool, match func(models.Profile) bool) (RetentionReport, error) {
+	report := RetentionReport{DryRun: dryRun}
+	byProfile := map[string][]models.ExportRecord{}
+	for _, rec := range a.History() {
+		byProfile[rec.ProfileID] = append(byProfile[rec.ProfileID], rec)
+	}
+
+	profiles := map[string]models.Profile{}
+	for _, p := range a.Profiles() {
+		if !match(p) {
+			continue
+		}
+		policy, _ := a.EffectiveRetention(p)
+		if policy == nil {
+			continue
+		}
+		// Rules count full backups; incrementals go with the full backup their chain starts from.
+		full, incremental := splitIncrementals(byProfile[p.ID])
+		var removals []RetentionRemoval
+		for _, records := range recordsByDatabase(full) {
+			removals = append(removals, planRetention(*policy, records, time.Now())...)
+		}
+		removals = append(removals, orphanedIncrementals(byProfile[p.ID], incremental, removals)...)
+		for _, removal := range removals {
+			report.Removed = append(report.Removed, removal)
+			report.FreedBytes += diskBytes(removal.Record)
+			profiles[removal.Record.ID] = p
+		}
+	}
+	if dryRun || len(report.Removed) == 0 {
+		return report, nil
+	}
+
+	var errs []error
+	removedIDs := map[string]bool{}
+	// Repositories whose backups were deleted are pruned afterwards, once per repository.
+	repositories := map[string]models.Profile{}
+	pruned := report.Removed[:0]
+	report.FreedBytes = 0
+	for _, removal := range report.Removed {
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			break
+		}
+		rec := removal.Record
+		profile := profiles[rec.ID]
+		if chunkstore.IsManifestPath(rec.FilePath) {
+			if m, err := chunkstore.ReadManifest(rec.FilePath); err == nil {
+				repositories[m.Root(rec.FilePath)] = profile
+			}
+		}
+		if err := os.Remove(rec.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			a.logPhaseWithFile(operationID, profile, "Retention", "delete", "", 0, "Could not delete backup file", "Error", "Failed", err.Error(), rec.FilePath, rec.FileSizeBytes)
+			errs = append(errs, fmt.Errorf("delete %s: %w", rec.FilePath, err))
+			continue
+		}
+		removedIDs[rec.ID] = true
+		pruned = append(pruned, removal)
+		if !chunkstore.IsManifestPath(rec.FilePath) {
+			report.FreedBytes += rec.FileSizeBytes
+		}
+		a.logPhaseWithFile(operationID, profile, "Retention", "delete", "", 0, "Deleted backup: "+removal.Reason, "Info", "Succeeded", "", rec.FilePath, rec.FileSizeBytes)
+	}
+	report.Removed = pruned
+	for root, profile := range repositories {
+		// A repository busy with a backup is pruned next time; that is not a retention failure.
+		if res, err := a.pruneRepository(operationID, profile, root); err == nil {
+			report.FreedBytes += res.FreedBytes
+		} else if !errors.Is(err, chunkstore.ErrInUse) {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(removedIDs) > 0 {
+		a.mu.Lock()
+		kept := a.history[:0]
+		for _, rec := range a.history {
+			if !removedIDs[rec.ID] {
+				kept = append(kept, rec)
+			}
+		}
+		a.history = kept
+		history := append([]models.ExportRecord(nil), a.history...)
+		a.mu.Unlock()
+		if err := a.store.SaveHistory(history); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// recordsByDatabase groups a host's records by database, in database name order, so a host
+// that backs up several databases keeps the policy's backups of each one.
+func recordsByDatabase(records []models.ExportRecord) [][]models.ExportRecord {
+	byName := map[string][]models.ExportRecord{}
+	var names []string
+	for _, rec := range records {
+		if _, ok := byName[rec.DatabaseName]; !ok {
+			names = append(names, rec.DatabaseName)
+		}
+		byName[rec.DatabaseName] = append(byName[rec.DatabaseName], rec)
+	}
+	sort.Strings(names)
+	out := make([][]models.ExportRecord, 0, len(names))
+	for _, name := range names {
+		out = append(out, byName[name])
+	}
+	return out
+}
+
+// enforceRetention prunes a host's backups after a successful backup; failures are only logged.
+func (a *App) enforceRetention(ctx context.Context, operationID string, profile models.Profile) {
+	report, err := a.applyRetention(ctx, operationID, false, func(p models.Profile) bool { return p.ID == profile.ID })
+	if err != nil {
+		log.Printf("app.enforceRetention: %q: %v", profile.Name, err)
+	}
+	if n := len(report.Removed); n > 0 {
+		a.logPhase(operationID, &profile, "Retention", "complete", "", 0, fmt.Sprintf("Removed %d old backup(s), freed %s", n, formatSize(report.FreedBytes)), "Info", "Succeeded", "")
+	}
+}
+
+// planRetention returns the records a policy removes, newest first. Keep rules follow the
+// usual GFS semantics: the newest backup of each day, ISO week and month counts toward that tier.
+func planRetention(policy models.RetentionPolicy, records []models.ExportRecord, now time.Time) []RetentionRemoval {
+	if !policy.Active() || len(records) == 0 {
+		return nil
+	}
+	sorted := append([]models.ExportRecord(nil), records...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ExportDate.After(sorted[j].ExportDate) })
+
+	keep := make([]bool, len(sorted))
+	hasKeepRules := policy.KeepLast > 0 || policy.KeepDaily > 0 || policy.KeepWeekly > 0 || policy.KeepMonthly > 0
+	if hasKeepRules {
+		for i := 0; i < len(sorted) && i < policy.KeepLast; i++ {
+			keep[i] = true
+		}
+		keepBuckets(sorted, keep, policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") })
+		keepBuckets(sorted, keep, policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		})
+		keepBuckets(sorted, keep, policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") })
+	}
+
+	cutoff := now.AddDate(0, 0, -policy.MaxAgeDays)
+	var removals []RetentionRemoval
+	for i, rec := range sorted {
+		var reason string
+		switch {
+		case i == 0:
+			continue
+		case policy.MaxAgeDays > 0 && rec.ExportDate.Before(cutoff):
+			reason = fmt.Sprintf("older than %d days", policy.MaxAgeDays)
+		case hasKeepRules && !keep[i]:
+			reason = "not kept by " + describeKeepRules(policy)
+		default:
+			continue
+		}
+		removals = append(removals, RetentionRemoval{Record: rec, Reason: reason})
+	}
+	return removals
+}
+
+// keepBuckets marks the newest record of each of the newest n buckets.
+func keepBuckets(sorted []models.ExportRecord, keep []bool, n int, bucket func(time.Time) string) {
+	if n <= 0 {
+		return
+	}
+	seen := map[string]bool{}
+	for i, rec := range sorted {
+		key := bucket(rec.ExportDate.Local())
+		if seen[key] {
+			continue
+		}
+		if len(seen) == n {
+			return
+		}
+		seen[key] = true
+		keep[i] = true
+	}
+}
+
+func describeKeepRules(p models.RetentionPolicy) string {
+	var parts []string
+	for _, r := range []struct {
+		n    int
+		name string
+	}{{p.KeepLast, "last"}, {p.KeepDaily, "daily"}, {p.KeepWeekly, "weekly"}, {p.KeepMonthly, "monthly"}} {
+		if r.n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", r.n, r.name))
+		}
+	}
+	return "keep " + strings.Join(parts, ", ")
+}
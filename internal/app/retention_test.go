@@ -0,0 +1,189 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func dailyRecords(now time.Time, days int) []models.ExportRecord {
+	var records []models.ExportRecord
+	for i := 0; i < days; i++ {
+		records = append(records, models.ExportRecord{
+			ID:         fmt.Sprintf("r%03d", i),
+			ProfileID:  "p1",
+			ExportDate: now.AddDate(0, 0, -i),
+		})
+	}
+	return records
+}
+
+func removedIDs(removals []RetentionRemoval) map[string]bool {
+	ids := map[string]bool{}
+	for _, r := range removals {
+		ids[r.Record.ID] = true
+	}
+	return ids
+}
+
+func TestPlanRetentionKeepLast(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	removals := planRetention(models.RetentionPolicy{KeepLast: 3}, dailyRecords(now, 10), now)
+	if len(removals) != 7 {
+		t.Fatalf("expected 7 removals, got %d", len(removals))
+	}
+	ids := removedIDs(removals)
+	for _, kept := range []string{"r000", "r001", "r002"} {
+		if ids[kept] {
+			t.Fatalf("%s should be kept", kept)
+		}
+	}
+}
+
+func TestPlanRetentionGFSTiers(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	records := dailyRecords(now, 120)
+	// A second backup on the newest day must not count as another daily slot.
+	records = append(records, models.ExportRecord{ID: "same-day", ProfileID: "p1", ExportDate: now.Add(-time.Hour)})
+
+	removals := planRetention(models.RetentionPolicy{KeepDaily: 3, KeepWeekly: 2, KeepMonthly: 4}, records, now)
+	ids := removedIDs(removals)
+	if !ids["same-day"] {
+		t.Fatal("expected the older backup of the same day to be removed")
+	}
+	for _, kept := range []string{"r000", "r001", "r002"} {
+		if ids[kept] {
+			t.Fatalf("daily backup %s should be kept", kept)
+		}
+	}
+	kept := len(records) - len(removals)
+	// 3 daily + up to 2 weekly + up to 4 monthly; tiers may overlap.
+	if kept < 5 || kept > 9 {
+		t.Fatalf("unexpected number of kept backups: %d", kept)
+	}
+}
+
+func TestPlanRetentionMaxAgeKeepsNewest(t *testing.T) {
+	now := time.Date(2026, 6, 15, 12, 0, 0, 0, time.Local)
+	records := dailyRecords(now.AddDate(0, 0, -60), 3)
+	removals := planRetention(models.RetentionPolicy{MaxAgeDays: 30}, records, now)
+	if len(removals) != 2 {
+		t.Fatalf("expected 2 removals, got %d", len(removals))
+	}
+	if removedIDs(removals)["r000"] {
+		t.Fatal("newest backup must never be removed")
+	}
+}
+
+func TestApplyRetentionDryRunAndDelete(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	dir := t.TempDir()
+	if err := a.SaveProfile(models.Profile{ID: "p1", Name: "Prod", Group: "Gold"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SetGroupRetention("Gold", &models.RetentionPolicy{KeepLast: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	var history []models.ExportRecord
+	for i := 0; i < 4; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("b%d.sql.gz", i))
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		history = append(history, models.ExportRecord{
+			ID:            fmt.Sprintf("r%d", i),
+			ProfileID:     "p1",
+			ProfileName:   "Prod",
+			ExportDate:    now.Add(-time.Duration(i) * time.Hour),
+			FilePath:      path,
+			FileSizeBytes: 4,
+		})
+	}
+	a.history = history
+	if err := a.store.SaveHistory(history); err != nil {
+		t.Fatal(err)
+	}
+
+	preview, err := a.ApplyRetention(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(preview.Removed) != 2 || len(a.History()) != 4 {
+		t.Fatalf("dry run must not delete: removed=%d history=%d", len(preview.Removed), len(a.History()))
+	}
+	if _, err := os.Stat(history[3].FilePath); err != nil {
+		t.Fatalf("dry run deleted a file: %v", err)
+	}
+
+	report, err := a.ApplyRetention(context.Background(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Removed) != 2 || report.FreedBytes != 8 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if got := len(a.History()); got != 2 {
+		t.Fatalf("expected 2 records left, got %d", got)
+	}
+	if _, err := os.Stat(history[3].FilePath); !os.IsNotExist(err) {
+		t.Fatalf("expected pruned file to be deleted, stat err=%v", err)
+	}
+	deletions := 0
+	for _, entry := range a.Logs() {
+		if entry.Action == "Retention" && entry.Phase == "delete" {
+			deletions++
+		}
+	}
+	if deletions != 2 {
+		t.Fatalf("expected 2 deletion log entries, got %d", deletions)
+	}
+}
+
+func TestEffectiveRetentionPrefersHostPolicy(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	if err := a.SetGroupRetention("Gold", &models.RetentionPolicy{KeepLast: 5}); err != nil {
+		t.Fatal(err)
+	}
+	p := models.Profile{ID: "p1", Group: "Gold"}
+	if policy, fromGroup := a.EffectiveRetention(p); policy == nil || !fromGroup || policy.KeepLast != 5 {
+		t.Fatalf("expected group policy, got %v %v", policy, fromGroup)
+	}
+	p.Retention = &models.RetentionPolicy{KeepDaily: 7}
+	if policy, fromGroup := a.EffectiveRetention(p); policy == nil || fromGroup || policy.KeepDaily != 7 {
+		t.Fatalf("expected host policy, got %v %v", policy, fromGroup)
+	}
+}
+
+func TestApplyRetentionKeepsEachDatabase(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	if err := a.SaveProfile(models.Profile{ID: "p1", Name: "Prod", Retention: &models.RetentionPolicy{KeepLast: 1}}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	var history []models.ExportRecord
+	for i, name := range []string{"shop", "crm", "shop", "crm"} {
+		history = append(history, models.ExportRecord{
+			ID:           fmt.Sprintf("r%d", i),
+			ProfileID:    "p1",
+			DatabaseName: name,
+			ExportDate:   now.Add(-time.Duration(i) * time.Hour),
+		})
+	}
+	a.history = history
+
+	report, err := a.ApplyRetention(context.Background(), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := removedIDs(report.Removed)
+	if len(ids) != 2 || !ids["r2"] || !ids["r3"] {
+		t.Fatalf("expected the older backup of each database to go, got %v", ids)
+	}
+}
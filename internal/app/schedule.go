@@ -0,0 +1,225 @@
+package app
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"dback/models"
+)
+
+// cronSpec is a parsed five-field cron expression (minute hour day-of-month month day-of-week).
+type cronSpec struct {
+	minute, hour, dom, month, dow [64]bool
+	domAny, dowAny                bool
+}
+
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseCron parses a standard five-field cron expression. Supports *, lists, ranges and steps.
+func parseCron(expr string) (cronSpec, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[strings.ToLower(expr)]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return cronSpec{}, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+	var spec cronSpec
+	bounds := []struct {
+		set      *[64]bool
+		min, max int
+		name     string
+	}{
+		{&spec.minute, 0, 59, "minute"},
+		{&spec.hour, 0, 23, "hour"},
+		{&spec.dom, 1, 31, "day of month"},
+		{&spec.month, 1, 12, "month"},
+		{&spec.dow, 0, 7, "day of week"},
+	}
+	for i, b := range bounds {
+		if err := parseCronField(fields[i], b.min, b.max, b.set); err != nil {
+			return cronSpec{}, fmt.Errorf("cron %s: %w", b.name, err)
+		}
+	}
+	if spec.dow[7] {
+		spec.dow[0] = true
+	}
+	spec.domAny = fields[2] == "*"
+	spec.dowAny = fields[4] == "*"
+	return spec, nil
+}
+
+func parseCronField(field string, min, max int, set *[64]bool) error {
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			n, err := strconv.Atoi(part[idx+1:])
+			if err != nil || n <= 0 {
+				return fmt.Errorf("invalid step in %q", part)
+			}
+			step = n
+			part = part[:idx]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			a, errA := strconv.Atoi(bounds[0])
+			b, errB := strconv.Atoi(bounds[1])
+			if errA != nil || errB != nil {
+				return fmt.Errorf("invalid range %q", part)
+			}
+			lo, hi = a, b
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return fmt.Errorf("invalid value %q", part)
+			}
+			lo = n
+			if step > 1 {
+				hi = max
+			} else {
+				hi = n
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return fmt.Errorf("value out of range %d-%d in %q", min, max, field)
+		}
+		for v := lo; v <= hi; v += step {
+			set[v] = true
+		}
+	}
+	return nil
+}
+
+func (c cronSpec) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+	domMatch := c.dom[t.Day()]
+	dowMatch := c.dow[int(t.Weekday())]
+	// Standard cron: when both day fields are restricted, either may match.
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowMatch
+	case c.dowAny:
+		return domMatch
+	default:
+		return domMatch || dowMatch
+	}
+}
+
+// scheduleWindow is a daily time-of-day range in minutes since midnight.
+type scheduleWindow struct {
+	start, end int
+	set        bool
+}
+
+func parseClock(v string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(v))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q (use HH:MM)", v)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWindow(s models.BackupSchedule) (scheduleWindow, error) {
+	return parseClockWindow(s.WindowStart, s.WindowEnd)
+}
+
+// parseClockWindow parses "HH:MM" bounds; both empty means no window.
+func parseClockWindow(startText, endText string) (scheduleWindow, error) {
+	if strings.TrimSpace(startText) == "" && strings.TrimSpace(endText) == "" {
+		return scheduleWindow{}, nil
+	}
+	start, err := parseClock(startText)
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("window start: %w", err)
+	}
+	end, err := parseClock(endText)
+	if err != nil {
+		return scheduleWindow{}, fmt.Errorf("window end: %w", err)
+	}
+	return scheduleWindow{start: start, end: end, set: start != end}, nil
+}
+
+func (w scheduleWindow) contains(t time.Time) bool {
+	if !w.set {
+		return true
+	}
+	m := t.Hour()*60 + t.Minute()
+	if w.start < w.end {
+		return m >= w.start && m < w.end
+	}
+	return m >= w.start || m < w.end
+}
+
+// ValidateSchedule checks a host schedule by finding its next run, so a cron expression
+// that never fires (0 0 31 2 *) or a window no run falls in is rejected with the fields.
+func ValidateSchedule(s models.BackupSchedule) error {
+	if !s.Enabled {
+		return nil
+	}
+	_, err := nextScheduledRun(s, time.Now())
+	return err
+}
+
+// nextScheduledRun returns the first time after `after` when the schedule fires inside its window.
+func nextScheduledRun(s models.BackupSchedule, after time.Time) (time.Time, error) {
+	window, err := parseWindow(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.AddDate(1, 0, 1)
+
+	if expr := strings.TrimSpace(s.Cron); expr != "" {
+		spec, err := parseCron(expr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for ; t.Before(limit); t = t.Add(time.Minute) {
+			if spec.matches(t) && window.contains(t) {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cron expression %q never fires", expr)
+	}
+
+	if s.IntervalMinutes <= 0 {
+		return time.Time{}, fmt.Errorf("schedule needs a cron expression or an interval")
+	}
+	t = after.Add(time.Duration(s.IntervalMinutes) * time.Minute)
+	for ; t.Before(limit); t = t.Truncate(time.Minute).Add(time.Minute) {
+		if window.contains(t) {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("schedule window never opens")
+}
+
+// scheduleDue reports whether a backup should start now. Runs missed while the app was
+// closed collapse into one catch-up run, started as soon as the window allows.
+func scheduleDue(s models.BackupSchedule, lastRun, now time.Time) bool {
+	if !s.Active() || lastRun.IsZero() {
+		return false
+	}
+	next, err := nextScheduledRun(s, lastRun)
+	if err != nil || next.After(now) {
+		return false
+	}
+	window, err := parseWindow(s)
+	return err == nil && window.contains(now)
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"dback/models"
+)
+
+func TestParseCronRejectsBadExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Fatalf("expected %q to be rejected", expr)
+		}
+	}
+	for _, expr := range []string{"@daily", "0 3 * * *", "*/15 1-5 * * 1-5", "0 0 1,15 * 7"} {
+		if _, err := parseCron(expr); err != nil {
+			t.Fatalf("%q: %v", expr, err)
+		}
+	}
+}
+
+func TestNextScheduledRunCron(t *testing.T) {
+	s := models.BackupSchedule{Enabled: true, Cron: "30 3 * * 1-5"}
+	// Friday 2026-01-02 04:00 → next weekday is Monday 2026-01-05 03:30.
+	after := time.Date(2026, 1, 2, 4, 0, 0, 0, time.Local)
+	next, err := nextScheduledRun(s, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 1, 5, 3, 30, 0, 0, time.Local)
+	if !next.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, next)
+	}
+}
+
+func TestNextScheduledRunIntervalWaitsForWindow(t *testing.T) {
+	s := models.BackupSchedule{Enabled: true, IntervalMinutes: 60, WindowStart: "22:00", WindowEnd: "02:00"}
+	after := time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)
+	next, err := nextScheduledRun(s, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2026, 1, 1, 22, 0, 0, 0, time.Local)
+	if !next.Equal(want) {
+		t.Fatalf("expected %v, got %v", want, next)
+	}
+}
+
+func TestScheduleWindowWrapsMidnight(t *testing.T) {
+	w, err := parseWindow(models.BackupSchedule{WindowStart: "22:00", WindowEnd: "02:00"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for hour, want := range map[int]bool{21: false, 22: true, 23: true, 1: true, 2: false, 12: false} {
+		if got := w.contains(time.Date(2026, 1, 1, hour, 0, 0, 0, time.Local)); got != want {
+			t.Fatalf("hour %d: expected %v, got %v", hour, want, got)
+		}
+	}
+}
+
+func TestScheduleDueCatchesUpMissedRunsOnce(t *testing.T) {
+	s := models.BackupSchedule{Enabled: true, Cron: "0 3 * * *", WindowStart: "01:00", WindowEnd: "06:00"}
+	last := time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)
+
+	// App was closed for three days; outside the window nothing starts.
+	if scheduleDue(s, last, time.Date(2026, 1, 4, 12, 0, 0, 0, time.Local)) {
+		t.Fatal("expected no run outside the window")
+	}
+	now := time.Date(2026, 1, 5, 1, 5, 0, 0, time.Local)
+	if !scheduleDue(s, last, now) {
+		t.Fatal("expected a catch-up run once the window opens")
+	}
+	// After the catch-up run is recorded the next one waits for the regular slot.
+	if scheduleDue(s, now, now.Add(time.Hour)) {
+		t.Fatal("expected missed runs to collapse into one")
+	}
+	if !scheduleDue(s, now, time.Date(2026, 1, 5, 3, 0, 0, 0, time.Local)) {
+		t.Fatal("expected the regular run at 03:00")
+	}
+}
+
+func TestScheduleDueIgnoresDisabledSchedules(t *testing.T) {
+	s := models.BackupSchedule{Enabled: false, IntervalMinutes: 1}
+	last := time.Now().Add(-time.Hour)
+	if scheduleDue(s, last, time.Now()) {
+		t.Fatal("disabled schedule must not run")
+	}
+}
+
+func TestSaveProfileValidatesSchedule(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	bad := models.Profile{ID: "p1", Name: "Prod", Schedule: &models.BackupSchedule{Enabled: true, Cron: "99 * * * *"}}
+	if err := a.SaveProfile(bad); err == nil {
+		t.Fatal("expected invalid cron to be rejected")
+	}
+	good := models.Profile{ID: "p1", Name: "Prod", Schedule: &models.BackupSchedule{Enabled: true, Cron: "@daily"}}
+	if err := a.SaveProfile(good); err != nil {
+		t.Fatal(err)
+	}
+	if next := a.NextScheduledBackup(a.Profiles()[0]); next.IsZero() {
+		t.Fatal("expected a next run for an active schedule")
+	}
+}
+
+func TestValidateScheduleRejectsCronThatNeverFires(t *testing.T) {
+	for _, s := range []models.BackupSchedule{
+		{Enabled: true, Cron: "0 0 31 2 *"},
+		{Enabled: true, Cron: "0 3 * * *", WindowStart: "08:00", WindowEnd: "18:00"},
+		{Enabled: true},
+	} {
+		if err := ValidateSchedule(s); err == nil {
+			t.Fatalf("%+v should be rejected", s)
+		}
+	}
+	if err := ValidateSchedule(models.BackupSchedule{Enabled: true, Cron: "0 0 28 2 *"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSchedulerRecordsLastRunOnlyAfterSuccess(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	s := &scheduler{app: a, running: map[string]bool{}, failed: map[string]time.Time{}}
+	p := models.Profile{ID: "p1", Name: "Prod"}
+	seeded := time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)
+	if err := a.store.SetScheduleLastRun(p.ID, seeded); err != nil {
+		t.Fatal(err)
+	}
+
+	started := time.Date(2026, 1, 2, 3, 0, 0, 0, time.Local)
+	if !s.claim(p.ID, p.ID, started) || s.claim(p.ID, p.ID, started) {
+		t.Fatal("expected one claim per host")
+	}
+	s.finish(p, p.ID, started, errors.New("connection refused"))
+	if got := a.store.ScheduleLastRun(p.ID); !got.Equal(seeded) {
+		t.Fatalf("failed run recorded as last run: %v", got)
+	}
+	if s.claim(p.ID, p.ID, started.Add(time.Minute)) {
+		t.Fatal("expected the failed run to wait before a retry")
+	}
+	retry := started.Add(scheduleRetryDelay)
+	if !s.claim(p.ID, p.ID, retry) {
+		t.Fatal("expected a retry after the delay")
+	}
+	s.finish(p, p.ID, retry, nil)
+	if got := a.store.ScheduleLastRun(p.ID); !got.Equal(retry) {
+		t.Fatalf("last run = %v, want %v", got, retry)
+	}
+}
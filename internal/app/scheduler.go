@@ -0,0 +1,218 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"dback/models"
+)
+
+const schedulerTick = 30 * time.Second
+
+// scheduleRetryDelay is how long a scheduled run that failed waits before it is tried again.
+const scheduleRetryDelay = 10 * time.Minute
+
+// ScheduledRunFunc is called when the scheduler starts a backup; kind is models.JobKindBackup
+// or models.JobKindBinlog. The returned progress func receives updates and done is called with
+// the result (UI jobs table hook).
+type ScheduledRunFunc func(profile models.Profile, kind string, cancel context.CancelFunc) (progress ProgressFunc, done func(models.ExportRecord, error))
+
+type scheduler struct {
+	app     *App
+	observe ScheduledRunFunc
+	now     func() time.Time
+
+	mu      sync.Mutex
+	running map[string]bool
+	// failed holds when each schedule key last started a run that did not succeed.
+	failed map[string]time.Time
+	wg     sync.WaitGroup
+}
+
+// StartScheduler runs due scheduled backups until ctx is canceled or the vault is locked.
+// A first pass runs immediately so backups missed while the app was closed catch up.
+func (a *App) StartScheduler(ctx context.Context, observe ScheduledRunFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	a.mu.Lock()
+	if a.stopScheduler != nil {
+		a.stopScheduler()
+	}
+	a.stopScheduler = cancel
+	a.mu.Unlock()
+
+	s := &scheduler{app: a, observe: observe, now: time.Now, running: map[string]bool{}, failed: map[string]time.Time{}}
+	go func() {
+		ticker := time.NewTicker(schedulerTick)
+		defer ticker.Stop()
+		for {
+			s.runDue(ctx)
+			select {
+			case <-ctx.Done():
+				s.wg.Wait()
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// StopScheduler cancels the scheduler loop and any scheduled backups it started.
+func (a *App) StopScheduler() {
+	a.mu.Lock()
+	stop := a.stopScheduler
+	a.stopScheduler = nil
+	a.mu.Unlock()
+	if stop != nil {
+		stop()
+	}
+}
+
+// NextScheduledBackup returns when a host's schedule fires next, or zero if it is inactive.
+func (a *App) NextScheduledBackup(profile models.Profile) time.Time {
+	if !profile.Schedule.Active() {
+		return time.Time{}
+	}
+	last := a.store.ScheduleLastRun(profile.ID)
+	if last.IsZero() {
+		last = time.Now()
+	}
+	next, err := nextScheduledRun(*profile.Schedule, last)
+	if err != nil {
+		return time.Time{}
+	}
+	return next
+}
+
+func (s *scheduler) runDue(ctx context.Context) {
+	if ctx.Err() != nil || !s.app.IsUnlocked() {
+		return
+	}
+	now := s.now()
+	for _, p := range s.app.Profiles() {
+		s.runDueBinlog(ctx, p, now)
+		if !p.Schedule.Active() {
+			continue
+		}
+		last := s.app.store.ScheduleLastRun(p.ID)
+		if last.IsZero() {
+			// First time this schedule is seen: start counting from now instead of firing at once.
+			if err := s.app.store.SetScheduleLastRun(p.ID, now); err != nil {
+				log.Printf("scheduler: seed last run for %q: %v", p.Name, err)
+			}
+			continue
+		}
+		if !scheduleDue(*p.Schedule, last, now) || !s.claim(p.ID, p.ID, now) {
+			continue
+		}
+		s.wg.Add(1)
+		go s.run(ctx, p, now)
+	}
+}
+
+// binlogRunKey keys a host's last incremental pull in the schedule state.
+func binlogRunKey(profileID string) string {
+	return "binlog:" + profileID
+}
+
+// runDueBinlog starts an incremental pull when the host's interval has passed. Hosts without
+// a full backup to continue from are skipped until one exists.
+func (s *scheduler) runDueBinlog(ctx context.Context, p models.Profile, now time.Time) {
+	if !p.Binlog.Active() || p.Binlog.IntervalMinutes <= 0 {
+		return
+	}
+	if _, ok := s.app.binlogTip(p); !ok {
+		return
+	}
+	key := binlogRunKey(p.ID)
+	last := s.app.store.ScheduleLastRun(key)
+	if !last.IsZero() && now.Sub(last) < time.Duration(p.Binlog.IntervalMinutes)*time.Minute {
+		return
+	}
+	if !s.claim(p.ID, key, now) {
+		return
+	}
+	s.wg.Add(1)
+	go s.runBinlog(ctx, p, now)
+}
+
+// claim reserves the host for a run of the schedule key, unless one is already running or
+// the key's last run failed less than scheduleRetryDelay ago.
+func (s *scheduler) claim(profileID, key string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[profileID] {
+		return false
+	}
+	if at, ok := s.failed[key]; ok && now.Sub(at) < scheduleRetryDelay {
+		return false
+	}
+	s.running[profileID] = true
+	return true
+}
+
+// finish releases the host after a run of key that started at started. Only a run that
+// succeeded counts as the schedule's last run; a failed one is retried after
+// scheduleRetryDelay, and one cut short by the app closing on the next start.
+func (s *scheduler) finish(p models.Profile, key string, started time.Time, err error) {
+	s.mu.Lock()
+	delete(s.running, p.ID)
+	if err != nil {
+		s.failed[key] = started
+	} else {
+		delete(s.failed, key)
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+	if err := s.app.store.SetScheduleLastRun(key, started); err != nil {
+		log.Printf("scheduler: record last run for %q: %v", p.Name, err)
+	}
+}
+
+func (s *scheduler) run(ctx context.Context, p models.Profile, started time.Time) {
+	defer s.wg.Done()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var progress ProgressFunc
+	var done func(models.ExportRecord, error)
+	if s.observe != nil {
+		progress, done = s.observe(p, models.JobKindBackup, cancel)
+	}
+	record, err := s.app.BackupWithTrigger(runCtx, p, models.TriggerScheduled, progress)
+	if err != nil {
+		log.Printf("scheduler: scheduled backup for %q failed: %v", p.Name, err)
+	}
+	s.finish(p, p.ID, started, err)
+	if done != nil {
+		done(record, err)
+	}
+}
+
+func (s *scheduler) runBinlog(ctx context.Context, p models.Profile, started time.Time) {
+	defer s.wg.Done()
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var progress ProgressFunc
+	var done func(models.ExportRecord, error)
+	if s.observe != nil {
+		progress, done = s.observe(p, models.JobKindBinlog, cancel)
+	}
+	record, err := s.app.BackupBinlog(runCtx, p, models.TriggerScheduled, progress)
+	if errors.Is(err, ErrNoBinlogEvents) {
+		s.finish(p, binlogRunKey(p.ID), started, nil)
+	} else {
+		if err != nil {
+			log.Printf("scheduler: incremental backup for %q failed: %v", p.Name, err)
+		}
+		s.finish(p, binlogRunKey(p.ID), started, err)
+	}
+	if done != nil {
+		done(record, err)
+	}
+}
@@ -0,0 +1,17 @@
+package app
+
+import (
+	"dback/backend/searchreplace"
+	"dback/models"
+)
+
+// DefaultSearchReplace suggests the search/replace pairs for restoring a backup to
+// destination: when both the backup's host and the destination are WordPress sites with
+// different URLs, the pairs that move the site to the destination's URL.
+func (a *App) DefaultSearchReplace(record models.ExportRecord, destination models.Profile) []models.ReplacePair {
+	source, ok := a.profileByID(record.ProfileID)
+	if !ok || source.ID == destination.ID {
+		return nil
+	}
+	return searchreplace.SiteURLPairs(source.WPUrl, destination.WPUrl)
+}
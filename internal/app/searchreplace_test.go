@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestDefaultSearchReplace(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	source := models.Profile{ID: "live", Name: "Live", ConnectionType: models.ConnectionTypeWordPress, WPUrl: "https://shop.example/"}
+	if err := a.SaveProfile(source); err != nil {
+		t.Fatal(err)
+	}
+	record := models.ExportRecord{ProfileID: source.ID}
+	dest := models.Profile{ID: "staging", Name: "Staging", WPUrl: "https://staging.shop.example"}
+	pairs := a.DefaultSearchReplace(record, dest)
+	if len(pairs) != 2 || pairs[0] != (models.ReplacePair{From: "https://shop.example", To: "https://staging.shop.example"}) {
+		t.Fatalf("pairs = %+v", pairs)
+	}
+	if got := a.DefaultSearchReplace(record, source); got != nil {
+		t.Fatalf("restoring onto the source host: %+v", got)
+	}
+	if got := a.DefaultSearchReplace(record, models.Profile{ID: "ssh", Name: "Server"}); got != nil {
+		t.Fatalf("destination without a site URL: %+v", got)
+	}
+}
+
+func TestRestoreWithOptionsRejectsSearchReplace(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	dest := models.Profile{ID: "staging", Name: "Staging", ConnectionType: models.ConnectionTypeSSH}
+	err := a.RestoreWithOptions(context.Background(), models.ExportRecord{}, dest, RestoreOptions{
+		SearchReplace: []models.ReplacePair{{From: "", To: "x"}},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "empty") {
+		t.Fatalf("empty search text: %v", err)
+	}
+	replace := []models.ReplacePair{{From: "a.example", To: "b.example"}}
+	if err := checkPhysicalRestore(dest, runOptions{replace: replace}); err == nil {
+		t.Fatal("a physical backup cannot be search/replaced")
+	}
+}
+
+func TestRestoreWithOptionsRejectsDefinerMode(t *testing.T) {
+	a := openApp(t, t.TempDir())
+	dest := models.Profile{ID: "staging", Name: "Staging", ConnectionType: models.ConnectionTypeSSH}
+	err := a.RestoreWithOptions(context.Background(), models.ExportRecord{}, dest, RestoreOptions{Definer: "drop"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "DEFINER mode") {
+		t.Fatalf("unknown mode: %v", err)
+	}
+	if err := checkPhysicalRestore(dest, runOptions{definer: models.DefinerStrip}); err == nil {
+		t.Fatal("a physical backup has no DEFINER clauses to rewrite")
+	}
+}
@@ -110,6 +110,11 @@ func (a *App) applyImportedAppData(imported store.AppImportData) error {
 			return err
 		}
 	}
+	if len(imported.BackupKeys) > 0 {
+		if err := a.store.AddBackupKeys(imported.BackupKeys); err != nil {
+			return err
+		}
+	}
 	return a.Reload()
 }
 
@@ -6,9 +6,12 @@ import (
 	"strings"
 	"time"
 
+	"dback/backend/crypt"
 	"dback/backend/db"
+	"dback/backend/sqldump"
 	"dback/backend/transfer"
 	"dback/backend/verify"
+	"dback/internal/notify"
 	"dback/models"
 )
 
@@ -56,6 +59,12 @@ func (a *App) captureBackupMetadata(ctx context.Context, profile models.Profile,
 	if err == nil {
 		sha256 = sum
 	}
+	if sqldump.IsSplitPath(filePath) {
+		if m, err := sqldump.ReadManifest(filePath); err == nil {
+			fp := verify.FingerprintFromManifest(m)
+			return sha256, &fp
+		}
+	}
 	runner := appQueryRunner{app: a}
 	fp, err := verify.CaptureFingerprint(ctx, runner, profile, databaseName, verify.ModeFast)
 	if err == nil {
@@ -127,7 +136,9 @@ func BackupDeepVerifyStatus(record models.ExportRecord) string {
 	return "none"
 }
 
-// QuickVerify checks local file SHA256 against the stored checksum.
+// QuickVerify checks local file SHA256 against the stored checksum. A backup without one
+// is checked by decrypting and decompressing it, and its checksum is stored when that
+// passes. An encrypted backup must also still open with the vault or recovery key.
 func (a *App) QuickVerify(ctx context.Context, recordID string) (models.LastVerified, error) {
 	if err := ctx.Err(); err != nil {
 		return models.LastVerified{}, err
@@ -136,7 +147,23 @@ func (a *App) QuickVerify(ctx context.Context, recordID string) (models.LastVeri
 	if err != nil {
 		return models.LastVerified{}, err
 	}
-	result, err := verify.QuickCheck(record.FilePath, record.Sha256)
+	var result verify.QuickCheckResult
+	if record.Sha256 == "" {
+		// Without a checksum, test that the file decompresses and keep its checksum.
+		result, err = verify.CodecCheck(record.FilePath, a.decryptionKeys(record))
+		if err == nil && result.Passed {
+			record.Sha256 = result.Actual
+			if record.Compression == "" {
+				record.Compression = result.Compression
+			}
+		}
+	} else {
+		result, err = verify.QuickCheck(record.FilePath, record.Sha256)
+		if err == nil && result.Passed && record.Encrypted {
+			// The checksum proves the file is intact; also make sure the keys still open it.
+			err = crypt.CheckKey(record.FilePath, a.decryptionKeys(record))
+		}
+	}
 	if err != nil {
 		return models.LastVerified{}, err
 	}
@@ -149,6 +176,9 @@ func (a *App) QuickVerify(ctx context.Context, recordID string) (models.LastVeri
 	if err := a.UpdateHistoryRecord(record); err != nil {
 		return last, err
 	}
+	if result.Problem != "" {
+		return last, fmt.Errorf("file is corrupted: %s", result.Problem)
+	}
 	if !result.Passed {
 		return last, fmt.Errorf("file is corrupted or has been modified")
 	}
@@ -157,6 +187,18 @@ func (a *App) QuickVerify(ctx context.Context, recordID string) (models.LastVeri
 
 // DeepVerify restores the backup to a temporary database and compares row counts.
 func (a *App) DeepVerify(ctx context.Context, recordID string, destination models.Profile, progress ProgressFunc) (models.LastVerified, error) {
+	operationID := newID()
+	started := time.Now()
+	last, err := a.deepVerify(ctx, recordID, destination, operationID, progress)
+	var notified *models.ExportRecord
+	if record, _, findErr := a.findHistoryRecord(recordID); findErr == nil {
+		notified = &record
+	}
+	a.notify(notify.EventDeepVerify, operationID, destination, "", started, notified, err)
+	return last, err
+}
+
+func (a *App) deepVerify(ctx context.Context, recordID string, destination models.Profile, operationID string, progress ProgressFunc) (models.LastVerified, error) {
 	if err := ctx.Err(); err != nil {
 		return models.LastVerified{}, err
 	}
@@ -167,6 +209,15 @@ func (a *App) DeepVerify(ctx context.Context, recordID string, destination model
 	if err != nil {
 		return models.LastVerified{}, err
 	}
+	if record.Incremental() {
+		return models.LastVerified{}, fmt.Errorf("incremental backups are checked by point-in-time restore of their full backup")
+	}
+	if record.Physical() {
+		return models.LastVerified{}, fmt.Errorf("deep verify restores into a scratch database; physical backups only support quick verify")
+	}
+	if err := checkPostgresRestore(record, destination, runOptions{}); err != nil {
+		return models.LastVerified{}, err
+	}
 	if record.Fingerprint == nil {
 		return models.LastVerified{}, fmt.Errorf("no fingerprint available; re-create this backup to enable deep verify")
 	}
@@ -199,7 +250,6 @@ func (a *App) DeepVerify(ctx context.Context, recordID string, destination model
 		}
 	}
 
-	operationID := newID()
 	logger := a.newOpLogger(operationID, &destination)
 	restoreReq := transfer.RestoreRequest{
 		Profile:          destination,
@@ -209,6 +259,9 @@ func (a *App) DeepVerify(ctx context.Context, recordID string, destination model
 		Logger:           logger,
 		Progress:         progress,
 		TargetDBOverride: tempDB,
+		Keys:             a.decryptionKeys(record),
+		SourceVersion:    record.ServerVersion,
+		Bandwidth:        a.transferLimits(destination),
 	}
 	var restoreErr error
 	if destination.UsesWordPress() {
@@ -258,15 +311,12 @@ func (a *App) DeepVerify(ctx context.Context, recordID string, destination model
 }
 
 func (a *App) prepareVerifyDatabase(ctx context.Context, profile models.Profile, tempDB string) error {
-	sql := db.BuildDropDatabaseCommand(tempDB) + " " + fmt.Sprintf(
-		"CREATE DATABASE %s CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci;",
-		db.SQLIdent(tempDB),
-	)
+	sql := db.DropDatabaseSQL(profile, tempDB) + " " + db.CreateDatabaseSQL(profile, tempDB)
 	_, err := a.RunImportQuery(ctx, profile, sql, false)
 	return err
 }
 
 func (a *App) dropVerifyDatabase(ctx context.Context, profile models.Profile, tempDB string) error {
-	_, err := a.RunImportQuery(ctx, profile, db.BuildDropDatabaseCommand(tempDB), false)
+	_, err := a.RunImportQuery(ctx, profile, db.DropDatabaseSQL(profile, tempDB), false)
 	return err
 }
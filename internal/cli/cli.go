@@ -0,0 +1,272 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	coreapp "dback/internal/app"
+	"dback/models"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK             = 0
+	ExitFailed         = 1 // operation failed
+	ExitUsage          = 2 // bad arguments or unknown profile/record
+	ExitVault          = 3 // vault missing, passphrase missing or wrong
+	ExitVerifyMismatch = 4 // verify ran but the backup did not pass
+	ExitCanceled       = 130
+)
+
+const (
+	envPassphrase     = "DBACK_PASSPHRASE"
+	envPassphraseFile = "DBACK_PASSPHRASE_FILE"
+	envRecovery       = "DBACK_RECOVERY_PASSPHRASE"
+)
+
+var commands = map[string]func(*env, []string) int{
+	"backup":  runBackup,
+	"restore": runRestore,
+	"verify":  runVerify,
+	"query":   runQuery,
+	"history": runHistory,
+	"prune":   runPrune,
+	"decrypt": runDecrypt,
+}
+
+// IsCommand reports whether args start with a headless subcommand.
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		return true
+	}
+	_, ok := commands[args[0]]
+	return ok
+}
+
+type env struct {
+	ctx     context.Context
+	baseDir string
+	stdin   io.Reader
+	stdout  io.Writer
+	stderr  io.Writer
+	core    *coreapp.App
+}
+
+// Run executes a headless subcommand against the vault in baseDir and returns a process exit code.
+func Run(args []string, baseDir string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "--help" || args[0] == "-h" {
+		printUsage(stdout)
+		return ExitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "dback: unknown command %q\n", args[0])
+		printUsage(stderr)
+		return ExitUsage
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	e := &env{ctx: ctx, baseDir: baseDir, stdin: stdin, stdout: stdout, stderr: stderr}
+	code := cmd(e, args[1:])
+	// The process exits when Run returns; let the notifications of the operation go out.
+	if e.core != nil && !e.core.WaitNotifications() {
+		fmt.Fprintln(stderr, "dback: gave up waiting for notifications to be sent")
+	}
+	return code
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprint(w, `Usage: dback <command> [flags]
+
+Commands:
+  backup   --profile NAME [--binlog] | --group NAME [--concurrency N]
+                                    Back up a host or every host in a group
+  restore  --record ID --to NAME [--until TIME | --mask-dry-run]
+           [--replace 'FROM=>TO' ...] [--replace-urls]
+           [--definer keep|strip|rewrite|invoker] [--as DATABASE]
+           [--parallel N]
+                                    Restore a backup to a host, or list the columns
+                                    the host's masking rules would change; --replace
+                                    rewrites text (PHP-serialized values included);
+                                    --definer handles DEFINER clauses the destination
+                                    has no user for; --as restores into a new
+                                    database, leaving the host's own untouched;
+                                    --parallel imports N tables at once
+  verify   --record ID | --profile NAME | --all [--deep --on NAME]
+                                    Quick (SHA256) or deep (temp restore) verify
+  query    --profile NAME [--db] [--sql SQL | --file PATH]
+                                    Run SQL (reads stdin when no --sql/--file)
+  history  [--profile NAME]         List backup records
+  prune    [--profile NAME] [--dry-run]
+                                    Apply retention policies to old backups
+  decrypt  --in FILE [--out FILE] [--recovery-passphrase-file PATH]
+                                    Decrypt an encrypted backup file; with a recovery
+                                    passphrase no vault is needed
+
+Vault flags (all commands):
+  --passphrase-file PATH   Read the master key from a file
+  --data-dir DIR           Vault directory (default: app data dir)
+
+The master key may also be set with DBACK_PASSPHRASE or DBACK_PASSPHRASE_FILE, and a
+recovery passphrase with DBACK_RECOVERY_PASSPHRASE.
+Run without a command to start the desktop app.
+
+Exit codes: 0 ok, 1 failed, 2 usage, 3 vault, 4 verify mismatch, 130 canceled.
+`)
+}
+
+type vaultFlags struct {
+	passphraseFile string
+	dataDir        string
+}
+
+func newFlagSet(e *env, name string) (*flag.FlagSet, *vaultFlags) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	vf := &vaultFlags{}
+	fs.StringVar(&vf.passphraseFile, "passphrase-file", "", "read the vault master key from `PATH`")
+	fs.StringVar(&vf.dataDir, "data-dir", "", "vault directory")
+	return fs, vf
+}
+
+// open unlocks the vault and returns an exit code other than ExitOK on failure.
+func (e *env) open(vf *vaultFlags) int {
+	dir := e.baseDir
+	if strings.TrimSpace(vf.dataDir) != "" {
+		dir = vf.dataDir
+	}
+	core, err := coreapp.New(dir)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dback: %v\n", err)
+		return ExitVault
+	}
+	if !core.HasVault() {
+		fmt.Fprintf(e.stderr, "dback: no vault found in %s; create one from the desktop app first\n", dir)
+		return ExitVault
+	}
+	passphrase, err := resolvePassphrase(vf.passphraseFile)
+	if err != nil {
+		fmt.Fprintf(e.stderr, "dback: %v\n", err)
+		return ExitVault
+	}
+	if err := core.Unlock(passphrase); err != nil {
+		fmt.Fprintf(e.stderr, "dback: unlock vault: %v\n", err)
+		return ExitVault
+	}
+	e.core = core
+	return ExitOK
+}
+
+// resolvePassphrase reads the master key from --passphrase-file, DBACK_PASSPHRASE_FILE or DBACK_PASSPHRASE.
+func resolvePassphrase(path string) (string, error) {
+	if strings.TrimSpace(path) == "" {
+		path = os.Getenv(envPassphraseFile)
+	}
+	if strings.TrimSpace(path) != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read passphrase file: %w", err)
+		}
+		pass := strings.TrimRight(string(data), "\r\n")
+		if pass == "" {
+			return "", fmt.Errorf("passphrase file %s is empty", path)
+		}
+		return pass, nil
+	}
+	if pass := os.Getenv(envPassphrase); pass != "" {
+		return pass, nil
+	}
+	return "", fmt.Errorf("vault master key required: use --passphrase-file, %s or %s", envPassphraseFile, envPassphrase)
+}
+
+// findProfile matches a profile by ID first, then by case-insensitive name.
+func findProfile(profiles []models.Profile, key string) (models.Profile, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return models.Profile{}, errors.New("profile is required")
+	}
+	for _, p := range profiles {
+		if p.ID == key {
+			return p, nil
+		}
+	}
+	var matches []models.Profile
+	for _, p := range profiles {
+		if strings.EqualFold(strings.TrimSpace(p.Name), key) {
+			matches = append(matches, p)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return models.Profile{}, fmt.Errorf("profile %q not found", key)
+	case 1:
+		return matches[0], nil
+	default:
+		return models.Profile{}, fmt.Errorf("profile name %q is ambiguous; use the profile ID", key)
+	}
+}
+
+func findRecord(history []models.ExportRecord, id string) (models.ExportRecord, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return models.ExportRecord{}, errors.New("record is required")
+	}
+	for _, rec := range history {
+		if rec.ID == id {
+			return rec, nil
+		}
+	}
+	return models.ExportRecord{}, fmt.Errorf("backup record %q not found", id)
+}
+
+// latestRecord returns the newest backup record for a profile.
+func latestRecord(history []models.ExportRecord, profileID string) (models.ExportRecord, bool) {
+	var latest models.ExportRecord
+	found := false
+	for _, rec := range history {
+		if rec.ProfileID != profileID {
+			continue
+		}
+		if !found || rec.ExportDate.After(latest.ExportDate) {
+			latest = rec
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// exitCodeFor maps an operation error to an exit code.
+func exitCodeFor(err error) int {
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.Is(err, context.Canceled):
+		return ExitCanceled
+	default:
+		return ExitFailed
+	}
+}
+
+func (e *env) fail(err error) int {
+	if errors.Is(err, context.Canceled) {
+		fmt.Fprintln(e.stderr, "dback: canceled")
+	} else {
+		fmt.Fprintf(e.stderr, "dback: %v\n", err)
+	}
+	return exitCodeFor(err)
+}
+
+func (e *env) usageError(err error) int {
+	fmt.Fprintf(e.stderr, "dback: %v\n", err)
+	return ExitUsage
+}
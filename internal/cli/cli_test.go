@@ -0,0 +1,309 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"dback/backend/crypt"
+	coreapp "dback/internal/app"
+	"dback/internal/store"
+	"dback/models"
+)
+
+const testMasterKey = "test-master-key"
+
+func newVault(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	a, err := coreapp.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.CreateVault(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveProfile(models.Profile{ID: "p1", Name: "Production", Group: "Gold", DBType: models.DBTypeMySQL}); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func run(t *testing.T, dir string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, dir, strings.NewReader(""), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestIsCommand(t *testing.T) {
+	for _, args := range [][]string{{"backup"}, {"restore", "--record", "1"}, {"help"}} {
+		if !IsCommand(args) {
+			t.Fatalf("expected %v to be a command", args)
+		}
+	}
+	for _, args := range [][]string{nil, {"--debug"}, {"unknown"}} {
+		if IsCommand(args) {
+			t.Fatalf("expected %v to start the desktop app", args)
+		}
+	}
+}
+
+func TestRunRequiresPassphrase(t *testing.T) {
+	dir := newVault(t)
+	t.Setenv(envPassphrase, "")
+	t.Setenv(envPassphraseFile, "")
+	code, _, stderr := run(t, dir, "history")
+	if code != ExitVault {
+		t.Fatalf("expected exit %d, got %d (%s)", ExitVault, code, stderr)
+	}
+}
+
+func TestRunWrongPassphrase(t *testing.T) {
+	dir := newVault(t)
+	t.Setenv(envPassphrase, "not-the-key")
+	code, _, _ := run(t, dir, "history")
+	if code != ExitVault {
+		t.Fatalf("expected exit %d, got %d", ExitVault, code)
+	}
+}
+
+func TestRunPassphraseFile(t *testing.T) {
+	dir := newVault(t)
+	t.Setenv(envPassphrase, "")
+	keyFile := filepath.Join(t.TempDir(), "key")
+	if err := os.WriteFile(keyFile, []byte(testMasterKey+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr := run(t, dir, "history", "--passphrase-file", keyFile)
+	if code != ExitOK {
+		t.Fatalf("expected exit 0, got %d (%s)", code, stderr)
+	}
+	if !strings.Contains(stdout, "PROFILE") {
+		t.Fatalf("expected history header, got %q", stdout)
+	}
+}
+
+func TestRunUsageErrors(t *testing.T) {
+	dir := newVault(t)
+	t.Setenv(envPassphrase, testMasterKey)
+	cases := [][]string{
+		{"nope"},
+		{"backup"},
+		{"backup", "--profile", "Missing"},
+		{"restore", "--record", "1"},
+		{"restore", "--record", "1", "--to", "Staging", "--replace", "old.example"},
+		{"restore", "--record", "1", "--to", "Staging", "--definer", "drop"},
+		{"restore", "--record", "1", "--to", "Staging", "--as", "shop copy"},
+		{"restore", "--record", "1", "--to", "Staging", "--parallel", "64"},
+		{"verify"},
+		{"verify", "--all", "--record", "1"},
+		{"query", "--profile", "Production"},
+	}
+	for _, args := range cases {
+		if code, _, _ := run(t, dir, args...); code != ExitUsage {
+			t.Fatalf("%v: expected exit %d, got %d", args, ExitUsage, code)
+		}
+	}
+}
+
+func TestRestoreParallelOutOfRange(t *testing.T) {
+	dir := newVault(t)
+	t.Setenv(envPassphrase, testMasterKey)
+	for _, n := range []string{"-1", "17"} {
+		code, _, stderr := run(t, dir, "restore", "--record", "1", "--to", "Staging", "--parallel", n)
+		if code != ExitUsage || !strings.Contains(stderr, "--parallel takes 0 to 16 sessions") {
+			t.Fatalf("--parallel %s: exit %d, stderr %q", n, code, stderr)
+		}
+	}
+	// In range, 0 and 1 included, the check passes and the restore fails on the missing record.
+	for _, n := range []string{"0", "1", "16"} {
+		if _, _, stderr := run(t, dir, "restore", "--record", "1", "--to", "Staging", "--parallel", n); strings.Contains(stderr, "--parallel") {
+			t.Fatalf("--parallel %s: %s", n, stderr)
+		}
+	}
+}
+
+func TestRunSendsNotificationsBeforeReturning(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	dir := newVault(t)
+	t.Setenv(envPassphrase, testMasterKey)
+	a, err := coreapp.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Unlock(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "sent")
+	// The delay keeps the sink running well after the backup has failed.
+	sink := models.NotificationSink{Name: "cron alert", Kind: models.NotifyCommand, Enabled: true, On: models.NotifyOnFailure, Command: `sleep 0.3; echo "$DBACK_STATUS" > "` + out + `"`}
+	if _, err := a.SaveNotificationSink(sink); err != nil {
+		t.Fatal(err)
+	}
+	a.Lock()
+
+	// Production has no DB user, so the backup fails.
+	if code, _, _ := run(t, dir, "backup", "--profile", "Production"); code != ExitFailed {
+		t.Fatalf("exit = %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil || strings.TrimSpace(string(data)) != "failure" {
+		t.Fatalf("the failure alert was not sent before Run returned: %q, %v", data, err)
+	}
+}
+
+func TestRunBackupWarnsWithoutRecoveryPassphrase(t *testing.T) {
+	dir := newVault(t)
+	t.Setenv(envPassphrase, testMasterKey)
+	a, err := coreapp.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Unlock(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveProfile(models.Profile{ID: "p2", Name: "Vaulted", ConnectionType: models.ConnectionTypeSSH, DBType: models.DBTypeMySQL, Encryption: &models.BackupEncryption{Enabled: true}}); err != nil {
+		t.Fatal(err)
+	}
+	a.Lock()
+
+	// The backup fails for want of a DB user; the warning comes first.
+	_, _, stderr := run(t, dir, "backup", "--profile", "Vaulted")
+	if !strings.Contains(stderr, "Vaulted: "+coreapp.NoRecoveryPassphraseWarning) {
+		t.Fatalf("stderr = %q", stderr)
+	}
+	if _, _, stderr := run(t, dir, "backup", "--profile", "Production"); strings.Contains(stderr, coreapp.NoRecoveryPassphraseWarning) {
+		t.Fatalf("plain host warned: %q", stderr)
+	}
+}
+
+func TestFindProfileByIDOrName(t *testing.T) {
+	profiles := []models.Profile{{ID: "a", Name: "Prod"}, {ID: "b", Name: "Stage"}, {ID: "c", Name: "stage"}}
+	if p, err := findProfile(profiles, "a"); err != nil || p.Name != "Prod" {
+		t.Fatalf("lookup by ID: %v %v", p, err)
+	}
+	if p, err := findProfile(profiles, "prod"); err != nil || p.ID != "a" {
+		t.Fatalf("lookup by name: %v %v", p, err)
+	}
+	if _, err := findProfile(profiles, "Stage"); err == nil {
+		t.Fatal("expected ambiguous name error")
+	}
+}
+
+func TestLatestRecord(t *testing.T) {
+	now := time.Now()
+	history := []models.ExportRecord{
+		{ID: "old", ProfileID: "p1", ExportDate: now.Add(-time.Hour)},
+		{ID: "new", ProfileID: "p1", ExportDate: now},
+		{ID: "other", ProfileID: "p2", ExportDate: now.Add(time.Hour)},
+	}
+	rec, ok := latestRecord(history, "p1")
+	if !ok || rec.ID != "new" {
+		t.Fatalf("expected newest p1 record, got %v", rec)
+	}
+}
+
+func TestProgressPrinterThrottlesSameStep(t *testing.T) {
+	var buf bytes.Buffer
+	p := newProgressPrinter(&buf)
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p.now = func() time.Time { return now }
+	p.print("Streaming backup 1.0%")
+	p.print("Streaming backup 2.0%")
+	p.print("Capturing fingerprint...")
+	now = now.Add(3 * time.Second)
+	p.print("Capturing fingerprint...")
+	if got := strings.Count(buf.String(), "\n"); got != 3 {
+		t.Fatalf("expected 3 lines, got %d: %q", got, buf.String())
+	}
+}
+
+func TestRunDecryptWithRecoveryPassphrase(t *testing.T) {
+	t.Setenv(envPassphrase, "")
+	t.Setenv(envRecovery, "")
+	dir := t.TempDir()
+	src := filepath.Join(dir, "shop_01.sql.gz.enc")
+	var sealed bytes.Buffer
+	w, err := crypt.NewWriter(&sealed, crypt.Keys{Passphrase: "recovery-phrase"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("dump bytes"))
+	w.Close()
+	if err := os.WriteFile(src, sealed.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	passFile := filepath.Join(dir, "recovery")
+	if err := os.WriteFile(passFile, []byte("wrong-phrase\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// No vault exists in dir: the recovery passphrase alone must be enough.
+	if code, _, stderr := run(t, dir, "decrypt", "--in", src, "--recovery-passphrase-file", passFile); code != ExitVault {
+		t.Fatalf("wrong passphrase: expected exit %d, got %d (%s)", ExitVault, code, stderr)
+	}
+	t.Setenv(envRecovery, "recovery-phrase")
+	if code, _, stderr := run(t, dir, "decrypt", "--in", src); code != ExitOK {
+		t.Fatalf("expected exit 0, got %d (%s)", code, stderr)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "shop_01.sql.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "dump bytes" {
+		t.Fatalf("decrypted %q", got)
+	}
+	if code, _, _ := run(t, dir, "decrypt", "--in", src); code != ExitUsage {
+		t.Fatalf("expected exit %d when the output exists, got %d", ExitUsage, code)
+	}
+}
+
+func TestRunRestoreMaskDryRun(t *testing.T) {
+	t.Setenv(envPassphrase, testMasterKey)
+	dir := newVault(t)
+	a, err := coreapp.New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Unlock(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SaveProfile(models.Profile{ID: "p2", Name: "Staging", Masking: []models.MaskRule{
+		{Table: "users", Column: "email", Strategy: models.MaskFakeEmail},
+		{Table: "users", Column: "phone", Strategy: models.MaskNull},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	dump := filepath.Join(dir, "shop.sql")
+	body := "CREATE TABLE `users` (\n  `id` int,\n  `email` varchar(80)\n);\n" +
+		"INSERT INTO `users` VALUES (1,'ann@shop.example'),(2,'bob@shop.example');\n" +
+		strings.Repeat("-- padding so the file is not rejected as too small\n", 3)
+	if err := os.WriteFile(dump, []byte(body), 0600); err != nil {
+		t.Fatal(err)
+	}
+	st := store.New(dir)
+	if err := st.Unlock(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SaveHistory([]models.ExportRecord{{ID: "r1", ProfileID: "p1", FilePath: dump, FileSizeBytes: int64(len(body))}}); err != nil {
+		t.Fatal(err)
+	}
+
+	code, stdout, stderr := run(t, dir, "restore", "--record", "r1", "--to", "Staging", "--mask-dry-run")
+	if code != ExitFailed || !strings.Contains(stderr, "no column phone") {
+		t.Fatalf("expected exit %d for the missing column, got %d (%s)", ExitFailed, code, stderr)
+	}
+	if !strings.Contains(stdout, "users") || !strings.Contains(stdout, "fake_email") || !strings.Contains(stdout, "  2\n") {
+		t.Fatalf("stdout = %q", stdout)
+	}
+	if data, _ := os.ReadFile(dump); string(data) != body {
+		t.Fatal("dry run changed the backup file")
+	}
+}
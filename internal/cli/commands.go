@@ -0,0 +1,583 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"dback/backend/crypt"
+	"dback/backend/db"
+	"dback/backend/definer"
+	"dback/backend/searchreplace"
+	"dback/backend/transfer"
+	coreapp "dback/internal/app"
+	"dback/models"
+)
+
+func runBackup(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "backup")
+	profileKey := fs.String("profile", "", "host profile name or ID")
+	group := fs.String("group", "", "back up every host in this group")
+	concurrency := fs.Int("concurrency", coreapp.DefaultGroupBackupConcurrency, "parallel backups for --group")
+	binlog := fs.Bool("binlog", false, "pull new binary logs as an incremental backup instead of a full dump")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	hasProfile, hasGroup := strings.TrimSpace(*profileKey) != "", strings.TrimSpace(*group) != ""
+	if hasProfile == hasGroup {
+		return e.usageError(errors.New("backup: use exactly one of --profile or --group"))
+	}
+	if *binlog && hasGroup {
+		return e.usageError(errors.New("backup: --binlog needs --profile"))
+	}
+	if code := e.open(vf); code != ExitOK {
+		return code
+	}
+	if hasGroup {
+		return runGroupBackup(e, *group, *concurrency)
+	}
+	profile, err := findProfile(e.core.Profiles(), *profileKey)
+	if err != nil {
+		return e.usageError(err)
+	}
+
+	if *binlog {
+		return runBinlogBackup(e, profile)
+	}
+	warnRecovery(e, profile)
+	fmt.Fprintf(e.stderr, "Backing up %s...\n", profile.Name)
+	record, err := e.core.Backup(e.ctx, profile, newProgressPrinter(e.stderr).Func())
+	if err != nil {
+		return e.fail(err)
+	}
+	fmt.Fprintf(e.stdout, "%s\t%s\t%s\n", record.ID, record.FileSize, record.FilePath)
+	if record.QuickVerified != nil && !record.QuickVerified.Passed {
+		fmt.Fprintln(e.stderr, "dback: backup written but quick verify failed")
+		return ExitVerifyMismatch
+	}
+	return ExitOK
+}
+
+// warnRecovery warns before a backup that only this vault's key will open.
+func warnRecovery(e *env, p models.Profile) {
+	if warning := coreapp.RecoveryWarning(p); warning != "" {
+		fmt.Fprintf(e.stderr, "dback: %s: %s\n", p.Name, warning)
+	}
+}
+
+func runBinlogBackup(e *env, profile models.Profile) int {
+	fmt.Fprintf(e.stderr, "Pulling binary logs of %s...\n", profile.Name)
+	record, err := e.core.BackupBinlog(e.ctx, profile, models.TriggerManual, newProgressPrinter(e.stderr).Func())
+	if errors.Is(err, coreapp.ErrNoBinlogEvents) {
+		fmt.Fprintln(e.stderr, "No new binary log events")
+		return ExitOK
+	}
+	if err != nil {
+		return e.fail(err)
+	}
+	fmt.Fprintf(e.stdout, "%s\t%s\t%s\n", record.ID, record.FileSize, record.FilePath)
+	return ExitOK
+}
+
+func runGroupBackup(e *env, group string, concurrency int) int {
+	if len(e.core.GroupProfiles(group)) == 0 {
+		return e.usageError(fmt.Errorf("group %q has no hosts", group))
+	}
+	for _, p := range e.core.GroupProfiles(group) {
+		warnRecovery(e, p)
+	}
+	fmt.Fprintf(e.stderr, "Backing up group %s...\n", group)
+	var mu sync.Mutex
+	printers := map[string]*progressPrinter{}
+	summary, err := e.core.BackupGroup(e.ctx, group, concurrency, func(p models.Profile, message string, current, total int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		printer, ok := printers[p.ID]
+		if !ok {
+			printer = newHostProgressPrinter(e.stderr, p.Name)
+			printers[p.ID] = printer
+		}
+		printer.print(message)
+	})
+	for _, r := range summary.Results {
+		detail := r.Record.FilePath
+		if r.Err != nil {
+			detail = r.Err.Error()
+		}
+		fmt.Fprintf(e.stdout, "%s\t%s\t%s\t%s\n", r.Profile.Name, r.Status, r.Record.ID, detail)
+	}
+	fmt.Fprintf(e.stderr, "Group %s (operation %s): %s\n", summary.Group, summary.OperationID, summary)
+	if err != nil {
+		return e.fail(err)
+	}
+	if _, failed, _ := summary.Counts(); failed > 0 {
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+func runRestore(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "restore")
+	recordID := fs.String("record", "", "backup record ID (see dback history)")
+	destKey := fs.String("to", "", "destination host profile name or ID")
+	until := fs.String("until", "", "replay binlog incrementals up to this local time (YYYY-MM-DD HH:MM:SS)")
+	maskDryRun := fs.Bool("mask-dry-run", false, "list the columns the destination's masking rules would change, without restoring")
+	var replace replaceFlag
+	fs.Var(&replace, "replace", "replace text in the restored rows, written as 'FROM=>TO' (repeatable)")
+	replaceURLs := fs.Bool("replace-urls", false, "replace the backup's WordPress site URL with the destination's")
+	definerFlag := fs.String("definer", "keep", "DEFINER clauses of views, triggers and routines: keep, strip, rewrite (to the destination's user) or invoker")
+	targetDB := fs.String("as", "", "restore into this new database instead of the destination's configured one")
+	parallel := fs.Int("parallel", 0, fmt.Sprintf("import tables over this many sessions at once, 2 to %d; 0 or 1 restores in one session", transfer.MaxParallelRestore))
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if *parallel < 0 || *parallel > transfer.MaxParallelRestore {
+		return e.usageError(fmt.Errorf("restore: --parallel takes 0 to %d sessions (0 or 1 restores in one session)", transfer.MaxParallelRestore))
+	}
+	*targetDB = strings.TrimSpace(*targetDB)
+	if *targetDB != "" {
+		if err := db.ValidateDatabaseName(*targetDB); err != nil {
+			return e.usageError(fmt.Errorf("restore: --as: %w", err))
+		}
+	}
+	definerMode, err := definer.ParseMode(*definerFlag)
+	if err != nil {
+		return e.usageError(fmt.Errorf("restore: --definer: %w", err))
+	}
+	var pointInTime time.Time
+	if strings.TrimSpace(*until) != "" {
+		t, err := time.ParseInLocation(time.DateTime, strings.TrimSpace(*until), time.Local)
+		if err != nil {
+			return e.usageError(fmt.Errorf("restore: --until must look like 2006-01-02 15:04:05"))
+		}
+		pointInTime = t
+	}
+	if strings.TrimSpace(*recordID) == "" || strings.TrimSpace(*destKey) == "" {
+		return e.usageError(errors.New("restore: --record and --to are required"))
+	}
+	if code := e.open(vf); code != ExitOK {
+		return code
+	}
+	record, err := findRecord(e.core.History(), *recordID)
+	if err != nil {
+		return e.usageError(err)
+	}
+	dest, err := findProfile(e.core.Profiles(), *destKey)
+	if err != nil {
+		return e.usageError(err)
+	}
+	if !dest.AllowsImport() {
+		return e.usageError(fmt.Errorf("host %q is protected from import", dest.Name))
+	}
+	if *maskDryRun {
+		return runMaskDryRun(e, record, dest)
+	}
+	pairs := []models.ReplacePair(replace)
+	if *replaceURLs {
+		urls := e.core.DefaultSearchReplace(record, dest)
+		if len(urls) == 0 {
+			return e.usageError(fmt.Errorf("restore: --replace-urls needs WordPress site URLs on both hosts, and different ones"))
+		}
+		pairs = append(urls, pairs...)
+	}
+	if len(pairs) > 0 && !pointInTime.IsZero() {
+		return e.usageError(errors.New("restore: --replace cannot be combined with --until"))
+	}
+	if definerMode != models.DefinerKeep && !pointInTime.IsZero() {
+		return e.usageError(errors.New("restore: --definer cannot be combined with --until"))
+	}
+	if *targetDB != "" && !pointInTime.IsZero() {
+		return e.usageError(errors.New("restore: --as cannot be combined with --until"))
+	}
+	if *parallel > 1 && !pointInTime.IsZero() {
+		return e.usageError(errors.New("restore: --parallel cannot be combined with --until"))
+	}
+
+	if *targetDB != "" {
+		fmt.Fprintf(e.stderr, "Restoring %s to %s as database %s...\n", record.FilePath, dest.Name, *targetDB)
+	} else {
+		fmt.Fprintf(e.stderr, "Restoring %s to %s...\n", record.FilePath, dest.Name)
+	}
+	progress := newProgressPrinter(e.stderr).Func()
+	switch {
+	case !pointInTime.IsZero():
+		err = e.core.RestorePointInTime(e.ctx, record, pointInTime, dest, progress)
+	case len(pairs) > 0 || definerMode != models.DefinerKeep || *targetDB != "" || *parallel > 1:
+		opts := coreapp.RestoreOptions{SearchReplace: pairs, Definer: definerMode, TargetDB: *targetDB, Parallel: *parallel}
+		err = e.core.RestoreWithOptions(e.ctx, record, dest, opts, progress)
+	default:
+		err = e.core.Restore(e.ctx, record, dest, progress)
+	}
+	if err != nil {
+		return e.fail(err)
+	}
+	fmt.Fprintln(e.stderr, "Restore completed")
+	return ExitOK
+}
+
+// replaceFlag collects repeated --replace 'FROM=>TO' flags.
+type replaceFlag []models.ReplacePair
+
+func (f *replaceFlag) String() string { return searchreplace.FormatPairs(*f) }
+
+func (f *replaceFlag) Set(s string) error {
+	pairs, err := searchreplace.ParsePairs(s)
+	if err != nil {
+		return err
+	}
+	if len(pairs) != 1 {
+		return errors.New("write it as 'FROM=>TO'")
+	}
+	*f = append(*f, pairs[0])
+	return nil
+}
+
+// runMaskDryRun prints the columns a restore to dest would mask, one per line with the
+// number of values. Rules that cannot be applied fail the run, as they would the restore.
+func runMaskDryRun(e *env, record models.ExportRecord, dest models.Profile) int {
+	report, err := e.core.PreviewMasking(record, dest)
+	if err != nil {
+		return e.fail(err)
+	}
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tCOLUMN\tSTRATEGY\tVALUES")
+	for _, c := range report.Columns {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", c.Table, c.Column, c.Strategy, c.Values)
+	}
+	tw.Flush()
+	for _, r := range report.Unused {
+		fmt.Fprintf(e.stderr, "No table in this backup matches %s.%s\n", r.Table, r.Column)
+	}
+	for _, problem := range report.Problems {
+		fmt.Fprintln(e.stderr, "dback: "+problem)
+	}
+	if len(report.Problems) > 0 {
+		return ExitFailed
+	}
+	return ExitOK
+}
+
+func runVerify(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "verify")
+	recordID := fs.String("record", "", "backup record ID")
+	profileKey := fs.String("profile", "", "verify the latest backup of this host")
+	all := fs.Bool("all", false, "verify every backup record")
+	deep := fs.Bool("deep", false, "restore into a temporary database and compare row counts")
+	onKey := fs.String("on", "", "host used for deep verify (default: first importable localhost host)")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	selectors := 0
+	for _, set := range []bool{*recordID != "", *profileKey != "", *all} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return e.usageError(errors.New("verify: use exactly one of --record, --profile or --all"))
+	}
+	if code := e.open(vf); code != ExitOK {
+		return code
+	}
+
+	var records []models.ExportRecord
+	history := e.core.History()
+	switch {
+	case *recordID != "":
+		rec, err := findRecord(history, *recordID)
+		if err != nil {
+			return e.usageError(err)
+		}
+		records = append(records, rec)
+	case *profileKey != "":
+		profile, err := findProfile(e.core.Profiles(), *profileKey)
+		if err != nil {
+			return e.usageError(err)
+		}
+		rec, ok := latestRecord(history, profile.ID)
+		if !ok {
+			return e.usageError(fmt.Errorf("no backups recorded for %q", profile.Name))
+		}
+		records = append(records, rec)
+	default:
+		records = history
+	}
+
+	var dest models.Profile
+	if *deep {
+		var err error
+		dest, err = deepVerifyHost(e.core.Profiles(), *onKey)
+		if err != nil {
+			return e.usageError(err)
+		}
+	}
+
+	code := ExitOK
+	for _, rec := range records {
+		if err := e.ctx.Err(); err != nil {
+			return e.fail(err)
+		}
+		var last models.LastVerified
+		var err error
+		if *deep {
+			fmt.Fprintf(e.stderr, "Deep verifying %s on %s...\n", rec.FilePath, dest.Name)
+			last, err = e.core.DeepVerify(e.ctx, rec.ID, dest, newProgressPrinter(e.stderr).Func())
+		} else {
+			last, err = e.core.QuickVerify(e.ctx, rec.ID)
+		}
+		status := "passed"
+		if err != nil || !last.Passed {
+			status = "failed"
+		}
+		fmt.Fprintf(e.stdout, "%s\t%s\t%s\n", rec.ID, status, rec.FilePath)
+		for _, row := range last.Report {
+			if !row.Match {
+				fmt.Fprintf(e.stdout, "  %s: expected %d, got %d\n", row.Table, row.Expected, row.Actual)
+			}
+		}
+		if err == nil {
+			continue
+		}
+		fmt.Fprintf(e.stderr, "dback: %s: %v\n", rec.ID, err)
+		switch c := exitCodeFor(err); {
+		case c == ExitCanceled:
+			return c
+		case last.VerifiedAt.IsZero():
+			code = ExitFailed
+		case code == ExitOK:
+			code = ExitVerifyMismatch
+		}
+	}
+	return code
+}
+
+// deepVerifyHost resolves --on, falling back to the same default the desktop app uses.
+func deepVerifyHost(profiles []models.Profile, key string) (models.Profile, error) {
+	if strings.TrimSpace(key) != "" {
+		p, err := findProfile(profiles, key)
+		if err != nil {
+			return models.Profile{}, err
+		}
+		if !p.AllowsImport() {
+			return models.Profile{}, fmt.Errorf("host %q is protected from import", p.Name)
+		}
+		return p, nil
+	}
+	var importable []models.Profile
+	for _, p := range profiles {
+		if p.AllowsImport() {
+			importable = append(importable, p)
+		}
+	}
+	for _, p := range importable {
+		if p.IsLocalhost() {
+			return p, nil
+		}
+	}
+	if len(importable) > 0 {
+		return importable[0], nil
+	}
+	return models.Profile{}, errors.New("verify: no importable host for deep verify; pass --on")
+}
+
+func runQuery(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "query")
+	profileKey := fs.String("profile", "", "host profile name or ID")
+	connectDB := fs.Bool("db", false, "connect to the profile's target database")
+	sql := fs.String("sql", "", "SQL to run")
+	file := fs.String("file", "", "read SQL from `PATH`")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if strings.TrimSpace(*profileKey) == "" {
+		return e.usageError(errors.New("query: --profile is required"))
+	}
+	query := *sql
+	switch {
+	case query != "" && *file != "":
+		return e.usageError(errors.New("query: use either --sql or --file"))
+	case *file != "":
+		data, err := os.ReadFile(*file)
+		if err != nil {
+			return e.usageError(err)
+		}
+		query = string(data)
+	case query == "":
+		data, err := io.ReadAll(e.stdin)
+		if err != nil {
+			return e.usageError(err)
+		}
+		query = string(data)
+	}
+	if strings.TrimSpace(query) == "" {
+		return e.usageError(errors.New("query: SQL is empty"))
+	}
+	if code := e.open(vf); code != ExitOK {
+		return code
+	}
+	profile, err := findProfile(e.core.Profiles(), *profileKey)
+	if err != nil {
+		return e.usageError(err)
+	}
+	query = models.SubstituteQuery(query, profile.QueryVars())
+
+	result, err := e.core.RunImportQuery(e.ctx, profile, query, *connectDB)
+	if err != nil {
+		return e.fail(err)
+	}
+	if len(result.Columns) > 0 {
+		fmt.Fprintln(e.stdout, strings.Join(result.Columns, "\t"))
+	}
+	for _, row := range result.Rows {
+		fmt.Fprintln(e.stdout, strings.Join(row, "\t"))
+	}
+	return ExitOK
+}
+
+func runHistory(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "history")
+	profileKey := fs.String("profile", "", "only show backups of this host")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if code := e.open(vf); code != ExitOK {
+		return code
+	}
+	records := e.core.History()
+	if strings.TrimSpace(*profileKey) != "" {
+		profile, err := findProfile(e.core.Profiles(), *profileKey)
+		if err != nil {
+			return e.usageError(err)
+		}
+		filtered := records[:0]
+		for _, rec := range records {
+			if rec.ProfileID == profile.ID {
+				filtered = append(filtered, rec)
+			}
+		}
+		records = filtered
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ExportDate.After(records[j].ExportDate) })
+
+	tw := tabwriter.NewWriter(e.stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tDATE\tPROFILE\tDATABASE\tSIZE\tVERIFY\tFILE")
+	for _, rec := range records {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			rec.ID,
+			rec.ExportDate.Format("2006-01-02 15:04"),
+			rec.ProfileName,
+			rec.DatabaseName,
+			rec.FileSize,
+			coreapp.BackupVerifyStatus(rec),
+			rec.FilePath,
+		)
+	}
+	_ = tw.Flush()
+	return ExitOK
+}
+
+func runPrune(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "prune")
+	profileKey := fs.String("profile", "", "only prune backups of this host")
+	dryRun := fs.Bool("dry-run", false, "list backups that would be removed without deleting them")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	if code := e.open(vf); code != ExitOK {
+		return code
+	}
+	var report coreapp.RetentionReport
+	var err error
+	if strings.TrimSpace(*profileKey) != "" {
+		profile, ferr := findProfile(e.core.Profiles(), *profileKey)
+		if ferr != nil {
+			return e.usageError(ferr)
+		}
+		report, err = e.core.ApplyProfileRetention(e.ctx, profile.ID, *dryRun)
+	} else {
+		report, err = e.core.ApplyRetention(e.ctx, *dryRun)
+	}
+	for _, removal := range report.Removed {
+		fmt.Fprintf(e.stdout, "%s\t%s\t%s\t%s\n", removal.Record.ID, removal.Record.ProfileName, removal.Reason, removal.Record.FilePath)
+	}
+	verb := "Removed"
+	if report.DryRun {
+		verb = "Would remove"
+	}
+	fmt.Fprintf(e.stderr, "%s %d backup(s), %.2f MB\n", verb, len(report.Removed), float64(report.FreedBytes)/1024/1024)
+	if err != nil {
+		return e.fail(err)
+	}
+	return ExitOK
+}
+
+// runDecrypt writes the plaintext of an encrypted backup for disaster recovery. With the
+// host's recovery passphrase it works without the vault; otherwise it opens the vault for
+// its backup key.
+func runDecrypt(e *env, args []string) int {
+	fs, vf := newFlagSet(e, "decrypt")
+	in := fs.String("in", "", "encrypted backup file")
+	out := fs.String("out", "", "where to write the decrypted dump (default: the input without .enc)")
+	recoveryFile := fs.String("recovery-passphrase-file", "", "read the host's recovery passphrase from `PATH`")
+	if err := fs.Parse(args); err != nil {
+		return ExitUsage
+	}
+	src := strings.TrimSpace(*in)
+	if src == "" {
+		return e.usageError(errors.New("decrypt: --in is required"))
+	}
+	dst := strings.TrimSpace(*out)
+	if dst == "" {
+		dst = crypt.TrimExt(src)
+		if dst == src {
+			return e.usageError(errors.New("decrypt: --out is required when the file does not end in " + crypt.Ext))
+		}
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return e.usageError(fmt.Errorf("decrypt: %s already exists", dst))
+	}
+	recovery, err := resolveRecoveryPassphrase(*recoveryFile)
+	if err != nil {
+		return e.usageError(err)
+	}
+	if recovery != "" {
+		err = crypt.DecryptFile(src, dst, crypt.Keys{Passphrase: recovery})
+	} else {
+		if code := e.open(vf); code != ExitOK {
+			return code
+		}
+		err = e.core.DecryptBackupFile(src, dst, "")
+	}
+	if errors.Is(err, crypt.ErrNoKey) || errors.Is(err, crypt.ErrWrongKey) {
+		fmt.Fprintf(e.stderr, "dback: %v\n", err)
+		return ExitVault
+	}
+	if err != nil {
+		return e.fail(err)
+	}
+	fmt.Fprintf(e.stderr, "Decrypted %s to %s\n", src, dst)
+	return ExitOK
+}
+
+// resolveRecoveryPassphrase reads a recovery passphrase from path or DBACK_RECOVERY_PASSPHRASE;
+// empty when neither is set.
+func resolveRecoveryPassphrase(path string) (string, error) {
+	if strings.TrimSpace(path) != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("read recovery passphrase file: %w", err)
+		}
+		pass := strings.TrimRight(string(data), "\r\n")
+		if pass == "" {
+			return "", fmt.Errorf("recovery passphrase file %s is empty", path)
+		}
+		return pass, nil
+	}
+	return os.Getenv(envRecovery), nil
+}
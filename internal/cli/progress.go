@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	coreapp "dback/internal/app"
+)
+
+const progressInterval = 2 * time.Second
+
+// progressPrinter writes ProgressFunc updates to w as plain lines.
+// Repeated percentage ticks of the same step are throttled so logs stay readable in cron mail.
+type progressPrinter struct {
+	w        io.Writer
+	prefix   string // host name when several backups share stderr
+	now      func() time.Time
+	mu       sync.Mutex
+	lastStep string
+	lastAt   time.Time
+}
+
+func newProgressPrinter(w io.Writer) *progressPrinter {
+	return &progressPrinter{w: w, now: time.Now}
+}
+
+// newHostProgressPrinter prefixes every line with the host name (group backups).
+func newHostProgressPrinter(w io.Writer, host string) *progressPrinter {
+	p := newProgressPrinter(w)
+	p.prefix = host + ": "
+	return p
+}
+
+func (p *progressPrinter) Func() coreapp.ProgressFunc {
+	return func(message string, current int64, total int64) {
+		p.print(message)
+	}
+}
+
+func (p *progressPrinter) print(message string) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	step := progressStep(message)
+	now := p.now()
+	if step == p.lastStep && now.Sub(p.lastAt) < progressInterval {
+		return
+	}
+	p.lastStep = step
+	p.lastAt = now
+	fmt.Fprintf(p.w, "[%s] %s%s\n", now.Format("15:04:05"), p.prefix, message)
+}
+
+// progressStep strips numbers from a progress message so "Streaming backup 12.1%"
+// and "Streaming backup 12.7%" count as the same step.
+func progressStep(message string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) || r == '.' || r == '%' {
+			return -1
+		}
+		return r
+	}, message))
+}
@@ -0,0 +1,338 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"dback/models"
+)
+
+// Event types carried in Event.Type.
+const (
+	EventBackup     = "backup"
+	EventRestore    = "restore"
+	EventDeepVerify = "deep_verify"
+)
+
+// Event statuses carried in Event.Status.
+const (
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// DefaultSMTPPort is used when a sink leaves SMTPPort empty.
+const DefaultSMTPPort = 587
+
+// Event is the payload sent to every sink. Webhooks and command hooks receive it as JSON.
+type Event struct {
+	Type        string               `json:"type"`
+	Status      string               `json:"status"`
+	OperationID string               `json:"operation_id"`
+	ProfileID   string               `json:"profile_id"`
+	ProfileName string               `json:"profile_name"`
+	Group       string               `json:"group,omitempty"`
+	Trigger     string               `json:"trigger,omitempty"`
+	StartedAt   time.Time            `json:"started_at"`
+	FinishedAt  time.Time            `json:"finished_at"`
+	Error       string               `json:"error,omitempty"`
+	Record      *models.ExportRecord `json:"record,omitempty"`
+	Logs        []models.LogEntry    `json:"logs,omitempty"`
+}
+
+// Subject is a one-line summary used as the email subject.
+func (e Event) Subject() string {
+	outcome := "succeeded"
+	if e.Status == StatusFailure {
+		outcome = "failed"
+	}
+	return fmt.Sprintf("[DBack] %s %s: %s", eventLabel(e.Type), outcome, e.ProfileName)
+}
+
+// Text is a plain-text body for email.
+func (e Event) Text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", e.Subject())
+	fmt.Fprintf(&b, "Host: %s\n", e.ProfileName)
+	if e.Group != "" {
+		fmt.Fprintf(&b, "Group: %s\n", e.Group)
+	}
+	if e.Trigger != "" {
+		fmt.Fprintf(&b, "Trigger: %s\n", e.Trigger)
+	}
+	fmt.Fprintf(&b, "Started: %s\n", e.StartedAt.Local().Format(time.RFC1123))
+	fmt.Fprintf(&b, "Finished: %s (%s)\n", e.FinishedAt.Local().Format(time.RFC1123), e.FinishedAt.Sub(e.StartedAt).Round(time.Second))
+	fmt.Fprintf(&b, "Operation: %s\n", e.OperationID)
+	if e.Record != nil {
+		fmt.Fprintf(&b, "File: %s (%s)\n", e.Record.FilePath, e.Record.FileSize)
+	}
+	if e.Error != "" {
+		fmt.Fprintf(&b, "\nError: %s\n", e.Error)
+	}
+	if len(e.Logs) > 0 {
+		b.WriteString("\nActivity:\n")
+		for _, entry := range e.Logs {
+			line := entry.Details
+			if entry.Error != "" {
+				line += ": " + entry.Error
+			}
+			fmt.Fprintf(&b, "%s  %-8s %s\n", entry.Timestamp.Local().Format("15:04:05"), entry.Status, line)
+		}
+	}
+	return b.String()
+}
+
+func eventLabel(kind string) string {
+	switch kind {
+	case EventRestore:
+		return "Restore"
+	case EventDeepVerify:
+		return "Deep verify"
+	default:
+		return "Backup"
+	}
+}
+
+// Matches reports whether an enabled sink's rule covers the event.
+func Matches(sink models.NotificationSink, e Event) bool {
+	if !sink.Enabled {
+		return false
+	}
+	switch sink.On {
+	case models.NotifyOnBoth:
+	case models.NotifyOnSuccess, models.NotifyOnFailure:
+		if sink.On != e.Status {
+			return false
+		}
+	default:
+		return false
+	}
+	if sink.ProfileID != "" && sink.ProfileID != e.ProfileID {
+		return false
+	}
+	if group := strings.TrimSpace(sink.Group); group != "" && !strings.EqualFold(group, e.Group) {
+		return false
+	}
+	return true
+}
+
+// Validate checks that a sink has what its kind needs.
+func Validate(sink models.NotificationSink) error {
+	if strings.TrimSpace(sink.Name) == "" {
+		return fmt.Errorf("notification name is required")
+	}
+	switch sink.On {
+	case models.NotifyOnSuccess, models.NotifyOnFailure, models.NotifyOnBoth:
+	default:
+		return fmt.Errorf("notify on must be success, failure or both")
+	}
+	switch sink.Kind {
+	case models.NotifyWebhook:
+		u, err := url.Parse(strings.TrimSpace(sink.WebhookURL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook URL must be an http or https URL")
+		}
+	case models.NotifySMTP:
+		if strings.TrimSpace(sink.SMTPHost) == "" || strings.TrimSpace(sink.SMTPFrom) == "" || len(Recipients(sink)) == 0 {
+			return fmt.Errorf("SMTP host, from and to addresses are required")
+		}
+		if sink.SMTPPort < 0 || sink.SMTPPort > 65535 {
+			return fmt.Errorf("SMTP port must be between 1 and 65535")
+		}
+	case models.NotifyCommand:
+		if strings.TrimSpace(sink.Command) == "" {
+			return fmt.Errorf("command is required")
+		}
+	default:
+		return fmt.Errorf("unknown notification kind %q", sink.Kind)
+	}
+	return nil
+}
+
+// Recipients splits the sink's comma- or semicolon-separated To list.
+func Recipients(sink models.NotificationSink) []string {
+	var out []string
+	for _, addr := range strings.FieldsFunc(sink.SMTPTo, func(r rune) bool { return r == ',' || r == ';' }) {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			out = append(out, addr)
+		}
+	}
+	return out
+}
+
+// Send delivers one event to one sink.
+func Send(ctx context.Context, sink models.NotificationSink, e Event) error {
+	if err := Validate(sink); err != nil {
+		return err
+	}
+	switch sink.Kind {
+	case models.NotifyWebhook:
+		return sendWebhook(ctx, sink, e)
+	case models.NotifySMTP:
+		return sendSMTP(ctx, sink, e)
+	default:
+		return runCommand(ctx, sink, e)
+	}
+}
+
+func sendWebhook(ctx context.Context, sink models.NotificationSink, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSpace(sink.WebhookURL), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "DBack")
+	for k, v := range sink.WebhookHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("webhook returned %s: %s", resp.Status, strings.TrimSpace(string(snippet)))
+	}
+	return nil
+}
+
+func sendSMTP(ctx context.Context, sink models.NotificationSink, e Event) error {
+	port := sink.SMTPPort
+	if port == 0 {
+		port = DefaultSMTPPort
+	}
+	host := strings.TrimSpace(sink.SMTPHost)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Port 465 is SMTPS (implicit TLS); other ports upgrade with STARTTLS when offered.
+	if port == 465 {
+		conn = tls.Client(conn, &tls.Config{ServerName: host})
+	}
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok && port != 465 {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if sink.SMTPUser != "" {
+		if err := c.Auth(smtp.PlainAuth("", sink.SMTPUser, sink.SMTPPassword, host)); err != nil {
+			return err
+		}
+	}
+	from := strings.TrimSpace(sink.SMTPFrom)
+	to := Recipients(sink)
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMessage(from, to, e)); err != nil {
+		w.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func buildMessage(from string, to []string, e Event) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(strings.Join(to, ", ")))
+	fmt.Fprintf(&b, "Subject: %s\r\n", headerValue(e.Subject()))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(e.Text(), "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// headerValue keeps host names and errors from injecting extra mail headers.
+func headerValue(s string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(s)
+}
+
+// runCommand runs the sink's local command with the event as JSON on stdin and the main
+// fields in DBACK_* environment variables.
+func runCommand(ctx context.Context, sink models.NotificationSink, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", sink.Command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", sink.Command)
+	}
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"DBACK_EVENT="+e.Type,
+		"DBACK_STATUS="+e.Status,
+		"DBACK_OPERATION_ID="+e.OperationID,
+		"DBACK_PROFILE="+e.ProfileName,
+		"DBACK_GROUP="+e.Group,
+		"DBACK_ERROR="+e.Error,
+	)
+	if e.Record != nil {
+		cmd.Env = append(cmd.Env, "DBACK_FILE="+e.Record.FilePath)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(out)); msg != "" {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				return fmt.Errorf("%w: %s", err, truncate(msg, 200))
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"dback/models"
+)
+
+func TestMatchesRules(t *testing.T) {
+	failed := Event{Status: StatusFailure, ProfileID: "p1", Group: "Gold"}
+	tests := []struct {
+		name string
+		sink models.NotificationSink
+		want bool
+	}{
+		{"disabled", models.NotificationSink{On: models.NotifyOnBoth}, false},
+		{"both", models.NotificationSink{Enabled: true, On: models.NotifyOnBoth}, true},
+		{"failure only", models.NotificationSink{Enabled: true, On: models.NotifyOnFailure}, true},
+		{"success only", models.NotificationSink{Enabled: true, On: models.NotifyOnSuccess}, false},
+		{"other host", models.NotificationSink{Enabled: true, On: models.NotifyOnBoth, ProfileID: "p2"}, false},
+		{"same group", models.NotificationSink{Enabled: true, On: models.NotifyOnBoth, Group: "gold"}, true},
+		{"other group", models.NotificationSink{Enabled: true, On: models.NotifyOnBoth, Group: "Silver"}, false},
+	}
+	for _, tc := range tests {
+		if got := Matches(tc.sink, failed); got != tc.want {
+			t.Fatalf("%s: Matches = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := []models.NotificationSink{
+		{Name: "hook", Kind: models.NotifyWebhook, On: models.NotifyOnBoth, WebhookURL: "https://example.com/hook"},
+		{Name: "mail", Kind: models.NotifySMTP, On: models.NotifyOnFailure, SMTPHost: "smtp.example.com", SMTPFrom: "dback@example.com", SMTPTo: "a@example.com; b@example.com"},
+		{Name: "cmd", Kind: models.NotifyCommand, On: models.NotifyOnSuccess, Command: "true"},
+	}
+	for _, sink := range valid {
+		if err := Validate(sink); err != nil {
+			t.Fatalf("%s: %v", sink.Name, err)
+		}
+	}
+	invalid := []models.NotificationSink{
+		{Kind: models.NotifyCommand, On: models.NotifyOnBoth, Command: "true"},
+		{Name: "hook", Kind: models.NotifyWebhook, On: models.NotifyOnBoth, WebhookURL: "ftp://example.com"},
+		{Name: "mail", Kind: models.NotifySMTP, On: models.NotifyOnBoth, SMTPHost: "smtp.example.com"},
+		{Name: "cmd", Kind: models.NotifyCommand, On: "sometimes", Command: "true"},
+		{Name: "x", Kind: "pager", On: models.NotifyOnBoth},
+	}
+	for _, sink := range invalid {
+		if err := Validate(sink); err == nil {
+			t.Fatalf("expected %+v to be invalid", sink)
+		}
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	var got Event
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	sink := models.NotificationSink{Name: "hook", Kind: models.NotifyWebhook, On: models.NotifyOnBoth, WebhookURL: srv.URL, WebhookHeaders: map[string]string{"Authorization": "Bearer x"}}
+	event := Event{Type: EventBackup, Status: StatusFailure, ProfileName: "Prod", Error: "boom", Record: &models.ExportRecord{FilePath: "/b/prod.sql.gz"}}
+	if err := Send(context.Background(), sink, event); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != StatusFailure || got.Error != "boom" || got.Record == nil || got.Record.FilePath != "/b/prod.sql.gz" {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+	if auth != "Bearer x" {
+		t.Fatalf("custom header not sent: %q", auth)
+	}
+}
+
+func TestSendWebhookErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	sink := models.NotificationSink{Name: "hook", Kind: models.NotifyWebhook, On: models.NotifyOnBoth, WebhookURL: srv.URL}
+	if err := Send(context.Background(), sink, Event{}); err == nil || !strings.Contains(err.Error(), "502") {
+		t.Fatalf("expected 502 error, got %v", err)
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	sink := models.NotificationSink{Name: "cmd", Kind: models.NotifyCommand, On: models.NotifyOnBoth, Command: `cat > "` + out + `"; echo "$DBACK_STATUS $DBACK_PROFILE" >> "` + out + `"`}
+	if err := Send(context.Background(), sink, Event{Type: EventRestore, Status: StatusSuccess, ProfileName: "Staging"}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"type":"restore"`) || !strings.Contains(string(data), "success Staging") {
+		t.Fatalf("unexpected command output: %s", data)
+	}
+
+	sink.Command = "echo broken >&2; exit 3"
+	if err := Send(context.Background(), sink, Event{}); err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Fatalf("expected failing command error with output, got %v", err)
+	}
+}
+
+func TestBuildMessageStripsHeaderNewlines(t *testing.T) {
+	msg := string(buildMessage("a@example.com", []string{"b@example.com"}, Event{Status: StatusFailure, ProfileName: "evil\r\nBcc: x@example.com"}))
+	headers, _, _ := strings.Cut(msg, "\r\n\r\n")
+	if strings.Contains(headers, "\r\nBcc:") {
+		t.Fatalf("header injection not prevented:\n%s", msg)
+	}
+}
@@ -166,10 +166,13 @@ type appPlainPayload struct {
 	History   []models.ExportRecord `json:"history"`
 	Logs      []models.LogEntry     `json:"logs"`
 	Sync      *models.SyncSettings  `json:"sync,omitempty"`
+	// BackupKeys are the vault keys of encrypted backup files.
+	BackupKeys []string `json:"backup_keys,omitempty"`
 }
 
-// EncryptAppBundle encrypts profile secrets and app metadata into an encrypted AppBundle.
-func EncryptAppBundle(profiles []models.Profile, templates []models.SQLTemplate, history []models.ExportRecord, logs []models.LogEntry, sync *models.SyncSettings, passphrase string) (models.AppBundle, error) {
+// EncryptAppBundle encrypts profile secrets, the vault's backup keys and app metadata into
+// an encrypted AppBundle.
+func EncryptAppBundle(profiles []models.Profile, templates []models.SQLTemplate, history []models.ExportRecord, logs []models.LogEntry, sync *models.SyncSettings, backupKeys []string, passphrase string) (models.AppBundle, error) {
 	if passphrase == "" {
 		return models.AppBundle{}, errors.New("passphrase required for encrypted export")
 	}
@@ -193,12 +196,13 @@ func EncryptAppBundle(profiles []models.Profile, templates []models.SQLTemplate,
 	}
 
 	inner, err := json.Marshal(appPlainPayload{
-		Profiles:  stripped,
-		Secrets:   appSecretPayload{Profiles: secretsList},
-		Templates: templates,
-		History:   history,
-		Logs:      logs,
-		Sync:      sync,
+		Profiles:   stripped,
+		Secrets:    appSecretPayload{Profiles: secretsList},
+		Templates:  templates,
+		History:    history,
+		Logs:       logs,
+		Sync:       sync,
+		BackupKeys: backupKeys,
 	})
 	if err != nil {
 		return models.AppBundle{}, err
@@ -285,5 +289,6 @@ func DecryptAppBundle(bundle models.AppBundle, passphrase string) (models.AppBun
 		History:    payload.History,
 		Logs:       payload.Logs,
 		Sync:       payload.Sync,
+		BackupKeys: payload.BackupKeys,
 	}, nil
 }
@@ -0,0 +1,150 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"dback/models"
+)
+
+// mergeVaultPayload applies what this process changed in the vault, from base to ours, to
+// theirs, the vault as another process left it on disk. The desktop app and the CLI share
+// one vault, so each write keeps the other's changes. Lists are merged item by item on
+// their IDs: items this process added or changed replace or follow theirs, items it removed
+// go unless the other process changed them since. Maps merge key by key; any other field
+// takes ours when this process changed it and theirs otherwise.
+func mergeVaultPayload(base, ours, theirs models.AppVaultPayload) models.AppVaultPayload {
+	merged := models.AppVaultPayload{
+		Version:             ours.Version,
+		Profiles:            mergeByID(base.Profiles, ours.Profiles, theirs.Profiles, func(p models.Profile) string { return p.ID }),
+		Templates:           mergeByID(base.Templates, ours.Templates, theirs.Templates, func(t models.SQLTemplate) string { return t.ID }),
+		History:             mergeByID(base.History, ours.History, theirs.History, func(r models.ExportRecord) string { return r.ID }),
+		Logs:                mergeByID(base.Logs, ours.Logs, theirs.Logs, func(e models.LogEntry) string { return e.ID }),
+		Sync:                mergeValue(base.Sync, ours.Sync, theirs.Sync),
+		SyncActivity:        mergeValue(base.SyncActivity, ours.SyncActivity, theirs.SyncActivity),
+		ImportDestByProfile: mergeMap(base.ImportDestByProfile, ours.ImportDestByProfile, theirs.ImportDestByProfile),
+		ScheduleLastRun:     mergeMap(base.ScheduleLastRun, ours.ScheduleLastRun, theirs.ScheduleLastRun),
+		GroupRetention:      mergeMap(base.GroupRetention, ours.GroupRetention, theirs.GroupRetention),
+		Jobs:                mergeByID(base.Jobs, ours.Jobs, theirs.Jobs, func(j models.JobRecord) string { return j.ID }),
+		Notifications:       mergeByID(base.Notifications, ours.Notifications, theirs.Notifications, func(n models.NotificationSink) string { return n.ID }),
+		Bandwidth:           mergeValue(base.Bandwidth, ours.Bandwidth, theirs.Bandwidth),
+	}
+	merged.BackupKey, merged.OtherBackupKeys = mergeBackupKeys(ours, theirs)
+	return merged
+}
+
+// mergeBackupKeys keeps every backup key either side knows. When both processes gave the
+// vault a key, the one on disk stays the vault's key, as backups may already use it, and
+// the other is kept with the imported ones.
+func mergeBackupKeys(ours, theirs models.AppVaultPayload) (string, []string) {
+	key := theirs.BackupKey
+	if key == "" {
+		key = ours.BackupKey
+	}
+	seen := map[string]bool{key: true}
+	var others []string
+	for _, list := range [][]string{theirs.OtherBackupKeys, ours.OtherBackupKeys, {ours.BackupKey}} {
+		for _, k := range list {
+			if k != "" && !seen[k] {
+				seen[k] = true
+				others = append(others, k)
+			}
+		}
+	}
+	return key, others
+}
+
+// mergeByID merges three versions of a list keyed by id, in the order of theirs with the
+// items only ours has at the end. A list with items that have no ID merges as a whole.
+func mergeByID[T any](base, ours, theirs []T, id func(T) string) []T {
+	baseByID, ok := indexByID(base, id)
+	if !ok {
+		return mergeValue(base, ours, theirs)
+	}
+	oursByID, ok := indexByID(ours, id)
+	if !ok {
+		return mergeValue(base, ours, theirs)
+	}
+	var out []T
+	seen := map[string]bool{}
+	for _, t := range theirs {
+		key := id(t)
+		seen[key] = true
+		o, inOurs := oursByID[key]
+		b, inBase := baseByID[key]
+		switch {
+		case inOurs && (!inBase || !sameJSON(o, b)):
+			out = append(out, o)
+		case inBase && !inOurs && sameJSON(t, b):
+			// Removed here and left alone there.
+		default:
+			out = append(out, t)
+		}
+	}
+	for _, o := range ours {
+		key := id(o)
+		if seen[key] {
+			continue
+		}
+		// Added here, or changed here after the other process removed it.
+		if b, inBase := baseByID[key]; !inBase || !sameJSON(o, b) {
+			out = append(out, o)
+		}
+	}
+	if out == nil && theirs != nil {
+		out = []T{}
+	}
+	return out
+}
+
+func indexByID[T any](items []T, id func(T) string) (map[string]T, bool) {
+	byID := make(map[string]T, len(items))
+	for _, item := range items {
+		key := id(item)
+		if key == "" {
+			return nil, false
+		}
+		byID[key] = item
+	}
+	return byID, true
+}
+
+// mergeMap merges three versions of a map key by key.
+func mergeMap[V any](base, ours, theirs map[string]V) map[string]V {
+	out := make(map[string]V, len(theirs))
+	for k, v := range theirs {
+		out[k] = v
+	}
+	for k, o := range ours {
+		if b, inBase := base[k]; !inBase || !sameJSON(o, b) {
+			out[k] = o
+		}
+	}
+	for k, b := range base {
+		if _, inOurs := ours[k]; !inOurs {
+			if t, inTheirs := theirs[k]; inTheirs && sameJSON(t, b) {
+				delete(out, k)
+			}
+		}
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// mergeValue returns ours when this process changed the value and theirs otherwise.
+func mergeValue[T any](base, ours, theirs T) T {
+	if sameJSON(ours, base) {
+		return theirs
+	}
+	return ours
+}
+
+// sameJSON compares values as the vault stores them, so times read back from disk equal
+// the ones they were written from.
+func sameJSON(a, b any) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
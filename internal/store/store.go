@@ -1,6 +1,8 @@
 package store
 
 import (
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -24,6 +26,11 @@ type Store struct {
 	masterKey []byte
 	vaultSalt string
 	revision  uint64
+	// vaultNonce is the nonce of the vault file this store last read or wrote, and written
+	// its contents; base is this store's data as of then, which the next write merges from.
+	vaultNonce string
+	written    models.AppVaultPayload
+	base       models.AppVaultPayload
 
 	profiles  []models.Profile
 	templates []models.SQLTemplate
@@ -32,6 +39,13 @@ type Store struct {
 	sync                 *models.SyncSettings
 	syncActivity         models.SyncActivity
 	importDestByProfile  map[string]string
+	scheduleLastRun      map[string]time.Time
+	groupRetention       map[string]models.RetentionPolicy
+	jobs                 []models.JobRecord
+	notifications        []models.NotificationSink
+	bandwidth            *models.BandwidthLimit
+	backupKey            string
+	otherBackupKeys      []string
 }
 
 func New(baseDir string) *Store {
@@ -144,6 +158,59 @@ func (s *Store) SaveHistory(records []models.ExportRecord) error {
 	return s.persistVaultLocked()
 }
 
+// LoadJobs returns persisted backup/restore jobs (device-local, not synced or exported).
+func (s *Store) LoadJobs() ([]models.JobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return nil, ErrVaultLocked
+	}
+	return append([]models.JobRecord(nil), s.jobs...), nil
+}
+
+func (s *Store) SaveJobs(jobs []models.JobRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return ErrVaultLocked
+	}
+	s.jobs = append([]models.JobRecord(nil), jobs...)
+	s.bumpRevisionLocked()
+	return s.persistVaultLocked()
+}
+
+// LoadNotificationSinks returns configured notification sinks (secrets included).
+func (s *Store) LoadNotificationSinks() ([]models.NotificationSink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return nil, ErrVaultLocked
+	}
+	return cloneNotificationSinks(s.notifications), nil
+}
+
+func (s *Store) SaveNotificationSinks(sinks []models.NotificationSink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return ErrVaultLocked
+	}
+	s.notifications = cloneNotificationSinks(sinks)
+	s.bumpRevisionLocked()
+	return s.persistVaultLocked()
+}
+
+func cloneNotificationSinks(sinks []models.NotificationSink) []models.NotificationSink {
+	if sinks == nil {
+		return nil
+	}
+	out := make([]models.NotificationSink, len(sinks))
+	for i, sink := range sinks {
+		out[i] = sink.Clone()
+	}
+	return out
+}
+
 func (s *Store) LoadLogs() ([]models.LogEntry, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -254,6 +321,200 @@ func (s *Store) SetImportDestForProfile(sourceProfileID, destProfileID string) e
 	return s.persistVaultLocked()
 }
 
+// ScheduleLastRun returns when the scheduler last started (or first saw) a host's schedule.
+func (s *Store) ScheduleLastRun(profileID string) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return time.Time{}
+	}
+	return s.scheduleLastRun[profileID]
+}
+
+// SetScheduleLastRun records the last scheduled run for a host profile.
+func (s *Store) SetScheduleLastRun(profileID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return ErrVaultLocked
+	}
+	if profileID == "" {
+		return nil
+	}
+	if s.scheduleLastRun == nil {
+		s.scheduleLastRun = map[string]time.Time{}
+	}
+	s.scheduleLastRun[profileID] = at
+	s.bumpRevisionLocked()
+	return s.persistVaultLocked()
+}
+
+// GroupRetention returns the retention policy shared by hosts in a group, or nil.
+func (s *Store) GroupRetention(group string) *models.RetentionPolicy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return nil
+	}
+	policy, ok := s.groupRetention[group]
+	if !ok {
+		return nil
+	}
+	return &policy
+}
+
+// SetGroupRetention stores a group retention policy; nil or an inactive policy removes it.
+func (s *Store) SetGroupRetention(group string, policy *models.RetentionPolicy) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return ErrVaultLocked
+	}
+	if group == "" {
+		return nil
+	}
+	if !policy.Active() {
+		delete(s.groupRetention, group)
+	} else {
+		if s.groupRetention == nil {
+			s.groupRetention = map[string]models.RetentionPolicy{}
+		}
+		s.groupRetention[group] = *policy
+	}
+	s.bumpRevisionLocked()
+	return s.persistVaultLocked()
+}
+
+// Bandwidth returns the app-wide transfer cap, or nil.
+func (s *Store) Bandwidth() *models.BandwidthLimit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return nil
+	}
+	return cloneBandwidth(s.bandwidth)
+}
+
+// SetBandwidth stores the app-wide transfer cap; nil or an inactive cap removes it.
+func (s *Store) SetBandwidth(limit *models.BandwidthLimit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return ErrVaultLocked
+	}
+	if !limit.Active() {
+		limit = nil
+	}
+	s.bandwidth = cloneBandwidth(limit)
+	s.bumpRevisionLocked()
+	return s.persistVaultLocked()
+}
+
+func cloneBandwidth(limit *models.BandwidthLimit) *models.BandwidthLimit {
+	if limit == nil {
+		return nil
+	}
+	c := *limit
+	return &c
+}
+
+// newBackupKey returns a random base64 key for encrypted backups. A vault gets its key
+// when it is created or first unlocked, so every process using it writes with the same one.
+func newBackupKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// BackupKey returns the key new encrypted backups are written with.
+func (s *Store) BackupKey() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return nil, ErrVaultLocked
+	}
+	if s.backupKey == "" {
+		return nil, errors.New("vault has no backup key")
+	}
+	return base64.StdEncoding.DecodeString(s.backupKey)
+}
+
+// BackupKeys returns every key encrypted backups may have been written with: the vault's
+// own first, then those of other vaults that app data was imported from.
+func (s *Store) BackupKeys() ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.unlocked {
+		return nil, ErrVaultLocked
+	}
+	var keys [][]byte
+	for _, encoded := range s.backupKeysLocked() {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// AddBackupKeys keeps the backup keys of an imported bundle, so backups written by the
+// vault it came from open here too. New backups stay on the vault's own key.
+func (s *Store) AddBackupKeys(keys []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.requireUnlocked(); err != nil {
+		return err
+	}
+	known := map[string]bool{}
+	for _, key := range s.backupKeysLocked() {
+		known[key] = true
+	}
+	added := false
+	for _, key := range keys {
+		if known[key] {
+			continue
+		}
+		if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 32 {
+			return errors.New("imported backup key is not a 32-byte base64 key")
+		}
+		known[key] = true
+		s.otherBackupKeys = append(s.otherBackupKeys, key)
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	s.bumpRevisionLocked()
+	return s.persistVaultLocked()
+}
+
+func (s *Store) backupKeysLocked() []string {
+	var keys []string
+	if s.backupKey != "" {
+		keys = append(keys, s.backupKey)
+	}
+	return append(keys, s.otherBackupKeys...)
+}
+
+func cloneRetentionMap(src map[string]models.RetentionPolicy) map[string]models.RetentionPolicy {
+	out := make(map[string]models.RetentionPolicy, len(src))
+	for k, v := range src {
+		out[k] = v
+	}
+	return out
+}
+
+func cloneTimeMap(src map[string]time.Time) map[string]time.Time {
+	out := make(map[string]time.Time, len(src))
+	for k, v := range src {
+		out[k] = v
+	}
+	return out
+}
+
 // ValidateMasterPassphrase checks the passphrase against the vault unlock key.
 func (s *Store) ValidateMasterPassphrase(passphrase string) error {
 	s.mu.Lock()
@@ -312,6 +573,9 @@ type AppImportData struct {
 	History   []models.ExportRecord
 	Logs      []models.LogEntry
 	Sync      *models.SyncSettings
+	// BackupKeys are the vault keys encrypted backups are written with, carried only by
+	// bundles that include secrets.
+	BackupKeys []string
 }
 
 // TemplateConflict describes an imported template that replaces an existing one.
@@ -381,11 +645,12 @@ func (s *Store) decodeAppBundle(bundle models.AppBundle, includeSecrets bool, pa
 			return AppImportData{}, err
 		}
 		return AppImportData{
-			Profiles:  flattenProfiles(decoded.Profiles),
-			Templates: decoded.Templates,
-			History:   decoded.History,
-			Logs:      decoded.Logs,
-			Sync:      decoded.Sync.Clone(),
+			Profiles:   flattenProfiles(decoded.Profiles),
+			Templates:  decoded.Templates,
+			History:    decoded.History,
+			Logs:       decoded.Logs,
+			Sync:       decoded.Sync.Clone(),
+			BackupKeys: decoded.BackupKeys,
 		}, nil
 	}
 	profiles := flattenProfiles(bundle.Profiles)
@@ -425,7 +690,12 @@ func (s *Store) MarshalAppDataBundle(data AppImportData, includeSecrets bool, pa
 		payload.Profiles[i].ImportSettings = nil
 	}
 	if includeSecrets && passphrase != "" {
-		bundle, err := secrets.EncryptAppBundle(payload.Profiles, payload.Templates, payload.History, payload.Logs, payload.Sync, passphrase)
+		// Without the vault's backup keys, encrypted backups open only with a recovery
+		// passphrase wherever the bundle is imported.
+		s.mu.Lock()
+		keys := s.backupKeysLocked()
+		s.mu.Unlock()
+		bundle, err := secrets.EncryptAppBundle(payload.Profiles, payload.Templates, payload.History, payload.Logs, payload.Sync, keys, passphrase)
 		if err != nil {
 			return nil, err
 		}
@@ -765,14 +1035,17 @@ func normalizeProfile(p models.Profile) models.Profile {
 	}
 	if p.DBPort == "" {
 		p.DBPort = "3306"
+		if p.UsesPostgreSQL() {
+			p.DBPort = "5432"
+		}
 	}
 	return p
 }
 
 func normalizeDBType(t models.DBType) models.DBType {
 	switch t {
-	case models.DBTypeMariaDB:
-		return models.DBTypeMariaDB
+	case models.DBTypeMariaDB, models.DBTypePostgreSQL:
+		return t
 	default:
 		return models.DBTypeMySQL
 	}
@@ -786,6 +1059,11 @@ func stripSecrets(profiles []models.Profile) []models.Profile {
 		profiles[i].AuthKeyPEM = ""
 		profiles[i].JumpAuthKeyPEM = ""
 		profiles[i].WPKey = ""
+		if e := profiles[i].Encryption; e != nil {
+			stripped := *e
+			stripped.RecoveryPassphrase = ""
+			profiles[i].Encryption = &stripped
+		}
 	}
 	return profiles
 }
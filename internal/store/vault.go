@@ -95,6 +95,11 @@ func (s *Store) CreateVault(passphrase string) error {
 		History:   []models.ExportRecord{},
 		Logs:      []models.LogEntry{},
 	}
+	key, err := newBackupKey()
+	if err != nil {
+		return err
+	}
+	payload.BackupKey = key
 	if err := s.writeVaultLocked(passphrase, payload); err != nil {
 		log.Printf("store.CreateVault: writeVaultLocked failed: %v", err)
 		return err
@@ -136,6 +141,15 @@ func (s *Store) Unlock(passphrase string) error {
 		s.setMasterKeyLocked(passphrase)
 		s.unlocked = true
 		s.bumpRevisionLocked()
+		if s.backupKey == "" {
+			// Vaults from before backup encryption get their key once, here, rather than
+			// from whichever process encrypts a backup first.
+			if err := s.addBackupKeyLocked(); err != nil {
+				log.Printf("store.Unlock: creating the backup key failed: %v", err)
+				s.lockLocked()
+				return err
+			}
+		}
 		_ = s.removeLegacyPlaintextLocked()
 		log.Printf("store.Unlock: vault unlocked (profiles=%d templates=%d)", len(payload.Profiles), len(payload.Templates))
 		return nil
@@ -148,6 +162,9 @@ func (s *Store) Unlock(passphrase string) error {
 			log.Printf("store.Unlock: loadLegacyPayloadLocked failed: %v", err)
 			return err
 		}
+		if payload.BackupKey, err = newBackupKey(); err != nil {
+			return err
+		}
 		if err := s.writeVaultLocked(passphrase, payload); err != nil {
 			log.Printf("store.Unlock: writeVaultLocked (migrate) failed: %v", err)
 			return err
@@ -183,13 +200,51 @@ func (s *Store) applyPayloadLocked(payload models.AppVaultPayload) {
 	} else {
 		s.importDestByProfile = map[string]string{}
 	}
+	s.scheduleLastRun = cloneTimeMap(payload.ScheduleLastRun)
+	s.groupRetention = cloneRetentionMap(payload.GroupRetention)
+	s.jobs = append([]models.JobRecord(nil), payload.Jobs...)
+	s.notifications = cloneNotificationSinks(payload.Notifications)
+	s.bandwidth = cloneBandwidth(payload.Bandwidth)
+	s.backupKey = payload.BackupKey
+	s.otherBackupKeys = append([]string(nil), payload.OtherBackupKeys...)
+	s.base = s.currentPayloadLocked()
+}
+
+// addBackupKeyLocked gives an unlocked vault without a backup key its key.
+func (s *Store) addBackupKeyLocked() error {
+	key, err := newBackupKey()
+	if err != nil {
+		return err
+	}
+	s.backupKey = key
+	if err := s.persistVaultLocked(); err != nil {
+		s.backupKey = ""
+		return err
+	}
+	return nil
 }
 
+func (s *Store) vaultLockPath() string {
+	return s.VaultPath() + ".lock"
+}
+
+// persistVaultLocked writes this store's data to the vault. Another process may have
+// written the vault since this store read it, so under the vault's file lock the file is
+// read again and this store's changes are merged into it (mergeVaultPayload).
 func (s *Store) persistVaultLocked() error {
 	if !s.unlocked || len(s.dataKey) == 0 {
 		return ErrVaultLocked
 	}
-	payload := s.currentPayloadLocked()
+	unlock, err := lockVaultFile(s.vaultLockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	theirs, err := s.readLatestVaultLocked()
+	if err != nil {
+		return err
+	}
+	payload := mergeVaultPayload(s.base, s.currentPayloadLocked(), theirs)
 	nonce, ciphertext, err := secrets.MarshalEncryptVault(s.dataKey, payload)
 	if err != nil {
 		return err
@@ -201,7 +256,45 @@ func (s *Store) persistVaultLocked() error {
 		UpdatedAt:        time.Now(),
 		EncryptedPayload: base64.StdEncoding.EncodeToString(ciphertext),
 	}
-	return writeJSON(s.VaultPath(), file)
+	if err := writeJSON(s.VaultPath(), file); err != nil {
+		return err
+	}
+	// The other process may have given the vault its backup key first.
+	s.backupKey = payload.BackupKey
+	s.otherBackupKeys = append([]string(nil), payload.OtherBackupKeys...)
+	s.vaultNonce, s.written = file.Nonce, payload
+	s.base = s.currentPayloadLocked()
+	return nil
+}
+
+// readLatestVaultLocked returns the vault as it is on disk: what this store last wrote or
+// read when no other process has written it since, or the file decrypted again.
+func (s *Store) readLatestVaultLocked() (models.AppVaultPayload, error) {
+	var file models.AppVaultFile
+	if err := readJSON(s.VaultPath(), &file); err != nil {
+		return models.AppVaultPayload{}, err
+	}
+	if file.Nonce == s.vaultNonce {
+		return s.written, nil
+	}
+	key := s.dataKey
+	if file.Salt != s.vaultSalt {
+		passphrase, err := s.masterPassphraseLocked()
+		if err != nil {
+			return models.AppVaultPayload{}, err
+		}
+		salt, err := base64.StdEncoding.DecodeString(file.Salt)
+		if err != nil {
+			return models.AppVaultPayload{}, fmt.Errorf("invalid vault salt: %w", err)
+		}
+		key = secrets.DeriveKey(passphrase, salt)
+	}
+	payload, err := decryptVaultFile(file, key)
+	if err != nil {
+		return models.AppVaultPayload{}, fmt.Errorf("read vault written by another process: %w", err)
+	}
+	s.dataKey, s.vaultSalt = key, file.Salt
+	return payload, nil
 }
 
 func (s *Store) currentPayloadLocked() models.AppVaultPayload {
@@ -219,6 +312,13 @@ func (s *Store) currentPayloadLocked() models.AppVaultPayload {
 		Sync:                s.sync.Clone(),
 		SyncActivity:        s.syncActivity,
 		ImportDestByProfile: cloneStringMap(s.importDestByProfile),
+		ScheduleLastRun:     cloneTimeMap(s.scheduleLastRun),
+		GroupRetention:      cloneRetentionMap(s.groupRetention),
+		Jobs:                append([]models.JobRecord(nil), s.jobs...),
+		Notifications:       cloneNotificationSinks(s.notifications),
+		Bandwidth:           cloneBandwidth(s.bandwidth),
+		BackupKey:           s.backupKey,
+		OtherBackupKeys:     append([]string(nil), s.otherBackupKeys...),
 	}
 }
 
@@ -241,12 +341,18 @@ func (s *Store) writeVaultLocked(passphrase string, payload models.AppVaultPaylo
 		UpdatedAt:        time.Now(),
 		EncryptedPayload: base64.StdEncoding.EncodeToString(ciphertext),
 	}
+	unlock, err := lockVaultFile(s.vaultLockPath())
+	if err != nil {
+		return err
+	}
+	defer unlock()
 	if err := writeJSON(s.VaultPath(), file); err != nil {
 		log.Printf("store.writeVaultLocked: writeJSON to %q failed: %v", s.VaultPath(), err)
 		return err
 	}
 	s.dataKey = key
 	s.vaultSalt = file.Salt
+	s.vaultNonce, s.written = file.Nonce, payload
 	return nil
 }
 
@@ -260,22 +366,31 @@ func (s *Store) readVaultFileLocked(passphrase string) (models.AppVaultPayload,
 	if err != nil {
 		return models.AppVaultPayload{}, nil, fmt.Errorf("invalid vault salt: %w", err)
 	}
+	key := secrets.DeriveKey(passphrase, salt)
+	payload, err := decryptVaultFile(file, key)
+	if err != nil {
+		log.Printf("store.readVaultFileLocked: decrypt failed (wrong key or corrupt vault): %v", err)
+		return models.AppVaultPayload{}, nil, err
+	}
+	s.vaultSalt = file.Salt
+	s.vaultNonce, s.written = file.Nonce, payload
+	return payload, key, nil
+}
+
+func decryptVaultFile(file models.AppVaultFile, key []byte) (models.AppVaultPayload, error) {
 	nonce, err := base64.StdEncoding.DecodeString(file.Nonce)
 	if err != nil {
-		return models.AppVaultPayload{}, nil, fmt.Errorf("invalid vault nonce: %w", err)
+		return models.AppVaultPayload{}, fmt.Errorf("invalid vault nonce: %w", err)
 	}
 	ciphertext, err := base64.StdEncoding.DecodeString(file.EncryptedPayload)
 	if err != nil {
-		return models.AppVaultPayload{}, nil, fmt.Errorf("invalid vault payload: %w", err)
+		return models.AppVaultPayload{}, fmt.Errorf("invalid vault payload: %w", err)
 	}
-	key := secrets.DeriveKey(passphrase, salt)
 	payload, err := secrets.DecryptUnmarshalVault(key, nonce, ciphertext)
 	if err != nil {
-		log.Printf("store.readVaultFileLocked: decrypt failed (wrong key or corrupt vault): %v", err)
-		return models.AppVaultPayload{}, nil, ErrWrongMasterKey
+		return models.AppVaultPayload{}, ErrWrongMasterKey
 	}
-	s.vaultSalt = file.Salt
-	return payload, key, nil
+	return payload, nil
 }
 
 func (s *Store) hasLegacyPlaintextLocked() bool {
@@ -391,6 +506,10 @@ func (s *Store) removeLegacyPlaintextLocked() error {
 func (s *Store) Lock() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.lockLocked()
+}
+
+func (s *Store) lockLocked() {
 	for i := range s.dataKey {
 		s.dataKey[i] = 0
 	}
@@ -404,6 +523,16 @@ func (s *Store) Lock() {
 	s.logs = nil
 	s.sync = nil
 	s.syncActivity = models.SyncActivity{}
+	s.scheduleLastRun = nil
+	s.groupRetention = nil
+	s.jobs = nil
+	s.notifications = nil
+	s.bandwidth = nil
+	s.backupKey = ""
+	s.otherBackupKeys = nil
+	s.vaultNonce = ""
+	s.written = models.AppVaultPayload{}
+	s.base = models.AppVaultPayload{}
 }
 
 func (s *Store) setMasterKeyLocked(passphrase string) {
@@ -1,10 +1,16 @@
 package store
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"dback/models"
 )
@@ -152,6 +158,28 @@ func TestVaultPersistsImportDestByProfile(t *testing.T) {
 	}
 }
 
+func TestVaultPersistsScheduleLastRun(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	unlockStore(t, s)
+
+	at := time.Date(2026, 3, 1, 3, 0, 0, 0, time.UTC)
+	if err := s.SetScheduleLastRun("p1", at); err != nil {
+		t.Fatal(err)
+	}
+
+	s2 := New(dir)
+	if err := s2.Unlock(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	if got := s2.ScheduleLastRun("p1"); !got.Equal(at) {
+		t.Fatalf("schedule last run not persisted: %v", got)
+	}
+	if got := s2.ScheduleLastRun("p2"); !got.IsZero() {
+		t.Fatalf("expected zero time for unknown profile, got %v", got)
+	}
+}
+
 func TestVaultPersistsTemplatesHistoryLogs(t *testing.T) {
 	dir := t.TempDir()
 	s := New(dir)
@@ -185,3 +213,219 @@ func TestVaultPersistsTemplatesHistoryLogs(t *testing.T) {
 		t.Fatalf("logs not persisted: %#v err=%v", logs, err)
 	}
 }
+
+func TestBackupKeyPersistsAcrossUnlock(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	if _, err := s.BackupKey(); err != ErrVaultLocked {
+		t.Fatalf("locked store: got %v, want ErrVaultLocked", err)
+	}
+	unlockStore(t, s)
+
+	// Another process opening the vault reads the key made with it.
+	other := New(dir)
+	unlockStore(t, other)
+	key, err := other.BackupKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(key) != 32 {
+		t.Fatalf("key is %d bytes, want 32", len(key))
+	}
+	if again, err := s.BackupKey(); err != nil || string(again) != string(key) {
+		t.Fatalf("two stores of one vault use different backup keys: %v", err)
+	}
+
+	// A vault from before backup encryption gets its key once, on unlock.
+	s.mu.Lock()
+	old := s.currentPayloadLocked()
+	old.BackupKey = ""
+	if err := s.writeVaultLocked(testMasterKey, old); err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Unlock()
+	first := New(dir)
+	unlockStore(t, first)
+	second := New(dir)
+	unlockStore(t, second)
+	a, _ := first.BackupKey()
+	b, err := second.BackupKey()
+	if err != nil || len(a) != 32 || string(a) != string(b) {
+		t.Fatalf("upgraded vault: keys %x and %x, %v", a, b, err)
+	}
+}
+
+func TestAppDataBundleCarriesBackupKeys(t *testing.T) {
+	s := New(t.TempDir())
+	unlockStore(t, s)
+	key, err := s.BackupKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(key)
+
+	plain, err := s.MarshalAppDataBundle(AppImportData{}, false, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), encoded) {
+		t.Fatal("a bundle without secrets carries the backup key")
+	}
+	raw, err := s.MarshalAppDataBundleForSync(AppImportData{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(raw), encoded) {
+		t.Fatal("the backup key is in the clear")
+	}
+
+	other := New(t.TempDir())
+	unlockStore(t, other)
+	imported, err := other.ImportAppDataBytes(raw, true, testMasterKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(imported.BackupKeys) != 1 || imported.BackupKeys[0] != encoded {
+		t.Fatalf("imported keys = %v", imported.BackupKeys)
+	}
+	if err := other.AddBackupKeys(imported.BackupKeys); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.AddBackupKeys(imported.BackupKeys); err != nil {
+		t.Fatal(err)
+	}
+	reopened := New(other.baseDir)
+	unlockStore(t, reopened)
+	keys, err := reopened.BackupKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || string(keys[1]) != string(key) {
+		t.Fatalf("keys after import = %d", len(keys))
+	}
+}
+
+func TestMergeVaultPayload(t *testing.T) {
+	base := models.AppVaultPayload{
+		Profiles:        []models.Profile{{ID: "a", Name: "A"}, {ID: "b", Name: "B"}, {ID: "c", Name: "C"}, {ID: "d", Name: "D"}},
+		ScheduleLastRun: map[string]time.Time{"a": time.Unix(1, 0), "b": time.Unix(1, 0)},
+		Bandwidth:       &models.BandwidthLimit{UploadMBps: 100},
+		BackupKey:       "",
+	}
+	ours := models.AppVaultPayload{
+		// a renamed, b removed, c removed, e added.
+		Profiles:        []models.Profile{{ID: "a", Name: "A2"}, {ID: "d", Name: "D"}, {ID: "e", Name: "E"}},
+		ScheduleLastRun: map[string]time.Time{"a": time.Unix(2, 0)},
+		Bandwidth:       &models.BandwidthLimit{UploadMBps: 100},
+		BackupKey:       "ours",
+	}
+	theirs := models.AppVaultPayload{
+		// a renamed too, c changed, d changed, f added.
+		Profiles:        []models.Profile{{ID: "a", Name: "A3"}, {ID: "b", Name: "B"}, {ID: "c", Name: "C3"}, {ID: "d", Name: "D3"}, {ID: "f", Name: "F"}},
+		ScheduleLastRun: map[string]time.Time{"a": time.Unix(1, 0), "b": time.Unix(1, 0), "f": time.Unix(3, 0)},
+		Bandwidth:       &models.BandwidthLimit{UploadMBps: 50},
+		BackupKey:       "theirs",
+	}
+	merged := mergeVaultPayload(base, ours, theirs)
+	var names []string
+	for _, p := range merged.Profiles {
+		names = append(names, p.ID+"="+p.Name)
+	}
+	if got := strings.Join(names, ","); got != "a=A2,c=C3,d=D3,f=F,e=E" {
+		t.Fatalf("profiles = %s", got)
+	}
+	if len(merged.ScheduleLastRun) != 2 || !merged.ScheduleLastRun["a"].Equal(time.Unix(2, 0)) || !merged.ScheduleLastRun["f"].Equal(time.Unix(3, 0)) {
+		t.Fatalf("schedule = %v", merged.ScheduleLastRun)
+	}
+	if merged.Bandwidth.UploadMBps != 50 {
+		t.Fatalf("bandwidth = %+v", merged.Bandwidth)
+	}
+	if merged.BackupKey != "theirs" || len(merged.OtherBackupKeys) != 1 || merged.OtherBackupKeys[0] != "ours" {
+		t.Fatalf("backup keys = %q %q", merged.BackupKey, merged.OtherBackupKeys)
+	}
+}
+
+// TestVaultHelperProcess is the other process of TestVaultKeepsWritesOfOtherProcesses.
+func TestVaultHelperProcess(t *testing.T) {
+	dir, name := os.Getenv("DBACK_VAULT_HELPER_DIR"), os.Getenv("DBACK_VAULT_HELPER_NAME")
+	if dir == "" {
+		return
+	}
+	s := New(dir)
+	if err := s.Unlock(testMasterKey); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		logs, err := s.LoadLogs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveLogs(append(logs, models.LogEntry{ID: fmt.Sprintf("%s-%d", name, i)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profiles, err := s.LoadProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveProfiles(append(profiles, models.Profile{ID: name, Name: name})); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultKeepsWritesOfOtherProcesses(t *testing.T) {
+	dir := t.TempDir()
+	s := New(dir)
+	unlockStore(t, s)
+	if err := s.SaveProfiles([]models.Profile{{ID: "desktop", Name: "desktop"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Two CLI runs write the vault while this store, unlocked before them, keeps writing
+	// from its own copy.
+	type helper struct {
+		cmd *exec.Cmd
+		out bytes.Buffer
+	}
+	var helpers []*helper
+	for _, name := range []string{"cli-a", "cli-b"} {
+		h := &helper{cmd: exec.Command(os.Args[0], "-test.run=^TestVaultHelperProcess$")}
+		h.cmd.Env = append(os.Environ(), "DBACK_VAULT_HELPER_DIR="+dir, "DBACK_VAULT_HELPER_NAME="+name)
+		h.cmd.Stdout, h.cmd.Stderr = &h.out, &h.out
+		if err := h.cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		helpers = append(helpers, h)
+	}
+	for i := 0; i < 5; i++ {
+		logs, _ := s.LoadLogs()
+		if err := s.SaveLogs(append(logs, models.LogEntry{ID: fmt.Sprintf("desktop-%d", i)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, h := range helpers {
+		if err := h.cmd.Wait(); err != nil {
+			t.Fatalf("helper: %v\n%s", err, h.out.String())
+		}
+	}
+	// Removing a profile here keeps the ones the other processes added.
+	if err := s.SaveProfiles([]models.Profile{{ID: "desktop-2", Name: "desktop-2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := New(dir)
+	unlockStore(t, reopened)
+	logs, _ := reopened.LoadLogs()
+	if len(logs) != 15 {
+		t.Fatalf("%d log entries, want 15: %v", len(logs), logs)
+	}
+	profiles, _ := reopened.LoadProfiles()
+	var ids []string
+	for _, p := range profiles {
+		ids = append(ids, p.ID)
+	}
+	sort.Strings(ids)
+	if got := strings.Join(ids, ","); got != "cli-a,cli-b,desktop-2" {
+		t.Fatalf("profiles = %s", got)
+	}
+}
@@ -2,9 +2,11 @@ package main
 
 import (
 	_ "embed"
+	"io"
 	"log"
 	"os"
 
+	"dback/internal/cli"
 	"dback/internal/debug"
 	"dback/ui"
 )
@@ -25,6 +27,13 @@ func main() {
 	}
 	os.Args = append([]string{os.Args[0]}, stripDebugFlag(args)...)
 
+	if cli.IsCommand(os.Args[1:]) {
+		if !debug.Enabled {
+			log.SetOutput(io.Discard)
+		}
+		os.Exit(cli.Run(os.Args[1:], ui.DesktopPlatform{}.AppDataDir(), os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			log.Printf("panic: %v\n%s", r, debug.Stack())
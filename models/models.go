@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
@@ -23,12 +24,13 @@ const (
 	ConnectionTypeWordPress  ConnectionType = "WordPress"
 )
 
-// DBType defines the database type (MySQL/MariaDB only)
+// DBType defines the database type (MySQL/MariaDB, or PostgreSQL on SSH hosts)
 type DBType string
 
 const (
-	DBTypeMySQL   DBType = "MySQL"
-	DBTypeMariaDB DBType = "MariaDB"
+	DBTypeMySQL      DBType = "MySQL"
+	DBTypeMariaDB    DBType = "MariaDB"
+	DBTypePostgreSQL DBType = "PostgreSQL"
 )
 
 // Profile represents an independent Host with unified connection settings.
@@ -72,14 +74,272 @@ type Profile struct {
 	PostImportQuery      string `json:"post_import_query,omitempty"`
 	RunQueryAfterImport  bool   `json:"run_query_after_import,omitempty"`
 
+	// Databases backs up several databases per run instead of TargetDBName alone (SSH hosts).
+	Databases *DatabaseSelection `json:"databases,omitempty"`
+
+	// Tables filters which tables each dump contains (SSH and WordPress hosts).
+	Tables *TableFilter `json:"tables,omitempty"`
+
+	// DumpFormat is how backups are stored: one .sql.gz (default), a split archive or a
+	// manifest in the destination's deduplicating repository.
+	DumpFormat DumpFormat `json:"dump_format,omitempty"`
+	// Compression is the codec dumps are written with; empty means gzip. CompressionLevel
+	// 0 uses the codec's default level.
+	Compression      Compression `json:"compression,omitempty"`
+	CompressionLevel int         `json:"compression_level,omitempty"`
+	// PhysicalBackup copies the server's data files with mariadb-backup or xtrabackup instead
+	// of dumping SQL when preflight finds the tool (SSH and Localhost hosts).
+	PhysicalBackup bool `json:"physical_backup,omitempty"`
+	// Encryption encrypts dump files as they are written (SSH and Localhost hosts).
+	Encryption *BackupEncryption `json:"encryption,omitempty"`
+
+	// Export hooks run around the dump: SQL against TargetDBName and, on SSH hosts, a remote
+	// shell command. Post hooks run even when the dump fails. Hook failures are only logged
+	// unless AbortOnExportHookFailure is set.
+	PreExportQuery           string `json:"pre_export_sql,omitempty"`
+	PostExportQuery          string `json:"post_export_sql,omitempty"`
+	PreExportCommand         string `json:"pre_export_command,omitempty"`
+	PostExportCommand        string `json:"post_export_command,omitempty"`
+	AbortOnExportHookFailure bool   `json:"abort_on_export_hook_failure,omitempty"`
+
 	// ImportProtected blocks restore/import to this host (production safety).
 	ImportProtected bool `json:"import_protected,omitempty"`
+	// Masking rewrites columns of the rows restored to this host before they are uploaded.
+	Masking []MaskRule `json:"masking,omitempty"`
+
+	// Schedule runs backups automatically while the app is unlocked.
+	Schedule *BackupSchedule `json:"schedule,omitempty"`
+
+	// Binlog adds incremental backups from the server's binary logs (SSH and Localhost hosts).
+	Binlog *BinlogSettings `json:"binlog,omitempty"`
+
+	// Retention prunes old backups of this host; nil falls back to the group policy.
+	Retention *RetentionPolicy `json:"retention,omitempty"`
+
+	// Bandwidth caps this host's transfers, on top of the app-wide cap.
+	Bandwidth *BandwidthLimit `json:"bandwidth,omitempty"`
 
 	// Legacy fields — read-only for migration; not written on save.
 	ExportSettings *TransferSettings `json:"export_settings,omitempty"`
 	ImportSettings *TransferSettings `json:"import_settings,omitempty"`
 }
 
+// BackupSchedule configures automatic backups for a host.
+// Cron takes precedence over IntervalMinutes. WindowStart/WindowEnd ("HH:MM", local time)
+// limit when a due backup may start; a window may wrap past midnight.
+type BackupSchedule struct {
+	Enabled         bool   `json:"enabled,omitempty"`
+	Cron            string `json:"cron,omitempty"`
+	IntervalMinutes int    `json:"interval_minutes,omitempty"`
+	WindowStart     string `json:"window_start,omitempty"`
+	WindowEnd       string `json:"window_end,omitempty"`
+}
+
+// Active reports whether the schedule is enabled and has a cron expression or interval.
+func (s *BackupSchedule) Active() bool {
+	return s != nil && s.Enabled && (strings.TrimSpace(s.Cron) != "" || s.IntervalMinutes > 0)
+}
+
+// BandwidthLimit caps transfer speed in MB/s: Upload for data sent to a host (restores,
+// deep verify), Download for data received from it (backups). Zero leaves that direction
+// unlimited. WindowStart/WindowEnd ("HH:MM", local time) limit when the caps apply; a window
+// may wrap past midnight, and without one they always apply.
+type BandwidthLimit struct {
+	UploadMBps   float64 `json:"upload_mbps,omitempty"`
+	DownloadMBps float64 `json:"download_mbps,omitempty"`
+	WindowStart  string  `json:"window_start,omitempty"`
+	WindowEnd    string  `json:"window_end,omitempty"`
+}
+
+// Active reports whether either direction is capped.
+func (b *BandwidthLimit) Active() bool {
+	return b != nil && (b.UploadMBps > 0 || b.DownloadMBps > 0)
+}
+
+// BinlogSettings configures binlog-based incremental backups. Full dumps record the binary
+// log position they are consistent with; every IntervalMinutes the events written since the
+// newest backup are pulled with mysqlbinlog and stored as the next link of its chain.
+type BinlogSettings struct {
+	Enabled         bool `json:"enabled,omitempty"`
+	IntervalMinutes int  `json:"interval_minutes,omitempty"`
+}
+
+// Active reports whether full dumps record binlog positions and incrementals may be pulled.
+func (s *BinlogSettings) Active() bool {
+	return s != nil && s.Enabled
+}
+
+// BackupEncryption encrypts a host's backup files at rest with the vault's backup key. A
+// recovery passphrase, when set, opens the files too, so they can be decrypted with
+// "dback decrypt" without the vault.
+type BackupEncryption struct {
+	Enabled            bool   `json:"enabled,omitempty"`
+	RecoveryPassphrase string `json:"recovery_passphrase,omitempty"`
+}
+
+// Active reports whether new backups are encrypted.
+func (e *BackupEncryption) Active() bool {
+	return e != nil && e.Enabled
+}
+
+// MaskRule masks one column of the rows a restore sends to a host. Table is a glob pattern
+// ("wp_*users"); Column is matched case-insensitively. Value is the replacement for
+// MaskFixed. NULL values are left as they are.
+type MaskRule struct {
+	Table    string       `json:"table"`
+	Column   string       `json:"column"`
+	Strategy MaskStrategy `json:"strategy"`
+	Value    string       `json:"value,omitempty"`
+}
+
+// MaskStrategy is how a masked column's values are replaced.
+type MaskStrategy string
+
+const (
+	// MaskFakeEmail replaces the value with user-{hash}@example.com.
+	MaskFakeEmail MaskStrategy = "fake_email"
+	// MaskKeepDomain replaces the part before the @ and keeps the domain.
+	MaskKeepDomain MaskStrategy = "keep_domain"
+	// MaskHash replaces the value with its SHA-256 in hex.
+	MaskHash MaskStrategy = "hash"
+	// MaskNull replaces the value with NULL.
+	MaskNull MaskStrategy = "null"
+	// MaskFixed replaces the value with the rule's Value.
+	MaskFixed MaskStrategy = "fixed"
+)
+
+// ReplacePair is one search/replace of a restore: every occurrence of From in the restored
+// rows becomes To. PHP-serialized values keep their string lengths right.
+type ReplacePair struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DefinerMode is what a restore does with the DEFINER clauses of views, triggers,
+// routines and events, which name a user the destination may not have.
+type DefinerMode string
+
+const (
+	// DefinerKeep restores the clauses as they are in the backup.
+	DefinerKeep DefinerMode = ""
+	// DefinerStrip removes them: the objects belong to the user the restore connects as.
+	DefinerStrip DefinerMode = "strip"
+	// DefinerRewrite names the destination's DBUser, as CURRENT_USER, so the host part
+	// matches the account the restore connects as.
+	DefinerRewrite DefinerMode = "rewrite"
+	// DefinerInvoker removes them and turns SQL SECURITY DEFINER into INVOKER, so views
+	// and routines run with the privileges of whoever calls them.
+	DefinerInvoker DefinerMode = "invoker"
+)
+
+// DatabaseSelection picks the databases a multi-database backup dumps. Each database gets its
+// own .sql.gz file and history record, all under one operation ID.
+type DatabaseSelection struct {
+	Mode    string   `json:"mode"`
+	Names   []string `json:"names,omitempty"`   // DatabasesList
+	Pattern string   `json:"pattern,omitempty"` // DatabasesPattern: comma-separated globs, e.g. "shop_*, crm"
+}
+
+// Database selection modes.
+const (
+	DatabasesList    = "list"
+	DatabasesPattern = "pattern"
+	DatabasesAll     = "all" // every non-system database
+)
+
+// Active reports whether the selection replaces the single TargetDBName backup.
+func (s *DatabaseSelection) Active() bool {
+	return s != nil && s.Mode != ""
+}
+
+// TableFilter limits a dump to some tables. Entries are glob patterns ("wp_*", "log_??")
+// matched against table names. A table is dumped when it matches Include (or Include is
+// empty) and matches no Exclude; SchemaOnly tables are dumped without rows. Rows keeps only
+// some rows of the dumped tables, making the backup a subset.
+type TableFilter struct {
+	Include    []string    `json:"include,omitempty"`
+	Exclude    []string    `json:"exclude,omitempty"`
+	SchemaOnly []string    `json:"schema_only,omitempty"`
+	Rows       []RowFilter `json:"rows,omitempty"`
+}
+
+// Active reports whether the filter changes what a dump contains.
+func (f *TableFilter) Active() bool {
+	return f != nil && (len(f.Include) > 0 || len(f.Exclude) > 0 || len(f.SchemaOnly) > 0 || len(f.Rows) > 0)
+}
+
+// RowFilter dumps only the rows of the tables matching Table (a glob pattern) that satisfy
+// Where, an SQL condition such as "status <> 'trash'", or, with Column and Days instead,
+// the rows whose Column is within the last Days days. The first matching filter applies.
+type RowFilter struct {
+	Table  string `json:"table"`
+	Where  string `json:"where,omitempty"`
+	Column string `json:"column,omitempty"`
+	Days   int    `json:"days,omitempty"`
+}
+
+// TableSelection restores only some tables of a backup, without dropping the target
+// database. Triggers adds the triggers defined on those tables.
+type TableSelection struct {
+	Tables   []string `json:"tables"`
+	Triggers bool     `json:"triggers,omitempty"`
+}
+
+// Active reports whether the restore is limited to some tables.
+func (s *TableSelection) Active() bool {
+	return s != nil && len(s.Tables) > 0
+}
+
+// DumpFormat selects the backup file layout.
+type DumpFormat string
+
+const (
+	// DumpFormatSingle stores the dump as one .sql.gz file.
+	DumpFormatSingle DumpFormat = "single"
+	// DumpFormatSplit stores a .split.tar with one compressed SQL file per table and a
+	// manifest of table names, sizes, row counts and checksums.
+	DumpFormatSplit DumpFormat = "split"
+	// DumpFormatRepository stores the dump in the destination folder's deduplicating chunk
+	// repository and keeps a .chunks.json manifest of its chunks in the host folder.
+	DumpFormatRepository DumpFormat = "repository"
+)
+
+// Compression is the codec a backup file is compressed with.
+type Compression string
+
+const (
+	// CompressionGzip uses pigz when the host has it, otherwise gzip.
+	CompressionGzip Compression = "gzip"
+	CompressionZstd Compression = "zstd"
+	CompressionXz   Compression = "xz"
+	// CompressionBzip2 is only read: restores accept .bz2 files made elsewhere.
+	CompressionBzip2 Compression = "bzip2"
+	// CompressionNone stores plain SQL.
+	CompressionNone Compression = "none"
+)
+
+// RetentionPolicy prunes old backup files and history records (grandfather-father-son).
+// A backup survives when any Keep rule selects it; MaxAgeDays then removes anything older,
+// except the newest backup of a host, which is never pruned. Zero fields are ignored.
+type RetentionPolicy struct {
+	KeepLast    int `json:"keep_last,omitempty"`
+	KeepDaily   int `json:"keep_daily,omitempty"`
+	KeepWeekly  int `json:"keep_weekly,omitempty"`
+	KeepMonthly int `json:"keep_monthly,omitempty"`
+	MaxAgeDays  int `json:"max_age_days,omitempty"`
+}
+
+// Active reports whether the policy would prune anything.
+func (p *RetentionPolicy) Active() bool {
+	return p != nil && (p.KeepLast > 0 || p.KeepDaily > 0 || p.KeepWeekly > 0 || p.KeepMonthly > 0 || p.MaxAgeDays > 0)
+}
+
+// Operation triggers recorded on backup history and activity log entries.
+const (
+	TriggerManual    = "manual"
+	TriggerScheduled = "scheduled"
+)
+
 // TransferSettings legacy nested settings (migration only).
 type TransferSettings struct {
 	ConnectionType       ConnectionType `json:"connection_type"`
@@ -161,6 +421,9 @@ type LogEntry struct {
 	FileSize    string    `json:"file_size"`
 	Status      string    `json:"status"`
 	Error       string    `json:"error,omitempty"`
+	Trigger     string    `json:"trigger,omitempty"`
+	// ParentOperationID links the entry to an aggregate operation (e.g. a group backup).
+	ParentOperationID string `json:"parent_operation_id,omitempty"`
 }
 
 type AppConfig struct {
@@ -177,6 +440,12 @@ type BackupFingerprint struct {
 	Mode       string                     `json:"mode"` // "fast" | "exact"
 	Tables     map[string]FingerprintTable `json:"tables"`
 	TotalRows  int64                      `json:"total_rows"`
+	// Tables left out of the dump by the host's TableFilter, and tables dumped without rows.
+	// Excluded tables are not in Tables; schema-only tables are recorded with zero rows.
+	ExcludedTables   []string `json:"excluded_tables,omitempty"`
+	SchemaOnlyTables []string `json:"schema_only_tables,omitempty"`
+	// FilteredTables were dumped with a row filter; Tables holds their filtered row counts.
+	FilteredTables []string `json:"filtered_tables,omitempty"`
 }
 
 type TableVerifyResult struct {
@@ -209,6 +478,166 @@ type ExportRecord struct {
 	QuickVerified  *LastVerified      `json:"quick_verified,omitempty"`
 	DeepVerified   *LastVerified      `json:"deep_verified,omitempty"`
 	LastVerified   *LastVerified      `json:"last_verified,omitempty"` // legacy; prefer QuickVerified/DeepVerified
+	Trigger        string             `json:"trigger,omitempty"`
+	ParentOperationID string          `json:"parent_operation_id,omitempty"`
+	// Compression is the codec of the file at FilePath; records made before it existed leave
+	// it empty and are gzip.
+	Compression Compression `json:"compression,omitempty"`
+	// Encrypted marks a file written with the host's backup encryption.
+	Encrypted bool `json:"encrypted,omitempty"`
+	// Partial marks a subset backup: row filters left out some rows of some tables.
+	Partial bool `json:"partial,omitempty"`
+	// AddedBytes is what a repository backup added to the chunk repository; the rest of
+	// its chunks were already stored by earlier backups.
+	AddedBytes int64 `json:"added_bytes,omitempty"`
+	// DBType is the server the dump was taken from; records made before it existed leave it
+	// empty and hold MySQL or MariaDB dumps.
+	DBType DBType `json:"db_type,omitempty"`
+	// ServerVersion is the database version preflight found on the source host, as the
+	// mysql or mysqldump client printed it. Restores into the other MySQL flavor are
+	// rewritten for it (see compat.Select).
+	ServerVersion string `json:"server_version,omitempty"`
+
+	// Type is empty for a full logical dump.
+	Type BackupType `json:"type,omitempty"`
+	// Binlog is the binary log position a full dump is consistent with, or the events an
+	// incremental backup holds.
+	Binlog *BinlogRange `json:"binlog,omitempty"`
+	// ParentRecordID links an incremental to the backup it continues: the full dump or the
+	// previous incremental of the chain.
+	ParentRecordID string `json:"parent_record_id,omitempty"`
+	// Restores lists where the backup was restored, oldest first and capped at
+	// RestoreHistoryLimit entries.
+	Restores []RestoreEntry `json:"restores,omitempty"`
+}
+
+// RestoreHistoryLimit is how many RestoreEntry values an ExportRecord keeps.
+const RestoreHistoryLimit = 20
+
+// RestoreEntry records one completed restore of a backup.
+type RestoreEntry struct {
+	RestoredAt      time.Time `json:"restored_at"`
+	OperationID     string    `json:"operation_id,omitempty"`
+	DestProfileID   string    `json:"dest_profile_id"`
+	DestProfileName string    `json:"dest_profile_name"`
+	// Database is the database the backup was restored into; empty for a physical restore,
+	// which replaces the whole server.
+	Database string `json:"database,omitempty"`
+	// NewDatabase marks a restore under a new name, leaving the host's configured database
+	// untouched.
+	NewDatabase bool `json:"new_database,omitempty"`
+}
+
+// BackupType tells full dumps from physical and incremental backups.
+type BackupType string
+
+const (
+	// BackupTypePhysical is a compressed xbstream of the whole server's data files, taken with
+	// mariadb-backup or xtrabackup. It restores with prepare and copy-back, replacing the
+	// destination server's data directory.
+	BackupTypePhysical BackupType = "physical"
+
+	// BackupTypeBinlog is an incremental backup: mysqlbinlog output for the events between
+	// Binlog.Start and Binlog.End, gzip-compressed.
+	BackupTypeBinlog BackupType = "binlog"
+)
+
+// Incremental reports whether the record is an incremental backup that only restores on
+// top of its chain's full dump.
+func (r ExportRecord) Incremental() bool {
+	return r.Type == BackupTypeBinlog
+}
+
+// Physical reports whether the record is a physical backup of a whole server.
+func (r ExportRecord) Physical() bool {
+	return r.Type == BackupTypePhysical
+}
+
+// PostgreSQL reports whether the backup is a pg_dump of a PostgreSQL database.
+func (r ExportRecord) PostgreSQL() bool {
+	return r.DBType == DBTypePostgreSQL
+}
+
+// BinlogPosition is a binary log file and byte offset.
+type BinlogPosition struct {
+	File string `json:"file"`
+	Pos  int64  `json:"pos"`
+}
+
+// IsZero reports whether no position was recorded.
+func (p BinlogPosition) IsZero() bool {
+	return p.File == ""
+}
+
+// String formats the position as file:offset.
+func (p BinlogPosition) String() string {
+	return fmt.Sprintf("%s:%d", p.File, p.Pos)
+}
+
+// BinlogRange is the span of binary log a backup covers. For a full dump Start and End are
+// both the position the dump is consistent with. FirstEvent and LastEvent are the (UTC)
+// timestamps of the first and last event of an incremental.
+type BinlogRange struct {
+	Start      BinlogPosition `json:"start"`
+	End        BinlogPosition `json:"end"`
+	FirstEvent time.Time      `json:"first_event,omitempty"`
+	LastEvent  time.Time      `json:"last_event,omitempty"`
+	Events     int            `json:"events,omitempty"`
+}
+
+// JobRecord is a persisted backup or restore job. Jobs left queued or running when the app
+// exits are marked interrupted on the next unlock and can be resumed or retried.
+type JobRecord struct {
+	ID            string    `json:"id"`
+	OperationID   string    `json:"operation_id"`
+	Kind          string    `json:"kind"`
+	ProfileID     string    `json:"profile_id"`
+	ProfileName   string    `json:"profile_name"`
+	DestProfileID string    `json:"dest_profile_id,omitempty"` // restore destination
+	RecordID      string    `json:"record_id,omitempty"`
+	Status        string    `json:"status"`
+	Error         string    `json:"error,omitempty"`
+	Attempts      int       `json:"attempts,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	StartedAt     time.Time `json:"started_at,omitempty"`
+	FinishedAt    time.Time `json:"finished_at,omitempty"`
+
+	// Tables limits a restore job to some tables of the backup.
+	Tables *TableSelection `json:"tables,omitempty"`
+	// PointInTime replays the backup's binlog chain up to this time after restoring it.
+	PointInTime *time.Time `json:"point_in_time,omitempty"`
+	// SearchReplace rewrites the restored data, for example a WordPress site's URL.
+	SearchReplace []ReplacePair `json:"search_replace,omitempty"`
+	// Definer is what the restore did with DEFINER clauses.
+	Definer DefinerMode `json:"definer,omitempty"`
+	// TargetDB restores into this new database instead of the host's configured one.
+	TargetDB string `json:"target_db,omitempty"`
+	// Parallel imports the backup's tables over this many sessions at once.
+	Parallel int `json:"parallel,omitempty"`
+	// OwnerPID and Owner identify the process running the job. The desktop app and the CLI
+	// share the vault; only jobs whose process has exited are marked interrupted.
+	OwnerPID int    `json:"owner_pid,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+}
+
+// Job kinds and statuses stored on JobRecord.
+const (
+	JobKindBackup  = "backup"
+	JobKindRestore = "restore"
+	JobKindBinlog  = "binlog" // incremental binlog backup
+
+	JobQueued      = "queued"
+	JobRunning     = "running"
+	JobSucceeded   = "succeeded"
+	JobFailed      = "failed"
+	JobCanceled    = "canceled"
+	JobInterrupted = "interrupted"
+	JobDismissed   = "dismissed"
+)
+
+// Unfinished reports whether the job had not completed when it was last saved.
+func (j JobRecord) Unfinished() bool {
+	return j.Status == JobQueued || j.Status == JobRunning
 }
 
 type ProfileBundle struct {
@@ -230,6 +659,52 @@ type AppVaultFile struct {
 	EncryptedPayload string    `json:"encrypted_payload"`
 }
 
+// NotificationSink delivers a message when a backup, restore or deep verify finishes.
+// A sink without ProfileID and Group applies to every host.
+type NotificationSink struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	Enabled   bool   `json:"enabled"`
+	On        string `json:"on"`
+	ProfileID string `json:"profile_id,omitempty"`
+	Group     string `json:"group,omitempty"`
+
+	WebhookURL     string            `json:"webhook_url,omitempty"`
+	WebhookHeaders map[string]string `json:"webhook_headers,omitempty"`
+
+	SMTPHost     string `json:"smtp_host,omitempty"`
+	SMTPPort     int    `json:"smtp_port,omitempty"`
+	SMTPUser     string `json:"smtp_user,omitempty"`
+	SMTPPassword string `json:"smtp_password,omitempty"`
+	SMTPFrom     string `json:"smtp_from,omitempty"`
+	SMTPTo       string `json:"smtp_to,omitempty"` // comma-separated
+
+	Command string `json:"command,omitempty"`
+}
+
+// Notification sink kinds and rules.
+const (
+	NotifyWebhook = "webhook"
+	NotifySMTP    = "smtp"
+	NotifyCommand = "command"
+
+	NotifyOnSuccess = "success"
+	NotifyOnFailure = "failure"
+	NotifyOnBoth    = "both"
+)
+
+func (n NotificationSink) Clone() NotificationSink {
+	c := n
+	if n.WebhookHeaders != nil {
+		c.WebhookHeaders = make(map[string]string, len(n.WebhookHeaders))
+		for k, v := range n.WebhookHeaders {
+			c.WebhookHeaders[k] = v
+		}
+	}
+	return c
+}
+
 // SyncSettings holds S3-compatible remote sync configuration.
 type SyncSettings struct {
 	Endpoint    string `json:"endpoint"`
@@ -264,6 +739,17 @@ type AppVaultPayload struct {
 	Sync                 *SyncSettings     `json:"sync,omitempty"`
 	SyncActivity         SyncActivity      `json:"sync_activity,omitempty"`
 	ImportDestByProfile  map[string]string `json:"import_dest_by_profile,omitempty"`
+	ScheduleLastRun      map[string]time.Time `json:"schedule_last_run,omitempty"`
+	GroupRetention       map[string]RetentionPolicy `json:"group_retention,omitempty"`
+	Jobs                 []JobRecord       `json:"jobs,omitempty"`
+	Notifications        []NotificationSink `json:"notifications,omitempty"`
+	// Bandwidth caps all transfers together; hosts may add their own caps.
+	Bandwidth *BandwidthLimit `json:"bandwidth,omitempty"`
+	// BackupKey is the base64 key encrypted backups are written with.
+	BackupKey string `json:"backup_key,omitempty"`
+	// OtherBackupKeys are the backup keys of vaults app data was imported from, kept so
+	// their backups still open.
+	OtherBackupKeys []string `json:"other_backup_keys,omitempty"`
 }
 
 // AppBundle exports hosts, templates, backup history metadata, and activity logs.
@@ -280,6 +766,9 @@ type AppBundle struct {
 	Logs             []LogEntry     `json:"logs,omitempty"`
 	Sync             *SyncSettings  `json:"sync,omitempty"`
 	EncryptedPayload string         `json:"encrypted_payload,omitempty"`
+	// BackupKeys are set on a decrypted bundle only: the vault's backup keys travel inside
+	// EncryptedPayload, never in the clear.
+	BackupKeys []string `json:"-"`
 }
 
 type BackupHistory struct {
@@ -374,8 +863,8 @@ func (p Profile) withSettings(s *TransferSettings) Profile {
 
 func normalizeDBType(t DBType) DBType {
 	switch t {
-	case DBTypeMariaDB:
-		return DBTypeMariaDB
+	case DBTypeMariaDB, DBTypePostgreSQL:
+		return t
 	default:
 		return DBTypeMySQL
 	}
@@ -419,7 +908,7 @@ func (p Profile) SupportsSQLQuery() bool {
 	if p.UsesWordPress() {
 		return true
 	}
-	return mysqlOrMariaDB(p)
+	return mysqlOrMariaDB(p) || p.UsesPostgreSQL()
 }
 
 func (p Profile) UsesSSH() bool {
@@ -442,8 +931,13 @@ func mysqlOrMariaDB(p Profile) bool {
 	return p.DBType == DBTypeMySQL || p.DBType == DBTypeMariaDB
 }
 
+// UsesPostgreSQL reports whether the host is a PostgreSQL server, dumped with pg_dump.
+func (p Profile) UsesPostgreSQL() bool {
+	return p.DBType == DBTypePostgreSQL
+}
+
 func (s TransferSettings) SupportsSQLQuery() bool {
-	return s.DBType == DBTypeMySQL || s.DBType == DBTypeMariaDB
+	return s.DBType == DBTypeMySQL || s.DBType == DBTypeMariaDB || s.DBType == DBTypePostgreSQL
 }
 
 func (p Profile) SupportsImportSQLQuery() bool {
@@ -34,7 +34,10 @@ func TestNormalizeDBType(t *testing.T) {
 	if got := normalizeDBType(DBTypeMariaDB); got != DBTypeMariaDB {
 		t.Fatalf("expected MariaDB, got %q", got)
 	}
-	if got := normalizeDBType("PostgreSQL"); got != DBTypeMySQL {
-		t.Fatalf("non-mysql types should normalize to MySQL, got %q", got)
+	if got := normalizeDBType(DBTypePostgreSQL); got != DBTypePostgreSQL {
+		t.Fatalf("expected PostgreSQL, got %q", got)
+	}
+	if got := normalizeDBType("CouchDB"); got != DBTypeMySQL {
+		t.Fatalf("unknown types should normalize to MySQL, got %q", got)
 	}
 }
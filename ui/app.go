@@ -69,6 +69,15 @@ type UI struct {
 	backupHostDropdown DropdownState
 	destSelect       widget.Enum
 	destHostDropdown   DropdownState
+	restoreTables      tableRestoreState
+	restorePITR        pitrRestoreState
+	restoreMask        maskPreviewState
+	restoreReplace     searchReplaceState
+	restoreDefiner     definerState
+	restoreAs          restoreAsState
+	restoreParallel    parallelState
+	bandwidthSettings  bandwidthSettingsState
+	repoStats          repoStatsState
 	backupList       widget.List
 	jobsList         widget.List
 
@@ -93,6 +102,7 @@ type UI struct {
 	navSettings         widget.Clickable
 	navAbout            widget.Clickable
 	addHostBtn          widget.Clickable
+	backupGroupBtn      widget.Clickable
 	addTemplateBtn      widget.Clickable
 	saveProfileBtn      widget.Clickable
 	saveTemplateBtn     widget.Clickable
@@ -102,6 +112,9 @@ type UI struct {
 	importAppDataBtn    widget.Clickable
 	tabSettingsExport   widget.Clickable
 	tabSettingsSync     widget.Clickable
+	tabSettingsNotify   widget.Clickable
+	tabSettingsRepo     widget.Clickable
+	tabSettingsBandwidth widget.Clickable
 	saveSyncBtn         widget.Clickable
 	testSyncBtn         widget.Clickable
 	syncPushBtn         widget.Clickable
@@ -111,6 +124,7 @@ type UI struct {
 	syncPushPending      bool
 	syncSavedBaseline    *models.SyncSettings
 	syncActivity         models.SyncActivity
+	notifyForm           *NotifyForm
 	settingsList         widget.List
 	tabConnection        widget.Clickable
 	tabQuery            widget.Clickable
@@ -172,6 +186,7 @@ type profileCardWidgets struct {
 	duplicate *widget.Clickable
 	delete    *widget.Clickable
 	more      *widget.Clickable
+	binlog    *widget.Clickable
 }
 
 type backupRowMenuWidgets struct {
@@ -245,6 +260,7 @@ func (u *UI) Run() {
 		log.Printf("loop: starting event loop")
 		u.loop()
 		log.Printf("loop: event loop ended, exiting")
+		u.core.WaitNotifications()
 		os.Exit(0)
 	}()
 	log.Printf("startup: calling app.Main()")
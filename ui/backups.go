@@ -7,7 +7,10 @@ import (
 	"image"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"dback/backend/chunkstore"
+	coreapp "dback/internal/app"
 	"dback/models"
 
 	"gioui.org/layout"
@@ -124,7 +127,7 @@ func (u *UI) layoutBackupFiles(gtx layout.Context, th *material.Theme, theme *Ap
 								return backupTableRow(gtx, th, theme, selected, []string{
 									formatRelativeTime(rec.ExportDate),
 									rec.ProfileName,
-									rec.DatabaseName,
+									backupDatabaseLabel(rec),
 									rec.FileSize,
 									u.backupQuickVerifyStatus(rec),
 									u.backupDeepVerifyStatus(rec),
@@ -236,6 +239,13 @@ func (u *UI) rememberImportDest(sourceProfileID, destProfileID string) {
 func (u *UI) openBackupDetail(record models.ExportRecord) {
 	u.selectedBackup = &record
 	u.view = ViewBackupDetail
+	u.restoreTables.reset(record.ID)
+	u.restorePITR.reset(record.ID)
+	u.restoreMask.reset(record.ID)
+	u.restoreReplace.reset(record.ID)
+	u.restoreDefiner.reset(record.ID)
+	u.restoreAs.reset(record.ID)
+	u.restoreParallel.reset(record.ID)
 	importable := importableProfiles(u.core.Profiles())
 	u.destSelect.Value = u.defaultImportDestID(record.ProfileID, importable)
 	u.invalidate()
@@ -261,7 +271,7 @@ func (u *UI) layoutBackupDetail(gtx layout.Context, th *material.Theme) layout.D
 		labels = append(labels, label)
 	}
 
-	canImport := len(profiles) > 0
+	canImport := len(profiles) > 0 && !record.Incremental()
 
 	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -288,11 +298,26 @@ func (u *UI) layoutBackupDetail(gtx layout.Context, th *material.Theme) layout.D
 						return lbl.Layout(gtx)
 					}),
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-						when := formatRelativeTime(record.ExportDate)
-						if when == "" {
-							return mutedLabel(gtx, th, theme, record.FileSize)
+						line := record.FileSize
+						if when := formatRelativeTime(record.ExportDate); when != "" {
+							line = when + " · " + line
 						}
-						return mutedLabel(gtx, th, theme, when+" · "+record.FileSize)
+						if record.Trigger == models.TriggerScheduled {
+							line += " · scheduled"
+						}
+						if record.Compression != "" {
+							line += " · " + string(record.Compression)
+						}
+						if record.Encrypted {
+							line += " · encrypted"
+						}
+						if record.Partial {
+							line += " · partial (row filters)"
+						}
+						if chunkstore.IsManifestPath(record.FilePath) {
+							line += " · deduplicated, " + formatBytesMB(record.AddedBytes) + " added"
+						}
+						return mutedLabel(gtx, th, theme, line)
 					}),
 					layout.Rigid(vgap(theme)),
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -318,8 +343,14 @@ func (u *UI) layoutBackupDetail(gtx layout.Context, th *material.Theme) layout.D
 				)
 			})
 		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return u.layoutRestoreHistory(gtx, th, *record)
+		}),
 		layout.Rigid(vgap(theme)),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if record.Incremental() {
+				return mutedLabel(gtx, th, theme, incrementalSummary(*record)+". Restore it through point-in-time restore of its full backup.")
+			}
 			if canImport {
 				sourceProfileID := record.ProfileID
 				return labeledEnumDropdownField(gtx, th, theme, &u.destSelect, "Destination Host", values, labels, &u.destHostDropdown, u.invalidate, func(destID string) {
@@ -329,6 +360,72 @@ func (u *UI) layoutBackupDetail(gtx layout.Context, th *material.Theme) layout.D
 			return mutedLabel(gtx, th, theme, "No import destinations available. All hosts are protected from import, or no hosts exist.")
 		}),
 		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport {
+				return layout.Dimensions{}
+			}
+			if record.Physical() {
+				return mutedLabel(gtx, th, theme, "Physical backup of the whole server. Importing stops the destination's database server, replaces its data directory and keeps the old one beside it.")
+			}
+			return u.layoutPointInTime(gtx, th, *record)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport || record.Physical() || u.restorePITR.enabled.Value {
+				return layout.Dimensions{}
+			}
+			return u.layoutRestoreTables(gtx, th, *record)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport || record.Physical() {
+				return layout.Dimensions{}
+			}
+			for _, p := range importableProfiles(u.core.Profiles()) {
+				if p.ID == u.destSelect.Value {
+					return u.layoutRestoreMasking(gtx, th, *record, p)
+				}
+			}
+			return layout.Dimensions{}
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport || record.Physical() || u.restorePITR.enabled.Value {
+				return layout.Dimensions{}
+			}
+			for _, p := range importableProfiles(u.core.Profiles()) {
+				if p.ID == u.destSelect.Value {
+					return u.layoutRestoreSearchReplace(gtx, th, *record, p)
+				}
+			}
+			return layout.Dimensions{}
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport || record.Physical() || record.PostgreSQL() || u.restorePITR.enabled.Value {
+				return layout.Dimensions{}
+			}
+			return u.layoutRestoreDefiner(gtx, th)
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport || record.Physical() || u.restorePITR.enabled.Value {
+				return layout.Dimensions{}
+			}
+			for _, p := range importableProfiles(u.core.Profiles()) {
+				if p.ID == u.destSelect.Value {
+					return u.layoutRestoreAs(gtx, th, *record, p)
+				}
+			}
+			return layout.Dimensions{}
+		}),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			if !canImport || record.Physical() || record.PostgreSQL() || u.restorePITR.enabled.Value {
+				return layout.Dimensions{}
+			}
+			for _, p := range importableProfiles(u.core.Profiles()) {
+				if p.ID == u.destSelect.Value && !p.UsesWordPress() {
+					return u.layoutRestoreParallel(gtx, th)
+				}
+			}
+			return layout.Dimensions{}
+		}),
+		layout.Rigid(vgap(theme)),
 		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
 				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -374,6 +471,46 @@ func (u *UI) runRestore(record models.ExportRecord) {
 		u.showError(fmt.Errorf("host %q is protected from import", dest.Name))
 		return
 	}
+	if record.Physical() {
+		u.showConfirm("Physical restore", fmt.Sprintf("Stop the database server on %q and replace all of its data with this backup?", dest.Name), func() {
+			u.startRestore(record, dest, nil, coreapp.RestoreOptions{})
+		})
+		return
+	}
+	pointInTime, err := u.restorePITR.targetTime()
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	tables := u.restoreTables.selection()
+	pairs, err := u.restoreReplace.selection()
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	definerMode := u.restoreDefiner.selection()
+	targetDB, err := u.restoreAs.targetDB()
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	parallel := u.restoreParallel.selection()
+	if pointInTime != nil || dest.UsesWordPress() {
+		parallel = 0
+	}
+	if pointInTime != nil {
+		tables, pairs, definerMode, targetDB = nil, nil, models.DefinerKeep, ""
+	}
+	if tables != nil && !tables.Active() {
+		u.showError(fmt.Errorf("select at least one table to restore"))
+		return
+	}
+	u.startRestore(record, dest, pointInTime, coreapp.RestoreOptions{Tables: tables, SearchReplace: pairs, Definer: definerMode, TargetDB: targetDB, Parallel: parallel})
+}
+
+// startRestore runs a restore job in the background: to a point in time, or of the whole
+// backup with opts applied.
+func (u *UI) startRestore(record models.ExportRecord, dest models.Profile, pointInTime *time.Time, opts coreapp.RestoreOptions) {
 	ctx, cancel := context.WithCancel(context.Background())
 	job := u.addJob("Import", dest.Name, cancel)
 	u.backupTab = 1
@@ -381,13 +518,22 @@ func (u *UI) runRestore(record models.ExportRecord) {
 	u.openBackups()
 	go func() {
 		defer cancel()
-		err := u.core.Restore(ctx, record, dest, func(message string, current int64, total int64) {
+		progress := func(message string, current int64, total int64) {
 			progress := float64(0)
 			if total > 0 {
 				progress = float64(current) / float64(total)
 			}
 			u.updateJob(job.ID, message, progress, "")
-		})
+		}
+		var err error
+		switch {
+		case pointInTime != nil:
+			err = u.core.RestorePointInTime(ctx, record, *pointInTime, dest, progress)
+		case opts.Tables != nil || len(opts.SearchReplace) > 0 || opts.Definer != models.DefinerKeep || opts.TargetDB != "" || opts.Parallel > 1:
+			err = u.core.RestoreWithOptions(ctx, record, dest, opts, progress)
+		default:
+			err = u.core.Restore(ctx, record, dest, progress)
+		}
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
 				u.finishJob(job.ID, "Import canceled", nil)
@@ -133,6 +133,8 @@ func (u *UI) completeUnlock() {
 	u.loginPassword.SetText("")
 	u.loginConfirmPassword.SetText("")
 	u.invalidateBackupCache()
+	u.startScheduler()
+	u.promptInterruptedJobs()
 	u.invalidate()
 }
 
@@ -137,3 +137,8 @@ func hostConnectionSubtitle(p models.Profile) string {
 	}
 	return subtitle
 }
+
+// formatBytesMB matches the "%.2f MB" size format stored on backup records.
+func formatBytesMB(n int64) string {
+	return fmt.Sprintf("%.2f MB", float64(n)/1024/1024)
+}
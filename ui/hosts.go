@@ -8,8 +8,10 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	coreapp "dback/internal/app"
 	"dback/internal/paths"
 	"dback/models"
 
@@ -64,7 +66,22 @@ func (u *UI) layoutHostsList(gtx layout.Context, th *material.Theme, theme *AppT
 					}),
 					layout.Rigid(spacer(theme, theme.SectionGap)),
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-						return sectionLabel(gtx, th, theme, "Hosts")
+						if u.selectedGroup == groupFilterAll {
+							return sectionLabel(gtx, th, theme, "Hosts")
+						}
+						group := u.selectedGroup
+						return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+							layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+								return sectionLabel(gtx, th, theme, "Hosts")
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return secondaryButton(gtx, th, theme, &u.backupGroupBtn, "Back Up Group", func() {
+									u.showConfirm("Back up group", fmt.Sprintf("Back up every host in %q?", group), func() {
+										u.runGroupBackup(group)
+									})
+								})
+							}),
+						)
 					}),
 					layout.Rigid(vgap(theme)),
 					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
@@ -143,6 +160,7 @@ func (u *UI) layoutProfileCards(gtx layout.Context, th *material.Theme, theme *A
 				duplicate: new(widget.Clickable),
 				delete:    new(widget.Clickable),
 				more:      new(widget.Clickable),
+				binlog:    new(widget.Clickable),
 			}
 			u.profileCards[p.ID] = cards
 		}
@@ -247,6 +265,17 @@ func (u *UI) layoutCardMoreButton(gtx layout.Context, th *material.Theme, theme
 		},
 	}
 
+	if p.Binlog.Active() {
+		items = append([]menuPopupItem{{
+			label: "Pull binary logs",
+			btn:   cards.binlog,
+			onClick: func() {
+				u.menuOpenID = ""
+				u.runBinlogBackup(p)
+			},
+		}}, items...)
+	}
+
 	// Dismiss menu when backdrop is clicked
 	if u.menuCloseArea.Clicked(gtx) {
 		u.menuOpenID = ""
@@ -326,30 +355,139 @@ func (u *UI) runBackup(p models.Profile) {
 	u.openBackups()
 	go func() {
 		defer cancel()
-		record, err := u.core.Backup(ctx, p, func(message string, current int64, total int64) {
-			progress := float64(0)
+		record, err := u.core.Backup(ctx, p, u.backupJobProgress(job.ID))
+		u.finishBackupJob(job.ID, record, err)
+	}()
+}
+
+// runBinlogBackup pulls the host's new binary log events as an incremental backup.
+func (u *UI) runBinlogBackup(p models.Profile) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := u.addJob("Incremental Backup", p.Name, cancel)
+	u.backupTab = 1
+	u.openBackups()
+	go func() {
+		defer cancel()
+		record, err := u.core.BackupBinlog(ctx, p, models.TriggerManual, u.backupJobProgress(job.ID))
+		if errors.Is(err, coreapp.ErrNoBinlogEvents) {
+			u.finishJob(job.ID, "No new binary log events", nil)
+			return
+		}
+		u.finishBackupJob(job.ID, record, err)
+	}()
+}
+
+// runGroupBackup backs up every host in a group as one job; progress is the average across hosts.
+func (u *UI) runGroupBackup(group string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := u.addJob("Group Backup", group, cancel)
+	u.backupTab = 1
+	u.openBackups()
+	go func() {
+		defer cancel()
+		hosts := len(u.core.GroupProfiles(group))
+		var mu sync.Mutex
+		fractions := map[string]float64{}
+		summary, err := u.core.BackupGroup(ctx, group, coreapp.DefaultGroupBackupConcurrency, func(p models.Profile, message string, current, total int64) {
+			mu.Lock()
 			if total > 0 {
-				progress = float64(current) / float64(total)
+				fractions[p.ID] = float64(current) / float64(total)
 			}
-			verifyPhase := strings.Contains(message, "Capturing fingerprint") ||
-				strings.Contains(message, "Verifying backup integrity")
-			u.setBackupJobProgress(job.ID, message, progress, verifyPhase)
-			if verifyPhase {
-				u.invalidateBackupCache()
+			sum := 0.0
+			for _, f := range fractions {
+				sum += f
 			}
+			mu.Unlock()
+			u.setBackupJobProgress(job.ID, p.Name+": "+message, sum/float64(max(hosts, 1)), false)
 		})
 		u.invalidateBackupCache()
-		if err != nil {
-			if errors.Is(err, context.Canceled) {
-				u.finishJob(job.ID, "Backup canceled", nil)
+		switch _, failed, _ := summary.Counts(); {
+		case errors.Is(err, context.Canceled):
+			u.finishJob(job.ID, "Group backup canceled: "+summary.String(), nil)
+		case err != nil:
+			u.finishJob(job.ID, "Group backup failed", err)
+		case failed > 0:
+			u.finishJob(job.ID, "Group backup: "+summary.String(), fmt.Errorf("%d host(s) failed", failed))
+		default:
+			u.finishJob(job.ID, "Group backup complete: "+summary.String(), nil)
+		}
+	}()
+}
+
+// startScheduler runs host schedules in the background and shows each run in the jobs table.
+func (u *UI) startScheduler() {
+	u.core.StartScheduler(context.Background(), func(p models.Profile, kind string, cancel context.CancelFunc) (coreapp.ProgressFunc, func(models.ExportRecord, error)) {
+		title := "Scheduled Backup"
+		if kind == models.JobKindBinlog {
+			title = "Incremental Backup"
+		}
+		job := u.addJob(title, p.Name, cancel)
+		return u.backupJobProgress(job.ID), func(record models.ExportRecord, err error) {
+			if errors.Is(err, coreapp.ErrNoBinlogEvents) {
+				u.finishJob(job.ID, "No new binary log events", nil)
 				return
 			}
-			u.finishJob(job.ID, "Backup failed", err)
+			u.finishBackupJob(job.ID, record, err)
+		}
+	})
+}
+
+// previewRetention lists the backups the saved retention policy would delete and offers to delete them.
+func (u *UI) previewRetention(p models.Profile) {
+	report, err := u.core.ApplyProfileRetention(context.Background(), p.ID, true)
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	if len(report.Removed) == 0 {
+		u.showInfo("Retention", "No backups would be removed with the saved policy.")
+		return
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d backup(s), %s would be removed:\n", len(report.Removed), formatBytesMB(report.FreedBytes))
+	for _, removal := range report.Removed {
+		fmt.Fprintf(&b, "\n%s — %s (%s)", removal.Record.ExportDate.Local().Format("2006-01-02 15:04"), filepath.Base(removal.Record.FilePath), removal.Reason)
+	}
+	u.showConfirm("Retention preview", b.String(), func() {
+		go func() {
+			report, err := u.core.ApplyProfileRetention(context.Background(), p.ID, false)
+			u.invalidateBackupCache()
+			if err != nil {
+				u.showError(err)
+				return
+			}
+			u.showInfo("Retention", fmt.Sprintf("Removed %d backup(s), freed %s", len(report.Removed), formatBytesMB(report.FreedBytes)))
+		}()
+	})
+}
+
+func (u *UI) backupJobProgress(jobID string) coreapp.ProgressFunc {
+	return func(message string, current int64, total int64) {
+		progress := float64(0)
+		if total > 0 {
+			progress = float64(current) / float64(total)
+		}
+		verifyPhase := strings.Contains(message, "Capturing fingerprint") ||
+			strings.Contains(message, "Verifying backup integrity")
+		u.setBackupJobProgress(jobID, message, progress, verifyPhase)
+		if verifyPhase {
+			u.invalidateBackupCache()
+		}
+	}
+}
+
+func (u *UI) finishBackupJob(jobID string, record models.ExportRecord, err error) {
+	u.invalidateBackupCache()
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			u.finishJob(jobID, "Backup canceled", nil)
 			return
 		}
-		u.setBackupJobRecord(job.ID, record.ID)
-		u.finishJob(job.ID, "Backup complete: "+filepath.Base(record.FilePath), nil)
-	}()
+		u.finishJob(jobID, "Backup failed", err)
+		return
+	}
+	u.setBackupJobRecord(jobID, record.ID)
+	u.finishJob(jobID, "Backup complete: "+filepath.Base(record.FilePath), nil)
 }
 
 func (u *UI) openProfileEditor(p models.Profile) {
@@ -358,6 +496,7 @@ func (u *UI) openProfileEditor(p models.Profile) {
 	setEditorText(&u.profileGroup, p.Group)
 	defaultDest := paths.DefaultBackupDestination()
 	u.hostForm = newSettingsForm(p, defaultDest)
+	u.hostForm.setRetention(u.core.EffectiveRetention(p))
 	u.queryForm = newQueryForm(p)
 	u.profileTab = 0
 	u.view = ViewProfileEditor
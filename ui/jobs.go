@@ -2,8 +2,12 @@ package ui
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"dback/models"
 )
 
 type operationJob struct {
@@ -157,3 +161,76 @@ func (u *UI) requestBackupsRefresh(force bool) {
 	u.jobsUIMu.Unlock()
 	u.invalidate()
 }
+
+// promptInterruptedJobs offers to resume backups and imports that were still running when
+// DBack last closed. Cancel dismisses them so the prompt does not come back.
+func (u *UI) promptInterruptedJobs() {
+	interrupted := u.core.InterruptedJobs()
+	if len(interrupted) == 0 {
+		return
+	}
+	var b strings.Builder
+	b.WriteString("These jobs were interrupted when DBack last closed:\n")
+	for _, job := range interrupted {
+		how := "starts over"
+		if u.core.CanResumeJob(job) {
+			how = "resumes where it stopped"
+		}
+		fmt.Fprintf(&b, "\n%s %s (%s, %s)", jobKindLabel(job.Kind), job.ProfileName, job.StartedAt.Local().Format("2006-01-02 15:04"), how)
+	}
+	u.showDialog(DialogState{
+		Kind:    DialogConfirm,
+		Title:   "Resume interrupted jobs?",
+		Message: b.String(),
+		OKLabel: "Resume",
+		OnOK: func() {
+			for _, job := range interrupted {
+				u.resumeJob(job)
+			}
+		},
+		OnCancel: func() {
+			for _, job := range interrupted {
+				if err := u.core.DismissJob(job.ID); err != nil {
+					u.showError(err)
+					return
+				}
+			}
+		},
+	})
+}
+
+func (u *UI) resumeJob(rec models.JobRecord) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := u.addJob(jobKindLabel(rec.Kind), rec.ProfileName, cancel)
+	go func() {
+		defer cancel()
+		if rec.Kind == models.JobKindBackup {
+			record, err := u.core.ResumeJob(ctx, rec.ID, u.backupJobProgress(job.ID))
+			u.finishBackupJob(job.ID, record, err)
+			return
+		}
+		_, err := u.core.ResumeJob(ctx, rec.ID, func(message string, current int64, total int64) {
+			progress := float64(0)
+			if total > 0 {
+				progress = float64(current) / float64(total)
+			}
+			u.updateJob(job.ID, message, progress, "")
+		})
+		switch {
+		case errors.Is(err, context.Canceled):
+			u.finishJob(job.ID, "Import canceled", nil)
+		case err != nil:
+			u.finishJob(job.ID, "Import failed", err)
+		default:
+			u.finishJob(job.ID, "Import complete", nil)
+		}
+	}()
+}
+
+// jobKindLabel maps a persisted job kind to the label used in the jobs table.
+func jobKindLabel(kind string) string {
+	if kind == models.JobKindRestore {
+		return "Import"
+	}
+	return "Backup"
+}
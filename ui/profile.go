@@ -127,13 +127,30 @@ func (u *UI) profileFromEditors() models.Profile {
 	p.IsDocker = host.IsDocker
 	p.ContainerID = host.ContainerID
 	p.TargetDBName = host.TargetDBName
+	p.Databases = host.Databases
+	p.Tables = host.Tables
+	p.DumpFormat = host.DumpFormat
+	p.PhysicalBackup = host.PhysicalBackup
+	p.Encryption = host.Encryption
+	p.Compression = host.Compression
+	p.CompressionLevel = host.CompressionLevel
 	p.Destination = host.Destination
 	p.ImportProtected = host.ImportProtected
+	p.Masking = host.Masking
+	p.Schedule = host.Schedule
+	p.Binlog = host.Binlog
+	p.Retention = host.Retention
+	p.Bandwidth = host.Bandwidth
 	qs := u.queryForm.settings()
 	p.PreImportQuery = qs.PreImportQuery
 	p.RunQueryBeforeImport = qs.RunQueryBeforeImport
 	p.PostImportQuery = qs.PostImportQuery
 	p.RunQueryAfterImport = qs.RunQueryAfterImport
+	p.PreExportQuery = qs.PreExportQuery
+	p.PostExportQuery = qs.PostExportQuery
+	p.PreExportCommand = qs.PreExportCommand
+	p.PostExportCommand = qs.PostExportCommand
+	p.AbortOnExportHookFailure = qs.AbortOnExportHookFailure
 	return p
 }
 
@@ -143,10 +160,28 @@ func (u *UI) saveProfile() {
 		u.showError(fmt.Errorf("host name is required"))
 		return
 	}
+	if _, err := u.hostForm.rowFilters(); err != nil {
+		u.showError(err)
+		return
+	}
+	if _, err := u.hostForm.masking(); err != nil {
+		u.showError(err)
+		return
+	}
+	if _, err := u.hostForm.Bandwidth.limit(); err != nil {
+		u.showError(err)
+		return
+	}
 	if err := u.core.SaveProfile(p); err != nil {
 		u.showError(err)
 		return
 	}
+	if u.hostForm.RetentionForGroup.Value {
+		if err := u.core.SetGroupRetention(p.Group, u.hostForm.retention()); err != nil {
+			u.showError(err)
+			return
+		}
+	}
 	u.view = ViewList
 	u.invalidate()
 }
@@ -41,10 +41,20 @@ type QuerySection struct {
 type QueryForm struct {
 	Before        QuerySection
 	After         QuerySection
+	Hooks         ExportHooksSection
 	scrollList    widget.List
 	templateCache templateOptionCache
 }
 
+// ExportHooksSection edits the SQL and shell hooks run around each backup.
+type ExportHooksSection struct {
+	PreQuery     widget.Editor
+	PostQuery    widget.Editor
+	PreCommand   widget.Editor
+	PostCommand  widget.Editor
+	AbortOnError widget.Bool
+}
+
 func newQueryForm(p models.Profile) *QueryForm {
 	before := QuerySection{
 		Title:            "Before import",
@@ -66,7 +76,15 @@ func newQueryForm(p models.Profile) *QueryForm {
 	setEditorText(&after.Query, p.PostImportQuery)
 	after.RunOnImport.Value = p.RunQueryAfterImport || strings.TrimSpace(p.PostImportQuery) != ""
 
-	return &QueryForm{Before: before, After: after}
+	f := &QueryForm{Before: before, After: after}
+	f.Hooks.PreCommand.SingleLine = true
+	f.Hooks.PostCommand.SingleLine = true
+	setEditorText(&f.Hooks.PreQuery, p.PreExportQuery)
+	setEditorText(&f.Hooks.PostQuery, p.PostExportQuery)
+	setEditorText(&f.Hooks.PreCommand, p.PreExportCommand)
+	setEditorText(&f.Hooks.PostCommand, p.PostExportCommand)
+	f.Hooks.AbortOnError.Value = p.AbortOnExportHookFailure
+	return f
 }
 
 func (f *QueryForm) settings() models.Profile {
@@ -77,6 +95,12 @@ func (f *QueryForm) settings() models.Profile {
 		RunQueryBeforeImport: f.Before.RunOnImport.Value || preImport != "",
 		PostImportQuery:      postImport,
 		RunQueryAfterImport:  f.After.RunOnImport.Value || postImport != "",
+
+		PreExportQuery:           strings.TrimSpace(editorText(&f.Hooks.PreQuery)),
+		PostExportQuery:          strings.TrimSpace(editorText(&f.Hooks.PostQuery)),
+		PreExportCommand:         strings.TrimSpace(editorText(&f.Hooks.PreCommand)),
+		PostExportCommand:        strings.TrimSpace(editorText(&f.Hooks.PostCommand)),
+		AbortOnExportHookFailure: f.Hooks.AbortOnError.Value,
 	}
 }
 
@@ -93,10 +117,70 @@ func (f *QueryForm) layout(gtx layout.Context, th *material.Theme, theme *AppThe
 			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
 				return f.After.layoutSection(gtx, th, theme, u, profileFn, &f.templateCache, false)
 			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return f.Hooks.layout(gtx, th, theme, profileFn().UsesWordPress())
+			}),
 		)
 	})
 }
 
+func (h *ExportHooksSection) layout(gtx layout.Context, th *material.Theme, theme *AppTheme, wordPress bool) layout.Dimensions {
+	h.AbortOnError.Update(gtx)
+	helper := "Run around every backup of this host. SQL runs against the target database; shell commands run on the SSH host (not inside Docker). Post-backup hooks run even if the dump fails. Placeholders in SQL: {databasename}, {host}, {profile}, {dbuser}"
+	if wordPress {
+		helper = "SQL runs through the DBack plugin around every backup of this site. Post-backup SQL runs even if the export fails. Shell hooks are not available for WordPress hosts."
+	}
+	sqlField := func(label string, e *widget.Editor, hint string) layout.FlexChild {
+		return layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return labeledField(gtx, th, theme, label, func(gtx layout.Context) layout.Dimensions {
+				gtx.Constraints.Min.Y = gtx.Dp(unit.Dp(72))
+				return editorMultiline(gtx, th, theme, e, hint)
+			})
+		})
+	}
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.Subtitle1(th, "Backup hooks")
+			lbl.Color = theme.Text
+			return lbl.Layout(gtx)
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return mutedLabel(gtx, th, theme, helper)
+		}),
+		layout.Rigid(vgap(theme)),
+		sqlField("Before backup (SQL)", &h.PreQuery, "FLUSH LOGS;"),
+		layout.Rigid(vgap(theme)),
+		sqlField("After backup (SQL)", &h.PostQuery, "UPDATE options SET maintenance = 0;"),
+	}
+	if !wordPress {
+		children = append(children,
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "Before backup (shell)", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &h.PreCommand, "php artisan down")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "After backup (shell)", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &h.PostCommand, "php artisan up")
+				})
+			}),
+		)
+	}
+	children = append(children,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return checkboxField(gtx, th, theme, &h.AbortOnError, "Fail the backup when a hook fails")
+		}),
+	)
+	return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
 func (q *QuerySection) layoutSection(gtx layout.Context, th *material.Theme, theme *AppTheme, u *UI, profileFn func() models.Profile, cache *templateOptionCache, isBefore bool) layout.Dimensions {
 	return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
 		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"dback/backend/db"
+	"dback/models"
+)
+
+// restoreAsState backs the "Restore into a new database" option on the backup detail page.
+type restoreAsState struct {
+	enabled  widget.Bool
+	name     widget.Editor
+	recordID string
+}
+
+func (s *restoreAsState) reset(recordID string) {
+	*s = restoreAsState{recordID: recordID}
+	s.name.SingleLine = true
+}
+
+// targetDB returns the typed database name, or "" when the option is off.
+func (s *restoreAsState) targetDB() (string, error) {
+	if !s.enabled.Value {
+		return "", nil
+	}
+	name := strings.TrimSpace(editorText(&s.name))
+	if err := db.ValidateDatabaseName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (u *UI) layoutRestoreAs(gtx layout.Context, th *material.Theme, record models.ExportRecord, dest models.Profile) layout.Dimensions {
+	theme := u.theme
+	s := &u.restoreAs
+	if s.enabled.Update(gtx) && s.enabled.Value && strings.TrimSpace(editorText(&s.name)) == "" {
+		setEditorText(&s.name, record.DatabaseName+"_"+record.ExportDate.Local().Format("20060102"))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return checkboxField(gtx, th, theme, &s.enabled, "Restore into a new database")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if !s.enabled.Value {
+							return layout.Dimensions{}
+						}
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return labeledField(gtx, th, theme, "Database Name", func(gtx layout.Context) layout.Dimensions {
+									return editorField(gtx, th, theme, &s.name, "shop_copy")
+								})
+							}),
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return mutedLabel(gtx, th, theme, fmt.Sprintf("The database is created on %s and must not exist yet. The host's database %s is left untouched, and its pre- and post-import queries are skipped.", dest.Name, dest.TargetDBName))
+							}),
+						)
+					}),
+				)
+			})
+		}),
+	)
+}
+
+// restoreHistoryLines describes where a backup was restored, newest first.
+func restoreHistoryLines(restores []models.RestoreEntry) []string {
+	lines := make([]string, 0, len(restores))
+	for i := len(restores) - 1; i >= 0; i-- {
+		e := restores[i]
+		where := e.DestProfileName
+		switch {
+		case e.Database == "":
+			where += " (whole server)"
+		case e.NewDatabase:
+			where += " as new database " + e.Database
+		default:
+			where += " · " + e.Database
+		}
+		lines = append(lines, e.RestoredAt.Local().Format("2006-01-02 15:04")+" · "+where)
+	}
+	return lines
+}
+
+func (u *UI) layoutRestoreHistory(gtx layout.Context, th *material.Theme, record models.ExportRecord) layout.Dimensions {
+	if len(record.Restores) == 0 {
+		return layout.Dimensions{}
+	}
+	theme := u.theme
+	lines := restoreHistoryLines(record.Restores)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				children := []layout.FlexChild{
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return sectionLabel(gtx, th, theme, "Restored To")
+					}),
+					layout.Rigid(vgap(theme)),
+				}
+				for _, line := range lines {
+					line := line
+					children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, line)
+					}))
+				}
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+			})
+		}),
+	)
+}
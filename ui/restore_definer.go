@@ -0,0 +1,51 @@
+package ui
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"dback/backend/definer"
+	"dback/models"
+)
+
+var (
+	definerValues = []string{"keep", string(models.DefinerStrip), string(models.DefinerRewrite), string(models.DefinerInvoker)}
+	definerLabels = []string{"Keep as in the backup", "Strip DEFINER", "Rewrite to the destination's user", "Strip and use SQL SECURITY INVOKER"}
+)
+
+// definerState backs the DEFINER option on the backup detail page.
+type definerState struct {
+	mode     widget.Enum
+	recordID string
+}
+
+func (s *definerState) reset(recordID string) {
+	*s = definerState{recordID: recordID}
+}
+
+// selection returns the chosen mode; keep when nothing was picked.
+func (s *definerState) selection() models.DefinerMode {
+	mode, _ := definer.ParseMode(s.mode.Value)
+	return mode
+}
+
+func (u *UI) layoutRestoreDefiner(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	theme := u.theme
+	s := &u.restoreDefiner
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledEnumField(gtx, th, theme, &s.mode, "Views, triggers and routines (DEFINER)", definerValues, definerLabels)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "Backups name the user that created each view, trigger, routine and event. Pick another option when the destination has no such user; the activity log lists every object changed.")
+					}),
+				)
+			})
+		}),
+	)
+}
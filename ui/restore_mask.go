@@ -0,0 +1,108 @@
+package ui
+
+import (
+	"fmt"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"dback/models"
+)
+
+// maskPreviewState backs the masking note on the backup detail page: which columns the
+// destination's masking rules would change, loaded in the background on request.
+type maskPreviewState struct {
+	previewBtn widget.Clickable
+	recordID   string
+	destID     string
+	loading    bool
+	loadErr    string
+	lines      []string
+}
+
+func (s *maskPreviewState) reset(recordID string) {
+	*s = maskPreviewState{recordID: recordID}
+}
+
+func (u *UI) loadMaskPreview(record models.ExportRecord, dest models.Profile) {
+	s := &u.restoreMask
+	if s.loading {
+		return
+	}
+	s.loading, s.loadErr, s.lines, s.destID = true, "", nil, dest.ID
+	go func() {
+		report, err := u.core.PreviewMasking(record, dest)
+		if s.recordID != record.ID || s.destID != dest.ID {
+			return
+		}
+		s.loading = false
+		if err != nil {
+			s.loadErr = err.Error()
+			u.invalidate()
+			return
+		}
+		var lines []string
+		for _, c := range report.Columns {
+			lines = append(lines, fmt.Sprintf("%s.%s — %s, %d value(s)", c.Table, c.Column, c.Strategy, c.Values))
+		}
+		if len(lines) == 0 {
+			lines = append(lines, "No column in this backup matches the rules.")
+		}
+		for _, r := range report.Unused {
+			lines = append(lines, fmt.Sprintf("%s.%s — no such table in this backup", r.Table, r.Column))
+		}
+		for _, problem := range report.Problems {
+			lines = append(lines, "Import would fail: "+problem)
+		}
+		s.lines = lines
+		u.invalidate()
+	}()
+}
+
+func (u *UI) layoutRestoreMasking(gtx layout.Context, th *material.Theme, record models.ExportRecord, dest models.Profile) layout.Dimensions {
+	theme := u.theme
+	s := &u.restoreMask
+	if len(dest.Masking) == 0 {
+		return layout.Dimensions{}
+	}
+	if s.destID != dest.ID && !s.loading {
+		s.lines, s.loadErr = nil, ""
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				children := []layout.FlexChild{
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return sectionLabel(gtx, th, theme, "Data masking")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, fmt.Sprintf("%d masking rule(s) of %s rewrite the rows before they are uploaded.", len(dest.Masking), dest.Name))
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if s.loading {
+							return mutedLabel(gtx, th, theme, "Reading backup...")
+						}
+						return secondaryButton(gtx, th, theme, &s.previewBtn, "Preview masking", func() {
+							u.loadMaskPreview(record, dest)
+						})
+					}),
+				}
+				if s.loadErr != "" {
+					children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "Could not preview masking: "+s.loadErr)
+					}))
+				}
+				for _, line := range s.lines {
+					line := line
+					children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, line)
+					}))
+				}
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+			})
+		}),
+	)
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"strconv"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+var (
+	parallelValues = []string{"1", "2", "4", "8"}
+	parallelLabels = []string{"One session", "2 sessions", "4 sessions", "8 sessions"}
+)
+
+// parallelState backs the parallel import option on the backup detail page.
+type parallelState struct {
+	sessions widget.Enum
+	recordID string
+}
+
+func (s *parallelState) reset(recordID string) {
+	*s = parallelState{recordID: recordID}
+}
+
+// selection returns the chosen number of import sessions; 0 when nothing was picked.
+func (s *parallelState) selection() int {
+	n, _ := strconv.Atoi(s.sessions.Value)
+	return n
+}
+
+func (u *UI) layoutRestoreParallel(gtx layout.Context, th *material.Theme) layout.Dimensions {
+	theme := u.theme
+	s := &u.restoreParallel
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledEnumField(gtx, th, theme, &s.sessions, "Import Sessions", parallelValues, parallelLabels)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "Imports several tables at once, largest first, then views, triggers and routines in one last session. Foreign key checks are off while tables load. Falls back to one session if the dump cannot be split.")
+					}),
+				)
+			})
+		}),
+	)
+}
@@ -0,0 +1,112 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"dback/models"
+)
+
+// pitrTimeLayout is how point-in-time targets are typed, in local time.
+const pitrTimeLayout = "2006-01-02 15:04:05"
+
+// pitrRestoreState backs the "Restore to a point in time" option on the backup detail page
+// of a full backup with binlog incrementals.
+type pitrRestoreState struct {
+	enabled  widget.Bool
+	target   widget.Editor
+	recordID string
+}
+
+func (s *pitrRestoreState) reset(recordID string) {
+	*s = pitrRestoreState{recordID: recordID}
+	s.target.SingleLine = true
+}
+
+// targetTime parses the typed target, or returns nil when point-in-time restore is off.
+func (s *pitrRestoreState) targetTime() (*time.Time, error) {
+	if !s.enabled.Value {
+		return nil, nil
+	}
+	text := strings.TrimSpace(editorText(&s.target))
+	t, err := time.ParseInLocation(pitrTimeLayout, text, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("enter the point in time as YYYY-MM-DD HH:MM:SS")
+	}
+	return &t, nil
+}
+
+// backupDatabaseLabel is the Database column of the backups table.
+func backupDatabaseLabel(rec models.ExportRecord) string {
+	if rec.Incremental() {
+		return rec.DatabaseName + " (binlog)"
+	}
+	if rec.Physical() {
+		return rec.DatabaseName + " (physical)"
+	}
+	return rec.DatabaseName
+}
+
+// incrementalSummary describes the events an incremental backup holds.
+func incrementalSummary(rec models.ExportRecord) string {
+	b := rec.Binlog
+	if b == nil {
+		return "Incremental backup"
+	}
+	if b.Events == 0 {
+		return fmt.Sprintf("Incremental backup from %s to %s with no events", b.Start, b.End)
+	}
+	return fmt.Sprintf("Incremental backup of %d event(s), %s to %s",
+		b.Events, b.FirstEvent.Local().Format(pitrTimeLayout), b.LastEvent.Local().Format(pitrTimeLayout))
+}
+
+func (u *UI) layoutPointInTime(gtx layout.Context, th *material.Theme, record models.ExportRecord) layout.Dimensions {
+	theme := u.theme
+	s := &u.restorePITR
+	from, to, ok := u.core.PointInTimeRange(record)
+	if !ok {
+		return layout.Dimensions{}
+	}
+	if s.enabled.Update(gtx) && s.enabled.Value && strings.TrimSpace(editorText(&s.target)) == "" {
+		setEditorText(&s.target, to.Local().Format(pitrTimeLayout))
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return checkboxField(gtx, th, theme, &s.enabled, "Restore to a point in time")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						chain := u.core.BinlogChain(record)
+						return mutedLabel(gtx, th, theme, fmt.Sprintf("%d incremental backup(s) cover %s to %s.",
+							len(chain), from.Local().Format(pitrTimeLayout), to.Local().Format(pitrTimeLayout)))
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if !s.enabled.Value {
+							return layout.Dimensions{}
+						}
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return labeledField(gtx, th, theme, "Restore Up To (local time)", func(gtx layout.Context) layout.Dimensions {
+									return editorField(gtx, th, theme, &s.target, pitrTimeLayout)
+								})
+							}),
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return mutedLabel(gtx, th, theme, "The full backup is restored, then the binary logs are replayed up to this time. The destination must use database "+record.DatabaseName+".")
+							}),
+						)
+					}),
+				)
+			})
+		}),
+		layout.Rigid(vgap(theme)),
+	)
+}
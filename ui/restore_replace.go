@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"dback/backend/searchreplace"
+	"dback/models"
+)
+
+// searchReplaceState backs the search/replace option on the backup detail page. The pairs
+// are prefilled with the site URL move each time another destination is picked.
+type searchReplaceState struct {
+	enabled  widget.Bool
+	pairs    widget.Editor
+	recordID string
+	destID   string
+}
+
+func (s *searchReplaceState) reset(recordID string) {
+	*s = searchReplaceState{recordID: recordID}
+}
+
+// prefill fills the editor with the suggested pairs for dest, once per destination.
+func (u *UI) prefillSearchReplace(record models.ExportRecord, dest models.Profile) {
+	s := &u.restoreReplace
+	if s.destID == dest.ID {
+		return
+	}
+	s.destID = dest.ID
+	pairs := u.core.DefaultSearchReplace(record, dest)
+	s.pairs.SetText(searchreplace.FormatPairs(pairs))
+	s.enabled.Value = len(pairs) > 0
+}
+
+// selection returns the pairs to apply, or nil when search/replace is off.
+func (s *searchReplaceState) selection() ([]models.ReplacePair, error) {
+	if !s.enabled.Value {
+		return nil, nil
+	}
+	return searchreplace.ParsePairs(s.pairs.Text())
+}
+
+func (u *UI) layoutRestoreSearchReplace(gtx layout.Context, th *material.Theme, record models.ExportRecord, dest models.Profile) layout.Dimensions {
+	theme := u.theme
+	s := &u.restoreReplace
+	u.prefillSearchReplace(record, dest)
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return checkboxField(gtx, th, theme, &s.enabled, "Search and replace")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if !s.enabled.Value {
+							return mutedLabel(gtx, th, theme, "Restores the rows as they are in the backup.")
+						}
+						return mutedLabel(gtx, th, theme, "Rewrites text in the restored rows, one \"from => to\" per line. PHP-serialized values keep valid lengths.")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if !s.enabled.Value {
+							return layout.Dimensions{}
+						}
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return editorMultiline(gtx, th, theme, &s.pairs, "https://www.example.com => https://staging.example.com")
+							}),
+						)
+					}),
+				)
+			})
+		}),
+	)
+}
@@ -0,0 +1,120 @@
+package ui
+
+import (
+	"fmt"
+
+	"gioui.org/layout"
+	"gioui.org/unit"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+
+	"dback/models"
+)
+
+// tableRestoreState backs the "Restore only selected tables" picker on the backup detail
+// page. The table list is loaded in the background the first time the picker is opened.
+type tableRestoreState struct {
+	enabled  widget.Bool
+	triggers widget.Bool
+	recordID string
+	loading  bool
+	loadErr  string
+	tables   []string
+	checks   map[string]*widget.Bool
+	list     widget.List
+}
+
+func (s *tableRestoreState) reset(recordID string) {
+	*s = tableRestoreState{recordID: recordID}
+}
+
+// selection returns the tables picked for restore, or nil for a full restore.
+func (s *tableRestoreState) selection() *models.TableSelection {
+	if !s.enabled.Value {
+		return nil
+	}
+	sel := models.TableSelection{Triggers: s.triggers.Value}
+	for _, name := range s.tables {
+		if c := s.checks[name]; c != nil && c.Value {
+			sel.Tables = append(sel.Tables, name)
+		}
+	}
+	return &sel
+}
+
+func (u *UI) loadRestoreTables(record models.ExportRecord) {
+	s := &u.restoreTables
+	if s.loading || s.tables != nil || s.loadErr != "" {
+		return
+	}
+	s.loading = true
+	go func() {
+		tables, err := u.core.BackupTables(record)
+		if s.recordID != record.ID {
+			return
+		}
+		s.loading = false
+		if err != nil {
+			s.loadErr = err.Error()
+		} else {
+			s.checks = make(map[string]*widget.Bool, len(tables))
+			for _, name := range tables {
+				s.checks[name] = new(widget.Bool)
+			}
+			s.tables = tables
+		}
+		u.invalidate()
+	}()
+}
+
+func (u *UI) layoutRestoreTables(gtx layout.Context, th *material.Theme, record models.ExportRecord) layout.Dimensions {
+	theme := u.theme
+	s := &u.restoreTables
+	if s.enabled.Update(gtx) && s.enabled.Value {
+		u.loadRestoreTables(record)
+	}
+	return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return checkboxField(gtx, th, theme, &s.enabled, "Restore only selected tables")
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !s.enabled.Value {
+					return mutedLabel(gtx, th, theme, "Restores the whole backup. The destination database is replaced.")
+				}
+				return mutedLabel(gtx, th, theme, "Only the checked tables are dropped and recreated; other tables in the destination are left as they are.")
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if !s.enabled.Value {
+					return layout.Dimensions{}
+				}
+				switch {
+				case s.loading:
+					return mutedLabel(gtx, th, theme, "Reading tables from backup...")
+				case s.loadErr != "":
+					return mutedLabel(gtx, th, theme, "Could not read tables: "+s.loadErr)
+				case len(s.tables) == 0:
+					return mutedLabel(gtx, th, theme, "No tables found in this backup.")
+				}
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return checkboxField(gtx, th, theme, &s.triggers, "Include the tables' triggers")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						gtx.Constraints.Max.Y = gtx.Dp(unit.Dp(220))
+						s.list.Axis = layout.Vertical
+						return material.List(th, &s.list).Layout(gtx, len(s.tables), func(gtx layout.Context, index int) layout.Dimensions {
+							name := s.tables[index]
+							return checkboxField(gtx, th, theme, s.checks[name], name)
+						})
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						sel := s.selection()
+						return mutedLabel(gtx, th, theme, fmt.Sprintf("%d of %d table(s) selected", len(sel.Tables), len(s.tables)))
+					}),
+				)
+			}),
+		)
+	})
+}
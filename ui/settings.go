@@ -31,12 +31,40 @@ func (u *UI) layoutSettings(gtx layout.Context, th *material.Theme) layout.Dimen
 							u.invalidate()
 						})
 					},
+					func(gtx layout.Context) layout.Dimensions {
+						return tabButton(gtx, th, theme, &u.tabSettingsNotify, "Notifications", u.settingsTab == 2, func() {
+							u.settingsTab = 2
+							u.loadNotifyFormFromCore()
+							u.invalidate()
+						})
+					},
+					func(gtx layout.Context) layout.Dimensions {
+						return tabButton(gtx, th, theme, &u.tabSettingsRepo, "Repository", u.settingsTab == 3, func() {
+							u.settingsTab = 3
+							u.loadRepositoryStats()
+							u.invalidate()
+						})
+					},
+					func(gtx layout.Context) layout.Dimensions {
+						return tabButton(gtx, th, theme, &u.tabSettingsBandwidth, "Bandwidth", u.settingsTab == 4, func() {
+							u.settingsTab = 4
+							u.loadBandwidthFormFromCore()
+							u.invalidate()
+						})
+					},
 				)
 			}),
 			layout.Rigid(vgap(theme)),
 			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
-				if u.settingsTab == 1 {
+				switch u.settingsTab {
+				case 1:
 					return u.layoutSettingsSync(gtx, th, theme)
+				case 2:
+					return u.layoutSettingsNotify(gtx, th, theme)
+				case 3:
+					return u.layoutSettingsRepository(gtx, th, theme)
+				case 4:
+					return u.layoutSettingsBandwidth(gtx, th, theme)
 				}
 				return u.layoutSettingsExport(gtx, th, theme)
 			}),
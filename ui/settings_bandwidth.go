@@ -0,0 +1,148 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"dback/models"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// bandwidthFields edit a bandwidth cap: the app-wide one on the Bandwidth settings tab and
+// a host's own in the host form.
+type bandwidthFields struct {
+	Upload      widget.Editor
+	Download    widget.Editor
+	WindowStart widget.Editor
+	WindowEnd   widget.Editor
+}
+
+func (f *bandwidthFields) set(limit *models.BandwidthLimit) {
+	for _, e := range []*widget.Editor{&f.Upload, &f.Download, &f.WindowStart, &f.WindowEnd} {
+		e.SingleLine = true
+		setEditorText(e, "")
+	}
+	if limit == nil {
+		return
+	}
+	if limit.UploadMBps > 0 {
+		setEditorText(&f.Upload, strconv.FormatFloat(limit.UploadMBps, 'f', -1, 64))
+	}
+	if limit.DownloadMBps > 0 {
+		setEditorText(&f.Download, strconv.FormatFloat(limit.DownloadMBps, 'f', -1, 64))
+	}
+	setEditorText(&f.WindowStart, limit.WindowStart)
+	setEditorText(&f.WindowEnd, limit.WindowEnd)
+}
+
+// limit returns nil when neither direction is capped.
+func (f *bandwidthFields) limit() (*models.BandwidthLimit, error) {
+	mbps := func(e *widget.Editor, label string) (float64, error) {
+		text := strings.TrimSpace(editorText(e))
+		if text == "" {
+			return 0, nil
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil || v < 0 {
+			return 0, fmt.Errorf("%s limit must be a number of MB/s", label)
+		}
+		return v, nil
+	}
+	upload, err := mbps(&f.Upload, "upload")
+	if err != nil {
+		return nil, err
+	}
+	download, err := mbps(&f.Download, "download")
+	if err != nil {
+		return nil, err
+	}
+	limit := models.BandwidthLimit{
+		UploadMBps:   upload,
+		DownloadMBps: download,
+		WindowStart:  strings.TrimSpace(editorText(&f.WindowStart)),
+		WindowEnd:    strings.TrimSpace(editorText(&f.WindowEnd)),
+	}
+	if !limit.Active() {
+		return nil, nil
+	}
+	return &limit, nil
+}
+
+func (f *bandwidthFields) layout(gtx layout.Context, th *material.Theme, theme *AppTheme) layout.Dimensions {
+	pair := func(leftLabel string, left *widget.Editor, leftHint, rightLabel string, right *widget.Editor, rightHint string) layout.Widget {
+		return func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					return labeledField(gtx, th, theme, leftLabel, func(gtx layout.Context) layout.Dimensions {
+						return editorField(gtx, th, theme, left, leftHint)
+					})
+				}),
+				layout.Rigid(hgap(theme)),
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					return labeledField(gtx, th, theme, rightLabel, func(gtx layout.Context) layout.Dimensions {
+						return editorField(gtx, th, theme, right, rightHint)
+					})
+				}),
+			)
+		}
+	}
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+		layout.Rigid(pair("Download (MB/s)", &f.Download, "unlimited", "Upload (MB/s)", &f.Upload, "unlimited")),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(pair("Limit From", &f.WindowStart, "08:00", "Limit Until", &f.WindowEnd, "18:00")),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return mutedLabel(gtx, th, theme, "Download caps backups, upload caps restores and deep verify. Leave a field empty for no cap. With times set the caps apply only between them (local time, may wrap past midnight). Localhost hosts are never limited.")
+		}),
+	)
+}
+
+// bandwidthSettingsState backs the Bandwidth settings tab.
+type bandwidthSettingsState struct {
+	fields  bandwidthFields
+	saveBtn widget.Clickable
+}
+
+func (u *UI) loadBandwidthFormFromCore() {
+	u.bandwidthSettings.fields.set(u.core.Bandwidth())
+}
+
+func (u *UI) saveBandwidthSettings() {
+	limit, err := u.bandwidthSettings.fields.limit()
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	if err := u.core.SetBandwidth(limit); err != nil {
+		u.showError(err)
+		return
+	}
+	u.loadBandwidthFormFromCore()
+	u.showInfo("Bandwidth saved", "Running transfers use the new caps within a second.")
+}
+
+func (u *UI) layoutSettingsBandwidth(gtx layout.Context, th *material.Theme, theme *AppTheme) layout.Dimensions {
+	s := &u.bandwidthSettings
+	return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return sectionLabel(gtx, th, theme, "Bandwidth")
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return mutedLabel(gtx, th, theme, "Caps all backups and restores together, however many run at once. Hosts can set a lower cap of their own.")
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return s.fields.layout(gtx, th, theme)
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return successButton(gtx, th, theme, &s.saveBtn, "Save", u.saveBandwidthSettings)
+			}),
+		)
+	})
+}
@@ -2,8 +2,13 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
+	"dback/backend/codec"
+	"dback/backend/db"
+	"dback/backend/mask"
+	coreapp "dback/internal/app"
 	"dback/models"
 
 	"gioui.org/layout"
@@ -19,7 +24,17 @@ var (
 		string(models.ConnectionTypeWordPress),
 	}
 	authTypeValues = []string{string(models.AuthTypePassword), string(models.AuthTypeKeyFile)}
-	dbTypeValues   = []string{string(models.DBTypeMySQL), string(models.DBTypeMariaDB)}
+	dbTypeValues   = []string{string(models.DBTypeMySQL), string(models.DBTypeMariaDB), string(models.DBTypePostgreSQL)}
+
+	// dbSelectionSingle is the form value for "no DatabaseSelection": back up TargetDBName only.
+	dbSelectionSingle = "single"
+	dbSelectionValues = []string{dbSelectionSingle, models.DatabasesList, models.DatabasesPattern, models.DatabasesAll}
+	dbSelectionLabels = []string{"Database above", "List", "Pattern", "All non-system"}
+
+	dumpFormatValues = []string{string(models.DumpFormatSingle), string(models.DumpFormatSplit), string(models.DumpFormatRepository)}
+	dumpFormatLabels = []string{"Single file", "Split per table", "Repository (deduplicated)"}
+	compressionValues = []string{string(models.CompressionGzip), string(models.CompressionZstd), string(models.CompressionXz), string(models.CompressionNone)}
+	compressionLabels = []string{"gzip / pigz", "zstd", "xz", "None"}
 )
 
 type SettingsForm struct {
@@ -48,8 +63,35 @@ type SettingsForm struct {
 	IsDocker       widget.Bool
 	ContainerID    widget.Editor
 	TargetDB       widget.Editor
+	DBSelection    widget.Enum
+	DBSelectionArg widget.Editor
+	TablesInclude    widget.Editor
+	TablesExclude    widget.Editor
+	TablesSchemaOnly widget.Editor
+	TablesRows       widget.Editor
 	Destination    widget.Editor
+	DumpFormat     widget.Enum
+	PhysicalBackup widget.Bool
+	EncryptBackups widget.Bool
+	RecoveryPassphrase widget.Editor
+	Compression    widget.Enum
+	CompressionLevel widget.Editor
 	ImportProtected widget.Bool
+	MaskRules       widget.Editor
+	ScheduleEnabled     widget.Bool
+	ScheduleCron        widget.Editor
+	ScheduleInterval    widget.Editor
+	ScheduleWindowStart widget.Editor
+	ScheduleWindowEnd   widget.Editor
+	BinlogEnabled       widget.Bool
+	BinlogInterval      widget.Editor
+	RetentionKeepLast    widget.Editor
+	RetentionKeepDaily   widget.Editor
+	RetentionKeepWeekly  widget.Editor
+	RetentionKeepMonthly widget.Editor
+	RetentionMaxAgeDays  widget.Editor
+	RetentionForGroup    widget.Bool
+	Bandwidth            bandwidthFields
 
 	defaultDestination string
 	scrollList         widget.List
@@ -58,15 +100,19 @@ type SettingsForm struct {
 	selectJumpKeyBtn    widget.Clickable
 	selectFolderBtn     widget.Clickable
 	generateWPKeyBtn    widget.Clickable
+	previewRetentionBtn widget.Clickable
 	downloadPluginBtn   widget.Clickable
 	wpPasswordVisible   bool
 	wpPasswordToggle    widget.Clickable
 	sshPasswordVisible  bool
 	jumpPasswordVisible bool
 	dbPasswordVisible   bool
+	shownDBType         string
 	sshPasswordToggle   widget.Clickable
 	jumpPasswordToggle  widget.Clickable
 	dbPasswordToggle    widget.Clickable
+	recoveryVisible     bool
+	recoveryToggle      widget.Clickable
 }
 
 func newSettingsForm(p models.Profile, defaultDest string) *SettingsForm {
@@ -93,28 +139,253 @@ func newSettingsForm(p models.Profile, defaultDest string) *SettingsForm {
 	setEditorText(&f.WPUrl, wpURL)
 	setEditorText(&f.WPKey, p.WPKey)
 	setEditorText(&f.DBHost, defaultString(p.DBHost, "127.0.0.1"))
-	setEditorText(&f.DBPort, defaultString(p.DBPort, "3306"))
+	setEditorText(&f.DBPort, defaultString(p.DBPort, defaultDBPort(string(p.DBType))))
 	setEditorText(&f.DBUser, p.DBUser)
 	setEditorText(&f.DBPassword, p.DBPassword)
 	f.DBType.Value = defaultString(string(p.DBType), string(models.DBTypeMySQL))
+	f.shownDBType = f.DBType.Value
 	f.IsDocker.Value = p.IsDocker
 	setEditorText(&f.ContainerID, p.ContainerID)
 	setEditorText(&f.TargetDB, p.TargetDBName)
+	f.DBSelection.Value = dbSelectionSingle
+	if sel := p.Databases; sel.Active() {
+		f.DBSelection.Value = sel.Mode
+		switch sel.Mode {
+		case models.DatabasesList:
+			setEditorText(&f.DBSelectionArg, strings.Join(sel.Names, ", "))
+		case models.DatabasesPattern:
+			setEditorText(&f.DBSelectionArg, sel.Pattern)
+		}
+	}
+	if t := p.Tables; t != nil {
+		setEditorText(&f.TablesInclude, strings.Join(t.Include, ", "))
+		setEditorText(&f.TablesExclude, strings.Join(t.Exclude, ", "))
+		setEditorText(&f.TablesSchemaOnly, strings.Join(t.SchemaOnly, ", "))
+		setEditorText(&f.TablesRows, db.FormatRowFilters(t.Rows))
+	}
 	dest := p.Destination
 	if strings.TrimSpace(dest) == "" && defaultDest != "" {
 		dest = defaultDest
 	}
 	setEditorText(&f.Destination, dest)
+	f.DumpFormat.Value = defaultString(string(p.DumpFormat), string(models.DumpFormatSingle))
+	f.PhysicalBackup.Value = p.PhysicalBackup
+	if e := p.Encryption; e != nil {
+		f.EncryptBackups.Value = e.Enabled
+		setEditorText(&f.RecoveryPassphrase, e.RecoveryPassphrase)
+	}
+	f.Compression.Value = string(codec.Normalize(p.Compression))
+	if p.CompressionLevel > 0 {
+		setEditorText(&f.CompressionLevel, strconv.Itoa(p.CompressionLevel))
+	}
 	f.ImportProtected.Value = p.ImportProtected
+	setEditorText(&f.MaskRules, mask.FormatRules(p.Masking))
+	if s := p.Schedule; s != nil {
+		f.ScheduleEnabled.Value = s.Enabled
+		setEditorText(&f.ScheduleCron, s.Cron)
+		if s.IntervalMinutes > 0 {
+			setEditorText(&f.ScheduleInterval, strconv.Itoa(s.IntervalMinutes))
+		}
+		setEditorText(&f.ScheduleWindowStart, s.WindowStart)
+		setEditorText(&f.ScheduleWindowEnd, s.WindowEnd)
+	}
+	f.Bandwidth.set(p.Bandwidth)
+	if b := p.Binlog; b != nil {
+		f.BinlogEnabled.Value = b.Enabled
+		if b.IntervalMinutes > 0 {
+			setEditorText(&f.BinlogInterval, strconv.Itoa(b.IntervalMinutes))
+		}
+	}
 	return f
 }
 
+// setRetention fills the retention fields with the host's effective policy.
+func (f *SettingsForm) setRetention(p *models.RetentionPolicy, fromGroup bool) {
+	f.RetentionForGroup.Value = fromGroup
+	if p == nil {
+		return
+	}
+	for _, field := range []struct {
+		e *widget.Editor
+		v int
+	}{
+		{&f.RetentionKeepLast, p.KeepLast},
+		{&f.RetentionKeepDaily, p.KeepDaily},
+		{&f.RetentionKeepWeekly, p.KeepWeekly},
+		{&f.RetentionKeepMonthly, p.KeepMonthly},
+		{&f.RetentionMaxAgeDays, p.MaxAgeDays},
+	} {
+		if field.v > 0 {
+			setEditorText(field.e, strconv.Itoa(field.v))
+		}
+	}
+}
+
+// retention returns nil when no retention field is set.
+func (f *SettingsForm) retention() *models.RetentionPolicy {
+	num := func(e *widget.Editor) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(editorText(e)))
+		return n
+	}
+	p := models.RetentionPolicy{
+		KeepLast:    num(&f.RetentionKeepLast),
+		KeepDaily:   num(&f.RetentionKeepDaily),
+		KeepWeekly:  num(&f.RetentionKeepWeekly),
+		KeepMonthly: num(&f.RetentionKeepMonthly),
+		MaxAgeDays:  num(&f.RetentionMaxAgeDays),
+	}
+	if !p.Active() {
+		return nil
+	}
+	return &p
+}
+
+// hostRetention is the host's own policy; group policies are saved separately.
+func (f *SettingsForm) hostRetention() *models.RetentionPolicy {
+	if f.RetentionForGroup.Value {
+		return nil
+	}
+	return f.retention()
+}
+
+// databases returns nil unless the host backs up several databases.
+func (f *SettingsForm) databases() *models.DatabaseSelection {
+	if f.ConnectionType.Value == string(models.ConnectionTypeWordPress) {
+		return nil
+	}
+	arg := strings.TrimSpace(editorText(&f.DBSelectionArg))
+	switch f.DBSelection.Value {
+	case models.DatabasesList:
+		names := strings.FieldsFunc(arg, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' })
+		return &models.DatabaseSelection{Mode: models.DatabasesList, Names: names}
+	case models.DatabasesPattern:
+		return &models.DatabaseSelection{Mode: models.DatabasesPattern, Pattern: arg}
+	case models.DatabasesAll:
+		return &models.DatabaseSelection{Mode: models.DatabasesAll}
+	}
+	return nil
+}
+
+// tables returns nil when no table pattern or row filter is set. Row filters that do not
+// parse are left out; saveProfile reports them before saving.
+func (f *SettingsForm) tables() *models.TableFilter {
+	patterns := func(e *widget.Editor) []string {
+		return strings.FieldsFunc(editorText(e), func(r rune) bool { return r == ',' || r == ' ' || r == '\n' })
+	}
+	rows, _ := f.rowFilters()
+	t := models.TableFilter{
+		Include:    patterns(&f.TablesInclude),
+		Exclude:    patterns(&f.TablesExclude),
+		SchemaOnly: patterns(&f.TablesSchemaOnly),
+		Rows:       rows,
+	}
+	if !t.Active() {
+		return nil
+	}
+	return &t
+}
+
+// rowFilters parses the row filters, one per line.
+func (f *SettingsForm) rowFilters() ([]models.RowFilter, error) {
+	return db.ParseRowFilters(editorText(&f.TablesRows))
+}
+
+// masking parses the masking rules, one per line.
+func (f *SettingsForm) masking() ([]models.MaskRule, error) {
+	return mask.ParseRules(editorText(&f.MaskRules))
+}
+
+// maskingRules is masking without the error; saveProfile reports it before saving.
+func (f *SettingsForm) maskingRules() []models.MaskRule {
+	rules, _ := f.masking()
+	return rules
+}
+
+// bandwidth is the host's cap without the error; saveProfile reports it before saving.
+// Localhost hosts are never limited.
+func (f *SettingsForm) bandwidth() *models.BandwidthLimit {
+	if f.ConnectionType.Value == string(models.ConnectionTypeLocalhost) {
+		return nil
+	}
+	limit, _ := f.Bandwidth.limit()
+	return limit
+}
+
+// schedule returns nil when every schedule field is empty so unscheduled hosts stay unchanged.
+func (f *SettingsForm) schedule() *models.BackupSchedule {
+	interval, _ := strconv.Atoi(strings.TrimSpace(editorText(&f.ScheduleInterval)))
+	s := models.BackupSchedule{
+		Enabled:         f.ScheduleEnabled.Value,
+		Cron:            strings.TrimSpace(editorText(&f.ScheduleCron)),
+		IntervalMinutes: interval,
+		WindowStart:     strings.TrimSpace(editorText(&f.ScheduleWindowStart)),
+		WindowEnd:       strings.TrimSpace(editorText(&f.ScheduleWindowEnd)),
+	}
+	if s == (models.BackupSchedule{}) {
+		return nil
+	}
+	return &s
+}
+
+// binlog returns nil unless incremental backups are turned on.
+// compression is the codec picked for dumps; WordPress exports are always gzip.
+func (f *SettingsForm) compression() models.Compression {
+	if f.ConnectionType.Value == string(models.ConnectionTypeWordPress) {
+		return ""
+	}
+	return models.Compression(f.Compression.Value)
+}
+
+func (f *SettingsForm) compressionLevel() int {
+	if f.ConnectionType.Value == string(models.ConnectionTypeWordPress) {
+		return 0
+	}
+	level, _ := strconv.Atoi(strings.TrimSpace(editorText(&f.CompressionLevel)))
+	return level
+}
+
+// encryption returns nil unless backup files are encrypted.
+func (f *SettingsForm) encryption() *models.BackupEncryption {
+	if !f.EncryptBackups.Value || f.ConnectionType.Value == string(models.ConnectionTypeWordPress) {
+		return nil
+	}
+	return &models.BackupEncryption{Enabled: true, RecoveryPassphrase: editorText(&f.RecoveryPassphrase)}
+}
+
+func (f *SettingsForm) binlog() *models.BinlogSettings {
+	if !f.BinlogEnabled.Value || f.ConnectionType.Value == string(models.ConnectionTypeWordPress) || f.DBType.Value == string(models.DBTypePostgreSQL) {
+		return nil
+	}
+	interval, _ := strconv.Atoi(strings.TrimSpace(editorText(&f.BinlogInterval)))
+	return &models.BinlogSettings{Enabled: true, IntervalMinutes: interval}
+}
+
 func (f *SettingsForm) supportsSQLQuery() bool {
 	if f.ConnectionType.Value == string(models.ConnectionTypeWordPress) {
 		return true
 	}
 	db := f.DBType.Value
-	return db == string(models.DBTypeMySQL) || db == string(models.DBTypeMariaDB)
+	return db == string(models.DBTypeMySQL) || db == string(models.DBTypeMariaDB) || db == string(models.DBTypePostgreSQL)
+}
+
+// defaultDBPort is the server's usual port for a DB type.
+func defaultDBPort(dbType string) string {
+	if dbType == string(models.DBTypePostgreSQL) {
+		return "5432"
+	}
+	return "3306"
+}
+
+// syncDBPort swaps the port for the new type's default when the DB type changes and the
+// port still holds the old type's default.
+func (f *SettingsForm) syncDBPort() {
+	if f.DBType.Value == f.shownDBType {
+		return
+	}
+	if strings.TrimSpace(editorText(&f.DBPort)) == defaultDBPort(f.shownDBType) {
+		setEditorText(&f.DBPort, defaultDBPort(f.DBType.Value))
+	}
+	f.shownDBType = f.DBType.Value
 }
 
 func (f *SettingsForm) profile() models.Profile {
@@ -144,8 +415,20 @@ func (f *SettingsForm) profile() models.Profile {
 		IsDocker:        f.IsDocker.Value,
 		ContainerID:     strings.TrimSpace(editorText(&f.ContainerID)),
 		TargetDBName:    strings.TrimSpace(editorText(&f.TargetDB)),
+		Databases:       f.databases(),
+		Tables:          f.tables(),
 		Destination:     strings.TrimSpace(editorText(&f.Destination)),
+		DumpFormat:      models.DumpFormat(f.DumpFormat.Value),
+		PhysicalBackup:  f.PhysicalBackup.Value && f.ConnectionType.Value != string(models.ConnectionTypeWordPress) && f.DBType.Value != string(models.DBTypePostgreSQL),
+		Encryption:      f.encryption(),
+		Compression:      f.compression(),
+		CompressionLevel: f.compressionLevel(),
 		ImportProtected:   f.ImportProtected.Value,
+		Masking:           f.maskingRules(),
+		Schedule:        f.schedule(),
+		Binlog:          f.binlog(),
+		Retention:       f.hostRetention(),
+		Bandwidth:       f.bandwidth(),
 	}
 }
 
@@ -156,6 +439,8 @@ func (f *SettingsForm) layout(gtx layout.Context, th *material.Theme, theme *App
 	useKey := f.AuthType.Value == string(models.AuthTypeKeyFile)
 	useJumpKey := f.JumpAuthType.Value == string(models.AuthTypeKeyFile)
 	isDocker := f.IsDocker.Value
+	isPostgres := f.DBType.Value == string(models.DBTypePostgreSQL) && !isWordPress
+	f.syncDBPort()
 
 	return scrollArea(gtx, th, &f.scrollList, func(gtx layout.Context) layout.Dimensions {
 		var sections []layout.FlexChild
@@ -443,7 +728,7 @@ func (f *SettingsForm) layout(gtx layout.Context, th *material.Theme, theme *App
 							return layout.Dimensions{}
 						}
 						return labeledField(gtx, th, theme, "DB Port", func(gtx layout.Context) layout.Dimensions {
-							return editorField(gtx, th, theme, &f.DBPort, "3306")
+							return editorField(gtx, th, theme, &f.DBPort, defaultDBPort(f.DBType.Value))
 						})
 					}),
 					layout.Rigid(vgap(theme)),
@@ -482,6 +767,38 @@ func (f *SettingsForm) layout(gtx layout.Context, th *material.Theme, theme *App
 						}
 						return mutedLabel(gtx, th, theme, "Empty uses the WordPress database from wp-config.php. A custom name creates/selects that database before import.")
 					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress {
+							return layout.Dimensions{}
+						}
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return labeledEnumField(gtx, th, theme, &f.DBSelection, "Back Up", dbSelectionValues, dbSelectionLabels)
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								switch f.DBSelection.Value {
+								case models.DatabasesList:
+									return labeledField(gtx, th, theme, "Databases", func(gtx layout.Context) layout.Dimensions {
+										return editorField(gtx, th, theme, &f.DBSelectionArg, "shop, crm, blog")
+									})
+								case models.DatabasesPattern:
+									return labeledField(gtx, th, theme, "Database Pattern", func(gtx layout.Context) layout.Dimensions {
+										return editorField(gtx, th, theme, &f.DBSelectionArg, "shop_*, crm")
+									})
+								case models.DatabasesAll:
+									return mutedLabel(gtx, th, theme, "Every database except information_schema, performance_schema, mysql and sys.")
+								}
+								return layout.Dimensions{}
+							}),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								if f.DBSelection.Value == dbSelectionSingle {
+									return layout.Dimensions{}
+								}
+								return mutedLabel(gtx, th, theme, "Each database is saved to its own file. Restores and hooks still use the database above.")
+							}),
+						)
+					}),
 				)
 			})
 		}))
@@ -518,6 +835,138 @@ func (f *SettingsForm) layout(gtx layout.Context, th *material.Theme, theme *App
 							}),
 						)
 					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledEnumField(gtx, th, theme, &f.DumpFormat, "Format", dumpFormatValues, dumpFormatLabels)
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						switch f.DumpFormat.Value {
+						case string(models.DumpFormatSplit):
+							return mutedLabel(gtx, th, theme, "Stores a .split.tar with one compressed SQL file per table and a manifest of row counts and checksums. Restores reassemble it automatically.")
+						case string(models.DumpFormatRepository):
+							return mutedLabel(gtx, th, theme, "Stores each dump as content-defined chunks in the destination's .dback-repo folder, shared with earlier backups, plus a .chunks.json manifest. Nightly dumps of the same database only add what changed. Restores reassemble it automatically; see Settings → Repository for space used.")
+						}
+						return layout.Dimensions{}
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: theme.Gap}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return labeledEnumField(gtx, th, theme, &f.Compression, "Compression", compressionValues, compressionLabels)
+						})
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						c := models.Compression(f.Compression.Value)
+						lo, hi, def := codec.Levels(c)
+						if isWordPress || hi == 0 {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: theme.Gap}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return labeledField(gtx, th, theme, fmt.Sprintf("Level (%d-%d)", lo, hi), func(gtx layout.Context) layout.Dimensions {
+								return editorField(gtx, th, theme, &f.CompressionLevel, strconv.Itoa(def))
+							})
+						})
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress {
+							return layout.Dimensions{}
+						}
+						return mutedLabel(gtx, th, theme, "Files are named .sql.gz, .sql.zst, .sql.xz or .sql to match. The host needs the chosen tool; restores read any of them, and bzip2 files too.")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress || isPostgres {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: theme.Gap}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return checkboxField(gtx, th, theme, &f.PhysicalBackup, "Physical backup with mariadb-backup or xtrabackup")
+						})
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress || isPostgres || !f.PhysicalBackup.Value {
+							return layout.Dimensions{}
+						}
+						return mutedLabel(gtx, th, theme, "Copies the whole server's data files as a compressed xbstream. Falls back to a dump when neither tool is installed. Restoring stops the server and needs root or sudo.")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: theme.Gap}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return checkboxField(gtx, th, theme, &f.EncryptBackups, "Encrypt backup files")
+						})
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress || !f.EncryptBackups.Value {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: theme.Gap}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							return labeledField(gtx, th, theme, "Recovery Passphrase", func(gtx layout.Context) layout.Dimensions {
+								return passwordField(gtx, th, theme, &f.RecoveryPassphrase, "Optional, 8+ characters", &f.recoveryVisible, &f.recoveryToggle)
+							})
+						})
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress || !f.EncryptBackups.Value {
+							return layout.Dimensions{}
+						}
+						return mutedLabel(gtx, th, theme, "Dumps are saved as .enc files encrypted with AES-256-GCM under a key kept in the vault. Restore and verify decrypt them automatically. Keep the recovery passphrase somewhere safe: with it, \"dback decrypt\" opens the files without the vault.")
+					}),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if isWordPress || !f.EncryptBackups.Value || editorText(&f.RecoveryPassphrase) != "" {
+							return layout.Dimensions{}
+						}
+						return layout.Inset{Top: theme.Gap}.Layout(gtx, func(gtx layout.Context) layout.Dimensions {
+							lbl := material.Body2(th, coreapp.NoRecoveryPassphraseWarning)
+							lbl.Color = theme.Danger
+							return lbl.Layout(gtx)
+						})
+					}),
+				)
+			})
+		}))
+
+		sections = append(sections, layout.Rigid(vgap(theme)))
+		sections = append(sections, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						lbl := material.Subtitle1(th, "Tables")
+						lbl.Color = theme.Text
+						return lbl.Layout(gtx)
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledField(gtx, th, theme, "Include Only", func(gtx layout.Context) layout.Dimensions {
+							return editorField(gtx, th, theme, &f.TablesInclude, "All tables")
+						})
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledField(gtx, th, theme, "Exclude", func(gtx layout.Context) layout.Dimensions {
+							return editorField(gtx, th, theme, &f.TablesExclude, "log_*, cache_*")
+						})
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledField(gtx, th, theme, "Structure Only (no data)", func(gtx layout.Context) layout.Dimensions {
+							return editorField(gtx, th, theme, &f.TablesSchemaOnly, "sessions, wp_actionscheduler_logs")
+						})
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "Comma-separated glob patterns (* and ?). Excluded tables are left out of the dump; structure-only tables are restored empty. Deep verify skips excluded tables.")
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledField(gtx, th, theme, "Row Filters (subset backup)", func(gtx layout.Context) layout.Dimensions {
+							return editorMultiline(gtx, th, theme, &f.TablesRows, "orders: last 90 days by created_at")
+						})
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "One filter per line: table: SQL condition, or table: last N days by column. Backups with filtered rows are marked partial, and deep verify expects the filtered row counts.")
+					}),
 				)
 			})
 		}))
@@ -543,6 +992,191 @@ func (f *SettingsForm) layout(gtx layout.Context, th *material.Theme, theme *App
 			})
 		}))
 
+		sections = append(sections, layout.Rigid(vgap(theme)))
+		sections = append(sections, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						lbl := material.Subtitle1(th, "Data Masking")
+						lbl.Color = theme.Text
+						return lbl.Layout(gtx)
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return labeledField(gtx, th, theme, "Rules for restores to this host", func(gtx layout.Context) layout.Dimensions {
+							return editorMultiline(gtx, th, theme, &f.MaskRules, "wp_users.user_email keep_domain")
+						})
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "One rule per line: table.column strategy. Strategies: fake_email, keep_domain, hash, null, or fixed followed by the value. Tables may use * and ?. Rows are masked on this machine before upload; use Preview masking on a backup to see which columns change.")
+					}),
+				)
+			})
+		}))
+
+		sections = append(sections, layout.Rigid(vgap(theme)))
+		sections = append(sections, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						lbl := material.Subtitle1(th, "Schedule")
+						lbl.Color = theme.Text
+						return lbl.Layout(gtx)
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return checkboxField(gtx, th, theme, &f.ScheduleEnabled, "Back up this host automatically")
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if !f.ScheduleEnabled.Value {
+							return layout.Dimensions{}
+						}
+						return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return labeledField(gtx, th, theme, "Cron Expression", func(gtx layout.Context) layout.Dimensions {
+									return editorField(gtx, th, theme, &f.ScheduleCron, "0 3 * * *")
+								})
+							}),
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return labeledField(gtx, th, theme, "Or Every N Minutes", func(gtx layout.Context) layout.Dimensions {
+									return editorField(gtx, th, theme, &f.ScheduleInterval, "360")
+								})
+							}),
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+									layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+										return labeledField(gtx, th, theme, "Window Start", func(gtx layout.Context) layout.Dimensions {
+											return editorField(gtx, th, theme, &f.ScheduleWindowStart, "01:00")
+										})
+									}),
+									layout.Rigid(hgap(theme)),
+									layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+										return labeledField(gtx, th, theme, "Window End", func(gtx layout.Context) layout.Dimensions {
+											return editorField(gtx, th, theme, &f.ScheduleWindowEnd, "05:00")
+										})
+									}),
+								)
+							}),
+							layout.Rigid(vgap(theme)),
+							layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+								return mutedLabel(gtx, th, theme, "Runs while DBack is open and unlocked. A cron expression wins over the interval. Backups missed while the app was closed run once at the next opportunity inside the window.")
+							}),
+						)
+					}),
+				)
+			})
+		}))
+
+		if !isLocal {
+			sections = append(sections, layout.Rigid(vgap(theme)))
+			sections = append(sections, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							lbl := material.Subtitle1(th, "Bandwidth")
+							lbl.Color = theme.Text
+							return lbl.Layout(gtx)
+						}),
+						layout.Rigid(vgap(theme)),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							return f.Bandwidth.layout(gtx, th, theme)
+						}),
+					)
+				})
+			}))
+		}
+
+		if !isWordPress && !isPostgres {
+			sections = append(sections, layout.Rigid(vgap(theme)))
+			sections = append(sections, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							lbl := material.Subtitle1(th, "Incremental Backups")
+							lbl.Color = theme.Text
+							return lbl.Layout(gtx)
+						}),
+						layout.Rigid(vgap(theme)),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							return checkboxField(gtx, th, theme, &f.BinlogEnabled, "Pull binary logs between full backups")
+						}),
+						layout.Rigid(vgap(theme)),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							if !f.BinlogEnabled.Value {
+								return layout.Dimensions{}
+							}
+							return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+								layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+									return labeledField(gtx, th, theme, "Pull Every N Minutes", func(gtx layout.Context) layout.Dimensions {
+										return editorField(gtx, th, theme, &f.BinlogInterval, "15")
+									})
+								}),
+								layout.Rigid(vgap(theme)),
+							)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							return mutedLabel(gtx, th, theme, "Full backups record their binary log position; new events are then pulled with mysqlbinlog into the host's backup folder, so a backup can be restored to any point in time. Needs binary logging on the server and a database user with RELOAD and REPLICATION CLIENT/SLAVE privileges. Leave the interval empty to pull only on demand.")
+						}),
+					)
+				})
+			}))
+		}
+
+		sections = append(sections, layout.Rigid(vgap(theme)))
+		sections = append(sections, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+				numField := func(label string, e *widget.Editor, hint string) layout.FlexChild {
+					return layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+						return labeledField(gtx, th, theme, label, func(gtx layout.Context) layout.Dimensions {
+							return editorField(gtx, th, theme, e, hint)
+						})
+					})
+				}
+				return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						lbl := material.Subtitle1(th, "Retention")
+						lbl.Color = theme.Text
+						return lbl.Layout(gtx)
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return layout.Flex{Axis: layout.Horizontal}.Layout(gtx,
+							numField("Keep Last", &f.RetentionKeepLast, "7"),
+							layout.Rigid(hgap(theme)),
+							numField("Daily", &f.RetentionKeepDaily, "7"),
+							layout.Rigid(hgap(theme)),
+							numField("Weekly", &f.RetentionKeepWeekly, "4"),
+							layout.Rigid(hgap(theme)),
+							numField("Monthly", &f.RetentionKeepMonthly, "12"),
+							layout.Rigid(hgap(theme)),
+							numField("Max Age (days)", &f.RetentionMaxAgeDays, "365"),
+						)
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return checkboxField(gtx, th, theme, &f.RetentionForGroup, "Use for every host in this group")
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						return mutedLabel(gtx, th, theme, "Empty fields are ignored. A backup is kept if any rule keeps it; backups older than the max age are deleted. The newest backup is never deleted. Retention runs after each successful backup.")
+					}),
+					layout.Rigid(vgap(theme)),
+					layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+						if u.editingProfile.ID == "" {
+							return layout.Dimensions{}
+						}
+						return secondaryButton(gtx, th, theme, &f.previewRetentionBtn, "Preview Cleanup", func() {
+							u.previewRetention(u.editingProfile)
+						})
+					}),
+				)
+			})
+		}))
+
 		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, sections...)
 	})
 }
@@ -0,0 +1,459 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"dback/models"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// Scope values in the notification form: all hosts, one group, or one host.
+const (
+	notifyScopeGroup = "group:"
+	notifyScopeHost  = "host:"
+)
+
+type notifyRowWidgets struct {
+	edit   widget.Clickable
+	remove widget.Clickable
+}
+
+type NotifyForm struct {
+	editingID string
+
+	Name           widget.Editor
+	Enabled        widget.Bool
+	Kind           widget.Enum
+	On             widget.Enum
+	Scope          widget.Enum
+	WebhookURL     widget.Editor
+	WebhookHeaders widget.Editor
+	SMTPHost       widget.Editor
+	SMTPPort       widget.Editor
+	SMTPUser       widget.Editor
+	SMTPPassword   widget.Editor
+	SMTPFrom       widget.Editor
+	SMTPTo         widget.Editor
+	Command        widget.Editor
+
+	kindDropdown  DropdownState
+	onDropdown    DropdownState
+	scopeDropdown DropdownState
+
+	passwordVisible bool
+	passwordToggle  widget.Clickable
+
+	saveBtn widget.Clickable
+	testBtn widget.Clickable
+	newBtn  widget.Clickable
+
+	sinks []models.NotificationSink
+	rows  map[string]*notifyRowWidgets
+}
+
+func newNotifyForm() *NotifyForm {
+	f := &NotifyForm{rows: make(map[string]*notifyRowWidgets)}
+	for _, e := range []*widget.Editor{&f.Name, &f.WebhookURL, &f.SMTPHost, &f.SMTPPort, &f.SMTPUser, &f.SMTPPassword, &f.SMTPFrom, &f.SMTPTo, &f.Command} {
+		e.SingleLine = true
+	}
+	f.load(models.NotificationSink{Kind: models.NotifyWebhook, On: models.NotifyOnFailure, Enabled: true})
+	return f
+}
+
+func (f *NotifyForm) load(sink models.NotificationSink) {
+	f.editingID = sink.ID
+	setEditorText(&f.Name, sink.Name)
+	f.Enabled.Value = sink.Enabled
+	f.Kind.Value = sink.Kind
+	f.On.Value = sink.On
+	switch {
+	case sink.ProfileID != "":
+		f.Scope.Value = notifyScopeHost + sink.ProfileID
+	case sink.Group != "":
+		f.Scope.Value = notifyScopeGroup + sink.Group
+	default:
+		f.Scope.Value = ""
+	}
+	setEditorText(&f.WebhookURL, sink.WebhookURL)
+	setEditorText(&f.WebhookHeaders, formatNotifyHeaders(sink.WebhookHeaders))
+	setEditorText(&f.SMTPHost, sink.SMTPHost)
+	port := ""
+	if sink.SMTPPort > 0 {
+		port = strconv.Itoa(sink.SMTPPort)
+	}
+	setEditorText(&f.SMTPPort, port)
+	setEditorText(&f.SMTPUser, sink.SMTPUser)
+	setEditorText(&f.SMTPPassword, sink.SMTPPassword)
+	setEditorText(&f.SMTPFrom, sink.SMTPFrom)
+	setEditorText(&f.SMTPTo, sink.SMTPTo)
+	setEditorText(&f.Command, sink.Command)
+}
+
+func (f *NotifyForm) sink() (models.NotificationSink, error) {
+	sink := models.NotificationSink{
+		ID:             f.editingID,
+		Name:           strings.TrimSpace(editorText(&f.Name)),
+		Kind:           f.Kind.Value,
+		Enabled:        f.Enabled.Value,
+		On:             f.On.Value,
+		WebhookURL:     strings.TrimSpace(editorText(&f.WebhookURL)),
+		WebhookHeaders: parseNotifyHeaders(editorText(&f.WebhookHeaders)),
+		SMTPHost:       strings.TrimSpace(editorText(&f.SMTPHost)),
+		SMTPUser:       strings.TrimSpace(editorText(&f.SMTPUser)),
+		SMTPPassword:   editorText(&f.SMTPPassword),
+		SMTPFrom:       strings.TrimSpace(editorText(&f.SMTPFrom)),
+		SMTPTo:         strings.TrimSpace(editorText(&f.SMTPTo)),
+		Command:        strings.TrimSpace(editorText(&f.Command)),
+	}
+	switch {
+	case strings.HasPrefix(f.Scope.Value, notifyScopeHost):
+		sink.ProfileID = strings.TrimPrefix(f.Scope.Value, notifyScopeHost)
+	case strings.HasPrefix(f.Scope.Value, notifyScopeGroup):
+		sink.Group = strings.TrimPrefix(f.Scope.Value, notifyScopeGroup)
+	}
+	if port := strings.TrimSpace(editorText(&f.SMTPPort)); port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n <= 0 {
+			return sink, fmt.Errorf("SMTP port must be a number")
+		}
+		sink.SMTPPort = n
+	}
+	return sink, nil
+}
+
+// parseNotifyHeaders reads "Name: value" lines.
+func parseNotifyHeaders(text string) map[string]string {
+	headers := map[string]string{}
+	for _, line := range strings.Split(text, "\n") {
+		name, value, ok := strings.Cut(line, ":")
+		if name = strings.TrimSpace(name); ok && name != "" {
+			headers[name] = strings.TrimSpace(value)
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	return headers
+}
+
+func formatNotifyHeaders(headers map[string]string) string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, name+": "+headers[name])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (u *UI) loadNotifyFormFromCore() {
+	if u.notifyForm == nil {
+		u.notifyForm = newNotifyForm()
+	}
+	sinks, err := u.core.NotificationSinks()
+	if err != nil {
+		return
+	}
+	u.notifyForm.sinks = sinks
+}
+
+// notifyScopeOptions lists "All hosts", every group and every host.
+func (u *UI) notifyScopeOptions() (values, labels []string) {
+	profiles := u.core.Profiles()
+	values = []string{""}
+	labels = []string{"All hosts"}
+	for _, group := range collectGroups(profiles) {
+		values = append(values, notifyScopeGroup+group)
+		labels = append(labels, "Group: "+group)
+	}
+	for _, p := range profiles {
+		values = append(values, notifyScopeHost+p.ID)
+		labels = append(labels, "Host: "+p.Name)
+	}
+	return values, labels
+}
+
+func (u *UI) notifySinkSummary(sink models.NotificationSink) string {
+	scope := "all hosts"
+	switch {
+	case sink.ProfileID != "":
+		scope = "host (deleted)"
+		for _, p := range u.core.Profiles() {
+			if p.ID == sink.ProfileID {
+				scope = "host " + p.Name
+				break
+			}
+		}
+	case sink.Group != "":
+		scope = "group " + sink.Group
+	}
+	on := map[string]string{
+		models.NotifyOnSuccess: "on success",
+		models.NotifyOnFailure: "on failure",
+		models.NotifyOnBoth:    "on success and failure",
+	}[sink.On]
+	summary := fmt.Sprintf("%s · %s · %s", sink.Kind, on, scope)
+	if !sink.Enabled {
+		summary += " · disabled"
+	}
+	return summary
+}
+
+func (u *UI) layoutSettingsNotify(gtx layout.Context, th *material.Theme, theme *AppTheme) layout.Dimensions {
+	if u.notifyForm == nil {
+		u.loadNotifyFormFromCore()
+	}
+	f := u.notifyForm
+	f.Enabled.Update(gtx)
+
+	title := "New Notification"
+	if f.editingID != "" {
+		title = "Edit Notification"
+	}
+	scopeValues, scopeLabels := u.notifyScopeOptions()
+
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.Subtitle1(th, "Notifications")
+			lbl.Color = theme.Text
+			return lbl.Layout(gtx)
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return mutedLabel(gtx, th, theme, "Send a webhook, an email or run a local command when a backup, restore or deep verify finishes. Canceled operations are not reported. Delivery results appear in the activity log.")
+		}),
+		layout.Rigid(vgap(theme)),
+	}
+	if len(f.sinks) == 0 {
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return mutedLabel(gtx, th, theme, "No notifications configured.")
+		}))
+	}
+	for _, sink := range f.sinks {
+		sink := sink
+		row, ok := f.rows[sink.ID]
+		if !ok {
+			row = &notifyRowWidgets{}
+			f.rows[sink.ID] = row
+		}
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Flexed(1, func(gtx layout.Context) layout.Dimensions {
+					return layout.Flex{Axis: layout.Vertical}.Layout(gtx,
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							lbl := material.Body1(th, sink.Name)
+							lbl.Color = theme.Text
+							return lbl.Layout(gtx)
+						}),
+						layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+							return mutedLabel(gtx, th, theme, u.notifySinkSummary(sink))
+						}),
+					)
+				}),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return secondaryButton(gtx, th, theme, &row.edit, "Edit", func() {
+						f.load(sink)
+						u.invalidate()
+					})
+				}),
+				layout.Rigid(hgap(theme)),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return dangerButton(gtx, th, theme, &row.remove, "Delete", func() {
+						u.deleteNotificationSink(sink)
+					})
+				}),
+			)
+		}), layout.Rigid(vgap(theme)))
+	}
+
+	children = append(children,
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return divider(gtx, theme)
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.Subtitle2(th, title)
+			lbl.Color = theme.Text
+			return lbl.Layout(gtx)
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return labeledField(gtx, th, theme, "Name", func(gtx layout.Context) layout.Dimensions {
+				return editorField(gtx, th, theme, &f.Name, "On-call webhook")
+			})
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return labeledEnumDropdownField(gtx, th, theme, &f.Kind, "Type",
+				[]string{models.NotifyWebhook, models.NotifySMTP, models.NotifyCommand},
+				[]string{"HTTP webhook (JSON)", "Email (SMTP)", "Local command"},
+				&f.kindDropdown, u.invalidate, nil)
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return labeledEnumDropdownField(gtx, th, theme, &f.On, "Notify On",
+				[]string{models.NotifyOnFailure, models.NotifyOnSuccess, models.NotifyOnBoth},
+				[]string{"Failure", "Success", "Success and failure"},
+				&f.onDropdown, u.invalidate, nil)
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return labeledEnumDropdownField(gtx, th, theme, &f.Scope, "Hosts", scopeValues, scopeLabels, &f.scopeDropdown, u.invalidate, nil)
+		}),
+		layout.Rigid(vgap(theme)),
+	)
+
+	switch f.Kind.Value {
+	case models.NotifySMTP:
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "SMTP Host", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.SMTPHost, "smtp.example.com")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "SMTP Port", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.SMTPPort, "587 (465 for implicit TLS)")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "Username", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.SMTPUser, "optional")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "Password", func(gtx layout.Context) layout.Dimensions {
+					return passwordField(gtx, th, theme, &f.SMTPPassword, "", &f.passwordVisible, &f.passwordToggle)
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "From", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.SMTPFrom, "dback@example.com")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "To", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.SMTPTo, "oncall@example.com, dba@example.com")
+				})
+			}),
+		)
+	case models.NotifyCommand:
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "Command", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.Command, "/usr/local/bin/notify-dback.sh")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return mutedLabel(gtx, th, theme, "Runs with sh -c (cmd /C on Windows). The event JSON is on stdin; DBACK_EVENT, DBACK_STATUS, DBACK_PROFILE, DBACK_GROUP, DBACK_FILE and DBACK_ERROR are set.")
+			}),
+		)
+	default:
+		children = append(children,
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "Webhook URL", func(gtx layout.Context) layout.Dimensions {
+					return editorField(gtx, th, theme, &f.WebhookURL, "https://hooks.example.com/dback")
+				})
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return labeledField(gtx, th, theme, "Headers", func(gtx layout.Context) layout.Dimensions {
+					return editorMultiline(gtx, th, theme, &f.WebhookHeaders, "Authorization: Bearer … (one per line)")
+				})
+			}),
+		)
+	}
+
+	children = append(children,
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return checkboxField(gtx, th, theme, &f.Enabled, "Enabled")
+		}),
+		layout.Rigid(vgap(theme)),
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return layout.Flex{Axis: layout.Horizontal, Alignment: layout.Middle}.Layout(gtx,
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return successButton(gtx, th, theme, &f.saveBtn, "Save", u.saveNotificationSink)
+				}),
+				layout.Rigid(hgap(theme)),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return secondaryButton(gtx, th, theme, &f.testBtn, "Send Test", u.testNotificationSink)
+				}),
+				layout.Rigid(hgap(theme)),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return secondaryButton(gtx, th, theme, &f.newBtn, "New", func() {
+						f.load(models.NotificationSink{Kind: models.NotifyWebhook, On: models.NotifyOnFailure, Enabled: true})
+						u.invalidate()
+					})
+				}),
+			)
+		}),
+	)
+
+	return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+func (u *UI) saveNotificationSink() {
+	sink, err := u.notifyForm.sink()
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	saved, err := u.core.SaveNotificationSink(sink)
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	u.notifyForm.load(saved)
+	u.loadNotifyFormFromCore()
+	u.invalidate()
+}
+
+func (u *UI) testNotificationSink() {
+	sink, err := u.notifyForm.sink()
+	if err != nil {
+		u.showError(err)
+		return
+	}
+	u.showLoading("Notification test", "Sending test notification...")
+	go func() {
+		if err := u.core.TestNotificationSink(context.Background(), sink); err != nil {
+			u.showError(err)
+			return
+		}
+		u.showInfo("Notification test", "Test notification sent.")
+	}()
+}
+
+func (u *UI) deleteNotificationSink(sink models.NotificationSink) {
+	u.showConfirm("Delete notification?", fmt.Sprintf("Delete %q? It will no longer be sent.", sink.Name), func() {
+		if err := u.core.DeleteNotificationSink(sink.ID); err != nil {
+			u.showError(err)
+			return
+		}
+		if u.notifyForm.editingID == sink.ID {
+			u.notifyForm.load(models.NotificationSink{Kind: models.NotifyWebhook, On: models.NotifyOnFailure, Enabled: true})
+		}
+		delete(u.notifyForm.rows, sink.ID)
+		u.loadNotifyFormFromCore()
+		u.invalidate()
+	})
+}
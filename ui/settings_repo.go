@@ -0,0 +1,137 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+
+	"dback/backend/chunkstore"
+
+	"gioui.org/layout"
+	"gioui.org/widget"
+	"gioui.org/widget/material"
+)
+
+// repoStatsState backs the Repository settings tab: the chunk repositories of the backup
+// destinations, read in the background when the tab is opened or refreshed.
+type repoStatsState struct {
+	refreshBtn widget.Clickable
+	pruneBtns  []widget.Clickable
+	loading    bool
+	pruning    bool
+	loadErr    string
+	stats      []chunkstore.Stats
+}
+
+func (u *UI) loadRepositoryStats() {
+	s := &u.repoStats
+	if s.loading || s.pruning {
+		return
+	}
+	s.loading, s.loadErr = true, ""
+	go func() {
+		stats, err := u.core.RepositoryStats(context.Background())
+		s.loading, s.stats = false, stats
+		if err != nil {
+			s.loadErr = err.Error()
+		}
+		if len(s.pruneBtns) < len(stats) {
+			s.pruneBtns = make([]widget.Clickable, len(stats))
+		}
+		u.invalidate()
+	}()
+}
+
+func (u *UI) pruneRepository(st chunkstore.Stats) {
+	s := &u.repoStats
+	u.showConfirm("Reclaim space", fmt.Sprintf("Delete %d chunk(s) no backup uses any more from %s, freeing %s?", st.UnusedChunks, st.Root, formatBytesMB(st.ReclaimableBytes)), func() {
+		s.pruning = true
+		go func() {
+			res, err := u.core.PruneRepository(context.Background(), st.Root)
+			s.pruning = false
+			if err != nil {
+				u.showError(err)
+			} else {
+				u.showInfo("Space reclaimed", fmt.Sprintf("Deleted %d chunk(s), freed %s.", res.Chunks, formatBytesMB(res.FreedBytes)))
+			}
+			u.loadRepositoryStats()
+		}()
+	})
+}
+
+func (u *UI) layoutSettingsRepository(gtx layout.Context, th *material.Theme, theme *AppTheme) layout.Dimensions {
+	s := &u.repoStats
+	return card(gtx, theme, func(gtx layout.Context) layout.Dimensions {
+		children := []layout.FlexChild{
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return sectionLabel(gtx, th, theme, "Deduplicated repositories")
+			}),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return mutedLabel(gtx, th, theme, "Hosts using the Repository dump format store each backup as chunks shared with earlier backups, in a .dback-repo folder of the backup destination. Chunks left behind by deleted backups are reclaimed by retention or here.")
+			}),
+			layout.Rigid(vgap(theme)),
+			layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				if s.loading {
+					return mutedLabel(gtx, th, theme, "Reading repositories...")
+				}
+				if s.pruning {
+					return mutedLabel(gtx, th, theme, "Reclaiming space...")
+				}
+				return secondaryButton(gtx, th, theme, &s.refreshBtn, "Refresh", u.loadRepositoryStats)
+			}),
+		}
+		if s.loadErr != "" {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return mutedLabel(gtx, th, theme, "Could not read repository: "+s.loadErr)
+			}))
+		}
+		if !s.loading && s.loadErr == "" && len(s.stats) == 0 {
+			children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+				return mutedLabel(gtx, th, theme, "No repository yet. Set a host's dump format to Repository and run a backup.")
+			}))
+		}
+		for i := range s.stats {
+			st := s.stats[i]
+			btn := &s.pruneBtns[i]
+			children = append(children,
+				layout.Rigid(vgap(theme)),
+				layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+					return layoutRepositoryStats(gtx, th, theme, st, func(gtx layout.Context) layout.Dimensions {
+						if st.UnusedChunks == 0 || s.pruning {
+							return layout.Dimensions{}
+						}
+						return secondaryButton(gtx, th, theme, btn, "Reclaim "+formatBytesMB(st.ReclaimableBytes), func() {
+							u.pruneRepository(st)
+						})
+					})
+				}),
+			)
+		}
+		return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+	})
+}
+
+func layoutRepositoryStats(gtx layout.Context, th *material.Theme, theme *AppTheme, st chunkstore.Stats, action layout.Widget) layout.Dimensions {
+	lines := []string{
+		fmt.Sprintf("%d backup(s), %s of SQL stored in %s (%d chunks)", st.Backups, formatBytesMB(st.LogicalBytes), formatBytesMB(st.StoredBytes), st.Chunks),
+		fmt.Sprintf("Deduplication ratio %.1f×: %s of unique data", st.DedupRatio(), formatBytesMB(st.UniqueBytes)),
+		fmt.Sprintf("Reclaimable: %s in %d unused chunk(s)", formatBytesMB(st.ReclaimableBytes), st.UnusedChunks),
+	}
+	if st.MissingChunks > 0 {
+		lines = append(lines, fmt.Sprintf("%d chunk(s) used by backups are missing; those backups cannot be restored. Run quick verify to find them.", st.MissingChunks))
+	}
+	children := []layout.FlexChild{
+		layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			lbl := material.Body1(th, st.Root)
+			lbl.Color = theme.Text
+			return lbl.Layout(gtx)
+		}),
+	}
+	for _, line := range lines {
+		line := line
+		children = append(children, layout.Rigid(func(gtx layout.Context) layout.Dimensions {
+			return mutedLabel(gtx, th, theme, line)
+		}))
+	}
+	children = append(children, layout.Rigid(action))
+	return layout.Flex{Axis: layout.Vertical}.Layout(gtx, children...)
+}
@@ -181,13 +181,20 @@ func (u *UI) runDeepVerifyPrompt(record models.ExportRecord) {
 	u.deepVerifySelect.Value = defaultDeepVerifyHostID(hosts)
 	rec := record
 	fpMode := ""
+	message := "Restore runs only in a temporary database (dback_verify_*). Your production database is not modified. The temp database is deleted when verify finishes."
 	if rec.Fingerprint != nil {
 		fpMode = rec.Fingerprint.Mode
+		if n := len(rec.Fingerprint.ExcludedTables); n > 0 {
+			message += fmt.Sprintf(" %d table(s) were excluded from this backup and are not checked.", n)
+		}
+		if n := len(rec.Fingerprint.FilteredTables); n > 0 {
+			message += fmt.Sprintf(" %d table(s) were dumped with row filters and are checked against their filtered row counts.", n)
+		}
 	}
 	u.showDialog(DialogState{
 		Kind:    DialogDeepVerifyConfirm,
 		Title:   "Deep verify",
-		Message: "Restore runs only in a temporary database (dback_verify_*). Your production database is not modified. The temp database is deleted when verify finishes.",
+		Message: message,
 		OnOK: func() {
 			dest, ok := profileByID(hosts, u.deepVerifySelect.Value)
 			if !ok {
@@ -11,9 +11,14 @@ class DBack_Exporter_Mysqli {
     /**
      * Stream a gzip-compressed SQL dump using WordPress mysqli connection.
      *
+     * @param array<string,array<int,string>> $options See DBack_Exporter::stream_gzip().
      * @throws Exception
      */
-    public static function stream_gzip() {
+    public static function stream_gzip($options = array()) {
+        $exclude = isset($options['exclude_tables']) ? (array) $options['exclude_tables'] : array();
+        $no_data = isset($options['no_data_tables']) ? (array) $options['no_data_tables'] : array();
+        $wheres = isset($options['table_where']) ? (array) $options['table_where'] : array();
+
         @set_time_limit(0);
         if (function_exists('ini_set')) {
             @ini_set('memory_limit', '512M');
@@ -39,8 +44,13 @@ class DBack_Exporter_Mysqli {
                 $table_name = $table_info[0];
                 $table_type = isset($table_info[1]) ? $table_info[1] : 'BASE TABLE';
 
+                if (in_array($table_name, $exclude, true)) {
+                    continue;
+                }
+
                 if ('BASE TABLE' === $table_type) {
-                    self::dump_table($wpdb, $stream, $table_name);
+                    $where = isset($wheres[$table_name]) ? (string) $wheres[$table_name] : '';
+                    self::dump_table($wpdb, $stream, $table_name, !in_array($table_name, $no_data, true), $where);
                     continue;
                 }
 
@@ -89,8 +99,10 @@ class DBack_Exporter_Mysqli {
      * @param wpdb $wpdb
      * @param DBack_Gzip_Stream $stream
      * @param string $table
+     * @param bool $with_rows
+     * @param string $where Condition limiting the rows dumped, empty for all rows.
      */
-    private static function dump_table($wpdb, $stream, $table) {
+    private static function dump_table($wpdb, $stream, $table, $with_rows = true, $where = '') {
         $quoted_table = self::quote_identifier($table);
         $create = $wpdb->get_row('SHOW CREATE TABLE ' . $quoted_table, ARRAY_N);
         DBack_Database::assert_no_db_error($wpdb);
@@ -103,10 +115,13 @@ class DBack_Exporter_Mysqli {
         $stream->write_line('-- Table structure for `' . $table . '`');
         $stream->write_line('DROP TABLE IF EXISTS ' . $quoted_table . ';');
         $stream->write_line($create[1] . ';');
+        if (!$with_rows) {
+            return;
+        }
         $stream->write_line('LOCK TABLES ' . $quoted_table . ' WRITE;');
         $stream->write_line('/*!40000 ALTER TABLE ' . $quoted_table . ' DISABLE KEYS */;');
 
-        self::dump_table_rows($wpdb, $stream, $table);
+        self::dump_table_rows($wpdb, $stream, $table, $where);
 
         $stream->write_line('/*!40000 ALTER TABLE ' . $quoted_table . ' ENABLE KEYS */;');
         $stream->write_line('UNLOCK TABLES;');
@@ -116,8 +131,9 @@ class DBack_Exporter_Mysqli {
      * @param wpdb $wpdb
      * @param DBack_Gzip_Stream $stream
      * @param string $table
+     * @param string $where
      */
-    private static function dump_table_rows($wpdb, $stream, $table) {
+    private static function dump_table_rows($wpdb, $stream, $table, $where = '') {
         $columns = $wpdb->get_col('DESCRIBE ' . self::quote_identifier($table), 0);
         DBack_Database::assert_no_db_error($wpdb);
 
@@ -126,11 +142,24 @@ class DBack_Exporter_Mysqli {
         }
 
         if ($wpdb->dbh instanceof mysqli) {
-            self::dump_table_rows_unbuffered($wpdb, $stream, $table, $columns);
+            self::dump_table_rows_unbuffered($wpdb, $stream, $table, $columns, $where);
             return;
         }
 
-        self::dump_table_rows_batched($wpdb, $stream, $table, $columns);
+        self::dump_table_rows_batched($wpdb, $stream, $table, $columns, $where);
+    }
+
+    /**
+     * @param string $table
+     * @param string $where
+     * @return string
+     */
+    private static function select_rows_sql($table, $where) {
+        $sql = 'SELECT * FROM ' . self::quote_identifier($table);
+        if ('' !== $where) {
+            $sql .= ' WHERE (' . $where . ')';
+        }
+        return $sql;
     }
 
     /**
@@ -138,10 +167,10 @@ class DBack_Exporter_Mysqli {
      * @param DBack_Gzip_Stream $stream
      * @param string $table
      * @param array<int,string> $columns
+     * @param string $where
      */
-    private static function dump_table_rows_unbuffered($wpdb, $stream, $table, $columns) {
-        $quoted_table = self::quote_identifier($table);
-        $result = mysqli_query($wpdb->dbh, 'SELECT * FROM ' . $quoted_table, MYSQLI_USE_RESULT);
+    private static function dump_table_rows_unbuffered($wpdb, $stream, $table, $columns, $where = '') {
+        $result = mysqli_query($wpdb->dbh, self::select_rows_sql($table, $where), MYSQLI_USE_RESULT);
         if (false === $result) {
             throw new RuntimeException($wpdb->last_error ?: 'Unable to read table data.');
         }
@@ -170,14 +199,15 @@ class DBack_Exporter_Mysqli {
      * @param DBack_Gzip_Stream $stream
      * @param string $table
      * @param array<int,string> $columns
+     * @param string $where
      */
-    private static function dump_table_rows_batched($wpdb, $stream, $table, $columns) {
-        $quoted_table = self::quote_identifier($table);
+    private static function dump_table_rows_batched($wpdb, $stream, $table, $columns, $where = '') {
+        $select = self::select_rows_sql($table, $where);
         $offset = 0;
 
         while (true) {
             $rows = $wpdb->get_results(
-                'SELECT * FROM ' . $quoted_table . ' LIMIT ' . (int) $offset . ', ' . self::INSERT_BATCH_SIZE,
+                $select . ' LIMIT ' . (int) $offset . ', ' . self::INSERT_BATCH_SIZE,
                 ARRAY_A
             );
             DBack_Database::assert_no_db_error($wpdb);
@@ -11,24 +11,61 @@ class DBack_Exporter {
     /**
      * Stream a gzip-compressed SQL dump directly to the client.
      *
+     * Options: exclude_tables (tables left out) and no_data_tables (structure only),
+     * both exact table names, and table_where (table name => WHERE condition) for
+     * tables dumped with only some of their rows.
+     *
+     * @param array<string,array<int|string,string>> $options
      * @throws Exception
      */
-    public static function stream_gzip() {
+    public static function stream_gzip($options = array()) {
+        $options = self::normalize_options($options);
+
         if (!function_exists('deflate_init')) {
             throw new RuntimeException('The zlib extension is required for gzip export.');
         }
 
         if (DBack_Database::has_pdo_mysql()) {
-            self::stream_gzip_with_mysqldump();
+            self::stream_gzip_with_mysqldump($options);
+        }
+
+        DBack_Exporter_Mysqli::stream_gzip($options);
+    }
+
+    /**
+     * @param mixed $options
+     * @return array<string,array<int|string,string>>
+     */
+    private static function normalize_options($options) {
+        $normalized = array(
+            'exclude_tables' => array(),
+            'no_data_tables' => array(),
+            'table_where' => array(),
+        );
+        if (!is_array($options)) {
+            return $normalized;
+        }
+        foreach (array('exclude_tables', 'no_data_tables') as $key) {
+            if (!empty($options[$key]) && is_array($options[$key])) {
+                $normalized[$key] = array_values(array_filter($options[$key], 'is_string'));
+            }
+        }
+        if (!empty($options['table_where']) && is_array($options['table_where'])) {
+            foreach ($options['table_where'] as $table => $where) {
+                if (is_string($table) && is_string($where) && '' !== $where) {
+                    $normalized['table_where'][$table] = $where;
+                }
+            }
         }
 
-        DBack_Exporter_Mysqli::stream_gzip();
+        return $normalized;
     }
 
     /**
+     * @param array<string,array<int|string,string>> $options
      * @throws Exception
      */
-    private static function stream_gzip_with_mysqldump() {
+    private static function stream_gzip_with_mysqldump($options) {
         @set_time_limit(0);
         if (function_exists('ini_set')) {
             @ini_set('memory_limit', '512M');
@@ -65,6 +102,8 @@ class DBack_Exporter {
             'add-locks' => true,
             'skip-comments' => false,
             'skip-dump-date' => false,
+            'exclude-tables' => $options['exclude_tables'],
+            'no-data' => $options['no_data_tables'],
         );
 
         $pdo_settings = DBack_Database::pdo_options(false);
@@ -76,6 +115,7 @@ class DBack_Exporter {
             $dump_settings,
             $pdo_settings
         );
+        $dump->setTableWheres($options['table_where']);
 
         $dump->start('php://output');
         exit;
@@ -23,6 +23,25 @@ class DBack_Rest_Controller {
             'methods' => WP_REST_Server::READABLE,
             'callback' => array($this, 'handle_export'),
             'permission_callback' => array($this, 'check_permission'),
+            'args' => array(
+                'exclude_tables' => array(
+                    'required' => false,
+                    'type' => 'array',
+                    'items' => array('type' => 'string'),
+                    'sanitize_callback' => array($this, 'sanitize_table_list_param'),
+                ),
+                'no_data_tables' => array(
+                    'required' => false,
+                    'type' => 'array',
+                    'items' => array('type' => 'string'),
+                    'sanitize_callback' => array($this, 'sanitize_table_list_param'),
+                ),
+                'table_where' => array(
+                    'required' => false,
+                    'type' => 'object',
+                    'sanitize_callback' => array($this, 'sanitize_table_where_param'),
+                ),
+            ),
         ));
 
         register_rest_route(DBACK_DB_TOOLS_REST_NAMESPACE, '/import', array(
@@ -96,6 +115,52 @@ class DBack_Rest_Controller {
         return trim($value);
     }
 
+    /**
+     * @param mixed $value
+     * @return array<int,string>
+     */
+    public function sanitize_table_list_param($value) {
+        if (!is_array($value)) {
+            return array();
+        }
+
+        $tables = array();
+        foreach ($value as $table) {
+            if (is_string($table) && '' !== trim($table)) {
+                $tables[] = trim($table);
+            }
+        }
+
+        return array_values(array_unique($tables));
+    }
+
+    /**
+     * Keeps table => WHERE condition pairs whose condition is a single expression.
+     *
+     * @param mixed $value
+     * @return array<string,string>
+     */
+    public function sanitize_table_where_param($value) {
+        if (!is_array($value)) {
+            return array();
+        }
+
+        $wheres = array();
+        foreach ($value as $table => $where) {
+            if (!is_string($table) || !is_string($where)) {
+                continue;
+            }
+            $table = trim($table);
+            $where = trim($where);
+            if ('' === $table || '' === $where || false !== strpos($where, ';')) {
+                continue;
+            }
+            $wheres[$table] = $where;
+        }
+
+        return $wheres;
+    }
+
     /**
      * @param WP_REST_Request $request
      * @return bool|WP_Error
@@ -161,7 +226,11 @@ class DBack_Rest_Controller {
      */
     public function handle_export($request) {
         try {
-            DBack_Exporter::stream_gzip();
+            DBack_Exporter::stream_gzip(array(
+                'exclude_tables' => (array) $request->get_param('exclude_tables'),
+                'no_data_tables' => (array) $request->get_param('no_data_tables'),
+                'table_where' => (array) $request->get_param('table_where'),
+            ));
         } catch (Throwable $exception) {
             return DBack_Error_Logger::to_wp_error('export', 'dback_export_failed', $exception);
         }